@@ -8,17 +8,21 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/charliek/prox/internal/config"
 	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/domain"
 	"github.com/charliek/prox/internal/logs"
 	"github.com/charliek/prox/internal/proxy"
 	"github.com/charliek/prox/internal/supervisor"
+	"github.com/charliek/prox/internal/websocket"
 )
 
 // Handlers contains all HTTP handlers
@@ -27,8 +31,11 @@ type Handlers struct {
 	logManager     *logs.Manager
 	requestManager *proxy.RequestManager
 	captureManager *proxy.CaptureManager
+	statsTracker   *proxy.StatsTracker
+	proxyService   *proxy.Service
 	configFile     string
 	shutdownFn     func()
+	dumpFn         func(reason string) (string, error)
 }
 
 // NewHandlers creates new HTTP handlers
@@ -54,18 +61,47 @@ func (h *Handlers) SetCaptureManager(cm *proxy.CaptureManager) {
 	h.captureManager = cm
 }
 
+// SetStatsTracker sets the tracker used for per-service latency stats.
+func (h *Handlers) SetStatsTracker(st *proxy.StatsTracker) {
+	h.statsTracker = st
+}
+
+// SetProxyService sets the proxy service used to control routing to processes.
+func (h *Handlers) SetProxyService(ps *proxy.Service) {
+	h.proxyService = ps
+}
+
+// SetDumpFunc sets the function that writes a postmortem dump of the daemon's
+// state and returns its path.
+func (h *Handlers) SetDumpFunc(fn func(reason string) (string, error)) {
+	h.dumpFn = fn
+}
+
 // GetStatus handles GET /api/v1/status
 func (h *Handlers) GetStatus(w http.ResponseWriter, r *http.Request) {
-	status := h.supervisor.Status()
-
-	resp := StatusResponse{
-		Status:        status.State,
-		UptimeSeconds: status.UptimeSeconds(),
-		ConfigFile:    h.configFile,
-		APIVersion:    "v1",
+	snap := statusSnapshot{
+		Supervisor: h.supervisor.Status(),
+		ConfigFile: h.configFile,
+		Asleep:     h.supervisor.Asleep(),
+		Failures:   h.supervisor.StartFailures(),
+	}
+	if h.logManager != nil {
+		snap.Logs = ToLogsStatusResponse(h.logManager.Stats())
+	}
+	if r.URL.Query().Get("detail") == "true" {
+		snap.Detail = &StatusDetailResponse{
+			Goroutines: runtime.NumGoroutine(),
+			Watchdog:   ToWatchdogResponse(h.supervisor.Watchdog().Stats()),
+		}
 	}
 
-	writeJSON(w, http.StatusOK, resp)
+	switch requestVersion(r) {
+	case V2:
+		snap.Processes = h.supervisor.Processes()
+		writeJSON(w, http.StatusOK, toStatusResponseV2(snap))
+	default:
+		writeJSON(w, http.StatusOK, toStatusResponse(snap))
+	}
 }
 
 // GetProcesses handles GET /api/v1/processes
@@ -97,6 +133,74 @@ func (h *Handlers) GetProcess(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// AddProcess handles POST /api/v1/processes
+// It registers a process that isn't in the config and starts it.
+func (h *Handlers) AddProcess(w http.ResponseWriter, r *http.Request) {
+	var req AddProcessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" || req.Cmd == "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Error: `request body must be {"name": "...", "cmd": "..."}`,
+			Code:  domain.ErrCodeInvalidRequest,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	if err := h.supervisor.AddProcess(ctx, req.Name, config.ProcessConfig{Cmd: req.Cmd, Env: req.Env}); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	info, err := h.supervisor.Process(req.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ToProcessDetailResponse(info))
+}
+
+// UpdateProcess handles PATCH /api/v1/processes/{name}
+// It changes the process's cmd, env, or healthcheck without editing the
+// config file. The change is applied when the process next restarts, or
+// right away when the request sets restart.
+func (h *Handlers) UpdateProcess(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var req UpdateProcessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Error: `request body must be {"cmd": "...", "env": {...}, "healthcheck": {...}, "restart": true}`,
+			Code:  domain.ErrCodeInvalidRequest,
+		})
+		return
+	}
+	update, err := req.toProcessUpdate()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Error: err.Error(),
+			Code:  domain.ErrCodeInvalidRequest,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	if _, err := h.supervisor.UpdateProcess(ctx, name, update, req.Restart); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	info, err := h.supervisor.Process(name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ToProcessDetailResponse(info))
+}
+
 // StartProcess handles POST /api/v1/processes/{name}/start
 func (h *Handlers) StartProcess(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
@@ -128,12 +232,30 @@ func (h *Handlers) StopProcess(w http.ResponseWriter, r *http.Request) {
 }
 
 // RestartProcess handles POST /api/v1/processes/{name}/restart
+// With ?strategy=blue-green, a new instance is started on a fresh port and
+// proxy traffic is switched to it before the old instance is stopped.
 func (h *Handlers) RestartProcess(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
 
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
+	if r.URL.Query().Get("strategy") == "blue-green" {
+		var switchFn supervisor.SwitchFunc
+		if h.proxyService != nil {
+			switchFn = func(port int) error {
+				h.proxyService.SetProcessPort(name, port)
+				return nil
+			}
+		}
+		if err := h.supervisor.BlueGreenRestart(ctx, name, switchFn); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, SuccessResponse{Success: true})
+		return
+	}
+
 	if err := h.supervisor.RestartProcess(ctx, name); err != nil {
 		writeError(w, err)
 		return
@@ -142,6 +264,181 @@ func (h *Handlers) RestartProcess(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, SuccessResponse{Success: true})
 }
 
+// SignalProcess handles POST /api/v1/processes/{name}/signal
+// It sends a signal to the running process, e.g. SIGUSR2 for a live reload,
+// without stopping it.
+func (h *Handlers) SignalProcess(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var req SignalProcessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Signal == "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Error: `request body must be {"signal": "..."}`,
+			Code:  domain.ErrCodeInvalidRequest,
+		})
+		return
+	}
+	sig, err := supervisor.ParseSignal(req.Signal)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := h.supervisor.SignalProcess(name, sig); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SuccessResponse{Success: true})
+}
+
+// AttachProcess handles GET /api/v1/processes/{name}/attach, a WebSocket
+// carrying a process's input and output as binary messages: messages from
+// the client are written to the process's stdin, and what it writes to
+// stdout and stderr is sent as it is written. The server closes the
+// connection when the process exits.
+func (h *Handlers) AttachProcess(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsUpgrade(r) {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Error: "websocket upgrade required",
+			Code:  domain.ErrCodeInvalidRequest,
+		})
+		return
+	}
+	// Browsers can't add the token to a WebSocket request, and on localhost
+	// auth is off, so without this any web page could type into the process
+	if origin := r.Header.Get("Origin"); origin != "" && !isLocalhostOrigin(origin) {
+		writeJSON(w, http.StatusForbidden, ErrorResponse{
+			Error: fmt.Sprintf("attaching from origin %s is not allowed", origin),
+			Code:  domain.ErrCodeForbiddenOrigin,
+		})
+		return
+	}
+
+	attachment, err := h.supervisor.AttachProcess(chi.URLParam(r, "name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		attachment.Close()
+		if errors.Is(err, websocket.ErrBadHandshake) {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{
+				Error: err.Error(),
+				Code:  domain.ErrCodeInvalidRequest,
+			})
+		}
+		return
+	}
+
+	// The connection outlives the request, whose timeout doesn't apply to it
+	go serveAttachment(conn, attachment)
+}
+
+// serveAttachment copies input from conn to the process and the process's
+// output to conn until either side closes
+func serveAttachment(conn *websocket.Conn, attachment *supervisor.Attachment) {
+	defer conn.Close()
+	go func() {
+		defer attachment.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if _, err := attachment.Write(data); err != nil {
+				return
+			}
+		}
+	}()
+	for chunk := range attachment.Output {
+		if err := conn.WriteMessage(websocket.OpBinary, chunk); err != nil {
+			attachment.Close()
+		}
+	}
+}
+
+// GetTasks handles GET /api/v1/tasks
+func (h *Handlers) GetTasks(w http.ResponseWriter, r *http.Request) {
+	tasks := h.supervisor.Tasks()
+	resp := TaskListResponse{Tasks: make([]TaskResponse, len(tasks))}
+	for i, task := range tasks {
+		resp.Tasks[i] = ToTaskResponse(task)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetTask handles GET /api/v1/tasks/{name}
+func (h *Handlers) GetTask(w http.ResponseWriter, r *http.Request) {
+	task, err := h.supervisor.Task(chi.URLParam(r, "name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ToTaskResponse(task))
+}
+
+// RunTask handles POST /api/v1/tasks/{name}/run
+// It starts the task and responds without waiting for it to finish; poll
+// GET /tasks/{name} for its exit status, and read its output from the logs.
+func (h *Handlers) RunTask(w http.ResponseWriter, r *http.Request) {
+	task, err := h.supervisor.RunTask(context.Background(), chi.URLParam(r, "name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, ToTaskResponse(task))
+}
+
+// DrainProcess handles POST /api/v1/processes/{name}/drain
+// It stops routing new proxy traffic to the process, waits for in-flight
+// requests to finish, then stops the process.
+func (h *Handlers) DrainProcess(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	if h.proxyService == nil {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{
+			Error: "proxy not enabled",
+			Code:  domain.ErrCodeProxyNotEnabled,
+		})
+		return
+	}
+
+	info, err := h.supervisor.Process(name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if info.State != domain.ProcessStateRunning {
+		writeError(w, fmt.Errorf("%w: %s", domain.ErrProcessNotRunning, name))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	drainCtx, drainCancel := context.WithTimeout(ctx, constants.DefaultDrainTimeout)
+	defer drainCancel()
+
+	if err := h.proxyService.DrainProcess(drainCtx, name); err != nil {
+		if errors.Is(err, domain.ErrNoProxyServices) {
+			writeError(w, err)
+			return
+		}
+		// In-flight requests did not finish in time; stop anyway
+		h.supervisor.SystemLog("drain of %s timed out, stopping with requests in flight", name)
+	}
+
+	if err := h.supervisor.StopProcess(ctx, name); err != nil {
+		h.proxyService.ResumeProcess(name)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SuccessResponse{Success: true})
+}
+
 // GetLogs handles GET /api/v1/logs
 func (h *Handlers) GetLogs(w http.ResponseWriter, r *http.Request) {
 	filter, limit, err := parseLogParams(r)
@@ -153,25 +450,169 @@ func (h *Handlers) GetLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	entries, total, err := h.logManager.QueryLast(filter, limit)
+	after, size, paged, err := parsePageParams(r, constants.DefaultLogLimit, constants.MaxLogLines)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Error: err.Error(),
+			Code:  domain.ErrCodeInvalidRequest,
+		})
+		return
+	}
+	if paged {
+		page, err := h.logManager.Page(filter, after, size)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		resp := LogsResponse{
+			Logs:          make([]LogEntryResponse, len(page.Entries)),
+			FilteredCount: len(page.Entries),
+			TotalCount:    len(page.Entries),
+			NextCursor:    strconv.FormatUint(page.Next, 10),
+			HasMore:       page.More,
+		}
+		for i, e := range page.Entries {
+			resp.Logs[i] = ToLogEntryResponse(e)
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	result, err := h.logManager.Search(filter, limit)
 	if err != nil {
 		writeError(w, err)
 		return
 	}
 
 	resp := LogsResponse{
-		Logs:          make([]LogEntryResponse, len(entries)),
-		FilteredCount: len(entries),
-		TotalCount:    total,
+		Logs:          make([]LogEntryResponse, len(result.Entries)),
+		FilteredCount: len(result.Entries),
+		TotalCount:    result.Total,
+		Truncated:     result.Truncated,
+		Index:         result.Index,
 	}
 
-	for i, e := range entries {
+	for i, e := range result.Entries {
 		resp.Logs[i] = ToLogEntryResponse(e)
 	}
 
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// GetProblems handles GET /api/v1/problems
+// It returns the compiler and test failures that processes' problem matchers
+// find in the buffered logs, oldest first.
+func (h *Handlers) GetProblems(w http.ResponseWriter, r *http.Request) {
+	matcher := h.supervisor.ProblemMatcher()
+	resp := ProblemsResponse{Problems: []ProblemResponse{}}
+
+	filter := domain.LogFilter{Processes: problemProcesses(r, matcher)}
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		if t, err := time.Parse(time.RFC3339Nano, sinceStr); err == nil {
+			filter.Since = t
+		}
+	}
+	if len(filter.Processes) == 0 {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	result, err := h.logManager.Search(filter, constants.MaxLogLines)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	for _, p := range matcher.FindProblems(result.Entries) {
+		resp.Problems = append(resp.Problems, ToProblemResponse(p))
+	}
+	resp.Truncated = result.Truncated
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// StreamProblems handles GET /api/v1/problems/stream (SSE)
+// It sends each problem found in new log lines as it is logged.
+func (h *Handlers) StreamProblems(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{
+			Error: "streaming not supported",
+			Code:  domain.ErrCodeStreamingNotSupported,
+		})
+		return
+	}
+
+	// Set SSE headers
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	// Subscribe to every process, so problem matchers added by a reload
+	// apply to the open stream
+	filter := domain.LogFilter{}
+	if processes := r.URL.Query().Get("process"); processes != "" {
+		filter.Processes = strings.Split(processes, ",")
+	}
+	subID, ch, err := h.logManager.Subscribe(filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer h.logManager.Unsubscribe(subID)
+	handle := h.trackStream(w, "problems-"+subID, func() time.Time {
+		return h.logManager.SubscriptionStalledSince(subID)
+	}, func() {
+		h.logManager.Unsubscribe(subID)
+	})
+	defer handle.Done()
+
+	// Send initial comment to establish connection
+	fmt.Fprintf(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case entry, ok := <-ch:
+			if !ok {
+				writeShutdownEvent(w, flusher, h.logManager)
+				return
+			}
+
+			problem, found := h.supervisor.ProblemMatcher().Match(entry)
+			if !found {
+				continue
+			}
+			data, err := json.Marshal(ToProblemResponse(problem))
+			if err != nil {
+				continue
+			}
+			if _, err := w.Write([]byte("data: " + string(data) + "\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// problemProcesses returns the processes with problem matchers, narrowed to
+// those named by the request's process parameter, if given
+func problemProcesses(r *http.Request, matcher *logs.ProblemMatcher) []string {
+	processes := matcher.Processes()
+	requested := r.URL.Query().Get("process")
+	if requested == "" {
+		return processes
+	}
+	var selected []string
+	for _, name := range strings.Split(requested, ",") {
+		if slices.Contains(processes, name) {
+			selected = append(selected, name)
+		}
+	}
+	return selected
+}
+
 // Shutdown handles POST /api/v1/shutdown
 func (h *Handlers) Shutdown(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, SuccessResponse{Success: true})
@@ -185,21 +626,76 @@ func (h *Handlers) Shutdown(w http.ResponseWriter, r *http.Request) {
 	}()
 }
 
-// parseLogParams extracts log filter parameters from request
-func parseLogParams(r *http.Request) (domain.LogFilter, int, error) {
-	filter := domain.LogFilter{}
+// Reload handles POST /api/v1/reload
+// It re-reads the config file and applies changes to process definitions:
+// added processes are started, removed ones stopped, and changed ones
+// restarted. An invalid config leaves everything running as it was.
+func (h *Handlers) Reload(w http.ResponseWriter, r *http.Request) {
+	cfg, err := config.Load(h.configFile)
+	if err != nil {
+		if !errors.Is(err, domain.ErrInvalidConfig) {
+			err = fmt.Errorf("%w: %v", domain.ErrInvalidConfig, err)
+		}
+		writeError(w, err)
+		return
+	}
 
-	// Process filter
-	if processes := r.URL.Query().Get("process"); processes != "" {
-		filter.Processes = strings.Split(processes, ",")
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	result, err := h.supervisor.Reload(ctx, cfg)
+	if err != nil {
+		writeError(w, err)
+		return
 	}
 
-	// Pattern filter
-	filter.Pattern = r.URL.Query().Get("pattern")
+	writeJSON(w, http.StatusOK, ToReloadResponse(result))
+}
 
-	// Regex flag
-	if r.URL.Query().Get("regex") == "true" {
-		filter.IsRegex = true
+// TriggerDump handles POST /api/v1/debug/dump
+// It writes the same postmortem dump as SIGQUIT, without stopping the daemon.
+func (h *Handlers) TriggerDump(w http.ResponseWriter, r *http.Request) {
+	if h.dumpFn == nil {
+		writeError(w, errors.New("postmortem dumps are not available"))
+		return
+	}
+
+	path, err := h.dumpFn("requested via API")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, DumpResponse{Path: path})
+}
+
+// nonEmpty returns the non-empty values, or nil if there are none
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// parseLogParams extracts log filter parameters from request
+func parseLogParams(r *http.Request) (domain.LogFilter, int, error) {
+	filter := parseLogFilter(r)
+
+	// Time range
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		if t, err := time.Parse(time.RFC3339Nano, sinceStr); err == nil {
+			filter.Since = t
+		}
+	}
+	if untilStr := r.URL.Query().Get("until"); untilStr != "" {
+		if t, err := time.Parse(time.RFC3339Nano, untilStr); err == nil {
+			filter.Until = t
+		}
+	}
+	if _, err := filter.Compile(); err != nil {
+		return filter, 0, err
 	}
 
 	// Lines limit (default 100, max 10000 to prevent DoS)
@@ -217,6 +713,77 @@ func parseLogParams(r *http.Request) (domain.LogFilter, int, error) {
 	return filter, limit, nil
 }
 
+// parsePageParams extracts the cursor and page_size parameters of the
+// endpoints that page through history. paged is false when neither is
+// given. The cursor is the next_cursor of the previous page; the page size
+// defaults to defaultSize and is capped at maxSize.
+func parsePageParams(r *http.Request, defaultSize, maxSize int) (after uint64, size int, paged bool, err error) {
+	cursor := r.URL.Query().Get("cursor")
+	sizeStr := r.URL.Query().Get("page_size")
+	if cursor == "" && sizeStr == "" {
+		return 0, 0, false, nil
+	}
+
+	if cursor != "" {
+		if after, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			return 0, 0, false, fmt.Errorf("invalid cursor: %q", cursor)
+		}
+	}
+	size = defaultSize
+	if sizeStr != "" {
+		n, err := strconv.Atoi(sizeStr)
+		if err != nil || n <= 0 {
+			return 0, 0, false, fmt.Errorf("invalid page_size: %q", sizeStr)
+		}
+		size = min(n, maxSize)
+	}
+	return after, size, true, nil
+}
+
+// parseLogFilter extracts the process, pattern, level, and stream filters
+// shared by the log endpoints
+func parseLogFilter(r *http.Request) domain.LogFilter {
+	filter := domain.LogFilter{}
+
+	// Process filter
+	if processes := r.URL.Query().Get("process"); processes != "" {
+		filter.Processes = strings.Split(processes, ",")
+	}
+
+	// Pattern filters, repeated to require every pattern to match
+	filter.Patterns = nonEmpty(r.URL.Query()["pattern"])
+
+	// Regex flag
+	if r.URL.Query().Get("regex") == "true" {
+		filter.IsRegex = true
+	}
+
+	filter.Level = parseLogLevel(r)
+	filter.Stream = parseLogStream(r)
+	return filter
+}
+
+// parseLogStream returns the stream query parameter, with "both" as no
+// stream. An unknown stream is returned as given so the filter rejects it.
+func parseLogStream(r *http.Request) domain.Stream {
+	stream := r.URL.Query().Get("stream")
+	if parsed, err := domain.ParseStreamFilter(stream); err == nil {
+		return parsed
+	}
+	return domain.Stream(stream)
+}
+
+// parseLogLevel returns the level query parameter normalized, e.g.
+// "warning" as "warn". An unknown level is returned as given so the filter
+// rejects it.
+func parseLogLevel(r *http.Request) string {
+	level := r.URL.Query().Get("level")
+	if normalized := domain.NormalizeLogLevel(level); normalized != "" {
+		return normalized
+	}
+	return level
+}
+
 // writeJSON writes a JSON response
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -245,6 +812,10 @@ func writeError(w http.ResponseWriter, err error) {
 		status = http.StatusConflict
 		code = domain.ErrCodeProcessNotRunning
 		message = err.Error()
+	case errors.Is(err, domain.ErrProcessExists):
+		status = http.StatusConflict
+		code = domain.ErrCodeProcessExists
+		message = err.Error()
 	case errors.Is(err, domain.ErrInvalidPattern):
 		status = http.StatusBadRequest
 		code = domain.ErrCodeInvalidPattern
@@ -253,6 +824,50 @@ func writeError(w http.ResponseWriter, err error) {
 		status = http.StatusServiceUnavailable
 		code = domain.ErrCodeShutdownInProgress
 		message = err.Error()
+	case errors.Is(err, domain.ErrNoProxyServices):
+		status = http.StatusBadRequest
+		code = domain.ErrCodeNoProxyServices
+		message = err.Error()
+	case errors.Is(err, domain.ErrPortNotAuto):
+		status = http.StatusBadRequest
+		code = domain.ErrCodePortNotAuto
+		message = err.Error()
+	case errors.Is(err, domain.ErrProcessNotReady):
+		status = http.StatusServiceUnavailable
+		code = domain.ErrCodeProcessNotReady
+		message = err.Error()
+	case errors.Is(err, domain.ErrDependencyUnavailable):
+		status = http.StatusServiceUnavailable
+		code = domain.ErrCodeDependencyUnavailable
+		message = err.Error()
+	case errors.Is(err, domain.ErrRuleNotFound):
+		status = http.StatusNotFound
+		code = domain.ErrCodeRuleNotFound
+		message = err.Error()
+	case errors.Is(err, domain.ErrInvalidConfig):
+		status = http.StatusBadRequest
+		code = domain.ErrCodeInvalidConfig
+		message = err.Error()
+	case errors.Is(err, domain.ErrInvalidSignal):
+		status = http.StatusBadRequest
+		code = domain.ErrCodeInvalidSignal
+		message = err.Error()
+	case errors.Is(err, domain.ErrTaskNotFound):
+		status = http.StatusNotFound
+		code = domain.ErrCodeTaskNotFound
+		message = err.Error()
+	case errors.Is(err, domain.ErrTaskRunning):
+		status = http.StatusConflict
+		code = domain.ErrCodeTaskRunning
+		message = err.Error()
+	case errors.Is(err, domain.ErrStdinDisabled):
+		status = http.StatusBadRequest
+		code = domain.ErrCodeStdinDisabled
+		message = err.Error()
+	case errors.Is(err, domain.ErrProcessFailed):
+		status = http.StatusConflict
+		code = domain.ErrCodeProcessFailed
+		message = err.Error()
 	default:
 		// For unknown errors, log the actual error but return a sanitized message
 		// to avoid leaking internal paths or sensitive information
@@ -276,6 +891,30 @@ func (h *Handlers) GetProxyRequests(w http.ResponseWriter, r *http.Request) {
 	}
 
 	filter := parseProxyRequestParams(r)
+	after, size, paged, err := parsePageParams(r, constants.DefaultProxyRequestLimit, constants.MaxProxyRequests)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Error: err.Error(),
+			Code:  domain.ErrCodeInvalidRequest,
+		})
+		return
+	}
+	if paged {
+		filter.Limit = size
+		page := h.requestManager.Page(filter, after)
+		resp := ProxyRequestsResponse{
+			Requests:      make([]ProxyRequestResponse, len(page.Records)),
+			FilteredCount: len(page.Records),
+			TotalCount:    h.requestManager.Count(),
+			NextCursor:    strconv.FormatUint(page.Next, 10),
+			HasMore:       page.More,
+		}
+		for i, req := range page.Records {
+			resp.Requests[i] = ToProxyRequestResponse(req)
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
 
 	requests := h.requestManager.Recent(filter)
 	total := h.requestManager.Count()
@@ -293,6 +932,40 @@ func (h *Handlers) GetProxyRequests(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// ExportProxyRequests handles GET /api/v1/proxy/requests/export
+// It returns the request history as an HTTP Archive (format=har) for import
+// into browser dev tools. The requests filters apply, but without limit the
+// whole history is exported.
+func (h *Handlers) ExportProxyRequests(w http.ResponseWriter, r *http.Request) {
+	if h.requestManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{
+			Error: "proxy not enabled",
+			Code:  domain.ErrCodeProxyNotEnabled,
+		})
+		return
+	}
+
+	if format := r.URL.Query().Get("format"); format != "" && format != "har" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Error: fmt.Sprintf("unsupported export format %q, expected har", format),
+			Code:  domain.ErrCodeInvalidRequest,
+		})
+		return
+	}
+
+	filter := parseProxyRequestParams(r)
+	if r.URL.Query().Get("limit") == "" {
+		filter.Limit = 0
+	}
+	origin := func(subdomain string) string { return "http://" + subdomain }
+	if h.proxyService != nil {
+		origin = h.proxyService.Origin
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="prox.har"`)
+	writeJSON(w, http.StatusOK, proxy.ExportHAR(h.requestManager, h.captureManager, filter, origin))
+}
+
 // GetProxyRequest handles GET /api/v1/proxy/requests/{id}
 func (h *Handlers) GetProxyRequest(w http.ResponseWriter, r *http.Request) {
 	if h.requestManager == nil {
@@ -336,6 +1009,118 @@ func (h *Handlers) GetProxyRequest(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// GetProxyStats handles GET /api/v1/proxy/stats
+func (h *Handlers) GetProxyStats(w http.ResponseWriter, r *http.Request) {
+	if h.statsTracker == nil {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{
+			Error: "proxy not enabled",
+			Code:  domain.ErrCodeProxyNotEnabled,
+		})
+		return
+	}
+
+	stats := h.statsTracker.Stats()
+
+	resp := ProxyStatsResponse{
+		WindowSeconds: int64(h.statsTracker.Window().Seconds()),
+		Services:      make([]ServiceStatsResponse, len(stats)),
+	}
+
+	for i, s := range stats {
+		resp.Services[i] = ToServiceStatsResponse(s)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetProxyRules handles GET /api/v1/proxy/rules
+func (h *Handlers) GetProxyRules(w http.ResponseWriter, r *http.Request) {
+	if h.proxyService == nil {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{
+			Error: "proxy not enabled",
+			Code:  domain.ErrCodeProxyNotEnabled,
+		})
+		return
+	}
+
+	rules := h.proxyService.Rules()
+
+	resp := ProxyRulesResponse{
+		Rules: make([]ProxyRuleResponse, len(rules)),
+	}
+	for i, rule := range rules {
+		resp.Rules[i] = ToProxyRuleResponse(rule)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// EnableProxyRule handles POST /api/v1/proxy/rules/{id}/enable
+func (h *Handlers) EnableProxyRule(w http.ResponseWriter, r *http.Request) {
+	h.setProxyRuleEnabled(w, r, true)
+}
+
+// DisableProxyRule handles POST /api/v1/proxy/rules/{id}/disable
+func (h *Handlers) DisableProxyRule(w http.ResponseWriter, r *http.Request) {
+	h.setProxyRuleEnabled(w, r, false)
+}
+
+// setProxyRuleEnabled toggles a proxy rule and responds with its new state
+func (h *Handlers) setProxyRuleEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	if h.proxyService == nil {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{
+			Error: "proxy not enabled",
+			Code:  domain.ErrCodeProxyNotEnabled,
+		})
+		return
+	}
+
+	rule, err := h.proxyService.SetRuleEnabled(chi.URLParam(r, "id"), enabled)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ToProxyRuleResponse(rule))
+}
+
+// GetProxyRecording handles GET /api/v1/proxy/recording
+func (h *Handlers) GetProxyRecording(w http.ResponseWriter, r *http.Request) {
+	if h.proxyService == nil {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{
+			Error: "proxy not enabled",
+			Code:  domain.ErrCodeProxyNotEnabled,
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ProxyRecordingResponse{Enabled: h.proxyService.Recording()})
+}
+
+// SetProxyRecording handles POST /api/v1/proxy/recording
+// It pauses or resumes recording proxied requests without stopping the proxy.
+func (h *Handlers) SetProxyRecording(w http.ResponseWriter, r *http.Request) {
+	if h.proxyService == nil {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{
+			Error: "proxy not enabled",
+			Code:  domain.ErrCodeProxyNotEnabled,
+		})
+		return
+	}
+
+	var req ProxyRecordingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Error: `request body must be {"enabled": true} or {"enabled": false}`,
+			Code:  domain.ErrCodeInvalidRequest,
+		})
+		return
+	}
+
+	h.proxyService.SetRecording(*req.Enabled)
+	writeJSON(w, http.StatusOK, ProxyRecordingResponse{Enabled: h.proxyService.Recording()})
+}
+
 // convertRequestDetails converts proxy.RequestDetails to RequestDetailsResponse
 func (h *Handlers) convertRequestDetails(details *proxy.RequestDetails, includeBody bool) *RequestDetailsResponse {
 	if details == nil {
@@ -425,6 +1210,10 @@ func (h *Handlers) StreamProxyRequests(w http.ResponseWriter, r *http.Request) {
 	filter := parseProxyRequestParams(r)
 	sub := h.requestManager.Subscribe(filter)
 	defer h.requestManager.Unsubscribe(sub.ID)
+	handle := h.trackStream(w, "requests-"+sub.ID, sub.StalledSince, func() {
+		h.requestManager.Unsubscribe(sub.ID)
+	})
+	defer handle.Done()
 
 	// Send initial comment to establish connection
 	fmt.Fprintf(w, ": connected\n\n")
@@ -454,6 +1243,56 @@ func (h *Handlers) StreamProxyRequests(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// SaveProxySession handles GET /api/v1/proxy/session
+// It returns the request history, with captured bodies, as a session archive
+// that can be loaded into another prox instance.
+func (h *Handlers) SaveProxySession(w http.ResponseWriter, r *http.Request) {
+	if h.requestManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{
+			Error: "proxy not enabled",
+			Code:  domain.ErrCodeProxyNotEnabled,
+		})
+		return
+	}
+
+	session := proxy.ExportSession(h.requestManager, h.captureManager)
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="prox.proxsession"`)
+	if err := proxy.WriteSession(w, session); err != nil {
+		log.Printf("Error writing session: %v", err)
+	}
+}
+
+// LoadProxySession handles POST /api/v1/proxy/session
+// It adds the requests of a session archive to the request history, marked
+// as imported, for inspection only.
+func (h *Handlers) LoadProxySession(w http.ResponseWriter, r *http.Request) {
+	if h.requestManager == nil {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{
+			Error: "proxy not enabled",
+			Code:  domain.ErrCodeProxyNotEnabled,
+		})
+		return
+	}
+
+	session, err := proxy.ReadSession(http.MaxBytesReader(w, r.Body, constants.MaxSessionSize))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Error: err.Error(),
+			Code:  domain.ErrCodeInvalidSession,
+		})
+		return
+	}
+
+	imported := h.requestManager.Import(session.Requests)
+	writeJSON(w, http.StatusOK, SessionLoadResponse{
+		Imported: imported,
+		Skipped:  len(session.Requests) - imported,
+		SavedAt:  session.SavedAt.Format(time.RFC3339),
+	})
+}
+
 // parseProxyRequestParams extracts proxy request filter parameters
 func parseProxyRequestParams(r *http.Request) proxy.RequestFilter {
 	filter := proxy.RequestFilter{}
@@ -479,6 +1318,12 @@ func parseProxyRequestParams(r *http.Request) proxy.RequestFilter {
 		}
 	}
 
+	if untilStr := r.URL.Query().Get("until"); untilStr != "" {
+		if t, err := time.Parse(time.RFC3339Nano, untilStr); err == nil {
+			filter.Until = t
+		}
+	}
+
 	limit := constants.DefaultProxyRequestLimit
 	if linesStr := r.URL.Query().Get("limit"); linesStr != "" {
 		if l, err := strconv.Atoi(linesStr); err == nil && l > 0 && l <= constants.MaxProxyRequests {
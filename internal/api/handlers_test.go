@@ -2,10 +2,16 @@ package api
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -15,10 +21,12 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/domain"
 	"github.com/charliek/prox/internal/logs"
 	"github.com/charliek/prox/internal/proxy"
 	"github.com/charliek/prox/internal/supervisor"
+	"github.com/charliek/prox/internal/websocket"
 )
 
 func setupTestServer(t *testing.T) (*Server, *supervisor.Supervisor, *logs.Manager, func()) {
@@ -29,6 +37,9 @@ func setupTestServer(t *testing.T) (*Server, *supervisor.Supervisor, *logs.Manag
 		Processes: map[string]config.ProcessConfig{
 			"test": {Cmd: "sleep 30"},
 		},
+		Tasks: map[string]config.TaskConfig{
+			"migrate": {Cmd: "sleep 30", Description: "Run migrations"},
+		},
 	}
 
 	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
@@ -68,6 +79,31 @@ func TestGetStatus(t *testing.T) {
 	assert.Equal(t, "running", resp.Status)
 	assert.Equal(t, "v1", resp.APIVersion)
 	assert.Equal(t, "prox.yaml", resp.ConfigFile)
+	assert.Nil(t, resp.Detail)
+	require.NotNil(t, resp.Logs)
+	assert.Positive(t, resp.Logs.BufferSize)
+	assert.Zero(t, resp.Logs.MaxMemoryBytes)
+}
+
+func TestGetStatus_Detail(t *testing.T) {
+	server, _, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	req := httptest.NewRequest("GET", "/api/v1/status?detail=true", nil)
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp StatusResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.NotNil(t, resp.Detail)
+	assert.Positive(t, resp.Detail.Goroutines)
+	for _, kind := range []string{"sse_subscriber", "output_reader", "health_checker"} {
+		assert.Contains(t, resp.Detail.Watchdog.Active, kind)
+		assert.Contains(t, resp.Detail.Watchdog.Leaked, kind)
+	}
 }
 
 func TestGetProcesses(t *testing.T) {
@@ -255,6 +291,116 @@ func TestGetLogs(t *testing.T) {
 		assert.Len(t, resp.Logs, 1)
 		assert.Equal(t, "api", resp.Logs[0].Process)
 	})
+
+	t.Run("every pattern must match", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/logs?pattern=line&pattern=api&pattern=", nil)
+		w := httptest.NewRecorder()
+
+		handlers.GetLogs(w, req)
+
+		var resp LogsResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+
+		require.Len(t, resp.Logs, 1)
+		assert.Equal(t, "api line", resp.Logs[0].Line)
+	})
+}
+
+func TestGetProblems(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	for _, entry := range []struct{ process, line string }{
+		{"build", "# example.com/app"},
+		{"build", "./main.go:12:5: undefined: handler"},
+		{"web", "./main.go:3:1: not a problem, web has no matchers"},
+		{"test", "FAIL spec/user_spec.rb:9"},
+	} {
+		logMgr.Write(domain.LogEntry{Timestamp: time.Now(), Process: entry.process, Stream: domain.StreamStderr, Line: entry.line})
+	}
+
+	cfg := &config.Config{
+		API: config.APIConfig{Port: 0},
+		Processes: map[string]config.ProcessConfig{
+			"build": {Cmd: "echo", ProblemMatchers: []string{"go"}},
+			"web":   {Cmd: "echo"},
+			"test":  {Cmd: "echo", ProblemMatchers: []string{"rspec"}},
+		},
+		ProblemMatchers: map[string]string{"rspec": `^FAIL (?P<file>[^:]+):(?P<line>\d+)$`},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
+
+	get := func(url string) ProblemsResponse {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		handlers.GetProblems(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp ProblemsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return resp
+	}
+
+	resp := get("/api/v1/problems")
+	require.Len(t, resp.Problems, 2)
+	assert.Equal(t, ProblemResponse{
+		Timestamp: resp.Problems[0].Timestamp,
+		Process:   "build",
+		File:      "./main.go",
+		Line:      12,
+		Column:    5,
+		Message:   "undefined: handler",
+	}, resp.Problems[0])
+	assert.Equal(t, "spec/user_spec.rb", resp.Problems[1].File)
+
+	resp = get("/api/v1/problems?process=test")
+	require.Len(t, resp.Problems, 1)
+	assert.Equal(t, "test", resp.Problems[0].Process)
+
+	resp = get("/api/v1/problems?process=web")
+	assert.Empty(t, resp.Problems)
+}
+
+func TestStreamProblems(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := &config.Config{
+		API: config.APIConfig{Port: 0},
+		Processes: map[string]config.ProcessConfig{
+			"build": {Cmd: "echo", ProblemMatchers: []string{"go"}},
+		},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	server := httptest.NewServer(http.HandlerFunc(NewHandlers(sup, logMgr, "prox.yaml", nil).StreamProblems))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, ": connected\n", line)
+
+	logMgr.Write(domain.LogEntry{Timestamp: time.Now(), Process: "build", Line: "compiling..."})
+	logMgr.Write(domain.LogEntry{Timestamp: time.Now(), Process: "build", Line: "server.go:7:2: missing return"})
+
+	for {
+		line, err = reader.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "data: ") {
+			break
+		}
+	}
+	var problem ProblemResponse
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &problem))
+	assert.Equal(t, "server.go", problem.File)
+	assert.Equal(t, 7, problem.Line)
+	assert.Equal(t, "missing return", problem.Message)
 }
 
 func TestHealthEndpoint(t *testing.T) {
@@ -270,6 +416,187 @@ func TestHealthEndpoint(t *testing.T) {
 	assert.Equal(t, "ok", w.Body.String())
 }
 
+func TestGetLogs_Level(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	for _, level := range []string{"debug", "info", "warn", "error"} {
+		logMgr.Write(domain.LogEntry{
+			Timestamp: time.Now(),
+			Process:   "web",
+			Stream:    domain.StreamStdout,
+			Line:      `{"level":"` + level + `","msg":"m"}`,
+			Level:     level,
+			Fields:    map[string]string{"level": level, "msg": "m"},
+		})
+	}
+	logMgr.Write(domain.LogEntry{Timestamp: time.Now(), Process: "web", Stream: domain.StreamStdout, Line: "plain"})
+
+	cfg := &config.Config{
+		API:       config.APIConfig{Port: 0},
+		Processes: map[string]config.ProcessConfig{},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
+
+	req := httptest.NewRequest("GET", "/api/v1/logs?level=warning", nil)
+	w := httptest.NewRecorder()
+	handlers.GetLogs(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp LogsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Logs, 2)
+	assert.Equal(t, "warn", resp.Logs[0].Level)
+	assert.Equal(t, "error", resp.Logs[1].Level)
+	assert.Equal(t, "m", resp.Logs[1].Fields["msg"])
+
+	req = httptest.NewRequest("GET", "/api/v1/logs?level=loud", nil)
+	w = httptest.NewRecorder()
+	handlers.GetLogs(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetLogs_Stream(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+	logMgr.Write(domain.LogEntry{Timestamp: time.Now(), Process: "web", Stream: domain.StreamStdout, Line: "listening"})
+	logMgr.Write(domain.LogEntry{Timestamp: time.Now(), Process: "web", Stream: domain.StreamStderr, Line: "panic"})
+
+	cfg := &config.Config{
+		API:       config.APIConfig{Port: 0},
+		Processes: map[string]config.ProcessConfig{},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
+
+	for query, want := range map[string][]string{
+		"stream=stderr": {"panic"},
+		"stream=stdout": {"listening"},
+		"stream=both":   {"listening", "panic"},
+	} {
+		req := httptest.NewRequest("GET", "/api/v1/logs?"+query, nil)
+		w := httptest.NewRecorder()
+		handlers.GetLogs(w, req)
+		require.Equal(t, http.StatusOK, w.Code, query)
+
+		var resp LogsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		var lines []string
+		for _, entry := range resp.Logs {
+			lines = append(lines, entry.Line)
+		}
+		assert.Equal(t, want, lines, query)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/logs?stream=stdin", nil)
+	w := httptest.NewRecorder()
+	handlers.GetLogs(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetLogs_QueryBudget(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{
+		BufferSize:  100,
+		QueryBudget: logs.QueryBudget{MaxScanned: 4},
+	})
+	defer logMgr.Close()
+
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		logMgr.Write(domain.LogEntry{
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			Process:   "web",
+			Stream:    domain.StreamStdout,
+			Line:      fmt.Sprintf("line %d", i),
+		})
+	}
+
+	cfg := &config.Config{
+		API:       config.APIConfig{Port: 0},
+		Processes: map[string]config.ProcessConfig{},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
+
+	t.Run("budget exceeded returns partial results", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/logs", nil)
+		w := httptest.NewRecorder()
+
+		handlers.GetLogs(w, req)
+
+		var resp LogsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.True(t, resp.Truncated)
+		assert.Equal(t, "scan", resp.Index)
+		require.Len(t, resp.Logs, 4)
+		assert.Equal(t, "line 9", resp.Logs[3].Line)
+	})
+
+	t.Run("time range fits in budget", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/logs?process=web&since=2024-01-15T10:02:00Z&until=2024-01-15T10:04:00Z", nil)
+		w := httptest.NewRecorder()
+
+		handlers.GetLogs(w, req)
+
+		var resp LogsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.False(t, resp.Truncated)
+		assert.Equal(t, "process+time", resp.Index)
+		require.Len(t, resp.Logs, 3)
+		assert.Equal(t, "line 2", resp.Logs[0].Line)
+	})
+}
+
+func TestGetLogs_Paging(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+	for i := 0; i < 5; i++ {
+		logMgr.Write(domain.LogEntry{Timestamp: time.Now(), Process: "web", Stream: domain.StreamStdout, Line: fmt.Sprintf("line %d", i)})
+	}
+
+	cfg := &config.Config{
+		API:       config.APIConfig{Port: 0},
+		Processes: map[string]config.ProcessConfig{},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
+
+	get := func(query string) (int, LogsResponse) {
+		w := httptest.NewRecorder()
+		handlers.GetLogs(w, httptest.NewRequest("GET", "/api/v1/logs?"+query, nil))
+		var resp LogsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return w.Code, resp
+	}
+
+	code, resp := get("page_size=3")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Logs, 3)
+	assert.Equal(t, "line 0", resp.Logs[0].Line)
+	assert.Equal(t, uint64(1), resp.Logs[0].ID)
+	assert.Equal(t, "3", resp.NextCursor)
+	assert.True(t, resp.HasMore)
+
+	code, resp = get("page_size=3&cursor=" + resp.NextCursor)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Logs, 2)
+	assert.Equal(t, "line 3", resp.Logs[0].Line)
+	assert.Equal(t, "5", resp.NextCursor)
+	assert.False(t, resp.HasMore)
+
+	// Without cursor or page_size, the newest lines are returned as before
+	_, resp = get("lines=2")
+	assert.Empty(t, resp.NextCursor)
+	require.Len(t, resp.Logs, 2)
+	assert.Equal(t, "line 3", resp.Logs[0].Line)
+
+	for _, query := range []string{"cursor=abc", "cursor=-1", "page_size=0", "page_size=x"} {
+		code, _ = get(query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
 func TestGetLogs_MaxLinesLimit(t *testing.T) {
 	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
 	defer logMgr.Close()
@@ -428,6 +755,231 @@ func TestProcessControl_Conflict(t *testing.T) {
 	})
 }
 
+func TestTasks(t *testing.T) {
+	server, _, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := do("GET", "/api/v1/tasks")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list TaskListResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	require.Len(t, list.Tasks, 1)
+	assert.Equal(t, TaskResponse{Name: "migrate", Cmd: "sleep 30", Description: "Run migrations", Status: "idle"}, list.Tasks[0])
+
+	w = do("POST", "/api/v1/tasks/migrate/run")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var task TaskResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&task))
+	assert.Equal(t, "running", task.Status)
+	assert.NotEmpty(t, task.StartedAt)
+	assert.Nil(t, task.ExitCode)
+
+	w = do("GET", "/api/v1/tasks/migrate")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&task))
+	assert.Equal(t, "running", task.Status)
+
+	tests := []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{"POST", "/api/v1/tasks/migrate/run", http.StatusConflict, domain.ErrCodeTaskRunning},
+		{"POST", "/api/v1/tasks/missing/run", http.StatusNotFound, domain.ErrCodeTaskNotFound},
+		{"GET", "/api/v1/tasks/missing", http.StatusNotFound, domain.ErrCodeTaskNotFound},
+	}
+	for _, tt := range tests {
+		w := do(tt.method, tt.path)
+		assert.Equal(t, tt.status, w.Code, tt.path)
+		var errResp ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+		assert.Equal(t, tt.code, errResp.Code, tt.path)
+	}
+}
+
+func TestAddProcess(t *testing.T) {
+	server, sup, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/processes", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		server.handlers.AddProcess(w, req)
+		return w
+	}
+
+	w := post(`{"name": "tunnel", "cmd": "sleep 30", "env": {"MODE": "test"}}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp ProcessDetailResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "tunnel", resp.Name)
+	assert.Equal(t, "running", resp.Status)
+	assert.Equal(t, "sleep 30", resp.Cmd)
+
+	info, err := sup.Process("tunnel")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+
+	tests := []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`{"name": "tunnel", "cmd": "sleep 30"}`, http.StatusConflict, domain.ErrCodeProcessExists},
+		{`{"name": "test", "cmd": "sleep 30"}`, http.StatusConflict, domain.ErrCodeProcessExists},
+		{`{"name": "bad name", "cmd": "sleep 30"}`, http.StatusBadRequest, domain.ErrCodeInvalidConfig},
+		{`{"name": "nocmd"}`, http.StatusBadRequest, domain.ErrCodeInvalidRequest},
+		{`not json`, http.StatusBadRequest, domain.ErrCodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		w := post(tt.body)
+		assert.Equal(t, tt.status, w.Code, tt.body)
+		var errResp ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+		assert.Equal(t, tt.code, errResp.Code, tt.body)
+	}
+}
+
+func TestAddProcess_CrossOrigin(t *testing.T) {
+	server, sup, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	// A web page can send this without a preflight
+	post := func(name, origin, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/processes", strings.NewReader(`{"name": "`+name+`", "cmd": "sleep 30"}`))
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name, origin, contentType string
+		status                    int
+		code                      string
+	}{
+		{"evil", "https://evil.example", "text/plain", http.StatusForbidden, domain.ErrCodeForbiddenOrigin},
+		{"evil", "https://evil.example", "application/json", http.StatusForbidden, domain.ErrCodeForbiddenOrigin},
+		{"plain", "", "text/plain;charset=UTF-8", http.StatusUnsupportedMediaType, domain.ErrCodeUnsupportedMediaType},
+		{"form", "http://localhost:3000", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType, domain.ErrCodeUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		w := post(tt.name, tt.origin, tt.contentType)
+		assert.Equal(t, tt.status, w.Code, tt.name)
+		var errResp ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+		assert.Equal(t, tt.code, errResp.Code, tt.name)
+
+		_, err := sup.Process(tt.name)
+		assert.ErrorIs(t, err, domain.ErrProcessNotFound, "%s was started", tt.name)
+	}
+
+	// Local pages and clients sending JSON are let through
+	w := post("local", "http://localhost:3000", "application/json")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Bodyless requests need no Content-Type, but still a local origin
+	req := httptest.NewRequest("POST", "/api/v1/processes/local/stop", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	req = httptest.NewRequest("POST", "/api/v1/processes/local/stop", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestUpdateProcess(t *testing.T) {
+	server, sup, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	patch := func(name, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/api/v1/processes/"+name, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := patch("test", `{"env": {"MODE": "debug"}, "healthcheck": {"cmd": "true", "start_period": "5s"}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ProcessDetailResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.True(t, resp.PendingRestart)
+	assert.Empty(t, resp.Env["MODE"])
+
+	w = patch("test", `{"cmd": "sleep 31", "healthcheck": null, "restart": true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = ProcessDetailResponse{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.False(t, resp.PendingRestart)
+	assert.Equal(t, "sleep 31", resp.Cmd)
+	assert.Equal(t, "debug", resp.Env["MODE"])
+	assert.Equal(t, "running", resp.Status)
+
+	info, err := sup.Process("test")
+	require.NoError(t, err)
+	assert.Nil(t, info.HealthDetails)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"missing", `{"cmd": "sleep 30"}`, http.StatusNotFound, domain.ErrCodeProcessNotFound},
+		{"test", `{"cmd": ""}`, http.StatusBadRequest, domain.ErrCodeInvalidRequest},
+		{"test", `{"healthcheck": {"command": "true"}}`, http.StatusBadRequest, domain.ErrCodeInvalidRequest},
+		{"test", `{"healthcheck": {"interval": "5s"}}`, http.StatusBadRequest, domain.ErrCodeInvalidConfig},
+		{"test", `not json`, http.StatusBadRequest, domain.ErrCodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		w := patch(tt.name, tt.body)
+		assert.Equal(t, tt.status, w.Code, tt.body)
+		var errResp ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+		assert.Equal(t, tt.code, errResp.Code, tt.body)
+	}
+}
+
+func TestTriggerDump(t *testing.T) {
+	server, _, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	dump := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/debug/dump", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Unavailable until the daemon sets a dump function
+	assert.Equal(t, http.StatusInternalServerError, dump().Code)
+
+	var gotReason string
+	server.handlers.SetDumpFunc(func(reason string) (string, error) {
+		gotReason = reason
+		return "/tmp/.prox/postmortem-1.txt", nil
+	})
+	w := dump()
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp DumpResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "/tmp/.prox/postmortem-1.txt", resp.Path)
+	assert.Equal(t, "requested via API", gotReason)
+}
+
 func TestGetProxyRequests(t *testing.T) {
 	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
 	defer logMgr.Close()
@@ -573,7 +1125,7 @@ func TestGetProxyRequests(t *testing.T) {
 	})
 }
 
-func TestGetProxyRequests_ProxyNotEnabled(t *testing.T) {
+func TestProxySession_SaveLoad(t *testing.T) {
 	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
 	defer logMgr.Close()
 
@@ -582,23 +1134,52 @@ func TestGetProxyRequests_ProxyNotEnabled(t *testing.T) {
 		Processes: map[string]config.ProcessConfig{},
 	}
 	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
-	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
-	// Don't set request manager to simulate proxy not enabled
 
-	req := httptest.NewRequest("GET", "/api/v1/proxy/requests", nil)
+	source := NewHandlers(sup, logMgr, "prox.yaml", nil)
+	rm := proxy.NewRequestManager(100)
+	source.SetRequestManager(rm)
+	rm.Record(proxy.RequestRecord{
+		ID:         "abc1234",
+		Timestamp:  time.Now(),
+		Method:     "GET",
+		URL:        "/api/users",
+		Subdomain:  "api",
+		StatusCode: 502,
+	})
+
+	req := httptest.NewRequest("GET", "/api/v1/proxy/session", nil)
 	w := httptest.NewRecorder()
+	NewServer(ServerConfig{Host: "127.0.0.1", Port: 0}, source).router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
 
-	handlers.GetProxyRequests(w, req)
+	target := NewHandlers(sup, logMgr, "prox.yaml", nil)
+	target.SetRequestManager(proxy.NewRequestManager(100))
+	server := NewServer(ServerConfig{Host: "127.0.0.1", Port: 0}, target)
 
-	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	req = httptest.NewRequest("POST", "/api/v1/proxy/session", w.Body)
+	req.Header.Set("Content-Type", "application/gzip")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
 
-	var resp ErrorResponse
-	err := json.NewDecoder(w.Body).Decode(&resp)
-	require.NoError(t, err)
-	assert.Equal(t, domain.ErrCodeProxyNotEnabled, resp.Code)
+	var loaded SessionLoadResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&loaded))
+	assert.Equal(t, 1, loaded.Imported)
+	assert.Equal(t, 0, loaded.Skipped)
+
+	req = httptest.NewRequest("GET", "/api/v1/proxy/requests/abc1234", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var detail ProxyRequestDetailResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&detail))
+	assert.Equal(t, 502, detail.StatusCode)
+	assert.True(t, detail.Imported)
 }
 
-func TestStreamProxyRequests(t *testing.T) {
+func TestExportProxyRequests(t *testing.T) {
 	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
 	defer logMgr.Close()
 
@@ -607,16 +1188,149 @@ func TestStreamProxyRequests(t *testing.T) {
 		Processes: map[string]config.ProcessConfig{},
 	}
 	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
-	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
 
-	rm := proxy.NewRequestManager(100)
+	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
+	rm := proxy.NewRequestManager(200)
 	handlers.SetRequestManager(rm)
+	for i := 0; i < constants.DefaultProxyRequestLimit+1; i++ {
+		rm.Record(proxy.RequestRecord{
+			Timestamp:  time.Now(),
+			Method:     "GET",
+			URL:        fmt.Sprintf("/api/users/%d", i),
+			Subdomain:  "api",
+			StatusCode: 200,
+		})
+	}
+	server := NewServer(ServerConfig{Host: "127.0.0.1", Port: 0}, handlers)
 
-	t.Run("SSE headers set correctly", func(t *testing.T) {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
+	req := httptest.NewRequest("GET", "/api/v1/proxy/requests/export?format=har", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "prox.har")
 
-		req := httptest.NewRequest("GET", "/api/v1/proxy/requests/stream", nil)
+	// The whole history is exported unless limited
+	var har proxy.HAR
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&har))
+	require.Len(t, har.Log.Entries, constants.DefaultProxyRequestLimit+1)
+	assert.Equal(t, "http://api/api/users/0", har.Log.Entries[0].Request.URL)
+
+	req = httptest.NewRequest("GET", "/api/v1/proxy/requests/export?format=csv", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+	assert.Equal(t, domain.ErrCodeInvalidRequest, errResp.Code)
+}
+
+func TestProxySession_LoadInvalid(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	sup := supervisor.New(&config.Config{}, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
+	handlers.SetRequestManager(proxy.NewRequestManager(100))
+
+	req := httptest.NewRequest("POST", "/api/v1/proxy/session", strings.NewReader("not a session"))
+	req.Header.Set("Content-Type", "application/gzip")
+	w := httptest.NewRecorder()
+	NewServer(ServerConfig{Host: "127.0.0.1", Port: 0}, handlers).router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, domain.ErrCodeInvalidSession, resp.Code)
+}
+
+func TestGetProxyRequests_ProxyNotEnabled(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := &config.Config{
+		API:       config.APIConfig{Port: 0},
+		Processes: map[string]config.ProcessConfig{},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
+	// Don't set request manager to simulate proxy not enabled
+
+	req := httptest.NewRequest("GET", "/api/v1/proxy/requests", nil)
+	w := httptest.NewRecorder()
+
+	handlers.GetProxyRequests(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var resp ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErrCodeProxyNotEnabled, resp.Code)
+}
+
+func TestGetProxyRequests_Paging(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := &config.Config{
+		API:       config.APIConfig{Port: 0},
+		Processes: map[string]config.ProcessConfig{},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
+	rm := proxy.NewRequestManager(100)
+	handlers.SetRequestManager(rm)
+	for i := 0; i < 3; i++ {
+		rm.Record(proxy.RequestRecord{Timestamp: time.Now(), Method: "GET", URL: fmt.Sprintf("/%d", i), Subdomain: "app", StatusCode: 200})
+	}
+
+	get := func(query string) (int, ProxyRequestsResponse) {
+		w := httptest.NewRecorder()
+		handlers.GetProxyRequests(w, httptest.NewRequest("GET", "/api/v1/proxy/requests?"+query, nil))
+		var resp ProxyRequestsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return w.Code, resp
+	}
+
+	code, resp := get("page_size=2")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Requests, 2)
+	assert.Equal(t, "/0", resp.Requests[0].URL)
+	assert.Equal(t, uint64(1), resp.Requests[0].Seq)
+	assert.True(t, resp.HasMore)
+
+	// A request recorded between pages isn't missed
+	rm.Record(proxy.RequestRecord{Timestamp: time.Now(), Method: "GET", URL: "/3", Subdomain: "app", StatusCode: 200})
+	_, resp = get("page_size=2&cursor=" + resp.NextCursor)
+	require.Len(t, resp.Requests, 2)
+	assert.Equal(t, "/2", resp.Requests[0].URL)
+	assert.Equal(t, "/3", resp.Requests[1].URL)
+	assert.False(t, resp.HasMore)
+	assert.Equal(t, "4", resp.NextCursor)
+
+	code, _ = get("cursor=next")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestStreamProxyRequests(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := &config.Config{
+		API:       config.APIConfig{Port: 0},
+		Processes: map[string]config.ProcessConfig{},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
+
+	rm := proxy.NewRequestManager(100)
+	handlers.SetRequestManager(rm)
+
+	t.Run("SSE headers set correctly", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req := httptest.NewRequest("GET", "/api/v1/proxy/requests/stream", nil)
 		req = req.WithContext(ctx)
 		w := httptest.NewRecorder()
 
@@ -763,3 +1477,485 @@ func TestStreamProxyRequests_ProxyNotEnabled(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, domain.ErrCodeProxyNotEnabled, resp.Code)
 }
+
+func TestGetProxyStats(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := &config.Config{
+		API:       config.APIConfig{Port: 0},
+		Processes: map[string]config.ProcessConfig{},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
+	server := NewServer(ServerConfig{Host: "127.0.0.1", Port: 0}, handlers)
+
+	rm := proxy.NewRequestManager(100)
+	tracker := proxy.NewStatsTracker(rm, map[string]time.Duration{
+		"api": 100 * time.Millisecond,
+	})
+	handlers.SetStatsTracker(tracker)
+
+	now := time.Now()
+	tracker.RecordProcessEvent([]string{"app"}, proxy.ProcessEvent{Process: "web", Type: proxy.ProcessEventCrashed, Time: now.Add(-time.Second)})
+	rm.Record(proxy.RequestRecord{Timestamp: now, Method: "GET", URL: "/a", Subdomain: "api", StatusCode: 200, Duration: 250 * time.Millisecond, SchemaViolations: []string{"$.id: expected integer, got string"}})
+	rm.Record(proxy.RequestRecord{Timestamp: now, Method: "GET", URL: "/b", Subdomain: "app", StatusCode: 500, Duration: 20 * time.Millisecond})
+
+	req := httptest.NewRequest("GET", "/api/v1/proxy/stats", nil)
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp ProxyStatsResponse
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(300), resp.WindowSeconds)
+	require.Len(t, resp.Services, 2)
+
+	assert.Equal(t, "api", resp.Services[0].Subdomain)
+	assert.Equal(t, int64(250), resp.Services[0].P95Ms)
+	assert.Equal(t, int64(100), resp.Services[0].BudgetMs)
+	assert.True(t, resp.Services[0].OverBudget)
+	assert.Equal(t, 1, resp.Services[0].SchemaViolations)
+
+	assert.Equal(t, "app", resp.Services[1].Subdomain)
+	assert.Equal(t, 1, resp.Services[1].Errors)
+	assert.Equal(t, int64(0), resp.Services[1].BudgetMs)
+	assert.False(t, resp.Services[1].OverBudget)
+
+	assert.Empty(t, resp.Services[0].Events)
+	require.Len(t, resp.Services[1].Events, 1)
+	assert.Equal(t, "web", resp.Services[1].Events[0].Process)
+	assert.Equal(t, "crashed", resp.Services[1].Events[0].Type)
+	assert.Equal(t, 1, resp.Services[1].Events[0].ErrorsAfter)
+}
+
+func TestGetProxyStats_ProxyNotEnabled(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := &config.Config{
+		API:       config.APIConfig{Port: 0},
+		Processes: map[string]config.ProcessConfig{},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "prox.yaml", nil)
+
+	req := httptest.NewRequest("GET", "/api/v1/proxy/stats", nil)
+	w := httptest.NewRecorder()
+
+	handlers.GetProxyStats(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var resp ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErrCodeProxyNotEnabled, resp.Code)
+}
+
+func TestDrainProcess_ProxyNotEnabled(t *testing.T) {
+	server, _, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	req := httptest.NewRequest("POST", "/api/v1/processes/test/drain", nil)
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var resp ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErrCodeProxyNotEnabled, resp.Code)
+}
+
+func TestDrainProcess_NoProxyServices(t *testing.T) {
+	server, sup, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	proxySvc, err := proxy.NewService(&config.ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.dev"},
+		map[string]config.ServiceConfig{"app": {Port: 3000, Host: "localhost"}}, nil, slog.Default(), t.TempDir())
+	require.NoError(t, err)
+	server.handlers.SetProxyService(proxySvc)
+
+	req := httptest.NewRequest("POST", "/api/v1/processes/test/drain", nil)
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp ErrorResponse
+	err = json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErrCodeNoProxyServices, resp.Code)
+
+	// Process is left running
+	info, err := sup.Process("test")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+}
+
+func TestDrainProcess_StopsProcess(t *testing.T) {
+	server, sup, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	proxySvc, err := proxy.NewService(&config.ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.dev"},
+		map[string]config.ServiceConfig{"app": {Port: 3000, Host: "localhost", Process: "test"}}, nil, slog.Default(), t.TempDir())
+	require.NoError(t, err)
+	server.handlers.SetProxyService(proxySvc)
+
+	req := httptest.NewRequest("POST", "/api/v1/processes/test/drain", nil)
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, proxySvc.IsDraining("app"))
+
+	info, err := sup.Process("test")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateStopped, info.State)
+}
+
+func TestRestartProcess_BlueGreenPortNotAuto(t *testing.T) {
+	server, sup, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	before, err := sup.Process("test")
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/api/v1/processes/test/restart?strategy=blue-green", nil)
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp ErrorResponse
+	err = json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErrCodePortNotAuto, resp.Code)
+
+	// Process is left untouched
+	after, err := sup.Process("test")
+	require.NoError(t, err)
+	assert.Equal(t, before.PID, after.PID)
+}
+
+func TestReload(t *testing.T) {
+	server, sup, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	path := filepath.Join(t.TempDir(), "prox.yaml")
+	server.handlers.configFile = path
+	require.NoError(t, os.WriteFile(path, []byte("processes:\n  test: sleep 30\n  worker: sleep 30\n"), 0600))
+
+	req := httptest.NewRequest("POST", "/api/v1/reload", nil)
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp ReloadResponse
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"worker"}, resp.Added)
+	assert.Empty(t, resp.Removed)
+	assert.Empty(t, resp.Changed)
+
+	info, err := sup.Process("worker")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+}
+
+func TestReload_InvalidConfig(t *testing.T) {
+	server, sup, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	path := filepath.Join(t.TempDir(), "prox.yaml")
+	server.handlers.configFile = path
+	require.NoError(t, os.WriteFile(path, []byte("processes:\n  test: [\n"), 0600))
+
+	req := httptest.NewRequest("POST", "/api/v1/reload", nil)
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErrCodeInvalidConfig, resp.Code)
+
+	// Running processes are left alone
+	info, err := sup.Process("test")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+}
+
+func TestGetProxyRules_ProxyNotEnabled(t *testing.T) {
+	server, _, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	req := httptest.NewRequest("GET", "/api/v1/proxy/rules", nil)
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var resp ErrorResponse
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErrCodeProxyNotEnabled, resp.Code)
+}
+
+func TestProxyRules(t *testing.T) {
+	server, _, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	proxySvc, err := proxy.NewService(&config.ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.dev"},
+		map[string]config.ServiceConfig{"app": {Port: 3000, Host: "localhost", Rewrite: &config.RewriteConfig{
+			RequestHeaders: &config.HeaderRewrite{Remove: []string{"Cookie"}},
+		}}}, nil, slog.Default(), t.TempDir())
+	require.NoError(t, err)
+	server.handlers.SetProxyService(proxySvc)
+
+	t.Run("list rules", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/proxy/rules", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp ProxyRulesResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		require.Len(t, resp.Rules, 1)
+		assert.Equal(t, "rewrite:app", resp.Rules[0].ID)
+		assert.Equal(t, "rewrite", resp.Rules[0].Kind)
+		assert.Equal(t, "app", resp.Rules[0].Service)
+		assert.Equal(t, "request: remove Cookie", resp.Rules[0].Description)
+		assert.True(t, resp.Rules[0].Enabled)
+	})
+
+	t.Run("disable rule", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/proxy/rules/rewrite:app/disable", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp ProxyRuleResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.False(t, resp.Enabled)
+		assert.False(t, proxySvc.Rules()[0].Enabled)
+	})
+
+	t.Run("enable rule", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/proxy/rules/rewrite:app/enable", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp ProxyRuleResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.True(t, resp.Enabled)
+		assert.True(t, proxySvc.Rules()[0].Enabled)
+	})
+
+	t.Run("unknown rule", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/proxy/rules/rewrite:missing/enable", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		var resp ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, domain.ErrCodeRuleNotFound, resp.Code)
+	})
+}
+
+func TestProxyRecording(t *testing.T) {
+	server, _, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	proxySvc, err := proxy.NewService(&config.ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.dev"},
+		map[string]config.ServiceConfig{"app": {Port: 3000, Host: "localhost"}}, nil, slog.Default(), t.TempDir())
+	require.NoError(t, err)
+	server.handlers.SetProxyService(proxySvc)
+
+	setRecording := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/proxy/recording", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := setRecording(`{"enabled": false}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp ProxyRecordingResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.False(t, resp.Enabled)
+	assert.False(t, proxySvc.Recording())
+
+	req := httptest.NewRequest("GET", "/api/v1/proxy/recording", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.False(t, resp.Enabled)
+
+	w = setRecording(`{"enabled": true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, proxySvc.Recording())
+
+	// The enabled field is required
+	w = setRecording(`{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+	assert.Equal(t, domain.ErrCodeInvalidRequest, errResp.Code)
+}
+
+func TestAttachProcess(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+	cfg := &config.Config{
+		API: config.APIConfig{Port: 0, Host: "127.0.0.1"},
+		Processes: map[string]config.ProcessConfig{
+			"repl":  {Cmd: "cat", Stdin: true},
+			"other": {Cmd: "sleep 30"},
+		},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	_, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	defer sup.Stop(context.Background())
+
+	server := NewServer(ServerConfig{Host: "127.0.0.1", Port: 0}, NewHandlers(sup, logMgr, "prox.yaml", nil))
+	ts := httptest.NewServer(server.router)
+	defer ts.Close()
+
+	conn, _, err := websocket.Dial(ts.Client(), ts.URL+"/api/v1/processes/repl/attach", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteMessage(websocket.OpBinary, []byte("hello\n")))
+	var output []byte
+	for !bytes.Contains(output, []byte("hello\n")) {
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		output = append(output, data...)
+	}
+
+	// The output is logged too
+	require.Eventually(t, func() bool {
+		entries, _, _ := logMgr.Query(domain.LogFilter{Processes: []string{"repl"}}, 0)
+		return len(entries) == 1 && entries[0].Line == "hello"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The connection closes when the process exits
+	require.NoError(t, sup.StopProcess(context.Background(), "repl"))
+	for err == nil {
+		_, _, err = conn.ReadMessage()
+	}
+	assert.Equal(t, io.EOF, err)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		code   string
+	}{
+		{name: "stdin disabled", path: "/api/v1/processes/other/attach", status: http.StatusBadRequest, code: domain.ErrCodeStdinDisabled},
+		{name: "not running", path: "/api/v1/processes/repl/attach", status: http.StatusConflict, code: domain.ErrCodeProcessNotRunning},
+		{name: "unknown process", path: "/api/v1/processes/missing/attach", status: http.StatusNotFound, code: domain.ErrCodeProcessNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp, err := websocket.Dial(ts.Client(), ts.URL+tt.path, nil)
+			require.ErrorIs(t, err, websocket.ErrBadHandshake)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode)
+			var errResp ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+			assert.Equal(t, tt.code, errResp.Code)
+		})
+	}
+
+	// Pages on other sites can't attach from the browser
+	require.NoError(t, sup.StartProcess(context.Background(), "repl"))
+	_, resp, err := websocket.Dial(ts.Client(), ts.URL+"/api/v1/processes/repl/attach", http.Header{"Origin": {"https://evil.example"}})
+	require.ErrorIs(t, err, websocket.ErrBadHandshake)
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, domain.ErrCodeForbiddenOrigin, errResp.Code)
+
+	conn, _, err = websocket.Dial(ts.Client(), ts.URL+"/api/v1/processes/repl/attach", http.Header{"Origin": {"http://localhost:3000"}})
+	require.NoError(t, err)
+	conn.Close()
+
+	// Not a WebSocket request
+	resp, err = ts.Client().Get(ts.URL + "/api/v1/processes/repl/attach")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestSignalProcess(t *testing.T) {
+	server, sup, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	before, err := sup.Process("test")
+	require.NoError(t, err)
+
+	// sleep keeps running after SIGCONT
+	req := httptest.NewRequest("POST", "/api/v1/processes/test/signal", strings.NewReader(`{"signal": "SIGCONT"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	after, err := sup.Process("test")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, after.State)
+	assert.Equal(t, before.PID, after.PID)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{name: "missing body", path: "/api/v1/processes/test/signal", body: `{}`, status: http.StatusBadRequest, code: domain.ErrCodeInvalidRequest},
+		{name: "unknown signal", path: "/api/v1/processes/test/signal", body: `{"signal": "SIGNOPE"}`, status: http.StatusBadRequest, code: domain.ErrCodeInvalidSignal},
+		{name: "unknown process", path: "/api/v1/processes/missing/signal", body: `{"signal": "SIGHUP"}`, status: http.StatusNotFound, code: domain.ErrCodeProcessNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			var resp ErrorResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			assert.Equal(t, tt.code, resp.Code)
+		})
+	}
+}
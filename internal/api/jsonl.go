@@ -0,0 +1,63 @@
+package api
+
+// Types of JSON Lines records, in the type field of every record so one
+// consumer can read several prox streams
+const (
+	JSONLTypeLog     = "log"
+	JSONLTypeRequest = "request"
+	JSONLTypeProblem = "problem"
+	JSONLTypeEvent   = "event"
+)
+
+// JSON Lines records are written one per line by prox logs, requests,
+// problems, and events with --output jsonl, for jq and log shippers. They
+// are the API's responses with a type field in front. Fields are only ever
+// added to them, never renamed or removed.
+
+// JSONLLog is a log entry as a JSON Lines record
+type JSONLLog struct {
+	Type string `json:"type"` // JSONLTypeLog
+	LogEntryResponse
+}
+
+// NewJSONLLog returns the record of a log entry
+func NewJSONLLog(entry LogEntryResponse) JSONLLog {
+	return JSONLLog{Type: JSONLTypeLog, LogEntryResponse: entry}
+}
+
+// JSONLRequest is a proxy request as a JSON Lines record. The request's own
+// type, which type would hide, is in request_type.
+type JSONLRequest struct {
+	Type        string `json:"type"`                   // JSONLTypeRequest
+	RequestType string `json:"request_type,omitempty"` // "websocket" for WebSocket upgrade requests
+	ProxyRequestResponse
+}
+
+// NewJSONLRequest returns the record of a proxy request
+func NewJSONLRequest(req ProxyRequestResponse) JSONLRequest {
+	return JSONLRequest{Type: JSONLTypeRequest, RequestType: req.Type, ProxyRequestResponse: req}
+}
+
+// JSONLProblem is a problem found in the logs as a JSON Lines record
+type JSONLProblem struct {
+	Type string `json:"type"` // JSONLTypeProblem
+	ProblemResponse
+}
+
+// NewJSONLProblem returns the record of a problem
+func NewJSONLProblem(problem ProblemResponse) JSONLProblem {
+	return JSONLProblem{Type: JSONLTypeProblem, ProblemResponse: problem}
+}
+
+// JSONLEvent is a supervisor event as a JSON Lines record. The event's own
+// type, which type would hide, is in event.
+type JSONLEvent struct {
+	Type  string `json:"type"`  // JSONLTypeEvent
+	Event string `json:"event"` // e.g. "process_crashed"
+	EventResponse
+}
+
+// NewJSONLEvent returns the record of a supervisor event
+func NewJSONLEvent(event EventResponse) JSONLEvent {
+	return JSONLEvent{Type: JSONLTypeEvent, Event: event.Type, EventResponse: event}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestJSONLRecords pins the JSON Lines schemas, which consumers parse: a
+// failure here means a field was renamed or removed
+func TestJSONLRecords(t *testing.T) {
+	tests := []struct {
+		name   string
+		record interface{}
+		want   string
+	}{
+		{
+			name: "log",
+			record: NewJSONLLog(LogEntryResponse{
+				ID: 7, Timestamp: "2024-01-15T10:30:00Z", Process: "web", Stream: "stdout", Line: "listening",
+				Level: "info", Fields: map[string]string{"port": "3000"},
+			}),
+			want: `{"type":"log","id":7,"timestamp":"2024-01-15T10:30:00Z","process":"web","stream":"stdout","line":"listening","level":"info","fields":{"port":"3000"}}`,
+		},
+		{
+			name: "websocket request",
+			record: NewJSONLRequest(ProxyRequestResponse{
+				ID: "abc1234", Timestamp: "2024-01-15T10:30:00Z", Method: "GET", URL: "/ws", Subdomain: "app",
+				StatusCode: 101, DurationMs: 1500, DurationHuman: "1.5s", RemoteAddr: "127.0.0.1", Type: "websocket",
+			}),
+			want: `{"type":"request","request_type":"websocket","id":"abc1234","timestamp":"2024-01-15T10:30:00Z","method":"GET","url":"/ws","subdomain":"app","status_code":101,"duration_ms":1500,"duration_human":"1.5s","remote_addr":"127.0.0.1","response_bytes":0}`,
+		},
+		{
+			name: "problem",
+			record: NewJSONLProblem(ProblemResponse{
+				Timestamp: "2024-01-15T10:30:00Z", Process: "build", File: "main.go", Line: 12, Column: 3, Severity: "error", Message: "undefined: x",
+			}),
+			want: `{"type":"problem","timestamp":"2024-01-15T10:30:00Z","process":"build","file":"main.go","line":12,"column":3,"severity":"error","message":"undefined: x"}`,
+		},
+		{
+			name:   "event",
+			record: NewJSONLEvent(EventResponse{Type: "process_crashed", Process: "web", Timestamp: "2024-01-15T10:30:00Z"}),
+			want:   `{"type":"event","event":"process_crashed","process":"web","timestamp":"2024-01-15T10:30:00Z"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.record)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/metrics"
+)
+
+// processStates are the states reported by the prox_process_state gauge
+var processStates = []domain.ProcessState{
+	domain.ProcessStateStarting,
+	domain.ProcessStateRunning,
+	domain.ProcessStateStopping,
+	domain.ProcessStateStopped,
+	domain.ProcessStateCrashed,
+	domain.ProcessStateFailed,
+}
+
+// GetMetrics handles GET /metrics
+// It reports process, proxy, and log metrics in the Prometheus text format.
+func (h *Handlers) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+
+	processes := h.supervisor.Processes()
+	var states, restarts []metrics.Sample
+	for _, p := range processes {
+		for _, state := range processStates {
+			value := 0.0
+			if p.State == state {
+				value = 1
+			}
+			states = append(states, metrics.Sample{Labels: []string{p.Name, string(state)}, Value: value})
+		}
+		restarts = append(restarts, metrics.Sample{Labels: []string{p.Name}, Value: float64(p.RestartCount)})
+	}
+	metrics.WriteMetric(&buf, "prox_process_state", "Whether a process is in a state (1) or not (0).",
+		"gauge", []string{"process", "state"}, states)
+	metrics.WriteMetric(&buf, "prox_process_restarts_total", "Times a process has been restarted.",
+		"counter", []string{"process"}, restarts)
+
+	h.logManager.WriteMetrics(&buf)
+	if h.proxyService != nil {
+		h.proxyService.WriteMetrics(&buf)
+	}
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/idle"
+)
+
+func TestGetMetrics(t *testing.T) {
+	server, _, logMgr, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	logMgr.Write(domain.LogEntry{Timestamp: time.Now(), Process: "test", Stream: domain.StreamStdout, Line: "hello"})
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	body := w.Body.String()
+	assert.Contains(t, body, `prox_process_state{process="test",state="running"} 1`)
+	assert.Contains(t, body, `prox_process_state{process="test",state="crashed"} 0`)
+	assert.Contains(t, body, `prox_process_restarts_total{process="test"} 0`)
+	assert.Contains(t, body, `prox_log_lines_total{process="test",stream="stdout"} 1`)
+	assert.Contains(t, body, `prox_log_bytes_total{process="test",stream="stdout"} 5`)
+	assert.NotContains(t, body, "prox_proxy_", "proxy metrics need the proxy")
+}
+
+func TestGetMetrics_Auth(t *testing.T) {
+	_, sup, logMgr, cleanup := setupTestServer(t)
+	defer cleanup()
+	server := NewServer(ServerConfig{AuthEnabled: true, Token: "secret"}, NewHandlers(sup, logMgr, "prox.yaml", nil))
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestGetMetrics_NotActivity(t *testing.T) {
+	_, sup, logMgr, cleanup := setupTestServer(t)
+	defer cleanup()
+	tracker := idle.NewTracker()
+	server := NewServer(ServerConfig{Idle: tracker}, NewHandlers(sup, logMgr, "prox.yaml", nil))
+
+	time.Sleep(20 * time.Millisecond)
+	server.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/metrics", nil))
+	assert.GreaterOrEqual(t, tracker.IdleFor(), 20*time.Millisecond, "scrapes should not keep the daemon awake")
+}
@@ -1,11 +1,19 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
 
+	"github.com/charliek/prox/internal/config"
 	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/charliek/prox/internal/logs"
 	"github.com/charliek/prox/internal/proxy"
+	"github.com/charliek/prox/internal/supervisor"
+	"github.com/charliek/prox/internal/watchdog"
 )
 
 // sensitiveEnvPatterns contains patterns that indicate sensitive environment variables
@@ -23,12 +31,115 @@ var sensitiveEnvPatterns = []string{
 	"ACCESSKEY",
 }
 
-// StatusResponse represents the response for GET /status
+// StatusResponse represents the API v1 response for GET /status
 type StatusResponse struct {
-	Status        string `json:"status"`
-	UptimeSeconds int64  `json:"uptime_seconds"`
-	ConfigFile    string `json:"config_file,omitempty"`
-	APIVersion    string `json:"api_version"`
+	Status        string                `json:"status"`
+	UptimeSeconds int64                 `json:"uptime_seconds"`
+	UptimeHuman   string                `json:"uptime_human"` // e.g. "3m12s"
+	ConfigFile    string                `json:"config_file,omitempty"`
+	APIVersion    string                `json:"api_version"`
+	Asleep        bool                  `json:"asleep,omitempty"` // Processes stopped by idle sleep
+	Errors        []StartErrorResponse  `json:"errors,omitempty"` // Processes whose last start failed
+	Logs          *LogsStatusResponse   `json:"logs,omitempty"`
+	Detail        *StatusDetailResponse `json:"detail,omitempty"`
+}
+
+// LogsStatusResponse reports how much of the log history is in use
+type LogsStatusResponse struct {
+	Entries        int   `json:"entries"`
+	BufferSize     int   `json:"buffer_size"`                // Most entries kept for the processes seen so far
+	MemoryBytes    int64 `json:"memory_bytes"`               // Approximate memory the entries use
+	MaxMemoryBytes int64 `json:"max_memory_bytes,omitempty"` // logs.max_memory, when set
+	Subscribers    int   `json:"subscribers"`
+}
+
+// ToLogsStatusResponse converts log manager stats to a response
+func ToLogsStatusResponse(stats domain.LogStats) *LogsStatusResponse {
+	return &LogsStatusResponse{
+		Entries:        stats.TotalEntries,
+		BufferSize:     stats.BufferSize,
+		MemoryBytes:    stats.MemoryBytes,
+		MaxMemoryBytes: stats.MaxMemory,
+		Subscribers:    stats.Subscribers,
+	}
+}
+
+// StartErrorResponse describes why a process last failed to start
+type StartErrorResponse struct {
+	Process string `json:"process"`
+	Error   string `json:"error"`
+	Time    string `json:"time"`
+}
+
+// statusSnapshot holds everything GET /status reports, gathered once by the
+// handler and shaped by the mapper for the requested version
+type statusSnapshot struct {
+	Supervisor supervisor.SupervisorStatus
+	ConfigFile string
+	Asleep     bool
+	Processes  []domain.ProcessInfo
+	Failures   []domain.StartFailure
+	Logs       *LogsStatusResponse
+	Detail     *StatusDetailResponse
+}
+
+// toStatusResponse maps a status snapshot to the v1 response
+func toStatusResponse(snap statusSnapshot) StatusResponse {
+	return StatusResponse{
+		Status:        snap.Supervisor.State,
+		UptimeSeconds: snap.Supervisor.UptimeSeconds(),
+		UptimeHuman:   humanSeconds(snap.Supervisor.UptimeSeconds()),
+		ConfigFile:    snap.ConfigFile,
+		APIVersion:    string(V1),
+		Asleep:        snap.Asleep,
+		Errors:        ToStartErrorResponses(snap.Failures),
+		Logs:          snap.Logs,
+		Detail:        snap.Detail,
+	}
+}
+
+// StatusDetailResponse holds runtime diagnostics for GET /status?detail=true
+type StatusDetailResponse struct {
+	Goroutines int              `json:"goroutines"`
+	Watchdog   WatchdogResponse `json:"watchdog"`
+}
+
+// WatchdogResponse reports tracked stream goroutines by kind
+// (sse_subscriber, output_reader, health_checker)
+type WatchdogResponse struct {
+	Active map[string]int `json:"active"`
+	Leaked map[string]int `json:"leaked"` // Force-cleaned since start
+}
+
+// ToWatchdogResponse converts watchdog stats to a response
+func ToWatchdogResponse(stats watchdog.Stats) WatchdogResponse {
+	resp := WatchdogResponse{
+		Active: make(map[string]int, len(stats.Active)),
+		Leaked: make(map[string]int, len(stats.Leaked)),
+	}
+	for kind, n := range stats.Active {
+		resp.Active[string(kind)] = n
+	}
+	for kind, n := range stats.Leaked {
+		resp.Leaked[string(kind)] = n
+	}
+	return resp
+}
+
+// ToStartErrorResponses converts start failures to responses
+func ToStartErrorResponses(failures []domain.StartFailure) []StartErrorResponse {
+	if len(failures) == 0 {
+		return nil
+	}
+	resp := make([]StartErrorResponse, len(failures))
+	for i, f := range failures {
+		resp[i] = StartErrorResponse{
+			Process: f.Process,
+			Error:   f.Error,
+			Time:    f.Time.Format(time.RFC3339),
+		}
+	}
+	return resp
 }
 
 // ProcessListResponse represents the response for GET /processes
@@ -41,22 +152,119 @@ type ProcessResponse struct {
 	Name          string `json:"name"`
 	Status        string `json:"status"`
 	PID           int    `json:"pid"`
+	Port          int    `json:"port,omitempty"`
 	UptimeSeconds int64  `json:"uptime_seconds"`
+	UptimeHuman   string `json:"uptime_human"`
 	Restarts      int    `json:"restarts"`
+	RestartReason string `json:"restart_reason,omitempty"`
 	Health        string `json:"health"`
+	Lazy          bool   `json:"lazy,omitempty"`
+	LastError     string `json:"last_error,omitempty"`
 }
 
 // ProcessDetailResponse represents the response for GET /processes/{name}
 type ProcessDetailResponse struct {
-	Name          string            `json:"name"`
-	Status        string            `json:"status"`
-	PID           int               `json:"pid"`
-	UptimeSeconds int64             `json:"uptime_seconds"`
-	Restarts      int               `json:"restarts"`
-	Health        string            `json:"health"`
-	Healthcheck   *HealthcheckInfo  `json:"healthcheck,omitempty"`
-	Cmd           string            `json:"cmd"`
-	Env           map[string]string `json:"env,omitempty"`
+	Name           string            `json:"name"`
+	Status         string            `json:"status"`
+	PID            int               `json:"pid"`
+	Port           int               `json:"port,omitempty"`
+	UptimeSeconds  int64             `json:"uptime_seconds"`
+	UptimeHuman    string            `json:"uptime_human"`
+	Restarts       int               `json:"restarts"`
+	RestartReason  string            `json:"restart_reason,omitempty"`
+	Health         string            `json:"health"`
+	Healthcheck    *HealthcheckInfo  `json:"healthcheck,omitempty"`
+	Cmd            string            `json:"cmd"`
+	Env            map[string]string `json:"env,omitempty"`
+	Lazy           bool              `json:"lazy,omitempty"`
+	LastError      string            `json:"last_error,omitempty"`
+	PendingRestart bool              `json:"pending_restart,omitempty"`
+}
+
+// AddProcessRequest is the body of POST /processes
+type AddProcessRequest struct {
+	Name string            `json:"name"`
+	Cmd  string            `json:"cmd"`
+	Env  map[string]string `json:"env,omitempty"`
+}
+
+// SignalProcessRequest is the body of POST /processes/{name}/signal
+type SignalProcessRequest struct {
+	Signal string `json:"signal"` // e.g. "SIGUSR2", "USR2", or "12"
+}
+
+// UpdateProcessRequest is the body of PATCH /processes/{name}. Omitted fields
+// are left as they are; env values of null unset a variable, and a
+// healthcheck of null removes it.
+type UpdateProcessRequest struct {
+	Cmd         *string            `json:"cmd,omitempty"`
+	Env         map[string]*string `json:"env,omitempty"`
+	Healthcheck json.RawMessage    `json:"healthcheck,omitempty"`
+	Restart     bool               `json:"restart,omitempty"`
+}
+
+// HealthcheckRequest is a process healthcheck in UpdateProcessRequest, with
+// the fields of a healthcheck in prox.yaml
+type HealthcheckRequest struct {
+	Type        string                  `json:"type,omitempty"`
+	Cmd         string                  `json:"cmd,omitempty"`
+	Service     string                  `json:"service,omitempty"`
+	Path        string                  `json:"path,omitempty"`
+	HTTP        *HealthcheckHTTPRequest `json:"http,omitempty"`
+	TCP         *HealthcheckTCPRequest  `json:"tcp,omitempty"`
+	Interval    string                  `json:"interval,omitempty"`
+	Timeout     string                  `json:"timeout,omitempty"`
+	Retries     int                     `json:"retries,omitempty"`
+	StartPeriod string                  `json:"start_period,omitempty"`
+}
+
+// HealthcheckHTTPRequest is the request of an http healthcheck
+type HealthcheckHTTPRequest struct {
+	URL            string `json:"url"`
+	ExpectedStatus int    `json:"expected_status,omitempty"`
+}
+
+// HealthcheckTCPRequest is the address of a tcp healthcheck
+type HealthcheckTCPRequest struct {
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+}
+
+// toProcessUpdate converts the request to a supervisor.ProcessUpdate
+func (r UpdateProcessRequest) toProcessUpdate() (supervisor.ProcessUpdate, error) {
+	update := supervisor.ProcessUpdate{Cmd: r.Cmd, Env: r.Env}
+	switch {
+	case len(r.Healthcheck) == 0:
+	case string(r.Healthcheck) == "null":
+		update.RemoveHealthcheck = true
+	default:
+		var hc HealthcheckRequest
+		dec := json.NewDecoder(bytes.NewReader(r.Healthcheck))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&hc); err != nil {
+			return update, fmt.Errorf("invalid healthcheck: %w", err)
+		}
+		update.Healthcheck = &config.HealthcheckConfig{
+			Type:        hc.Type,
+			Cmd:         hc.Cmd,
+			Service:     hc.Service,
+			Path:        hc.Path,
+			Interval:    hc.Interval,
+			Timeout:     hc.Timeout,
+			Retries:     hc.Retries,
+			StartPeriod: hc.StartPeriod,
+		}
+		if hc.HTTP != nil {
+			update.Healthcheck.HTTP = &config.HealthcheckHTTPConfig{URL: hc.HTTP.URL, ExpectedStatus: hc.HTTP.ExpectedStatus}
+		}
+		if hc.TCP != nil {
+			update.Healthcheck.TCP = &config.HealthcheckTCPConfig{Host: hc.TCP.Host, Port: hc.TCP.Port}
+		}
+	}
+	if r.Cmd != nil && *r.Cmd == "" {
+		return update, fmt.Errorf("cmd can't be empty")
+	}
+	return update, nil
 }
 
 // HealthcheckInfo represents health check details
@@ -72,14 +280,56 @@ type LogsResponse struct {
 	Logs          []LogEntryResponse `json:"logs"`
 	FilteredCount int                `json:"filtered_count"`
 	TotalCount    int                `json:"total_count"`
+	// Truncated is true when the query budget ran out before the whole log
+	// buffer was searched; Logs then holds the newest matches found
+	Truncated bool   `json:"truncated"`
+	Index     string `json:"index,omitempty"` // Index used to narrow the search
+	// NextCursor and HasMore are set when paging with cursor or page_size:
+	// the cursor to read the following page with, and whether more entries
+	// were stored past it when the page was read
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more,omitempty"`
 }
 
 // LogEntryResponse represents a single log entry
 type LogEntryResponse struct {
+	ID        uint64 `json:"id,omitempty"` // Increases with each entry logged
 	Timestamp string `json:"timestamp"`
 	Process   string `json:"process"`
 	Stream    string `json:"stream"`
 	Line      string `json:"line"`
+	// Level and Fields are set for processes with a log_format when the
+	// line parses in it
+	Level  string            `json:"level,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// EventResponse represents a supervisor event sent by GET /events
+type EventResponse struct {
+	Type      string `json:"type"`
+	Process   string `json:"process,omitempty"`
+	Timestamp string `json:"timestamp"`
+	// Info is the process's state after the event, for process events
+	Info *ProcessResponse `json:"process_info,omitempty"`
+}
+
+// ProblemsResponse represents the problems found in the buffered logs
+type ProblemsResponse struct {
+	Problems []ProblemResponse `json:"problems"`
+	// Truncated is true when the query budget ran out before the whole log
+	// buffer was searched; Problems then holds the newest problems found
+	Truncated bool `json:"truncated"`
+}
+
+// ProblemResponse represents a compiler or test failure found in a log line
+type ProblemResponse struct {
+	Timestamp string `json:"timestamp"`
+	Process   string `json:"process"`
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column,omitempty"`
+	Severity  string `json:"severity,omitempty"`
+	Message   string `json:"message"`
 }
 
 // SuccessResponse represents a simple success response
@@ -93,29 +343,46 @@ type ErrorResponse struct {
 	Code  string `json:"code"`
 }
 
+// humanSeconds formats a whole number of seconds, as reported in
+// uptime_seconds, for the matching _human field
+func humanSeconds(seconds int64) string {
+	return humanize.Duration(time.Duration(seconds) * time.Second)
+}
+
 // ToProcessResponse converts domain.ProcessInfo to ProcessResponse
 func ToProcessResponse(info domain.ProcessInfo) ProcessResponse {
 	return ProcessResponse{
 		Name:          info.Name,
 		Status:        string(info.State),
 		PID:           info.PID,
+		Port:          info.Port,
 		UptimeSeconds: info.UptimeSeconds(),
+		UptimeHuman:   humanSeconds(info.UptimeSeconds()),
 		Restarts:      info.RestartCount,
+		RestartReason: string(info.RestartReason),
 		Health:        string(info.Health),
+		Lazy:          info.Lazy,
+		LastError:     info.LastError,
 	}
 }
 
 // ToProcessDetailResponse converts domain.ProcessInfo to ProcessDetailResponse
 func ToProcessDetailResponse(info domain.ProcessInfo) ProcessDetailResponse {
 	resp := ProcessDetailResponse{
-		Name:          info.Name,
-		Status:        string(info.State),
-		PID:           info.PID,
-		UptimeSeconds: info.UptimeSeconds(),
-		Restarts:      info.RestartCount,
-		Health:        string(info.Health),
-		Cmd:           info.Cmd,
-		Env:           filterSensitiveEnv(info.Env),
+		Name:           info.Name,
+		Status:         string(info.State),
+		PID:            info.PID,
+		Port:           info.Port,
+		UptimeSeconds:  info.UptimeSeconds(),
+		UptimeHuman:    humanSeconds(info.UptimeSeconds()),
+		Restarts:       info.RestartCount,
+		RestartReason:  string(info.RestartReason),
+		Health:         string(info.Health),
+		Cmd:            info.Cmd,
+		Env:            FilterSensitiveEnv(info.Env),
+		Lazy:           info.Lazy,
+		LastError:      info.LastError,
+		PendingRestart: info.PendingRestart,
 	}
 
 	if info.HealthDetails != nil {
@@ -132,9 +399,50 @@ func ToProcessDetailResponse(info domain.ProcessInfo) ProcessDetailResponse {
 	return resp
 }
 
-// filterSensitiveEnv filters out sensitive environment variables
+// TaskListResponse represents the response for GET /tasks
+type TaskListResponse struct {
+	Tasks []TaskResponse `json:"tasks"`
+}
+
+// TaskResponse represents a task and its last run
+type TaskResponse struct {
+	Name        string `json:"name"`
+	Cmd         string `json:"cmd"`
+	Description string `json:"description,omitempty"`
+	Status      string `json:"status"` // idle, running, succeeded, or failed
+	PID         int    `json:"pid,omitempty"`
+	ExitCode    *int   `json:"exit_code,omitempty"`   // Set once the last run has exited
+	StartedAt   string `json:"started_at,omitempty"`  // When the last run started
+	FinishedAt  string `json:"finished_at,omitempty"` // When the last run finished
+	Error       string `json:"error,omitempty"`       // Why the last run failed to start
+}
+
+// ToTaskResponse converts domain.TaskInfo to TaskResponse
+func ToTaskResponse(info domain.TaskInfo) TaskResponse {
+	resp := TaskResponse{
+		Name:        info.Name,
+		Cmd:         info.Cmd,
+		Description: info.Description,
+		Status:      string(info.State),
+		PID:         info.PID,
+		Error:       info.Error,
+	}
+	if !info.StartedAt.IsZero() {
+		resp.StartedAt = info.StartedAt.Format(time.RFC3339Nano)
+	}
+	if !info.FinishedAt.IsZero() {
+		resp.FinishedAt = info.FinishedAt.Format(time.RFC3339Nano)
+		if info.Error == "" {
+			exitCode := info.ExitCode
+			resp.ExitCode = &exitCode
+		}
+	}
+	return resp
+}
+
+// FilterSensitiveEnv filters out sensitive environment variables for display
 // Variables matching sensitive patterns have their values replaced with "[REDACTED]"
-func filterSensitiveEnv(env map[string]string) map[string]string {
+func FilterSensitiveEnv(env map[string]string) map[string]string {
 	if env == nil {
 		return nil
 	}
@@ -164,23 +472,73 @@ func isSensitiveEnvVar(name string) bool {
 // ToLogEntryResponse converts domain.LogEntry to LogEntryResponse
 func ToLogEntryResponse(entry domain.LogEntry) LogEntryResponse {
 	return LogEntryResponse{
+		ID:        entry.ID,
 		Timestamp: entry.Timestamp.Format(time.RFC3339Nano),
 		Process:   entry.Process,
 		Stream:    string(entry.Stream),
 		Line:      entry.Line,
+		Level:     entry.Level,
+		Fields:    entry.Fields,
+	}
+}
+
+// ToEventResponse converts supervisor.SupervisorEvent to EventResponse
+func ToEventResponse(event supervisor.SupervisorEvent) EventResponse {
+	resp := EventResponse{
+		Type:      string(event.Type),
+		Process:   event.Process,
+		Timestamp: event.Timestamp.Format(time.RFC3339Nano),
+	}
+	if event.Process != "" {
+		info := ToProcessResponse(event.Info)
+		resp.Info = &info
+	}
+	return resp
+}
+
+// ToProblemResponse converts logs.Problem to ProblemResponse
+func ToProblemResponse(p logs.Problem) ProblemResponse {
+	return ProblemResponse{
+		Timestamp: p.Timestamp.Format(time.RFC3339Nano),
+		Process:   p.Process,
+		File:      p.File,
+		Line:      p.Line,
+		Column:    p.Column,
+		Severity:  p.Severity,
+		Message:   p.Message,
 	}
 }
 
 // ProxyRequestResponse represents a single proxy request
 type ProxyRequestResponse struct {
-	ID         string `json:"id"`
-	Timestamp  string `json:"timestamp"`
-	Method     string `json:"method"`
-	URL        string `json:"url"`
-	Subdomain  string `json:"subdomain"`
-	StatusCode int    `json:"status_code"`
-	DurationMs int64  `json:"duration_ms"`
-	RemoteAddr string `json:"remote_addr"`
+	ID               string   `json:"id"`
+	Timestamp        string   `json:"timestamp"`
+	Method           string   `json:"method"`
+	URL              string   `json:"url"`
+	Subdomain        string   `json:"subdomain"`
+	StatusCode       int      `json:"status_code"`
+	DurationMs       int64    `json:"duration_ms"`
+	DurationHuman    string   `json:"duration_human"` // e.g. "850ms" or "1.5s"
+	RemoteAddr       string   `json:"remote_addr"`
+	ResponseBytes    int64    `json:"response_bytes"` // Size of the response body sent to the client
+	SchemaViolations []string `json:"schema_violations,omitempty"`
+	Imported         bool     `json:"imported,omitempty"`
+
+	// Type is "websocket" for WebSocket upgrade requests
+	Type      string             `json:"type,omitempty"`
+	WebSocket *WebSocketResponse `json:"websocket,omitempty"` // Set once the connection is upgraded
+
+	// Seq increases with each request recorded, and orders requests for
+	// paging
+	Seq uint64 `json:"seq,omitempty"`
+}
+
+// WebSocketResponse describes the connection of an upgraded WebSocket request.
+// The request's duration is the connection's lifetime once it closes.
+type WebSocketResponse struct {
+	Open     bool  `json:"open"`
+	BytesIn  int64 `json:"bytes_in"`  // Sent by the client
+	BytesOut int64 `json:"bytes_out"` // Sent by the service
 }
 
 // ProxyRequestsResponse represents the response for GET /proxy/requests
@@ -188,20 +546,45 @@ type ProxyRequestsResponse struct {
 	Requests      []ProxyRequestResponse `json:"requests"`
 	FilteredCount int                    `json:"filtered_count"`
 	TotalCount    int                    `json:"total_count"`
+	// NextCursor and HasMore are set when paging with cursor or page_size,
+	// as for LogsResponse
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more,omitempty"`
 }
 
 // ToProxyRequestResponse converts proxy.RequestRecord to ProxyRequestResponse
 func ToProxyRequestResponse(req proxy.RequestRecord) ProxyRequestResponse {
-	return ProxyRequestResponse{
-		ID:         req.ID,
-		Timestamp:  req.Timestamp.Format(time.RFC3339Nano),
-		Method:     req.Method,
-		URL:        req.URL,
-		Subdomain:  req.Subdomain,
-		StatusCode: req.StatusCode,
-		DurationMs: req.Duration.Milliseconds(),
-		RemoteAddr: req.RemoteAddr,
+	resp := ProxyRequestResponse{
+		ID:               req.ID,
+		Seq:              req.Seq,
+		Timestamp:        req.Timestamp.Format(time.RFC3339Nano),
+		Method:           req.Method,
+		URL:              req.URL,
+		Subdomain:        req.Subdomain,
+		StatusCode:       req.StatusCode,
+		DurationMs:       req.Duration.Milliseconds(),
+		DurationHuman:    humanize.Duration(req.Duration),
+		RemoteAddr:       req.RemoteAddr,
+		ResponseBytes:    req.ResponseBytes,
+		SchemaViolations: req.SchemaViolations,
+		Imported:         req.Imported,
+		Type:             req.Type,
+	}
+	if req.WebSocket != nil {
+		resp.WebSocket = &WebSocketResponse{
+			Open:     req.WebSocket.Open,
+			BytesIn:  req.WebSocket.BytesIn,
+			BytesOut: req.WebSocket.BytesOut,
+		}
 	}
+	return resp
+}
+
+// SessionLoadResponse represents the response for POST /proxy/session
+type SessionLoadResponse struct {
+	Imported int    `json:"imported"`
+	Skipped  int    `json:"skipped"` // Requests already in the history
+	SavedAt  string `json:"saved_at"`
 }
 
 // CapturedBodyResponse represents a captured request or response body in API responses
@@ -226,3 +609,123 @@ type ProxyRequestDetailResponse struct {
 	ProxyRequestResponse
 	Details *RequestDetailsResponse `json:"details,omitempty"`
 }
+
+// ServiceStatsResponse represents rolling latency stats for a single service
+type ServiceStatsResponse struct {
+	Subdomain        string                 `json:"subdomain"`
+	Count            int                    `json:"count"`
+	Errors           int                    `json:"errors"`
+	P50Ms            int64                  `json:"p50_ms"`
+	P95Ms            int64                  `json:"p95_ms"`
+	P99Ms            int64                  `json:"p99_ms"`
+	BudgetMs         int64                  `json:"budget_ms,omitempty"`
+	OverBudget       bool                   `json:"over_budget"`
+	SchemaViolations int                    `json:"schema_violations"`
+	Events           []ProcessEventResponse `json:"events,omitempty"`
+}
+
+// ProcessEventResponse represents a start, stop, or crash of a service's
+// backing process within the stats window
+type ProcessEventResponse struct {
+	Process     string `json:"process"`
+	Type        string `json:"type"`
+	Timestamp   string `json:"timestamp"`
+	ErrorsAfter int    `json:"errors_after"`
+}
+
+// ProxyStatsResponse represents the response for GET /proxy/stats
+type ProxyStatsResponse struct {
+	WindowSeconds int64                  `json:"window_seconds"`
+	Services      []ServiceStatsResponse `json:"services"`
+}
+
+// ProxyRuleResponse represents a dynamic proxy rule in API responses
+type ProxyRuleResponse struct {
+	ID          string `json:"id"`
+	Kind        string `json:"kind"`
+	Service     string `json:"service"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// ProxyRulesResponse represents the response for GET /proxy/rules
+type ProxyRulesResponse struct {
+	Rules []ProxyRuleResponse `json:"rules"`
+}
+
+// ProxyRecordingRequest is the body of POST /proxy/recording
+type ProxyRecordingRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// ProxyRecordingResponse represents whether proxied requests are recorded,
+// for GET and POST /proxy/recording
+type ProxyRecordingResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+// ToProxyRuleResponse converts proxy.Rule to ProxyRuleResponse
+func ToProxyRuleResponse(rule proxy.Rule) ProxyRuleResponse {
+	return ProxyRuleResponse{
+		ID:          rule.ID,
+		Kind:        string(rule.Kind),
+		Service:     rule.Service,
+		Description: rule.Description,
+		Enabled:     rule.Enabled,
+	}
+}
+
+// ToServiceStatsResponse converts proxy.ServiceStats to ServiceStatsResponse
+func ToServiceStatsResponse(stats proxy.ServiceStats) ServiceStatsResponse {
+	resp := ServiceStatsResponse{
+		Subdomain:        stats.Subdomain,
+		Count:            stats.Count,
+		Errors:           stats.Errors,
+		P50Ms:            stats.P50.Milliseconds(),
+		P95Ms:            stats.P95.Milliseconds(),
+		P99Ms:            stats.P99.Milliseconds(),
+		BudgetMs:         stats.Budget.Milliseconds(),
+		OverBudget:       stats.OverBudget,
+		SchemaViolations: stats.SchemaViolations,
+	}
+	for _, event := range stats.Events {
+		resp.Events = append(resp.Events, ProcessEventResponse{
+			Process:     event.Process,
+			Type:        event.Type,
+			Timestamp:   event.Time.Format(time.RFC3339Nano),
+			ErrorsAfter: event.ErrorsAfter,
+		})
+	}
+	return resp
+}
+
+// ReloadResponse represents the response for POST /reload
+type ReloadResponse struct {
+	Added   []string          `json:"added"`
+	Removed []string          `json:"removed"`
+	Changed []string          `json:"changed"`
+	Failed  map[string]string `json:"failed,omitempty"`
+	Ignored []string          `json:"ignored,omitempty"`
+}
+
+// ToReloadResponse converts supervisor.ReloadResult to ReloadResponse
+func ToReloadResponse(result supervisor.ReloadResult) ReloadResponse {
+	resp := ReloadResponse{
+		Added:   append([]string{}, result.Added...),
+		Removed: append([]string{}, result.Removed...),
+		Changed: append([]string{}, result.Changed...),
+		Ignored: result.Ignored,
+	}
+	if len(result.Failed) > 0 {
+		resp.Failed = make(map[string]string, len(result.Failed))
+		for name, err := range result.Failed {
+			resp.Failed[name] = err.Error()
+		}
+	}
+	return resp
+}
+
+// DumpResponse represents the response for POST /debug/dump
+type DumpResponse struct {
+	Path string `json:"path"` // Where the dump was written
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -179,7 +180,7 @@ func TestFilterSensitiveEnv(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := filterSensitiveEnv(tt.input)
+			result := FilterSensitiveEnv(tt.input)
 
 			if tt.expected == nil {
 				if result != nil {
@@ -242,12 +243,13 @@ func TestIsSensitiveEnvVar(t *testing.T) {
 func TestToProcessResponse(t *testing.T) {
 	now := time.Now()
 	info := domain.ProcessInfo{
-		Name:         "test-process",
-		State:        domain.ProcessStateRunning,
-		PID:          1234,
-		StartedAt:    now.Add(-10 * time.Second),
-		RestartCount: 2,
-		Health:       domain.HealthStatusHealthy,
+		Name:          "test-process",
+		State:         domain.ProcessStateRunning,
+		PID:           1234,
+		StartedAt:     now.Add(-10 * time.Second),
+		RestartCount:  2,
+		RestartReason: domain.RestartReasonWatch,
+		Health:        domain.HealthStatusHealthy,
 	}
 
 	resp := ToProcessResponse(info)
@@ -264,6 +266,9 @@ func TestToProcessResponse(t *testing.T) {
 	if resp.Restarts != 2 {
 		t.Errorf("expected Restarts 2, got %d", resp.Restarts)
 	}
+	if resp.RestartReason != "file change" {
+		t.Errorf("expected RestartReason 'file change', got %q", resp.RestartReason)
+	}
 	if resp.Health != "healthy" {
 		t.Errorf("expected Health 'healthy', got %q", resp.Health)
 	}
@@ -271,6 +276,71 @@ func TestToProcessResponse(t *testing.T) {
 	if resp.UptimeSeconds < 9 || resp.UptimeSeconds > 11 {
 		t.Errorf("expected UptimeSeconds around 10, got %d", resp.UptimeSeconds)
 	}
+	if want := fmt.Sprintf("%ds", resp.UptimeSeconds); resp.UptimeHuman != want {
+		t.Errorf("expected UptimeHuman %q, got %q", want, resp.UptimeHuman)
+	}
+}
+
+func TestToProcessResponse_LastError(t *testing.T) {
+	info := domain.ProcessInfo{
+		Name:      "web",
+		State:     domain.ProcessStateStopped,
+		LastError: "waiting for dependencies: tcp://localhost:5432 unavailable",
+	}
+
+	if got := ToProcessResponse(info).LastError; got != info.LastError {
+		t.Errorf("expected LastError %q, got %q", info.LastError, got)
+	}
+	if got := ToProcessDetailResponse(info).LastError; got != info.LastError {
+		t.Errorf("expected detail LastError %q, got %q", info.LastError, got)
+	}
+}
+
+func TestToStartErrorResponses(t *testing.T) {
+	if resp := ToStartErrorResponses(nil); resp != nil {
+		t.Errorf("expected nil for no failures, got %v", resp)
+	}
+
+	at := time.Date(2025, 1, 19, 10, 30, 0, 0, time.UTC)
+	resp := ToStartErrorResponses([]domain.StartFailure{
+		{Process: "worker", Error: "failed to load environment: missing.env", Time: at},
+	})
+	if len(resp) != 1 {
+		t.Fatalf("expected 1 response, got %d", len(resp))
+	}
+	if resp[0].Process != "worker" || resp[0].Error != "failed to load environment: missing.env" {
+		t.Errorf("unexpected response: %+v", resp[0])
+	}
+	if resp[0].Time != "2025-01-19T10:30:00Z" {
+		t.Errorf("expected RFC3339 time, got %q", resp[0].Time)
+	}
+}
+
+func TestToTaskResponse(t *testing.T) {
+	started := time.Date(2025, 1, 19, 10, 30, 0, 0, time.UTC)
+	resp := ToTaskResponse(domain.TaskInfo{
+		Name:       "migrate",
+		Cmd:        "bin/rails db:migrate",
+		State:      domain.TaskStateFailed,
+		ExitCode:   3,
+		StartedAt:  started,
+		FinishedAt: started.Add(2 * time.Second),
+	})
+	if resp.Status != "failed" || resp.ExitCode == nil || *resp.ExitCode != 3 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.StartedAt != "2025-01-19T10:30:00Z" || resp.FinishedAt != "2025-01-19T10:30:02Z" {
+		t.Errorf("unexpected times: %q, %q", resp.StartedAt, resp.FinishedAt)
+	}
+
+	// No exit code until the task has exited, or if it never started
+	if resp := ToTaskResponse(domain.TaskInfo{Name: "migrate", State: domain.TaskStateRunning, StartedAt: started}); resp.ExitCode != nil {
+		t.Errorf("expected no exit code while running, got %d", *resp.ExitCode)
+	}
+	resp = ToTaskResponse(domain.TaskInfo{Name: "migrate", State: domain.TaskStateFailed, StartedAt: started, FinishedAt: started, Error: "failed to load environment"})
+	if resp.ExitCode != nil || resp.Error != "failed to load environment" {
+		t.Errorf("unexpected response for a start failure: %+v", resp)
+	}
 }
 
 func TestToProcessDetailResponse(t *testing.T) {
@@ -0,0 +1,56 @@
+package api
+
+// StatusResponseV2 is the structured GET /status response of API v2. The
+// supervisor's state moves into its own object, and process counts are
+// included so clients don't need a second request for an overview.
+type StatusResponseV2 struct {
+	APIVersion string                   `json:"api_version"`
+	ConfigFile string                   `json:"config_file,omitempty"`
+	Supervisor SupervisorStatusResponse `json:"supervisor"`
+	Processes  ProcessCountsResponse    `json:"processes"`
+	Errors     []StartErrorResponse     `json:"errors"` // Always present; empty when nothing failed
+	Logs       *LogsStatusResponse      `json:"logs,omitempty"`
+	Detail     *StatusDetailResponse    `json:"detail,omitempty"`
+}
+
+// SupervisorStatusResponse describes the supervisor in a v2 status response
+type SupervisorStatusResponse struct {
+	State         string `json:"state"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	UptimeHuman   string `json:"uptime_human"`
+	Asleep        bool   `json:"asleep"`
+}
+
+// ProcessCountsResponse counts processes in a v2 status response
+type ProcessCountsResponse struct {
+	Total    int            `json:"total"`
+	ByStatus map[string]int `json:"by_status"`
+}
+
+// toStatusResponseV2 maps a status snapshot to the v2 response
+func toStatusResponseV2(snap statusSnapshot) StatusResponseV2 {
+	resp := StatusResponseV2{
+		APIVersion: string(V2),
+		ConfigFile: snap.ConfigFile,
+		Supervisor: SupervisorStatusResponse{
+			State:         snap.Supervisor.State,
+			UptimeSeconds: snap.Supervisor.UptimeSeconds(),
+			UptimeHuman:   humanSeconds(snap.Supervisor.UptimeSeconds()),
+			Asleep:        snap.Asleep,
+		},
+		Processes: ProcessCountsResponse{
+			Total:    len(snap.Processes),
+			ByStatus: make(map[string]int),
+		},
+		Errors: ToStartErrorResponses(snap.Failures),
+		Logs:   snap.Logs,
+		Detail: snap.Detail,
+	}
+	for _, p := range snap.Processes {
+		resp.Processes.ByStatus[string(p.State)]++
+	}
+	if resp.Errors == nil {
+		resp.Errors = []StartErrorResponse{}
+	}
+	return resp
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"crypto/subtle"
 	"fmt"
+	"mime"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
@@ -11,6 +13,9 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/idle"
 )
 
 // ServerConfig holds configuration for the API server
@@ -19,6 +24,14 @@ type ServerConfig struct {
 	Port        int
 	AuthEnabled bool   // Whether authentication is required
 	Token       string // Authentication token (only used if AuthEnabled is true)
+
+	// TLSCertFile and TLSKeyFile serve the API over HTTPS when set
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// Idle records API calls as daemon activity (optional). Open log streams
+	// keep the daemon active until they close.
+	Idle *idle.Tracker
 }
 
 // Server represents the HTTP API server
@@ -26,6 +39,7 @@ type Server struct {
 	config     ServerConfig
 	router     *chi.Mux
 	httpServer *http.Server
+	listener   net.Listener // Bound by Listen, served by Start
 	handlers   *Handlers
 	mu         sync.Mutex
 }
@@ -44,6 +58,9 @@ func NewServer(config ServerConfig, handlers *Handlers) *Server {
 	// CORS - restricted to localhost only for security
 	r.Use(corsMiddleware())
 
+	// Count API calls as activity for idle shutdown
+	r.Use(activityMiddleware(config.Idle))
+
 	s := &Server{
 		config:   config,
 		router:   r,
@@ -56,6 +73,22 @@ func NewServer(config ServerConfig, handlers *Handlers) *Server {
 	return s
 }
 
+// activityMiddleware records each request as activity for the idle tracker.
+// Metrics scrapes don't count, or a scraper would keep the daemon awake.
+func activityMiddleware(tracker *idle.Tracker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/metrics" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			end := tracker.Begin()
+			defer end()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // corsMiddleware returns a CORS middleware restricted to localhost
 func corsMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -64,7 +97,7 @@ func corsMiddleware() func(http.Handler) http.Handler {
 			// Only allow localhost origins
 			if isLocalhostOrigin(origin) {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, OPTIONS")
 				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
 			}
@@ -107,6 +140,58 @@ func isLocalhostOrigin(origin string) bool {
 	return false
 }
 
+// requestContentTypes are the Content-Types the API accepts on requests with
+// a body: JSON, and gzip for session archives (POST /proxy/session). Neither
+// can be sent cross-origin without a CORS preflight.
+var requestContentTypes = []string{"application/json", "application/gzip"}
+
+// mutationGuardMiddleware keeps web pages from changing anything through the
+// API. On localhost auth is off, and corsMiddleware only sets response
+// headers, so without it a page on any origin could send a simple POST (a
+// text/plain or form body, which needs no preflight) and, say, run a command
+// with POST /processes. Requests other than GET and HEAD are rejected when
+// they come from a non-local Origin, or carry a body that isn't one of
+// requestContentTypes.
+//
+// A request with an Authorization header is let through from any origin:
+// browsers only send one after a preflight, which corsMiddleware allows for
+// localhost alone, so it can't be forged. This keeps the dashboard working
+// where it is served through the proxy, which always requires the token.
+func mutationGuardMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		origin := r.Header.Get("Origin")
+		if origin != "" && !isLocalhostOrigin(origin) && r.Header.Get("Authorization") == "" {
+			writeJSON(w, http.StatusForbidden, ErrorResponse{
+				Error: fmt.Sprintf("requests from origin %s are not allowed", origin),
+				Code:  domain.ErrCodeForbiddenOrigin,
+			})
+			return
+		}
+
+		contentType := r.Header.Get("Content-Type")
+		if contentType == "" && r.ContentLength == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		for _, allowed := range requestContentTypes {
+			if mediaType == allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		writeJSON(w, http.StatusUnsupportedMediaType, ErrorResponse{
+			Error: fmt.Sprintf("unsupported Content-Type %q, use application/json", contentType),
+			Code:  domain.ErrCodeUnsupportedMediaType,
+		})
+	})
+}
+
 // authMiddleware returns an authentication middleware
 func authMiddleware(authEnabled bool, token string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -156,61 +241,165 @@ func (s *Server) registerRoutes() {
 		_, _ = w.Write([]byte("ok"))
 	})
 
-	s.router.Route("/api/v1", func(r chi.Router) {
-		// Apply auth middleware to all API routes (only if auth is enabled)
-		r.Use(authMiddleware(s.config.AuthEnabled, s.config.Token))
-
-		// Supervisor status
-		r.Get("/status", s.handlers.GetStatus)
-
-		// Processes
-		r.Get("/processes", s.handlers.GetProcesses)
-		r.Get("/processes/{name}", s.handlers.GetProcess)
-		r.Post("/processes/{name}/start", s.handlers.StartProcess)
-		r.Post("/processes/{name}/stop", s.handlers.StopProcess)
-		r.Post("/processes/{name}/restart", s.handlers.RestartProcess)
-
-		// Logs
-		r.Get("/logs", s.handlers.GetLogs)
-		r.Get("/logs/stream", s.handlers.StreamLogs)
-
-		// Proxy requests
-		// Note: /proxy/requests/stream must come before /proxy/requests/{id}
-		// to prevent the parameterized route from matching "stream" as an ID
-		r.Get("/proxy/requests", s.handlers.GetProxyRequests)
-		r.Get("/proxy/requests/stream", s.handlers.StreamProxyRequests)
-		r.Get("/proxy/requests/{id}", s.handlers.GetProxyRequest)
-
-		// Shutdown
-		r.Post("/shutdown", s.handlers.Shutdown)
+	// Prometheus metrics at root, where scrapers expect them
+	s.router.With(authMiddleware(s.config.AuthEnabled, s.config.Token)).Get("/metrics", s.handlers.GetMetrics)
+
+	// Web dashboard
+	s.router.Get(strings.TrimSuffix(UIPath, "/"), func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, UIPath, http.StatusMovedPermanently)
+	})
+	s.router.Handle(UIPath+"*", uiHandler())
+
+	// Each version is served at its own prefix. Unversioned /api paths pick
+	// a version from the Accept-Version header.
+	for _, v := range Versions {
+		s.router.With(withVersion(v)).Route("/api/"+string(v), s.apiRoutes)
+	}
+	s.router.With(negotiateVersion).Route("/api", s.apiRoutes)
+}
+
+// apiRoutes registers the API routes shared by every version. Handlers use
+// requestVersion to pick the response mapper for the version being served.
+func (s *Server) apiRoutes(r chi.Router) {
+	// Apply auth middleware to all API routes (only if auth is enabled)
+	r.Use(authMiddleware(s.config.AuthEnabled, s.config.Token))
+	r.Use(mutationGuardMiddleware)
+
+	// Supervisor status
+	r.Get("/status", s.handlers.GetStatus)
+
+	// Processes
+	r.Get("/processes", s.handlers.GetProcesses)
+	r.Post("/processes", s.handlers.AddProcess)
+	r.Get("/processes/{name}", s.handlers.GetProcess)
+	r.Patch("/processes/{name}", s.handlers.UpdateProcess)
+	r.Post("/processes/{name}/start", s.handlers.StartProcess)
+	r.Post("/processes/{name}/stop", s.handlers.StopProcess)
+	r.Post("/processes/{name}/restart", s.handlers.RestartProcess)
+	r.Post("/processes/{name}/drain", s.handlers.DrainProcess)
+	r.Post("/processes/{name}/signal", s.handlers.SignalProcess)
+	r.Get("/processes/{name}/attach", s.handlers.AttachProcess)
+
+	// Tasks
+	r.Get("/tasks", s.handlers.GetTasks)
+	r.Get("/tasks/{name}", s.handlers.GetTask)
+	r.Post("/tasks/{name}/run", s.handlers.RunTask)
+
+	// Logs
+	r.Get("/logs", s.handlers.GetLogs)
+	r.Get("/logs/stream", s.handlers.StreamLogs)
+
+	// Problems found in logs by problem matchers
+	r.Get("/problems", s.handlers.GetProblems)
+	r.Get("/problems/stream", s.handlers.StreamProblems)
+
+	// Supervisor events
+	r.Get("/events", s.handlers.StreamEvents)
+
+	// Proxy requests
+	// Note: /proxy/requests/stream must come before /proxy/requests/{id}
+	// to prevent the parameterized route from matching "stream" as an ID
+	r.Get("/proxy/requests", s.handlers.GetProxyRequests)
+	r.Get("/proxy/requests/stream", s.handlers.StreamProxyRequests)
+	r.Get("/proxy/requests/export", s.handlers.ExportProxyRequests)
+	r.Get("/proxy/requests/{id}", s.handlers.GetProxyRequest)
+	r.Get("/proxy/stats", s.handlers.GetProxyStats)
+	r.Get("/proxy/session", s.handlers.SaveProxySession)
+	r.Post("/proxy/session", s.handlers.LoadProxySession)
+	r.Get("/proxy/rules", s.handlers.GetProxyRules)
+	r.Post("/proxy/rules/{id}/enable", s.handlers.EnableProxyRule)
+	r.Post("/proxy/rules/{id}/disable", s.handlers.DisableProxyRule)
+	r.Get("/proxy/recording", s.handlers.GetProxyRecording)
+	r.Post("/proxy/recording", s.handlers.SetProxyRecording)
+
+	// Config reload
+	r.Post("/reload", s.handlers.Reload)
+
+	// Debugging
+	r.Post("/debug/dump", s.handlers.TriggerDump)
+
+	// Shutdown
+	r.Post("/shutdown", s.handlers.Shutdown)
+}
+
+// ProxyHandler returns the API for serving through the reverse proxy.
+// Authentication is always required there, even when the local listener
+// does not require it, because the proxy is reachable by anything that can
+// resolve the proxy domain. The health check and the web dashboard's static
+// files stay public.
+func (s *Server) ProxyHandler() http.Handler {
+	if s.config.Token == "" {
+		// Never expose an unauthenticated API through the proxy
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{
+				Error: "API access through the proxy requires an auth token",
+				Code:  "UNAUTHORIZED",
+			})
+		})
+	}
+
+	authed := authMiddleware(true, s.config.Token)(s.router)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" || isUIPath(r.URL.Path) {
+			s.router.ServeHTTP(w, r)
+			return
+		}
+		authed.ServeHTTP(w, r)
 	})
 }
 
+// Listen binds the server's address without serving it yet, so a port
+// already in use is reported before anything depends on the API. Start
+// listens itself if Listen wasn't called.
+func (s *Server) Listen() error {
+	listener, err := net.Listen("tcp", s.Addr())
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+	return nil
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
-	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
-
 	s.mu.Lock()
 	s.httpServer = &http.Server{
-		Addr:         addr,
+		Addr:         s.Addr(),
 		Handler:      s.router,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 0, // Disable for SSE
 		IdleTimeout:  60 * time.Second,
 	}
 	server := s.httpServer
+	listener := s.listener
 	s.mu.Unlock()
 
-	return server.ListenAndServe()
+	if s.config.TLSCertFile != "" {
+		if listener == nil {
+			return server.ListenAndServeTLS(s.config.TLSCertFile, s.config.TLSKeyFile)
+		}
+		return server.ServeTLS(listener, s.config.TLSCertFile, s.config.TLSKeyFile)
+	}
+	if listener == nil {
+		return server.ListenAndServe()
+	}
+	return server.Serve(listener)
 }
 
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.mu.Lock()
 	server := s.httpServer
+	listener := s.listener
 	s.mu.Unlock()
 
 	if server == nil {
+		// A listener bound but never served is just closed
+		if listener != nil {
+			return listener.Close()
+		}
 		return nil
 	}
 	return server.Shutdown(ctx)
@@ -220,3 +409,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 func (s *Server) Addr() string {
 	return fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
 }
+
+// URL returns the server's base URL, https:// when it serves TLS
+func (s *Server) URL() string {
+	if s.config.TLSCertFile != "" {
+		return "https://" + s.Addr()
+	}
+	return "http://" + s.Addr()
+}
@@ -2,8 +2,11 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
@@ -11,7 +14,10 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/charliek/prox/internal/idle"
 	"github.com/charliek/prox/internal/logs"
+	"github.com/charliek/prox/internal/proxy/certs"
 	"github.com/charliek/prox/internal/supervisor"
 )
 
@@ -268,6 +274,137 @@ func TestAuthMiddleware_HealthEndpointNoAuth(t *testing.T) {
 	assert.Equal(t, "ok", w.Body.String())
 }
 
+func TestServer_UI(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := &config.Config{
+		API:       config.APIConfig{Port: 0, Host: "127.0.0.1"},
+		Processes: map[string]config.ProcessConfig{},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "test.yaml", nil)
+
+	// The dashboard's files are served without a token; its API calls need one
+	server := NewServer(ServerConfig{
+		Host:        "127.0.0.1",
+		Port:        0,
+		AuthEnabled: true,
+		Token:       "secret-token-123",
+	}, handlers)
+
+	req := httptest.NewRequest("GET", "/ui", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/ui/", w.Header().Get("Location"))
+
+	req = httptest.NewRequest("GET", "/ui/", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `<script src="app.js">`)
+
+	for _, file := range []string{"app.js", "style.css"} {
+		req = httptest.NewRequest("GET", "/ui/"+file, nil)
+		w = httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, file)
+	}
+
+	req = httptest.NewRequest("GET", "/ui/missing.js", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestProxyHandler_RequiresAuth(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := &config.Config{
+		API:       config.APIConfig{Port: 0, Host: "127.0.0.1"},
+		Processes: map[string]config.ProcessConfig{},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "test.yaml", nil)
+
+	// Auth is disabled on the local listener but enforced through the proxy
+	server := NewServer(ServerConfig{
+		Host:        "127.0.0.1",
+		Port:        0,
+		AuthEnabled: false,
+		Token:       "secret-token-123",
+	}, handlers)
+	handler := server.ProxyHandler()
+
+	tests := []struct {
+		name       string
+		path       string
+		authHeader string
+		wantStatus int
+	}{
+		{"missing token", "/api/v1/status", "", http.StatusUnauthorized},
+		{"wrong token", "/api/v1/status", "Bearer wrong", http.StatusUnauthorized},
+		{"valid token", "/api/v1/status", "Bearer secret-token-123", http.StatusOK},
+		{"health is public", "/health", "", http.StatusOK},
+		{"dashboard is public", "/ui/app.js", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	// The dashboard served through the proxy can make changes from its own
+	// origin, since it sends the token
+	req := httptest.NewRequest("POST", "/api/v1/processes/missing/start", nil)
+	req.Header.Set("Origin", "https://prox.local.myapp.dev")
+	req.Header.Set("Authorization", "Bearer secret-token-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The local listener still allows unauthenticated access
+	req = httptest.NewRequest("GET", "/api/v1/status", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestProxyHandler_NoToken(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := &config.Config{
+		API:       config.APIConfig{Port: 0, Host: "127.0.0.1"},
+		Processes: map[string]config.ProcessConfig{},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "test.yaml", nil)
+
+	server := NewServer(ServerConfig{Host: "127.0.0.1", Port: 0}, handlers)
+
+	// An empty bearer token must not unlock the API
+	req := httptest.NewRequest("GET", "/api/v1/status", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+
+	server.ProxyHandler().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
 func TestServerAddr(t *testing.T) {
 	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
 	defer logMgr.Close()
@@ -383,3 +520,103 @@ func TestIsLocalhostOrigin(t *testing.T) {
 		})
 	}
 }
+
+func TestActivityMiddleware(t *testing.T) {
+	tracker := idle.NewTracker()
+	time.Sleep(20 * time.Millisecond)
+
+	var idleDuring time.Duration
+	handler := activityMiddleware(tracker)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		idleDuring = tracker.IdleFor()
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/status", nil))
+
+	assert.Equal(t, time.Duration(0), idleDuring, "never idle while a request is in progress")
+	assert.Less(t, tracker.IdleFor(), 20*time.Millisecond, "idle time restarts when the request ends")
+}
+
+func TestActivityMiddleware_NoTracker(t *testing.T) {
+	handler := activityMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/status", nil))
+	assert.Equal(t, http.StatusTeapot, w.Code)
+}
+
+func TestServer_Listen(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := &config.Config{Processes: map[string]config.ProcessConfig{}}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "test.yaml", nil)
+
+	port, err := daemon.FindAvailablePort("127.0.0.1")
+	require.NoError(t, err)
+	server := NewServer(ServerConfig{Host: "127.0.0.1", Port: port}, handlers)
+	require.NoError(t, server.Listen())
+
+	// The port is taken from the moment Listen returns
+	busy := NewServer(ServerConfig{Host: "127.0.0.1", Port: port}, handlers)
+	assert.Error(t, busy.Listen())
+
+	done := make(chan error, 1)
+	go func() { done <- server.Start() }()
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + server.Addr() + "/health")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, server.Shutdown(context.Background()))
+	assert.ErrorIs(t, <-done, http.ErrServerClosed)
+}
+
+func TestServer_TLS(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := &config.Config{Processes: map[string]config.ProcessConfig{}}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	handlers := NewHandlers(sup, logMgr, "test.yaml", nil)
+
+	paths, err := certs.EnsureAPICert(t.TempDir(), []string{"127.0.0.1"})
+	require.NoError(t, err)
+	port, err := daemon.FindAvailablePort("127.0.0.1")
+	require.NoError(t, err)
+	server := NewServer(ServerConfig{Host: "127.0.0.1", Port: port, TLSCertFile: paths.CertFile, TLSKeyFile: paths.KeyFile}, handlers)
+	assert.Equal(t, "https://"+server.Addr(), server.URL())
+	require.NoError(t, server.Listen())
+
+	done := make(chan error, 1)
+	go func() { done <- server.Start() }()
+
+	// Clients trust the API with its certificate as the CA
+	pem, err := os.ReadFile(paths.CertFile)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(pem))
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	require.Eventually(t, func() bool {
+		resp, err := client.Get(server.URL() + "/health")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Clients without it don't
+	_, err = http.Get(server.URL() + "/health")
+	assert.Error(t, err)
+
+	require.NoError(t, server.Shutdown(context.Background()))
+	assert.ErrorIs(t, <-done, http.ErrServerClosed)
+}
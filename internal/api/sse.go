@@ -5,9 +5,16 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/charliek/prox/internal/supervisor"
+	"github.com/charliek/prox/internal/watchdog"
 )
 
 // StreamLogs handles GET /api/v1/logs/stream (SSE)
@@ -26,17 +33,29 @@ func (h *Handlers) StreamLogs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse filter parameters
-	filter := domain.LogFilter{}
-	if processes := r.URL.Query().Get("process"); processes != "" {
-		filter.Processes = strings.Split(processes, ",")
-	}
-	filter.Pattern = r.URL.Query().Get("pattern")
-	if r.URL.Query().Get("regex") == "true" {
-		filter.IsRegex = true
+	filter := parseLogFilter(r)
+
+	// Lines already logged to send first (capped like GET /logs)
+	backfill := 0
+	if backfillStr := r.URL.Query().Get("backfill"); backfillStr != "" {
+		if n, err := strconv.Atoi(backfillStr); err == nil && n > 0 {
+			backfill = min(n, constants.MaxLogLines)
+		}
 	}
 
-	// Subscribe to logs
-	subID, ch, err := h.logManager.Subscribe(filter)
+	// Subscribe to logs, taking the backfill in the same step so no line is
+	// missed or sent twice
+	var subID string
+	var ch <-chan domain.LogEntry
+	var history []domain.LogEntry
+	var err error
+	if backfill > 0 {
+		var result logs.QueryResult
+		subID, ch, result, err = h.logManager.SubscribeWithBackfill(filter, backfill)
+		history = result.Entries
+	} else {
+		subID, ch, err = h.logManager.Subscribe(filter)
+	}
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, ErrorResponse{
 			Error: err.Error(),
@@ -45,9 +64,24 @@ func (h *Handlers) StreamLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer h.logManager.Unsubscribe(subID)
+	handle := h.trackStream(w, subID, func() time.Time {
+		return h.logManager.SubscriptionStalledSince(subID)
+	}, func() {
+		h.logManager.Unsubscribe(subID)
+	})
+	defer handle.Done()
 
 	// Send initial comment to establish connection
 	fmt.Fprintf(w, ": connected\n\n")
+	for _, entry := range history {
+		data, err := json.Marshal(ToLogEntryResponse(entry))
+		if err != nil {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+	}
 	flusher.Flush()
 
 	// Stream logs
@@ -55,6 +89,8 @@ func (h *Handlers) StreamLogs(w http.ResponseWriter, r *http.Request) {
 	// 1. Log subscription uses a buffered channel - if client can't keep up, messages are dropped
 	// 2. Write errors cause the handler to return, cleaning up the subscription
 	// 3. Context cancellation (client disconnect) is handled via select
+	// 4. The watchdog unsubscribes a subscriber that stays stalled, e.g. when the
+	//    connection vanished without cancelling the context
 	ctx := r.Context()
 	for {
 		select {
@@ -62,6 +98,7 @@ func (h *Handlers) StreamLogs(w http.ResponseWriter, r *http.Request) {
 			return
 		case entry, ok := <-ch:
 			if !ok {
+				writeShutdownEvent(w, flusher, h.logManager)
 				return
 			}
 
@@ -82,3 +119,138 @@ func (h *Handlers) StreamLogs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+// StreamEvents handles GET /api/v1/events (SSE)
+// It sends supervisor events, such as process starts, crashes, and health
+// changes, as they happen. backfill sends the last N events first, and
+// follow=false ends the stream after them.
+func (h *Handlers) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{
+			Error: "streaming not supported",
+			Code:  domain.ErrCodeStreamingNotSupported,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	query := r.URL.Query()
+	var processes, types []string
+	if v := query.Get("process"); v != "" {
+		processes = strings.Split(v, ",")
+	}
+	if v := query.Get("type"); v != "" {
+		types = strings.Split(v, ",")
+	}
+	match := func(event supervisor.SupervisorEvent) bool {
+		return (len(processes) == 0 || slices.Contains(processes, event.Process)) &&
+			(len(types) == 0 || slices.Contains(types, string(event.Type)))
+	}
+	backfill := 0
+	if backfillStr := query.Get("backfill"); backfillStr != "" {
+		if n, err := strconv.Atoi(backfillStr); err == nil && n > 0 {
+			backfill = min(n, constants.SupervisorEventHistory)
+		}
+	}
+	follow := query.Get("follow") != "false"
+
+	ch, history := h.supervisor.SubscribeWithHistory(backfill)
+	defer h.supervisor.Unsubscribe(ch)
+
+	send := func(event supervisor.SupervisorEvent) bool {
+		if !match(event) {
+			return true
+		}
+		data, err := json.Marshal(ToEventResponse(event))
+		if err != nil {
+			return true
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		return err == nil
+	}
+
+	// Send initial comment to establish connection
+	fmt.Fprintf(w, ": connected\n\n")
+	for _, event := range history {
+		if !send(event) {
+			return
+		}
+	}
+	flusher.Flush()
+	if !follow {
+		return
+	}
+
+	// Events can be minutes apart, so keep the connection visibly alive
+	keepalive := time.NewTicker(constants.SSEKeepaliveInterval)
+	defer keepalive.Stop()
+
+	ctx := r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.logManager.Done():
+			// Send the events emitted while stopping, such as supervisor_stop
+			for pending := true; pending; {
+				select {
+				case event := <-ch:
+					pending = send(event)
+				default:
+					pending = false
+				}
+			}
+			writeShutdownEvent(w, flusher, h.logManager)
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprintf(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			if !send(event) {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeShutdownEvent ends a log stream whose subscription was closed with a
+// shutdown event if the log manager is shutting down, so clients can tell
+// prox stopping from a dropped connection
+func writeShutdownEvent(w http.ResponseWriter, flusher http.Flusher, logManager *logs.Manager) {
+	select {
+	case <-logManager.Done():
+		fmt.Fprintf(w, "event: shutdown\ndata: {}\n\n")
+		flusher.Flush()
+	default:
+	}
+}
+
+// trackStream registers an SSE stream with the supervisor's watchdog. A
+// stream whose subscription has been stalled for longer than
+// constants.WatchdogStallTimeout is treated as leaked: it is unsubscribed,
+// which ends the handler's loop, and its pending writes are failed so a
+// handler blocked on a dead connection returns too.
+func (h *Handlers) trackStream(w http.ResponseWriter, id string, stalledSince func() time.Time, unsubscribe func()) *watchdog.Handle {
+	if h.supervisor == nil {
+		return nil
+	}
+	rc := http.NewResponseController(w)
+	return h.supervisor.Watchdog().Track(watchdog.KindSSESubscriber, id, func() bool {
+		since := stalledSince()
+		return !since.IsZero() && time.Since(since) > constants.WatchdogStallTimeout
+	}, func() {
+		unsubscribe()
+		_ = rc.SetWriteDeadline(time.Now())
+	})
+}
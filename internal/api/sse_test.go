@@ -10,8 +10,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/charliek/prox/internal/config"
 	"github.com/charliek/prox/internal/domain"
 	"github.com/charliek/prox/internal/logs"
+	"github.com/charliek/prox/internal/supervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // mockSupervisor implements the minimum interface needed for handler tests
@@ -207,6 +211,59 @@ func TestStreamLogs_DataFormat(t *testing.T) {
 	}
 }
 
+func TestStreamLogs_Backfill(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{
+		BufferSize:         100,
+		SubscriptionBuffer: 10,
+	})
+	defer logMgr.Close()
+
+	for _, line := range []string{"one", "two", "three"} {
+		logMgr.Write(domain.LogEntry{Timestamp: time.Now(), Process: "web", Stream: domain.StreamStdout, Line: line})
+	}
+
+	handlers := NewHandlers(nil, logMgr, "test.yaml", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/api/v1/logs/stream?process=web&backfill=2", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handlers.StreamLogs(rec, req)
+		close(done)
+	}()
+
+	// Wait for connection to be established, then log a new line
+	time.Sleep(50 * time.Millisecond)
+	logMgr.Write(domain.LogEntry{Timestamp: time.Now(), Process: "web", Stream: domain.StreamStdout, Line: "four"})
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not finish")
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(rec.Body.String()))
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			var entry LogEntryResponse
+			if err := json.Unmarshal([]byte(data), &entry); err != nil {
+				t.Fatalf("failed to parse data line: %v", err)
+			}
+			lines = append(lines, entry.Line)
+		}
+	}
+
+	expected := []string{"two", "three", "four"}
+	if strings.Join(lines, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected lines %v, got %v", expected, lines)
+	}
+}
+
 func TestStreamLogs_InvalidPattern(t *testing.T) {
 	logMgr := logs.NewManager(logs.ManagerConfig{
 		BufferSize:         100,
@@ -238,3 +295,104 @@ func TestStreamLogs_InvalidPattern(t *testing.T) {
 		t.Errorf("expected code %q, got %q", domain.ErrCodeInvalidPattern, errResp.Code)
 	}
 }
+
+func TestStreamLogs_ShutdownEvent(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{
+		BufferSize:         100,
+		SubscriptionBuffer: 10,
+	})
+	handlers := NewHandlers(nil, logMgr, "test.yaml", nil)
+
+	req := httptest.NewRequest("GET", "/api/v1/logs/stream", nil)
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handlers.StreamLogs(rec, req)
+		close(done)
+	}()
+
+	// Wait for connection to be established
+	time.Sleep(50 * time.Millisecond)
+	logMgr.Write(domain.LogEntry{Timestamp: time.Now(), Process: "web", Stream: domain.StreamStdout, Line: "last line"})
+	logMgr.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not finish when the log manager closed")
+	}
+
+	// The entry written before Close is sent before the shutdown event
+	body := rec.Body.String()
+	lineAt := strings.Index(body, "last line")
+	shutdownAt := strings.Index(body, "event: shutdown\ndata: {}\n\n")
+	if lineAt < 0 || shutdownAt < lineAt {
+		t.Errorf("expected the line and then a shutdown event, got %q", body)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	cfg := &config.Config{
+		API:       config.APIConfig{Port: 0},
+		Processes: map[string]config.ProcessConfig{"web": {Cmd: "sleep 30"}},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	_, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	handlers := NewHandlers(sup, logMgr, "test.yaml", nil)
+
+	t.Run("backfill without follow", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/events?backfill=10&follow=false&type=process_started", nil)
+		rec := httptest.NewRecorder()
+		handlers.StreamEvents(rec, req)
+
+		assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+		var events []EventResponse
+		for _, line := range strings.Split(rec.Body.String(), "\n") {
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var event EventResponse
+				require.NoError(t, json.Unmarshal([]byte(data), &event))
+				events = append(events, event)
+			}
+		}
+		require.Len(t, events, 1)
+		assert.Equal(t, "process_started", events[0].Type)
+		assert.Equal(t, "web", events[0].Process)
+		require.NotNil(t, events[0].Info)
+		assert.Equal(t, "running", events[0].Info.Status)
+	})
+
+	t.Run("follow until shutdown", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/events", nil)
+		rec := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			handlers.StreamEvents(rec, req)
+			close(done)
+		}()
+
+		// Wait for connection to be established
+		time.Sleep(50 * time.Millisecond)
+		stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, sup.Stop(stopCtx))
+		logMgr.Close()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("handler did not finish when the log manager closed")
+		}
+
+		// Events sent while stopping come before the shutdown event
+		body := rec.Body.String()
+		assert.NotContains(t, body, "supervisor_start", "no backfill was asked for")
+		stopAt := strings.Index(body, `"type":"supervisor_stop"`)
+		shutdownAt := strings.Index(body, "event: shutdown\ndata: {}\n\n")
+		if stopAt < 0 || shutdownAt < stopAt {
+			t.Errorf("expected supervisor_stop and then a shutdown event, got %q", body)
+		}
+	})
+}
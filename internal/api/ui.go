@@ -0,0 +1,32 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// uiFiles is the web dashboard served at /ui. It is plain HTML, CSS, and
+// JavaScript built on the API, so it needs no build step.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// UIPath is where the API server serves the web dashboard
+const UIPath = "/ui/"
+
+// uiHandler serves the web dashboard. The files hold no data, so they are
+// served without auth; the dashboard sends the token with its API calls.
+func uiHandler() http.Handler {
+	files, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err) // The embedded directory always exists
+	}
+	return http.StripPrefix(UIPath, http.FileServer(http.FS(files)))
+}
+
+// isUIPath reports whether path is part of the web dashboard
+func isUIPath(path string) bool {
+	return path == strings.TrimSuffix(UIPath, "/") || strings.HasPrefix(path, UIPath)
+}
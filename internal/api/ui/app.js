@@ -0,0 +1,376 @@
+// prox web dashboard. Talks to the daemon's /api/v1 endpoints; log and
+// request streams are read with fetch so they can carry the auth token.
+"use strict";
+
+const API = "/api/v1";
+const MAX_LOG_ENTRIES = 1000;
+const MAX_REQUESTS = 1000;
+const REFRESH_MS = 2000;
+
+const state = {
+  token: "",
+  logs: [],
+  requests: [],
+  selectedRequest: "",
+};
+
+const $ = (sel) => document.querySelector(sel);
+
+// The token can be passed as /ui#token=... (kept out of server logs) or
+// entered when the API asks for it
+function loadToken() {
+  const match = location.hash.match(/token=([^&]+)/);
+  if (match) {
+    sessionStorage.setItem("prox-token", decodeURIComponent(match[1]));
+    history.replaceState(null, "", location.pathname);
+  }
+  state.token = sessionStorage.getItem("prox-token") || "";
+}
+
+class AuthError extends Error {}
+
+// UnavailableError is a permanent failure, e.g. the proxy isn't enabled
+class UnavailableError extends Error {}
+
+async function api(path, options = {}) {
+  const headers = { ...(options.headers || {}) };
+  if (state.token) {
+    headers.Authorization = "Bearer " + state.token;
+  }
+  const resp = await fetch(API + path, { ...options, headers });
+  if (resp.status === 401) {
+    $("#auth").hidden = false;
+    throw new AuthError("unauthorized");
+  }
+  return resp;
+}
+
+async function apiJSON(path, options) {
+  const resp = await api(path, options);
+  const body = await resp.json();
+  if (!resp.ok) {
+    throw new Error(body.error || resp.statusText);
+  }
+  return body;
+}
+
+function setConnection(text, isError) {
+  const el = $("#connection");
+  el.textContent = text;
+  el.className = isError ? "error" : "dim";
+}
+
+// stream reads server-sent events from path, calling onEvent with each
+// decoded data payload, and reconnects when the stream ends
+async function stream(path, onEvent, onConnect) {
+  for (;;) {
+    try {
+      const resp = await api(path);
+      if (!resp.ok) {
+        const body = await resp.json().catch(() => ({}));
+        if (body.code === "PROXY_NOT_ENABLED") {
+          throw new UnavailableError(body.error);
+        }
+        throw new Error(body.error || resp.statusText);
+      }
+      if (onConnect) {
+        await onConnect();
+      }
+      const reader = resp.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buffer = "";
+      for (;;) {
+        const { value, done } = await reader.read();
+        if (done) {
+          break;
+        }
+        buffer += value;
+        let end;
+        while ((end = buffer.indexOf("\n\n")) >= 0) {
+          const event = buffer.slice(0, end);
+          buffer = buffer.slice(end + 2);
+          // Named events, such as the shutdown event ending a log stream,
+          // carry no item
+          const lines = event.split("\n");
+          if (lines.some((line) => line.startsWith("event: "))) {
+            continue;
+          }
+          for (const line of lines) {
+            if (line.startsWith("data: ")) {
+              onEvent(JSON.parse(line.slice(6)));
+            }
+          }
+        }
+      }
+    } catch (err) {
+      if (err instanceof AuthError) {
+        return;
+      }
+      if (err instanceof UnavailableError) {
+        return err;
+      }
+      setConnection(path + ": " + err.message, true);
+    }
+    await new Promise((resolve) => setTimeout(resolve, 1000));
+  }
+}
+
+function formatDuration(seconds) {
+  if (seconds <= 0) {
+    return "-";
+  }
+  const h = Math.floor(seconds / 3600);
+  const m = Math.floor((seconds % 3600) / 60);
+  const s = seconds % 60;
+  if (h > 0) {
+    return `${h}h${m}m`;
+  }
+  if (m > 0) {
+    return `${m}m${s}s`;
+  }
+  return `${s}s`;
+}
+
+function cell(text, className) {
+  const td = document.createElement("td");
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+// Processes
+
+async function refreshProcesses() {
+  try {
+    const [status, list] = await Promise.all([apiJSON("/status"), apiJSON("/processes")]);
+    const asleep = status.asleep ? ", asleep" : "";
+    $("#supervisor").textContent = `${status.status}${asleep}, up ${formatDuration(status.uptime_seconds)} - ${status.config_file}`;
+    setConnection("", false);
+    renderProcesses(list.processes || []);
+  } catch (err) {
+    if (!(err instanceof AuthError)) {
+      setConnection("daemon unreachable: " + err.message, true);
+    }
+  }
+}
+
+function renderProcesses(processes) {
+  const tbody = $("#processes tbody");
+  tbody.replaceChildren();
+
+  const select = $("#log-process");
+  const selected = select.value;
+  select.replaceChildren(new Option("All processes", ""));
+
+  for (const p of processes) {
+    const tr = document.createElement("tr");
+    const status = p.lazy ? p.status + " (lazy)" : p.status;
+    tr.append(
+      cell(p.name),
+      cell(status, p.status),
+      cell(p.pid || "-"),
+      cell(formatDuration(p.uptime_seconds)),
+      cell(p.restarts),
+      cell(p.health, p.health),
+    );
+
+    const actions = document.createElement("td");
+    const running = p.status === "running" || p.status === "starting";
+    for (const action of running ? ["restart", "stop"] : ["start"]) {
+      const button = document.createElement("button");
+      button.textContent = action;
+      button.onclick = () => processAction(p.name, action);
+      actions.append(button);
+    }
+    if (p.last_error) {
+      actions.append(" ");
+      actions.append(Object.assign(document.createElement("span"), { className: "error", textContent: p.last_error }));
+    }
+    tr.append(actions);
+    tbody.append(tr);
+
+    select.append(new Option(p.name, p.name, false, p.name === selected));
+  }
+}
+
+async function processAction(name, action) {
+  try {
+    await apiJSON(`/processes/${encodeURIComponent(name)}/${action}`, { method: "POST" });
+  } catch (err) {
+    if (!(err instanceof AuthError)) {
+      setConnection(`${action} ${name} failed: ${err.message}`, true);
+    }
+  }
+  refreshProcesses();
+}
+
+// Logs
+
+function addLog(entry) {
+  state.logs.push(entry);
+  if (state.logs.length > MAX_LOG_ENTRIES) {
+    state.logs.splice(0, state.logs.length - MAX_LOG_ENTRIES);
+  }
+}
+
+function renderLogs() {
+  const process = $("#log-process").value;
+  const filter = $("#log-filter").value.toLowerCase();
+  const visible = state.logs.filter(
+    (e) => (!process || e.process === process) && (!filter || e.line.toLowerCase().includes(filter)),
+  );
+
+  const pre = $("#log-lines");
+  const lines = visible.map((e) => {
+    const span = document.createElement("span");
+    if (e.stream === "stderr") {
+      span.className = "stderr";
+    }
+    const time = new Date(e.timestamp).toLocaleTimeString();
+    span.textContent = `${time} ${e.process.padEnd(10)} ${e.line}\n`;
+    return span;
+  });
+  pre.replaceChildren(...lines);
+  $("#log-count").textContent = `${visible.length}/${state.logs.length} lines`;
+
+  if ($("#log-follow").checked) {
+    pre.scrollTop = pre.scrollHeight;
+  }
+}
+
+async function loadRecentLogs() {
+  const resp = await apiJSON(`/logs?lines=${MAX_LOG_ENTRIES}`);
+  state.logs = resp.logs || [];
+  renderLogs();
+}
+
+// Requests
+
+function statusClass(code) {
+  return "s" + Math.floor(code / 100) + "xx";
+}
+
+function addRequest(req) {
+  // WebSocket requests are sent again when their connection closes
+  if (req.type === "websocket") {
+    const i = state.requests.findIndex((r) => r.id === req.id);
+    if (i >= 0) {
+      state.requests[i] = req;
+      return;
+    }
+  }
+  state.requests.unshift(req);
+  if (state.requests.length > MAX_REQUESTS) {
+    state.requests.length = MAX_REQUESTS;
+  }
+}
+
+function renderRequests() {
+  const filter = $("#request-filter").value.toLowerCase();
+  const visible = state.requests.filter(
+    (r) => !filter || `${r.method} ${r.subdomain} ${r.url}`.toLowerCase().includes(filter),
+  );
+
+  const tbody = $("#request-list tbody");
+  tbody.replaceChildren(
+    ...visible.map((r) => {
+      const tr = document.createElement("tr");
+      if (r.id === state.selectedRequest) {
+        tr.className = "selected";
+      }
+      const schema = r.schema_violations && r.schema_violations.length ? " [schema]" : "";
+      const imported = r.imported ? " [imported]" : "";
+      const ws = r.websocket && r.websocket.open ? " [websocket open]" : r.type === "websocket" ? " [websocket]" : "";
+      tr.append(
+        cell(new Date(r.timestamp).toLocaleTimeString()),
+        cell(r.subdomain),
+        cell(r.method),
+        cell(r.status_code, statusClass(r.status_code)),
+        cell(r.duration_ms + "ms"),
+        cell(r.url + schema + ws + imported),
+      );
+      tr.onclick = () => showRequest(r.id);
+      return tr;
+    }),
+  );
+  $("#request-count").textContent = `${visible.length}/${state.requests.length} requests`;
+}
+
+async function loadRecentRequests() {
+  const resp = await apiJSON(`/proxy/requests?limit=${MAX_REQUESTS}`);
+  state.requests = (resp.requests || []).slice().sort((a, b) => b.timestamp.localeCompare(a.timestamp));
+  renderRequests();
+}
+
+async function showRequest(id) {
+  state.selectedRequest = id;
+  renderRequests();
+  const detail = $("#request-detail");
+  detail.className = "";
+  try {
+    const req = await apiJSON(`/proxy/requests/${encodeURIComponent(id)}?include=body`);
+    detail.textContent = JSON.stringify(req, null, 2);
+  } catch (err) {
+    detail.className = "error";
+    detail.textContent = err.message;
+  }
+}
+
+// Setup
+
+function showView(name) {
+  for (const button of document.querySelectorAll("nav button")) {
+    button.classList.toggle("active", button.dataset.view === name);
+  }
+  for (const view of document.querySelectorAll(".view")) {
+    view.hidden = view.id !== name;
+  }
+}
+
+let refreshTimer = null;
+
+// start begins polling and streaming; it is called again after the token is
+// entered, since the streams stop when the API rejects them
+function start() {
+  refreshProcesses();
+  if (refreshTimer === null) {
+    refreshTimer = setInterval(refreshProcesses, REFRESH_MS);
+  }
+
+  stream("/logs/stream", (entry) => {
+    addLog(entry);
+    renderLogs();
+  }, loadRecentLogs);
+
+  stream("/proxy/requests/stream", (req) => {
+    addRequest(req);
+    renderRequests();
+  }, loadRecentRequests).then((err) => {
+    if (err) {
+      $("#request-detail").textContent = err.message + " (requests are only recorded by the proxy)";
+    }
+  });
+}
+
+document.addEventListener("DOMContentLoaded", () => {
+  loadToken();
+
+  for (const button of document.querySelectorAll("nav button")) {
+    button.onclick = () => showView(button.dataset.view);
+  }
+  $("#log-process").onchange = renderLogs;
+  $("#log-filter").oninput = renderLogs;
+  $("#log-follow").onchange = renderLogs;
+  $("#request-filter").oninput = renderRequests;
+
+  $("#auth").onsubmit = (event) => {
+    event.preventDefault();
+    state.token = $("#token").value.trim();
+    sessionStorage.setItem("prox-token", state.token);
+    $("#auth").hidden = true;
+    start();
+  };
+
+  start();
+});
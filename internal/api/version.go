@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/charliek/prox/internal/domain"
+)
+
+// Version identifies an API version. Every version serves the same routes
+// through the same handlers; only the response mappers differ, so a breaking
+// response change ships under a new version while older clients keep the
+// shape they expect.
+type Version string
+
+const (
+	V1 Version = "v1"
+	V2 Version = "v2" // Structured status
+)
+
+// Versions lists the supported API versions, oldest first
+var Versions = []Version{V1, V2}
+
+// DefaultVersion is used for unversioned requests without Accept-Version.
+// It stays at V1 so existing clients are never broken.
+const DefaultVersion = V1
+
+// Headers for choosing a version on unversioned paths, and for reporting the
+// version a response was served with
+const (
+	AcceptVersionHeader = "Accept-Version"
+	VersionHeader       = "API-Version"
+)
+
+type versionKey struct{}
+
+// requestVersion returns the API version a request is served with
+func requestVersion(r *http.Request) Version {
+	if v, ok := r.Context().Value(versionKey{}).(Version); ok {
+		return v
+	}
+	return DefaultVersion
+}
+
+// withVersion serves requests with a fixed version, as chosen by the URL
+func withVersion(v Version) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(VersionHeader, string(v))
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionKey{}, v)))
+		})
+	}
+}
+
+// negotiateVersion serves requests with the version named by their
+// Accept-Version header, or DefaultVersion without one
+func negotiateVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, err := parseVersion(r.Header.Get(AcceptVersionHeader))
+		if err != nil {
+			writeJSON(w, http.StatusNotAcceptable, ErrorResponse{
+				Error: err.Error(),
+				Code:  domain.ErrCodeUnsupportedVersion,
+			})
+			return
+		}
+		withVersion(v)(next).ServeHTTP(w, r)
+	})
+}
+
+// parseVersion parses an Accept-Version value such as "v2" or "2"
+func parseVersion(value string) (Version, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return DefaultVersion, nil
+	}
+	if !strings.HasPrefix(value, "v") {
+		value = "v" + value
+	}
+	for _, v := range Versions {
+		if Version(value) == v {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported API version %q (supported: %s)", value, joinVersions(Versions))
+}
+
+// joinVersions formats versions as a comma-separated list
+func joinVersions(versions []Version) string {
+	names := make([]string, len(versions))
+	for i, v := range versions {
+		names[i] = string(v)
+	}
+	return strings.Join(names, ", ")
+}
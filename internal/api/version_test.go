@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/prox/internal/domain"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Version
+		wantErr bool
+	}{
+		{"", DefaultVersion, false},
+		{"v1", V1, false},
+		{"2", V2, false},
+		{" V2 ", V2, false},
+		{"v3", "", true},
+		{"latest", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseVersion(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	server, _, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	tests := []struct {
+		name          string
+		path          string
+		acceptVersion string
+		wantVersion   Version
+	}{
+		{name: "v1 URL", path: "/api/v1/status", wantVersion: V1},
+		{name: "v2 URL", path: "/api/v2/status", wantVersion: V2},
+		{name: "URL wins over header", path: "/api/v1/status", acceptVersion: "v2", wantVersion: V1},
+		{name: "unversioned defaults to v1", path: "/api/status", wantVersion: V1},
+		{name: "unversioned with header", path: "/api/status", acceptVersion: "2", wantVersion: V2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.acceptVersion != "" {
+				req.Header.Set(AcceptVersionHeader, tt.acceptVersion)
+			}
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, string(tt.wantVersion), w.Header().Get(VersionHeader))
+
+			var resp map[string]interface{}
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			assert.Equal(t, string(tt.wantVersion), resp["api_version"])
+		})
+	}
+}
+
+func TestVersionedRoutes_SharedHandlers(t *testing.T) {
+	server, _, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	for _, path := range []string{"/api/v1/processes", "/api/v2/processes", "/api/processes"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, path)
+		var resp ProcessListResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Len(t, resp.Processes, 1, path)
+	}
+}
+
+func TestVersionedRoutes_UnsupportedVersion(t *testing.T) {
+	server, _, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	req := httptest.NewRequest("GET", "/api/status", nil)
+	req.Header.Set(AcceptVersionHeader, "v9")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	var resp ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, domain.ErrCodeUnsupportedVersion, resp.Code)
+	assert.Contains(t, resp.Error, "v1, v2")
+}
+
+func TestGetStatus_V2(t *testing.T) {
+	server, _, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	req := httptest.NewRequest("GET", "/api/v2/status", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp StatusResponseV2
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "v2", resp.APIVersion)
+	assert.Equal(t, "running", resp.Supervisor.State)
+	assert.Equal(t, 1, resp.Processes.Total)
+	assert.Equal(t, 1, resp.Processes.ByStatus["running"])
+	assert.NotNil(t, resp.Errors, "v2 always includes the errors list")
+	assert.NotNil(t, resp.Logs)
+}
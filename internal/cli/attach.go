@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/charliek/prox/internal/websocket"
+)
+
+// attachDrainTimeout is how long prox attach keeps printing output once its
+// input ends, so the response to piped input is shown
+const attachDrainTimeout = 500 * time.Millisecond
+
+// attachProcess connects the terminal to a process's stdin and output until
+// the process exits or the user detaches
+func attachProcess(client *Client, name string) error {
+	conn, err := client.AttachProcess(name)
+	if err != nil {
+		return clientError(err, "")
+	}
+	defer conn.Close()
+	fmt.Fprintf(os.Stderr, "Attached to %s. Press Ctrl-D or Ctrl-C to detach.\n", name)
+
+	var detached atomic.Bool
+	detach := func() {
+		detached.Store(true)
+		conn.Close()
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		<-sigCh
+		detach()
+	}()
+
+	// The terminal stays in line mode, so input is echoed and edited locally
+	// and sent a line at a time
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if n > 0 {
+				if conn.WriteMessage(websocket.OpBinary, buf[:n]) != nil {
+					return
+				}
+			}
+			if err != nil {
+				time.AfterFunc(attachDrainTimeout, detach)
+				return
+			}
+		}
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		os.Stdout.Write(data)
+	}
+	if detached.Load() {
+		fmt.Fprintf(os.Stderr, "\nDetached from %s\n", name)
+	} else {
+		fmt.Fprintf(os.Stderr, "\n%s exited\n", name)
+	}
+	return nil
+}
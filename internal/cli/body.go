@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/constants"
+)
+
+// hexdumpPreviewBytes is how much of a binary body is shown as a hexdump
+const hexdumpPreviewBytes = 256
+
+// defaultPager pages output that doesn't fit on one screen, keeping colors
+const defaultPager = "less -FRX"
+
+// formatBody returns a captured body ready to print: JSON and XML are
+// indented, forms are split into one field per line, and binary bodies are
+// shown as a hexdump preview. With color, syntax is highlighted. Bodies that
+// don't parse, e.g. because they were truncated, are returned as is.
+func formatBody(body *api.CapturedBodyResponse, color bool) string {
+	if body.IsBinary {
+		return hexdumpPreview(body.Data)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(body.ContentType)
+	trimmed := strings.TrimSpace(body.Data)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		if formatted, ok := formatForm(trimmed, color); ok {
+			return formatted
+		}
+	case strings.HasSuffix(mediaType, "xml"):
+		if formatted, ok := formatXML(trimmed, color); ok {
+			return formatted
+		}
+	case strings.HasSuffix(mediaType, "json") || strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "["):
+		var buf bytes.Buffer
+		if json.Indent(&buf, []byte(trimmed), "", "  ") == nil {
+			if color {
+				return highlightJSON(buf.String())
+			}
+			return buf.String()
+		}
+	}
+	return body.Data
+}
+
+// hexdumpPreview decodes a base64 body and dumps its first bytes
+func hexdumpPreview(data string) string {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "[binary data, base64 encoded]\n" + data
+	}
+	preview := decoded
+	if len(preview) > hexdumpPreviewBytes {
+		preview = preview[:hexdumpPreviewBytes]
+	}
+	out := strings.TrimSuffix(hex.Dump(preview), "\n")
+	if rest := len(decoded) - len(preview); rest > 0 {
+		out += fmt.Sprintf("\n... %d more bytes", rest)
+	}
+	return out
+}
+
+// formatForm prints form fields one per line, sorted by name and decoded
+func formatForm(data string, color bool) (string, bool) {
+	values, err := url.ParseQuery(data)
+	if err != nil {
+		return "", false
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		for _, value := range values[name] {
+			lines = append(lines, colorize(name, constants.ColorSyntaxKey, color)+" = "+colorize(value, constants.ColorSyntaxString, color))
+		}
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+// formatXML indents an XML document, keeping text-only elements on one line
+func formatXML(data string, color bool) (string, bool) {
+	dec := xml.NewDecoder(strings.NewReader(data))
+	var lines []string
+	depth := 0
+	inline := false // The last line is a start tag without child elements
+
+	indent := func() string { return strings.Repeat("  ", depth) }
+	tag := func(s string) string { return colorize(s, constants.ColorSyntaxKey, color) }
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", false
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			var sb strings.Builder
+			sb.WriteString("<" + xmlName(t.Name))
+			for _, attr := range t.Attr {
+				var value bytes.Buffer
+				_ = xml.EscapeText(&value, []byte(attr.Value))
+				sb.WriteString(fmt.Sprintf(` %s="%s"`, xmlName(attr.Name), value.String()))
+			}
+			sb.WriteString(">")
+			lines = append(lines, indent()+tag(sb.String()))
+			depth++
+			inline = true
+		case xml.EndElement:
+			depth--
+			end := tag("</" + xmlName(t.Name) + ">")
+			if inline {
+				lines[len(lines)-1] += end
+			} else {
+				lines = append(lines, indent()+end)
+			}
+			inline = false
+		case xml.CharData:
+			text := strings.TrimSpace(string(t))
+			if text == "" {
+				continue
+			}
+			var escaped bytes.Buffer
+			_ = xml.EscapeText(&escaped, []byte(text))
+			if inline {
+				lines[len(lines)-1] += escaped.String()
+			} else {
+				lines = append(lines, indent()+escaped.String())
+			}
+		case xml.Comment:
+			lines = append(lines, indent()+"<!--"+string(t)+"-->")
+			inline = false
+		case xml.ProcInst:
+			lines = append(lines, indent()+tag("<?"+t.Target+" "+string(t.Inst)+"?>"))
+		case xml.Directive:
+			lines = append(lines, indent()+"<!"+string(t)+">")
+		}
+	}
+	if depth != 0 || len(lines) == 0 {
+		return "", false
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+// xmlName returns a raw token name with its namespace prefix
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// highlightJSON colors the keys, strings, numbers, and literals of indented
+// JSON
+func highlightJSON(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j++
+			if j > len(s) {
+				j = len(s)
+			}
+			color := constants.ColorSyntaxString
+			if strings.HasPrefix(strings.TrimLeft(s[j:], " "), ":") {
+				color = constants.ColorSyntaxKey
+			}
+			sb.WriteString(color + s[i:j] + constants.ColorReset)
+			i = j
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && strings.IndexByte("0123456789.eE+-", s[j]) >= 0 {
+				j++
+			}
+			sb.WriteString(constants.ColorSyntaxNumber + s[i:j] + constants.ColorReset)
+			i = j
+		case c == 't' || c == 'f' || c == 'n':
+			j := i + 1
+			for j < len(s) && s[j] >= 'a' && s[j] <= 'z' {
+				j++
+			}
+			sb.WriteString(constants.ColorSyntaxLiteral + s[i:j] + constants.ColorReset)
+			i = j
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// colorize wraps s in a color when color is on
+func colorize(s, color string, on bool) string {
+	if !on {
+		return s
+	}
+	return color + s + constants.ColorReset
+}
+
+// pageOutput prints text through $PAGER (less by default) when stdout is a
+// terminal, and directly otherwise
+func pageOutput(text string, usePager bool) {
+	if !usePager || !isTerminal() {
+		fmt.Print(text)
+		return
+	}
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = defaultPager
+	}
+	cmd := exec.Command(constants.DefaultShell, "-c", pager)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		fmt.Print(text)
+		return
+	}
+	_ = cmd.Wait()
+}
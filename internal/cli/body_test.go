@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body api.CapturedBodyResponse
+		want string
+	}{
+		{
+			name: "json",
+			body: api.CapturedBodyResponse{ContentType: "application/json; charset=utf-8", Data: `{"id":1,"tags":["a"]}`},
+			want: "{\n  \"id\": 1,\n  \"tags\": [\n    \"a\"\n  ]\n}",
+		},
+		{
+			name: "json without content type",
+			body: api.CapturedBodyResponse{Data: `[1,2]`},
+			want: "[\n  1,\n  2\n]",
+		},
+		{
+			name: "truncated json",
+			body: api.CapturedBodyResponse{ContentType: "application/json", Data: `{"id":1,"na`},
+			want: `{"id":1,"na`,
+		},
+		{
+			name: "xml",
+			body: api.CapturedBodyResponse{ContentType: "application/xml", Data: `<?xml version="1.0"?><user id="1"><name>Ann &amp; Bo</name><soap:tag/></user>`},
+			want: "<?xml version=\"1.0\"?>\n<user id=\"1\">\n  <name>Ann &amp; Bo</name>\n  <soap:tag></soap:tag>\n</user>",
+		},
+		{
+			name: "form",
+			body: api.CapturedBodyResponse{ContentType: "application/x-www-form-urlencoded", Data: "b=two+words&a=1&a=%2F"},
+			want: "a = 1\na = /\nb = two words",
+		},
+		{
+			name: "plain text",
+			body: api.CapturedBodyResponse{ContentType: "text/plain", Data: "hello {world"},
+			want: "hello {world",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatBody(&tt.body, false))
+		})
+	}
+}
+
+func TestFormatBody_Binary(t *testing.T) {
+	data := make([]byte, hexdumpPreviewBytes+10)
+	copy(data, "PNG")
+	body := api.CapturedBodyResponse{IsBinary: true, Data: base64.StdEncoding.EncodeToString(data)}
+
+	out := formatBody(&body, false)
+	assert.True(t, strings.HasPrefix(out, "00000000  50 4e 47 00"), out)
+	assert.True(t, strings.HasSuffix(out, "... 10 more bytes"), out)
+}
+
+func TestHighlightJSON(t *testing.T) {
+	out := highlightJSON(`{"a": "x: 1", "b": -1.5e3, "c": null}`)
+	reset := constants.ColorReset
+	assert.Contains(t, out, constants.ColorSyntaxKey+`"a"`+reset)
+	assert.Contains(t, out, constants.ColorSyntaxString+`"x: 1"`+reset)
+	assert.Contains(t, out, constants.ColorSyntaxNumber+"-1.5e3"+reset)
+	assert.Contains(t, out, constants.ColorSyntaxLiteral+"null"+reset)
+}
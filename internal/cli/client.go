@@ -2,6 +2,7 @@ package cli
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -14,7 +15,9 @@ import (
 	"time"
 
 	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/websocket"
 )
 
 // sseReadTimeout is the timeout for SSE reads. If no data is received within
@@ -48,15 +51,25 @@ type Client struct {
 
 // NewClient creates a new API client
 func NewClient(baseURL string) *Client {
-	// Try to load token from file
-	token, _ := loadToken() // Ignore error - token may not exist
+	// Take the token from the environment, or try to load it from file
+	token := os.Getenv(constants.APITokenEnv)
+	if token == "" {
+		token, _ = loadToken() // Ignore error - token may not exist
+	}
+
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+	if tlsConfig := apiTLSConfig(); tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		httpClient.Transport = transport
+	}
 
 	return &Client{
-		baseURL: strings.TrimSuffix(baseURL, "/"),
-		token:   token,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: httpClient,
 	}
 }
 
@@ -69,6 +82,15 @@ func (c *Client) GetStatus() (*api.StatusResponse, error) {
 	return &resp, nil
 }
 
+// GetStatusDetail gets supervisor status including runtime diagnostics
+func (c *Client) GetStatusDetail() (*api.StatusResponse, error) {
+	var resp api.StatusResponse
+	if err := c.get("/api/v1/status?detail=true", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 // GetProcesses gets all processes
 func (c *Client) GetProcesses() (*api.ProcessListResponse, error) {
 	var resp api.ProcessListResponse
@@ -87,6 +109,52 @@ func (c *Client) GetProcess(name string) (*api.ProcessDetailResponse, error) {
 	return &resp, nil
 }
 
+// AddProcess registers a process that isn't in the config and starts it
+func (c *Client) AddProcess(req api.AddProcessRequest) (*api.ProcessDetailResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+	resp, err := c.send("POST", "/api/v1/processes", bytes.NewReader(body), "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result api.ProcessDetailResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &result, nil
+}
+
+// AttachProcess opens a WebSocket to a running process's input and output.
+// Messages written to it go to the process's stdin, and the process's output
+// arrives as messages until it exits.
+func (c *Client) AttachProcess(name string) (*websocket.Conn, error) {
+	header := http.Header{}
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	}
+	// No timeout, since the connection lasts as long as the attach. HTTP/2
+	// can't upgrade to a WebSocket, and a custom transport doesn't attempt it.
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: apiTLSConfig()}}
+
+	conn, resp, err := websocket.Dial(client, c.baseURL+"/api/v1/processes/"+url.PathEscape(name)+"/attach", header)
+	if err != nil {
+		if resp == nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+		defer resp.Body.Close()
+		var errResp api.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err == nil {
+			return nil, httpStatusError(resp.StatusCode, &errResp)
+		}
+		return nil, httpStatusError(resp.StatusCode, nil)
+	}
+	return conn, nil
+}
+
 // StartProcess starts a process
 func (c *Client) StartProcess(name string) error {
 	var resp api.SuccessResponse
@@ -105,6 +173,83 @@ func (c *Client) RestartProcess(name string) error {
 	return c.post("/api/v1/processes/"+url.PathEscape(name)+"/restart", &resp)
 }
 
+// RestartProcessBlueGreen restarts a process by starting a new instance on a
+// new port and switching proxy traffic to it before stopping the old one
+func (c *Client) RestartProcessBlueGreen(name string) error {
+	var resp api.SuccessResponse
+	return c.post("/api/v1/processes/"+url.PathEscape(name)+"/restart?strategy=blue-green", &resp)
+}
+
+// DrainProcess drains proxy traffic from a process and then stops it
+func (c *Client) DrainProcess(name string) error {
+	var resp api.SuccessResponse
+	return c.post("/api/v1/processes/"+url.PathEscape(name)+"/drain", &resp)
+}
+
+// SignalProcess sends a signal, such as "SIGUSR2", to a running process
+func (c *Client) SignalProcess(name, signal string) error {
+	body, err := json.Marshal(api.SignalProcessRequest{Signal: signal})
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+	resp, err := c.send("POST", "/api/v1/processes/"+url.PathEscape(name)+"/signal", bytes.NewReader(body), "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result api.SuccessResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// GetTasks lists the configured tasks with their last runs
+func (c *Client) GetTasks() (*api.TaskListResponse, error) {
+	var resp api.TaskListResponse
+	if err := c.get("/api/v1/tasks", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetTask gets a task and its last run
+func (c *Client) GetTask(name string) (*api.TaskResponse, error) {
+	var resp api.TaskResponse
+	if err := c.get("/api/v1/tasks/"+url.PathEscape(name), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RunTask starts a task without waiting for it to finish
+func (c *Client) RunTask(name string) (*api.TaskResponse, error) {
+	var resp api.TaskResponse
+	if err := c.post("/api/v1/tasks/"+url.PathEscape(name)+"/run", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Reload makes the daemon re-read its config file and apply process changes
+func (c *Client) Reload() (*api.ReloadResponse, error) {
+	var resp api.ReloadResponse
+	if err := c.post("/api/v1/reload", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// TriggerDump writes a postmortem dump of the daemon's state
+func (c *Client) TriggerDump() (*api.DumpResponse, error) {
+	var resp api.DumpResponse
+	if err := c.post("/api/v1/debug/dump", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 // Shutdown shuts down the supervisor
 func (c *Client) Shutdown() error {
 	var resp api.SuccessResponse
@@ -120,12 +265,27 @@ func buildLogQueryParams(params domain.LogParams) url.Values {
 	if params.Lines > 0 {
 		query.Set("lines", fmt.Sprintf("%d", params.Lines))
 	}
-	if params.Pattern != "" {
-		query.Set("pattern", params.Pattern)
+	for _, pattern := range params.Patterns {
+		query.Add("pattern", pattern)
 	}
 	if params.Regex {
 		query.Set("regex", "true")
 	}
+	if params.Level != "" {
+		query.Set("level", params.Level)
+	}
+	if params.Stream != "" {
+		query.Set("stream", string(params.Stream))
+	}
+	if !params.Since.IsZero() {
+		query.Set("since", params.Since.Format(time.RFC3339Nano))
+	}
+	if !params.Until.IsZero() {
+		query.Set("until", params.Until.Format(time.RFC3339Nano))
+	}
+	if params.Backfill > 0 {
+		query.Set("backfill", fmt.Sprintf("%d", params.Backfill))
+	}
 	return query
 }
 
@@ -144,6 +304,9 @@ func buildProxyRequestQueryParams(params domain.ProxyRequestParams) url.Values {
 	if params.MaxStatus > 0 {
 		query.Set("max_status", fmt.Sprintf("%d", params.MaxStatus))
 	}
+	if !params.Until.IsZero() {
+		query.Set("until", params.Until.Format(time.RFC3339Nano))
+	}
 	if params.Limit > 0 {
 		query.Set("limit", fmt.Sprintf("%d", params.Limit))
 	}
@@ -166,6 +329,29 @@ func (c *Client) GetLogs(params domain.LogParams) (*api.LogsResponse, error) {
 	return &resp, nil
 }
 
+// GetProblems gets the problems found in the buffered logs of process, or of
+// every process if it is empty, since the given time if it isn't zero
+func (c *Client) GetProblems(process string, since time.Time) (*api.ProblemsResponse, error) {
+	query := url.Values{}
+	if process != "" {
+		query.Set("process", process)
+	}
+	if !since.IsZero() {
+		query.Set("since", since.Format(time.RFC3339Nano))
+	}
+
+	path := "/api/v1/problems"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var resp api.ProblemsResponse
+	if err := c.get(path, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 // GetProxyRequests gets recent proxy requests with optional filtering
 func (c *Client) GetProxyRequests(params domain.ProxyRequestParams) (*api.ProxyRequestsResponse, error) {
 	query := buildProxyRequestQueryParams(params)
@@ -196,6 +382,110 @@ func (c *Client) GetProxyRequest(id string, includeBody bool) (*api.ProxyRequest
 	return &resp, nil
 }
 
+// GetProxyStats gets rolling per-service latency stats
+func (c *Client) GetProxyStats() (*api.ProxyStatsResponse, error) {
+	var resp api.ProxyStatsResponse
+	if err := c.get("/api/v1/proxy/stats", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetProxyRules gets the dynamic proxy rules and their state
+func (c *Client) GetProxyRules() (*api.ProxyRulesResponse, error) {
+	var resp api.ProxyRulesResponse
+	if err := c.get("/api/v1/proxy/rules", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SetProxyRuleEnabled enables or disables a dynamic proxy rule
+func (c *Client) SetProxyRuleEnabled(id string, enabled bool) (*api.ProxyRuleResponse, error) {
+	action := "disable"
+	if enabled {
+		action = "enable"
+	}
+	var resp api.ProxyRuleResponse
+	if err := c.post("/api/v1/proxy/rules/"+url.PathEscape(id)+"/"+action, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetProxyRecording reports whether the proxy is recording requests
+func (c *Client) GetProxyRecording() (*api.ProxyRecordingResponse, error) {
+	var resp api.ProxyRecordingResponse
+	if err := c.get("/api/v1/proxy/recording", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SetProxyRecording pauses or resumes recording proxy requests
+func (c *Client) SetProxyRecording(enabled bool) (*api.ProxyRecordingResponse, error) {
+	body, err := json.Marshal(api.ProxyRecordingRequest{Enabled: &enabled})
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+	resp, err := c.send("POST", "/api/v1/proxy/recording", bytes.NewReader(body), "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result api.ProxyRecordingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &result, nil
+}
+
+// SaveProxySession writes the daemon's request history, with captured
+// bodies, to w as a session archive
+func (c *Client) SaveProxySession(w io.Writer) error {
+	resp, err := c.send("GET", "/api/v1/proxy/session", nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("reading session: %w", err)
+	}
+	return nil
+}
+
+// ExportProxyRequestsHAR writes the daemon's request history to w as an
+// HTTP Archive
+func (c *Client) ExportProxyRequestsHAR(w io.Writer) error {
+	resp, err := c.send("GET", "/api/v1/proxy/requests/export?format=har", nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("reading export: %w", err)
+	}
+	return nil
+}
+
+// LoadProxySession loads a session archive into the daemon's request history
+func (c *Client) LoadProxySession(r io.Reader) (*api.SessionLoadResponse, error) {
+	resp, err := c.send("POST", "/api/v1/proxy/session", r, "application/gzip")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result api.SessionLoadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &result, nil
+}
+
 // httpStatusError maps HTTP status codes to user-friendly error messages
 func httpStatusError(statusCode int, errResp *api.ErrorResponse) error {
 	if errResp != nil && errResp.Error != "" {
@@ -219,33 +509,48 @@ func httpStatusError(statusCode int, errResp *api.ErrorResponse) error {
 }
 
 func (c *Client) doRequest(method, path string, v interface{}) error {
-	req, err := http.NewRequest(method, c.baseURL+path, nil)
+	contentType := ""
+	if method == "POST" {
+		contentType = "application/json"
+	}
+	resp, err := c.send(method, path, nil, contentType)
 	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+		return err
 	}
-	if method == "POST" {
-		req.Header.Set("Content-Type", "application/json")
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// send performs a request and returns the response if it succeeded. The
+// caller must close the response body.
+func (c *Client) send(method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
 	}
 	c.addAuthHeader(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
 		var errResp api.ErrorResponse
 		if err := json.NewDecoder(resp.Body).Decode(&errResp); err == nil {
-			return httpStatusError(resp.StatusCode, &errResp)
+			return nil, httpStatusError(resp.StatusCode, &errResp)
 		}
-		return httpStatusError(resp.StatusCode, nil)
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
-		return fmt.Errorf("decoding response: %w", err)
+		return nil, httpStatusError(resp.StatusCode, nil)
 	}
-	return nil
+	return resp, nil
 }
 
 func (c *Client) get(path string, v interface{}) error {
@@ -285,6 +590,28 @@ func parseSSEProxyRequest(data string) (api.ProxyRequestResponse, bool) {
 	return req, true
 }
 
+// parseSSEProblem parses a single SSE data line into a problem.
+// Returns the parsed problem and true if successful, or an empty problem and false if parsing failed.
+func parseSSEProblem(data string) (api.ProblemResponse, bool) {
+	var problem api.ProblemResponse
+	if err := json.Unmarshal([]byte(data), &problem); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to parse SSE problem: %v\n", err)
+		return problem, false
+	}
+	return problem, true
+}
+
+// parseSSEEvent parses a single SSE data line into a supervisor event.
+// Returns the parsed event and true if successful, or an empty event and false if parsing failed.
+func parseSSEEvent(data string) (api.EventResponse, bool) {
+	var event api.EventResponse
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to parse SSE event: %v\n", err)
+		return event, false
+	}
+	return event, true
+}
+
 // streamSSE creates an SSE connection and returns a channel of parsed events.
 // The channel is closed when the connection ends or times out.
 func streamSSE[T any](req *http.Request, parse func(string) (T, bool)) (<-chan T, error) {
@@ -300,6 +627,7 @@ func streamSSE[T any](req *http.Request, parse func(string) (T, bool)) (<-chan T
 			conn, err = dialer.DialContext(ctx, network, addr)
 			return conn, err
 		},
+		TLSClientConfig: apiTLSConfig(),
 	}
 
 	client := &http.Client{
@@ -330,6 +658,9 @@ func streamSSE[T any](req *http.Request, parse func(string) (T, bool)) (<-chan T
 		}
 		reader := bufio.NewReader(bodyReader)
 
+		// Named events, such as the shutdown event ending a log stream,
+		// carry no item
+		event := ""
 		for {
 			line, err := reader.ReadString('\n')
 			if err != nil {
@@ -337,11 +668,19 @@ func streamSSE[T any](req *http.Request, parse func(string) (T, bool)) (<-chan T
 			}
 
 			line = strings.TrimSpace(line)
-			if line == "" || strings.HasPrefix(line, ":") {
+			if line == "" {
+				event = ""
+				continue
+			}
+			if strings.HasPrefix(line, ":") {
+				continue
+			}
+			if strings.HasPrefix(line, "event: ") {
+				event = strings.TrimPrefix(line, "event: ")
 				continue
 			}
 
-			if strings.HasPrefix(line, "data: ") {
+			if event == "" && strings.HasPrefix(line, "data: ") {
 				data := strings.TrimPrefix(line, "data: ")
 				if item, ok := parse(data); ok {
 					ch <- item
@@ -390,3 +729,47 @@ func (c *Client) StreamLogsChannel(params domain.LogParams) (<-chan api.LogEntry
 	c.addAuthHeader(req)
 	return streamSSE(req, parseSSELogEntry)
 }
+
+// StreamEventsChannel returns a channel that streams supervisor events via
+// SSE. The channel is closed when the connection ends or the read times
+// out, or after the backfill unless params.Follow is set.
+func (c *Client) StreamEventsChannel(params domain.EventParams) (<-chan api.EventResponse, error) {
+	query := url.Values{}
+	if params.Process != "" {
+		query.Set("process", params.Process)
+	}
+	if len(params.Types) > 0 {
+		query.Set("type", strings.Join(params.Types, ","))
+	}
+	if params.Backfill > 0 {
+		query.Set("backfill", fmt.Sprintf("%d", params.Backfill))
+	}
+	if !params.Follow {
+		query.Set("follow", "false")
+	}
+
+	req, err := http.NewRequest("GET", c.baseURL+"/api/v1/events?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	c.addAuthHeader(req)
+	return streamSSE(req, parseSSEEvent)
+}
+
+// StreamProblemsChannel returns a channel that streams problems via SSE.
+// The channel is closed when the connection ends or the read times out.
+func (c *Client) StreamProblemsChannel(process string) (<-chan api.ProblemResponse, error) {
+	path := "/api/v1/problems/stream"
+	if process != "" {
+		path += "?" + url.Values{"process": {process}}.Encode()
+	}
+
+	req, err := http.NewRequest("GET", c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	c.addAuthHeader(req)
+	return streamSSE(req, parseSSEProblem)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/charliek/prox/internal/api"
 	"github.com/charliek/prox/internal/domain"
@@ -210,6 +211,65 @@ func TestClient_RestartProcess(t *testing.T) {
 	}
 }
 
+func TestClient_RestartProcessBlueGreen(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/processes/api/restart" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("strategy") != "blue-green" {
+			t.Errorf("expected strategy=blue-green, got %q", r.URL.Query().Get("strategy"))
+		}
+		if r.Method != "POST" {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		called = true
+
+		resp := api.SuccessResponse{Success: true}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	err := client.RestartProcessBlueGreen("api")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !called {
+		t.Error("expected server to be called")
+	}
+}
+
+func TestClient_DrainProcess(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/processes/api/drain" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Method != "POST" {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		called = true
+
+		resp := api.SuccessResponse{Success: true}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	err := client.DrainProcess("api")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !called {
+		t.Error("expected server to be called")
+	}
+}
+
 func TestClient_Shutdown(t *testing.T) {
 	called := false
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -272,10 +332,10 @@ func TestClient_GetLogs(t *testing.T) {
 
 	client := NewClient(server.URL)
 	logs, err := client.GetLogs(domain.LogParams{
-		Process: "web",
-		Lines:   50,
-		Pattern: "error",
-		Regex:   true,
+		Process:  "web",
+		Lines:    50,
+		Patterns: []string{"error"},
+		Regex:    true,
 	})
 
 	if err != nil {
@@ -432,10 +492,10 @@ func TestBuildLogQueryParams(t *testing.T) {
 		{
 			name: "all params",
 			params: domain.LogParams{
-				Process: "api",
-				Lines:   100,
-				Pattern: "error",
-				Regex:   true,
+				Process:  "api",
+				Lines:    100,
+				Patterns: []string{"error"},
+				Regex:    true,
 			},
 			expected: map[string]string{
 				"process": "api",
@@ -454,11 +514,20 @@ func TestBuildLogQueryParams(t *testing.T) {
 				"process": "web",
 			},
 		},
+		{
+			name: "stream",
+			params: domain.LogParams{
+				Stream: domain.StreamStderr,
+			},
+			expected: map[string]string{
+				"stream": "stderr",
+			},
+		},
 		{
 			name: "regex false not included",
 			params: domain.LogParams{
-				Pattern: "test",
-				Regex:   false,
+				Patterns: []string{"test"},
+				Regex:    false,
 			},
 			expected: map[string]string{
 				"pattern": "test",
@@ -483,6 +552,13 @@ func TestBuildLogQueryParams(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("repeated patterns", func(t *testing.T) {
+		query := buildLogQueryParams(domain.LogParams{Patterns: []string{"timeout", "db"}})
+		if got := query["pattern"]; len(got) != 2 || got[0] != "timeout" || got[1] != "db" {
+			t.Errorf("expected pattern=timeout&pattern=db, got %v", got)
+		}
+	})
 }
 
 func TestBuildProxyRequestQueryParams(t *testing.T) {
@@ -531,6 +607,15 @@ func TestBuildProxyRequestQueryParams(t *testing.T) {
 				"limit":      "50",
 			},
 		},
+		{
+			name: "until",
+			params: domain.ProxyRequestParams{
+				Until: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+			},
+			expected: map[string]string{
+				"until": "2024-01-15T10:30:00Z",
+			},
+		},
 		{
 			name: "zero values not included",
 			params: domain.ProxyRequestParams{
@@ -629,6 +714,138 @@ func TestClient_GetProxyRequests(t *testing.T) {
 	}
 }
 
+func TestClient_GetProxyStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/proxy/stats" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+
+		resp := api.ProxyStatsResponse{
+			WindowSeconds: 300,
+			Services: []api.ServiceStatsResponse{
+				{Subdomain: "api", Count: 10, P95Ms: 450, BudgetMs: 300, OverBudget: true},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	resp, err := client.GetProxyStats()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.WindowSeconds != 300 {
+		t.Errorf("expected WindowSeconds 300, got %d", resp.WindowSeconds)
+	}
+	if len(resp.Services) != 1 {
+		t.Fatalf("expected 1 service, got %d", len(resp.Services))
+	}
+	if !resp.Services[0].OverBudget {
+		t.Error("expected service to be over budget")
+	}
+}
+
+func TestClient_GetProxyRules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/proxy/rules" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+
+		resp := api.ProxyRulesResponse{
+			Rules: []api.ProxyRuleResponse{
+				{ID: "rewrite:api", Kind: "rewrite", Service: "api", Enabled: true},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	resp, err := client.GetProxyRules()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Rules) != 1 {
+		t.Fatalf("expected 1 rule, got %d", len(resp.Rules))
+	}
+	if !resp.Rules[0].Enabled {
+		t.Error("expected rule to be enabled")
+	}
+}
+
+func TestClient_SetProxyRuleEnabled(t *testing.T) {
+	tests := []struct {
+		enabled  bool
+		wantPath string
+	}{
+		{true, "/api/v1/proxy/rules/rewrite:api/enable"},
+		{false, "/api/v1/proxy/rules/rewrite:api/disable"},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != tt.wantPath {
+				t.Errorf("unexpected path: %s", r.URL.Path)
+			}
+			if r.Method != "POST" {
+				t.Errorf("expected POST, got %s", r.Method)
+			}
+
+			resp := api.ProxyRuleResponse{ID: "rewrite:api", Enabled: tt.enabled}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(resp)
+		}))
+
+		client := NewClient(server.URL)
+		resp, err := client.SetProxyRuleEnabled("rewrite:api", tt.enabled)
+		server.Close()
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Enabled != tt.enabled {
+			t.Errorf("expected Enabled %v, got %v", tt.enabled, resp.Enabled)
+		}
+	}
+}
+
+func TestClient_SetProxyRecording(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v1/proxy/recording" {
+				t.Errorf("unexpected path: %s", r.URL.Path)
+			}
+			if r.Method != "POST" {
+				t.Errorf("expected POST, got %s", r.Method)
+			}
+			var req api.ProxyRecordingRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+				t.Errorf("expected an enabled field in the body")
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(api.ProxyRecordingResponse{Enabled: *req.Enabled})
+		}))
+
+		client := NewClient(server.URL)
+		resp, err := client.SetProxyRecording(enabled)
+		server.Close()
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Enabled != enabled {
+			t.Errorf("expected Enabled %v, got %v", enabled, resp.Enabled)
+		}
+	}
+}
+
 func TestParseSSEProxyRequest_ValidJSON(t *testing.T) {
 	data := `{"id":"a1b2c3d","timestamp":"2024-01-01T12:00:00Z","method":"GET","url":"/api/users","subdomain":"api","status_code":200,"duration_ms":45,"remote_addr":"127.0.0.1"}`
 
@@ -686,10 +903,10 @@ func TestClient_StreamLogsChannel_QueryParams(t *testing.T) {
 
 	client := NewClient(server.URL)
 	_, err := client.StreamLogsChannel(domain.LogParams{
-		Process: "web",
-		Lines:   50,
-		Pattern: "error",
-		Regex:   true,
+		Process:  "web",
+		Lines:    50,
+		Patterns: []string{"error"},
+		Regex:    true,
 	})
 
 	if err != nil {
@@ -713,3 +930,28 @@ func TestClient_StreamLogsChannel_QueryParams(t *testing.T) {
 		t.Errorf("expected regex=true in query, got %s", receivedQuery)
 	}
 }
+
+func TestClient_StreamLogsChannel_SkipsNamedEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(": connected\n\n"))
+		w.Write([]byte("data: {\"timestamp\":\"2024-01-01T00:00:00Z\",\"process\":\"web\",\"stream\":\"stdout\",\"line\":\"test\"}\n\n"))
+		w.Write([]byte("event: shutdown\ndata: {}\n\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	ch, err := client.StreamLogsChannel(domain.LogParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var lines []string
+	for entry := range ch {
+		lines = append(lines, entry.Line)
+	}
+	if len(lines) != 1 || lines[0] != "test" {
+		t.Errorf("expected only the log line, got %q", lines)
+	}
+}
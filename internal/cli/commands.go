@@ -1,23 +1,39 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/config"
 	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/daemon"
 	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/fsperm"
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/charliek/prox/internal/proxy"
+	"github.com/charliek/prox/internal/supervisor"
+	"github.com/charliek/prox/internal/theme"
 	"github.com/charliek/prox/internal/tui"
+	"github.com/charliek/prox/internal/watchdog"
 	"github.com/spf13/cobra"
 )
 
 // Status command flags
-var statusJSON bool
+var (
+	statusJSON    bool
+	statusDetail  bool
+	statusLastRun bool
+)
 
 // statusCmd represents the status command
 var statusCmd = &cobra.Command{
@@ -26,18 +42,32 @@ var statusCmd = &cobra.Command{
 	Long: `Show the status of all running processes.
 
 Displays process names, status, PIDs, uptime, restart counts, and health checks.
+With --detail, also shows goroutine counts and the stream goroutines
+(SSE subscribers, output readers, health checkers) tracked by the watchdog.
+With --last-run, shows the snapshot saved when the previous daemon shut down
+or crashed instead; prox doesn't need to be running.
 
 Examples:
-  prox status          # Show status in table format
-  prox status --json   # Output as JSON`,
+  prox status              # Show status in table format
+  prox status --detail     # Include runtime diagnostics
+  prox status --json       # Output as JSON
+  prox status --last-run   # Show how the previous run ended`,
 	RunE: runStatus,
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
+	if statusLastRun {
+		return runStatusLastRun()
+	}
+
 	client := NewClient(apiAddr)
 
 	// Get status
-	status, err := client.GetStatus()
+	getStatus := client.GetStatus
+	if statusDetail {
+		getStatus = client.GetStatusDetail
+	}
+	status, err := getStatus()
 	if err != nil {
 		return clientError(err, "Is prox running? Try 'prox up' first.")
 	}
@@ -60,9 +90,16 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	}
 
 	// Print status
-	fmt.Printf("Status: %s\n", status.Status)
-	fmt.Printf("Uptime: %s\n", formatDuration(time.Duration(status.UptimeSeconds)*time.Second))
+	if status.Asleep {
+		fmt.Printf("Status: %s (asleep until the next proxy request)\n", status.Status)
+	} else {
+		fmt.Printf("Status: %s\n", status.Status)
+	}
+	fmt.Printf("Uptime: %s\n", humanize.Duration(time.Duration(status.UptimeSeconds)*time.Second))
 	fmt.Printf("Config: %s\n", status.ConfigFile)
+	if status.Logs != nil {
+		fmt.Printf("Logs:   %s\n", logsUsage(status.Logs))
+	}
 	fmt.Println()
 
 	// Print processes table
@@ -71,70 +108,338 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	fmt.Fprintln(w, "----\t------\t---\t------\t--------\t------")
 
 	for _, p := range processes.Processes {
-		uptime := formatDuration(time.Duration(p.UptimeSeconds) * time.Second)
-		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\t%s\n",
-			p.Name, p.Status, p.PID, uptime, p.Restarts, p.Health)
+		uptime := humanize.Duration(time.Duration(p.UptimeSeconds) * time.Second)
+		status := p.Status
+		if p.Lazy {
+			status += " (lazy)"
+		}
+		restarts := strconv.Itoa(p.Restarts)
+		if p.RestartReason != "" {
+			restarts += " (" + p.RestartReason + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n",
+			p.Name, status, p.PID, uptime, restarts, p.Health)
+	}
+	w.Flush()
+
+	if len(status.Errors) > 0 {
+		printStartErrors(status.Errors)
+	}
+	if status.Detail != nil {
+		printStatusDetail(status.Detail)
+	}
+	return nil
+}
+
+// logsUsage describes how much of the log history is in use, e.g.
+// "812/1400 lines, 96 KiB of 64 MiB"
+func logsUsage(l *api.LogsStatusResponse) string {
+	usage := fmt.Sprintf("%d/%d lines, %s", l.Entries, l.BufferSize, humanize.Bytes(l.MemoryBytes))
+	if l.MaxMemoryBytes > 0 {
+		usage += " of " + humanize.Bytes(l.MaxMemoryBytes)
+	}
+	return usage
+}
+
+// printStartErrors prints why processes failed to start, which would
+// otherwise only appear in the daemon's output
+func printStartErrors(errs []api.StartErrorResponse) {
+	fmt.Println()
+	fmt.Println("Errors:")
+	for _, e := range errs {
+		when := ""
+		if t, err := time.Parse(time.RFC3339, e.Time); err == nil {
+			when = fmt.Sprintf(" (%s ago)", humanize.Duration(time.Since(t)))
+		}
+		fmt.Printf("  %s: failed to start%s: %s\n", e.Process, when, e.Error)
+	}
+}
+
+// runStatusLastRun prints the snapshot saved when the previous daemon ended
+func runStatusLastRun() error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	run, err := daemon.LoadLastRun(cwd)
+	if err != nil {
+		if err == daemon.ErrNoLastRun {
+			return fmt.Errorf("no last run recorded in %s", daemon.StateDir(cwd))
+		}
+		return err
+	}
+
+	if statusJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(run); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to encode output: %v\n", err)
+		}
+		return nil
+	}
+
+	fmt.Printf("Ended: %s (%s ago)\n", run.EndedAt.Local().Format(time.DateTime), humanize.Duration(time.Since(run.EndedAt)))
+	fmt.Printf("Reason: %s\n", run.Reason)
+	if !run.StartedAt.IsZero() {
+		fmt.Printf("Ran for: %s\n", humanize.Duration(run.EndedAt.Sub(run.StartedAt)))
+	}
+	fmt.Printf("Logs: %d/%d entries buffered, %d subscribers\n", run.Logs.Entries, run.Logs.BufferSize, run.Logs.Subscribers)
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tPID\tRESTARTS\tHEALTH")
+	fmt.Fprintln(w, "----\t------\t---\t--------\t------")
+	for _, p := range run.Processes {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", p.Name, p.Status, p.PID, p.Restarts, p.Health)
 	}
 	w.Flush()
+
+	var failed []daemon.LastRunProcess
+	for _, p := range run.Processes {
+		if p.LastError != "" {
+			failed = append(failed, p)
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Println()
+		fmt.Println("Errors:")
+		for _, p := range failed {
+			fmt.Printf("  %s: failed to start: %s\n", p.Name, p.LastError)
+		}
+	}
+
+	if run.Crashed() {
+		fmt.Println()
+		fmt.Println("Panic:")
+		fmt.Println(run.Panic)
+	}
 	return nil
 }
 
+// printStatusDetail prints runtime diagnostics from GET /status?detail=true
+func printStatusDetail(detail *api.StatusDetailResponse) {
+	fmt.Println()
+	fmt.Printf("Goroutines: %d\n", detail.Goroutines)
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STREAM\tACTIVE\tLEAKED")
+	fmt.Fprintln(w, "------\t------\t------")
+	for _, kind := range watchdog.Kinds {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", kind, detail.Watchdog.Active[string(kind)], detail.Watchdog.Leaked[string(kind)])
+	}
+	w.Flush()
+}
+
 // Logs command flags
 var (
-	logsFollow  bool
-	logsLines   int
-	logsProcess string
-	logsPattern string
-	logsRegex   bool
-	logsJSON    bool
+	logsFollow   bool
+	logsLines    int
+	logsProcess  string
+	logsPatterns []string
+	logsRegex    bool
+	logsLevel    string
+	logsStream   string
+	logsJSON     bool
+	logsOutput   string
+	logsSince    string
+	logsUntil    string
+
+	logsDistinctErrors bool
+)
+
+// Redaction flags (shared by logs, attach, and up)
+var (
+	redactOutput   bool
+	redactPatterns []string
 )
 
+// newRedactor returns a redactor for --redact display mode, or nil if it is
+// not enabled. Patterns come from the config file's redact section (when the
+// config can be loaded) plus any --redact-pattern flags.
+func newRedactor(cfg *config.Config) (*logs.Redactor, error) {
+	if !redactOutput {
+		return nil, nil
+	}
+	if cfg == nil {
+		cfg, _ = config.Load(configPath)
+	}
+	patterns := append(append([]string{}, cfg.RedactPatterns()...), redactPatterns...)
+	return logs.NewRedactor(patterns)
+}
+
+// addRedactFlags registers the --redact flags on a command
+func addRedactFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&redactOutput, "redact", false, "Mask emails, bearer tokens, IPs, and configured patterns in output")
+	cmd.Flags().StringArrayVar(&redactPatterns, "redact-pattern", nil, "Additional regex to mask with --redact (repeatable)")
+}
+
+// Time display flag (shared by logs and requests)
+var timeFlag string
+
+// addTimeFlag registers the --time flag on a command
+func addTimeFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&timeFlag, "time", "", "Show timestamps in local time, utc, or relative to now (default: the config's time, else local)")
+}
+
+// timeMode returns how timestamps are shown: --time if given, else the
+// config file's time setting (when the config can be loaded), else local time
+func timeMode() (humanize.TimeMode, error) {
+	if timeFlag != "" {
+		mode, err := humanize.ParseTimeMode(timeFlag)
+		if err != nil {
+			return "", fmt.Errorf("invalid --time: %w", err)
+		}
+		return mode, nil
+	}
+	cfg, _ := config.Load(configPath)
+	return cfg.TimeMode(), nil
+}
+
+// outputTheme returns the config file's theme (when the config can be
+// loaded), else the default theme
+func outputTheme() *theme.Theme {
+	cfg, _ := config.Load(configPath)
+	return cfg.OutputTheme()
+}
+
+// colorOutput reports whether output is colored: when stdout is a terminal
+// and the theme doesn't turn colors off
+func colorOutput() bool {
+	return isTerminal() && outputTheme().Colored()
+}
+
 // logsCmd represents the logs command
 var logsCmd = &cobra.Command{
 	Use:   "logs [process]",
 	Short: "Show recent logs",
 	Long: `Show recent logs from all or specific processes.
 
-Logs can be filtered by process name, pattern, or regex. Use -f to stream
-logs continuously.
+Logs can be filtered by process name, pattern, or regex, and by a time
+range with --since and --until. Repeat --pattern to show only lines matching
+every pattern. --level filters by the level parsed from processes with a
+log_format, and --stream by the output stream. Use -f to stream logs
+continuously.
 
 Examples:
   prox logs                    # All logs
   prox logs web                # Logs from web process
   prox logs -f                 # Stream logs continuously
+  prox logs -f -n 100 web      # Last 100 lines from web, then stream
+  prox logs -f -o jsonl        # Stream logs as one JSON object per line
   prox logs --process web -n 50 # Last 50 lines from web
   prox logs --pattern error    # Filter by pattern
-  prox logs --pattern "err.*" --regex  # Filter by regex`,
+  prox logs --pattern "err.*" --regex  # Filter by regex
+  prox logs --level warn       # Warnings and errors from processes with a log_format
+  prox logs web --stream stderr # Only what web writes to stderr
+  prox logs web --since 10m    # Logs from web in the last 10 minutes
+  prox logs --since 14:02 --until 14:05 --pattern timeout --pattern db  # A failure window
+  prox logs --distinct-errors  # Summarize errors from the last hour
+  prox logs --redact           # Mask emails, tokens, and IPs for screen sharing
+  prox logs --time relative    # Show how long ago each line was logged`,
 	Args:              cobra.MaximumNArgs(1),
 	RunE:              runLogs,
 	ValidArgsFunction: completeProcessNames,
 }
 
 func runLogs(cmd *cobra.Command, args []string) error {
+	if err := checkOutput(logsOutput, logsJSON); err != nil {
+		return err
+	}
 	params := domain.LogParams{
-		Lines:   logsLines,
-		Process: logsProcess,
-		Pattern: logsPattern,
-		Regex:   logsRegex,
+		Lines:    logsLines,
+		Process:  logsProcess,
+		Patterns: logsPatterns,
+		Regex:    logsRegex,
 	}
+	if logsLevel != "" {
+		params.Level = domain.NormalizeLogLevel(logsLevel)
+		if params.Level == "" {
+			return fmt.Errorf("invalid --level %q (want one of %s)", logsLevel, strings.Join(domain.LogLevels, ", "))
+		}
+	}
+	stream, err := domain.ParseStreamFilter(logsStream)
+	if err != nil {
+		return fmt.Errorf("invalid --stream: %w", err)
+	}
+	params.Stream = stream
 
 	// If a positional argument is provided, use it as the process filter
 	if len(args) > 0 && params.Process == "" {
 		params.Process = args[0]
 	}
 
+	since, until := logsSince, logsUntil
+	if logsDistinctErrors {
+		if logsFollow {
+			return fmt.Errorf("--distinct-errors cannot be used with --follow")
+		}
+		if logsOutput == outputJSONL {
+			return fmt.Errorf("--distinct-errors cannot be used with --output %s", outputJSONL)
+		}
+		// Summarize the last hour by default, over as many lines as allowed
+		if since == "" && until == "" {
+			since = distinctErrorsWindow.String()
+		}
+		if !cmd.Flags().Changed("lines") {
+			params.Lines = constants.MaxLogLines
+		}
+	}
+
+	if since != "" || until != "" {
+		if logsFollow {
+			return fmt.Errorf("--since and --until cannot be used with --follow")
+		}
+		now := time.Now()
+		if since != "" {
+			t, err := parseAtTime(since, now)
+			if err != nil {
+				return err
+			}
+			params.Since = t
+		}
+		if until != "" {
+			t, err := parseAtTime(until, now)
+			if err != nil {
+				return err
+			}
+			params.Until = t
+		}
+	}
+	if _, err := params.Filter().Compile(); err != nil {
+		return err
+	}
+
+	redactor, err := newRedactor(nil)
+	if err != nil {
+		return err
+	}
+	mode, err := timeMode()
+	if err != nil {
+		return err
+	}
+
 	client := NewClient(apiAddr)
 
 	printer := NewLogPrinter()
+	printer.SetRedactor(redactor)
+	printer.SetTimeMode(mode)
+	printer.SetTheme(outputTheme())
 
 	if logsFollow {
-		// Stream logs via channel
+		// Stream logs via channel, starting with the last -n lines if given
+		if cmd.Flags().Changed("lines") {
+			params.Backfill = params.Lines
+		}
 		ch, err := client.StreamLogsChannel(params)
 		if err != nil {
 			return clientError(err, "Is prox running? Try 'prox up' first.")
 		}
 		for entry := range ch {
-			if logsJSON {
+			if logsOutput == outputJSONL {
+				entry.Line = redactor.Redact(entry.Line)
+				writeJSONL(os.Stdout, api.NewJSONLLog(entry))
+			} else if logsJSON {
+				entry.Line = redactor.Redact(entry.Line)
 				if err := json.NewEncoder(os.Stdout).Encode(entry); err != nil {
 					fmt.Fprintf(os.Stderr, "Warning: failed to encode log entry: %v\n", err)
 				}
@@ -149,7 +454,17 @@ func runLogs(cmd *cobra.Command, args []string) error {
 			return clientError(err, "Is prox running? Try 'prox up' first.")
 		}
 
-		if logsJSON {
+		if logsDistinctErrors {
+			printDistinctErrors(logs.Logs, redactor)
+		} else if logsOutput == outputJSONL {
+			for _, entry := range logs.Logs {
+				entry.Line = redactor.Redact(entry.Line)
+				writeJSONL(os.Stdout, api.NewJSONLLog(entry))
+			}
+		} else if logsJSON {
+			for i := range logs.Logs {
+				logs.Logs[i].Line = redactor.Redact(logs.Logs[i].Line)
+			}
 			if err := json.NewEncoder(os.Stdout).Encode(logs); err != nil {
 				fmt.Fprintf(os.Stderr, "Warning: failed to encode logs: %v\n", err)
 			}
@@ -161,10 +476,64 @@ func runLogs(cmd *cobra.Command, args []string) error {
 				fmt.Printf("\n(showing %d of %d entries)\n", logs.FilteredCount, logs.TotalCount)
 			}
 		}
+		if logs.Truncated {
+			fmt.Fprintln(os.Stderr, "Warning: query budget exceeded, results are partial. Narrow the search with --process, --since, or --until.")
+		}
 	}
 	return nil
 }
 
+// distinctErrorsWindow is how far back --distinct-errors looks without --since
+const distinctErrorsWindow = time.Hour
+
+// printDistinctErrors prints error lines grouped into clusters of repeats,
+// most frequent first
+func printDistinctErrors(entries []api.LogEntryResponse, redactor *logs.Redactor) {
+	domainEntries := make([]domain.LogEntry, 0, len(entries))
+	for _, e := range entries {
+		ts, _ := time.Parse(time.RFC3339Nano, e.Timestamp)
+		domainEntries = append(domainEntries, domain.LogEntry{
+			Timestamp: ts,
+			Process:   e.Process,
+			Stream:    domain.Stream(e.Stream),
+			Line:      e.Line,
+		})
+	}
+	clusters := logs.ClusterErrors(domainEntries)
+	for i := range clusters {
+		clusters[i].Example = redactor.Redact(clusters[i].Example)
+		clusters[i].Signature = redactor.Redact(clusters[i].Signature)
+	}
+
+	if logsJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(map[string]interface{}{"clusters": clusters}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to encode error clusters: %v\n", err)
+		}
+		return
+	}
+
+	if len(clusters) == 0 {
+		fmt.Println("No errors found.")
+		return
+	}
+
+	total := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COUNT\tPROCESSES\tFIRST\tLAST\tERROR")
+	fmt.Fprintln(w, "-----\t---------\t-----\t----\t-----")
+	for _, c := range clusters {
+		total += c.Count
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+			c.Count,
+			strings.Join(c.Processes, ","),
+			c.First.Local().Format("15:04:05"),
+			c.Last.Local().Format("15:04:05"),
+			c.Example)
+	}
+	w.Flush()
+	fmt.Printf("\n%s in %s\n", humanize.Count(total, "error line", "error lines"), humanize.Count(len(clusters), "distinct error", "distinct errors"))
+}
+
 // stopCmd represents the stop command
 var stopCmd = &cobra.Command{
 	Use:   "stop [process]",
@@ -255,18 +624,34 @@ var restartCmd = &cobra.Command{
 
 The process will be stopped and then started again.
 
+With --blue-green, a process using "port: auto" is restarted without downtime:
+a second instance is started on a new port, and once it passes its
+healthcheck the proxy switches to it and the old instance is stopped.
+
 Examples:
   prox restart web
-  prox restart worker`,
+  prox restart worker
+  prox restart api --blue-green`,
 	Args:              cobra.ExactArgs(1),
 	RunE:              runRestart,
 	ValidArgsFunction: completeProcessNames,
 }
 
+// Restart command flags
+var restartBlueGreen bool
+
 func runRestart(cmd *cobra.Command, args []string) error {
 	processName := args[0]
 	client := NewClient(apiAddr)
 
+	if restartBlueGreen {
+		if err := client.RestartProcessBlueGreen(processName); err != nil {
+			return clientError(err, "Is prox running? Try 'prox up' first.")
+		}
+		fmt.Printf("Restarted process (blue-green): %s\n", processName)
+		return nil
+	}
+
 	if err := client.RestartProcess(processName); err != nil {
 		return clientError(err, "Is prox running? Try 'prox up' first.")
 	}
@@ -275,26 +660,186 @@ func runRestart(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// drainCmd represents the drain command
+var drainCmd = &cobra.Command{
+	Use:   "drain <process>",
+	Short: "Drain proxy traffic from a process, then stop it",
+	Long: `Drain proxy traffic from a process, then stop it.
+
+New proxy requests to the process's services receive 503 Service Unavailable
+while in-flight requests finish. Once they complete, the process is stopped.
+Routing resumes automatically when the process is started again.
+
+The process must serve at least one proxy service (services.<name>.process).
+
+Examples:
+  prox drain api
+  prox drain api && prox start api`,
+	Args:              cobra.ExactArgs(1),
+	RunE:              runDrain,
+	ValidArgsFunction: completeProcessNames,
+}
+
+func runDrain(cmd *cobra.Command, args []string) error {
+	processName := args[0]
+	client := NewClient(apiAddr)
+
+	if err := client.DrainProcess(processName); err != nil {
+		return clientError(err, "Is prox running with proxy enabled? Try 'prox up' first.")
+	}
+
+	fmt.Printf("Drained and stopped process: %s\n", processName)
+	return nil
+}
+
+// signalCmd represents the signal command
+var signalCmd = &cobra.Command{
+	Use:   "signal <process> <signal>",
+	Short: "Send a signal to a running process",
+	Long: `Send a signal to a running process without stopping it.
+
+Use it to trigger a process's own reload or debug handling, such as a config
+reload on SIGHUP or a live reload on SIGUSR2. The signal goes to the process's
+whole process group, like the signals prox sends to stop it. A process that
+doesn't handle the signal may exit; prox then treats it as a crash.
+
+The signal may be given as SIGUSR2, USR2, or its number.
+
+Examples:
+  prox signal web SIGUSR2
+  prox signal nginx hup`,
+	Args:              cobra.ExactArgs(2),
+	RunE:              runSignal,
+	ValidArgsFunction: completeSignalArgs,
+}
+
+// completeSignalArgs completes the process name, then the signal name
+func completeSignalArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return completeProcessNames(cmd, args, toComplete)
+	}
+	if len(args) == 1 {
+		return supervisor.SignalNames(), cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+func runSignal(cmd *cobra.Command, args []string) error {
+	processName := args[0]
+	sig, err := supervisor.ParseSignal(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid signal %q (want one of %s)", args[1], strings.Join(supervisor.SignalNames(), ", "))
+	}
+	name := supervisor.SignalName(sig)
+
+	client := NewClient(apiAddr)
+	if err := client.SignalProcess(processName, name); err != nil {
+		return clientError(err, "Is prox running? Try 'prox up' first.")
+	}
+
+	fmt.Printf("Sent %s to process: %s\n", name, processName)
+	return nil
+}
+
+// reloadCmd represents the reload command
+var reloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Reload the config file of the running instance",
+	Long: `Make the running prox instance re-read its config file.
+
+Process definitions are compared with the running ones: added processes are
+started, removed processes are stopped, and changed processes are restarted
+(or just updated, if they were stopped). Unchanged processes keep running.
+Changes to other sections, such as api or proxy, take effect when prox
+restarts. An invalid config file is rejected and nothing changes.
+
+Sending SIGHUP to the prox process does the same.
+
+Examples:
+  prox reload`,
+	Args: cobra.NoArgs,
+	RunE: runReload,
+}
+
+func runReload(cmd *cobra.Command, args []string) error {
+	client := NewClient(apiAddr)
+
+	resp, err := client.Reload()
+	if err != nil {
+		return clientError(err, "Is prox running? Try 'prox up' first.")
+	}
+
+	if len(resp.Added)+len(resp.Removed)+len(resp.Changed) == 0 {
+		fmt.Println("Config reloaded, no process changes")
+	} else {
+		fmt.Println("Config reloaded")
+		printReloadedProcesses("Added", resp.Added)
+		printReloadedProcesses("Removed", resp.Removed)
+		printReloadedProcesses("Changed", resp.Changed)
+	}
+	names := make([]string, 0, len(resp.Failed))
+	for name := range resp.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  Failed to start %s: %s\n", name, resp.Failed[name])
+	}
+	if len(resp.Ignored) > 0 {
+		fmt.Printf("Changes to %s take effect when prox restarts\n", strings.Join(resp.Ignored, ", "))
+	}
+	return nil
+}
+
+// printReloadedProcesses prints one line of a reload summary
+func printReloadedProcesses(label string, names []string) {
+	if len(names) > 0 {
+		fmt.Printf("  %-8s %s\n", label+":", strings.Join(names, ", "))
+	}
+}
+
 // attachCmd represents the attach command
 var attachCmd = &cobra.Command{
-	Use:   "attach",
-	Short: "Attach TUI to running daemon",
+	Use:   "attach [process]",
+	Short: "Attach TUI to running daemon, or a terminal to a process",
 	Long: `Attach the interactive TUI to a running prox daemon.
 
 This allows you to monitor and interact with processes started with
 'prox up -d' (daemon mode).
 
+With a process name, connects the terminal to that process instead: what you
+type is sent to its stdin, and its output is printed as it is written, for
+REPL-style dev servers. The process must have stdin: true in the config.
+Ctrl-D or Ctrl-C detaches, leaving the process running.
+
 Examples:
-  prox attach`,
-	RunE: runAttach,
+  prox attach
+  prox attach --redact   # Mask sensitive values for screen sharing
+  prox attach shell      # Type into the shell process`,
+	Args:              cobra.MaximumNArgs(1),
+	RunE:              runAttach,
+	ValidArgsFunction: completeProcessNames,
+}
+
+// loadTUIPrefs loads the project's TUI preferences. An unreadable file only
+// warns, since the TUI works without them; it is replaced on the next change.
+func loadTUIPrefs(dir string) *tui.Preferences {
+	prefs, err := tui.LoadPreferences(daemon.TUIPrefsPath(dir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return prefs
 }
 
 func runAttach(cmd *cobra.Command, args []string) error {
-	// Get working directory
+	// Get the project directory: --project, or the working directory
 	cwd, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("failed to get working directory: %w", err)
 	}
+	if projectDir != "" {
+		cwd = projectDir
+	}
 
 	// Check if daemon is running
 	state, err := daemon.GetRunningState(cwd)
@@ -308,7 +853,7 @@ func runAttach(cmd *cobra.Command, args []string) error {
 	// Use discovered API address or explicitly set one
 	addr := apiAddr
 	if !apiAddrExplicitlySet {
-		addr = fmt.Sprintf("http://%s:%d", state.Host, state.Port)
+		addr = stateURL(state)
 	}
 
 	// Create client
@@ -320,8 +865,21 @@ func runAttach(cmd *cobra.Command, args []string) error {
 		return clientError(err, "Is prox running? Try 'prox up -d' first.")
 	}
 
+	if len(args) == 1 {
+		return attachProcess(client, args[0])
+	}
+
+	redactor, err := newRedactor(nil)
+	if err != nil {
+		return err
+	}
+
+	// Use the instance's theme, from the config it was started with
+	cfg, _ := config.Load(state.ConfigFile)
+	tui.SetTheme(cfg.OutputTheme())
+
 	// Run TUI in client mode
-	if err := tui.RunClient(client); err != nil {
+	if err := tui.RunClient(client, redactor, loadTUIPrefs(cwd)); err != nil {
 		return fmt.Errorf("TUI error: %w", err)
 	}
 	return nil
@@ -335,7 +893,12 @@ var (
 	requestsMinStatus int
 	requestsLimit     int
 	requestsJSON      bool
+	requestsOutput    string
 	requestsBody      bool
+	requestsAt        string
+	requestsColumns   string
+	requestsSort      string
+	requestsSave      bool
 )
 
 // requestsCmd represents the requests command
@@ -354,18 +917,37 @@ Examples:
   prox requests --method GET       # Filter by HTTP method
   prox requests --min-status 400   # Show errors only (4xx and 5xx)
   prox requests --json             # Output as JSON
+  prox requests -f -o jsonl        # Stream requests as one JSON object per line
+  prox requests --at 14:32:05      # Show requests up to a point in time
+  prox requests --at 10m           # Show requests up to 10 minutes ago
+  prox requests --time utc         # Show timestamps in UTC
+  prox requests --sort duration    # Slowest requests first
+  prox requests --columns time,status,subdomain,url,bytes --save-defaults  # Keep a custom table
   prox requests abc1234            # Show details for request abc1234
-  prox requests abc1234 --body     # Include captured request/response bodies`,
+  prox requests abc1234 --body     # Include captured request/response bodies
+  prox requests show abc1234 --body # Pretty-print and page the bodies
+  prox requests stats              # Show per-service latency stats
+  prox requests save s.proxsession # Save the history for a teammate
+  prox requests load s.proxsession # Load a teammate's saved history
+  prox requests pause              # Stop recording, e.g. during a load test`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runRequests,
 }
 
 func runRequests(cmd *cobra.Command, args []string) error {
 	client := NewClient(apiAddr)
+	mode, err := timeMode()
+	if err != nil {
+		return err
+	}
 
 	// If an ID is provided, show request details
 	if len(args) > 0 {
-		return showRequestDetail(client, args[0], requestsBody, requestsJSON)
+		return showRequestDetail(client, args[0], requestsBody, requestsJSON, false, mode)
+	}
+
+	if err := checkOutput(requestsOutput, requestsJSON); err != nil {
+		return err
 	}
 
 	// Validate min-status is within valid HTTP status code range
@@ -380,19 +962,43 @@ func runRequests(cmd *cobra.Command, args []string) error {
 		Limit:     requestsLimit,
 	}
 
+	if requestsAt != "" {
+		if requestsFollow {
+			return fmt.Errorf("--at cannot be used with --follow")
+		}
+		at, err := parseAtTime(requestsAt, time.Now())
+		if err != nil {
+			return err
+		}
+		params.Until = at
+	}
+
+	layout, err := requestsLayout(cmd)
+	if err != nil {
+		return err
+	}
+	color := colorOutput()
+
 	if requestsFollow {
 		// Stream requests via SSE
 		ch, err := client.StreamProxyRequestsChannel(params)
 		if err != nil {
 			return clientError(err, "Is prox running with proxy enabled? Try 'prox up' first.")
 		}
+		if layout.custom && requestsOutput != outputJSONL && !requestsJSON {
+			fmt.Println(formatRequestRow(nil, layout.columns, mode, false))
+		}
 		for req := range ch {
-			if requestsJSON {
+			if requestsOutput == outputJSONL {
+				writeJSONL(os.Stdout, api.NewJSONLRequest(req))
+			} else if requestsJSON {
 				if err := json.NewEncoder(os.Stdout).Encode(req); err != nil {
 					fmt.Fprintf(os.Stderr, "Warning: failed to encode request: %v\n", err)
 				}
+			} else if layout.custom {
+				fmt.Println(formatRequestRow(&req, layout.columns, mode, color))
 			} else {
-				printProxyRequest(req)
+				printProxyRequest(req, mode, color)
 			}
 		}
 	} else {
@@ -401,8 +1007,13 @@ func runRequests(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return clientError(err, "Is prox running with proxy enabled? Try 'prox up' first.")
 		}
+		sortRequests(resp.Requests, layout.sort)
 
-		if requestsJSON {
+		if requestsOutput == outputJSONL {
+			for _, req := range resp.Requests {
+				writeJSONL(os.Stdout, api.NewJSONLRequest(req))
+			}
+		} else if requestsJSON {
 			if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
 				fmt.Fprintf(os.Stderr, "Warning: failed to encode requests: %v\n", err)
 			}
@@ -412,17 +1023,11 @@ func runRequests(cmd *cobra.Command, args []string) error {
 				return nil
 			}
 
-			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-			fmt.Fprintln(w, "ID\tTIME\tMETHOD\tSTATUS\tDURATION\tURL")
-			fmt.Fprintln(w, "-------\t--------\t------\t------\t--------\t---")
-
-			for _, req := range resp.Requests {
-				ts, _ := time.Parse(time.RFC3339Nano, req.Timestamp)
-				timeStr := ts.Format("15:04:05")
-				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%dms\t%s\n",
-					req.ID, timeStr, req.Method, req.StatusCode, req.DurationMs, req.URL)
+			if !params.Until.IsZero() {
+				fmt.Printf("Requests at or before %s\n\n", params.Until.Format("2006-01-02 15:04:05"))
 			}
-			w.Flush()
+
+			writeRequestsTable(os.Stdout, resp.Requests, layout.columns, mode)
 
 			if resp.FilteredCount < resp.TotalCount {
 				fmt.Printf("\n(showing %d of %d requests)\n", resp.FilteredCount, resp.TotalCount)
@@ -432,8 +1037,373 @@ func runRequests(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// showRequestDetail displays details for a specific request
-func showRequestDetail(client *Client, id string, includeBody, jsonOutput bool) error {
+// requestsTableLayout is the columns and order of the prox requests table
+type requestsTableLayout struct {
+	columns []string
+	sort    string
+	custom  bool // Columns were chosen with --columns or saved defaults
+}
+
+// requestsLayout resolves the requests table's columns and order from
+// --columns and --sort, falling back to the project's saved defaults. With
+// --save-defaults it saves the flags given as the new defaults, or clears
+// them when neither is given.
+func requestsLayout(cmd *cobra.Command) (requestsTableLayout, error) {
+	layout := requestsTableLayout{columns: defaultRequestColumns, sort: requestSortTime}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return layout, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	prefs, err := loadCLIPrefs(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	columnsSet := cmd.Flags().Changed("columns")
+	sortSet := cmd.Flags().Changed("sort")
+	if requestsSave {
+		prefs.RequestColumns, prefs.RequestSort = nil, ""
+	}
+
+	if columnsSet {
+		columns, err := parseRequestColumns(requestsColumns)
+		if err != nil {
+			return layout, fmt.Errorf("invalid --columns: %w", err)
+		}
+		layout.columns, layout.custom = columns, true
+		if requestsSave {
+			prefs.RequestColumns = columns
+		}
+	} else if len(prefs.RequestColumns) > 0 {
+		// Saved columns were checked when saved, but the file may be edited
+		if columns, err := parseRequestColumns(strings.Join(prefs.RequestColumns, ",")); err == nil {
+			layout.columns, layout.custom = columns, true
+		}
+	}
+
+	if sortSet {
+		if requestsFollow {
+			return layout, fmt.Errorf("--sort cannot be used with --follow")
+		}
+		if err := checkRequestSort(requestsSort); err != nil {
+			return layout, err
+		}
+		layout.sort = requestsSort
+		if requestsSave {
+			prefs.RequestSort = requestsSort
+		}
+	} else if prefs.RequestSort != "" && !requestsFollow && checkRequestSort(prefs.RequestSort) == nil {
+		layout.sort = prefs.RequestSort
+	}
+
+	if requestsSave {
+		if err := prefs.save(cwd); err != nil {
+			return layout, err
+		}
+		if columnsSet || sortSet {
+			fmt.Fprintf(os.Stderr, "Saved as the defaults for this project in %s\n", daemon.CLIPrefsPath(cwd))
+		} else {
+			fmt.Fprintln(os.Stderr, "Cleared this project's saved --columns and --sort defaults")
+		}
+	}
+	return layout, nil
+}
+
+// Requests stats command flags
+var requestsStatsJSON bool
+
+// requestsStatsCmd represents the requests stats command
+var requestsStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show per-service latency stats",
+	Long: `Show rolling latency percentiles and error counts for each proxied service.
+
+Services with a latency budget (slo.p95 in the config) are flagged when their
+rolling p95 exceeds the budget.
+
+Examples:
+  prox requests stats          # Show stats in table format
+  prox requests stats --json   # Output as JSON`,
+	Args: cobra.NoArgs,
+	RunE: runRequestsStats,
+}
+
+func runRequestsStats(cmd *cobra.Command, args []string) error {
+	client := NewClient(apiAddr)
+
+	resp, err := client.GetProxyStats()
+	if err != nil {
+		return clientError(err, "Is prox running with proxy enabled? Try 'prox up' first.")
+	}
+
+	if requestsStatsJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to encode stats: %v\n", err)
+		}
+		return nil
+	}
+
+	if len(resp.Services) == 0 {
+		fmt.Println("No proxy requests recorded")
+		return nil
+	}
+
+	fmt.Printf("Window: last %s\n\n", humanize.Duration(time.Duration(resp.WindowSeconds)*time.Second))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tREQUESTS\tERRORS\tSCHEMA\tP50\tP95\tP99\tBUDGET\tSLO")
+	fmt.Fprintln(w, "-------\t--------\t------\t------\t---\t---\t---\t------\t---")
+
+	for _, svc := range resp.Services {
+		budget := "-"
+		slo := "-"
+		if svc.BudgetMs > 0 {
+			budget = humanMs(svc.BudgetMs)
+			slo = "ok"
+			if svc.OverBudget {
+				slo = "OVER BUDGET"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			svc.Subdomain, svc.Count, svc.Errors, svc.SchemaViolations, humanMs(svc.P50Ms), humanMs(svc.P95Ms), humanMs(svc.P99Ms), budget, slo)
+	}
+	w.Flush()
+
+	printed := false
+	for _, svc := range resp.Services {
+		for _, event := range svc.Events {
+			if !printed {
+				fmt.Println("\nProcess events:")
+				printed = true
+			}
+			fmt.Println("  " + formatProcessEvent(svc.Subdomain, event))
+		}
+	}
+	return nil
+}
+
+// formatProcessEvent describes a process event in a service's stats window,
+// with the errors that followed it, e.g.
+// "api: 14:02:11 api crashed, 12 errors in the next 30s"
+func formatProcessEvent(subdomain string, event api.ProcessEventResponse) string {
+	at := event.Timestamp
+	if t, err := time.Parse(time.RFC3339Nano, event.Timestamp); err == nil {
+		at = t.Local().Format("15:04:05")
+	}
+	errors := "no errors"
+	if event.ErrorsAfter > 0 {
+		errors = humanize.Count(event.ErrorsAfter, "error", "errors")
+	}
+	return fmt.Sprintf("%s: %s %s %s, %s in the next %s",
+		subdomain, at, event.Process, event.Type, errors, humanize.Duration(constants.ProxyStatsEventErrorWindow))
+}
+
+// requestsSaveCmd represents the requests save command
+var requestsSaveCmd = &cobra.Command{
+	Use:   "save <file>",
+	Short: "Save the request history to a session file",
+	Long: `Save the proxy request history, including captured headers and bodies,
+to a portable session file. Load it into another prox instance with
+'prox requests load' so a teammate can inspect the exact traffic.
+
+Session files contain everything that was captured, which may include
+credentials in headers or bodies.
+
+Examples:
+  prox requests save bug-1234.proxsession`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRequestsSave,
+}
+
+func runRequestsSave(cmd *cobra.Command, args []string) error {
+	path := args[0]
+	client := NewClient(apiAddr)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fsperm.File())
+	if err != nil {
+		return fmt.Errorf("creating session file: %w", err)
+	}
+	if err := client.SaveProxySession(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return clientError(err, "Is prox running with proxy enabled? Try 'prox up' first.")
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing session file: %w", err)
+	}
+
+	fmt.Printf("Saved request session to %s\n", path)
+	return nil
+}
+
+// requestsLoadCmd represents the requests load command
+var requestsLoadCmd = &cobra.Command{
+	Use:   "load <file>",
+	Short: "Load a saved session into the request history",
+	Long: `Load a session file saved with 'prox requests save' into the running
+instance's request history. Loaded requests are marked as imported and can be
+inspected like any other request; nothing is sent to your services.
+
+Requests already in the history are skipped, so loading a file twice is
+harmless. Loaded requests take up room in the request buffer like live ones.
+
+Examples:
+  prox requests load bug-1234.proxsession
+  prox requests                  # Loaded requests are marked [imported]`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRequestsLoad,
+}
+
+func runRequestsLoad(cmd *cobra.Command, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("opening session file: %w", err)
+	}
+	defer f.Close()
+
+	client := NewClient(apiAddr)
+	resp, err := client.LoadProxySession(f)
+	if err != nil {
+		return clientError(err, "Is prox running with proxy enabled? Try 'prox up' first.")
+	}
+
+	fmt.Printf("Loaded %d requests from session saved %s", resp.Imported, resp.SavedAt)
+	if resp.Skipped > 0 {
+		fmt.Printf(" (%d already present)", resp.Skipped)
+	}
+	fmt.Println()
+	return nil
+}
+
+// Requests export command flags
+// Requests show command flags
+var (
+	requestsShowBody    bool
+	requestsShowJSON    bool
+	requestsShowNoPager bool
+)
+
+// requestsShowCmd represents the requests show command
+var requestsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a request with pretty-printed bodies",
+	Long: `Show the details of a proxy request. With --body, captured bodies are
+pretty-printed: JSON and XML are indented and highlighted, form bodies are
+listed one field per line, and binary bodies are shown as a hexdump of their
+first 256 bytes.
+
+On a terminal the output is paged with $PAGER (less by default).
+
+Examples:
+  prox requests show abc1234            # Show headers
+  prox requests show abc1234 --body     # Include pretty-printed bodies
+  prox requests show abc1234 --no-pager # Print without paging`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := timeMode()
+		if err != nil {
+			return err
+		}
+		return showRequestDetail(NewClient(apiAddr), args[0], requestsShowBody, requestsShowJSON, !requestsShowNoPager, mode)
+	},
+}
+
+var requestsExportHAR string
+
+// requestsExportCmd represents the requests export command
+var requestsExportCmd = &cobra.Command{
+	Use:   "export --har <file>",
+	Short: "Export the request history as a HAR file",
+	Long: `Export the proxy request history as an HTTP Archive (HAR) file, which
+browser dev tools and other HTTP tools can import. Captured headers and bodies
+are included when capture is enabled.
+
+Like session files, HAR files contain everything that was captured, which may
+include credentials in headers or bodies.
+
+Examples:
+  prox requests export --har bug-1234.har`,
+	Args: cobra.NoArgs,
+	RunE: runRequestsExport,
+}
+
+func runRequestsExport(cmd *cobra.Command, args []string) error {
+	path := requestsExportHAR
+	if path == "" {
+		return fmt.Errorf("--har <file> is required")
+	}
+	client := NewClient(apiAddr)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fsperm.File())
+	if err != nil {
+		return fmt.Errorf("creating HAR file: %w", err)
+	}
+	if err := client.ExportProxyRequestsHAR(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return clientError(err, "Is prox running with proxy enabled? Try 'prox up' first.")
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing HAR file: %w", err)
+	}
+
+	fmt.Printf("Exported request history to %s\n", path)
+	return nil
+}
+
+// requestsPauseCmd represents the requests pause command
+var requestsPauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Stop recording proxy requests",
+	Long: `Stop recording proxy requests without stopping the proxy, e.g. while
+running a noisy load test through it. Requests are still proxied, but they
+aren't added to the request history or captured. The TUI's requests view
+shows when recording is paused.
+
+Examples:
+  prox requests pause
+  prox requests resume`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setRequestsRecording(false)
+	},
+}
+
+// requestsResumeCmd represents the requests resume command
+var requestsResumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume recording proxy requests",
+	Long: `Resume recording proxy requests after 'prox requests pause'.
+
+Examples:
+  prox requests resume`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setRequestsRecording(true)
+	},
+}
+
+// setRequestsRecording pauses or resumes recording proxy requests
+func setRequestsRecording(enabled bool) error {
+	client := NewClient(apiAddr)
+	resp, err := client.SetProxyRecording(enabled)
+	if err != nil {
+		return clientError(err, "Is prox running with proxy enabled? Try 'prox up' first.")
+	}
+
+	if resp.Enabled {
+		fmt.Println("Recording proxy requests")
+	} else {
+		fmt.Println("Paused recording proxy requests (resume with 'prox requests resume')")
+	}
+	return nil
+}
+
+// showRequestDetail displays details for a specific request. Bodies are
+// pretty-printed, and highlighted and paged when usePager is set and stdout
+// is a terminal.
+func showRequestDetail(client *Client, id string, includeBody, jsonOutput, usePager bool, mode humanize.TimeMode) error {
 	resp, err := client.GetProxyRequest(id, includeBody)
 	if err != nil {
 		return clientError(err, "Is prox running with proxy enabled? Try 'prox up' first.")
@@ -446,75 +1416,92 @@ func showRequestDetail(client *Client, id string, includeBody, jsonOutput bool)
 		return nil
 	}
 
+	color := usePager && colorOutput()
+	var out bytes.Buffer
+
 	// Print formatted output
 	ts, _ := time.Parse(time.RFC3339Nano, resp.Timestamp)
 
-	fmt.Printf("Request: %s\n", resp.ID)
-	fmt.Printf("Time:    %s\n", ts.Format("2006-01-02 15:04:05.000"))
-	fmt.Printf("Method:  %s\n", resp.Method)
-	fmt.Printf("URL:     %s\n", resp.URL)
-	fmt.Printf("Status:  %d\n", resp.StatusCode)
-	fmt.Printf("Duration: %dms\n", resp.DurationMs)
-	fmt.Printf("Remote:  %s\n", resp.RemoteAddr)
+	fmt.Fprintf(&out, "Request: %s\n", resp.ID)
+	timeStr := humanize.Time(ts, "2006-01-02 15:04:05.000", mode, time.Now())
+	if mode == humanize.TimeRelative {
+		// A relative time alone can't be matched up with logs
+		timeStr += " (" + ts.Local().Format("2006-01-02 15:04:05.000") + ")"
+	}
+	fmt.Fprintf(&out, "Time:    %s\n", timeStr)
+	fmt.Fprintf(&out, "Method:  %s\n", resp.Method)
+	fmt.Fprintf(&out, "URL:     %s\n", resp.URL)
+	fmt.Fprintf(&out, "Status:  %d\n", resp.StatusCode)
+	fmt.Fprintf(&out, "Duration: %s\n", humanMs(resp.DurationMs))
+	fmt.Fprintf(&out, "Remote:  %s\n", resp.RemoteAddr)
+	if resp.Imported {
+		fmt.Fprintln(&out, "Source:  loaded from a saved session")
+	}
+	if ws := resp.WebSocket; ws != nil {
+		state := "closed"
+		if ws.Open {
+			state = "open"
+		}
+		fmt.Fprintf(&out, "WebSocket: %s, %d bytes in, %d bytes out\n", state, ws.BytesIn, ws.BytesOut)
+	}
+
+	if len(resp.SchemaViolations) > 0 {
+		fmt.Fprintln(&out, "\n--- Schema Violations ---")
+		for _, v := range resp.SchemaViolations {
+			fmt.Fprintf(&out, "  %s\n", v)
+		}
+	}
 
 	if resp.Details != nil {
 		// Print request headers
 		if len(resp.Details.RequestHeaders) > 0 {
-			fmt.Println("\n--- Request Headers ---")
-			printHeaders(resp.Details.RequestHeaders)
+			fmt.Fprintln(&out, "\n--- Request Headers ---")
+			printHeaders(&out, resp.Details.RequestHeaders)
 		}
 
 		// Print response headers
 		if len(resp.Details.ResponseHeaders) > 0 {
-			fmt.Println("\n--- Response Headers ---")
-			printHeaders(resp.Details.ResponseHeaders)
+			fmt.Fprintln(&out, "\n--- Response Headers ---")
+			printHeaders(&out, resp.Details.ResponseHeaders)
 		}
 
-		// Print request body
-		if resp.Details.RequestBody != nil {
-			fmt.Printf("\n--- Request Body (%d bytes", resp.Details.RequestBody.Size)
-			if resp.Details.RequestBody.Truncated {
-				fmt.Print(", truncated")
-			}
-			fmt.Println(") ---")
-			if includeBody && resp.Details.RequestBody.Data != "" {
-				if resp.Details.RequestBody.IsBinary {
-					fmt.Println("[binary data, base64 encoded]")
-				}
-				fmt.Println(resp.Details.RequestBody.Data)
-			} else if !includeBody && resp.Details.RequestBody.Size > 0 {
-				fmt.Println("(use --body to show content)")
-			}
-		}
-
-		// Print response body
-		if resp.Details.ResponseBody != nil {
-			fmt.Printf("\n--- Response Body (%d bytes", resp.Details.ResponseBody.Size)
-			if resp.Details.ResponseBody.Truncated {
-				fmt.Print(", truncated")
-			}
-			fmt.Println(") ---")
-			if includeBody && resp.Details.ResponseBody.Data != "" {
-				if resp.Details.ResponseBody.IsBinary {
-					fmt.Println("[binary data, base64 encoded]")
-				}
-				fmt.Println(resp.Details.ResponseBody.Data)
-			} else if !includeBody && resp.Details.ResponseBody.Size > 0 {
-				fmt.Println("(use --body to show content)")
-			}
-		}
+		printBody(&out, "Request Body", resp.Details.RequestBody, includeBody, color)
+		printBody(&out, "Response Body", resp.Details.ResponseBody, includeBody, color)
 	} else {
-		fmt.Println("\n(capture not enabled - use 'prox up --capture' to enable)")
+		fmt.Fprintln(&out, "\n(capture not enabled - use 'prox up --capture' to enable)")
 	}
 
+	pageOutput(out.String(), usePager)
 	return nil
 }
 
-// printHeaders prints HTTP headers in a readable format
-func printHeaders(headers map[string][]string) {
-	for name, values := range headers {
-		for _, value := range values {
-			fmt.Printf("  %s: %s\n", name, value)
+// printBody prints a captured body section
+func printBody(w io.Writer, title string, body *api.CapturedBodyResponse, includeBody, color bool) {
+	if body == nil {
+		return
+	}
+	fmt.Fprintf(w, "\n--- %s (%d bytes", title, body.Size)
+	if body.Truncated {
+		fmt.Fprint(w, ", truncated")
+	}
+	fmt.Fprintln(w, ") ---")
+	if includeBody && body.Data != "" {
+		fmt.Fprintln(w, formatBody(body, color))
+	} else if !includeBody && body.Size > 0 {
+		fmt.Fprintln(w, "(use --body to show content)")
+	}
+}
+
+// printHeaders prints HTTP headers in a readable format, sorted by name
+func printHeaders(w io.Writer, headers map[string][]string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range headers[name] {
+			fmt.Fprintf(w, "  %s: %s\n", name, value)
 		}
 	}
 }
@@ -528,32 +1515,40 @@ func isTerminal() bool {
 	return (fi.Mode() & os.ModeCharDevice) != 0
 }
 
-func printProxyRequest(req api.ProxyRequestResponse) {
+func printProxyRequest(req api.ProxyRequestResponse, mode humanize.TimeMode, colored bool) {
 	ts, _ := time.Parse(time.RFC3339Nano, req.Timestamp)
-	timeStr := ts.Format("15:04:05")
+	timeStr := humanize.Time(ts, "15:04:05", mode, time.Now())
 
 	// Only use colors if stdout is a terminal
-	statusColor := ""
+	color := ""
 	resetColor := ""
-	if isTerminal() {
+	if colored {
+		color = statusColor(req.StatusCode)
 		resetColor = constants.ColorReset
-		switch {
-		case req.StatusCode >= 500:
-			statusColor = constants.ColorStatusServer
-		case req.StatusCode >= 400:
-			statusColor = constants.ColorStatusClient
-		case req.StatusCode >= 300:
-			statusColor = constants.ColorStatusRedirect
-		case req.StatusCode >= 200:
-			statusColor = constants.ColorStatusSuccess
-		}
 	}
 
-	fmt.Printf("%s %s %s%d%s %s (%dms)\n",
-		req.ID, timeStr, statusColor, req.StatusCode, resetColor, req.Method, req.DurationMs)
+	schemaMark := ""
+	if len(req.SchemaViolations) > 0 {
+		schemaMark = " [schema]"
+	}
+
+	fmt.Printf("%s %s %s%d%s %s (%s)%s%s\n",
+		req.ID, timeStr, color, req.StatusCode, resetColor, req.Method, humanMs(req.DurationMs), schemaMark, websocketMark(req))
 	fmt.Printf("       %s\n", req.URL)
 }
 
+// websocketMark labels WebSocket requests in request lists, noting
+// connections that are still open
+func websocketMark(req api.ProxyRequestResponse) string {
+	switch {
+	case req.WebSocket != nil && req.WebSocket.Open:
+		return " [websocket open]"
+	case req.Type == proxy.RequestTypeWebSocket:
+		return " [websocket]"
+	}
+	return ""
+}
+
 func init() {
 	// Register all commands
 	rootCmd.AddCommand(statusCmd)
@@ -562,19 +1557,43 @@ func init() {
 	rootCmd.AddCommand(downCmd)
 	rootCmd.AddCommand(startProcessCmd)
 	rootCmd.AddCommand(restartCmd)
+	rootCmd.AddCommand(drainCmd)
+	rootCmd.AddCommand(signalCmd)
+	rootCmd.AddCommand(reloadCmd)
 	rootCmd.AddCommand(attachCmd)
 	rootCmd.AddCommand(requestsCmd)
+	requestsCmd.AddCommand(requestsShowCmd)
+	requestsCmd.AddCommand(requestsStatsCmd)
+	requestsCmd.AddCommand(requestsSaveCmd)
+	requestsCmd.AddCommand(requestsPauseCmd)
+	requestsCmd.AddCommand(requestsResumeCmd)
+	requestsCmd.AddCommand(requestsLoadCmd)
+	requestsCmd.AddCommand(requestsExportCmd)
 
 	// Status command flags
 	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Output as JSON")
+	statusCmd.Flags().BoolVar(&statusDetail, "detail", false, "Include goroutine and watchdog counts")
+	statusCmd.Flags().BoolVar(&statusLastRun, "last-run", false, "Show the snapshot saved when the previous daemon shut down or crashed")
 
 	// Logs command flags
 	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Stream logs continuously")
-	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", constants.DefaultLogLimit, "Number of lines to show")
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", constants.DefaultLogLimit, "Number of lines to show (with -f, lines to show before streaming)")
 	logsCmd.Flags().StringVar(&logsProcess, "process", "", "Filter by process (comma-separated)")
-	logsCmd.Flags().StringVar(&logsPattern, "pattern", "", "Filter by pattern")
+	logsCmd.Flags().StringArrayVar(&logsPatterns, "pattern", nil, "Filter by pattern (repeatable; lines must match every pattern)")
 	logsCmd.Flags().BoolVar(&logsRegex, "regex", false, "Treat pattern as regex")
+	logsCmd.Flags().StringVar(&logsLevel, "level", "", "Show lines at this level or more severe (trace, debug, info, warn, error, fatal); needs log_format")
+	logsCmd.Flags().StringVar(&logsStream, "stream", domain.StreamBoth, "Show lines from stdout, stderr, or both")
 	logsCmd.Flags().BoolVar(&logsJSON, "json", false, "Output as JSON")
+	logsCmd.Flags().StringVarP(&logsOutput, "output", "o", outputText, "Output format: text, or jsonl for one JSON object per line with a type field")
+	logsCmd.Flags().StringVar(&logsSince, "since", "", "Show logs at or after a time (RFC3339, HH:MM[:SS] today, or a duration ago like 10m)")
+	logsCmd.Flags().BoolVar(&logsDistinctErrors, "distinct-errors", false, "Summarize error lines, grouping repeats that differ only in numbers and ids (default window: last hour)")
+	logsCmd.Flags().StringVar(&logsUntil, "until", "", "Show logs at or before a time (RFC3339, HH:MM[:SS] today, or a duration ago like 10m)")
+	addRedactFlags(logsCmd)
+	addRedactFlags(attachCmd)
+	addTimeFlag(logsCmd)
+
+	// Restart command flags
+	restartCmd.Flags().BoolVar(&restartBlueGreen, "blue-green", false, "Start a new instance and switch traffic before stopping the old one (requires port: auto)")
 
 	// Requests command flags
 	requestsCmd.Flags().BoolVarP(&requestsFollow, "follow", "f", false, "Stream requests continuously")
@@ -583,13 +1602,33 @@ func init() {
 	requestsCmd.Flags().IntVar(&requestsMinStatus, "min-status", 0, "Filter by minimum status code (e.g., 400 for errors)")
 	requestsCmd.Flags().IntVarP(&requestsLimit, "limit", "n", constants.DefaultProxyRequestLimit, "Number of requests to show")
 	requestsCmd.Flags().BoolVar(&requestsJSON, "json", false, "Output as JSON")
+	requestsCmd.Flags().StringVarP(&requestsOutput, "output", "o", outputText, "Output format: text, or jsonl for one JSON object per line with a type field")
 	requestsCmd.Flags().BoolVar(&requestsBody, "body", false, "Include request/response bodies when showing details")
+	requestsCmd.Flags().StringVar(&requestsColumns, "columns", "", "Comma-separated table columns: id, time, method, status, duration, subdomain, url, bytes (default: saved defaults, else id,time,method,status,duration,url)")
+	requestsCmd.Flags().StringVar(&requestsSort, "sort", requestSortTime, "Order requests by time (newest first), duration, status, or bytes (largest first)")
+	requestsCmd.Flags().BoolVar(&requestsSave, "save-defaults", false, "Save --columns and --sort as this project's defaults, or clear the defaults if neither is given")
+	requestsCmd.Flags().StringVar(&requestsAt, "at", "", "Show requests at or before a time (RFC3339, HH:MM[:SS] today, or a duration ago like 10m)")
+	addTimeFlag(requestsCmd)
+
+	// Requests show command flags
+	requestsShowCmd.Flags().BoolVar(&requestsShowBody, "body", false, "Include pretty-printed request/response bodies")
+	requestsShowCmd.Flags().BoolVar(&requestsShowJSON, "json", false, "Output as JSON")
+	requestsShowCmd.Flags().BoolVar(&requestsShowNoPager, "no-pager", false, "Print without paging")
+	addTimeFlag(requestsShowCmd)
+
+	// Requests stats command flags
+	requestsStatsCmd.Flags().BoolVar(&requestsStatsJSON, "json", false, "Output as JSON")
+
+	// Requests export command flags
+	requestsExportCmd.Flags().StringVar(&requestsExportHAR, "har", "", "File to write the HAR archive to")
 
 	// Register completion for --process flag
 	// Error is ignored as it only fails for invalid flag names, which would be a programming error
 	_ = logsCmd.RegisterFlagCompletionFunc("process", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return getProcessNames(), cobra.ShellCompDirectiveNoFileComp
 	})
+	_ = logsCmd.RegisterFlagCompletionFunc("stream", cobra.FixedCompletions(
+		[]string{string(domain.StreamStdout), string(domain.StreamStderr), domain.StreamBoth}, cobra.ShellCompDirectiveNoFileComp))
 }
 
 // clientError wraps an error with an optional hint for the user.
@@ -601,13 +1640,26 @@ func clientError(err error, hint string) error {
 	return err
 }
 
-// formatDuration formats a duration nicely
-func formatDuration(d time.Duration) string {
-	if d < time.Minute {
-		return fmt.Sprintf("%ds", int(d.Seconds()))
+// humanMs formats a millisecond count as reported by the API
+func humanMs(ms int64) string {
+	return humanize.Duration(time.Duration(ms) * time.Millisecond)
+}
+
+// parseAtTime parses a --at value relative to now. It accepts an RFC3339
+// timestamp, a wall-clock time today (15:04 or 15:04:05), or a duration
+// meaning that long ago (e.g. 10m).
+func parseAtTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return time.Date(now.Year(), now.Month(), now.Day(),
+				t.Hour(), t.Minute(), t.Second(), 0, now.Location()), nil
+		}
 	}
-	if d < time.Hour {
-		return fmt.Sprintf("%dm%ds", int(d.Minutes()), int(d.Seconds())%60)
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
 	}
-	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	return time.Time{}, fmt.Errorf("invalid --at value %q: use RFC3339, HH:MM[:SS], or a duration like 10m", value)
 }
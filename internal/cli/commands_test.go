@@ -7,10 +7,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/daemon"
 )
 
 // captureOutput redirects stdout and stderr for testing
@@ -104,18 +108,189 @@ func TestRunStatus_JSONOutput(t *testing.T) {
 	}
 }
 
+func TestRunStatus_Detail(t *testing.T) {
+	originalApiAddr := apiAddr
+	defer func() { apiAddr = originalApiAddr }()
+
+	var gotDetail string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/api/v1/status":
+			gotDetail = r.URL.Query().Get("detail")
+			json.NewEncoder(w).Encode(api.StatusResponse{
+				Status:     "running",
+				APIVersion: "v1",
+				Detail: &api.StatusDetailResponse{
+					Goroutines: 42,
+					Watchdog: api.WatchdogResponse{
+						Active: map[string]int{"sse_subscriber": 2, "output_reader": 4, "health_checker": 1},
+						Leaked: map[string]int{"sse_subscriber": 1, "output_reader": 0, "health_checker": 0},
+					},
+				},
+			})
+		case "/api/v1/processes":
+			json.NewEncoder(w).Encode(api.ProcessListResponse{})
+		}
+	}))
+	defer server.Close()
+
+	apiAddr = server.URL
+	statusDetail = true
+	defer func() { statusDetail = false }()
+
+	stdout, _ := captureOutput(t, func() {
+		runStatus(statusCmd, []string{})
+	})
+
+	if gotDetail != "true" {
+		t.Errorf("expected detail=true query, got %q", gotDetail)
+	}
+	if !strings.Contains(stdout, "Goroutines: 42") {
+		t.Errorf("expected goroutine count in output, got:\n%s", stdout)
+	}
+	if !strings.Contains(stdout, "sse_subscriber  2       1") {
+		t.Errorf("expected sse_subscriber row in output, got:\n%s", stdout)
+	}
+}
+
+func TestRunStatus_StartErrors(t *testing.T) {
+	originalApiAddr := apiAddr
+	defer func() { apiAddr = originalApiAddr }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/api/v1/status":
+			json.NewEncoder(w).Encode(api.StatusResponse{
+				Status:     "running",
+				APIVersion: "v1",
+				Errors: []api.StartErrorResponse{
+					{Process: "worker", Error: "failed to load environment: missing.env", Time: time.Now().Add(-90 * time.Second).Format(time.RFC3339)},
+				},
+			})
+		case "/api/v1/processes":
+			json.NewEncoder(w).Encode(api.ProcessListResponse{})
+		}
+	}))
+	defer server.Close()
+
+	apiAddr = server.URL
+
+	stdout, _ := captureOutput(t, func() {
+		runStatus(statusCmd, []string{})
+	})
+
+	if !strings.Contains(stdout, "Errors:") {
+		t.Errorf("expected errors section in output, got:\n%s", stdout)
+	}
+	if !strings.Contains(stdout, "worker: failed to start (1m3") || !strings.Contains(stdout, "ago): failed to load environment: missing.env") {
+		t.Errorf("expected worker error in output, got:\n%s", stdout)
+	}
+}
+
+func TestRunStatus_Logs(t *testing.T) {
+	originalApiAddr := apiAddr
+	defer func() { apiAddr = originalApiAddr }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/api/v1/status":
+			json.NewEncoder(w).Encode(api.StatusResponse{
+				Status:     "running",
+				APIVersion: "v1",
+				Logs:       &api.LogsStatusResponse{Entries: 812, BufferSize: 1400, MemoryBytes: 3 << 20, MaxMemoryBytes: 64 << 20},
+			})
+		case "/api/v1/processes":
+			json.NewEncoder(w).Encode(api.ProcessListResponse{})
+		}
+	}))
+	defer server.Close()
+
+	apiAddr = server.URL
+
+	stdout, _ := captureOutput(t, func() {
+		runStatus(statusCmd, []string{})
+	})
+
+	if !strings.Contains(stdout, "Logs:   812/1400 lines, 3 MiB of 64 MiB") {
+		t.Errorf("expected log usage in output, got:\n%s", stdout)
+	}
+}
+
+func TestRunStatus_LastRun(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	defer func() { statusLastRun, statusJSON = false, false }()
+	statusLastRun = true
+
+	if err := runStatus(statusCmd, []string{}); err == nil || !strings.Contains(err.Error(), "no last run recorded") {
+		t.Errorf("expected no last run error, got %v", err)
+	}
+
+	run := &daemon.LastRun{
+		StartedAt: time.Now().Add(-2 * time.Hour),
+		EndedAt:   time.Now().Add(-time.Hour),
+		Reason:    "panic",
+		Panic:     "runtime error: invalid memory address",
+		Processes: []daemon.LastRunProcess{
+			{Name: "api", Status: "running", PID: 1234, Restarts: 3, Health: "unhealthy"},
+			{Name: "worker", Status: "stopped", Health: "unknown", LastError: "failed to load environment: missing.env"},
+		},
+		Logs: daemon.LastRunLogs{Entries: 812, BufferSize: 1000},
+	}
+	if err := run.Write(dir); err != nil {
+		t.Fatalf("writing last run: %v", err)
+	}
+
+	stdout, _ := captureOutput(t, func() {
+		if err := runStatus(statusCmd, []string{}); err != nil {
+			t.Errorf("runStatus() error = %v", err)
+		}
+	})
+	for _, want := range []string{
+		"Reason: panic",
+		"Ran for: 1h",
+		"Logs: 812/1000 entries buffered",
+		"api     running  1234  3",
+		"worker: failed to start: failed to load environment: missing.env",
+		"Panic:\nruntime error: invalid memory address",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, stdout)
+		}
+	}
+
+	statusJSON = true
+	stdout, _ = captureOutput(t, func() {
+		runStatus(statusCmd, []string{})
+	})
+	var decoded daemon.LastRun
+	if err := json.Unmarshal([]byte(stdout), &decoded); err != nil {
+		t.Fatalf("decoding JSON output: %v", err)
+	}
+	if decoded.Reason != "panic" || len(decoded.Processes) != 2 {
+		t.Errorf("unexpected JSON output: %+v", decoded)
+	}
+}
+
 func TestRunLogs_FilterParsing(t *testing.T) {
 	// Save original apiAddr and restore after test
 	originalApiAddr := apiAddr
 	defer func() { apiAddr = originalApiAddr }()
 
-	var receivedProcess, receivedPattern, receivedRegex, receivedLines string
+	var receivedProcess, receivedPattern, receivedRegex, receivedLines, receivedLevel string
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		receivedProcess = r.URL.Query().Get("process")
 		receivedPattern = r.URL.Query().Get("pattern")
 		receivedRegex = r.URL.Query().Get("regex")
 		receivedLines = r.URL.Query().Get("lines")
+		receivedLevel = r.URL.Query().Get("level")
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(api.LogsResponse{
@@ -130,15 +305,17 @@ func TestRunLogs_FilterParsing(t *testing.T) {
 
 	// Set flags
 	logsProcess = "web"
-	logsPattern = "error"
+	logsPatterns = []string{"error"}
 	logsRegex = true
+	logsLevel = "WARNING"
 	logsLines = 50
 	logsFollow = false
 	logsJSON = false
 	defer func() {
 		logsProcess = ""
-		logsPattern = ""
+		logsPatterns = nil
 		logsRegex = false
+		logsLevel = ""
 		logsLines = 100
 	}()
 
@@ -158,6 +335,37 @@ func TestRunLogs_FilterParsing(t *testing.T) {
 	if receivedLines != "50" {
 		t.Errorf("expected lines '50', got %q", receivedLines)
 	}
+	if receivedLevel != "warn" {
+		t.Errorf("expected level 'warn', got %q", receivedLevel)
+	}
+}
+
+func TestRunLogs_InvalidLevel(t *testing.T) {
+	logsLevel = "loud"
+	defer func() { logsLevel = "" }()
+
+	err := runLogs(logsCmd, []string{})
+	if err == nil || !strings.Contains(err.Error(), "invalid --level") {
+		t.Errorf("expected invalid --level error, got %v", err)
+	}
+}
+
+func TestRunLogs_InvalidRegex(t *testing.T) {
+	// Nothing listens here; the pattern is rejected before any request
+	originalApiAddr := apiAddr
+	apiAddr = "http://127.0.0.1:1"
+	logsPatterns = []string{"[unclosed"}
+	logsRegex = true
+	defer func() {
+		apiAddr = originalApiAddr
+		logsPatterns = nil
+		logsRegex = false
+	}()
+
+	err := runLogs(logsCmd, []string{})
+	if err == nil || !strings.Contains(err.Error(), "invalid filter pattern") {
+		t.Errorf("expected invalid filter pattern error, got %v", err)
+	}
 }
 
 func TestRunLogs_ProcessAsPositionalArg(t *testing.T) {
@@ -183,7 +391,7 @@ func TestRunLogs_ProcessAsPositionalArg(t *testing.T) {
 
 	// Reset flags
 	logsProcess = ""
-	logsPattern = ""
+	logsPatterns = nil
 	logsRegex = false
 	logsLines = 100
 	logsFollow = false
@@ -224,7 +432,7 @@ func TestRunLogs_JSONOutput(t *testing.T) {
 
 	// Set flags
 	logsProcess = ""
-	logsPattern = ""
+	logsPatterns = nil
 	logsRegex = false
 	logsLines = 100
 	logsFollow = false
@@ -246,6 +454,176 @@ func TestRunLogs_JSONOutput(t *testing.T) {
 	}
 }
 
+func TestRunLogs_JSONLOutput(t *testing.T) {
+	originalApiAddr := apiAddr
+	defer func() { apiAddr = originalApiAddr }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(api.LogsResponse{
+			Logs: []api.LogEntryResponse{
+				{Timestamp: "2024-01-15T10:30:00Z", Process: "web", Stream: "stdout", Line: "first"},
+				{Timestamp: "2024-01-15T10:30:01Z", Process: "api", Stream: "stderr", Line: "second"},
+			},
+			FilteredCount: 2,
+			TotalCount:    2,
+		})
+	}))
+	defer server.Close()
+	apiAddr = server.URL
+
+	logsProcess = ""
+	logsPatterns = nil
+	logsLines = 100
+	logsFollow = false
+	logsOutput = outputJSONL
+	defer func() { logsOutput = outputText }()
+
+	stdout, _ := captureOutput(t, func() {
+		if err := runLogs(logsCmd, []string{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), stdout)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatalf("failed to parse line: %v", err)
+	}
+	if record["type"] != "log" || record["process"] != "api" || record["line"] != "second" {
+		t.Errorf("unexpected record: %v", record)
+	}
+
+	// --output jsonl replaces --json
+	logsJSON = true
+	defer func() { logsJSON = false }()
+	if err := runLogs(logsCmd, []string{}); err == nil {
+		t.Error("expected error for --json with --output jsonl")
+	}
+	logsJSON = false
+	logsOutput = "yaml"
+	if err := runLogs(logsCmd, []string{}); err == nil {
+		t.Error("expected error for an unknown --output")
+	}
+}
+
+func TestRunLogs_TimeRange(t *testing.T) {
+	originalApiAddr := apiAddr
+	defer func() {
+		apiAddr = originalApiAddr
+		logsSince = ""
+		logsUntil = ""
+		logsFollow = false
+	}()
+
+	var since, until string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		since = r.URL.Query().Get("since")
+		until = r.URL.Query().Get("until")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(api.LogsResponse{Logs: []api.LogEntryResponse{}, Truncated: true})
+	}))
+	defer server.Close()
+	apiAddr = server.URL
+
+	logsProcess = ""
+	logsPatterns = nil
+	logsRegex = false
+	logsLines = 100
+	logsFollow = false
+	logsJSON = false
+	logsSince = "2024-01-15T10:00:00Z"
+	logsUntil = "2024-01-15T10:30:00Z"
+
+	_, stderr := captureOutput(t, func() {
+		if err := runLogs(logsCmd, []string{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	if since != "2024-01-15T10:00:00Z" {
+		t.Errorf("expected since=2024-01-15T10:00:00Z, got %q", since)
+	}
+	if until != "2024-01-15T10:30:00Z" {
+		t.Errorf("expected until=2024-01-15T10:30:00Z, got %q", until)
+	}
+	if !strings.Contains(stderr, "results are partial") {
+		t.Errorf("expected truncation warning, got stderr %q", stderr)
+	}
+
+	logsFollow = true
+	if err := runLogs(logsCmd, []string{}); err == nil {
+		t.Error("expected error for --since with --follow")
+	}
+}
+
+func TestRunLogs_DistinctErrors(t *testing.T) {
+	originalApiAddr := apiAddr
+	defer func() {
+		apiAddr = originalApiAddr
+		logsDistinctErrors = false
+		logsFollow = false
+	}()
+
+	now := time.Now()
+	var since, lines string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		since = r.URL.Query().Get("since")
+		lines = r.URL.Query().Get("lines")
+		entry := func(ago time.Duration, process, line string) api.LogEntryResponse {
+			return api.LogEntryResponse{Timestamp: now.Add(-ago).Format(time.RFC3339Nano), Process: process, Stream: "stderr", Line: line}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(api.LogsResponse{Logs: []api.LogEntryResponse{
+			entry(30*time.Minute, "api", "error: query 17 timed out after 5000ms"),
+			entry(20*time.Minute, "web", "GET / 200 4ms"),
+			entry(10*time.Minute, "worker", "error: query 23 timed out after 5000ms"),
+			entry(5*time.Minute, "api", "panic: nil map"),
+		}})
+	}))
+	defer server.Close()
+	apiAddr = server.URL
+
+	logsProcess = ""
+	logsPatterns = nil
+	logsRegex = false
+	logsJSON = false
+	logsSince = ""
+	logsUntil = ""
+	logsDistinctErrors = true
+
+	stdout, _ := captureOutput(t, func() {
+		if err := runLogs(logsCmd, []string{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	if since == "" {
+		t.Error("expected a default since window")
+	} else if ts, err := time.Parse(time.RFC3339Nano, since); err != nil || now.Sub(ts) < 59*time.Minute || now.Sub(ts) > 61*time.Minute {
+		t.Errorf("expected since about an hour ago, got %q", since)
+	}
+	if lines != strconv.Itoa(constants.MaxLogLines) {
+		t.Errorf("expected lines=%d, got %q", constants.MaxLogLines, lines)
+	}
+	if !strings.Contains(stdout, "api,worker") || !strings.Contains(stdout, "error: query 17 timed out after 5000ms") {
+		t.Errorf("expected clustered query timeouts, got:\n%s", stdout)
+	}
+	if !strings.Contains(stdout, "3 error lines in 2 distinct errors") {
+		t.Errorf("expected summary line, got:\n%s", stdout)
+	}
+	if strings.Contains(stdout, "GET / 200") {
+		t.Errorf("non-error lines should be left out, got:\n%s", stdout)
+	}
+
+	logsFollow = true
+	if err := runLogs(logsCmd, []string{}); err == nil {
+		t.Error("expected error for --distinct-errors with --follow")
+	}
+}
+
 func TestRunStop_Success(t *testing.T) {
 	// Save original apiAddr and restore after test
 	originalApiAddr := apiAddr
@@ -298,28 +676,215 @@ func TestRunRestart_Success(t *testing.T) {
 	}
 }
 
-func TestFormatDuration(t *testing.T) {
+func TestRunReload(t *testing.T) {
+	// Save original apiAddr and restore after test
+	originalApiAddr := apiAddr
+	defer func() { apiAddr = originalApiAddr }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/reload" && r.Method == "POST" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(api.ReloadResponse{
+				Added:   []string{"worker"},
+				Removed: []string{},
+				Changed: []string{"web"},
+				Failed:  map[string]string{"worker": "failed to load environment"},
+				Ignored: []string{"proxy"},
+			})
+		}
+	}))
+	defer server.Close()
+
+	apiAddr = server.URL
+
+	stdout, _ := captureOutput(t, func() {
+		if err := runReload(reloadCmd, nil); err != nil {
+			t.Errorf("runReload returned error: %v", err)
+		}
+	})
+
+	for _, want := range []string{
+		"Added:   worker",
+		"Changed: web",
+		"Failed to start worker: failed to load environment",
+		"Changes to proxy take effect when prox restarts",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "Removed:") {
+		t.Errorf("expected no Removed line, got:\n%s", stdout)
+	}
+}
+
+func TestFormatProcessEvent(t *testing.T) {
+	at := time.Date(2025, 1, 19, 14, 2, 11, 0, time.Local)
 	tests := []struct {
-		duration time.Duration
+		errors   int
 		expected string
 	}{
-		{0, "0s"},
-		{30 * time.Second, "30s"},
-		{59 * time.Second, "59s"},
-		{60 * time.Second, "1m0s"},
-		{90 * time.Second, "1m30s"},
-		{3600 * time.Second, "1h0m"},
-		{3661 * time.Second, "1h1m"},
-		{7200 * time.Second, "2h0m"},
+		{0, "api: 14:02:11 web crashed, no errors in the next 30s"},
+		{1, "api: 14:02:11 web crashed, 1 error in the next 30s"},
+		{12, "api: 14:02:11 web crashed, 12 errors in the next 30s"},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.expected, func(t *testing.T) {
-			result := formatDuration(tt.duration)
-			if result != tt.expected {
-				t.Errorf("formatDuration(%v) = %q, expected %q", tt.duration, result, tt.expected)
+		event := api.ProcessEventResponse{Process: "web", Type: "crashed", Timestamp: at.Format(time.RFC3339Nano), ErrorsAfter: tt.errors}
+		result := formatProcessEvent("api", event)
+		if result != tt.expected {
+			t.Errorf("formatProcessEvent() = %q, expected %q", result, tt.expected)
+		}
+	}
+}
+
+func TestParseAtTime(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{"2024-01-14T08:00:00Z", time.Date(2024, 1, 14, 8, 0, 0, 0, time.UTC), false},
+		{"09:15:30", time.Date(2024, 1, 15, 9, 15, 30, 0, time.UTC), false},
+		{"09:15", time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC), false},
+		{"10m", time.Date(2024, 1, 15, 10, 20, 0, 0, time.UTC), false},
+		{"-5m", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			result, err := parseAtTime(tt.value, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseAtTime(%q) expected error", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAtTime(%q) unexpected error: %v", tt.value, err)
+			}
+			if !result.Equal(tt.expected) {
+				t.Errorf("parseAtTime(%q) = %v, expected %v", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunRequests_At(t *testing.T) {
+	origAt := requestsAt
+	origFollow := requestsFollow
+	originalApiAddr := apiAddr
+	defer func() {
+		requestsAt = origAt
+		requestsFollow = origFollow
+		apiAddr = originalApiAddr
+	}()
+
+	var until string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		until = r.URL.Query().Get("until")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(api.ProxyRequestsResponse{})
+	}))
+	defer server.Close()
+	apiAddr = server.URL
+
+	requestsAt = "2024-01-15T10:30:00Z"
+	requestsFollow = false
+	_, _ = captureOutput(t, func() {
+		if err := runRequests(requestsCmd, []string{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	if until != "2024-01-15T10:30:00Z" {
+		t.Errorf("expected until=2024-01-15T10:30:00Z, got %q", until)
+	}
+
+	requestsFollow = true
+	if err := runRequests(requestsCmd, []string{}); err == nil {
+		t.Error("expected error for --at with --follow")
+	}
+}
+
+func TestRunRequests_JSONLOutput(t *testing.T) {
+	originalApiAddr := apiAddr
+	defer func() { apiAddr = originalApiAddr }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(api.ProxyRequestsResponse{
+			Requests: []api.ProxyRequestResponse{
+				{ID: "abc1234", Method: "GET", URL: "/users", Subdomain: "api", StatusCode: 200},
+			},
+			FilteredCount: 1,
+			TotalCount:    1,
+		})
+	}))
+	defer server.Close()
+	apiAddr = server.URL
+
+	requestsFollow = false
+	requestsOutput = outputJSONL
+	defer func() { requestsOutput = outputText }()
+
+	stdout, _ := captureOutput(t, func() {
+		if err := runRequests(requestsCmd, []string{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(stdout), &record); err != nil {
+		t.Fatalf("failed to parse output %q: %v", stdout, err)
+	}
+	if record["type"] != "request" || record["id"] != "abc1234" || record["status_code"] != float64(200) {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
+
+func TestRunRequests_Time(t *testing.T) {
+	originalApiAddr := apiAddr
+	defer func() {
+		apiAddr = originalApiAddr
+		timeFlag = ""
+	}()
+
+	ts := time.Now().Add(-90 * time.Second).UTC()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(api.ProxyRequestsResponse{
+			Requests: []api.ProxyRequestResponse{
+				{ID: "abc1234", Timestamp: ts.Format(time.RFC3339Nano), Method: "GET", URL: "/users", StatusCode: 200},
+			},
+			FilteredCount: 1,
+			TotalCount:    1,
+		})
+	}))
+	defer server.Close()
+	apiAddr = server.URL
+	requestsFollow = false
+
+	for flag, want := range map[string]string{
+		"utc":      ts.Format("15:04:05"),
+		"local":    ts.Local().Format("15:04:05"),
+		"relative": "1m30s ago",
+	} {
+		timeFlag = flag
+		stdout, _ := captureOutput(t, func() {
+			if err := runRequests(requestsCmd, []string{}); err != nil {
+				t.Errorf("unexpected error: %v", err)
 			}
 		})
+		if !strings.Contains(stdout, want) {
+			t.Errorf("--time %s: expected %q in output, got %q", flag, want, stdout)
+		}
+	}
+
+	timeFlag = "gmt"
+	if err := runRequests(requestsCmd, []string{}); err == nil || !strings.Contains(err.Error(), "invalid --time") {
+		t.Errorf("expected invalid --time error, got %v", err)
 	}
 }
 
@@ -497,3 +1062,40 @@ func TestLogPrinter(t *testing.T) {
 		t.Errorf("unexpected color: %q", color1)
 	}
 }
+
+func TestRunSignal(t *testing.T) {
+	originalApiAddr := apiAddr
+	defer func() { apiAddr = originalApiAddr }()
+
+	var receivedPath string
+	var received api.SignalProcessRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(api.SuccessResponse{Success: true})
+	}))
+	defer server.Close()
+	apiAddr = server.URL
+
+	output, _ := captureOutput(t, func() {
+		if err := runSignal(signalCmd, []string{"web", "usr2"}); err != nil {
+			t.Errorf("runSignal: %v", err)
+		}
+	})
+
+	if receivedPath != "/api/v1/processes/web/signal" {
+		t.Errorf("expected signal endpoint, got %q", receivedPath)
+	}
+	if received.Signal != "SIGUSR2" {
+		t.Errorf("expected signal SIGUSR2, got %q", received.Signal)
+	}
+	if !strings.Contains(output, "Sent SIGUSR2 to process: web") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	err := runSignal(signalCmd, []string{"web", "SIGNOPE"})
+	if err == nil || !strings.Contains(err.Error(), "invalid signal") {
+		t.Errorf("expected invalid signal error, got %v", err)
+	}
+}
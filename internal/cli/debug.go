@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// debugCmd represents the debug command
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Debug a running prox daemon",
+	Long: `Commands for debugging a running prox daemon.
+
+Examples:
+  prox debug trigger-dump   # Write a postmortem dump of the daemon's state`,
+}
+
+// debugTriggerDumpCmd represents the debug trigger-dump command
+var debugTriggerDumpCmd = &cobra.Command{
+	Use:   "trigger-dump",
+	Short: "Write a postmortem dump of the daemon's state",
+	Long: `Write the daemon's processes, in-flight proxy requests, log buffer stats
+and goroutine stacks to .prox/postmortem-<time>.txt, and print its path.
+
+This is the same dump the daemon writes when it receives SIGQUIT, but the
+daemon keeps running. Use it to see what a hung stack is waiting on.`,
+	Args: cobra.NoArgs,
+	RunE: runDebugTriggerDump,
+}
+
+func init() {
+	rootCmd.AddCommand(debugCmd)
+	debugCmd.AddCommand(debugTriggerDumpCmd)
+}
+
+func runDebugTriggerDump(cmd *cobra.Command, args []string) error {
+	client := NewClient(apiAddr)
+	resp, err := client.TriggerDump()
+	if err != nil {
+		return clientError(err, "Is prox running? Try 'prox up' first.")
+	}
+
+	fmt.Printf("Postmortem written to %s\n", resp.Path)
+	return nil
+}
@@ -0,0 +1,299 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/charliek/prox/internal/proxy/certs"
+	"github.com/charliek/prox/internal/proxy/hosts"
+	"github.com/spf13/cobra"
+)
+
+// Doctor command flags
+var doctorJSON bool
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment prox runs in",
+	Long: `Check that prox up will work here, and print how to fix what won't.
+
+doctor checks the config (as prox verify does), state files left by a prox
+that didn't shut down cleanly, ports already taken by other programs, and,
+when the proxy is enabled, its HTTPS certificate (expiry and whether browsers
+trust it) and that its hostnames resolve: /etc/hosts entries, the builtin DNS
+responder, or a .localhost domain.
+
+doctor exits with status 1 if a check fails, and 0 if there are only
+warnings.
+
+Examples:
+  prox doctor                 # Check prox.yaml and this directory
+  prox doctor -c other.yaml   # Check another config file
+  prox doctor --json          # Output as JSON`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// Doctor check statuses
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// certExpiryWarning is how long before its certificate expires doctor
+// starts warning about it
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// doctorCheck is the result of one prox doctor check
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// doctorReport is the JSON output of prox doctor
+type doctorReport struct {
+	Config  string        `json:"config"`
+	Healthy bool          `json:"healthy"`
+	Checks  []doctorCheck `json:"checks"`
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	report := doctorReport{Config: configPath, Healthy: true, Checks: doctorChecks(cwd, configPath, time.Now())}
+	for _, check := range report.Checks {
+		if check.Status == doctorFail {
+			report.Healthy = false
+		}
+	}
+
+	if doctorJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to encode report: %v\n", err)
+		}
+	} else {
+		for _, check := range report.Checks {
+			fmt.Printf("%-4s  %-7s  %s\n", check.Status, check.Name, check.Message)
+			if check.Fix != "" {
+				fmt.Printf("%15s%s\n", "fix: ", check.Fix)
+			}
+		}
+	}
+
+	if !report.Healthy {
+		return &exitError{code: 1}
+	}
+	return nil
+}
+
+// doctorChecks runs the prox doctor checks for the config at path, run in dir
+func doctorChecks(dir, path string, now time.Time) []doctorCheck {
+	checks := []doctorCheck{checkConfig(path)}
+	running := daemon.IsRunning(dir)
+	checks = append(checks, checkState(dir, running))
+
+	// The rest need a config that loads
+	cfg, err := config.Load(path)
+	if err != nil {
+		return checks
+	}
+	checks = append(checks, checkPorts(cfg, running)...)
+	if cfg.Proxy != nil && cfg.Proxy.Enabled {
+		if cfg.Proxy.HTTPSPort > 0 && cfg.Certs != nil {
+			checks = append(checks, checkCerts(cfg, now)...)
+		}
+		checks = append(checks, checkHostnames(cfg))
+	}
+	return checks
+}
+
+// checkConfig reports whether the config loads, with the problems prox
+// verify finds
+func checkConfig(path string) doctorCheck {
+	check := doctorCheck{Name: "config"}
+	findings, err := config.Verify(path)
+	if err != nil {
+		check.Status = doctorFail
+		check.Message = err.Error()
+		check.Fix = "create a prox.yaml or Procfile here, or pass --config"
+		return check
+	}
+
+	var errs, warnings int
+	var first string
+	for _, f := range findings {
+		if f.Level == config.FindingError {
+			if errs == 0 {
+				first = fmt.Sprintf("%s: %s", f.Field, f.Message)
+			}
+			errs++
+		} else {
+			warnings++
+		}
+	}
+	switch {
+	case errs > 0:
+		check.Status = doctorFail
+		check.Message = fmt.Sprintf("%s has %s, e.g. %s", path, humanize.Count(errs, "error", "errors"), first)
+		check.Fix = "run prox verify to list them"
+	case warnings > 0:
+		check.Status = doctorWarn
+		check.Message = fmt.Sprintf("%s is valid, with %s", path, humanize.Count(warnings, "warning", "warnings"))
+		check.Fix = "run prox verify to list them"
+	default:
+		check.Status = doctorOK
+		check.Message = fmt.Sprintf("%s is valid", path)
+	}
+	return check
+}
+
+// checkState reports state files left by a prox that didn't shut down
+// cleanly
+func checkState(dir string, running bool) doctorCheck {
+	check := doctorCheck{Name: "state", Status: doctorOK}
+	if running {
+		if state, err := daemon.LoadState(dir); err == nil {
+			check.Message = fmt.Sprintf("prox is running here (PID %d, API on %s:%d)", state.PID, state.Host, state.Port)
+		} else {
+			check.Message = "prox is running here"
+		}
+		return check
+	}
+	stale := daemon.StaleFiles(dir)
+	if len(stale) == 0 {
+		check.Message = "no state left by a stopped prox"
+		return check
+	}
+	names := make([]string, len(stale))
+	for i, path := range stale {
+		names[i] = filepath.Base(path)
+	}
+	check.Status = doctorWarn
+	check.Message = fmt.Sprintf("%s left by a prox that didn't shut down cleanly", strings.Join(names, ", "))
+	check.Fix = "run prox gc, or let the next prox up remove them"
+	return check
+}
+
+// checkPorts reports fixed ports another program is already listening on.
+// While prox runs, its own ports are taken by it, so they aren't checked.
+func checkPorts(cfg *config.Config, running bool) []doctorCheck {
+	if running {
+		return []doctorCheck{{Name: "ports", Status: doctorOK, Message: "ports are held by the running prox"}}
+	}
+
+	ports := cfg.ListenPorts()
+	numbers := make([]int, 0, len(ports))
+	for port := range ports {
+		numbers = append(numbers, port)
+	}
+	sort.Ints(numbers)
+
+	var checks []doctorCheck
+	for _, port := range numbers {
+		if port <= 0 || daemon.IsPortAvailable("", port) {
+			continue
+		}
+		checks = append(checks, doctorCheck{
+			Name:    "ports",
+			Status:  doctorFail,
+			Message: fmt.Sprintf("port %d (%s) is in use by another program", port, ports[port]),
+			Fix:     fmt.Sprintf("stop the program listening on it, or change %s", ports[port]),
+		})
+	}
+	if len(checks) == 0 {
+		checks = append(checks, doctorCheck{Name: "ports", Status: doctorOK, Message: fmt.Sprintf("%s free", humanize.Count(len(numbers), "port", "ports"))})
+	}
+	return checks
+}
+
+// checkCerts reports whether browsers will trust the proxy's certificate,
+// and when it expires
+func checkCerts(cfg *config.Config, now time.Time) []doctorCheck {
+	certMgr := certs.NewManager(cfg.Certs.Dir, cfg.Proxy.Domain, cfg.Certs.Provider)
+
+	trust := doctorCheck{Name: "certs", Status: doctorOK, Message: "mkcert CA is installed"}
+	if certMgr.SelfSigned() {
+		trust.Status = doctorWarn
+		trust.Message = "certificates are self-signed, so browsers warn about them"
+		trust.Fix = "install mkcert and set certs.provider: mkcert"
+	} else if err := certMgr.CheckMkcert(); err != nil {
+		trust.Status = doctorFail
+		trust.Message = "mkcert is not installed"
+		trust.Fix = "install mkcert (https://github.com/FiloSottile/mkcert), or set certs.provider: self-signed"
+	} else if installed, err := certMgr.CheckCAInstalled(); err != nil || !installed {
+		trust.Status = doctorFail
+		trust.Message = "mkcert CA is not installed, so browsers won't trust certificates"
+		trust.Fix = "run mkcert -install"
+	}
+
+	expiry := doctorCheck{Name: "certs", Status: doctorOK}
+	notAfter, err := certMgr.Expiry()
+	switch {
+	case err != nil:
+		expiry.Message = fmt.Sprintf("no certificate for %s yet; prox up generates one", cfg.Proxy.Domain)
+	case now.After(notAfter):
+		expiry.Status = doctorWarn
+		expiry.Message = fmt.Sprintf("certificate for %s expired %s ago; prox up regenerates it", cfg.Proxy.Domain, humanize.Duration(now.Sub(notAfter)))
+		expiry.Fix = "run prox certs --regenerate"
+	case notAfter.Sub(now) < certExpiryWarning:
+		expiry.Status = doctorWarn
+		expiry.Message = fmt.Sprintf("certificate for %s expires in %s", cfg.Proxy.Domain, humanize.Duration(notAfter.Sub(now)))
+		expiry.Fix = "run prox certs --regenerate"
+	default:
+		expiry.Message = fmt.Sprintf("certificate for %s is valid until %s", cfg.Proxy.Domain, notAfter.Local().Format("2006-01-02"))
+	}
+	return []doctorCheck{trust, expiry}
+}
+
+// checkHostnames reports whether the proxy's hostnames resolve to this
+// machine
+func checkHostnames(cfg *config.Config) doctorCheck {
+	check := doctorCheck{Name: "hosts", Status: doctorOK}
+	domain := cfg.Proxy.Domain
+	switch {
+	case config.IsLocalhostDomain(domain):
+		check.Message = fmt.Sprintf("names under %s resolve to 127.0.0.1 without setup", domain)
+		return check
+	case cfg.Proxy.DNSBuiltin():
+		check.Message = fmt.Sprintf("prox answers DNS queries for %s on 127.0.0.1:%d", domain, cfg.Proxy.DNSPortOrDefault())
+		return check
+	}
+
+	exists, upToDate, err := hosts.NewManager(domain, hostsServiceNames(cfg)).Check()
+	switch {
+	case err != nil:
+		check.Status = doctorWarn
+		check.Message = fmt.Sprintf("could not check the hosts file: %v", err)
+	case !exists:
+		check.Status = doctorFail
+		check.Message = fmt.Sprintf("no /etc/hosts entries for %s", domain)
+		check.Fix = "run prox hosts sync, or set proxy.dns: builtin"
+	case !upToDate:
+		check.Status = doctorWarn
+		check.Message = "/etc/hosts entries are out of date with the services"
+		check.Fix = "run prox hosts sync"
+	default:
+		check.Message = fmt.Sprintf("/etc/hosts has entries for %s", domain)
+	}
+	return check
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/prox/internal/daemon"
+)
+
+func TestDoctorChecks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prox.yaml")
+
+	// A port another program is listening on
+	ln, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	defer ln.Close()
+	taken := ln.Addr().(*net.TCPAddr).Port
+	free, err := daemon.FindAvailablePort("127.0.0.1")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`
+processes:
+  web:
+    cmd: sh -c "sleep 1"
+    port: %d
+proxy:
+  http_port: %d
+  domain: prox.localhost
+`, taken, free)), 0600))
+	state := &daemon.State{PID: 4000000, Port: 5555, Host: "127.0.0.1", ConfigFile: path}
+	require.NoError(t, state.Write(dir))
+
+	checks := doctorChecks(dir, path, time.Now())
+	byName := make(map[string]doctorCheck)
+	for _, check := range checks {
+		byName[check.Name] = check
+	}
+
+	assert.Equal(t, doctorOK, byName["config"].Status)
+	assert.Equal(t, doctorWarn, byName["state"].Status)
+	assert.Contains(t, byName["state"].Message, daemon.StateFileName)
+	assert.Equal(t, doctorFail, byName["ports"].Status)
+	assert.Equal(t, fmt.Sprintf("port %d (processes.web.port) is in use by another program", taken), byName["ports"].Message)
+	assert.Equal(t, doctorOK, byName["hosts"].Status, ".localhost needs no hosts entries")
+	assert.NotContains(t, byName, "certs", "HTTP only")
+}
+
+func TestDoctorChecks_BrokenConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prox.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("processes:\n  web:\n    cmd: \"\"\n"), 0600))
+
+	checks := doctorChecks(dir, path, time.Now())
+	require.Len(t, checks, 2, "checks needing the config are skipped")
+	assert.Equal(t, doctorFail, checks[0].Status)
+	assert.Equal(t, "run prox verify to list them", checks[0].Fix)
+	assert.Equal(t, doctorOK, checks[1].Status)
+
+	checks = doctorChecks(dir, filepath.Join(dir, "missing.yaml"), time.Now())
+	assert.Equal(t, doctorFail, checks[0].Status)
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// Env command flags
+var envFormat string
+
+// envCmd represents the env command
+var envCmd = &cobra.Command{
+	Use:   "env <process>",
+	Short: "Print the environment of a process",
+	Long: `Print the variables prox sets for a process: the global env_file, the
+process's env_file and env, and $PORT for a fixed port, merged as when prox
+starts it. Use it to run the process by hand, or to hand its environment to
+other tools. Variables prox passes through from its own environment aren't
+printed.
+
+Variables in the process's env_prompt that nothing sets are asked for first.
+prox doesn't need to be running, so the port of a port: auto process isn't
+known and isn't printed.
+
+Formats:
+  dotenv  KEY="value" lines, as read by env_file (default)
+  shell   export KEY='value' lines, for eval or source
+  json    A JSON object
+
+Examples:
+  prox env api                          # Print as .env
+  eval "$(prox env api --format shell)" # Set in the current shell
+  prox env api --format json | jq .DATABASE_URL`,
+	Args:              cobra.ExactArgs(1),
+	RunE:              runPrintEnv,
+	ValidArgsFunction: completeProcessNames,
+}
+
+func init() {
+	envCmd.Flags().StringVar(&envFormat, "format", envFormatDotenv, "Output format: dotenv, shell, or json")
+	rootCmd.AddCommand(envCmd)
+}
+
+// prox env output formats
+const (
+	envFormatDotenv = "dotenv"
+	envFormatShell  = "shell"
+	envFormatJSON   = "json"
+)
+
+func runPrintEnv(cmd *cobra.Command, args []string) error {
+	switch envFormat {
+	case envFormatDotenv, envFormatShell, envFormatJSON:
+	default:
+		return fmt.Errorf("invalid --format %q (want dotenv, shell, or json)", envFormat)
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	configDir := configDirFor(configPath)
+
+	promptedEnv, err := promptEnv(cfg, configDir, []string{args[0]})
+	if err != nil {
+		return err
+	}
+	env, err := processEnv(cfg, configDir, args[0], promptedEnv)
+	if err != nil {
+		return err
+	}
+	return writeEnv(os.Stdout, env, envFormat)
+}
+
+// writeEnv writes env in a prox env format, sorted by name
+func writeEnv(w io.Writer, env map[string]string, format string) error {
+	if format == envFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(env)
+	}
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var err error
+		if format == envFormatShell {
+			_, err = fmt.Fprintf(w, "export %s=%s\n", name, shellQuote(env[name]))
+		} else {
+			_, err = fmt.Fprintf(w, "%s=%s\n", name, dotenvQuote(env[name]))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dotenvPlain matches values that env files can hold unquoted
+var dotenvPlain = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,-]*$`)
+
+// dotenvQuote double-quotes a value for an env file where it needs quoting,
+// escaping what env files unescape in double-quoted values
+func dotenvQuote(value string) string {
+	if dotenvPlain.MatchString(value) {
+		return value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+	return `"` + r.Replace(value) + `"`
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"testing"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var envTestValues = map[string]string{
+	"PORT":         "3000",
+	"DATABASE_URL": "postgres://user:pa ss@localhost/app?sslmode=disable",
+	"GREETING":     "it's \"quoted\"\nand $HOME \\ too",
+	"EMPTY":        "",
+}
+
+func TestWriteEnv_Dotenv(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, writeEnv(&out, map[string]string{"PORT": "3000", "NAME": "my app"}, envFormatDotenv))
+	assert.Equal(t, "NAME=\"my app\"\nPORT=3000\n", out.String())
+
+	// Values read back as they were written
+	out.Reset()
+	require.NoError(t, writeEnv(&out, envTestValues, envFormatDotenv))
+	env, err := godotenv.Unmarshal(out.String())
+	require.NoError(t, err)
+	assert.Equal(t, envTestValues, env)
+}
+
+func TestWriteEnv_Shell(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, writeEnv(&out, envTestValues, envFormatShell))
+
+	// Values survive a shell
+	script := out.String() + `printf '%s' "$GREETING"`
+	got, err := exec.Command("sh", "-c", script).Output()
+	require.NoError(t, err)
+	assert.Equal(t, envTestValues["GREETING"], string(got))
+}
+
+func TestWriteEnv_JSON(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, writeEnv(&out, envTestValues, envFormatJSON))
+	var env map[string]string
+	require.NoError(t, json.Unmarshal(out.Bytes(), &env))
+	assert.Equal(t, envTestValues, env)
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/spf13/cobra"
+)
+
+// Events command flags
+var (
+	eventsFollow bool
+	eventsLines  int
+	eventsTypes  []string
+	eventsJSON   bool
+	eventsOutput string
+)
+
+// eventsCmd represents the events command
+var eventsCmd = &cobra.Command{
+	Use:   "events [process]",
+	Short: "Show process lifecycle events",
+	Long: `Show the supervisor's recent events: processes starting, stopping,
+crashing, and turning healthy or unhealthy, and prox itself starting and
+stopping. Use -f to print events as they happen, e.g. to run a command when a
+process crashes.
+
+Event types:
+  process_started, process_stopped, process_crashed, process_failed,
+  process_healthy, process_unhealthy,
+  supervisor_start, supervisor_stop
+
+Examples:
+  prox events                          # Recent events
+  prox events web                      # Recent events of the web process
+  prox events -f --type process_crashed
+  prox events -f -o jsonl | while read -r e; do notify-send prox "$e"; done`,
+	Args:              cobra.MaximumNArgs(1),
+	RunE:              runEvents,
+	ValidArgsFunction: completeProcessNames,
+}
+
+func init() {
+	eventsCmd.Flags().BoolVarP(&eventsFollow, "follow", "f", false, "Stream events as they happen")
+	eventsCmd.Flags().IntVarP(&eventsLines, "lines", "n", 20, "Number of recent events to show")
+	eventsCmd.Flags().StringSliceVar(&eventsTypes, "type", nil, "Only show events of these types (comma-separated)")
+	eventsCmd.Flags().BoolVar(&eventsJSON, "json", false, "Output as JSON")
+	eventsCmd.Flags().StringVarP(&eventsOutput, "output", "o", outputText, "Output format: text or jsonl")
+	rootCmd.AddCommand(eventsCmd)
+}
+
+func runEvents(cmd *cobra.Command, args []string) error {
+	if err := checkOutput(eventsOutput, eventsJSON); err != nil {
+		return err
+	}
+	mode, err := timeMode()
+	if err != nil {
+		return err
+	}
+
+	params := domain.EventParams{Types: eventsTypes, Follow: eventsFollow}
+	if len(args) > 0 {
+		params.Process = args[0]
+	}
+	// Following starts with new events unless -n is given, as prox logs -f
+	if !eventsFollow || cmd.Flags().Changed("lines") {
+		params.Backfill = eventsLines
+	}
+
+	client := NewClient(apiAddr)
+	ch, err := client.StreamEventsChannel(params)
+	if err != nil {
+		return clientError(err, "Is prox running? Try 'prox up' first.")
+	}
+	for event := range ch {
+		switch {
+		case eventsOutput == outputJSONL:
+			writeJSONL(os.Stdout, api.NewJSONLEvent(event))
+		case eventsJSON:
+			if err := json.NewEncoder(os.Stdout).Encode(event); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to encode event: %v\n", err)
+			}
+		default:
+			fmt.Println(formatEvent(event, mode, time.Now()))
+		}
+	}
+	return nil
+}
+
+// formatEvent formats an event as its time, type, and process, followed by
+// the process's status and health after it
+func formatEvent(event api.EventResponse, mode humanize.TimeMode, now time.Time) string {
+	ts, _ := time.Parse(time.RFC3339Nano, event.Timestamp)
+	parts := []string{humanize.Time(ts, "15:04:05", mode, now), fmt.Sprintf("%-17s", event.Type)}
+	if event.Process != "" {
+		parts = append(parts, event.Process)
+	}
+	if info := event.Info; info != nil {
+		detail := info.Status
+		if info.Health != "" && info.Health != string(domain.HealthStatusUnknown) {
+			detail += ", " + info.Health
+		}
+		parts = append(parts, "("+detail+")")
+	}
+	return strings.TrimRight(strings.Join(parts, " "), " ")
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatEvent(t *testing.T) {
+	ts := time.Date(2025, 1, 19, 14, 2, 1, 0, time.UTC)
+	at := ts.Format(time.RFC3339Nano)
+
+	tests := []struct {
+		event    api.EventResponse
+		expected string
+	}{
+		{
+			api.EventResponse{Type: "supervisor_start", Timestamp: at},
+			"14:02:01 supervisor_start",
+		},
+		{
+			api.EventResponse{Type: "process_crashed", Process: "web", Timestamp: at, Info: &api.ProcessResponse{Status: "crashed", Health: "unknown"}},
+			"14:02:01 process_crashed   web (crashed)",
+		},
+		{
+			api.EventResponse{Type: "process_unhealthy", Process: "api", Timestamp: at, Info: &api.ProcessResponse{Status: "running", Health: "unhealthy"}},
+			"14:02:01 process_unhealthy api (running, unhealthy)",
+		},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, formatEvent(tt.event, humanize.TimeUTC, ts))
+	}
+}
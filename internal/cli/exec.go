@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// exitError carries the exit code of a command run by prox, so prox exits
+// with it without printing an error of its own
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec <process> -- <command> [args...]",
+	Short: "Run a command with a process's environment",
+	Long: `Run a one-off command with the same environment as a process.
+
+The command gets the variables the process would: the global env_file, the
+process's env_file and env, and $PORT for a fixed port. Variables in the
+process's env_prompt that nothing sets are asked for first. The command runs
+in the current directory with the terminal attached, and prox exits with its
+exit code. prox doesn't need to be running.
+
+Examples:
+  prox exec api -- npm run migrate
+  prox exec api -- psql "$DATABASE_URL"
+  prox exec worker -- env`,
+	Args:              cobra.MinimumNArgs(2),
+	RunE:              runExec,
+	ValidArgsFunction: completeProcessNames,
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 && dash != 1 {
+		return fmt.Errorf("expected prox exec <process> -- <command>")
+	}
+	processName := args[0]
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	configDir := configDirFor(configPath)
+
+	promptedEnv, err := promptEnv(cfg, configDir, []string{processName})
+	if err != nil {
+		return err
+	}
+	env, err := processEnv(cfg, configDir, processName, promptedEnv)
+	if err != nil {
+		return err
+	}
+
+	command := exec.Command(args[1], args[2:]...)
+	command.Env = []string{}
+	if cfg.Processes[processName].InheritsEnv() {
+		command.Env = os.Environ()
+	}
+	for k, v := range env {
+		command.Env = append(command.Env, k+"="+v)
+	}
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+
+	// Ctrl+C reaches the command through the terminal; leave handling it,
+	// e.g. in a REPL, to the command
+	signal.Ignore(syscall.SIGINT)
+	defer signal.Reset(syscall.SIGINT)
+
+	if err := command.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &exitError{code: exitErr.ExitCode()}
+		}
+		return fmt.Errorf("running %s: %w", args[1], err)
+	}
+	return nil
+}
+
+// processEnv returns the environment a process is started with, less the
+// port of a port: auto process, which is only known to a running prox
+func processEnv(cfg *config.Config, configDir, name string, promptedEnv map[string]string) (map[string]string, error) {
+	proc, ok := cfg.Processes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown process: %s", name)
+	}
+
+	env, err := config.LoadProcessEnv(cfg.EnvFile, cfg.Env, proc.EnvFile, proc.Env, configDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment: %w", err)
+	}
+	config.AddPromptedEnv(env, proc.EnvPrompt, promptedEnv)
+	if port := proc.FixedPort(); port > 0 {
+		env["PORT"] = strconv.Itoa(port)
+	}
+	return env, nil
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessEnv(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("GLOBAL=1\nSHARED=file"), 0644))
+
+	cfg := &config.Config{
+		EnvFile: ".env",
+		Processes: map[string]config.ProcessConfig{
+			"api": {
+				Cmd:       "go run ./cmd/api",
+				Env:       map[string]string{"SHARED": "inline"},
+				Port:      "4000",
+				EnvPrompt: []string{"STRIPE_KEY"},
+			},
+			"worker": {Cmd: "go run ./cmd/worker", Port: config.PortAuto},
+		},
+	}
+
+	env, err := processEnv(cfg, dir, "api", map[string]string{"STRIPE_KEY": "sk_test"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"GLOBAL":     "1",
+		"SHARED":     "inline",
+		"PORT":       "4000",
+		"STRIPE_KEY": "sk_test",
+	}, env)
+
+	// An auto port is only known to a running prox
+	env, err = processEnv(cfg, dir, "worker", nil)
+	require.NoError(t, err)
+	assert.NotContains(t, env, "PORT")
+
+	_, err = processEnv(cfg, dir, "missing", nil)
+	assert.ErrorContains(t, err, "unknown process: missing")
+}
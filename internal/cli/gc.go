@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/spf13/cobra"
+)
+
+// GC command flags
+var (
+	gcRoots  []string
+	gcDryRun bool
+	gcJSON   bool
+)
+
+// gcCmd represents the gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Clean up state left by stopped prox instances",
+	Long: `Find directories prox has run in whose daemon is no longer running, and
+remove the state it left behind: the state and PID files, the daemon log,
+captured bodies, and output stream sockets. Runtime overrides, TUI
+preferences, last-run snapshots, and postmortems are kept.
+
+Every prox up records its directory in ~/.prox/instances.json. Directories
+that no longer exist, e.g. deleted git worktrees, are dropped from it. Use
+--root to also search a directory tree for .prox directories, which finds
+instances that ran before prox kept the registry.
+
+Examples:
+  prox gc                     # Clean up registered instances
+  prox gc --dry-run           # Show what would be removed
+  prox gc --root ~/src        # Also search ~/src for .prox directories`,
+	Args: cobra.NoArgs,
+	RunE: runGC,
+}
+
+func init() {
+	gcCmd.Flags().StringArrayVar(&gcRoots, "root", nil, "Also search a directory tree for .prox directories (repeatable)")
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "Show what would be removed without removing it")
+	gcCmd.Flags().BoolVar(&gcJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(gcCmd)
+}
+
+// gcReport is the JSON output of prox gc
+type gcReport struct {
+	DryRun         bool            `json:"dry_run"`
+	Instances      []gcReportEntry `json:"instances"`
+	ReclaimedBytes int64           `json:"reclaimed_bytes"`
+}
+
+// gcReportEntry is one cleaned up instance in the JSON output of prox gc
+type gcReportEntry struct {
+	Dir     string   `json:"dir"`
+	Reason  string   `json:"reason"`
+	Removed []string `json:"removed"`
+	Bytes   int64    `json:"bytes"`
+	Error   string   `json:"error,omitempty"`
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	registryPath, err := daemon.RegistryPath()
+	if err != nil {
+		return err
+	}
+	registry, err := daemon.LoadRegistry(registryPath)
+	if err != nil {
+		return err
+	}
+
+	dirs := make([]string, 0, len(registry.Instances))
+	seen := make(map[string]bool)
+	for _, inst := range registry.Instances {
+		dirs = append(dirs, inst.Dir)
+		seen[inst.Dir] = true
+	}
+	found, err := daemon.FindStateDirs(gcRoots)
+	if err != nil {
+		return err
+	}
+	for _, dir := range found {
+		if !seen[dir] {
+			dirs = append(dirs, dir)
+			seen[dir] = true
+		}
+	}
+
+	report := gcReport{DryRun: gcDryRun, Instances: []gcReportEntry{}}
+	unregistered := false
+	for _, item := range daemon.FindGarbage(dirs) {
+		entry := gcReportEntry{Dir: item.Dir, Reason: item.Reason, Removed: []string{}}
+		if !gcDryRun {
+			if err := item.Remove(); err != nil {
+				entry.Error = err.Error()
+				report.Instances = append(report.Instances, entry)
+				continue
+			}
+			if item.Deleted {
+				registry.Remove(item.Dir)
+				unregistered = true
+			}
+		}
+		for _, path := range item.Paths {
+			entry.Removed = append(entry.Removed, filepath.Base(path))
+		}
+		entry.Bytes = item.Bytes
+		report.ReclaimedBytes += item.Bytes
+		report.Instances = append(report.Instances, entry)
+	}
+
+	if unregistered {
+		if err := registry.Write(registryPath); err != nil {
+			return err
+		}
+	}
+
+	if gcJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to encode report: %v\n", err)
+		}
+		return nil
+	}
+
+	if len(report.Instances) == 0 {
+		fmt.Println("Nothing to clean up")
+		return nil
+	}
+	for _, entry := range report.Instances {
+		switch {
+		case entry.Error != "":
+			fmt.Printf("%s  %s: %s\n", entry.Dir, entry.Reason, entry.Error)
+		case len(entry.Removed) == 0 && gcDryRun:
+			fmt.Printf("%s  %s: would unregister\n", entry.Dir, entry.Reason)
+		case len(entry.Removed) == 0:
+			fmt.Printf("%s  %s: unregistered\n", entry.Dir, entry.Reason)
+		default:
+			fmt.Printf("%s  %s: %s (%s)\n", entry.Dir, entry.Reason, strings.Join(entry.Removed, ", "), humanize.Bytes(entry.Bytes))
+		}
+	}
+	verb := "Reclaimed"
+	if gcDryRun {
+		verb = "Would reclaim"
+	}
+	fmt.Printf("\n%s %s from %s\n", verb, humanize.Bytes(report.ReclaimedBytes), humanize.Count(len(report.Instances), "instance", "instances"))
+	return nil
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Output formats for --output
+const (
+	outputText  = "text"
+	outputJSONL = "jsonl"
+)
+
+// checkOutput validates an --output value, which can't be combined with
+// --json
+func checkOutput(output string, jsonFlag bool) error {
+	switch output {
+	case "", outputText:
+		return nil
+	case outputJSONL:
+		if jsonFlag {
+			return fmt.Errorf("--json cannot be used with --output %s", outputJSONL)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid --output %q: must be %s or %s", output, outputText, outputJSONL)
+	}
+}
+
+// writeJSONL writes a record, one of the api package's JSONL types, as one
+// line of JSON
+func writeJSONL(w io.Writer, record interface{}) {
+	if err := json.NewEncoder(w).Encode(record); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to encode record: %v\n", err)
+	}
+}
@@ -2,18 +2,34 @@ package cli
 
 import (
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/charliek/prox/internal/api"
 	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/charliek/prox/internal/theme"
 )
 
+// processPalette are the colors process names are given in turn on a dark
+// background
+var processPalette = []string{
+	"6", // cyan
+	"3", // yellow
+	"2", // green
+	"5", // magenta
+	"4", // blue
+	"1", // red
+}
+
 // LogPrinter handles consistent log formatting and color assignment
 type LogPrinter struct {
 	colors     map[string]string
 	colorIndex int
+	redactor   *logs.Redactor // nil unless --redact is set
+	timeMode   humanize.TimeMode
+	theme      *theme.Theme
 }
 
 // NewLogPrinter creates a new LogPrinter
@@ -23,46 +39,72 @@ func NewLogPrinter() *LogPrinter {
 	}
 }
 
+// SetRedactor sets the redactor used to mask sensitive values in printed lines
+func (lp *LogPrinter) SetRedactor(r *logs.Redactor) {
+	lp.redactor = r
+}
+
+// SetTimeMode sets how timestamps are shown; local time by default
+func (lp *LogPrinter) SetTimeMode(mode humanize.TimeMode) {
+	lp.timeMode = mode
+}
+
+// SetTheme sets the colors and clock of printed lines; the default theme
+// if not set
+func (lp *LogPrinter) SetTheme(t *theme.Theme) {
+	lp.theme = t
+}
+
+// formatTime formats a log entry's timestamp in the printer's time mode
+func (lp *LogPrinter) formatTime(t time.Time) string {
+	return humanize.Time(t, lp.theme.TimeLayout(), lp.timeMode, time.Now())
+}
+
 // PrintEntry prints a log entry with consistent color assignment
 func (lp *LogPrinter) PrintEntry(entry domain.LogEntry) {
-	ts := entry.Timestamp.Format("15:04:05")
-	if lp.isTerminal() {
+	ts := lp.formatTime(entry.Timestamp)
+	if lp.colored() {
 		color := lp.getColor(entry.Process)
-		fmt.Printf("%s %s%-8s%s | %s\n", ts, color, entry.Process, constants.ColorReset, entry.Line)
+		fmt.Printf("%s %s%-8s%s | %s\n", ts, color, entry.Process, constants.ColorReset, lp.redactor.Redact(entry.Line))
 	} else {
-		fmt.Printf("%s %-8s | %s\n", ts, entry.Process, entry.Line)
+		fmt.Printf("%s %-8s | %s\n", ts, entry.Process, lp.redactor.Redact(entry.Line))
 	}
 }
 
 // PrintAPIEntry prints an API log entry response
 func (lp *LogPrinter) PrintAPIEntry(entry api.LogEntryResponse) {
-	ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+	t, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
 	if err != nil {
-		ts = time.Now()
+		t = time.Now()
 	}
-	if lp.isTerminal() {
+	ts := lp.formatTime(t)
+	if lp.colored() {
 		color := lp.getColor(entry.Process)
-		fmt.Printf("%s %s%-8s%s | %s\n", ts.Format("15:04:05"), color, entry.Process, constants.ColorReset, entry.Line)
+		fmt.Printf("%s %s%-8s%s | %s\n", ts, color, entry.Process, constants.ColorReset, lp.redactor.Redact(entry.Line))
 	} else {
-		fmt.Printf("%s %-8s | %s\n", ts.Format("15:04:05"), entry.Process, entry.Line)
+		fmt.Printf("%s %-8s | %s\n", ts, entry.Process, lp.redactor.Redact(entry.Line))
 	}
 }
 
+// getColor returns the escape sequence coloring a process's name: its color
+// in the theme, else the next color of the theme's palette
 func (lp *LogPrinter) getColor(process string) string {
 	color, ok := lp.colors[process]
 	if !ok {
-		color = constants.ProcessColors[lp.colorIndex%len(constants.ProcessColors)]
+		c, set := lp.theme.ProcessColor(process)
+		if !set {
+			palette := lp.theme.Palette(processPalette)
+			c = palette[lp.colorIndex%len(palette)]
+			lp.colorIndex++
+		}
+		color = theme.ANSI(c)
 		lp.colors[process] = color
-		lp.colorIndex++
 	}
 	return color
 }
 
-// isTerminal returns true if stdout is connected to a terminal.
-func (lp *LogPrinter) isTerminal() bool {
-	fi, err := os.Stdout.Stat()
-	if err != nil {
-		return false
-	}
-	return (fi.Mode() & os.ModeCharDevice) != 0
+// colored reports whether printed lines are colored: when stdout is a
+// terminal and the theme doesn't turn colors off
+func (lp *LogPrinter) colored() bool {
+	return isTerminal() && lp.theme.Colored()
 }
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/charliek/prox/internal/fsperm"
+)
+
+// cliPrefs are per-project CLI settings that persist across runs, kept in
+// .prox/cli.json
+type cliPrefs struct {
+	RequestColumns []string `json:"request_columns,omitempty"` // prox requests --columns
+	RequestSort    string   `json:"request_sort,omitempty"`    // prox requests --sort
+}
+
+// loadCLIPrefs reads the project's CLI preferences. A missing file yields
+// empty preferences.
+func loadCLIPrefs(dir string) (*cliPrefs, error) {
+	prefs := &cliPrefs{}
+	data, err := os.ReadFile(daemon.CLIPrefsPath(dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return prefs, nil
+		}
+		return prefs, fmt.Errorf("reading CLI preferences: %w", err)
+	}
+	if err := json.Unmarshal(data, prefs); err != nil {
+		return &cliPrefs{}, fmt.Errorf("unmarshaling CLI preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// save writes the project's CLI preferences
+func (p *cliPrefs) save(dir string) error {
+	path := daemon.CLIPrefsPath(dir)
+	if err := fsperm.MkdirAll(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("creating CLI preferences directory: %w", err)
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling CLI preferences: %w", err)
+	}
+	if err := fsperm.WriteFile(path, data); err != nil {
+		return fmt.Errorf("writing CLI preferences: %w", err)
+	}
+	return nil
+}
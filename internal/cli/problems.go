@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/spf13/cobra"
+)
+
+// Problems command flags
+var (
+	problemsFollow bool
+	problemsSince  string
+	problemsJSON   bool
+	problemsOutput string
+)
+
+// problemsCmd represents the problems command
+var problemsCmd = &cobra.Command{
+	Use:   "problems [process]",
+	Short: "Show compiler and test failures found in logs",
+	Long: `Show the compiler and test failures that problem matchers find in the
+output of processes, e.g. a watched build or test runner. Processes opt in
+with problem_matchers in prox.yaml.
+
+Problems are printed as file:line:column: message, the format editors read
+into their quickfix or problems list. Use -f to print problems as they are
+logged.
+
+Examples:
+  prox problems                # Problems in the buffered logs
+  prox problems build          # Problems from the build process
+  prox problems --since 5m     # Problems from the last 5 minutes
+  prox problems -f             # Stream problems as they are logged
+  prox problems -f -o jsonl    # Stream problems as one JSON object per line`,
+	Args:              cobra.MaximumNArgs(1),
+	RunE:              runProblems,
+	ValidArgsFunction: completeProcessNames,
+}
+
+func init() {
+	problemsCmd.Flags().BoolVarP(&problemsFollow, "follow", "f", false, "Stream problems as they are logged")
+	problemsCmd.Flags().StringVar(&problemsSince, "since", "", "Show problems logged since a time (e.g. 10m or 14:02)")
+	problemsCmd.Flags().BoolVar(&problemsJSON, "json", false, "Output as JSON")
+	problemsCmd.Flags().StringVarP(&problemsOutput, "output", "o", outputText, "Output format: text or jsonl")
+	rootCmd.AddCommand(problemsCmd)
+}
+
+func runProblems(cmd *cobra.Command, args []string) error {
+	if err := checkOutput(problemsOutput, problemsJSON); err != nil {
+		return err
+	}
+	var process string
+	if len(args) > 0 {
+		process = args[0]
+	}
+
+	client := NewClient(apiAddr)
+
+	if problemsFollow {
+		if problemsSince != "" {
+			return fmt.Errorf("--since cannot be used with --follow")
+		}
+		ch, err := client.StreamProblemsChannel(process)
+		if err != nil {
+			return clientError(err, "Is prox running? Try 'prox up' first.")
+		}
+		for problem := range ch {
+			printProblem(problem)
+		}
+		return nil
+	}
+
+	var since time.Time
+	if problemsSince != "" {
+		t, err := parseAtTime(problemsSince, time.Now())
+		if err != nil {
+			return err
+		}
+		since = t
+	}
+	resp, err := client.GetProblems(process, since)
+	if err != nil {
+		return clientError(err, "Is prox running? Try 'prox up' first.")
+	}
+
+	if problemsJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to encode problems: %v\n", err)
+		}
+	} else {
+		for _, problem := range resp.Problems {
+			printProblem(problem)
+		}
+	}
+	if resp.Truncated {
+		fmt.Fprintln(os.Stderr, "Warning: query budget exceeded, results are partial. Narrow the search with a process or --since.")
+	}
+	return nil
+}
+
+// printProblem prints a problem in the format selected by --json and
+// --output
+func printProblem(problem api.ProblemResponse) {
+	switch {
+	case problemsOutput == outputJSONL:
+		writeJSONL(os.Stdout, api.NewJSONLProblem(problem))
+	case problemsJSON:
+		if err := json.NewEncoder(os.Stdout).Encode(problem); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to encode problem: %v\n", err)
+		}
+	default:
+		fmt.Println(formatProblem(problem))
+	}
+}
+
+// formatProblem formats a problem as file:line:column: severity: message,
+// leaving out the column and severity when the matcher didn't capture them
+func formatProblem(p api.ProblemResponse) string {
+	loc := p.File + ":" + strconv.Itoa(p.Line)
+	if p.Column > 0 {
+		loc += ":" + strconv.Itoa(p.Column)
+	}
+	if p.Severity != "" {
+		return loc + ": " + p.Severity + ": " + p.Message
+	}
+	return loc + ": " + p.Message
+}
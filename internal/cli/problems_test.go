@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/charliek/prox/internal/api"
+)
+
+func TestFormatProblem(t *testing.T) {
+	tests := []struct {
+		problem  api.ProblemResponse
+		expected string
+	}{
+		{api.ProblemResponse{File: "main.go", Line: 12, Column: 5, Message: "undefined: x"}, "main.go:12:5: undefined: x"},
+		{api.ProblemResponse{File: "server_test.go", Line: 48, Message: "expected 200"}, "server_test.go:48: expected 200"},
+		{api.ProblemResponse{File: "src/app.ts", Line: 7, Column: 3, Severity: "error", Message: "TS2322: bad type"}, "src/app.ts:7:3: error: TS2322: bad type"},
+	}
+
+	for _, tt := range tests {
+		if result := formatProblem(tt.problem); result != tt.expected {
+			t.Errorf("formatProblem() = %q, expected %q", result, tt.expected)
+		}
+	}
+}
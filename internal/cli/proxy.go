@@ -46,34 +46,43 @@ func runCerts(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create cert manager
-	certMgr := certs.NewManager(cfg.Certs.Dir, cfg.Proxy.Domain)
-
-	// Check mkcert installation
-	if err := certMgr.CheckMkcert(); err != nil {
-		fmt.Println("\nTo install mkcert:")
-		fmt.Println("  macOS:   brew install mkcert")
-		fmt.Println("  Linux:   See https://github.com/FiloSottile/mkcert#installation")
-		fmt.Println("  Windows: choco install mkcert")
-		return fmt.Errorf("mkcert not available: %w", err)
-	}
+	certMgr := certs.NewManager(cfg.Certs.Dir, cfg.Proxy.Domain, cfg.Certs.Provider)
 
-	// Check CA installation
-	caInstalled, err := certMgr.CheckCAInstalled()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: could not check CA status: %v\n", err)
-	}
+	if certMgr.SelfSigned() {
+		fmt.Printf("Domain: %s\n", cfg.Proxy.Domain)
+		fmt.Printf("Certs directory: %s\n", cfg.Certs.Dir)
+		fmt.Println()
+		fmt.Println("Provider: self-signed (browsers will warn; set certs.provider: mkcert for trusted certificates)")
+	} else {
+		// Check mkcert installation
+		if err := certMgr.CheckMkcert(); err != nil {
+			fmt.Println("\nTo install mkcert:")
+			fmt.Println("  macOS:   brew install mkcert")
+			fmt.Println("  Linux:   See https://github.com/FiloSottile/mkcert#installation")
+			fmt.Println("  Windows: choco install mkcert")
+			fmt.Println("\nOr set certs.provider: self-signed to use untrusted certificates without mkcert")
+			return fmt.Errorf("mkcert not available: %w", err)
+		}
 
-	// Print status
-	fmt.Printf("Domain: %s\n", cfg.Proxy.Domain)
-	fmt.Printf("Certs directory: %s\n", cfg.Certs.Dir)
-	fmt.Println()
+		// Check CA installation
+		caInstalled, err := certMgr.CheckCAInstalled()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not check CA status: %v\n", err)
+		}
 
-	if !caInstalled {
-		fmt.Println("CA Status: Not installed")
-		fmt.Println("Run 'mkcert -install' to install the CA (requires sudo)")
-		return nil
+		// Print status
+		fmt.Printf("Domain: %s\n", cfg.Proxy.Domain)
+		fmt.Printf("Certs directory: %s\n", cfg.Certs.Dir)
+		fmt.Println()
+
+		if !caInstalled {
+			fmt.Println("CA Status: Not installed")
+			fmt.Println("Run 'mkcert -install' to install the CA (requires sudo)")
+			return nil
+		}
+		fmt.Println("Provider: mkcert")
+		fmt.Println("CA Status: Installed")
 	}
-	fmt.Println("CA Status: Installed")
 
 	paths := certMgr.GetCertPaths()
 	fmt.Printf("Certificate: %s\n", paths.CertFile)
@@ -123,16 +132,44 @@ configured proxy services.
 
 Examples:
   prox hosts          # Show current status and entries
+  prox hosts sync     # Add or update entries, prompting for sudo if needed
+  prox hosts clean    # Remove entries, prompting for sudo if needed
   prox hosts --add    # Add proxy hosts to /etc/hosts
   prox hosts --remove # Remove proxy hosts from /etc/hosts
   prox hosts --show   # Show entries that would be added`,
 	RunE: runHosts,
 }
 
+// hostsSyncCmd represents the hosts sync command
+var hostsSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Add or update /etc/hosts entries for every service",
+	Long: `Add or update a prox managed block in /etc/hosts that maps the proxy
+domain and each configured <service>.<domain> to 127.0.0.1.
+
+Only the lines between the prox markers are touched. If /etc/hosts can't be
+written directly, the update is made with sudo, which prompts for a password.
+Run it again after adding or removing services.`,
+	Args: cobra.NoArgs,
+	RunE: runHostsSync,
+}
+
+// hostsCleanCmd represents the hosts clean command
+var hostsCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove prox entries from /etc/hosts",
+	Long: `Remove the prox managed block from /etc/hosts, leaving the rest of the
+file untouched. Like sync, it prompts for sudo when needed.`,
+	Args: cobra.NoArgs,
+	RunE: runHostsClean,
+}
+
 func init() {
 	// Register commands
 	rootCmd.AddCommand(certsCmd)
 	rootCmd.AddCommand(hostsCmd)
+	hostsCmd.AddCommand(hostsSyncCmd)
+	hostsCmd.AddCommand(hostsCleanCmd)
 
 	// Certs command flags
 	certsCmd.Flags().BoolVar(&certsRegenerate, "regenerate", false, "Force regenerate certificates")
@@ -144,11 +181,6 @@ func init() {
 }
 
 func runHosts(cmd *cobra.Command, args []string) error {
-	// Load config
-	cfg, err := config.Load(configPath)
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
 
 	// Validate mutually exclusive flags
 	flagsSet := 0
@@ -165,17 +197,10 @@ func runHosts(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("--add, --remove, and --show are mutually exclusive")
 	}
 
-	// Check if proxy is configured
-	if cfg.Proxy == nil || !cfg.Proxy.Enabled {
-		return fmt.Errorf("proxy is not configured or not enabled\nAdd a 'proxy' section to your prox.yaml to enable HTTPS proxy")
-	}
-
-	// Get service names
-	serviceNames := make([]string, 0, len(cfg.Services))
-	for name := range cfg.Services {
-		serviceNames = append(serviceNames, name)
+	cfg, serviceNames, err := loadHostsConfig()
+	if err != nil {
+		return err
 	}
-	sort.Strings(serviceNames)
 
 	if len(serviceNames) == 0 {
 		fmt.Println("No services configured.")
@@ -207,16 +232,26 @@ func runHosts(cmd *cobra.Command, args []string) error {
 		w.Flush()
 		fmt.Println()
 
+		switch {
+		case config.IsLocalhostDomain(cfg.Proxy.Domain):
+			fmt.Println("Status: Not needed, names under .localhost resolve to 127.0.0.1")
+			return nil
+		case cfg.Proxy.DNSBuiltin():
+			fmt.Printf("Status: Not needed, prox answers DNS queries for %s on 127.0.0.1:%d\n", cfg.Proxy.Domain, cfg.Proxy.DNSPortOrDefault())
+			fmt.Println("See the DNS Setup docs to point your resolver at it")
+			return nil
+		}
+
 		if exists {
 			if upToDate {
 				fmt.Println("Status: Entries are up to date in /etc/hosts")
 			} else {
 				fmt.Println("Status: Entries exist but need updating")
-				fmt.Println("Run 'prox hosts --add' to update")
+				fmt.Println("Run 'prox hosts sync' to update")
 			}
 		} else {
 			fmt.Println("Status: Entries not in /etc/hosts")
-			fmt.Println("Run 'prox hosts --add' to add entries (requires sudo)")
+			fmt.Println("Run 'prox hosts sync' to add entries (requires sudo)")
 		}
 
 		return nil
@@ -256,3 +291,80 @@ func runHosts(cmd *cobra.Command, args []string) error {
 	}
 	return nil
 }
+
+// loadHostsConfig loads the config and returns it with the sorted names of
+// the proxied services. Wildcard services are left out: the hosts file can't
+// hold wildcards, so their subdomains need a DNS resolver instead.
+func loadHostsConfig() (*config.Config, []string, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Check if proxy is configured
+	if cfg.Proxy == nil || !cfg.Proxy.Enabled {
+		return nil, nil, fmt.Errorf("proxy is not configured or not enabled\nAdd a 'proxy' section to your prox.yaml to enable HTTPS proxy")
+	}
+
+	return cfg, hostsServiceNames(cfg), nil
+}
+
+// hostsServiceNames returns the services that get a hosts entry, sorted.
+// Wildcard services can't be listed in /etc/hosts.
+func hostsServiceNames(cfg *config.Config) []string {
+	serviceNames := make([]string, 0, len(cfg.Services))
+	for name := range cfg.Services {
+		if _, wildcard := config.WildcardPrefix(name); wildcard {
+			continue
+		}
+		serviceNames = append(serviceNames, name)
+	}
+	sort.Strings(serviceNames)
+	return serviceNames
+}
+
+func runHostsSync(cmd *cobra.Command, args []string) error {
+	cfg, serviceNames, err := loadHostsConfig()
+	if err != nil {
+		return err
+	}
+	return syncHosts(hosts.NewManager(cfg.Proxy.Domain, serviceNames))
+}
+
+// syncHosts brings the managed block in the hosts file up to date
+func syncHosts(hostsMgr *hosts.Manager) error {
+	changed, err := hostsMgr.Sync()
+	if err != nil {
+		return fmt.Errorf("failed to sync hosts entries: %w", err)
+	}
+	if !changed {
+		fmt.Printf("%s is already up to date.\n", hostsMgr.Path())
+		return nil
+	}
+
+	fmt.Printf("Updated %s:\n\n", hostsMgr.Path())
+	fmt.Print(hostsMgr.PrintEntries())
+	fmt.Println()
+	fmt.Println("Run 'prox hosts clean' to remove these entries.")
+	return nil
+}
+
+func runHostsClean(cmd *cobra.Command, args []string) error {
+	// Removing the block doesn't need the services, so a config with the
+	// proxy since removed can still be cleaned up
+	return cleanHosts(hosts.NewManager("", nil))
+}
+
+// cleanHosts removes the managed block from the hosts file
+func cleanHosts(hostsMgr *hosts.Manager) error {
+	changed, err := hostsMgr.Clean()
+	if err != nil {
+		return fmt.Errorf("failed to clean hosts entries: %w", err)
+	}
+	if !changed {
+		fmt.Printf("No prox entries in %s.\n", hostsMgr.Path())
+		return nil
+	}
+	fmt.Printf("Removed prox entries from %s.\n", hostsMgr.Path())
+	return nil
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charliek/prox/internal/proxy/hosts"
+)
+
+func TestSyncAndCleanHosts(t *testing.T) {
+	hostsPath := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(hostsPath, []byte("127.0.0.1 localhost\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mgr := hosts.NewManagerWithPath(hostsPath, "local.myapp.dev", []string{"api", "app"})
+
+	stdout, _ := captureOutput(t, func() {
+		if err := syncHosts(mgr); err != nil {
+			t.Errorf("syncHosts() error = %v", err)
+		}
+	})
+	if !strings.Contains(stdout, "Updated "+hostsPath) {
+		t.Errorf("expected update message, got: %s", stdout)
+	}
+	if !strings.Contains(stdout, "127.0.0.1 local.myapp.dev api.local.myapp.dev app.local.myapp.dev") {
+		t.Errorf("expected entries in output, got: %s", stdout)
+	}
+
+	stdout, _ = captureOutput(t, func() {
+		if err := syncHosts(mgr); err != nil {
+			t.Errorf("syncHosts() error = %v", err)
+		}
+	})
+	if !strings.Contains(stdout, "already up to date") {
+		t.Errorf("expected up-to-date message, got: %s", stdout)
+	}
+
+	stdout, _ = captureOutput(t, func() {
+		if err := cleanHosts(mgr); err != nil {
+			t.Errorf("cleanHosts() error = %v", err)
+		}
+	})
+	if !strings.Contains(stdout, "Removed prox entries") {
+		t.Errorf("expected removal message, got: %s", stdout)
+	}
+	content, err := os.ReadFile(hostsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "127.0.0.1 localhost\n" {
+		t.Errorf("hosts file = %q, want original content", content)
+	}
+
+	stdout, _ = captureOutput(t, func() {
+		if err := cleanHosts(mgr); err != nil {
+			t.Errorf("cleanHosts() error = %v", err)
+		}
+	})
+	if !strings.Contains(stdout, "No prox entries") {
+		t.Errorf("expected nothing-to-remove message, got: %s", stdout)
+	}
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/spf13/cobra"
+)
+
+// PS command flags
+var psJSON bool
+
+// psTimeout bounds each instance's API call, so one hung daemon doesn't
+// hold up the list
+const psTimeout = 2 * time.Second
+
+// psCmd represents the ps command
+var psCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "List the prox instances running on this machine",
+	Long: `List every prox instance running on this machine, from the directories
+recorded in ~/.prox/instances.json by prox up: the project's name and
+directory, API address, uptime, and how many of its processes are running.
+
+Use --project with a directory or name to manage one of them from anywhere.
+
+Examples:
+  prox ps                                 # List running instances
+  prox ps --json                          # As JSON
+  prox stop --project ~/src/api           # Stop the instance in ~/src/api
+  prox attach --project web               # Attach to the instance named web`,
+	Args: cobra.NoArgs,
+	RunE: runPS,
+}
+
+func init() {
+	psCmd.Flags().BoolVar(&psJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(psCmd)
+}
+
+// psEntry is a running instance in the output of prox ps
+type psEntry struct {
+	Name          string `json:"name"` // For --project
+	Dir           string `json:"dir"`
+	ConfigFile    string `json:"config_file"`
+	PID           int    `json:"pid"`
+	URL           string `json:"url"`
+	StartedAt     string `json:"started_at"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	Processes     int    `json:"processes"`
+	Running       int    `json:"running"`
+	Error         string `json:"error,omitempty"` // Why the process counts are missing
+}
+
+// psReport is the JSON output of prox ps
+type psReport struct {
+	Instances []psEntry `json:"instances"`
+}
+
+func runPS(cmd *cobra.Command, args []string) error {
+	registryPath, err := daemon.RegistryPath()
+	if err != nil {
+		return err
+	}
+	registry, err := daemon.LoadRegistry(registryPath)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	report := psReport{Instances: []psEntry{}}
+	for _, inst := range registry.Instances {
+		state, err := daemon.GetRunningState(inst.Dir)
+		if err != nil {
+			continue
+		}
+		report.Instances = append(report.Instances, psInstance(inst.Dir, state, now))
+	}
+
+	if psJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to encode instances: %v\n", err)
+		}
+		return nil
+	}
+	if len(report.Instances) == 0 {
+		fmt.Println("No prox instances running")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDIR\tPID\tAPI\tUPTIME\tPROCESSES")
+	fmt.Fprintln(w, "----\t---\t---\t---\t------\t---------")
+	for _, entry := range report.Instances {
+		processes := fmt.Sprintf("%d/%d running", entry.Running, entry.Processes)
+		if entry.Error != "" {
+			processes = "? (" + entry.Error + ")"
+		}
+		uptime := humanize.Duration(time.Duration(entry.UptimeSeconds) * time.Second)
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", entry.Name, entry.Dir, entry.PID, entry.URL, uptime, processes)
+	}
+	return w.Flush()
+}
+
+// psInstance describes a running instance, asking its API for its processes
+func psInstance(dir string, state *daemon.State, now time.Time) psEntry {
+	entry := psEntry{
+		Name:          filepath.Base(dir),
+		Dir:           dir,
+		ConfigFile:    state.ConfigFile,
+		PID:           state.PID,
+		URL:           stateURL(state),
+		StartedAt:     state.StartedAt.Format(time.RFC3339),
+		UptimeSeconds: int64(now.Sub(state.StartedAt).Seconds()),
+	}
+
+	client := NewClient(entry.URL)
+	client.httpClient.Timeout = psTimeout
+	processes, err := client.GetProcesses()
+	if err != nil {
+		entry.Error = "API unreachable"
+		return entry
+	}
+	entry.Processes = len(processes.Processes)
+	for _, proc := range processes.Processes {
+		if proc.Status == string(domain.ProcessStateRunning) {
+			entry.Running++
+		}
+	}
+	return entry
+}
+
+// resolveProject returns the directory --project names: a directory, or the
+// name of a directory in the instance registry, preferring running
+// instances when several share it
+func resolveProject(project string) (string, error) {
+	if info, err := os.Stat(project); err == nil && info.IsDir() {
+		return filepath.Abs(project)
+	}
+
+	registryPath, err := daemon.RegistryPath()
+	if err != nil {
+		return "", err
+	}
+	registry, err := daemon.LoadRegistry(registryPath)
+	if err != nil {
+		return "", err
+	}
+	var matches, running []string
+	for _, inst := range registry.Instances {
+		if filepath.Base(inst.Dir) != project {
+			continue
+		}
+		matches = append(matches, inst.Dir)
+		if daemon.IsRunning(inst.Dir) {
+			running = append(running, inst.Dir)
+		}
+	}
+	if len(running) > 0 {
+		matches = running
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no project directory or registered project named %q\nSee running instances with 'prox ps'", project)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("project name %q is ambiguous, use its directory: %s", project, strings.Join(matches, ", "))
+	}
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPSInstance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(api.ProcessListResponse{Processes: []api.ProcessResponse{
+			{Name: "web", Status: "running"},
+			{Name: "worker", Status: "crashed"},
+			{Name: "db", Status: "running"},
+		}})
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	now := time.Now()
+	state := &daemon.State{PID: 4242, Host: u.Hostname(), Port: port, StartedAt: now.Add(-90 * time.Second), ConfigFile: "/src/app/prox.yaml"}
+	entry := psInstance("/src/app", state, now)
+	assert.Equal(t, psEntry{
+		Name:          "app",
+		Dir:           "/src/app",
+		ConfigFile:    "/src/app/prox.yaml",
+		PID:           4242,
+		URL:           server.URL,
+		StartedAt:     state.StartedAt.Format(time.RFC3339),
+		UptimeSeconds: 90,
+		Processes:     3,
+		Running:       2,
+	}, entry)
+
+	server.Close()
+	entry = psInstance("/src/app", state, now)
+	assert.Equal(t, "API unreachable", entry.Error)
+	assert.Zero(t, entry.Processes)
+}
+
+func TestResolveProject(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	registryPath, err := daemon.RegistryPath()
+	require.NoError(t, err)
+	registry := &daemon.Registry{}
+	registry.Add("/src/api", "/src/api/prox.yaml", time.Now())
+	registry.Add("/src/web", "/src/web/prox.yaml", time.Now())
+	registry.Add("/old/web", "/old/web/prox.yaml", time.Now())
+	require.NoError(t, registry.Write(registryPath))
+
+	dir, err := resolveProject("api")
+	require.NoError(t, err)
+	assert.Equal(t, "/src/api", dir)
+
+	_, err = resolveProject("web")
+	assert.ErrorContains(t, err, `project name "web" is ambiguous, use its directory: /old/web, /src/web`)
+	_, err = resolveProject("missing")
+	assert.ErrorContains(t, err, `no project directory or registered project named "missing"`)
+
+	// A directory wins over a registered name
+	local := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(local, "api"), 0755))
+	t.Chdir(local)
+	dir, err = resolveProject("api")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(local, "api"), dir)
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
+)
+
+// daemonizeAndWait starts prox up as a daemon, like --detach, and waits for
+// its processes to become ready
+func daemonizeAndWait(dir string) error {
+	child, err := daemon.StartDaemon(dir)
+	if err != nil {
+		return fmt.Errorf("failed to daemonize: %w", err)
+	}
+	if err := child.WaitReady(constants.DaemonStartTimeout); err != nil {
+		return err
+	}
+	fmt.Printf("prox started (pid %d)\n", child.Cmd.Process.Pid)
+
+	exited := make(chan error, 1)
+	go func() { exited <- child.Cmd.Wait() }()
+	return waitUntilReady(dir, exited, upWaitTimeout)
+}
+
+// waitUntilReady polls the daemon started in dir until every process it
+// started is ready, a process fails, or the timeout expires. exited receives
+// the daemon's exit status if it stops during startup.
+func waitUntilReady(dir string, exited <-chan error, timeout time.Duration) error {
+	start := time.Now()
+	deadline := start.Add(timeout)
+	var pending []string
+	for {
+		select {
+		case <-exited:
+			return fmt.Errorf("prox exited during startup (see %s)", daemon.LogPath(dir))
+		default:
+		}
+
+		// The API is served once every process has been started
+		if state, err := daemon.GetRunningState(dir); err == nil {
+			client := NewClient(stateURL(state))
+			if resp, err := client.GetProcesses(); err == nil {
+				var failed []string
+				pending, failed = processReadiness(resp.Processes)
+				if len(failed) > 0 {
+					return fmt.Errorf("processes failed to start: %s (prox is still running; see 'prox logs')", strings.Join(failed, "; "))
+				}
+				if len(pending) == 0 {
+					fmt.Printf("All processes ready after %s\n", humanize.Duration(time.Since(start)))
+					return nil
+				}
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if len(pending) == 0 {
+				return fmt.Errorf("prox did not start within %s (see %s)", timeout, daemon.LogPath(dir))
+			}
+			return fmt.Errorf("timed out after %s waiting for %s to become ready (prox is still running)", timeout, strings.Join(pending, ", "))
+		}
+		time.Sleep(constants.UpWaitPollInterval)
+	}
+}
+
+// processReadiness returns the processes still starting, waiting on their
+// ready probe, and the processes that failed with why. Stopped processes,
+// e.g. lazy ones, don't hold up readiness unless they failed to start.
+func processReadiness(processes []api.ProcessResponse) (pending, failed []string) {
+	for _, p := range processes {
+		switch domain.ProcessState(p.Status) {
+		case domain.ProcessStateStarting:
+			pending = append(pending, p.Name)
+		case domain.ProcessStateCrashed:
+			failed = append(failed, failureReason(p, "crashed"))
+		case domain.ProcessStateFailed:
+			failed = append(failed, failureReason(p, "crashed too often"))
+		case domain.ProcessStateStopped:
+			if p.LastError != "" {
+				failed = append(failed, failureReason(p, ""))
+			}
+		}
+	}
+	sort.Strings(pending)
+	sort.Strings(failed)
+	return pending, failed
+}
+
+// failureReason describes a failed process, preferring its last start error
+func failureReason(p api.ProcessResponse, fallback string) string {
+	if p.LastError != "" {
+		return p.Name + ": " + p.LastError
+	}
+	return p.Name + ": " + fallback
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/charliek/prox/internal/api"
+)
+
+func TestProcessReadiness(t *testing.T) {
+	processes := []api.ProcessResponse{
+		{Name: "web", Status: "running"},
+		{Name: "worker", Status: "starting"},
+		{Name: "api", Status: "starting", LastError: "process not ready: not ready after 1m0s"},
+		{Name: "db", Status: "crashed"},
+		{Name: "cache", Status: "stopped", LastError: "wait_for tcp://localhost:6379: timed out"},
+		{Name: "docs", Status: "stopped"},
+	}
+
+	pending, failed := processReadiness(processes)
+	if want := []string{"api", "worker"}; !slices.Equal(pending, want) {
+		t.Errorf("pending = %v, expected %v", pending, want)
+	}
+	want := []string{"cache: wait_for tcp://localhost:6379: timed out", "db: crashed"}
+	if !slices.Equal(failed, want) {
+		t.Errorf("failed = %v, expected %v", failed, want)
+	}
+}
@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/humanize"
+)
+
+// requestColumn is a column of the prox requests table
+type requestColumn struct {
+	header string
+	width  int // Width of the rule under the header, and of the column in --follow output
+	value  func(req api.ProxyRequestResponse, mode humanize.TimeMode) string
+}
+
+// requestColumns are the columns --columns can choose, by name
+var requestColumns = map[string]requestColumn{
+	"id": {"ID", 7, func(req api.ProxyRequestResponse, _ humanize.TimeMode) string {
+		return req.ID
+	}},
+	"time": {"TIME", 8, func(req api.ProxyRequestResponse, mode humanize.TimeMode) string {
+		ts, _ := time.Parse(time.RFC3339Nano, req.Timestamp)
+		return humanize.Time(ts, "15:04:05", mode, time.Now())
+	}},
+	"method": {"METHOD", 6, func(req api.ProxyRequestResponse, _ humanize.TimeMode) string {
+		return req.Method
+	}},
+	"status": {"STATUS", 6, func(req api.ProxyRequestResponse, _ humanize.TimeMode) string {
+		return strconv.Itoa(req.StatusCode)
+	}},
+	"duration": {"DURATION", 8, func(req api.ProxyRequestResponse, _ humanize.TimeMode) string {
+		return humanMs(req.DurationMs)
+	}},
+	"subdomain": {"SUBDOMAIN", 9, func(req api.ProxyRequestResponse, _ humanize.TimeMode) string {
+		return req.Subdomain
+	}},
+	"url": {"URL", 3, func(req api.ProxyRequestResponse, _ humanize.TimeMode) string {
+		return req.URL
+	}},
+	"bytes": {"BYTES", 8, func(req api.ProxyRequestResponse, _ humanize.TimeMode) string {
+		return humanize.Bytes(req.ResponseBytes)
+	}},
+}
+
+// requestColumnNames lists the column names in the order they're documented
+var requestColumnNames = []string{"id", "time", "method", "status", "duration", "subdomain", "url", "bytes"}
+
+// defaultRequestColumns are the columns shown without --columns or saved
+// defaults
+var defaultRequestColumns = []string{"id", "time", "method", "status", "duration", "url"}
+
+// parseRequestColumns parses a comma-separated --columns value
+func parseRequestColumns(value string) ([]string, error) {
+	var columns []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := requestColumns[name]; !ok {
+			return nil, fmt.Errorf("unknown column %q (want %s)", name, strings.Join(requestColumnNames, ", "))
+		}
+		if slices.Contains(columns, name) {
+			return nil, fmt.Errorf("column %q is listed twice", name)
+		}
+		columns = append(columns, name)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns given (want some of %s)", strings.Join(requestColumnNames, ", "))
+	}
+	return columns, nil
+}
+
+// requestSortTime is the default --sort order: newest first, as the API
+// lists requests
+const requestSortTime = "time"
+
+// requestSorts compares requests for each --sort order, largest first
+var requestSorts = map[string]func(a, b api.ProxyRequestResponse) int{
+	requestSortTime: nil, // The API's order
+	"duration": func(a, b api.ProxyRequestResponse) int {
+		return cmp.Compare(b.DurationMs, a.DurationMs)
+	},
+	"status": func(a, b api.ProxyRequestResponse) int {
+		return cmp.Compare(b.StatusCode, a.StatusCode)
+	},
+	"bytes": func(a, b api.ProxyRequestResponse) int {
+		return cmp.Compare(b.ResponseBytes, a.ResponseBytes)
+	},
+}
+
+// checkRequestSort returns an error if key isn't a --sort order
+func checkRequestSort(key string) error {
+	if _, ok := requestSorts[key]; !ok {
+		return fmt.Errorf("invalid --sort %q (want time, duration, status, or bytes)", key)
+	}
+	return nil
+}
+
+// sortRequests sorts requests in place by a --sort order. Ties keep the
+// API's order.
+func sortRequests(reqs []api.ProxyRequestResponse, key string) {
+	if compare := requestSorts[key]; compare != nil {
+		slices.SortStableFunc(reqs, compare)
+	}
+}
+
+// requestMarks labels requests that aren't plain recorded HTTP requests
+func requestMarks(req api.ProxyRequestResponse) string {
+	mark := websocketMark(req)
+	if req.Imported {
+		mark += " [imported]"
+	}
+	return mark
+}
+
+// writeRequestsTable writes requests as a table with the given columns
+func writeRequestsTable(out io.Writer, reqs []api.ProxyRequestResponse, columns []string, mode humanize.TimeMode) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	headers := make([]string, len(columns))
+	rules := make([]string, len(columns))
+	for i, name := range columns {
+		headers[i] = requestColumns[name].header
+		rules[i] = strings.Repeat("-", requestColumns[name].width)
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	fmt.Fprintln(w, strings.Join(rules, "\t"))
+
+	for _, req := range reqs {
+		cells := make([]string, len(columns))
+		for i, name := range columns {
+			cells[i] = requestColumns[name].value(req, mode)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t")+requestMarks(req))
+	}
+	w.Flush()
+}
+
+// formatRequestRow formats a request as a line of --follow output with the
+// given columns, padded to the columns' widths since rows are printed as they
+// arrive. The status is colored when color is set. With a nil request it
+// formats the header.
+func formatRequestRow(req *api.ProxyRequestResponse, columns []string, mode humanize.TimeMode, color bool) string {
+	cells := make([]string, len(columns))
+	for i, name := range columns {
+		col := requestColumns[name]
+		cell := col.header
+		if req != nil {
+			cell = col.value(*req, mode)
+		}
+		if i < len(columns)-1 {
+			cell = fmt.Sprintf("%-*s", col.width, cell)
+		}
+		if req != nil && name == "status" && color {
+			cell = statusColor(req.StatusCode) + cell + constants.ColorReset
+		}
+		cells[i] = cell
+	}
+	line := strings.Join(cells, "  ")
+	if req != nil {
+		line += requestMarks(*req)
+	}
+	return line
+}
+
+// statusColor returns the terminal color for an HTTP status code
+func statusColor(code int) string {
+	switch {
+	case code >= 500:
+		return constants.ColorStatusServer
+	case code >= 400:
+		return constants.ColorStatusClient
+	case code >= 300:
+		return constants.ColorStatusRedirect
+	case code >= 200:
+		return constants.ColorStatusSuccess
+	}
+	return ""
+}
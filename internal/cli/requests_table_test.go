@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/charliek/prox/internal/humanize"
+)
+
+func TestParseRequestColumns(t *testing.T) {
+	columns, err := parseRequestColumns(" Time,status , url,bytes")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"time", "status", "url", "bytes"}, columns)
+
+	for _, value := range []string{"", ",", "id,size", "id,id"} {
+		_, err := parseRequestColumns(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestSortRequests(t *testing.T) {
+	reqs := []api.ProxyRequestResponse{
+		{ID: "a", DurationMs: 10, StatusCode: 200, ResponseBytes: 300},
+		{ID: "b", DurationMs: 900, StatusCode: 500, ResponseBytes: 10},
+		{ID: "c", DurationMs: 10, StatusCode: 404, ResponseBytes: 2000},
+	}
+	ids := func() string {
+		var s string
+		for _, req := range reqs {
+			s += req.ID
+		}
+		return s
+	}
+
+	sortRequests(reqs, requestSortTime)
+	assert.Equal(t, "abc", ids())
+	sortRequests(reqs, "duration")
+	assert.Equal(t, "bac", ids(), "ties keep their order")
+	sortRequests(reqs, "status")
+	assert.Equal(t, "bca", ids())
+	sortRequests(reqs, "bytes")
+	assert.Equal(t, "cab", ids())
+
+	assert.Error(t, checkRequestSort("size"))
+}
+
+func TestWriteRequestsTable(t *testing.T) {
+	reqs := []api.ProxyRequestResponse{
+		{ID: "abc1234", Timestamp: "2024-01-15T10:30:00Z", Method: "GET", URL: "/users", Subdomain: "api", StatusCode: 200, DurationMs: 45, ResponseBytes: 2048},
+		{ID: "def5678", Timestamp: "2024-01-15T10:30:01Z", Method: "GET", URL: "/socket", Subdomain: "app", StatusCode: 101, Type: "websocket"},
+	}
+
+	var out bytes.Buffer
+	writeRequestsTable(&out, reqs, []string{"subdomain", "status", "bytes", "url"}, humanize.TimeUTC)
+	assert.Equal(t, strings.Join([]string{
+		"SUBDOMAIN  STATUS  BYTES     URL",
+		"---------  ------  --------  ---",
+		"api        200     2 KiB     /users",
+		"app        101     0 B       /socket [websocket]",
+		"",
+	}, "\n"), out.String())
+
+	assert.Equal(t, "STATUS  TIME      URL", formatRequestRow(nil, []string{"status", "time", "url"}, humanize.TimeUTC, false))
+	assert.Equal(t, "200     10:30:00  /users", formatRequestRow(&reqs[0], []string{"status", "time", "url"}, humanize.TimeUTC, false))
+}
+
+func TestRequestsLayout(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	defer func() {
+		requestsColumns, requestsSort, requestsSave, requestsFollow = "", requestSortTime, false, false
+	}()
+
+	newCmd := func(args ...string) *cobra.Command {
+		requestsColumns, requestsSort, requestsSave, requestsFollow = "", requestSortTime, false, false
+		cmd := &cobra.Command{}
+		cmd.Flags().StringVar(&requestsColumns, "columns", "", "")
+		cmd.Flags().StringVar(&requestsSort, "sort", requestSortTime, "")
+		cmd.Flags().BoolVar(&requestsSave, "save-defaults", false, "")
+		cmd.Flags().BoolVar(&requestsFollow, "follow", false, "")
+		require.NoError(t, cmd.Flags().Parse(args))
+		return cmd
+	}
+
+	// Without flags or saved defaults, the table is as it always was
+	layout, err := requestsLayout(newCmd())
+	require.NoError(t, err)
+	assert.Equal(t, requestsTableLayout{columns: defaultRequestColumns, sort: requestSortTime}, layout)
+
+	// Saved defaults apply to later runs
+	_, _ = captureOutput(t, func() {
+		_, err = requestsLayout(newCmd("--columns", "time,url", "--sort", "duration", "--save-defaults"))
+	})
+	require.NoError(t, err)
+	layout, err = requestsLayout(newCmd())
+	require.NoError(t, err)
+	assert.Equal(t, requestsTableLayout{columns: []string{"time", "url"}, sort: "duration", custom: true}, layout)
+
+	// The saved order doesn't apply to --follow, where --sort is an error
+	layout, err = requestsLayout(newCmd("--follow"))
+	require.NoError(t, err)
+	assert.Equal(t, requestSortTime, layout.sort)
+	_, err = requestsLayout(newCmd("--follow", "--sort", "bytes"))
+	assert.Error(t, err)
+
+	// --save-defaults alone clears them
+	_, _ = captureOutput(t, func() {
+		_, err = requestsLayout(newCmd("--save-defaults"))
+	})
+	require.NoError(t, err)
+	layout, err = requestsLayout(newCmd())
+	require.NoError(t, err)
+	assert.False(t, layout.custom)
+	_, err = os.Stat(daemon.CLIPrefsPath(dir))
+	assert.NoError(t, err)
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -20,8 +21,33 @@ var (
 	apiAddrExplicitlySet bool
 	detach               bool
 	verbose              bool
+	sshFlag              string
+	projectFlag          string
+	projectDir           string // The directory --project resolved to
 )
 
+// sshTunnelActive is the --ssh port forward for the current command, if any
+var sshTunnelActive *sshTunnel
+
+// clientCommands are the commands that talk to a running daemon's API
+var clientCommands = map[string]bool{
+	"status":   true,
+	"logs":     true,
+	"start":    true,
+	"stop":     true,
+	"restart":  true,
+	"drain":    true,
+	"down":     true,
+	"attach":   true,
+	"signal":   true,
+	"reload":   true,
+	"requests": true,
+	"events":   true,
+	"problems": true,
+	"task":     true,
+	"run":      true,
+}
+
 // rootCmd represents the base command
 var rootCmd = &cobra.Command{
 	Use:   "prox",
@@ -36,30 +62,90 @@ processes for local development. It supports:
 	Version:       Version,
 	SilenceUsage:  true,
 	SilenceErrors: true,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// Check if --addr was explicitly provided
 		if cmd.Flags().Changed("addr") {
 			apiAddrExplicitlySet = true
 		}
 
-		// For client commands, try to discover API address if not explicitly set
-		clientCommands := map[string]bool{
-			"status":  true,
-			"logs":    true,
-			"stop":    true,
-			"restart": true,
-			"down":    true,
-			"attach":  true,
+		// Without a prox.yaml, fall back to a Procfile
+		if !cmd.Flags().Changed("config") {
+			configPath = config.DetectConfigFile(configPath)
+		}
+
+		isClientCommand := clientCommands[cmd.Name()] && cmd.Parent() == cmd.Root()
+
+		if apiCAFile != "" {
+			if err := trustCert(apiCAFile); err != nil {
+				return err
+			}
+		}
+
+		// With --project, reach the daemon running in another directory
+		if projectFlag != "" {
+			if !isClientCommand {
+				return fmt.Errorf("--project is not supported by 'prox %s'", cmd.Name())
+			}
+			if apiAddrExplicitlySet || sshFlag != "" {
+				return fmt.Errorf("--project can't be combined with --addr or --ssh")
+			}
+			dir, err := resolveProject(projectFlag)
+			if err != nil {
+				return err
+			}
+			projectDir = dir
+			state, err := daemon.GetRunningState(dir)
+			if err != nil {
+				if err == daemon.ErrNotRunning {
+					return fmt.Errorf("prox is not running in %s\nSee running instances with 'prox ps'", dir)
+				}
+				return fmt.Errorf("failed to get daemon state: %w", err)
+			}
+			apiAddr = stateURL(state)
+			apiAddrExplicitlySet = true
+			return nil
 		}
-		if clientCommands[cmd.Name()] && !apiAddrExplicitlySet {
+
+		// With --ssh, reach the remote daemon through an ssh port forward
+		if sshFlag != "" {
+			if !isClientCommand {
+				return fmt.Errorf("--ssh is not supported by 'prox %s'", cmd.Name())
+			}
+			if apiAddrExplicitlySet {
+				return fmt.Errorf("--ssh and --addr are mutually exclusive")
+			}
+			target, err := parseSSHTarget(sshFlag)
+			if err != nil {
+				return err
+			}
+			tunnel, err := openSSHTunnel(target)
+			if err != nil {
+				return clientError(err, "Is prox running on the remote host? Try 'prox up -d' there first.")
+			}
+			sshTunnelActive = tunnel
+			apiAddr = tunnel.addr
+			return nil
+		}
+
+		// For client commands, try to discover API address if not explicitly set
+		if isClientCommand && !apiAddrExplicitlySet {
 			apiAddr = discoverAPIAddress()
 		}
+		return nil
 	},
 }
 
 // Execute runs the root command
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	if err := sshTunnelActive.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close ssh tunnel: %v\n", err)
+	}
+	var exit *exitError
+	if errors.As(err, &exit) {
+		os.Exit(exit.code)
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -78,8 +164,11 @@ func init() {
 	// Persistent flags available to all subcommands
 	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", constants.DefaultConfigFile, "Config file")
 	rootCmd.PersistentFlags().StringVar(&apiAddr, "addr", constants.DefaultAPIAddress, "API address for remote commands")
+	rootCmd.PersistentFlags().StringVar(&apiCAFile, "ca", "", "CA certificate to trust for an https:// API address")
 	rootCmd.PersistentFlags().BoolVarP(&detach, "detach", "d", false, "Run in background (daemon mode)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().StringVar(&sshFlag, "ssh", "", "Manage a daemon on a remote host over SSH ([user@]host[:dir])")
+	rootCmd.PersistentFlags().StringVar(&projectFlag, "project", "", "Manage the daemon running in another project directory")
 
 	// Set version template
 	rootCmd.SetVersionTemplate("prox version {{.Version}}\n")
@@ -105,7 +194,11 @@ func loadAPIAddrFromConfig() string {
 		port = constants.DefaultAPIPort
 	}
 
-	return fmt.Sprintf("http://%s:%d", host, port)
+	scheme := "http"
+	if cfg.API.TLS.IsEnabled() {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, host, port)
 }
 
 // discoverAPIAddress attempts to discover the API address.
@@ -119,7 +212,7 @@ func discoverAPIAddress() string {
 	if err == nil {
 		state, err := daemon.LoadState(cwd)
 		if err == nil {
-			return fmt.Sprintf("http://%s:%d", state.Host, state.Port)
+			return stateURL(state)
 		}
 	}
 
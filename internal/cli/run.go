@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/spf13/cobra"
+)
+
+// Run command flags
+var runEnv []string
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run <name> -- <command> [args...]",
+	Short: "Start an ad-hoc process without adding it to the config",
+	Long: `Start a process that isn't in prox.yaml in the running prox instance.
+
+The process is managed like a configured one: its output shows in prox logs
+and the TUI, and prox start, stop, and restart work on it. It gets the global
+env_file, plus any variables set with --env. Like commands in prox.yaml, the
+command runs through the shell, so quote it to use pipes or variables.
+
+Ad-hoc processes are saved in .prox/runtime.json, so the next prox up starts
+them again (prox up --fresh forgets them). A reload leaves them running,
+unless the config gains a process with the same name, which replaces the
+ad-hoc one.
+
+Examples:
+  prox run tunnel -- ngrok http 3000
+  prox run queue -e QUEUE=high -- bundle exec sidekiq
+  prox run watch -- 'npm run build -- --watch | tee build.log'`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runRun,
+}
+
+func init() {
+	runCmd.Flags().StringArrayVarP(&runEnv, "env", "e", nil, "Set an environment variable, as KEY=VALUE (repeatable)")
+	rootCmd.AddCommand(runCmd)
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 && dash != 1 {
+		return fmt.Errorf("expected prox run <name> -- <command>")
+	}
+
+	req := api.AddProcessRequest{
+		Name: args[0],
+		Cmd:  strings.Join(args[1:], " "),
+	}
+	for _, kv := range runEnv {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid --env %q: expected KEY=VALUE", kv)
+		}
+		if req.Env == nil {
+			req.Env = make(map[string]string)
+		}
+		req.Env[key] = value
+	}
+
+	client := NewClient(apiAddr)
+	proc, err := client.AddProcess(req)
+	if err != nil {
+		return clientError(err, "Is prox running? Try 'prox up' first.")
+	}
+
+	fmt.Printf("Started process: %s (pid %d)\n", proc.Name, proc.PID)
+	fmt.Printf("Follow its output with 'prox logs -f --process %s'\n", proc.Name)
+	return nil
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/daemon"
+)
+
+// sshCommand builds the ssh invocation; tests replace it to avoid a real ssh
+var sshCommand = func(args ...string) *exec.Cmd {
+	return exec.Command("ssh", args...)
+}
+
+// sshTarget is a parsed --ssh value of the form [user@]host[:dir]
+type sshTarget struct {
+	Host string // [user@]host, passed to ssh as-is
+	Dir  string // Remote project directory holding .prox (default: login directory)
+}
+
+// parseSSHTarget parses a --ssh value of the form [user@]host[:dir]
+func parseSSHTarget(value string) (sshTarget, error) {
+	host, dir, _ := strings.Cut(value, ":")
+	if host == "" || strings.HasPrefix(host, "-") {
+		return sshTarget{}, fmt.Errorf("invalid --ssh value %q: expected [user@]host[:dir]", value)
+	}
+	if dir == "" {
+		dir = "."
+	}
+	return sshTarget{Host: host, Dir: dir}, nil
+}
+
+// remoteStatePath returns the state file path on the remote host as a shell
+// word. A leading ~/ is left unquoted so the remote shell expands it.
+func (t sshTarget) remoteStatePath() string {
+	path := strings.TrimSuffix(t.Dir, "/") + "/" + daemon.StateDirName + "/" + daemon.StateFileName
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return "~/" + shellQuote(rest)
+	}
+	return shellQuote(path)
+}
+
+// shellQuote single-quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// loadRemoteState reads the prox state file on the remote host
+func loadRemoteState(target sshTarget) (*daemon.State, error) {
+	cmd := sshCommand(target.Host, "cat "+target.remoteStatePath())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("reading prox state on %s: %s", target.Host, msg)
+	}
+	return daemon.ParseState(out)
+}
+
+// sshTunnel is a running ssh port forward to a remote prox API
+type sshTunnel struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+	addr string // Local API address, e.g. http://127.0.0.1:54321
+}
+
+// openSSHTunnel finds the remote daemon's API port from its state file and
+// forwards a local port to it
+func openSSHTunnel(target sshTarget) (*sshTunnel, error) {
+	state, err := loadRemoteState(target)
+	if err != nil {
+		return nil, err
+	}
+
+	localPort, err := daemon.FindAvailablePort(constants.DefaultAPIHost)
+	if err != nil {
+		return nil, err
+	}
+	localAddr := net.JoinHostPort(constants.DefaultAPIHost, strconv.Itoa(localPort))
+	forward := localAddr + ":" + net.JoinHostPort(state.Host, strconv.Itoa(state.Port))
+
+	cmd := sshCommand("-N", "-o", "ExitOnForwardFailure=yes", "-L", forward, target.Host)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting ssh: %w", err)
+	}
+
+	scheme := "http://"
+	if state.TLS {
+		scheme = "https://"
+	}
+	tunnel := &sshTunnel{
+		cmd:  cmd,
+		done: make(chan struct{}),
+		addr: scheme + localAddr,
+	}
+	go func() {
+		_ = cmd.Wait()
+		close(tunnel.done)
+	}()
+
+	// Wait for the local end of the forward to accept connections
+	deadline := time.Now().Add(constants.SSHTunnelTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-tunnel.done:
+			return nil, fmt.Errorf("ssh tunnel to %s failed: %s", target.Host, strings.TrimSpace(stderr.String()))
+		default:
+		}
+		if conn, err := net.DialTimeout("tcp", localAddr, constants.SSHTunnelPollInterval); err == nil {
+			conn.Close()
+			return tunnel, nil
+		}
+		time.Sleep(constants.SSHTunnelPollInterval)
+	}
+
+	tunnel.Close()
+	return nil, fmt.Errorf("ssh tunnel to %s not ready after %s", target.Host, constants.SSHTunnelTimeout)
+}
+
+// Close stops the ssh process
+func (t *sshTunnel) Close() error {
+	if t == nil || t.cmd.Process == nil {
+		return nil
+	}
+	select {
+	case <-t.done:
+		return nil
+	default:
+	}
+	if err := t.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	<-t.done
+	return nil
+}
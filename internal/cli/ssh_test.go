@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// fakeSSH replaces sshCommand with this test binary acting as ssh (see
+// TestSSHHelperProcess) and restores it when the test ends
+func fakeSSH(t *testing.T, state string) {
+	t.Helper()
+	orig := sshCommand
+	t.Cleanup(func() { sshCommand = orig })
+	sshCommand = func(args ...string) *exec.Cmd {
+		cmd := exec.Command(os.Args[0], append([]string{"-test.run=TestSSHHelperProcess", "--"}, args...)...)
+		cmd.Env = append(os.Environ(), "PROX_FAKE_SSH=1", "PROX_FAKE_SSH_STATE="+state)
+		return cmd
+	}
+}
+
+// TestSSHHelperProcess is not a real test. It stands in for ssh: a remote
+// "cat" prints PROX_FAKE_SSH_STATE, and "-N -L" listens on the local end of
+// the forward until killed.
+func TestSSHHelperProcess(t *testing.T) {
+	if os.Getenv("PROX_FAKE_SSH") != "1" {
+		return
+	}
+	args := os.Args
+	for i, arg := range args {
+		if arg == "--" {
+			args = args[i+1:]
+			break
+		}
+	}
+
+	for i, arg := range args {
+		if arg == "-L" {
+			// local_host:local_port:remote_host:remote_port
+			parts := strings.Split(args[i+1], ":")
+			listener, err := net.Listen("tcp", parts[0]+":"+parts[1])
+			if err != nil {
+				os.Exit(2)
+			}
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					os.Exit(2)
+				}
+				conn.Close()
+			}
+		}
+	}
+
+	state := os.Getenv("PROX_FAKE_SSH_STATE")
+	if state == "" {
+		fmt.Fprintln(os.Stderr, "cat: .prox/prox.state: No such file or directory")
+		os.Exit(1)
+	}
+	fmt.Print(state)
+	os.Exit(0)
+}
+
+func TestParseSSHTarget(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected sshTarget
+		wantErr  bool
+	}{
+		{"devbox", sshTarget{Host: "devbox", Dir: "."}, false},
+		{"me@devbox", sshTarget{Host: "me@devbox", Dir: "."}, false},
+		{"me@devbox:~/src/app", sshTarget{Host: "me@devbox", Dir: "~/src/app"}, false},
+		{"devbox:/srv/app", sshTarget{Host: "devbox", Dir: "/srv/app"}, false},
+		{"", sshTarget{}, true},
+		{":/srv/app", sshTarget{}, true},
+		{"-oProxyCommand=x", sshTarget{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			result, err := parseSSHTarget(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseSSHTarget(%q) expected error", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSSHTarget(%q) unexpected error: %v", tt.value, err)
+			}
+			if result != tt.expected {
+				t.Errorf("parseSSHTarget(%q) = %+v, expected %+v", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSSHTarget_RemoteStatePath(t *testing.T) {
+	tests := []struct {
+		dir      string
+		expected string
+	}{
+		{".", `'./.prox/prox.state'`},
+		{"~/src/app/", `~/'src/app/.prox/prox.state'`},
+		{"/srv/it's", `'/srv/it'\''s/.prox/prox.state'`},
+	}
+
+	for _, tt := range tests {
+		result := sshTarget{Host: "devbox", Dir: tt.dir}.remoteStatePath()
+		if result != tt.expected {
+			t.Errorf("remoteStatePath(%q) = %s, expected %s", tt.dir, result, tt.expected)
+		}
+	}
+}
+
+func TestLoadRemoteState(t *testing.T) {
+	fakeSSH(t, `{"pid": 42, "port": 5555, "host": "127.0.0.1", "config_file": "prox.yaml"}`)
+
+	state, err := loadRemoteState(sshTarget{Host: "devbox", Dir: "."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.Port != 5555 || state.Host != "127.0.0.1" {
+		t.Errorf("unexpected state: %+v", state)
+	}
+}
+
+func TestLoadRemoteState_NotRunning(t *testing.T) {
+	fakeSSH(t, "")
+
+	_, err := loadRemoteState(sshTarget{Host: "devbox", Dir: "."})
+	if err == nil {
+		t.Fatal("expected error when remote state is missing")
+	}
+	if !strings.Contains(err.Error(), "No such file") {
+		t.Errorf("expected ssh stderr in error, got %v", err)
+	}
+}
+
+func TestOpenSSHTunnel(t *testing.T) {
+	fakeSSH(t, `{"pid": 42, "port": 5555, "host": "127.0.0.1", "config_file": "prox.yaml"}`)
+
+	tunnel, err := openSSHTunnel(sshTarget{Host: "devbox", Dir: "."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(tunnel.addr, "http://127.0.0.1:") {
+		t.Errorf("unexpected tunnel address %q", tunnel.addr)
+	}
+
+	if err := tunnel.Close(); err != nil {
+		t.Errorf("unexpected close error: %v", err)
+	}
+	// Closing twice is harmless
+	if err := tunnel.Close(); err != nil {
+		t.Errorf("unexpected second close error: %v", err)
+	}
+}
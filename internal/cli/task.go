@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/spf13/cobra"
+)
+
+// Task command flags
+var taskJSON bool
+
+// taskPollInterval is how often prox task checks whether the task finished
+const taskPollInterval = 200 * time.Millisecond
+
+// taskDrainTimeout is how long prox task waits for output still on its way
+// once the task has finished
+const taskDrainTimeout = 250 * time.Millisecond
+
+// taskCmd represents the task command
+var taskCmd = &cobra.Command{
+	Use:   "task [name]",
+	Short: "Run a one-shot task",
+	Long: `Run a task from the config's tasks section, such as a database
+migration or seeder, in the running prox.
+
+The task's output is printed as it runs, and is kept in the logs under the
+task's name. prox task exits with the task's exit status. Tasks aren't started
+by prox up, restarted, or health checked, and a task can't be run again until
+its last run has finished.
+
+Without a name, lists the tasks with the status of their last run.
+
+Examples:
+  prox task                 # List tasks
+  prox task migrate         # Run the migrate task
+  prox logs migrate         # Output of its last runs`,
+	Args:              cobra.MaximumNArgs(1),
+	RunE:              runTask,
+	ValidArgsFunction: completeTaskNames,
+}
+
+func init() {
+	taskCmd.Flags().BoolVar(&taskJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(taskCmd)
+}
+
+func runTask(cmd *cobra.Command, args []string) error {
+	client := NewClient(apiAddr)
+	if len(args) == 0 {
+		return listTasks(client)
+	}
+	name := args[0]
+
+	redactor, err := newRedactor(nil)
+	if err != nil {
+		return err
+	}
+	mode, err := timeMode()
+	if err != nil {
+		return err
+	}
+	printer := NewLogPrinter()
+	printer.SetRedactor(redactor)
+	printer.SetTimeMode(mode)
+	printer.SetTheme(outputTheme())
+
+	// Follow the task's logs before starting it, so no line is missed
+	ch, err := client.StreamLogsChannel(domain.LogParams{Process: name})
+	if err != nil {
+		return clientError(err, "Is prox running? Try 'prox up' first.")
+	}
+	task, err := client.RunTask(name)
+	if err != nil {
+		return clientError(err, "")
+	}
+
+	ticker := time.NewTicker(taskPollInterval)
+	defer ticker.Stop()
+	for task.Status == string(domain.TaskStateRunning) {
+		select {
+		case entry, ok := <-ch:
+			if !ok {
+				ch = nil
+				continue
+			}
+			printer.PrintAPIEntry(entry)
+		case <-ticker.C:
+			if task, err = client.GetTask(name); err != nil {
+				return clientError(err, "")
+			}
+		}
+	}
+
+	// Print the output still on its way
+	for ch != nil {
+		select {
+		case entry, ok := <-ch:
+			if !ok {
+				ch = nil
+				continue
+			}
+			printer.PrintAPIEntry(entry)
+		case <-time.After(taskDrainTimeout):
+			ch = nil
+		}
+	}
+
+	if task.Error != "" {
+		return fmt.Errorf("task %s failed to start: %s", name, task.Error)
+	}
+	if code := taskExitStatus(task); code != 0 {
+		return &exitError{code: code}
+	}
+	return nil
+}
+
+// taskExitStatus returns the status prox task exits with for a finished
+// task: its exit code, or 128 plus the signal number for a task killed by a
+// signal, as shells report it
+func taskExitStatus(task *api.TaskResponse) int {
+	if task.ExitCode == nil {
+		return 0
+	}
+	if code := *task.ExitCode; code < 0 {
+		return 128 - code
+	}
+	return *task.ExitCode
+}
+
+// listTasks prints the tasks with the status of their last run
+func listTasks(client *Client) error {
+	tasks, err := client.GetTasks()
+	if err != nil {
+		return clientError(err, "Is prox running? Try 'prox up' first.")
+	}
+
+	if taskJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(tasks); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to encode tasks: %v\n", err)
+		}
+		return nil
+	}
+	if len(tasks.Tasks) == 0 {
+		fmt.Println("No tasks configured")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tEXIT\tLAST RUN\tDESCRIPTION")
+	fmt.Fprintln(w, "----\t------\t----\t--------\t-----------")
+	for _, task := range tasks.Tasks {
+		exit := "-"
+		if task.ExitCode != nil {
+			exit = strconv.Itoa(*task.ExitCode)
+		}
+		lastRun := "-"
+		if t, err := time.Parse(time.RFC3339Nano, task.StartedAt); err == nil {
+			lastRun = humanize.Duration(time.Since(t)) + " ago"
+		}
+		description := task.Description
+		if description == "" {
+			description = task.Cmd
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", task.Name, task.Status, exit, lastRun, description)
+	}
+	return w.Flush()
+}
+
+// completeTaskNames completes the names of the tasks in the config
+func completeTaskNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := make([]string, 0, len(cfg.Tasks))
+	for name := range cfg.Tasks {
+		names = append(names, name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,17 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/charliek/prox/internal/api"
+)
+
+func TestTaskExitStatus(t *testing.T) {
+	code := func(n int) *int { return &n }
+	assert.Equal(t, 0, taskExitStatus(&api.TaskResponse{}), "no exit code")
+	assert.Equal(t, 0, taskExitStatus(&api.TaskResponse{ExitCode: code(0)}))
+	assert.Equal(t, 3, taskExitStatus(&api.TaskResponse{ExitCode: code(3)}))
+	assert.Equal(t, 143, taskExitStatus(&api.TaskResponse{ExitCode: code(-15)}), "killed by SIGTERM")
+}
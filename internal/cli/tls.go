@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/charliek/prox/internal/proxy/certs"
+)
+
+// apiCAFile is the --ca flag: a CA certificate to trust for an https:// API
+var apiCAFile string
+
+// apiRootCAs are the CAs trusted for an https:// API: the system's, --ca, and
+// the certificates of daemons found through their state files. nil until a
+// certificate is added, trusting the system's alone.
+var apiRootCAs *x509.CertPool
+
+// trustCert adds the PEM certificates in path to the CAs trusted for the API
+func trustCert(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading CA certificate: %w", err)
+	}
+	if apiRootCAs == nil {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		apiRootCAs = pool
+	}
+	if !apiRootCAs.AppendCertsFromPEM(data) {
+		return fmt.Errorf("%s: no PEM certificates", path)
+	}
+	return nil
+}
+
+// apiTLSConfig returns the TLS config for connections to the API, or nil for
+// Go's defaults
+func apiTLSConfig() *tls.Config {
+	if apiRootCAs == nil {
+		return nil
+	}
+	return &tls.Config{RootCAs: apiRootCAs}
+}
+
+// stateURL returns the API URL of a daemon found through its state file,
+// trusting the certificate it serves over HTTPS
+func stateURL(state *daemon.State) string {
+	if state.TLS && state.CertFile != "" {
+		if err := trustCert(state.CertFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	return state.URL()
+}
+
+// apiCertPaths returns the certificate the API serves over HTTPS: api.tls
+// cert and key, resolved against the config file's directory, or one
+// generated under certs.dir. The paths are absolute, for the state file.
+func apiCertPaths(cfg *config.Config, configDir string) (*certs.CertPaths, error) {
+	if cert := cfg.API.TLS.Cert; cert != "" {
+		key := cfg.API.TLS.Key
+		if !filepath.IsAbs(cert) {
+			cert = filepath.Join(configDir, cert)
+		}
+		if !filepath.IsAbs(key) {
+			key = filepath.Join(configDir, key)
+		}
+		if _, err := tls.LoadX509KeyPair(cert, key); err != nil {
+			return nil, fmt.Errorf("failed to load API certificate: %w", err)
+		}
+		return absCertPaths(&certs.CertPaths{CertFile: cert, KeyFile: key})
+	}
+
+	dir := constants.DefaultCertsDir
+	if cfg.Certs != nil && cfg.Certs.Dir != "" {
+		dir = cfg.Certs.Dir
+	}
+	paths, err := certs.EnsureAPICert(dir, apiCertHosts(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API certificate: %w", err)
+	}
+	return absCertPaths(paths)
+}
+
+// absCertPaths makes certificate paths absolute
+func absCertPaths(paths *certs.CertPaths) (*certs.CertPaths, error) {
+	cert, err := filepath.Abs(paths.CertFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := filepath.Abs(paths.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &certs.CertPaths{CertFile: cert, KeyFile: key}, nil
+}
+
+// apiCertHosts returns the names and addresses a generated API certificate
+// is valid for: loopback, the machine's host name, the API's host and, when
+// it binds every interface, the machine's addresses, then api.tls.hosts
+func apiCertHosts(cfg *config.Config) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if name, err := os.Hostname(); err == nil {
+		hosts = append(hosts, name)
+	}
+	if ip := net.ParseIP(cfg.API.Host); ip != nil && ip.IsUnspecified() {
+		if addrs, err := net.InterfaceAddrs(); err == nil {
+			for _, addr := range addrs {
+				if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && !ipNet.IP.IsLinkLocalUnicast() {
+					hosts = append(hosts, ipNet.IP.String())
+				}
+			}
+		}
+	}
+	if cfg.API.Host != "" {
+		// Local clients connect to the host in the state file, even 0.0.0.0
+		hosts = append(hosts, cfg.API.Host)
+	}
+	if cfg.API.TLS != nil {
+		hosts = append(hosts, cfg.API.TLS.Hosts...)
+	}
+
+	seen := make(map[string]bool, len(hosts))
+	unique := hosts[:0]
+	for _, host := range hosts {
+		if !seen[host] {
+			seen[host] = true
+			unique = append(unique, host)
+		}
+	}
+	return unique
+}
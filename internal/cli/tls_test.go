@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_TLS(t *testing.T) {
+	defer func() { apiRootCAs = nil }()
+	t.Setenv(constants.APITokenEnv, "secret")
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		_ = json.NewEncoder(w).Encode(api.StatusResponse{Status: "running"})
+	}))
+	defer server.Close()
+
+	// Untrusted until its certificate is given as the CA
+	_, err := NewClient(server.URL).GetStatus()
+	assert.Error(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0644))
+	require.NoError(t, trustCert(caFile))
+
+	status, err := NewClient(server.URL).GetStatus()
+	require.NoError(t, err)
+	assert.Equal(t, "running", status.Status)
+
+	assert.Error(t, trustCert(filepath.Join(t.TempDir(), "missing.pem")))
+}
+
+func TestStateURL(t *testing.T) {
+	defer func() { apiRootCAs = nil }()
+
+	state := &daemon.State{Host: "127.0.0.1", Port: 5555}
+	assert.Equal(t, "http://127.0.0.1:5555", stateURL(state))
+	assert.Nil(t, apiTLSConfig())
+
+	paths, err := apiCertPaths(&config.Config{
+		API:   config.APIConfig{Host: "127.0.0.1", TLS: &config.APITLSConfig{Enabled: true}},
+		Certs: &config.CertsConfig{Dir: t.TempDir()},
+	}, ".")
+	require.NoError(t, err)
+	state = &daemon.State{Host: "127.0.0.1", Port: 5555, TLS: true, CertFile: paths.CertFile}
+	assert.Equal(t, "https://127.0.0.1:5555", stateURL(state))
+	assert.NotNil(t, apiTLSConfig(), "trusts the daemon's certificate")
+}
+
+func TestAPICertPaths_Configured(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		API:   config.APIConfig{Host: "127.0.0.1", TLS: &config.APITLSConfig{Enabled: true}},
+		Certs: &config.CertsConfig{Dir: dir},
+	}
+	generated, err := apiCertPaths(cfg, ".")
+	require.NoError(t, err)
+
+	// A configured certificate is resolved against the config file's directory
+	cfg.API.TLS.Cert = "api.pem"
+	cfg.API.TLS.Key = "api-key.pem"
+	paths, err := apiCertPaths(cfg, dir)
+	require.NoError(t, err)
+	assert.Equal(t, generated, paths)
+
+	cfg.API.TLS.Key = "missing.pem"
+	_, err = apiCertPaths(cfg, dir)
+	assert.ErrorContains(t, err, "failed to load API certificate")
+}
+
+func TestAPICertHosts(t *testing.T) {
+	hosts := apiCertHosts(&config.Config{API: config.APIConfig{
+		Host: "127.0.0.1",
+		TLS:  &config.APITLSConfig{Enabled: true, Hosts: []string{"devbox.internal", "localhost"}},
+	}})
+	assert.Equal(t, []string{"localhost", "127.0.0.1", "::1"}, hosts[:3])
+	assert.Contains(t, hosts, "devbox.internal")
+	assert.NotContains(t, hosts, "0.0.0.0")
+	assert.NotContains(t, hosts[1:], "localhost", "deduplicated")
+
+	hosts = apiCertHosts(&config.Config{API: config.APIConfig{Host: "0.0.0.0"}})
+	assert.Contains(t, hosts, "0.0.0.0")
+}
@@ -11,6 +11,9 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -20,10 +23,20 @@ import (
 	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/daemon"
 	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/fsperm"
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/charliek/prox/internal/idle"
 	"github.com/charliek/prox/internal/logs"
+	"github.com/charliek/prox/internal/notify"
 	"github.com/charliek/prox/internal/proxy"
+	"github.com/charliek/prox/internal/proxy/certs"
+	"github.com/charliek/prox/internal/proxy/dns"
+	"github.com/charliek/prox/internal/storage"
+	"github.com/charliek/prox/internal/streams"
 	"github.com/charliek/prox/internal/supervisor"
+	"github.com/charliek/prox/internal/theme"
 	"github.com/charliek/prox/internal/tui"
+	"github.com/charmbracelet/x/term"
 	"github.com/spf13/cobra"
 )
 
@@ -42,6 +55,13 @@ var (
 	httpPort      int
 	httpsPort     int
 	enableCapture bool
+	enableStreams bool
+	freshStart    bool
+	upProfiles    []string
+	upScale       []string
+	upFile        string
+	upWait        bool
+	upWaitTimeout time.Duration
 )
 
 // upCmd represents the up command
@@ -52,14 +72,25 @@ var upCmd = &cobra.Command{
 
 By default, processes run in the foreground with logs streaming to the terminal.
 Use -d/--detach to run in background (daemon mode), or --tui for interactive mode.
+With --wait, prox starts in the background and returns once every process
+is ready (past its ready probe), failing if one crashes or the timeout expires.
+
+Without a prox.yaml, prox runs the Procfile in the current directory, so
+projects using foreman or overmind work as they are.
 
 Examples:
   prox up                     # Start all processes (foreground)
   prox up -d                  # Start in background (daemon mode)
   prox up --tui               # Start with interactive TUI
   prox up web api             # Start specific processes
+  prox up --profile backend   # Start the processes tagged with a profile
+  prox up --scale worker=3    # Run worker-1 through worker-3
+  prox up -f Procfile.dev     # Run the processes of a foreman Procfile
   prox up --no-proxy          # Start without proxy
-  prox up --capture           # Enable request/response capture`,
+  prox up --capture           # Enable request/response capture
+  prox up --streams           # Publish process stdout under .prox/streams
+  prox up --fresh             # Ignore what the last run left: stopped, added, or scaled processes
+  prox up --wait              # Start in background, return once processes are ready`,
 	Args:              cobra.ArbitraryArgs,
 	RunE:              runUp,
 	ValidArgsFunction: completeProcessNames,
@@ -74,6 +105,82 @@ func init() {
 	upCmd.Flags().IntVar(&httpPort, "http-port", 0, "Override proxy HTTP port")
 	upCmd.Flags().IntVar(&httpsPort, "https-port", 0, "Override proxy HTTPS port")
 	upCmd.Flags().BoolVar(&enableCapture, "capture", false, "Enable request/response body capture")
+	upCmd.Flags().BoolVar(&enableStreams, "streams", false, "Publish each process's stdout on a unix socket under .prox/streams")
+	upCmd.Flags().BoolVar(&freshStart, "fresh", false, "Ignore runtime overrides saved by the last run")
+	upCmd.Flags().StringVarP(&upFile, "file", "f", "", "Config file or Procfile to run (same as --config)")
+	upCmd.Flags().StringArrayVar(&upProfiles, "profile", nil, "Start only the processes tagged with a profile (repeatable)")
+	upCmd.Flags().StringArrayVar(&upScale, "scale", nil, "Run several instances of a process, e.g. worker=3 (repeatable)")
+	upCmd.Flags().BoolVar(&upWait, "wait", false, "Start in background and wait until all processes are ready (implies --detach)")
+	upCmd.Flags().DurationVar(&upWaitTimeout, "wait-timeout", constants.DefaultUpWaitTimeout, "How long --wait waits for processes to become ready")
+	addRedactFlags(upCmd)
+
+	// Error is ignored as it only fails for invalid flag names, which would be a programming error
+	_ = upCmd.RegisterFlagCompletionFunc("profile", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return cfg.Profiles(), cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// upProcesses returns the processes prox up starts: those named on the
+// command line and those tagged with the --profile profiles. Empty means all.
+func upProcesses(cfg *config.Config, names, profiles []string) ([]string, error) {
+	if len(profiles) == 0 {
+		return names, nil
+	}
+	selected, err := cfg.ProfileProcesses(profiles)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if !slices.Contains(selected, name) {
+			selected = append(selected, name)
+		}
+	}
+	return selected, nil
+}
+
+// parseScale parses --scale values of the form process=instances
+func parseScale(values []string) (map[string]int, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	scale := make(map[string]int, len(values))
+	for _, value := range values {
+		name, count, ok := strings.Cut(value, "=")
+		n, err := strconv.Atoi(count)
+		if !ok || name == "" || err != nil {
+			return nil, fmt.Errorf("invalid --scale %q (want process=instances, e.g. worker=3)", value)
+		}
+		scale[name] = n
+	}
+	return scale, nil
+}
+
+// restoreScale adds the scale saved by the last run to the --scale values,
+// for processes they don't name. Saved scales the config no longer allows
+// are dropped.
+func restoreScale(dir string, cfg *config.Config, scale map[string]int) map[string]int {
+	overrides, err := daemon.LoadRuntimeOverrides(dir)
+	if err != nil {
+		// Reported by the supervisor when it loads the overrides
+		return scale
+	}
+	for name, n := range overrides.Scale {
+		if _, ok := scale[name]; ok {
+			continue
+		}
+		if err := config.ValidateScale(cfg, map[string]int{name: n}); err != nil {
+			continue
+		}
+		if scale == nil {
+			scale = make(map[string]int)
+		}
+		scale[name] = n
+	}
+	return scale
 }
 
 // completeProcessNames provides shell completion for process names
@@ -82,13 +189,29 @@ func completeProcessNames(cmd *cobra.Command, args []string, toComplete string)
 	return names, cobra.ShellCompDirectiveNoFileComp
 }
 
-func runUp(cmd *cobra.Command, args []string) error {
+func runUp(cmd *cobra.Command, args []string) (err error) {
 	processes := args
+	if upFile != "" {
+		if cmd.Flags().Changed("config") {
+			return fmt.Errorf("--file and --config are mutually exclusive")
+		}
+		configPath = upFile
+	}
 
 	// Validate mutually exclusive flags
+	if useTUI && upWait {
+		return fmt.Errorf("--tui and --wait are mutually exclusive")
+	}
 	if useTUI && detach {
 		return fmt.Errorf("--tui and --detach are mutually exclusive")
 	}
+	if upWait {
+		detach = true
+	}
+	scale, err := parseScale(upScale)
+	if err != nil {
+		return err
+	}
 
 	// Get working directory for state files
 	cwd, err := os.Getwd()
@@ -102,19 +225,43 @@ func runUp(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
-		// Daemonize - this will re-exec and exit the parent
-		if err := daemon.Daemonize(); err != nil {
-			return fmt.Errorf("failed to daemonize: %w", err)
+		// The daemon can't prompt, so fail here where the error is seen.
+		// A config that doesn't load is reported by the daemon as before.
+		if cfg, err := config.Load(configPath); err == nil {
+			if processes, err = upProcesses(cfg, processes, upProfiles); err != nil {
+				return err
+			}
+			if err := config.ValidateScale(cfg, scale); err != nil {
+				return err
+			}
+			if missing := config.MissingPromptedEnv(cfg, configDirFor(configPath), processes); len(missing) > 0 {
+				return missingPromptsError(missing)
+			}
+			// Report permission problems here too; the daemon's go to its log
+			fsperm.SetModes(cfg.Daemon.FileModeOrDefault(), cfg.Daemon.DirModeOrDefault())
+			hardenPermissions(cwd)
+		}
+
+		if upWait {
+			return daemonizeAndWait(cwd)
 		}
-		// Parent exits in Daemonize(), this is unreachable for parent
+
+		// Daemonize - this will re-exec and exit the parent once the daemon
+		// has started, or return the daemon's startup error
+		return daemon.Daemonize(cwd)
 	}
 
-	// If we're the daemon child, set up logging
+	// If we're the daemon child, set up logging, and report the outcome of
+	// startup to the parent waiting in Daemonize
 	var logFile *os.File
+	var readiness *daemon.Readiness
 	if daemon.IsDaemonChild() {
+		readiness = daemon.ChildReadiness()
+		defer func() { readiness.Failed(err) }()
+
 		logFile, err = daemon.SetupLogging(cwd)
 		if err != nil {
-			// Can't write to stderr in daemon mode, but try anyway
+			// Reported to the parent through the readiness pipe
 			return fmt.Errorf("failed to setup logging: %w", err)
 		}
 		defer logFile.Close()
@@ -125,6 +272,20 @@ func runUp(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	if processes, err = upProcesses(cfg, args, upProfiles); err != nil {
+		return err
+	}
+	if err := config.ValidateScale(cfg, scale); err != nil {
+		return err
+	}
+	if !freshStart {
+		scale = restoreScale(cwd, cfg, scale)
+	}
+
+	redactor, err := newRedactor(cfg)
+	if err != nil {
+		return err
+	}
 
 	// Validate port flags
 	if apiPort < 0 {
@@ -191,6 +352,14 @@ func runUp(cmd *cobra.Command, args []string) error {
 		cfg.Proxy.Capture.Enabled = true
 	}
 
+	// Enable output streams if --streams flag is set
+	if enableStreams {
+		if cfg.Streams == nil {
+			cfg.Streams = &config.StreamsConfig{}
+		}
+		cfg.Streams.Enabled = true
+	}
+
 	// For foreground mode, also check if already running and handle state
 	if !detach {
 		if err := ensureNotAlreadyRunning(cwd); err != nil {
@@ -198,10 +367,30 @@ func runUp(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Create state directory
+	// Get config directory for resolving relative paths in env files
+	configDir := configDirFor(configPath)
+
+	// Serve the API over HTTPS with the configured certificate, or one
+	// generated for this machine
+	var apiCert *certs.CertPaths
+	if cfg.API.TLS.IsEnabled() {
+		if apiCert, err = apiCertPaths(cfg, configDir); err != nil {
+			return err
+		}
+	}
+
+	// Ask for secrets before the TUI takes over the terminal
+	promptedEnv, err := promptEnv(cfg, configDir, processes)
+	if err != nil {
+		return err
+	}
+
+	// Create state directory with the configured permissions
+	fsperm.SetModes(cfg.Daemon.FileModeOrDefault(), cfg.Daemon.DirModeOrDefault())
 	if err := daemon.EnsureStateDir(cwd); err != nil {
 		return fmt.Errorf("failed to create state directory: %w", err)
 	}
+	hardenPermissions(cwd)
 
 	// Get host for state file
 	host := cfg.API.Host
@@ -239,12 +428,21 @@ func runUp(cmd *cobra.Command, args []string) error {
 		StartedAt:  time.Now(),
 		ConfigFile: absConfigPath,
 	}
+	if apiCert != nil {
+		state.TLS = true
+		state.CertFile = apiCert.CertFile
+	}
 	if err := state.Write(cwd); err != nil {
 		// Clean up PID file on state file failure
 		_ = pidFile.Release()
 		return fmt.Errorf("failed to write state file: %w", err)
 	}
 
+	// Record the directory so prox gc can clean it up once it's abandoned
+	if err := daemon.RegisterInstance(cwd, absConfigPath, state.StartedAt); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to register instance for prox gc: %v\n", err)
+	}
+
 	// Register cleanup defer FIRST (will run LAST due to LIFO)
 	defer func() {
 		_ = daemon.CleanupStateDir(cwd)
@@ -255,24 +453,45 @@ func runUp(cmd *cobra.Command, args []string) error {
 		_ = pidFile.Release()
 	}()
 
+	// Open the history database when storage.backend is sqlite
+	history, err := openHistory(cfg, cwd, configDir)
+	if err != nil {
+		return err
+	}
+	if history != nil {
+		defer func() {
+			if err := history.Err(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: history storage: %v\n", err)
+			}
+			_ = history.Close()
+		}()
+	}
+
 	// Create log manager
-	logMgr := logs.NewManager(logs.ManagerConfig{
-		BufferSize:         1000,
+	logConfig := logs.ManagerConfig{
+		BufferSize:         cfg.Logs.BufferSizeOrDefault(),
+		ProcessBufferSize:  cfg.Logs.ProcessBufferSizeOrDefault(),
+		MaxMemory:          cfg.Logs.MaxMemoryBytes(),
 		SubscriptionBuffer: 1000,
-	})
-
-	// Get config directory for resolving relative paths in env files
-	configDir := filepath.Dir(configPath)
-	if configDir == "." {
-		// Try to get absolute path
-		if absPath, err := filepath.Abs(configPath); err == nil {
-			configDir = filepath.Dir(absPath)
-		}
 	}
+	if history != nil {
+		logConfig.Store = history.Logs()
+	}
+	logMgr := logs.NewManager(logConfig)
 
 	// Create supervisor
 	supConfig := supervisor.DefaultSupervisorConfig()
 	supConfig.ConfigDir = configDir
+	supConfig.RuntimeStateDir = cwd
+	supConfig.FreshStart = freshStart
+	supConfig.PromptedEnv = promptedEnv
+	supConfig.Scale = scale
+	if cfg.Supervisor != nil {
+		supConfig.StartConcurrency = cfg.Supervisor.StartConcurrency
+		supConfig.StopConcurrency = cfg.Supervisor.StopConcurrency
+	}
+	supConfig.CrashLimit = cfg.Supervisor.CrashLimitOrDefault()
+	supConfig.CrashWindow = cfg.Supervisor.CrashWindowDuration()
 	sup := supervisor.New(cfg, logMgr, nil, supConfig)
 
 	// Create shutdown channel
@@ -285,8 +504,11 @@ func runUp(cmd *cobra.Command, args []string) error {
 	authEnabled := isAuthRequired(cfg)
 	var token string
 
+	// The API served through the proxy always requires a token
+	proxyAPIEnabled := !noProxy && cfg.Proxy != nil && cfg.Proxy.Enabled && cfg.Proxy.APIEnabled()
+
 	// Generate authentication token only if auth is enabled
-	if authEnabled {
+	if authEnabled || proxyAPIEnabled {
 		token, err = generateToken()
 		if err != nil {
 			return fmt.Errorf("failed to generate auth token: %w", err)
@@ -300,41 +522,95 @@ func runUp(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "         Any network client can control this supervisor.\n")
 	}
 
+	// Track API calls, proxy requests, and attached clients for idle shutdown
+	idleTimeout := cfg.Daemon.IdleTimeoutDuration()
+	var idleTracker *idle.Tracker
+	if idleTimeout > 0 {
+		idleTracker = idle.NewTracker()
+	}
+	idleAction := cfg.Daemon.IdleActionOrDefault()
+
 	// Create API handlers and server
 	handlers := api.NewHandlers(sup, logMgr, configPath, shutdownFn)
-	apiServer := api.NewServer(api.ServerConfig{
+	apiConfig := api.ServerConfig{
 		Host:        cfg.API.Host,
 		Port:        cfg.API.Port,
 		AuthEnabled: authEnabled,
 		Token:       token,
-	}, handlers)
+		Idle:        idleTracker,
+	}
+	if apiCert != nil {
+		apiConfig.TLSCertFile = apiCert.CertFile
+		apiConfig.TLSKeyFile = apiCert.KeyFile
+	}
+	apiServer := api.NewServer(apiConfig, handlers)
 
-	// Set up signal handling
+	// Bind the API before starting anything, so a port in use stops prox
+	// here. The daemon has started once it is listening.
+	if err := apiServer.Listen(); err != nil {
+		return fmt.Errorf("failed to start API server: %w", err)
+	}
+	readiness.Ready()
+
+	// Set up signal handling. SIGQUIT dumps the daemon's state before
+	// shutting down (see writePostmortem).
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	if !useTUI {
+		signal.Notify(sigCh, syscall.SIGQUIT)
+	}
 
 	// Start context
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Publish process output before starting processes so no early lines
+	// are missed
+	var publisher *streams.Publisher
+	if cfg.Streams.IsEnabled() {
+		publisher = streams.New(daemon.StreamsDir(cwd), logMgr, sup.SystemLog)
+		scaled := cfg.Scaled(scale)
+		names := make([]string, 0, len(scaled.Processes))
+		for name := range scaled.Processes {
+			names = append(names, name)
+		}
+		if err := publisher.Start(names); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: process output streams disabled: %v\n", err)
+			publisher = nil
+		}
+	}
+
+	// Send notifications for crashes and failing health checks, subscribing
+	// before processes start so early crashes are seen
+	if cfg.Notifications != nil {
+		go notify.New(cfg.Notifications, sup.SystemLog).Run(sup.Subscribe())
+	}
+
 	// Start supervisor
 	fmt.Printf("Starting prox with config: %s\n", configPath)
 	if isLocalhost(cfg.API.Host) {
 		if authEnabled {
-			fmt.Printf("API server: http://%s (local only, auth enabled)\n", apiServer.Addr())
+			fmt.Printf("API server: %s (local only, auth enabled)\n", apiServer.URL())
 		} else {
-			fmt.Printf("API server: http://%s (local only, no auth)\n", apiServer.Addr())
+			fmt.Printf("API server: %s (local only, no auth)\n", apiServer.URL())
 		}
 	} else {
 		if authEnabled {
-			fmt.Printf("API server: http://%s (network accessible, auth enabled)\n", apiServer.Addr())
+			fmt.Printf("API server: %s (network accessible, auth enabled)\n", apiServer.URL())
 		} else {
-			fmt.Printf("API server: http://%s (network accessible, no auth)\n", apiServer.Addr())
+			fmt.Printf("API server: %s (network accessible, no auth)\n", apiServer.URL())
 		}
 	}
-	if authEnabled {
+	if apiCert != nil {
+		fmt.Printf("API certificate: %s (trust it on other machines with --ca)\n", apiCert.CertFile)
+	}
+	fmt.Printf("Web UI: %s%s\n", apiServer.URL(), api.UIPath)
+	if authEnabled || proxyAPIEnabled {
 		fmt.Printf("Auth token saved to: %s\n", tokenPath())
 	}
+	if publisher != nil {
+		fmt.Printf("Process output streams: %s\n", publisher.Dir())
+	}
 
 	if len(processes) > 0 {
 		fmt.Printf("Starting processes: %s\n", strings.Join(processes, ", "))
@@ -379,6 +655,22 @@ func runUp(cmd *cobra.Command, args []string) error {
 		logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
 		var err error
 		proxyService, err = proxy.NewService(cfg.Proxy, cfg.Services, cfg.Certs, logger, cwd)
+		if err == nil && proxyAPIEnabled {
+			proxyService.SetAPIHandler(cfg.Proxy.APISubdomain(), apiServer.ProxyHandler())
+		}
+		if err == nil {
+			proxyService.SetProcessStarter(sup.UseProcess)
+			proxyService.SetRestartWaiter(sup.WaitRestart)
+			if history != nil {
+				proxyService.RequestManager().SetStore(history.Requests())
+			}
+		}
+		if err == nil && idleTracker != nil {
+			proxyService.SetIdleTracker(idleTracker)
+			if idleAction == config.IdleActionSleep {
+				proxyService.SetWakeFunc(sup.Wake)
+			}
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error creating proxy service: %v\n", err)
 			// Continue without proxy - this is not fatal
@@ -398,39 +690,106 @@ func runUp(cmd *cobra.Command, args []string) error {
 			if len(proxyAddrs) > 0 {
 				fmt.Printf("Proxy server: %s\n", strings.Join(proxyAddrs, ", "))
 			}
+			if proxyAPIEnabled {
+				fmt.Printf("API via proxy: %s (auth enabled)\n", proxyAPIURL(cfg.Proxy))
+			}
+			if cfg.Proxy.DNSBuiltin() {
+				startDNS(ctx, cfg.Proxy, logger)
+			}
 			// Wire up request manager and capture manager to API handlers
 			handlers.SetRequestManager(proxyService.RequestManager())
 			handlers.SetCaptureManager(proxyService.CaptureManager())
+			handlers.SetStatsTracker(proxyService.StatsTracker())
+			handlers.SetProxyService(proxyService)
+
+			// Keep proxy routes in sync with process ports and drain state
+			go syncProxyRoutes(sup, proxyService)
+
+			// Surface sustained latency budget violations as system log events
+			proxyService.StatsTracker().SetViolationCallback(func(stats proxy.ServiceStats, since time.Time) {
+				sup.SystemLog("service %s p95 latency %s over budget %s for %s",
+					stats.Subdomain, stats.P95, stats.Budget, time.Since(since).Round(time.Second))
+			})
+
+			// Write proxy requests to the logs as they're recorded
+			if cfg.Proxy.AccessLogEnabled() {
+				go proxyService.WriteAccessLog(ctx, cfg.Proxy.AccessLog, logMgr.Write)
+			}
 		}
 	}
 
+	// Dump the daemon's state on request, for prox debug trigger-dump
+	handlers.SetDumpFunc(func(reason string) (string, error) {
+		return writePostmortem(cwd, sup, logMgr, proxyService, reason)
+	})
+
+	// Watch for idle shutdown. Sleeping processes are started again by the
+	// proxy, so without it an idle daemon stops instead.
+	idleCh := make(chan struct{})
+	if idleTracker != nil {
+		if idleAction == config.IdleActionSleep && proxyService == nil {
+			fmt.Fprintf(os.Stderr, "Warning: daemon.idle_action sleep requires the proxy; stopping when idle instead\n")
+			idleAction = config.IdleActionStop
+		}
+		fmt.Printf("Idle timeout: %s (%s)\n", idleTimeout, idleAction)
+		go watchIdle(ctx, sup, idleTracker, idleTimeout, idleAction, idleCh)
+	}
+
+	// Reload the config file on SIGHUP
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go reloadOnSignal(ctx, sup, configPath, hupCh)
+
+	// Record what the stack looked like if the daemon panics
+	defer func() {
+		if r := recover(); r != nil {
+			writeLastRun(cwd, sup, logMgr, "panic", fmt.Sprintf("%v\n\n%s", r, debug.Stack()))
+			panic(r)
+		}
+	}()
+
 	// Handle TUI vs terminal output
+	var reason string
 	if useTUI {
-		// Run TUI - it blocks until quit
-		var reqMgr *proxy.RequestManager
-		if proxyService != nil {
-			reqMgr = proxyService.RequestManager()
-		}
-		if err := tui.Run(sup, logMgr, reqMgr); err != nil {
+		// Run TUI - it blocks until quit. An open TUI keeps the daemon active.
+		endTUI := idleTracker.Begin()
+		tui.SetTheme(cfg.OutputTheme())
+		if err := tui.Run(sup, logMgr, proxyService, redactor, loadTUIPrefs(cwd)); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		}
+		endTUI()
+		reason = "TUI closed"
 	} else {
 		// Subscribe to logs and print to terminal
-		go printLogs(logMgr)
+		go printLogs(logMgr, redactor, cfg.TimeMode(), cfg.OutputTheme())
 
 		// Wait for shutdown signal
 		select {
 		case sig := <-sigCh:
 			fmt.Println() // Print newline after ^C
-			sup.SystemLog("%s received", sig)
+			reason = fmt.Sprintf("%s received", sig)
+			if sig == syscall.SIGQUIT {
+				if path, err := writePostmortem(cwd, sup, logMgr, proxyService, reason); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to write postmortem: %v\n", err)
+				} else {
+					sup.SystemLog("postmortem written to %s", path)
+				}
+			}
 		case <-shutdownCh:
 			fmt.Println() // Print newline
-			sup.SystemLog("shutdown requested via API")
+			reason = "shutdown requested via API"
+		case <-idleCh:
+			reason = fmt.Sprintf("idle for %s", idleTimeout)
 		}
+		sup.SystemLog("%s, shutting down", reason)
 	}
 
 	// Stop signal handler to prevent additional signals during shutdown
 	signal.Stop(sigCh)
+	signal.Stop(hupCh)
+
+	// Snapshot process states before stopping them
+	writeLastRun(cwd, sup, logMgr, reason, "")
 
 	// Graceful shutdown
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
@@ -453,6 +812,11 @@ func runUp(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 	}
 
+	// Disconnect stream readers once the processes' last output is out
+	if publisher != nil {
+		publisher.Close()
+	}
+
 	// Log shutdown complete before closing the log manager
 	sup.SystemLog("shutdown complete")
 
@@ -464,6 +828,145 @@ func runUp(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// openHistory opens the database of the sqlite storage backend, or returns
+// nil for the in-memory default. A relative storage.path is resolved against
+// the config file's directory.
+func openHistory(cfg *config.Config, cwd, configDir string) (*storage.SQLite, error) {
+	if cfg.Storage.BackendOrDefault() != config.StorageBackendSQLite {
+		return nil, nil
+	}
+	path := filepath.Join(daemon.StateDir(cwd), constants.DefaultStorageFileName)
+	if cfg.Storage.Path != "" {
+		path = cfg.Storage.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(configDir, path)
+		}
+	}
+	history, err := storage.OpenSQLite(storage.SQLiteConfig{
+		Path:        path,
+		MaxLogs:     cfg.Storage.MaxLogsOrDefault(),
+		MaxRequests: cfg.Storage.MaxRequestsOrDefault(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("opening history storage: %w", err)
+	}
+	return history, nil
+}
+
+// writeLastRun saves a snapshot of the stack to .prox/last-run.json for
+// `prox status --last-run`. panicMsg is empty unless the daemon crashed.
+func writeLastRun(dir string, sup *supervisor.Supervisor, logMgr *logs.Manager, reason, panicMsg string) {
+	stats := logMgr.Stats()
+	run := &daemon.LastRun{
+		StartedAt: sup.Status().StartedAt,
+		EndedAt:   time.Now(),
+		Reason:    reason,
+		Panic:     panicMsg,
+		Logs: daemon.LastRunLogs{
+			Entries:     stats.TotalEntries,
+			BufferSize:  stats.BufferSize,
+			Subscribers: stats.Subscribers,
+		},
+	}
+	for _, p := range sup.Processes() {
+		run.Processes = append(run.Processes, daemon.LastRunProcess{
+			Name:      p.Name,
+			Status:    string(p.State),
+			PID:       p.PID,
+			Restarts:  p.RestartCount,
+			Health:    string(p.Health),
+			LastError: p.LastError,
+		})
+	}
+
+	if err := run.Write(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save last run: %v\n", err)
+	}
+}
+
+// writePostmortem dumps the daemon's processes, in-flight proxy requests,
+// log buffer and goroutine stacks to .prox/postmortem-<time>.txt, for
+// SIGQUIT and `prox debug trigger-dump`. proxyService may be nil.
+func writePostmortem(dir string, sup *supervisor.Supervisor, logMgr *logs.Manager, proxyService *proxy.Service, reason string) (string, error) {
+	stats := logMgr.Stats()
+	dump := &daemon.Postmortem{
+		Time:      time.Now(),
+		Reason:    reason,
+		StartedAt: sup.Status().StartedAt,
+		Logs: daemon.LastRunLogs{
+			Entries:     stats.TotalEntries,
+			BufferSize:  stats.BufferSize,
+			Subscribers: stats.Subscribers,
+		},
+		Goroutines: daemon.GoroutineStacks(),
+	}
+	for _, p := range sup.Processes() {
+		dump.Processes = append(dump.Processes, daemon.LastRunProcess{
+			Name:      p.Name,
+			Status:    string(p.State),
+			PID:       p.PID,
+			Restarts:  p.RestartCount,
+			Health:    string(p.Health),
+			LastError: p.LastError,
+		})
+	}
+	if proxyService != nil {
+		for _, req := range proxyService.ActiveRequests() {
+			dump.Requests = append(dump.Requests, daemon.PostmortemRequest{
+				ID:        req.ID,
+				Method:    req.Method,
+				URL:       req.URL,
+				Subdomain: req.Subdomain,
+				Start:     req.Start,
+			})
+		}
+	}
+	return dump.Write(dir)
+}
+
+// configDirFor returns the directory of the config file, against which
+// relative paths in it are resolved
+func configDirFor(path string) string {
+	configDir := filepath.Dir(path)
+	if configDir == "." {
+		// Try to get absolute path
+		if absPath, err := filepath.Abs(path); err == nil {
+			configDir = filepath.Dir(absPath)
+		}
+	}
+	return configDir
+}
+
+// promptEnv asks on the terminal for the env_prompt variables that nothing
+// sets, without echoing the input, and returns the values entered. Without a
+// terminal to ask on it fails, listing the variables.
+func promptEnv(cfg *config.Config, configDir string, processes []string) (map[string]string, error) {
+	missing := config.MissingPromptedEnv(cfg, configDir, processes)
+	if len(missing) == 0 {
+		return nil, nil
+	}
+	if daemon.IsDaemonChild() || !term.IsTerminal(os.Stdin.Fd()) {
+		return nil, missingPromptsError(missing)
+	}
+
+	values := make(map[string]string, len(missing))
+	for _, variable := range missing {
+		fmt.Fprintf(os.Stderr, "%s: ", variable)
+		value, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", variable, err)
+		}
+		values[variable] = string(value)
+	}
+	return values, nil
+}
+
+// missingPromptsError reports env_prompt variables that can't be asked for
+func missingPromptsError(missing []string) error {
+	return fmt.Errorf("env_prompt variables not set: %s (export them to start without a terminal)", strings.Join(missing, ", "))
+}
+
 // proxDir returns the prox config directory path (~/.prox)
 func proxDir() string {
 	home, err := os.UserHomeDir()
@@ -478,6 +981,16 @@ func tokenPath() string {
 	return filepath.Join(proxDir(), "token")
 }
 
+// proxyAPIURL returns the URL of the API served through the proxy,
+// preferring HTTPS when it is enabled
+func proxyAPIURL(cfg *config.ProxyConfig) string {
+	host := cfg.APISubdomain() + "." + cfg.Domain
+	if cfg.HTTPSPort > 0 {
+		return fmt.Sprintf("https://%s:%d", host, cfg.HTTPSPort)
+	}
+	return fmt.Sprintf("http://%s:%d", host, cfg.HTTPPort)
+}
+
 // generateToken generates a cryptographically secure random token
 func generateToken() (string, error) {
 	bytes := make([]byte, 32)
@@ -487,14 +1000,34 @@ func generateToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// hardenPermissions tightens the permissions of prox's existing files in the
+// project and user state directories to the configured modes, and warns if
+// other users can still write to either directory. Certificates keep their
+// own modes, since the CA certificate is meant to be readable.
+func hardenPermissions(cwd string) {
+	certsDir := filepath.Join(proxDir(), "certs")
+	for _, dir := range []string{daemon.StateDir(cwd), proxDir()} {
+		fixes, err := fsperm.Harden(dir, certsDir)
+		for _, fix := range fixes {
+			fmt.Fprintf(os.Stderr, "Warning: tightened permissions of %s\n", fix)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to check permissions in %s: %v\n", dir, err)
+		}
+		if writable, mode, err := fsperm.OthersCanWrite(dir); err == nil && writable {
+			fmt.Fprintf(os.Stderr, "WARNING: %s is writable by other users (mode %04o); they can change prox's state and token. Set daemon.dir_mode or run: chmod go-w %s\n", dir, mode, dir)
+		}
+	}
+}
+
 // saveToken saves the token to ~/.prox/token
 func saveToken(token string) error {
 	dir := proxDir()
-	if err := os.MkdirAll(dir, 0700); err != nil {
+	if err := fsperm.MkdirAll(dir); err != nil {
 		return fmt.Errorf("creating prox directory: %w", err)
 	}
-	// Write token with restrictive permissions (owner read/write only)
-	if err := os.WriteFile(tokenPath(), []byte(token), 0600); err != nil {
+	// Write token with restrictive permissions (owner read/write only by default)
+	if err := fsperm.WriteFile(tokenPath(), []byte(token)); err != nil {
 		return fmt.Errorf("writing token file: %w", err)
 	}
 	return nil
@@ -539,14 +1072,114 @@ func ensureNotAlreadyRunning(cwd string) error {
 	return nil
 }
 
+// syncProxyRoutes keeps proxy services pointed at their process's port and
+// resumes routing to a process whenever it starts, so services drained with
+// 'prox drain' receive traffic again after a restart. Process starts, stops,
+// and crashes are recorded in the proxy stats.
+func syncProxyRoutes(sup *supervisor.Supervisor, proxyService *proxy.Service) {
+	events := sup.Subscribe()
+
+	// Wire ports of processes that started before the proxy
+	for _, info := range sup.Processes() {
+		if info.Port > 0 {
+			proxyService.SetProcessPort(info.Name, info.Port)
+		}
+	}
+
+	for event := range events {
+		switch event.Type {
+		case supervisor.EventTypeProcessStopped:
+			proxyService.RecordProcessEvent(event.Process, event.Info.Port, proxy.ProcessEventStopped, event.Timestamp)
+			continue
+		case supervisor.EventTypeProcessCrashed:
+			proxyService.RecordProcessEvent(event.Process, event.Info.Port, proxy.ProcessEventCrashed, event.Timestamp)
+			continue
+		case supervisor.EventTypeProcessStarted:
+			proxyService.RecordProcessEvent(event.Process, event.Info.Port, proxy.ProcessEventStarted, event.Timestamp)
+		default:
+			continue
+		}
+		if event.Info.Port > 0 {
+			proxyService.SetProcessPort(event.Process, event.Info.Port)
+		}
+		proxyService.ResumeProcess(event.Process)
+	}
+}
+
+// reloadOnSignal re-reads the config file and applies process changes each
+// time a signal arrives, until ctx is cancelled. An invalid config file is
+// logged and leaves the running processes alone.
+func reloadOnSignal(ctx context.Context, sup *supervisor.Supervisor, configPath string, sigCh <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigCh:
+			sup.SystemLog("%s received, reloading %s", sig, configPath)
+		}
+
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			sup.SystemLog("config reload failed, keeping the current config: %v", err)
+			continue
+		}
+		if _, err := sup.Reload(ctx, cfg); err != nil {
+			sup.SystemLog("config reload failed: %v", err)
+		}
+	}
+}
+
+// watchIdle waits for the daemon to go unused for timeout. With the stop
+// action it closes idleCh to shut the daemon down; with the sleep action it
+// stops all processes, which the proxy starts again on the next request.
+func watchIdle(ctx context.Context, sup *supervisor.Supervisor, tracker *idle.Tracker, timeout time.Duration, action string, idleCh chan<- struct{}) {
+	for tracker.Wait(ctx, timeout) {
+		if action != config.IdleActionSleep {
+			close(idleCh)
+			return
+		}
+		if !sup.Asleep() {
+			names := sup.Sleep(ctx)
+			sup.SystemLog("idle for %s, stopped %d processes until the next proxy request", timeout, len(names))
+		}
+		// Start a fresh idle period so an already sleeping daemon isn't
+		// re-checked in a tight loop
+		tracker.Touch()
+	}
+}
+
 // printLogs subscribes to logs and prints them to terminal
-func printLogs(logMgr *logs.Manager) {
+// startDNS starts the builtin DNS responder for the proxy domain. Failing to
+// start it isn't fatal: names can still be added to /etc/hosts.
+func startDNS(ctx context.Context, cfg *config.ProxyConfig, logger *slog.Logger) {
+	addr := fmt.Sprintf("127.0.0.1:%d", cfg.DNSPortOrDefault())
+	srv, err := dns.Listen(addr, cfg.Domain, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting DNS server: %v\n", err)
+		return
+	}
+	fmt.Printf("DNS server: %s (*.%s)\n", addr, cfg.Domain)
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	go func() {
+		if err := srv.Serve(); err != nil {
+			fmt.Fprintf(os.Stderr, "DNS server error: %v\n", err)
+		}
+	}()
+}
+
+func printLogs(logMgr *logs.Manager, redactor *logs.Redactor, timeMode humanize.TimeMode, th *theme.Theme) {
 	_, ch, err := logMgr.Subscribe(domain.LogFilter{})
 	if err != nil {
 		return
 	}
 
 	printer := NewLogPrinter()
+	printer.SetRedactor(redactor)
+	printer.SetTimeMode(timeMode)
+	printer.SetTheme(th)
 	for entry := range ch {
 		printer.PrintEntry(entry)
 	}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/charliek/prox/internal/idle"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/charliek/prox/internal/supervisor"
+)
+
+func TestWatchIdle_Stop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idleCh := make(chan struct{})
+	go watchIdle(ctx, nil, idle.NewTracker(), 20*time.Millisecond, config.IdleActionStop, idleCh)
+
+	select {
+	case <-idleCh:
+	case <-time.After(time.Second):
+		t.Fatal("expected idle shutdown")
+	}
+}
+
+func TestWatchIdle_Sleep(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := &config.Config{
+		Processes: map[string]config.ProcessConfig{"web": {Cmd: "sleep 30"}},
+	}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := sup.Start(ctx); err != nil {
+		t.Fatalf("starting supervisor: %v", err)
+	}
+	defer sup.Stop(context.Background())
+
+	idleCh := make(chan struct{})
+	go watchIdle(ctx, sup, idle.NewTracker(), 20*time.Millisecond, config.IdleActionSleep, idleCh)
+
+	deadline := time.Now().Add(time.Second)
+	for !sup.Asleep() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !sup.Asleep() {
+		t.Fatal("expected processes to be put to sleep")
+	}
+	if info, _ := sup.Process("web"); !info.State.IsStopped() {
+		t.Errorf("expected web to be stopped, got %s", info.State)
+	}
+
+	select {
+	case <-idleCh:
+		t.Error("sleep action should not shut the daemon down")
+	default:
+	}
+}
+
+func TestUpProcesses(t *testing.T) {
+	cfg := &config.Config{Processes: map[string]config.ProcessConfig{
+		"web":    {Cmd: "npm run dev", Profiles: []string{"frontend"}},
+		"api":    {Cmd: "go run ./cmd/api", Profiles: []string{"backend"}},
+		"worker": {Cmd: "go run ./cmd/worker", Profiles: []string{"backend"}},
+	}}
+
+	names, err := upProcesses(cfg, []string{"web"}, nil)
+	if err != nil || !slices.Equal(names, []string{"web"}) {
+		t.Errorf("without profiles got %v, %v", names, err)
+	}
+
+	names, err = upProcesses(cfg, []string{"web", "api"}, []string{"backend"})
+	if err != nil || !slices.Equal(names, []string{"api", "worker", "web"}) {
+		t.Errorf("with a profile got %v, %v", names, err)
+	}
+
+	if _, err := upProcesses(cfg, nil, []string{"ops"}); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func TestRestoreScale(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{Processes: map[string]config.ProcessConfig{
+		"web":    {Cmd: "./web", Lazy: true},
+		"worker": {Cmd: "./worker"},
+		"jobs":   {Cmd: "./jobs"},
+	}}
+
+	// Without saved overrides the flags are used as given
+	if scale := restoreScale(dir, cfg, nil); scale != nil {
+		t.Errorf("got %v, want nil", scale)
+	}
+
+	saved := &daemon.RuntimeOverrides{Scale: map[string]int{"worker": 3, "jobs": 2, "web": 2, "removed": 2}}
+	if err := saved.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+	// Flags win, and saved scales the config no longer allows (web is now
+	// lazy) are dropped
+	scale := restoreScale(dir, cfg, map[string]int{"jobs": 4})
+	want := map[string]int{"worker": 3, "jobs": 4}
+	if len(scale) != len(want) || scale["worker"] != 3 || scale["jobs"] != 4 {
+		t.Errorf("got %v, want %v", scale, want)
+	}
+}
+
+func TestParseScale(t *testing.T) {
+	scale, err := parseScale([]string{"worker=3", "web=1"})
+	if err != nil || scale["worker"] != 3 || scale["web"] != 1 || len(scale) != 2 {
+		t.Errorf("got %v, %v", scale, err)
+	}
+
+	for _, value := range []string{"worker", "worker=", "=3", "worker=many"} {
+		if _, err := parseScale([]string{value}); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
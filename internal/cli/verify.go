@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// Verify command flags
+var verifyJSON bool
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the config for problems, e.g. in CI",
+	Long: `Check the config file without starting anything, and report every problem
+found rather than stopping at the first.
+
+Besides the validation prox up does, verify checks that env files exist, that
+the commands of processes and healthchecks are on PATH (best-effort, reported
+as warnings), and that no two listeners share a port: processes, prox's own
+API and proxy ports, and services linked to a different process than the one
+declaring their port.
+
+prox verify exits with status 1 if there are errors, and 0 if there are only
+warnings. With --json the findings are printed as a JSON report.
+
+Examples:
+  prox verify                       # Check prox.yaml
+  prox verify -c ci/prox.yaml       # Check another config file
+  prox verify --json                # Output as JSON`,
+	Args: cobra.NoArgs,
+	RunE: runVerify,
+}
+
+func init() {
+	verifyCmd.Flags().BoolVar(&verifyJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(verifyCmd)
+}
+
+// verifyReport is the JSON output of prox verify
+type verifyReport struct {
+	Config   string           `json:"config"`
+	Valid    bool             `json:"valid"`
+	Errors   int              `json:"errors"`
+	Warnings int              `json:"warnings"`
+	Findings []config.Finding `json:"findings"`
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	findings, err := config.Verify(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	report := verifyReport{Config: configPath, Findings: findings}
+	if report.Findings == nil {
+		report.Findings = []config.Finding{}
+	}
+	for _, f := range findings {
+		if f.Level == config.FindingError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	report.Valid = report.Errors == 0
+
+	if verifyJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to encode report: %v\n", err)
+		}
+	} else {
+		for _, f := range findings {
+			fmt.Printf("%-7s  %s: %s\n", f.Level, f.Field, f.Message)
+		}
+		if len(findings) > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s: %d error(s), %d warning(s)\n", configPath, report.Errors, report.Warnings)
+	}
+
+	if !report.Valid {
+		return &exitError{code: 1}
+	}
+	return nil
+}
@@ -0,0 +1,389 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
+)
+
+// Workspace command flags
+var (
+	workspacePath  string
+	wsStatusJSON   bool
+	wsLogsFollow   bool
+	wsLogsLines    int
+	wsLogsPatterns []string
+	wsLogsRegex    bool
+	wsLogsJSON     bool
+)
+
+// wsCmd represents the ws command
+var wsCmd = &cobra.Command{
+	Use:   "ws",
+	Short: "Operate on all projects in a workspace",
+	Long: `Operate on several prox projects at once.
+
+A workspace file (prox-workspace.yaml by default) maps project names to
+project directories or config files:
+
+  projects:
+    api: ../api-service
+    web: ../web-frontend/prox.yaml
+
+Each project still runs its own prox daemon; ws commands start, inspect,
+and tail them together.`,
+}
+
+// wsUpCmd represents the ws up command
+var wsUpCmd = &cobra.Command{
+	Use:   "up [projects...]",
+	Short: "Start workspace projects in the background",
+	Long: `Start each workspace project as a background daemon (like 'prox up -d').
+
+Projects that are already running are left alone.
+
+Examples:
+  prox ws up            # Start all projects
+  prox ws up api        # Start only the api project`,
+	RunE: runWsUp,
+}
+
+// wsStatusCmd represents the ws status command
+var wsStatusCmd = &cobra.Command{
+	Use:   "status [projects...]",
+	Short: "Show process status across workspace projects",
+	Long: `Show the processes of every workspace project in one table.
+
+Examples:
+  prox ws status          # Show all projects
+  prox ws status --json   # Output as JSON`,
+	RunE: runWsStatus,
+}
+
+// wsLogsCmd represents the ws logs command
+var wsLogsCmd = &cobra.Command{
+	Use:   "logs [projects...]",
+	Short: "Show logs across workspace projects",
+	Long: `Show or stream logs from every workspace project, prefixed with
+project/process.
+
+Examples:
+  prox ws logs                  # Show recent logs from all projects
+  prox ws logs -f               # Stream logs from all projects
+  prox ws logs api -f           # Stream logs from the api project
+  prox ws logs --pattern error  # Filter by pattern`,
+	RunE: runWsLogs,
+}
+
+// loadWorkspaceProjects loads the workspace file and returns the projects
+// named in args, or all projects if args is empty
+func loadWorkspaceProjects(args []string) ([]config.WorkspaceProject, error) {
+	ws, err := config.LoadWorkspace(workspacePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workspace: %w", err)
+	}
+	if len(args) == 0 {
+		return ws.Projects, nil
+	}
+
+	byName := make(map[string]config.WorkspaceProject, len(ws.Projects))
+	for _, p := range ws.Projects {
+		byName[p.Name] = p
+	}
+	projects := make([]config.WorkspaceProject, 0, len(args))
+	for _, name := range args {
+		p, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown workspace project %q", name)
+		}
+		projects = append(projects, p)
+	}
+	return projects, nil
+}
+
+// projectClient returns an API client for a running workspace project
+func projectClient(p config.WorkspaceProject) (*Client, error) {
+	state, err := daemon.GetRunningState(p.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(stateURL(state)), nil
+}
+
+func runWsUp(cmd *cobra.Command, args []string) error {
+	projects, err := loadWorkspaceProjects(args)
+	if err != nil {
+		return err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("getting executable path: %w", err)
+	}
+
+	var failed []string
+	for _, p := range projects {
+		if daemon.IsRunning(p.Dir) {
+			fmt.Printf("%s: already running\n", p.Name)
+			continue
+		}
+
+		// Start the project's daemon from its own directory so state lands in
+		// <project>/.prox, exactly as 'prox up -d' run there would
+		upArgs := append([]string{"up", "--detach", "--config", p.Config}, workspaceAPIPortArgs(p)...)
+		upCmd := exec.Command(executable, upArgs...)
+		upCmd.Dir = p.Dir
+		if out, err := upCmd.CombinedOutput(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: failed to start: %s\n", p.Name, strings.TrimSpace(string(out)))
+			failed = append(failed, p.Name)
+			continue
+		}
+
+		if err := waitForProject(p); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v (see %s)\n", p.Name, err, daemon.LogPath(p.Dir))
+			failed = append(failed, p.Name)
+			continue
+		}
+		fmt.Printf("%s: started\n", p.Name)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to start: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// workspaceAPIPortArgs returns an --api-port override when the project's
+// configured API port is already taken, typically by another workspace
+// project left on the default port. Clients find the chosen port through the
+// project's state file.
+func workspaceAPIPortArgs(p config.WorkspaceProject) []string {
+	cfg, err := config.Load(p.Config)
+	if err != nil {
+		return nil // prox up reports the config error
+	}
+	host := cfg.API.Host
+	if host == "" {
+		host = constants.DefaultAPIHost
+	}
+	if daemon.IsPortAvailable(host, cfg.API.Port) {
+		return nil
+	}
+	port, err := daemon.FindAvailablePort(host)
+	if err != nil {
+		return nil
+	}
+	return []string{"--api-port", strconv.Itoa(port)}
+}
+
+// waitForProject waits for a freshly started project daemon to write its state
+func waitForProject(p config.WorkspaceProject) error {
+	deadline := time.Now().Add(constants.WorkspaceStartupTimeout)
+	for time.Now().Before(deadline) {
+		if _, err := daemon.GetRunningState(p.Dir); err == nil {
+			return nil
+		}
+		time.Sleep(constants.WorkspaceStartupPollInterval)
+	}
+	return fmt.Errorf("did not start within %s", constants.WorkspaceStartupTimeout)
+}
+
+// wsProjectStatus is the JSON output of prox ws status for one project
+type wsProjectStatus struct {
+	Project   string                `json:"project"`
+	Dir       string                `json:"dir"`
+	Running   bool                  `json:"running"`
+	Processes []api.ProcessResponse `json:"processes,omitempty"`
+	Error     string                `json:"error,omitempty"`
+}
+
+func runWsStatus(cmd *cobra.Command, args []string) error {
+	projects, err := loadWorkspaceProjects(args)
+	if err != nil {
+		return err
+	}
+
+	statuses := make([]wsProjectStatus, 0, len(projects))
+	for _, p := range projects {
+		status := wsProjectStatus{Project: p.Name, Dir: p.Dir}
+		client, err := projectClient(p)
+		if err == nil {
+			status.Running = true
+			resp, err := client.GetProcesses()
+			if err != nil {
+				status.Error = err.Error()
+			} else {
+				status.Processes = resp.Processes
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	if wsStatusJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(statuses); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to encode output: %v\n", err)
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROJECT\tNAME\tSTATUS\tPID\tUPTIME\tRESTARTS\tHEALTH")
+	fmt.Fprintln(w, "-------\t----\t------\t---\t------\t--------\t------")
+
+	for _, s := range statuses {
+		switch {
+		case !s.Running:
+			fmt.Fprintf(w, "%s\t-\tnot running\t-\t-\t-\t-\n", s.Project)
+		case s.Error != "":
+			fmt.Fprintf(w, "%s\t-\terror: %s\t-\t-\t-\t-\n", s.Project, s.Error)
+		default:
+			for _, proc := range s.Processes {
+				uptime := humanize.Duration(time.Duration(proc.UptimeSeconds) * time.Second)
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%d\t%s\n",
+					s.Project, proc.Name, proc.Status, proc.PID, uptime, proc.Restarts, proc.Health)
+			}
+		}
+	}
+	w.Flush()
+	return nil
+}
+
+func runWsLogs(cmd *cobra.Command, args []string) error {
+	projects, err := loadWorkspaceProjects(args)
+	if err != nil {
+		return err
+	}
+
+	params := domain.LogParams{
+		Lines:    wsLogsLines,
+		Patterns: wsLogsPatterns,
+		Regex:    wsLogsRegex,
+	}
+	if _, err := params.Filter().Compile(); err != nil {
+		return err
+	}
+
+	clients := make(map[string]*Client, len(projects))
+	for _, p := range projects {
+		client, err := projectClient(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: project %s is not running\n", p.Name)
+			continue
+		}
+		clients[p.Name] = client
+	}
+	if len(clients) == 0 {
+		return clientError(errors.New("no workspace projects are running"), "Try 'prox ws up' first.")
+	}
+
+	printer := NewLogPrinter()
+	emit := func(entry api.LogEntryResponse) {
+		if wsLogsJSON {
+			if err := json.NewEncoder(os.Stdout).Encode(entry); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to encode log entry: %v\n", err)
+			}
+			return
+		}
+		printer.PrintAPIEntry(entry)
+	}
+
+	if wsLogsFollow {
+		for entry := range streamWorkspaceLogs(clients, params) {
+			emit(entry)
+		}
+		return nil
+	}
+
+	for _, entry := range collectWorkspaceLogs(clients, params) {
+		emit(entry)
+	}
+	return nil
+}
+
+// collectWorkspaceLogs fetches recent logs from each project and merges them
+// in timestamp order, keeping the last params.Lines entries overall
+func collectWorkspaceLogs(clients map[string]*Client, params domain.LogParams) []api.LogEntryResponse {
+	var entries []api.LogEntryResponse
+	for project, client := range clients {
+		resp, err := client.GetLogs(params)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to get logs for %s: %v\n", project, err)
+			continue
+		}
+		for _, entry := range resp.Logs {
+			entry.Process = project + "/" + entry.Process
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339Nano, entries[i].Timestamp)
+		tj, _ := time.Parse(time.RFC3339Nano, entries[j].Timestamp)
+		return ti.Before(tj)
+	})
+	if params.Lines > 0 && len(entries) > params.Lines {
+		entries = entries[len(entries)-params.Lines:]
+	}
+	return entries
+}
+
+// streamWorkspaceLogs fans in log streams from each project. The returned
+// channel closes once every project's stream has ended.
+func streamWorkspaceLogs(clients map[string]*Client, params domain.LogParams) <-chan api.LogEntryResponse {
+	out := make(chan api.LogEntryResponse)
+	var wg sync.WaitGroup
+	for project, client := range clients {
+		ch, err := client.StreamLogsChannel(params)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to stream logs for %s: %v\n", project, err)
+			continue
+		}
+		wg.Add(1)
+		go func(project string, ch <-chan api.LogEntryResponse) {
+			defer wg.Done()
+			for entry := range ch {
+				entry.Process = project + "/" + entry.Process
+				out <- entry
+			}
+		}(project, ch)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+func init() {
+	rootCmd.AddCommand(wsCmd)
+	wsCmd.AddCommand(wsUpCmd)
+	wsCmd.AddCommand(wsStatusCmd)
+	wsCmd.AddCommand(wsLogsCmd)
+
+	wsCmd.PersistentFlags().StringVarP(&workspacePath, "workspace", "w", constants.DefaultWorkspaceFile, "Workspace file")
+
+	wsStatusCmd.Flags().BoolVar(&wsStatusJSON, "json", false, "Output as JSON")
+
+	wsLogsCmd.Flags().BoolVarP(&wsLogsFollow, "follow", "f", false, "Stream logs continuously")
+	wsLogsCmd.Flags().IntVarP(&wsLogsLines, "lines", "n", constants.DefaultLogLimit, "Number of lines to show")
+	wsLogsCmd.Flags().StringArrayVar(&wsLogsPatterns, "pattern", nil, "Filter by pattern (repeatable; lines must match every pattern)")
+	wsLogsCmd.Flags().BoolVar(&wsLogsRegex, "regex", false, "Treat pattern as regex")
+	wsLogsCmd.Flags().BoolVar(&wsLogsJSON, "json", false, "Output as JSON")
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/charliek/prox/internal/domain"
+)
+
+// writeTestWorkspace creates a workspace with the given project names and
+// returns the workspace file path and project directories by name
+func writeTestWorkspace(t *testing.T, names ...string) (string, map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	dirs := make(map[string]string, len(names))
+	var sb strings.Builder
+	sb.WriteString("projects:\n")
+	for _, name := range names {
+		dirs[name] = filepath.Join(root, name)
+		if err := os.MkdirAll(dirs[name], 0700); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&sb, "  %s: %s\n", name, name)
+	}
+	path := filepath.Join(root, "prox-workspace.yaml")
+	if err := os.WriteFile(path, []byte(sb.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, dirs
+}
+
+// markProjectRunning writes a state file so the project looks like a running
+// daemon serving its API at serverURL
+func markProjectRunning(t *testing.T, dir, serverURL string) {
+	t.Helper()
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+	state := &daemon.State{PID: os.Getpid(), Host: u.Hostname(), Port: port, ConfigFile: "prox.yaml"}
+	if err := state.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadWorkspaceProjects(t *testing.T) {
+	path, _ := writeTestWorkspace(t, "api", "web")
+	origPath := workspacePath
+	defer func() { workspacePath = origPath }()
+	workspacePath = path
+
+	projects, err := loadWorkspaceProjects(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("expected 2 projects, got %d", len(projects))
+	}
+
+	projects, err = loadWorkspaceProjects([]string{"web"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(projects) != 1 || projects[0].Name != "web" {
+		t.Errorf("expected only web, got %v", projects)
+	}
+
+	if _, err := loadWorkspaceProjects([]string{"missing"}); err == nil {
+		t.Error("expected error for unknown project")
+	}
+}
+
+func TestRunWsStatus(t *testing.T) {
+	path, dirs := writeTestWorkspace(t, "api", "web")
+	origPath := workspacePath
+	defer func() {
+		workspacePath = origPath
+		wsStatusJSON = false
+	}()
+	workspacePath = path
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(api.ProcessListResponse{
+			Processes: []api.ProcessResponse{{Name: "server", Status: "running", PID: 1234}},
+		})
+	}))
+	defer server.Close()
+	markProjectRunning(t, dirs["api"], server.URL)
+
+	stdout, _ := captureOutput(t, func() {
+		if err := runWsStatus(wsStatusCmd, nil); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	if !strings.Contains(stdout, "server") || !strings.Contains(stdout, "1234") {
+		t.Errorf("expected api process row, got:\n%s", stdout)
+	}
+	if !strings.Contains(stdout, "not running") {
+		t.Errorf("expected web to be not running, got:\n%s", stdout)
+	}
+
+	wsStatusJSON = true
+	stdout, _ = captureOutput(t, func() {
+		runWsStatus(wsStatusCmd, nil)
+	})
+	var statuses []wsProjectStatus
+	if err := json.Unmarshal([]byte(stdout), &statuses); err != nil {
+		t.Fatalf("failed to parse JSON output: %v", err)
+	}
+	if len(statuses) != 2 || !statuses[0].Running || statuses[1].Running {
+		t.Errorf("unexpected statuses: %+v", statuses)
+	}
+}
+
+func TestCollectWorkspaceLogs(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	newLogServer := func(process string, offsets ...int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var resp api.LogsResponse
+			for _, s := range offsets {
+				resp.Logs = append(resp.Logs, api.LogEntryResponse{
+					Timestamp: base.Add(time.Duration(s) * time.Second).Format(time.RFC3339Nano),
+					Process:   process,
+					Line:      fmt.Sprintf("line %d", s),
+				})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(resp)
+		}))
+	}
+	apiServer := newLogServer("server", 1, 3)
+	defer apiServer.Close()
+	webServer := newLogServer("vite", 2, 4)
+	defer webServer.Close()
+
+	clients := map[string]*Client{
+		"api": NewClient(apiServer.URL),
+		"web": NewClient(webServer.URL),
+	}
+
+	entries := collectWorkspaceLogs(clients, domain.LogParams{Lines: 3})
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	expected := []string{"web/vite", "api/server", "web/vite"}
+	for i, entry := range entries {
+		if entry.Process != expected[i] {
+			t.Errorf("entry %d: expected process %q, got %q", i, expected[i], entry.Process)
+		}
+	}
+	if entries[2].Line != "line 4" {
+		t.Errorf("expected newest line last, got %q", entries[2].Line)
+	}
+}
@@ -2,99 +2,967 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/charliek/prox/internal/theme"
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the top-level prox configuration
 type Config struct {
-	API       APIConfig                `yaml:"api"`
-	EnvFile   string                   `yaml:"env_file"`
-	Processes map[string]ProcessConfig `yaml:"processes"`
-	Proxy     *ProxyConfig             `yaml:"proxy,omitempty"`
-	Services  map[string]ServiceConfig `yaml:"services,omitempty"`
-	Certs     *CertsConfig             `yaml:"certs,omitempty"`
+	API             APIConfig                `yaml:"api"`
+	EnvFile         string                   `yaml:"env_file"`
+	Env             map[string]string        `yaml:"env,omitempty"`    // Variables set for every process and task
+	Shell           string                   `yaml:"shell,omitempty"`  // Shell commands run through, e.g. "/bin/zsh -l" (default sh)
+	Direnv          bool                     `yaml:"direnv,omitempty"` // Run commands through direnv exec
+	Time            string                   `yaml:"time,omitempty"`   // How the CLI shows timestamps: local (default), utc, or relative
+	Processes       map[string]ProcessConfig `yaml:"processes"`
+	Tasks           map[string]TaskConfig    `yaml:"tasks,omitempty"` // Commands run on demand with prox task
+	Proxy           *ProxyConfig             `yaml:"proxy,omitempty"`
+	Services        map[string]ServiceConfig `yaml:"services,omitempty"`
+	Certs           *CertsConfig             `yaml:"certs,omitempty"`
+	Redact          *RedactConfig            `yaml:"redact,omitempty"`
+	Supervisor      *SupervisorConfig        `yaml:"supervisor,omitempty"`
+	ProblemMatchers map[string]string        `yaml:"problem_matchers,omitempty"` // Regexes, by name, that find failures in output
+	Daemon          *DaemonConfig            `yaml:"daemon,omitempty"`
+	Streams         *StreamsConfig           `yaml:"streams,omitempty"`
+	Logs            *LogsConfig              `yaml:"logs,omitempty"`
+	Storage         *StorageConfig           `yaml:"storage,omitempty"`
+	Notifications   *NotificationsConfig     `yaml:"notifications,omitempty"`
+	Theme           *ThemeConfig             `yaml:"theme,omitempty"`
+
+	// instances maps each scaled process to its instances in a config
+	// returned by Scaled
+	instances map[string][]string
+}
+
+// TimeMode returns how the CLI shows timestamps, defaulting to local time.
+// An invalid value, which validation rejects, is also local time.
+func (c *Config) TimeMode() humanize.TimeMode {
+	if c == nil {
+		return humanize.TimeLocal
+	}
+	mode, err := humanize.ParseTimeMode(c.Time)
+	if err != nil {
+		return humanize.TimeLocal
+	}
+	return mode
+}
+
+// ThemeConfig sets how log output is colored and timestamped by the CLI and
+// the TUI
+type ThemeConfig struct {
+	Background string            `yaml:"background,omitempty"` // Terminal background colors are chosen for: dark (default) or light
+	Clock      string            `yaml:"clock,omitempty"`      // Timestamps with a 24h (default) or 12h clock
+	Colors     map[string]string `yaml:"colors,omitempty"`     // Color of each process's name, by process
+	NoColor    bool              `yaml:"no_color,omitempty"`   // No colors, as with NO_COLOR
+}
+
+// OutputTheme returns how log output is colored and timestamped, defaulting
+// to colors for a dark background and a 24-hour clock in the config's time
+// mode
+func (c *Config) OutputTheme() *theme.Theme {
+	var t *theme.Theme
+	if c == nil || c.Theme == nil {
+		t = theme.New("", "", nil, false)
+	} else {
+		t = theme.New(c.Theme.Background, c.Theme.Clock, c.Theme.Colors, c.Theme.NoColor)
+	}
+	t.TimeMode = c.TimeMode()
+	return t
+}
+
+// LogsConfig sizes the in-memory log history
+type LogsConfig struct {
+	BufferSize        int    `yaml:"buffer_size,omitempty"`         // Entries shared by all processes
+	ProcessBufferSize int    `yaml:"process_buffer_size,omitempty"` // Entries reserved for each process
+	MaxMemory         string `yaml:"max_memory,omitempty"`          // Approximate cap on the memory the history uses, e.g. "64MB" (empty = no cap)
+}
+
+// BufferSizeOrDefault returns the shared log buffer size
+func (l *LogsConfig) BufferSizeOrDefault() int {
+	if l == nil || l.BufferSize == 0 {
+		return constants.DefaultLogBufferSize
+	}
+	return l.BufferSize
+}
+
+// ProcessBufferSizeOrDefault returns the log entries reserved for each process
+func (l *LogsConfig) ProcessBufferSizeOrDefault() int {
+	if l == nil || l.ProcessBufferSize == 0 {
+		return constants.DefaultLogProcessBufferSize
+	}
+	return l.ProcessBufferSize
+}
+
+// MaxMemoryBytes returns the cap on the log history's memory in bytes, or 0
+// for no cap. An invalid size, which validation rejects, is no cap.
+func (l *LogsConfig) MaxMemoryBytes() int64 {
+	if l == nil {
+		return 0
+	}
+	size, err := ParseSize(l.MaxMemory)
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// Storage backends for storage.backend
+const (
+	StorageBackendMemory = "memory" // Recent history in memory (default)
+	StorageBackendSQLite = "sqlite" // History in a SQLite database that outlives prox
+)
+
+// StorageConfig selects where log and request history is kept
+type StorageConfig struct {
+	Backend     string `yaml:"backend,omitempty"`      // "memory" (default) or "sqlite"
+	Path        string `yaml:"path,omitempty"`         // SQLite database, relative to the config file (default .prox/history.db)
+	MaxLogs     int    `yaml:"max_logs,omitempty"`     // Log entries the sqlite backend keeps
+	MaxRequests int    `yaml:"max_requests,omitempty"` // Proxy requests the sqlite backend keeps
+}
+
+// BackendOrDefault returns the storage backend, defaulting to memory
+func (s *StorageConfig) BackendOrDefault() string {
+	if s == nil || s.Backend == "" {
+		return StorageBackendMemory
+	}
+	return s.Backend
+}
+
+// MaxLogsOrDefault returns the log entries the sqlite backend keeps
+func (s *StorageConfig) MaxLogsOrDefault() int {
+	if s == nil || s.MaxLogs == 0 {
+		return constants.DefaultStorageMaxLogs
+	}
+	return s.MaxLogs
+}
+
+// MaxRequestsOrDefault returns the proxy requests the sqlite backend keeps
+func (s *StorageConfig) MaxRequestsOrDefault() int {
+	if s == nil || s.MaxRequests == 0 {
+		return constants.DefaultStorageMaxRequests
+	}
+	return s.MaxRequests
+}
+
+// StreamsConfig controls publishing process stdout on unix sockets under
+// .prox/streams
+type StreamsConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// IsEnabled reports whether process output streams are published
+func (s *StreamsConfig) IsEnabled() bool {
+	return s != nil && s.Enabled
+}
+
+// Events notifications can be sent for, named as in GET /events
+const (
+	NotifyEventCrashed   = "process_crashed"
+	NotifyEventFailed    = "process_failed"
+	NotifyEventUnhealthy = "process_unhealthy"
+	NotifyEventHealthy   = "process_healthy"
+)
+
+// NotificationsConfig controls notifications sent when processes crash or
+// fail their health checks
+type NotificationsConfig struct {
+	Desktop bool     `yaml:"desktop,omitempty"` // Show a desktop notification (macOS and Linux)
+	Command string   `yaml:"command,omitempty"` // Shell command run for each notification
+	Webhook string   `yaml:"webhook,omitempty"` // URL each notification is POSTed to as JSON
+	Events  []string `yaml:"events,omitempty"`  // Events to notify for
+}
+
+// EventsOrDefault returns the events to notify for, defaulting to crashes
+// and failing health checks
+func (n *NotificationsConfig) EventsOrDefault() []string {
+	if n == nil || len(n.Events) == 0 {
+		return []string{NotifyEventCrashed, NotifyEventUnhealthy}
+	}
+	return n.Events
+}
+
+// Idle actions for daemon.idle_action
+const (
+	IdleActionStop  = "stop"  // Stop processes and exit the daemon
+	IdleActionSleep = "sleep" // Stop processes; the next proxy request starts them again
+)
+
+// DaemonConfig controls the daemon's behavior when unused
+type DaemonConfig struct {
+	IdleTimeout string `yaml:"idle_timeout,omitempty"` // e.g., "2h"; empty disables idle shutdown
+	IdleAction  string `yaml:"idle_action,omitempty"`  // "stop" (default) or "sleep"
+	FileMode    string `yaml:"file_mode,omitempty"`    // Octal mode of state, token, log, and capture files (default "0600")
+	DirMode     string `yaml:"dir_mode,omitempty"`     // Octal mode of the directories holding them (default "0700")
+}
+
+// IdleTimeoutDuration returns the parsed idle timeout, or 0 if idle shutdown is disabled
+func (d *DaemonConfig) IdleTimeoutDuration() time.Duration {
+	if d == nil || d.IdleTimeout == "" {
+		return 0
+	}
+	timeout, err := time.ParseDuration(d.IdleTimeout)
+	if err != nil {
+		return 0
+	}
+	return timeout
+}
+
+// IdleActionOrDefault returns the idle action, defaulting to stop
+func (d *DaemonConfig) IdleActionOrDefault() string {
+	if d == nil || d.IdleAction == "" {
+		return IdleActionStop
+	}
+	return d.IdleAction
+}
+
+// FileModeOrDefault returns the mode of files prox creates, defaulting to
+// owner read and write
+func (d *DaemonConfig) FileModeOrDefault() os.FileMode {
+	if d != nil {
+		if mode, err := parseMode(d.FileMode); err == nil {
+			return mode
+		}
+	}
+	return constants.FilePermissionPrivate
+}
+
+// DirModeOrDefault returns the mode of directories prox creates, defaulting
+// to owner only
+func (d *DaemonConfig) DirModeOrDefault() os.FileMode {
+	if d != nil {
+		if mode, err := parseMode(d.DirMode); err == nil {
+			return mode
+		}
+	}
+	return constants.DirPermissionPrivate
+}
+
+// parseMode parses an octal permission mode such as "0640"
+func parseMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid mode %q", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// SupervisorConfig limits how many processes are started or stopped at once
+type SupervisorConfig struct {
+	StartConcurrency int    `yaml:"start_concurrency,omitempty"` // 0 = unlimited
+	StopConcurrency  int    `yaml:"stop_concurrency,omitempty"`  // 0 = unlimited
+	CrashLimit       int    `yaml:"crash_limit,omitempty"`       // Crashes within crash_window that mark a process failed (default 5)
+	CrashWindow      string `yaml:"crash_window,omitempty"`      // e.g. "1m" (default); "0" never marks processes failed
+}
+
+// CrashLimitOrDefault returns how many crashes within the crash window mark
+// a process failed
+func (s *SupervisorConfig) CrashLimitOrDefault() int {
+	if s == nil || s.CrashLimit <= 0 {
+		return constants.DefaultCrashLimit
+	}
+	return s.CrashLimit
+}
+
+// CrashWindowDuration returns the window crashes are counted in, or 0 if
+// processes are never marked failed
+func (s *SupervisorConfig) CrashWindowDuration() time.Duration {
+	if s == nil || s.CrashWindow == "" {
+		return constants.DefaultCrashWindow
+	}
+	d, err := time.ParseDuration(s.CrashWindow)
+	if err != nil || d < 0 {
+		return constants.DefaultCrashWindow
+	}
+	return d
+}
+
+// RedactConfig defines extra patterns masked by --redact display mode
+type RedactConfig struct {
+	Patterns []string `yaml:"patterns"` // Regular expressions to mask
+}
+
+// RedactPatterns returns the user-defined redact patterns, if any
+func (c *Config) RedactPatterns() []string {
+	if c == nil || c.Redact == nil {
+		return nil
+	}
+	return c.Redact.Patterns
+}
+
+// BuiltinProblemMatchers are the problem matchers available without defining
+// them in problem_matchers. A matcher's named groups give the problem's file
+// and line, and optionally its column, severity, and message.
+var BuiltinProblemMatchers = map[string]string{
+	// go build, go vet, and go test failures, e.g. "main.go:12:5: undefined: x"
+	"go": `^\s*(?P<file>[^\s:]+\.go):(?P<line>\d+)(?::(?P<column>\d+))?:\s*(?P<message>.+)$`,
+	// tsc, e.g. "src/app.ts(12,5): error TS2322: ..."
+	"tsc": `^(?P<file>[^\s(]+\.[cm]?[jt]sx?)\((?P<line>\d+),(?P<column>\d+)\):\s*(?P<severity>error|warning)\s+(?P<message>.+)$`,
+	// gcc, clang, and tools with the same format, e.g. "a.c:3:1: error: ..."
+	"gcc": `^(?P<file>[^\s:]+):(?P<line>\d+):(?P<column>\d+):\s*(?:fatal )?(?P<severity>error|warning|note):\s*(?P<message>.+)$`,
+}
+
+// ProblemMatcherGroups are the named groups a problem matcher must have
+var ProblemMatcherGroups = []string{"file", "line"}
+
+// ProblemMatcherPatterns returns the regexes of each process's problem
+// matchers, by process. Matchers defined in problem_matchers take precedence
+// over built-in ones with the same name; unknown names are skipped.
+func (c *Config) ProblemMatcherPatterns() map[string][]string {
+	patterns := make(map[string][]string)
+	for name, proc := range c.Processes {
+		for _, matcher := range proc.ProblemMatchers {
+			if pattern, ok := c.problemMatcher(matcher); ok {
+				patterns[name] = append(patterns[name], pattern)
+			}
+		}
+	}
+	return patterns
+}
+
+// problemMatcher returns the regex of the named problem matcher
+func (c *Config) problemMatcher(name string) (string, bool) {
+	if pattern, ok := c.ProblemMatchers[name]; ok {
+		return pattern, true
+	}
+	pattern, ok := BuiltinProblemMatchers[name]
+	return pattern, ok
 }
 
 // ProxyConfig defines the HTTP/HTTPS reverse proxy configuration
 type ProxyConfig struct {
-	Enabled   bool           `yaml:"enabled"`
-	HTTPPort  int            `yaml:"http_port"`
-	HTTPSPort int            `yaml:"https_port"`
-	Domain    string         `yaml:"domain"`
-	Capture   *CaptureConfig `yaml:"capture,omitempty"`
+	Enabled   bool                `yaml:"enabled"`
+	HTTPPort  int                 `yaml:"http_port"`
+	HTTPSPort int                 `yaml:"https_port"`
+	Domain    string              `yaml:"domain"`
+	Capture   *CaptureConfig      `yaml:"capture,omitempty"`
+	API       *ProxyAPIConfig     `yaml:"api,omitempty"`
+	AccessLog *AccessLogConfig    `yaml:"access_log,omitempty"`
+	DNS       string              `yaml:"dns,omitempty"`      // How proxy hostnames resolve: "hosts" (default) or "builtin"
+	DNSPort   int                 `yaml:"dns_port,omitempty"` // Port of the builtin DNS responder (default: 5354)
+	History   *ProxyHistoryConfig `yaml:"history,omitempty"`
+
+	// RestartWait is how long requests for a restarting process wait for it
+	// to come back, e.g. "10s" ("0" forwards them at once; default: 30s)
+	RestartWait string `yaml:"restart_wait,omitempty"`
+}
+
+// RestartWaitDuration returns how long proxied requests wait for a
+// restarting process, or 0 if they don't
+func (p *ProxyConfig) RestartWaitDuration() time.Duration {
+	if p == nil || p.RestartWait == "" {
+		return constants.DefaultProxyRestartWait
+	}
+	d, err := time.ParseDuration(p.RestartWait)
+	if err != nil || d < 0 {
+		return constants.DefaultProxyRestartWait
+	}
+	return d
+}
+
+// ProxyHistoryConfig controls the history of proxied requests
+type ProxyHistoryConfig struct {
+	Persist bool `yaml:"persist,omitempty"` // Keep the history in .prox across restarts
+	Max     int  `yaml:"max,omitempty"`     // Requests kept (default: 1000)
+}
+
+// HistoryPersisted reports whether the request history is kept across
+// restarts
+func (p *ProxyConfig) HistoryPersisted() bool {
+	return p != nil && p.History != nil && p.History.Persist
+}
+
+// HistoryMaxOrDefault returns the number of proxied requests kept
+func (p *ProxyConfig) HistoryMaxOrDefault() int {
+	if p == nil || p.History == nil || p.History.Max == 0 {
+		return constants.DefaultProxyRequestBufferSize
+	}
+	return p.History.Max
+}
+
+// Proxy DNS modes
+const (
+	ProxyDNSHosts   = "hosts"   // Hostnames are added to /etc/hosts with prox hosts sync
+	ProxyDNSBuiltin = "builtin" // prox answers DNS queries for the domain itself
+)
+
+// DNSBuiltin reports whether prox runs its own DNS responder for the domain
+func (p *ProxyConfig) DNSBuiltin() bool {
+	return p != nil && p.DNS == ProxyDNSBuiltin
+}
+
+// DNSPortOrDefault returns the port of the builtin DNS responder
+func (p *ProxyConfig) DNSPortOrDefault() int {
+	if p == nil || p.DNSPort == 0 {
+		return constants.DefaultDNSPort
+	}
+	return p.DNSPort
+}
+
+// IsLocalhostDomain reports whether domain is localhost or under it. Names
+// under .localhost always resolve to the loopback address (RFC 6761), so
+// they need neither /etc/hosts entries nor a DNS responder.
+func IsLocalhostDomain(domain string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	return domain == "localhost" || strings.HasSuffix(domain, ".localhost")
+}
+
+// ProxyAPIConfig exposes the control API through the proxy at a reserved
+// subdomain. Authentication is always required there.
+type ProxyAPIConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Subdomain string `yaml:"subdomain,omitempty"` // Reserved subdomain (default: prox)
+}
+
+// APIEnabled reports whether the control API is exposed through the proxy
+func (p *ProxyConfig) APIEnabled() bool {
+	return p != nil && p.API != nil && p.API.Enabled
+}
+
+// APISubdomain returns the reserved subdomain for the proxied API
+func (p *ProxyConfig) APISubdomain() string {
+	if p == nil || p.API == nil || p.API.Subdomain == "" {
+		return constants.DefaultProxyAPISubdomain
+	}
+	return p.API.Subdomain
+}
+
+// AccessLogConfig writes each proxied request to the logs, so proxy traffic
+// is interleaved with the output of the processes serving it
+type AccessLogConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Format  string `yaml:"format,omitempty"`  // "text" (default) or "json"
+	Process string `yaml:"process,omitempty"` // Process name the lines are logged under (default: proxy)
+}
+
+// Access log formats
+const (
+	AccessLogFormatText = "text" // e.g. "a1b2c3d GET api /users 200 45ms 1.8 KiB"
+	AccessLogFormatJSON = "json" // One JSON object per request
+)
+
+// AccessLogEnabled reports whether proxied requests are written to the logs
+func (p *ProxyConfig) AccessLogEnabled() bool {
+	return p != nil && p.AccessLog != nil && p.AccessLog.Enabled
+}
+
+// FormatOrDefault returns the access log format, defaulting to text
+func (a *AccessLogConfig) FormatOrDefault() string {
+	if a == nil || a.Format == "" {
+		return AccessLogFormatText
+	}
+	return a.Format
+}
+
+// ProcessOrDefault returns the process name access log lines are logged
+// under, defaulting to proxy
+func (a *AccessLogConfig) ProcessOrDefault() string {
+	if a == nil || a.Process == "" {
+		return constants.DefaultAccessLogProcess
+	}
+	return a.Process
 }
 
 // CaptureConfig defines request/response capture settings
 type CaptureConfig struct {
-	Enabled     bool   `yaml:"enabled"`
-	MaxBodySize string `yaml:"max_body_size"` // e.g., "1MB", "512KB"
+	Enabled     bool          `yaml:"enabled"`
+	MaxBodySize string        `yaml:"max_body_size"`     // e.g., "1MB", "512KB"
+	Include     []CaptureRule `yaml:"include,omitempty"` // Capture only requests matching one of these (empty = all)
+	Exclude     []CaptureRule `yaml:"exclude,omitempty"` // Don't capture requests matching any of these
+}
+
+// CaptureRule matches proxied requests for capture. A request matches when
+// every field that is set matches it.
+type CaptureRule struct {
+	Subdomain   string   `yaml:"subdomain,omitempty"`    // Subdomain, or a glob such as "pr-*"
+	Path        string   `yaml:"path,omitempty"`         // Regex matched against the URL path, e.g. "^/static/"
+	Methods     []string `yaml:"methods,omitempty"`      // e.g. [POST, PUT]
+	ContentType string   `yaml:"content_type,omitempty"` // Response media type, e.g. "application/json" or "image/*"
+	WebSocket   bool     `yaml:"websocket,omitempty"`    // Only WebSocket upgrades
 }
 
 // ServiceConfig represents a service routing configuration that can be either
 // a simple port number or an expanded form with additional options
 type ServiceConfig struct {
-	Port int    `yaml:"port"`
-	Host string `yaml:"host"`
+	Port      int            `yaml:"port"`
+	Host      string         `yaml:"host"`
+	PortRange string         `yaml:"port_range,omitempty"` // Wildcard services only, e.g. "4000-4099"
+	Process   string         `yaml:"process,omitempty"`    // Process that serves this service
+	Protocol  string         `yaml:"protocol,omitempty"`   // "http" (default) or "h2c"
+	SLO       *SLOConfig     `yaml:"slo,omitempty"`
+	Schema    *SchemaConfig  `yaml:"schema,omitempty"`
+	Rewrite   *RewriteConfig `yaml:"rewrite,omitempty"`
+}
+
+// Service backend protocols
+const (
+	ServiceProtocolHTTP = "http" // HTTP/1.1 to the backend
+	ServiceProtocolH2C  = "h2c"  // HTTP/2 over cleartext to the backend, e.g. for gRPC
+)
+
+// MatchPlaceholder is replaced in the host of a wildcard service with the
+// part of the subdomain the wildcard matched
+const MatchPlaceholder = "{match}"
+
+// WildcardPrefix returns the prefix of a wildcard service name, e.g. "pr-"
+// for "pr-*" and "" for the fallback service "*". ok is false for names
+// without a wildcard.
+func WildcardPrefix(name string) (prefix string, ok bool) {
+	return strings.CutSuffix(name, "*")
+}
+
+// ParsePortRange parses a port range like "4000-4099"
+func ParsePortRange(s string) (start, end int, err error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("must be start-end, got %q", s)
+	}
+	start, err = strconv.Atoi(strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid start port %q", from)
+	}
+	end, err = strconv.Atoi(strings.TrimSpace(to))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid end port %q", to)
+	}
+	if start < 1 || end > 65535 || start > end {
+		return 0, 0, fmt.Errorf("must be within 1-65535 with start <= end, got %q", s)
+	}
+	return start, end, nil
+}
+
+// ForMatch returns the target of a wildcard service for the part of the
+// subdomain its wildcard matched. With a port range the match must be a
+// number, and the port is the start of the range plus that number; a
+// {match} placeholder in the host is replaced with the match. ok is false
+// if the match doesn't map to a valid target.
+func (s ServiceConfig) ForMatch(match string) (ServiceConfig, bool) {
+	if s.PortRange != "" {
+		start, end, err := ParsePortRange(s.PortRange)
+		if err != nil {
+			return ServiceConfig{}, false
+		}
+		n, err := strconv.Atoi(match)
+		if err != nil || n < 0 || n > end-start {
+			return ServiceConfig{}, false
+		}
+		s.Port = start + n
+	}
+	if strings.Contains(s.Host, MatchPlaceholder) {
+		s.Host = strings.ReplaceAll(s.Host, MatchPlaceholder, strings.ToLower(match))
+		if validateHost(s.Host) != nil {
+			return ServiceConfig{}, false
+		}
+	}
+	return s, true
+}
+
+// SLOConfig defines the expected latency budget for a proxied service
+type SLOConfig struct {
+	P95 string `yaml:"p95"` // e.g., "300ms", "1s"
+}
+
+// SchemaConfig attaches a JSON Schema that captured responses are checked against
+type SchemaConfig struct {
+	File   string  `yaml:"file"`             // Path to a JSON Schema file, relative to the project directory
+	Sample float64 `yaml:"sample,omitempty"` // Fraction of responses to validate, 0 < sample <= 1 (default: 1)
+}
+
+// RewriteConfig changes requests on their way to a service and responses on
+// their way back, e.g. to inject an Authorization header or strip a
+// Content-Security-Policy during local development
+type RewriteConfig struct {
+	RequestHeaders  *HeaderRewrite `yaml:"request_headers,omitempty"`
+	ResponseHeaders *HeaderRewrite `yaml:"response_headers,omitempty"`
+	Host            string         `yaml:"host,omitempty"` // "preserve" (default) or "target"
+}
+
+// HeaderRewrite removes and sets headers. Removals apply first, so a header
+// can be both removed and set to replace every value it had.
+type HeaderRewrite struct {
+	Set    map[string]string `yaml:"set,omitempty"`
+	Remove []string          `yaml:"remove,omitempty"`
+}
+
+// Host header modes for requests sent to a service
+const (
+	RewriteHostPreserve = "preserve" // Send the Host the client used, e.g. app.local.myapp.dev
+	RewriteHostTarget   = "target"   // Send the service's host and port, e.g. localhost:3000
+)
+
+// HostMode returns the Host header mode, defaulting to preserve
+func (r *RewriteConfig) HostMode() string {
+	if r == nil || r.Host == "" {
+		return RewriteHostPreserve
+	}
+	return r.Host
+}
+
+// SampleRate returns the fraction of responses to validate, defaulting to 1
+func (s SchemaConfig) SampleRate() float64 {
+	if s.Sample == 0 {
+		return 1
+	}
+	return s.Sample
+}
+
+// P95Budget returns the parsed p95 latency budget, or 0 if none is configured
+func (s ServiceConfig) P95Budget() time.Duration {
+	if s.SLO == nil || s.SLO.P95 == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s.SLO.P95)
+	if err != nil {
+		return 0
+	}
+	return d
 }
 
 // CertsConfig defines certificate configuration
 type CertsConfig struct {
 	Dir          string `yaml:"dir"`
 	AutoGenerate bool   `yaml:"auto_generate"`
+	Provider     string `yaml:"provider,omitempty"` // "mkcert" (default) or "self-signed"
+}
+
+// Certificate providers
+const (
+	CertProviderMkcert     = "mkcert"      // Certificates signed by mkcert's locally trusted CA
+	CertProviderSelfSigned = "self-signed" // Self-signed certificates generated by prox; browsers warn about them
+)
+
+// ProviderOrDefault returns the certificate provider, defaulting to mkcert
+func (c *CertsConfig) ProviderOrDefault() string {
+	if c == nil || c.Provider == "" {
+		return CertProviderMkcert
+	}
+	return c.Provider
 }
 
 // APIConfig defines the HTTP API configuration
 type APIConfig struct {
-	Port int    `yaml:"port"`
-	Host string `yaml:"host"`
-	Auth *bool  `yaml:"auth,omitempty"` // nil = auto-determine based on host
+	Port int           `yaml:"port"`
+	Host string        `yaml:"host"`
+	Auth *bool         `yaml:"auth,omitempty"` // nil = auto-determine based on host
+	TLS  *APITLSConfig `yaml:"tls,omitempty"`
+}
+
+// APITLSConfig serves the API over HTTPS, with the given certificate or one
+// generated under certs.dir
+type APITLSConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Cert    string   `yaml:"cert,omitempty"`  // PEM certificate, relative to the config file
+	Key     string   `yaml:"key,omitempty"`   // PEM private key, relative to the config file
+	Hosts   []string `yaml:"hosts,omitempty"` // Extra names and IPs for the generated certificate
+}
+
+// IsEnabled reports whether the API is served over HTTPS
+func (t *APITLSConfig) IsEnabled() bool {
+	return t != nil && t.Enabled
 }
 
 // ProcessConfig represents a process configuration that can be either
 // a simple string command or an expanded form with additional options
 type ProcessConfig struct {
-	Cmd         string             `yaml:"cmd"`
-	Env         map[string]string  `yaml:"env"`
-	EnvFile     string             `yaml:"env_file"`
-	Healthcheck *HealthcheckConfig `yaml:"healthcheck"`
+	Cmd             string             `yaml:"cmd"`
+	Shell           string             `yaml:"shell,omitempty"`  // Overrides the global shell
+	Direnv          *bool              `yaml:"direnv,omitempty"` // Overrides the global direnv
+	Env             map[string]string  `yaml:"env"`
+	EnvFile         string             `yaml:"env_file"`
+	InheritEnv      *bool              `yaml:"inherit_env,omitempty"` // false to start without prox's own environment
+	Port            string             `yaml:"port,omitempty"`        // "auto" or a fixed port number, injected as $PORT
+	Healthcheck     *HealthcheckConfig `yaml:"healthcheck"`
+	WaitFor         []string           `yaml:"wait_for,omitempty"`          // e.g., tcp://localhost:5432, http://localhost:9200/health
+	WaitTimeout     string             `yaml:"wait_timeout,omitempty"`      // e.g., "60s"
+	DependsOn       []string           `yaml:"depends_on,omitempty"`        // Processes to start first; replaces the order inferred from wait_for
+	Ready           string             `yaml:"ready,omitempty"`             // Probe that must pass before the process counts as running, e.g. http://localhost:$PORT/health
+	ReadyTimeout    string             `yaml:"ready_timeout,omitempty"`     // e.g., "60s"
+	Lazy            bool               `yaml:"lazy,omitempty"`              // Start on the first proxy request instead of at prox up
+	IdleTimeout     string             `yaml:"idle_timeout,omitempty"`      // Stop a lazy process after this long without requests
+	LogBuffer       int                `yaml:"log_buffer,omitempty"`        // Log entries reserved for this process (0 = logs.process_buffer_size)
+	MaxLinesPerSec  int                `yaml:"max_lines_per_sec,omitempty"` // Log lines kept per second; the rest are dropped and counted (0 = unlimited)
+	CollapseRepeats bool               `yaml:"collapse_repeats,omitempty"`  // Keep one of a run of identical log lines, noting how often it repeated
+	EnvPrompt       []string           `yaml:"env_prompt,omitempty"`        // Variables to ask for at startup when unset, e.g. secrets
+	Profiles        []string           `yaml:"profiles,omitempty"`          // Profiles that start this process with prox up --profile
+	ProblemMatchers []string           `yaml:"problem_matchers,omitempty"`  // Built-in or problem_matchers names applied to output
+	LogFormat       string             `yaml:"log_format,omitempty"`        // "json" or "logfmt" to parse lines into fields such as level
+	Type            string             `yaml:"type,omitempty"`              // "synthetic" for a generated process instead of cmd
+	Synthetic       *SyntheticConfig   `yaml:"synthetic,omitempty"`         // Output and exits of a type: synthetic process
+	Scale           int                `yaml:"scale,omitempty"`             // Instances to run, named name-1 through name-N (default 1)
+	Watch           []string           `yaml:"watch,omitempty"`             // Globs, relative to the config file, whose changes restart the process
+	WatchIgnore     []string           `yaml:"watch_ignore,omitempty"`      // Globs whose changes don't, even if watch matches them
+	WatchDebounce   string             `yaml:"watch_debounce,omitempty"`    // Quiet time after a change before restarting, e.g. "500ms"
+	Stdin           bool               `yaml:"stdin,omitempty"`             // Accept input from prox attach, e.g. for a REPL
+}
+
+// TaskConfig is a short-lived command run on demand with prox task, such as
+// a database migration or seeder. prox up doesn't start tasks, and they
+// aren't restarted or health checked; their output is logged under the
+// task's name.
+type TaskConfig struct {
+	Cmd         string            `yaml:"cmd"`
+	Description string            `yaml:"description,omitempty"` // Shown by prox task
+	Shell       string            `yaml:"shell,omitempty"`       // Overrides the global shell
+	Direnv      *bool             `yaml:"direnv,omitempty"`      // Overrides the global direnv
+	Env         map[string]string `yaml:"env,omitempty"`
+	EnvFile     string            `yaml:"env_file,omitempty"`
+	InheritEnv  *bool             `yaml:"inherit_env,omitempty"` // false to start without prox's own environment
+}
+
+// ProcessConfig returns the process config a task's command runs as, for
+// resolving its shell and environment like a process's
+func (t TaskConfig) ProcessConfig() ProcessConfig {
+	return ProcessConfig{Cmd: t.Cmd, Shell: t.Shell, Direnv: t.Direnv, Env: t.Env, EnvFile: t.EnvFile, InheritEnv: t.InheritEnv}
+}
+
+// ProcessTypeSynthetic is the type of a process prox runs itself, writing
+// generated log lines instead of running a command
+const ProcessTypeSynthetic = "synthetic"
+
+// SyntheticConfig defines a synthetic process in YAML
+type SyntheticConfig struct {
+	Rate             int     `yaml:"rate,omitempty"`              // Lines per second (default 10)
+	ExitAfter        string  `yaml:"exit_after,omitempty"`        // Exit with status 0 after this long, e.g. "30s"
+	CrashProbability float64 `yaml:"crash_probability,omitempty"` // Chance each second of exiting with status 1, from 0 to 1
+}
+
+// PortAuto is the process port value that requests a dynamically allocated port
+const PortAuto = "auto"
+
+// AutoPort returns true if the process requests a dynamically allocated port
+func (p ProcessConfig) AutoPort() bool {
+	return p.Port == PortAuto
+}
+
+// InheritsEnv reports whether the process starts with prox's own
+// environment, which it does unless inherit_env is false
+func (p ProcessConfig) InheritsEnv() bool {
+	return p.InheritEnv == nil || *p.InheritEnv
+}
+
+// LogThrottle returns the limits on how much of the process's output is logged
+func (p ProcessConfig) LogThrottle() domain.LogThrottle {
+	return domain.LogThrottle{MaxLinesPerSec: p.MaxLinesPerSec, CollapseRepeats: p.CollapseRepeats}
+}
+
+// FixedPort returns the configured fixed port, or 0 if none is configured
+func (p ProcessConfig) FixedPort() int {
+	if p.Port == "" || p.AutoPort() {
+		return 0
+	}
+	port, err := strconv.Atoi(p.Port)
+	if err != nil {
+		return 0
+	}
+	return port
+}
+
+// WaitTimeoutDuration returns the parsed wait_for timeout, or 0 if none is configured
+func (p ProcessConfig) WaitTimeoutDuration() time.Duration {
+	if p.WaitTimeout == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(p.WaitTimeout)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// WatchDebounceDuration returns the parsed watch debounce, defaulting to
+// constants.DefaultWatchDebounce
+func (p ProcessConfig) WatchDebounceDuration() time.Duration {
+	d, err := time.ParseDuration(p.WatchDebounce)
+	if err != nil || d <= 0 {
+		return constants.DefaultWatchDebounce
+	}
+	return d
+}
+
+// ReadyTimeoutDuration returns the parsed ready probe timeout, or 0 if none is configured
+func (p ProcessConfig) ReadyTimeoutDuration() time.Duration {
+	if p.ReadyTimeout == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(p.ReadyTimeout)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// ReadyTarget returns the ready probe with $PORT replaced by the process's port
+func (p ProcessConfig) ReadyTarget(port int) string {
+	return readyPortRegex.ReplaceAllString(p.Ready, strconv.Itoa(port))
+}
+
+// readyPortRegex matches $PORT and ${PORT} in a ready probe
+var readyPortRegex = regexp.MustCompile(`\$(PORT\b|\{PORT\})`)
+
+// IdleTimeoutDuration returns the parsed idle timeout of a lazy process, or 0 if none is configured
+func (p ProcessConfig) IdleTimeoutDuration() time.Duration {
+	if p.IdleTimeout == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(p.IdleTimeout)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// SyntheticProcess returns the settings of a type: synthetic process, or nil
+// for a process that runs cmd. Invalid durations are left as zero.
+func (p ProcessConfig) SyntheticProcess() *domain.SyntheticConfig {
+	if p.Type != ProcessTypeSynthetic {
+		return nil
+	}
+	result := &domain.SyntheticConfig{Rate: constants.DefaultSyntheticRate}
+	if s := p.Synthetic; s != nil {
+		if s.Rate > 0 {
+			result.Rate = s.Rate
+		}
+		if d, err := time.ParseDuration(s.ExitAfter); err == nil {
+			result.ExitAfter = d
+		}
+		result.CrashProbability = s.CrashProbability
+	}
+	return result
 }
 
 // HealthcheckConfig defines health check configuration in YAML
 type HealthcheckConfig struct {
-	Cmd         string `yaml:"cmd"`
-	Interval    string `yaml:"interval"`
-	Timeout     string `yaml:"timeout"`
-	Retries     int    `yaml:"retries"`
-	StartPeriod string `yaml:"start_period"`
+	Type        string                 `yaml:"type,omitempty"` // "cmd" (default), "proxy", "http", or "tcp"
+	Cmd         string                 `yaml:"cmd"`
+	Service     string                 `yaml:"service,omitempty"` // Proxy checks: service to request (default: the one linked to the process)
+	Path        string                 `yaml:"path,omitempty"`    // Proxy checks: path to request (default: /)
+	HTTP        *HealthcheckHTTPConfig `yaml:"http,omitempty"`    // HTTP checks: URL to request
+	TCP         *HealthcheckTCPConfig  `yaml:"tcp,omitempty"`     // TCP checks: address to connect to
+	Interval    string                 `yaml:"interval"`
+	Timeout     string                 `yaml:"timeout"`
+	Retries     int                    `yaml:"retries"`
+	StartPeriod string                 `yaml:"start_period"`
+}
+
+// HealthcheckHTTPConfig is the request of an HTTP healthcheck
+type HealthcheckHTTPConfig struct {
+	URL            string `yaml:"url"`                       // May use the process's environment, e.g. $PORT
+	ExpectedStatus int    `yaml:"expected_status,omitempty"` // Healthy status (default: any 2xx or 3xx)
+}
+
+// HealthcheckTCPConfig is the address of a TCP healthcheck
+type HealthcheckTCPConfig struct {
+	Host string `yaml:"host,omitempty"` // Default: 127.0.0.1
+	Port int    `yaml:"port,omitempty"` // Default: the process's $PORT
+}
+
+// Address returns the address a TCP check connects to, which refers to
+// $PORT when no port is set
+func (t *HealthcheckTCPConfig) Address() string {
+	host := t.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	port := "$PORT"
+	if t.Port != 0 {
+		port = strconv.Itoa(t.Port)
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// Healthcheck types
+const (
+	HealthcheckTypeCmd   = "cmd"   // Run a command; exit status 0 is healthy
+	HealthcheckTypeProxy = "proxy" // Request the process's service through the proxy
+	HealthcheckTypeHTTP  = "http"  // Request a URL; the expected status is healthy
+	HealthcheckTypeTCP   = "tcp"   // Connect to an address; accepting is healthy
+)
+
+// TypeOrDefault returns the healthcheck's type. Without a type, a check
+// with http or tcp set is of that type, and any other is a command.
+func (hc *HealthcheckConfig) TypeOrDefault() string {
+	switch {
+	case hc.Type != "":
+		return hc.Type
+	case hc.HTTP != nil:
+		return HealthcheckTypeHTTP
+	case hc.TCP != nil:
+		return HealthcheckTypeTCP
+	default:
+		return HealthcheckTypeCmd
+	}
 }
 
 type rawProxyConfig struct {
-	Enabled   *bool          `yaml:"enabled,omitempty"`
-	HTTPPort  int            `yaml:"http_port"`
-	HTTPSPort int            `yaml:"https_port"`
-	Domain    string         `yaml:"domain"`
-	Capture   *CaptureConfig `yaml:"capture,omitempty"`
+	Enabled   *bool               `yaml:"enabled,omitempty"`
+	HTTPPort  int                 `yaml:"http_port"`
+	HTTPSPort int                 `yaml:"https_port"`
+	Domain    string              `yaml:"domain"`
+	Capture   *CaptureConfig      `yaml:"capture,omitempty"`
+	API       *ProxyAPIConfig     `yaml:"api,omitempty"`
+	AccessLog *AccessLogConfig    `yaml:"access_log,omitempty"`
+	DNS       string              `yaml:"dns,omitempty"`
+	DNSPort   int                 `yaml:"dns_port,omitempty"`
+	History   *ProxyHistoryConfig `yaml:"history,omitempty"`
 }
 
 // rawConfig is used for initial YAML parsing to handle the flexible process/service format
 type rawConfig struct {
-	API       APIConfig              `yaml:"api"`
-	EnvFile   string                 `yaml:"env_file"`
-	Processes map[string]interface{} `yaml:"processes"`
-	Proxy     *rawProxyConfig        `yaml:"proxy,omitempty"`
-	Services  map[string]interface{} `yaml:"services,omitempty"`
-	Certs     *CertsConfig           `yaml:"certs,omitempty"`
+	API             APIConfig              `yaml:"api"`
+	EnvFile         string                 `yaml:"env_file"`
+	Env             map[string]string      `yaml:"env,omitempty"`
+	Shell           string                 `yaml:"shell,omitempty"`
+	Direnv          bool                   `yaml:"direnv,omitempty"`
+	Time            string                 `yaml:"time,omitempty"`
+	Processes       map[string]interface{} `yaml:"processes"`
+	Tasks           map[string]interface{} `yaml:"tasks,omitempty"`
+	Proxy           *rawProxyConfig        `yaml:"proxy,omitempty"`
+	Services        map[string]interface{} `yaml:"services,omitempty"`
+	Certs           *CertsConfig           `yaml:"certs,omitempty"`
+	Redact          *RedactConfig          `yaml:"redact,omitempty"`
+	Supervisor      *SupervisorConfig      `yaml:"supervisor,omitempty"`
+	ProblemMatchers map[string]string      `yaml:"problem_matchers,omitempty"`
+	Daemon          *DaemonConfig          `yaml:"daemon,omitempty"`
+	Streams         *StreamsConfig         `yaml:"streams,omitempty"`
+	Logs            *LogsConfig            `yaml:"logs,omitempty"`
+	Storage         *StorageConfig         `yaml:"storage,omitempty"`
+	Notifications   *NotificationsConfig   `yaml:"notifications,omitempty"`
+	Theme           *ThemeConfig           `yaml:"theme,omitempty"`
 }
 
-// Load reads and parses a configuration file
+// Load reads and parses a configuration file, which may be a Procfile
 func Load(path string) (*Config, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !IsProcfile(path) {
+		return Parse(data)
+	}
+	config, err := parseProcfile(data, filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// readConfigFile reads a configuration file after checking its permissions
+func readConfigFile(path string) ([]byte, error) {
 	// First check if file exists
 	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
@@ -112,23 +980,49 @@ func Load(path string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("reading config file: %w", err)
 	}
-
-	return Parse(data)
+	return data, nil
 }
 
 // Parse parses configuration from YAML bytes
 func Parse(data []byte) (*Config, error) {
+	config, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// parse parses configuration from YAML bytes and applies defaults without
+// validating it
+func parse(data []byte) (*Config, error) {
 	var raw rawConfig
 	if err := yaml.Unmarshal(data, &raw); err != nil {
 		return nil, fmt.Errorf("parsing yaml: %w", err)
 	}
 
 	config := &Config{
-		API:       raw.API,
-		EnvFile:   raw.EnvFile,
-		Processes: make(map[string]ProcessConfig),
-		Services:  make(map[string]ServiceConfig),
-		Certs:     raw.Certs,
+		API:             raw.API,
+		EnvFile:         raw.EnvFile,
+		Env:             raw.Env,
+		Shell:           raw.Shell,
+		Direnv:          raw.Direnv,
+		Time:            raw.Time,
+		Processes:       make(map[string]ProcessConfig),
+		Tasks:           make(map[string]TaskConfig),
+		Services:        make(map[string]ServiceConfig),
+		Certs:           raw.Certs,
+		Redact:          raw.Redact,
+		Supervisor:      raw.Supervisor,
+		ProblemMatchers: raw.ProblemMatchers,
+		Daemon:          raw.Daemon,
+		Streams:         raw.Streams,
+		Notifications:   raw.Notifications,
+		Theme:           raw.Theme,
+		Logs:            raw.Logs,
+		Storage:         raw.Storage,
 	}
 	if raw.Proxy != nil {
 		config.Proxy = &ProxyConfig{
@@ -136,6 +1030,11 @@ func Parse(data []byte) (*Config, error) {
 			HTTPSPort: raw.Proxy.HTTPSPort,
 			Domain:    raw.Proxy.Domain,
 			Capture:   raw.Proxy.Capture,
+			API:       raw.Proxy.API,
+			AccessLog: raw.Proxy.AccessLog,
+			DNS:       raw.Proxy.DNS,
+			DNSPort:   raw.Proxy.DNSPort,
+			History:   raw.Proxy.History,
 		}
 		if raw.Proxy.Enabled != nil {
 			config.Proxy.Enabled = *raw.Proxy.Enabled
@@ -159,6 +1058,15 @@ func Parse(data []byte) (*Config, error) {
 		config.Processes[name] = proc
 	}
 
+	// Parse tasks (can be string or expanded form)
+	for name, value := range raw.Tasks {
+		task, err := parseTaskConfig(value)
+		if err != nil {
+			return nil, fmt.Errorf("task %q: %w", name, err)
+		}
+		config.Tasks[name] = task
+	}
+
 	// Parse services (can be int port or expanded form)
 	for name, value := range raw.Services {
 		svc, err := parseServiceConfig(name, value)
@@ -194,10 +1102,6 @@ func Parse(data []byte) (*Config, error) {
 		}
 	}
 
-	if err := Validate(config); err != nil {
-		return nil, err
-	}
-
 	return config, nil
 }
 
@@ -223,6 +1127,28 @@ func parseProcessConfig(name string, value interface{}) (ProcessConfig, error) {
 	}
 }
 
+// parseTaskConfig handles both simple and expanded task definitions
+func parseTaskConfig(value interface{}) (TaskConfig, error) {
+	switch v := value.(type) {
+	case string:
+		// Simple form: migrate: bin/rails db:migrate
+		return TaskConfig{Cmd: v}, nil
+	case map[string]interface{}:
+		// Expanded form: re-marshal and unmarshal to struct
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return TaskConfig{}, fmt.Errorf("marshaling task config: %w", err)
+		}
+		var task TaskConfig
+		if err := yaml.Unmarshal(data, &task); err != nil {
+			return TaskConfig{}, fmt.Errorf("unmarshaling task config: %w", err)
+		}
+		return task, nil
+	default:
+		return TaskConfig{}, fmt.Errorf("invalid task configuration type: %T", value)
+	}
+}
+
 // parseServiceConfig handles both simple (port only) and expanded service definitions
 func parseServiceConfig(name string, value interface{}) (ServiceConfig, error) {
 	switch v := value.(type) {
@@ -252,43 +1178,207 @@ func parseServiceConfig(name string, value interface{}) (ServiceConfig, error) {
 	}
 }
 
+// ShellFor returns the shell command line a process's cmd runs through,
+// split into words: the process's shell, else the global one, else sh. The
+// shell is split on whitespace without interpreting quotes, and the cmd is
+// passed to it as the single argument after -c.
+func (c *Config) ShellFor(proc ProcessConfig) []string {
+	shell := proc.Shell
+	if shell == "" {
+		shell = c.Shell
+	}
+	if words := strings.Fields(shell); len(words) > 0 {
+		return words
+	}
+	return []string{constants.DefaultShell}
+}
+
+// DependenciesOf returns the processes a process's depends_on names, with
+// the instances of scaled processes in place of their names
+func (c *Config) DependenciesOf(proc ProcessConfig) []string {
+	var names []string
+	for _, name := range proc.DependsOn {
+		names = append(names, c.Instances(name)...)
+	}
+	return names
+}
+
+// DirenvFor reports whether a process's shell runs through direnv exec
+func (c *Config) DirenvFor(proc ProcessConfig) bool {
+	if proc.Direnv != nil {
+		return *proc.Direnv
+	}
+	return c.Direnv
+}
+
+// Profiles returns the names of all profiles processes are tagged with,
+// sorted
+func (c *Config) Profiles() []string {
+	seen := make(map[string]bool)
+	var profiles []string
+	for _, proc := range c.Processes {
+		for _, profile := range proc.Profiles {
+			if !seen[profile] {
+				seen[profile] = true
+				profiles = append(profiles, profile)
+			}
+		}
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// ProfileProcesses returns the names of the processes tagged with any of the
+// profiles, sorted. It fails if a profile has no processes.
+func (c *Config) ProfileProcesses(profiles []string) ([]string, error) {
+	selected := make(map[string]bool)
+	for _, profile := range profiles {
+		found := false
+		for name, proc := range c.Processes {
+			if slices.Contains(proc.Profiles, profile) {
+				selected[name] = true
+				found = true
+			}
+		}
+		if !found {
+			known := c.Profiles()
+			if len(known) == 0 {
+				return nil, fmt.Errorf("unknown profile %q: no process has profiles", profile)
+			}
+			return nil, fmt.Errorf("unknown profile %q (profiles: %s)", profile, strings.Join(known, ", "))
+		}
+	}
+
+	names := make([]string, 0, len(selected))
+	for name := range selected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 // ToDomainProcesses converts config processes to domain ProcessConfig slice
 func (c *Config) ToDomainProcesses() []domain.ProcessConfig {
 	processes := make([]domain.ProcessConfig, 0, len(c.Processes))
 	for name, proc := range c.Processes {
 		domainProc := domain.ProcessConfig{
-			Name:    name,
-			Cmd:     proc.Cmd,
-			Env:     proc.Env,
-			EnvFile: proc.EnvFile,
-		}
-		if proc.Healthcheck != nil {
-			hc := &domain.HealthConfig{
-				Cmd:     proc.Healthcheck.Cmd,
-				Retries: proc.Healthcheck.Retries,
-			}
-			if proc.Healthcheck.Interval != "" {
-				if d, err := time.ParseDuration(proc.Healthcheck.Interval); err == nil {
-					hc.Interval = d
-				}
-			}
-			if proc.Healthcheck.Timeout != "" {
-				if d, err := time.ParseDuration(proc.Healthcheck.Timeout); err == nil {
-					hc.Timeout = d
-				}
-			}
-			if proc.Healthcheck.StartPeriod != "" {
-				if d, err := time.ParseDuration(proc.Healthcheck.StartPeriod); err == nil {
-					hc.StartPeriod = d
-				}
-			}
-			domainProc.Healthcheck = hc
+			Name:        name,
+			Cmd:         proc.Cmd,
+			Shell:       c.ShellFor(proc),
+			Direnv:      c.DirenvFor(proc),
+			Env:         proc.Env,
+			EnvFile:     proc.EnvFile,
+			CleanEnv:    !proc.InheritsEnv(),
+			Port:        proc.FixedPort(),
+			AutoPort:    proc.AutoPort(),
+			WaitFor:     proc.WaitFor,
+			WaitTimeout: proc.WaitTimeoutDuration(),
+			DependsOn:   c.DependenciesOf(proc),
+			Lazy:        proc.Lazy,
+			IdleTimeout: proc.IdleTimeoutDuration(),
+			LogBuffer:   proc.LogBuffer,
+			LogThrottle: proc.LogThrottle(),
+			LogFormat:   proc.LogFormat,
+			Stdin:       proc.Stdin,
+			Synthetic:   proc.SyntheticProcess(),
 		}
+		domainProc.Healthcheck = c.ProcessHealthcheck(name)
 		processes = append(processes, domainProc)
 	}
 	return processes
 }
 
+// ProcessHealthcheck returns the healthcheck of a process, or nil if it has
+// none. A proxy check becomes a request to the proxy's own listener with the
+// service's host name, so it fails when the route from the subdomain to the
+// process is broken, not only when the process is.
+func (c *Config) ProcessHealthcheck(name string) *domain.HealthConfig {
+	hc := c.Processes[name].Healthcheck
+	if hc == nil {
+		return nil
+	}
+	result := hc.ToDomain()
+	if hc.TypeOrDefault() != HealthcheckTypeProxy {
+		return result
+	}
+
+	result.Cmd = ""
+	service := hc.Service
+	if service == "" {
+		service = c.processService(name)
+	}
+	// Validation rules these out; leave the check disabled
+	if service == "" || c.Proxy == nil || !c.Proxy.Enabled {
+		return result
+	}
+	path := hc.Path
+	if path == "" {
+		path = "/"
+	}
+	result.Host = service + "." + c.Proxy.Domain
+	if c.Proxy.HTTPPort > 0 {
+		result.URL = fmt.Sprintf("http://127.0.0.1:%d%s", c.Proxy.HTTPPort, path)
+	} else {
+		result.URL = fmt.Sprintf("https://127.0.0.1:%d%s", c.Proxy.HTTPSPort, path)
+	}
+	return result
+}
+
+// processService returns the first service, by name, linked to the process,
+// or "" if none is. Wildcard services are skipped since they have no single
+// subdomain.
+func (c *Config) processService(process string) string {
+	var names []string
+	for name, svc := range c.Services {
+		if _, wildcard := WildcardPrefix(name); svc.Process == process && !wildcard {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+// ToDomain converts the YAML healthcheck config to a domain.HealthConfig.
+// Invalid durations are left as zero so defaults apply.
+func (hc *HealthcheckConfig) ToDomain() *domain.HealthConfig {
+	result := &domain.HealthConfig{
+		Cmd:     hc.Cmd,
+		Retries: hc.Retries,
+	}
+	switch hc.TypeOrDefault() {
+	case HealthcheckTypeHTTP:
+		result.Cmd = ""
+		if hc.HTTP != nil {
+			result.URL = hc.HTTP.URL
+			result.ExpectedStatus = hc.HTTP.ExpectedStatus
+		}
+	case HealthcheckTypeTCP:
+		result.Cmd = ""
+		if hc.TCP != nil {
+			result.TCP = hc.TCP.Address()
+		}
+	}
+	if hc.Interval != "" {
+		if d, err := time.ParseDuration(hc.Interval); err == nil {
+			result.Interval = d
+		}
+	}
+	if hc.Timeout != "" {
+		if d, err := time.ParseDuration(hc.Timeout); err == nil {
+			result.Timeout = d
+		}
+	}
+	if hc.StartPeriod != "" {
+		if d, err := time.ParseDuration(hc.StartPeriod); err == nil {
+			result.StartPeriod = d
+		}
+	}
+	return result
+}
+
 // ParseSize parses a human-readable size string (e.g., "1MB", "512KB", "1024")
 // into bytes. Supported suffixes: B, KB, MB, GB (case-insensitive).
 // If no suffix is provided, the value is treated as bytes.
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -79,6 +84,450 @@ func TestParse_InvalidYAML(t *testing.T) {
 	assert.Contains(t, err.Error(), "parsing yaml")
 }
 
+func TestParse_ProcessPort(t *testing.T) {
+	cfg, err := Parse([]byte(`
+processes:
+  api:
+    cmd: ./server
+    port: auto
+  web:
+    cmd: npm run dev
+    port: 3000
+  worker: python worker.py
+`))
+	require.NoError(t, err)
+
+	assert.True(t, cfg.Processes["api"].AutoPort())
+	assert.Equal(t, 0, cfg.Processes["api"].FixedPort())
+
+	assert.False(t, cfg.Processes["web"].AutoPort())
+	assert.Equal(t, 3000, cfg.Processes["web"].FixedPort())
+
+	assert.False(t, cfg.Processes["worker"].AutoPort())
+	assert.Equal(t, 0, cfg.Processes["worker"].FixedPort())
+}
+
+func TestParse_Redact(t *testing.T) {
+	cfg, err := Parse([]byte(`
+processes:
+  web: npm run dev
+redact:
+  patterns:
+    - 'acct_[0-9]+'
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acct_[0-9]+"}, cfg.RedactPatterns())
+
+	cfg, err = Parse([]byte(`
+processes:
+  web: npm run dev
+`))
+	require.NoError(t, err)
+	assert.Empty(t, cfg.RedactPatterns())
+}
+
+func TestParse_Supervisor(t *testing.T) {
+	cfg, err := Parse([]byte(`
+processes:
+  web: npm run dev
+supervisor:
+  start_concurrency: 4
+  stop_concurrency: 2
+`))
+	require.NoError(t, err)
+	require.NotNil(t, cfg.Supervisor)
+	assert.Equal(t, 4, cfg.Supervisor.StartConcurrency)
+	assert.Equal(t, 2, cfg.Supervisor.StopConcurrency)
+
+	cfg, err = Parse([]byte(`
+processes:
+  web: npm run dev
+`))
+	require.NoError(t, err)
+	assert.Nil(t, cfg.Supervisor)
+}
+
+func TestParse_Daemon(t *testing.T) {
+	cfg, err := Parse([]byte(`
+processes:
+  web: npm run dev
+proxy:
+  enabled: true
+  http_port: 6788
+  domain: local.myapp.dev
+daemon:
+  idle_timeout: 2h
+  idle_action: sleep
+  file_mode: 0640
+  dir_mode: "0750"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, cfg.Daemon.IdleTimeoutDuration())
+	assert.Equal(t, IdleActionSleep, cfg.Daemon.IdleActionOrDefault())
+	assert.Equal(t, os.FileMode(0640), cfg.Daemon.FileModeOrDefault())
+	assert.Equal(t, os.FileMode(0750), cfg.Daemon.DirModeOrDefault())
+
+	cfg, err = Parse([]byte(`
+processes:
+  web: npm run dev
+`))
+	require.NoError(t, err)
+	assert.Nil(t, cfg.Daemon)
+	assert.Equal(t, time.Duration(0), cfg.Daemon.IdleTimeoutDuration())
+	assert.Equal(t, IdleActionStop, cfg.Daemon.IdleActionOrDefault())
+	assert.Equal(t, os.FileMode(0600), cfg.Daemon.FileModeOrDefault())
+	assert.Equal(t, os.FileMode(0700), cfg.Daemon.DirModeOrDefault())
+}
+
+func TestParse_Storage(t *testing.T) {
+	cfg, err := Parse([]byte(`
+processes:
+  web: npm run dev
+storage:
+  backend: sqlite
+  path: history/prox.db
+  max_logs: 50000
+`))
+	require.NoError(t, err)
+	assert.Equal(t, StorageBackendSQLite, cfg.Storage.BackendOrDefault())
+	assert.Equal(t, "history/prox.db", cfg.Storage.Path)
+	assert.Equal(t, 50000, cfg.Storage.MaxLogsOrDefault())
+	assert.Equal(t, constants.DefaultStorageMaxRequests, cfg.Storage.MaxRequestsOrDefault())
+
+	cfg, err = Parse([]byte(`
+processes:
+  web: npm run dev
+`))
+	require.NoError(t, err)
+	assert.Nil(t, cfg.Storage)
+	assert.Equal(t, StorageBackendMemory, cfg.Storage.BackendOrDefault())
+}
+
+func TestParse_Streams(t *testing.T) {
+	cfg, err := Parse([]byte(`
+processes:
+  web: npm run dev
+streams:
+  enabled: true
+`))
+	require.NoError(t, err)
+	assert.True(t, cfg.Streams.IsEnabled())
+
+	cfg, err = Parse([]byte(`
+processes:
+  web: npm run dev
+`))
+	require.NoError(t, err)
+	assert.False(t, cfg.Streams.IsEnabled())
+}
+
+func TestParse_Notifications(t *testing.T) {
+	cfg, err := Parse([]byte(`
+processes:
+  web: npm run dev
+notifications:
+  desktop: true
+  webhook: https://hooks.example.com/prox
+`))
+	require.NoError(t, err)
+	assert.True(t, cfg.Notifications.Desktop)
+	assert.Equal(t, "https://hooks.example.com/prox", cfg.Notifications.Webhook)
+	assert.Equal(t, []string{NotifyEventCrashed, NotifyEventUnhealthy}, cfg.Notifications.EventsOrDefault())
+
+	cfg, err = Parse([]byte(`
+processes:
+  web: npm run dev
+notifications:
+  command: say "$PROX_MESSAGE"
+  events: [process_crashed]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{NotifyEventCrashed}, cfg.Notifications.EventsOrDefault())
+}
+
+func TestParse_EnvPrompt(t *testing.T) {
+	cfg, err := Parse([]byte(`
+processes:
+  api:
+    cmd: go run ./cmd/api
+    env_prompt: [STRIPE_KEY, GITHUB_TOKEN]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"STRIPE_KEY", "GITHUB_TOKEN"}, cfg.Processes["api"].EnvPrompt)
+}
+
+func TestParse_Time(t *testing.T) {
+	cfg, err := Parse([]byte(`
+time: relative
+processes:
+  web: npm run dev
+`))
+	require.NoError(t, err)
+	assert.Equal(t, humanize.TimeRelative, cfg.TimeMode())
+
+	var unset *Config
+	assert.Equal(t, humanize.TimeLocal, unset.TimeMode())
+
+	_, err = Parse([]byte(`
+time: gmt
+processes:
+  web: npm run dev
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `time: must be local, utc, or relative, got "gmt"`)
+}
+
+func TestParse_Env(t *testing.T) {
+	cfg, err := Parse([]byte(`
+env:
+  TZ: UTC
+processes:
+  web: npm run dev
+  api:
+    cmd: go run ./cmd/api
+    inherit_env: false
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"TZ": "UTC"}, cfg.Env)
+	assert.True(t, cfg.Processes["web"].InheritsEnv())
+	assert.False(t, cfg.Processes["api"].InheritsEnv())
+
+	for _, proc := range cfg.ToDomainProcesses() {
+		assert.Equal(t, proc.Name == "api", proc.CleanEnv, proc.Name)
+	}
+}
+
+func TestParse_Shell(t *testing.T) {
+	cfg, err := Parse([]byte(`
+shell: /bin/zsh -l
+direnv: true
+processes:
+  web: npm run dev
+  api:
+    cmd: go run ./cmd/api
+    shell: bash
+    direnv: false
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/bin/zsh", "-l"}, cfg.ShellFor(cfg.Processes["web"]))
+	assert.True(t, cfg.DirenvFor(cfg.Processes["web"]))
+	assert.Equal(t, []string{"bash"}, cfg.ShellFor(cfg.Processes["api"]))
+	assert.False(t, cfg.DirenvFor(cfg.Processes["api"]))
+
+	procs := cfg.ToDomainProcesses()
+	require.Len(t, procs, 2)
+	for _, proc := range procs {
+		if proc.Name == "web" {
+			assert.Equal(t, []string{"/bin/zsh", "-l"}, proc.Shell)
+			assert.True(t, proc.Direnv)
+		}
+	}
+
+	// Without a shell, commands run through sh
+	cfg, err = Parse([]byte(`
+processes:
+  web: npm run dev
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{constants.DefaultShell}, cfg.ShellFor(cfg.Processes["web"]))
+	assert.False(t, cfg.DirenvFor(cfg.Processes["web"]))
+
+	_, err = Parse([]byte(`
+processes:
+  web:
+    cmd: npm run dev
+    shell: "'/Applications/My Shell' -l"
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "processes.web.shell: quotes aren't supported")
+}
+
+func TestParse_Profiles(t *testing.T) {
+	cfg, err := Parse([]byte(`
+processes:
+  web:
+    cmd: npm run dev
+    profiles: [frontend]
+  api:
+    cmd: go run ./cmd/api
+    profiles: [backend, frontend]
+  worker:
+    cmd: go run ./cmd/worker
+    profiles: [backend]
+  docs: mkdocs serve
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backend", "frontend"}, cfg.Profiles())
+
+	names, err := cfg.ProfileProcesses([]string{"backend"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api", "worker"}, names)
+
+	names, err = cfg.ProfileProcesses([]string{"backend", "frontend"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api", "web", "worker"}, names)
+
+	_, err = cfg.ProfileProcesses([]string{"ops"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown profile "ops" (profiles: backend, frontend)`)
+
+	_, err = Parse([]byte(`
+processes:
+  web:
+    cmd: npm run dev
+    profiles: ["front end"]
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `processes.web.profiles: invalid profile name "front end"`)
+}
+
+func TestParse_Synthetic(t *testing.T) {
+	cfg, err := Parse([]byte(`
+processes:
+  noise:
+    type: synthetic
+  load:
+    type: synthetic
+    synthetic:
+      rate: 5000
+      exit_after: 30s
+      crash_probability: 0.05
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, &domain.SyntheticConfig{Rate: constants.DefaultSyntheticRate}, cfg.Processes["noise"].SyntheticProcess())
+	assert.Equal(t, &domain.SyntheticConfig{Rate: 5000, ExitAfter: 30 * time.Second, CrashProbability: 0.05},
+		cfg.Processes["load"].SyntheticProcess())
+	assert.Nil(t, ProcessConfig{Cmd: "npm run dev"}.SyntheticProcess())
+}
+
+func TestParse_Logs(t *testing.T) {
+	cfg, err := Parse([]byte(`
+processes:
+  web: npm run dev
+  api:
+    cmd: go run ./cmd/api
+    log_buffer: 2000
+    log_format: logfmt
+    scale: 3
+    watch: ["**/*.go", config/]
+    watch_ignore: ["*_test.go"]
+    watch_debounce: 1s
+logs:
+  buffer_size: 5000
+  process_buffer_size: 300
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 5000, cfg.Logs.BufferSizeOrDefault())
+	assert.Equal(t, 300, cfg.Logs.ProcessBufferSizeOrDefault())
+	assert.Equal(t, 2000, cfg.Processes["api"].LogBuffer)
+	assert.Equal(t, "logfmt", cfg.Processes["api"].LogFormat)
+	assert.Equal(t, 3, cfg.Processes["api"].Scale)
+	assert.Equal(t, []string{"**/*.go", "config/"}, cfg.Processes["api"].Watch)
+	assert.Equal(t, []string{"*_test.go"}, cfg.Processes["api"].WatchIgnore)
+	assert.Equal(t, time.Second, cfg.Processes["api"].WatchDebounceDuration())
+	assert.Equal(t, constants.DefaultWatchDebounce, cfg.Processes["web"].WatchDebounceDuration())
+
+	cfg, err = Parse([]byte(`
+processes:
+  web: npm run dev
+`))
+	require.NoError(t, err)
+	assert.Equal(t, constants.DefaultLogBufferSize, cfg.Logs.BufferSizeOrDefault())
+	assert.Equal(t, constants.DefaultLogProcessBufferSize, cfg.Logs.ProcessBufferSizeOrDefault())
+}
+
+func TestParse_LazyProcess(t *testing.T) {
+	cfg, err := Parse([]byte(`
+processes:
+  web:
+    cmd: npm run dev
+    lazy: true
+    idle_timeout: 15m
+proxy:
+  enabled: true
+  http_port: 6788
+  domain: local.myapp.dev
+services:
+  app:
+    port: 3000
+    process: web
+`))
+	require.NoError(t, err)
+	assert.True(t, cfg.Processes["web"].Lazy)
+	assert.Equal(t, 15*time.Minute, cfg.Processes["web"].IdleTimeoutDuration())
+
+	procs := cfg.ToDomainProcesses()
+	require.Len(t, procs, 1)
+	assert.True(t, procs[0].Lazy)
+	assert.Equal(t, 15*time.Minute, procs[0].IdleTimeout)
+}
+
+func TestParse_Tasks(t *testing.T) {
+	cfg, err := Parse([]byte(`
+processes:
+  web: npm run dev
+tasks:
+  migrate: bin/rails db:migrate
+  seed:
+    cmd: bin/rails db:seed
+    description: Load sample data
+    env:
+      SEED_SIZE: small
+`))
+	require.NoError(t, err)
+	assert.Equal(t, TaskConfig{Cmd: "bin/rails db:migrate"}, cfg.Tasks["migrate"])
+	assert.Equal(t, "Load sample data", cfg.Tasks["seed"].Description)
+	assert.Equal(t, "small", cfg.Tasks["seed"].Env["SEED_SIZE"])
+	assert.Len(t, cfg.ToDomainProcesses(), 1, "tasks aren't processes")
+}
+
+func TestConfig_ProcessHealthcheck(t *testing.T) {
+	cfg := &Config{
+		Processes: map[string]ProcessConfig{
+			"web":    {Cmd: "npm run dev", Healthcheck: &HealthcheckConfig{Type: "proxy", Path: "/health", Retries: 2}},
+			"api":    {Cmd: "./api", Healthcheck: &HealthcheckConfig{Type: "proxy", Service: "api"}},
+			"worker": {Cmd: "./worker", Healthcheck: &HealthcheckConfig{Cmd: "true"}},
+			"plain":  {Cmd: "./plain"},
+		},
+		Proxy: &ProxyConfig{Enabled: true, HTTPSPort: 6789, Domain: "local.dev"},
+		Services: map[string]ServiceConfig{
+			"www":  {Port: 3000, Process: "web"},
+			"app":  {Port: 3000, Process: "web"},
+			"api":  {Port: 8000},
+			"pr-*": {PortRange: "4000-4099", Process: "web"},
+		},
+	}
+
+	web := cfg.ProcessHealthcheck("web")
+	require.NotNil(t, web)
+	assert.Equal(t, "https://127.0.0.1:6789/health", web.URL)
+	assert.Equal(t, "app.local.dev", web.Host)
+	assert.Empty(t, web.Cmd)
+	assert.Equal(t, 2, web.Retries)
+
+	cfg.Proxy.HTTPPort = 6788
+	api := cfg.ProcessHealthcheck("api")
+	assert.Equal(t, "http://127.0.0.1:6788/", api.URL)
+	assert.Equal(t, "api.local.dev", api.Host)
+
+	assert.Equal(t, "true", cfg.ProcessHealthcheck("worker").Cmd)
+	assert.Empty(t, cfg.ProcessHealthcheck("worker").URL)
+	assert.Nil(t, cfg.ProcessHealthcheck("plain"))
+}
+
+func TestHealthcheckConfig_ToDomainProbes(t *testing.T) {
+	http := (&HealthcheckConfig{HTTP: &HealthcheckHTTPConfig{URL: "http://localhost:$PORT/health", ExpectedStatus: 204}}).ToDomain()
+	assert.Equal(t, "http://localhost:$PORT/health", http.URL)
+	assert.Equal(t, 204, http.ExpectedStatus)
+	assert.Empty(t, http.Host)
+	assert.True(t, http.IsDirect())
+
+	tcp := (&HealthcheckConfig{Type: "tcp", TCP: &HealthcheckTCPConfig{Port: 5432}}).ToDomain()
+	assert.Equal(t, "127.0.0.1:5432", tcp.TCP)
+	assert.Equal(t, "[::1]:$PORT", (&HealthcheckTCPConfig{Host: "::1"}).Address())
+}
+
 func TestConfig_ToDomainProcesses(t *testing.T) {
 	cfg := &Config{
 		Processes: map[string]ProcessConfig{
@@ -353,6 +802,91 @@ services:
 		assert.Nil(t, cfg.Certs)
 	})
 
+	t.Run("service with latency budget", func(t *testing.T) {
+		yaml := `
+processes:
+  web: npm run dev
+
+proxy:
+  http_port: 6788
+  domain: local.test.dev
+
+services:
+  app: 3000
+  api:
+    port: 8000
+    slo:
+      p95: 300ms
+`
+		cfg, err := Parse([]byte(yaml))
+		require.NoError(t, err)
+
+		assert.Nil(t, cfg.Services["app"].SLO)
+		assert.Equal(t, time.Duration(0), cfg.Services["app"].P95Budget())
+
+		require.NotNil(t, cfg.Services["api"].SLO)
+		assert.Equal(t, "300ms", cfg.Services["api"].SLO.P95)
+		assert.Equal(t, 300*time.Millisecond, cfg.Services["api"].P95Budget())
+	})
+
+	t.Run("parses proxy api", func(t *testing.T) {
+		yaml := `
+processes:
+  web: npm run dev
+
+proxy:
+  http_port: 6788
+  domain: local.test.dev
+  api:
+    enabled: true
+`
+		cfg, err := Parse([]byte(yaml))
+		require.NoError(t, err)
+
+		assert.True(t, cfg.Proxy.APIEnabled())
+		assert.Equal(t, "prox", cfg.Proxy.APISubdomain())
+
+		cfg.Proxy.API.Subdomain = "control"
+		assert.Equal(t, "control", cfg.Proxy.APISubdomain())
+
+		cfg.Proxy.API = nil
+		assert.False(t, cfg.Proxy.APIEnabled())
+	})
+
+	t.Run("parses service schema", func(t *testing.T) {
+		yaml := `
+processes:
+  web: npm run dev
+
+proxy:
+  http_port: 6788
+  domain: local.test.dev
+
+services:
+  app: 3000
+  api:
+    port: 8000
+    schema:
+      file: schemas/api.json
+      sample: 0.25
+  web:
+    port: 8080
+    schema:
+      file: schemas/web.json
+`
+		cfg, err := Parse([]byte(yaml))
+		require.NoError(t, err)
+
+		assert.Nil(t, cfg.Services["app"].Schema)
+
+		require.NotNil(t, cfg.Services["api"].Schema)
+		assert.Equal(t, "schemas/api.json", cfg.Services["api"].Schema.File)
+		assert.Equal(t, 0.25, cfg.Services["api"].Schema.SampleRate())
+
+		require.NotNil(t, cfg.Services["web"].Schema)
+		assert.Equal(t, 1.0, cfg.Services["web"].Schema.SampleRate())
+	})
+
 	t.Run("loads HTTP only config from file", func(t *testing.T) {
 		cfg, err := Load(filepath.Join("..", "..", "testdata", "configs", "http_only.yaml"))
 		require.NoError(t, err)
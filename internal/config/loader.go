@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
 
 	"github.com/joho/godotenv"
 )
@@ -42,16 +44,17 @@ func MergeEnv(envMaps ...map[string]string) map[string]string {
 // LoadProcessEnv loads and merges environment variables for a process
 // Priority (lowest to highest):
 // 1. Global env_file
-// 2. Process env_file
-// 3. Process env variables
-func LoadProcessEnv(globalEnvFile, processEnvFile string, processEnv map[string]string, configDir string) (map[string]string, error) {
-	var globalEnv, procFileEnv map[string]string
+// 2. Global env variables
+// 3. Process env_file
+// 4. Process env variables
+func LoadProcessEnv(globalEnvFile string, globalEnv map[string]string, processEnvFile string, processEnv map[string]string, configDir string) (map[string]string, error) {
+	var globalFileEnv, procFileEnv map[string]string
 	var err error
 
 	// Load global env file
 	if globalEnvFile != "" {
 		envPath := resolvePath(globalEnvFile, configDir)
-		globalEnv, err = LoadEnvFile(envPath)
+		globalFileEnv, err = LoadEnvFile(envPath)
 		if err != nil {
 			return nil, fmt.Errorf("loading global env file: %w", err)
 		}
@@ -67,7 +70,52 @@ func LoadProcessEnv(globalEnvFile, processEnvFile string, processEnv map[string]
 	}
 
 	// Merge in order of priority
-	return MergeEnv(globalEnv, procFileEnv, processEnv), nil
+	return MergeEnv(globalFileEnv, globalEnv, procFileEnv, processEnv), nil
+}
+
+// MissingPromptedEnv returns the env_prompt variables of the named processes
+// (all processes if names is empty) that are set neither in prox's own
+// environment, for processes that inherit it, nor by the config's env or
+// env_file, sorted. Env files that
+// can't be read are ignored here; starting the process reports them.
+func MissingPromptedEnv(cfg *Config, configDir string, names []string) []string {
+	missing := make(map[string]bool)
+	for name, proc := range cfg.Processes {
+		if len(proc.EnvPrompt) == 0 || (len(names) > 0 && !slices.Contains(names, name)) {
+			continue
+		}
+		env, err := LoadProcessEnv(cfg.EnvFile, cfg.Env, proc.EnvFile, proc.Env, configDir)
+		if err != nil {
+			env = MergeEnv(cfg.Env, proc.Env)
+		}
+		for _, variable := range proc.EnvPrompt {
+			if _, ok := env[variable]; ok {
+				continue
+			}
+			if _, ok := os.LookupEnv(variable); ok && proc.InheritsEnv() {
+				continue
+			}
+			missing[variable] = true
+		}
+	}
+
+	result := make([]string, 0, len(missing))
+	for variable := range missing {
+		result = append(result, variable)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// AddPromptedEnv adds the values entered for a process's env_prompt
+// variables to its environment, where it doesn't set them already
+func AddPromptedEnv(env map[string]string, prompts []string, values map[string]string) {
+	for _, variable := range prompts {
+		value, ok := values[variable]
+		if _, set := env[variable]; ok && !set {
+			env[variable] = value
+		}
+	}
 }
 
 // resolvePath resolves a potentially relative path against a base directory
@@ -69,7 +69,7 @@ func TestLoadProcessEnv(t *testing.T) {
 	require.NoError(t, err)
 
 	t.Run("merges all sources", func(t *testing.T) {
-		env, err := LoadProcessEnv(".env", ".env.proc", map[string]string{
+		env, err := LoadProcessEnv(".env", nil, ".env.proc", map[string]string{
 			"INLINE": "3",
 			"SHARED": "inline",
 		}, dir)
@@ -81,12 +81,60 @@ func TestLoadProcessEnv(t *testing.T) {
 		assert.Equal(t, "inline", env["SHARED"]) // inline wins
 	})
 
+	t.Run("global env sits between the env files", func(t *testing.T) {
+		env, err := LoadProcessEnv(".env", map[string]string{
+			"GLOBAL":     "env",
+			"PROC":       "env",
+			"GLOBAL_ENV": "4",
+		}, ".env.proc", nil, dir)
+		require.NoError(t, err)
+
+		assert.Equal(t, "env", env["GLOBAL"]) // overrides the global env_file
+		assert.Equal(t, "2", env["PROC"])     // overridden by the process env_file
+		assert.Equal(t, "4", env["GLOBAL_ENV"])
+	})
+
 	t.Run("handles missing global env file", func(t *testing.T) {
-		_, err := LoadProcessEnv("nonexistent.env", "", nil, dir)
+		_, err := LoadProcessEnv("nonexistent.env", nil, "", nil, dir)
 		require.Error(t, err)
 	})
 }
 
+func TestMissingPromptedEnv(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("FROM_FILE=1"), 0644))
+	t.Setenv("PROX_TEST_EXPORTED", "1")
+
+	cfg := &Config{
+		EnvFile: ".env",
+		Processes: map[string]ProcessConfig{
+			"web": {
+				Cmd:       "npm run dev",
+				Env:       map[string]string{"INLINE": "1"},
+				EnvPrompt: []string{"STRIPE_KEY", "INLINE", "FROM_FILE", "PROX_TEST_EXPORTED"},
+			},
+			"worker": {Cmd: "npm run worker", EnvPrompt: []string{"STRIPE_KEY", "INLINE", "QUEUE_TOKEN"}},
+			"api":    {Cmd: "go run ."},
+		},
+	}
+
+	// Variables set by env, env_file, or prox's environment aren't asked for
+	assert.Equal(t, []string{"INLINE", "QUEUE_TOKEN", "STRIPE_KEY"}, MissingPromptedEnv(cfg, dir, nil))
+	assert.Equal(t, []string{"STRIPE_KEY"}, MissingPromptedEnv(cfg, dir, []string{"web"}))
+	assert.Empty(t, MissingPromptedEnv(cfg, dir, []string{"api"}))
+
+	// The config's env counts; prox's environment doesn't for a process that
+	// doesn't inherit it
+	inherit := false
+	cfg.Env = map[string]string{"STRIPE_KEY": "1"}
+	cfg.Processes["worker"] = ProcessConfig{
+		Cmd:        "npm run worker",
+		InheritEnv: &inherit,
+		EnvPrompt:  []string{"STRIPE_KEY", "PROX_TEST_EXPORTED"},
+	}
+	assert.Equal(t, []string{"PROX_TEST_EXPORTED"}, MissingPromptedEnv(cfg, dir, []string{"worker"}))
+}
+
 func TestFindConfigFile(t *testing.T) {
 	// This test depends on the current directory state
 	// In a clean directory, it should fail
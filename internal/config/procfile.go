@@ -0,0 +1,84 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ProcfileName is the name of the file foreman and overmind read processes
+// from
+const ProcfileName = "Procfile"
+
+// procfileEnvFile is the env file foreman loads next to a Procfile
+const procfileEnvFile = ".env"
+
+// procfileLineRegex matches a Procfile entry, e.g. "web: bundle exec puma"
+var procfileLineRegex = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.+)$`)
+
+// procfilePortRegex matches commands that use the $PORT foreman assigns
+var procfilePortRegex = regexp.MustCompile(`\$\{?PORT\b`)
+
+// IsProcfile reports whether path names a Procfile, e.g. "Procfile" or
+// "Procfile.dev", rather than a prox.yaml
+func IsProcfile(path string) bool {
+	base := filepath.Base(path)
+	return base == ProcfileName || strings.HasPrefix(base, ProcfileName+".")
+}
+
+// DetectConfigFile returns path if it exists. Otherwise, if a Procfile
+// exists in the same directory, it returns the Procfile's path, so projects
+// migrating from foreman or overmind run without a prox.yaml.
+func DetectConfigFile(path string) string {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return path
+	}
+	procfile := filepath.Join(filepath.Dir(path), ProcfileName)
+	if _, err := os.Stat(procfile); err == nil {
+		return procfile
+	}
+	return path
+}
+
+// parseProcfile parses a Procfile into a configuration with the defaults
+// applied, without validating it. As with foreman, commands that use $PORT
+// get a port (port: auto) and a .env file next to the Procfile is loaded.
+func parseProcfile(data []byte, dir string) (*Config, error) {
+	config, err := parse(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := procfileLineRegex.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("procfile line %d: expected \"name: command\", got %q", lineNum, line)
+		}
+		name, cmd := m[1], strings.TrimSpace(m[2])
+		if _, ok := config.Processes[name]; ok {
+			return nil, fmt.Errorf("procfile line %d: process %q is defined twice", lineNum, name)
+		}
+		proc := ProcessConfig{Cmd: cmd}
+		if procfilePortRegex.MatchString(cmd) {
+			proc.Port = PortAuto
+		}
+		config.Processes[name] = proc
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading procfile: %w", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, procfileEnvFile)); err == nil {
+		config.EnvFile = procfileEnvFile
+	}
+	return config, nil
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Procfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Procfile.dev")
+	require.NoError(t, os.WriteFile(path, []byte(`# Development processes
+web: bundle exec puma -p $PORT
+
+worker:bundle exec sidekiq
+assets: npm run watch -- --port=${PORT}
+`), 0600))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ProcessConfig{
+		"web":    {Cmd: "bundle exec puma -p $PORT", Port: PortAuto},
+		"worker": {Cmd: "bundle exec sidekiq"},
+		"assets": {Cmd: "npm run watch -- --port=${PORT}", Port: PortAuto},
+	}, cfg.Processes)
+	assert.Empty(t, cfg.EnvFile)
+	assert.NotZero(t, cfg.API.Port, "defaults should be applied")
+
+	// foreman loads a .env next to the Procfile
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("FOO=bar\n"), 0600))
+	cfg, err = Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, ".env", cfg.EnvFile)
+}
+
+func TestLoad_ProcfileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{"invalid line", "web: rails s\nnot a process\n", `procfile line 2: expected "name: command"`},
+		{"duplicate", "web: rails s\nweb: puma\n", `procfile line 2: process "web" is defined twice`},
+		{"empty", "# nothing yet\n", "at least one process must be defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ProcfileName)
+			require.NoError(t, os.WriteFile(path, []byte(tt.contents), 0600))
+			_, err := Load(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestDetectConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "prox.yaml")
+	assert.Equal(t, yamlPath, DetectConfigFile(yamlPath), "nothing to fall back to")
+
+	procfile := filepath.Join(dir, ProcfileName)
+	require.NoError(t, os.WriteFile(procfile, []byte("web: rails s\n"), 0600))
+	assert.Equal(t, procfile, DetectConfigFile(yamlPath))
+
+	require.NoError(t, os.WriteFile(yamlPath, []byte("processes:\n  web: rails s\n"), 0600))
+	assert.Equal(t, yamlPath, DetectConfigFile(yamlPath), "prox.yaml takes precedence")
+}
+
+func TestIsProcfile(t *testing.T) {
+	assert.True(t, IsProcfile("Procfile"))
+	assert.True(t, IsProcfile("/app/Procfile.dev"))
+	assert.False(t, IsProcfile("prox.yaml"))
+	assert.False(t, IsProcfile("MyProcfile"))
+}
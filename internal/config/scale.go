@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charliek/prox/internal/domain"
+)
+
+// InstanceEnv is the variable holding a scaled process instance's number,
+// from 1
+const InstanceEnv = "INSTANCE"
+
+// InstanceName returns the name of the i'th instance, from 1, of a scaled
+// process, e.g. "worker-2"
+func InstanceName(name string, i int) string {
+	return name + "-" + strconv.Itoa(i)
+}
+
+// scaleOf returns how many instances of a process run, from overrides or
+// the process's scale field
+func scaleOf(name string, proc ProcessConfig, overrides map[string]int) int {
+	if n, ok := overrides[name]; ok {
+		return n
+	}
+	return proc.Scale
+}
+
+// Scaled returns a copy of c in which each process scaled above 1, by its
+// scale field or by overrides, is replaced by its instances, name-1 through
+// name-N. Instance i has INSTANCE=i in its env and, with a fixed port, port
+// + i - 1. c itself is returned when no process is scaled.
+func (c *Config) Scaled(overrides map[string]int) *Config {
+	instances := make(map[string][]string)
+	for name, proc := range c.Processes {
+		if scaleOf(name, proc, overrides) > 1 {
+			instances[name] = nil
+		}
+	}
+	if len(instances) == 0 {
+		return c
+	}
+
+	scaled := *c
+	scaled.Processes = make(map[string]ProcessConfig, len(c.Processes))
+	for name, proc := range c.Processes {
+		if _, ok := instances[name]; !ok {
+			scaled.Processes[name] = proc
+			continue
+		}
+		n := scaleOf(name, proc, overrides)
+		for i := 1; i <= n; i++ {
+			inst := proc
+			inst.Scale = 0
+			inst.Env = maps.Clone(proc.Env)
+			if inst.Env == nil {
+				inst.Env = make(map[string]string)
+			}
+			inst.Env[InstanceEnv] = strconv.Itoa(i)
+			if port := proc.FixedPort(); port > 0 {
+				inst.Port = strconv.Itoa(port + i - 1)
+			}
+			instanceName := InstanceName(name, i)
+			scaled.Processes[instanceName] = inst
+			instances[name] = append(instances[name], instanceName)
+		}
+	}
+	scaled.instances = instances
+	return &scaled
+}
+
+// Instances returns the names of the processes run for a process in a
+// config returned by Scaled: its instances if it was scaled, or the name
+// itself
+func (c *Config) Instances(name string) []string {
+	if names, ok := c.instances[name]; ok {
+		return names
+	}
+	return []string{name}
+}
+
+// ValidateScale checks scale overrides, such as those of prox up --scale,
+// against the config
+func ValidateScale(c *Config, overrides map[string]int) error {
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []string
+	for _, name := range names {
+		proc, ok := c.Processes[name]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("scale: unknown process %q", name))
+			continue
+		}
+		if overrides[name] < 1 {
+			errs = append(errs, fmt.Sprintf("scale: %s must run at least 1 instance, got %d", name, overrides[name]))
+			continue
+		}
+		errs = append(errs, validateScale(c, name, proc, overrides[name])...)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %s", domain.ErrInvalidConfig, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// validateScale checks that a process can run n instances
+func validateScale(c *Config, name string, proc ProcessConfig, n int) []string {
+	prefix := fmt.Sprintf("processes.%s.scale", name)
+	if n < 0 {
+		return []string{fmt.Sprintf("%s: must be at least 1, got %d", prefix, n)}
+	}
+	if n <= 1 {
+		return nil
+	}
+
+	var errs []string
+	if proc.Lazy {
+		errs = append(errs, fmt.Sprintf("%s: cannot be combined with lazy: true", prefix))
+	}
+	var services []string
+	for svc, cfg := range c.Services {
+		if cfg.Process == name {
+			services = append(services, svc)
+		}
+	}
+	sort.Strings(services)
+	for _, svc := range services {
+		errs = append(errs, fmt.Sprintf("%s: services.%s.process can't name a scaled process", prefix, svc))
+	}
+	if port := proc.FixedPort(); port > 0 && port+n-1 > 65535 {
+		errs = append(errs, fmt.Sprintf("%s: instance ports %d through %d exceed 65535", prefix, port, port+n-1))
+	}
+	for i := 1; i <= n; i++ {
+		instance := InstanceName(name, i)
+		if _, ok := c.Processes[instance]; ok {
+			errs = append(errs, fmt.Sprintf("%s: instance %s has the name of another process", prefix, instance))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Scaled(t *testing.T) {
+	cfg := &Config{
+		Processes: map[string]ProcessConfig{
+			"web":    {Cmd: "npm run dev", Port: "3000"},
+			"worker": {Cmd: "./worker", Port: "4000", Env: map[string]string{"QUEUE": "jobs"}, Scale: 3},
+		},
+	}
+
+	scaled := cfg.Scaled(nil)
+	require.Len(t, scaled.Processes, 4)
+	assert.Equal(t, cfg.Processes["web"], scaled.Processes["web"])
+	assert.Equal(t, []string{"web"}, scaled.Instances("web"))
+	assert.Equal(t, []string{"worker-1", "worker-2", "worker-3"}, scaled.Instances("worker"))
+
+	for i, name := range scaled.Instances("worker") {
+		inst := scaled.Processes[name]
+		assert.Equal(t, "./worker", inst.Cmd)
+		assert.Equal(t, 0, inst.Scale)
+		assert.Equal(t, 4000+i, inst.FixedPort())
+		assert.Equal(t, "jobs", inst.Env["QUEUE"])
+		assert.Equal(t, strconv.Itoa(i+1), inst.Env[InstanceEnv])
+	}
+
+	// The original config is untouched
+	assert.Len(t, cfg.Processes, 2)
+	assert.NotContains(t, cfg.Processes["worker"].Env, InstanceEnv)
+}
+
+func TestConfig_Scaled_Overrides(t *testing.T) {
+	cfg := &Config{
+		Processes: map[string]ProcessConfig{
+			"web":    {Cmd: "npm run dev", Port: "auto"},
+			"worker": {Cmd: "./worker", Scale: 3},
+		},
+	}
+
+	scaled := cfg.Scaled(map[string]int{"web": 2, "worker": 1})
+	assert.Equal(t, []string{"web-1", "web-2"}, scaled.Instances("web"))
+	assert.Equal(t, []string{"worker"}, scaled.Instances("worker"))
+	assert.True(t, scaled.Processes["web-2"].AutoPort())
+	assert.Equal(t, "2", scaled.Processes["web-2"].Env[InstanceEnv])
+
+	// Nothing scaled returns the config itself
+	assert.Same(t, cfg, cfg.Scaled(map[string]int{"worker": 1}))
+}
+
+func TestValidateScale(t *testing.T) {
+	tests := []struct {
+		name    string
+		proc    ProcessConfig
+		others  map[string]ProcessConfig
+		service bool // Link the "app" service to the process
+		wantErr string
+	}{
+		{name: "scaled", proc: ProcessConfig{Cmd: "./worker", Port: "4000", Scale: 3}},
+		{name: "single instance", proc: ProcessConfig{Cmd: "./worker", Scale: 1}},
+		{name: "negative", proc: ProcessConfig{Cmd: "./worker", Scale: -1}, wantErr: "processes.worker.scale: must be at least 1"},
+		{name: "lazy", proc: ProcessConfig{Cmd: "./worker", Lazy: true, Scale: 2}, service: true, wantErr: "processes.worker.scale: cannot be combined with lazy: true"},
+		{name: "service", proc: ProcessConfig{Cmd: "./worker", Scale: 2}, service: true, wantErr: "processes.worker.scale: services.app.process can't name a scaled process"},
+		{name: "ports overflow", proc: ProcessConfig{Cmd: "./worker", Port: "65535", Scale: 2}, wantErr: "instance ports 65535 through 65536 exceed 65535"},
+		{
+			name:    "instance name taken",
+			proc:    ProcessConfig{Cmd: "./worker", Scale: 2},
+			others:  map[string]ProcessConfig{"worker-2": {Cmd: "./other"}},
+			wantErr: "processes.worker.scale: instance worker-2 has the name of another process",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				API:       APIConfig{Port: 5555, Host: "127.0.0.1"},
+				Processes: map[string]ProcessConfig{"worker": tt.proc},
+			}
+			for name, proc := range tt.others {
+				cfg.Processes[name] = proc
+			}
+			if tt.service {
+				cfg.Proxy = &ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.dev"}
+				cfg.Services = map[string]ServiceConfig{"app": {Port: 3000, Host: "localhost", Process: "worker"}}
+			}
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateScale_Overrides(t *testing.T) {
+	cfg := &Config{
+		Processes: map[string]ProcessConfig{
+			"web":    {Cmd: "npm run dev", Lazy: true},
+			"worker": {Cmd: "./worker"},
+		},
+	}
+
+	assert.NoError(t, ValidateScale(cfg, map[string]int{"worker": 4}))
+	assert.NoError(t, ValidateScale(cfg, nil))
+
+	err := ValidateScale(cfg, map[string]int{"queue": 2})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `scale: unknown process "queue"`)
+
+	err = ValidateScale(cfg, map[string]int{"worker": 0})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "scale: worker must run at least 1 instance, got 0")
+
+	err = ValidateScale(cfg, map[string]int{"web": 2})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "processes.web.scale: cannot be combined with lazy: true")
+}
@@ -3,10 +3,18 @@ package config
 import (
 	"fmt"
 	"net"
+	"net/url"
+	"os"
+	"path"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/charliek/prox/internal/theme"
+	"github.com/charliek/prox/internal/watch"
 )
 
 // domainRegex validates domain format (basic DNS name validation)
@@ -24,12 +32,53 @@ func (e ValidationError) Error() string {
 
 // Validate checks the configuration for errors
 func Validate(config *Config) error {
+	if errs := validationErrors(config); len(errs) > 0 {
+		return fmt.Errorf("%w: %s", domain.ErrInvalidConfig, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// validationErrors returns every configuration error, each as
+// "field: message"
+func validationErrors(config *Config) []string {
 	var errs []string
 
 	// Validate API config
 	if config.API.Port < 0 || config.API.Port > 65535 {
 		errs = append(errs, fmt.Sprintf("api.port: must be between 0 and 65535, got %d", config.API.Port))
 	}
+	if tls := config.API.TLS; tls != nil {
+		if (tls.Cert == "") != (tls.Key == "") {
+			errs = append(errs, "api.tls: cert and key must be set together")
+		}
+		if tls.Cert != "" && len(tls.Hosts) > 0 {
+			errs = append(errs, "api.tls.hosts: only valid for a generated certificate, without cert and key")
+		}
+	}
+
+	if err := validateShell(config.Shell); err != nil {
+		errs = append(errs, fmt.Sprintf("shell: %v", err))
+	}
+	if _, err := humanize.ParseTimeMode(config.Time); err != nil {
+		errs = append(errs, fmt.Sprintf("time: %v", err))
+	}
+	if t := config.Theme; t != nil {
+		switch t.Background {
+		case "", theme.BackgroundDark, theme.BackgroundLight:
+		default:
+			errs = append(errs, fmt.Sprintf("theme.background: must be %q or %q, got %q", theme.BackgroundDark, theme.BackgroundLight, t.Background))
+		}
+		switch t.Clock {
+		case "", theme.Clock24h, theme.Clock12h:
+		default:
+			errs = append(errs, fmt.Sprintf("theme.clock: must be %q or %q, got %q", theme.Clock24h, theme.Clock12h, t.Clock))
+		}
+		for process, color := range t.Colors {
+			if _, err := theme.ParseColor(color); err != nil {
+				errs = append(errs, fmt.Sprintf("theme.colors.%s: %v", process, err))
+			}
+		}
+	}
 
 	// Validate processes
 	if len(config.Processes) == 0 {
@@ -37,14 +86,144 @@ func Validate(config *Config) error {
 	}
 
 	for name, proc := range config.Processes {
-		if proc.Cmd == "" {
+		switch {
+		case proc.Type == ProcessTypeSynthetic:
+			if proc.Cmd != "" {
+				errs = append(errs, fmt.Sprintf("processes.%s.cmd: cannot be combined with type %q", name, ProcessTypeSynthetic))
+			}
+			errs = append(errs, validateSynthetic(fmt.Sprintf("processes.%s.synthetic", name), proc.Synthetic)...)
+		case proc.Type != "":
+			errs = append(errs, fmt.Sprintf("processes.%s.type: must be %q or unset, got %q", name, ProcessTypeSynthetic, proc.Type))
+		case proc.Cmd == "":
 			errs = append(errs, fmt.Sprintf("processes.%s.cmd: command is required", name))
 		}
+		if proc.Synthetic != nil && proc.Type != ProcessTypeSynthetic {
+			errs = append(errs, fmt.Sprintf("processes.%s.synthetic: only valid with type: %s", name, ProcessTypeSynthetic))
+		}
+		if proc.Stdin && proc.Type == ProcessTypeSynthetic {
+			errs = append(errs, fmt.Sprintf("processes.%s.stdin: cannot be combined with type %q", name, ProcessTypeSynthetic))
+		}
+		if err := validateShell(proc.Shell); err != nil {
+			errs = append(errs, fmt.Sprintf("processes.%s.shell: %v", name, err))
+		}
+
+		// Validate port if present
+		if proc.Port != "" && !proc.AutoPort() {
+			if port := proc.FixedPort(); port <= 0 || port > 65535 {
+				errs = append(errs, fmt.Sprintf("processes.%s.port: must be \"auto\" or between 1 and 65535, got %q", name, proc.Port))
+			}
+		}
+
+		// Validate wait_for dependencies
+		for i, target := range proc.WaitFor {
+			if err := validateWaitFor(target); err != nil {
+				errs = append(errs, fmt.Sprintf("processes.%s.wait_for[%d]: %v", name, i, err))
+			}
+		}
+		for i, dep := range proc.DependsOn {
+			if _, ok := config.Processes[dep]; !ok {
+				errs = append(errs, fmt.Sprintf("processes.%s.depends_on[%d]: unknown process %q", name, i, dep))
+			} else if dep == name {
+				errs = append(errs, fmt.Sprintf("processes.%s.depends_on[%d]: a process can't depend on itself", name, i))
+			}
+		}
+		if proc.WaitTimeout != "" {
+			if d, err := time.ParseDuration(proc.WaitTimeout); err != nil {
+				errs = append(errs, fmt.Sprintf("processes.%s.wait_timeout: invalid duration %q", name, proc.WaitTimeout))
+			} else if d <= 0 {
+				errs = append(errs, fmt.Sprintf("processes.%s.wait_timeout: must be positive", name))
+			}
+		}
+
+		// Validate the ready probe, with $PORT standing in for the process's port
+		if proc.Ready != "" {
+			target := proc.ReadyTarget(1)
+			if err := validateWaitFor(target); err != nil {
+				errs = append(errs, fmt.Sprintf("processes.%s.ready: %s", name, strings.ReplaceAll(err.Error(), target, proc.Ready)))
+			} else if target != proc.Ready && proc.Port == "" {
+				errs = append(errs, fmt.Sprintf("processes.%s.ready: uses $PORT, but the process has no port", name))
+			}
+		}
+		if proc.ReadyTimeout != "" {
+			if proc.Ready == "" {
+				errs = append(errs, fmt.Sprintf("processes.%s.ready_timeout: only valid with ready", name))
+			}
+			if d, err := time.ParseDuration(proc.ReadyTimeout); err != nil {
+				errs = append(errs, fmt.Sprintf("processes.%s.ready_timeout: invalid duration %q", name, proc.ReadyTimeout))
+			} else if d <= 0 {
+				errs = append(errs, fmt.Sprintf("processes.%s.ready_timeout: must be positive", name))
+			}
+		}
+
+		// Lazy processes are started by requests to a service linked to them
+		if proc.Lazy && !servesProcess(config.Services, name) {
+			errs = append(errs, fmt.Sprintf("processes.%s.lazy: no service has process: %s, so nothing would start it", name, name))
+		}
+		if proc.IdleTimeout != "" {
+			if !proc.Lazy {
+				errs = append(errs, fmt.Sprintf("processes.%s.idle_timeout: only valid with lazy: true", name))
+			}
+			if d, err := time.ParseDuration(proc.IdleTimeout); err != nil {
+				errs = append(errs, fmt.Sprintf("processes.%s.idle_timeout: invalid duration %q", name, proc.IdleTimeout))
+			} else if d <= 0 {
+				errs = append(errs, fmt.Sprintf("processes.%s.idle_timeout: must be positive", name))
+			}
+		}
+
+		errs = append(errs, validateScale(config, name, proc, proc.Scale)...)
+		errs = append(errs, validateWatch(fmt.Sprintf("processes.%s", name), proc)...)
+
+		if proc.LogBuffer < 0 {
+			errs = append(errs, fmt.Sprintf("processes.%s.log_buffer: must be non-negative, got %d", name, proc.LogBuffer))
+		}
+		if proc.MaxLinesPerSec < 0 {
+			errs = append(errs, fmt.Sprintf("processes.%s.max_lines_per_sec: must be non-negative, got %d", name, proc.MaxLinesPerSec))
+		}
+
+		switch proc.LogFormat {
+		case "", domain.LogFormatJSON, domain.LogFormatLogfmt:
+		default:
+			errs = append(errs, fmt.Sprintf("processes.%s.log_format: must be %q or %q, got %q", name, domain.LogFormatJSON, domain.LogFormatLogfmt, proc.LogFormat))
+		}
+
+		for _, variable := range proc.EnvPrompt {
+			if variable == "" || strings.ContainsAny(variable, "= \t") {
+				errs = append(errs, fmt.Sprintf("processes.%s.env_prompt: invalid variable name %q", name, variable))
+			}
+		}
+
+		for _, profile := range proc.Profiles {
+			if profile == "" || strings.ContainsAny(profile, ", \t") {
+				errs = append(errs, fmt.Sprintf("processes.%s.profiles: invalid profile name %q", name, profile))
+			}
+		}
+
+		for _, matcher := range proc.ProblemMatchers {
+			if _, ok := config.problemMatcher(matcher); !ok {
+				errs = append(errs, fmt.Sprintf("processes.%s.problem_matchers: unknown matcher %q", name, matcher))
+			}
+		}
 
 		// Validate healthcheck if present
 		if proc.Healthcheck != nil {
-			if proc.Healthcheck.Cmd == "" {
-				errs = append(errs, fmt.Sprintf("processes.%s.healthcheck.cmd: command is required", name))
+			switch proc.Healthcheck.TypeOrDefault() {
+			case HealthcheckTypeCmd:
+				if proc.Healthcheck.Cmd == "" {
+					errs = append(errs, fmt.Sprintf("processes.%s.healthcheck.cmd: command is required", name))
+				}
+			case HealthcheckTypeProxy:
+				errs = append(errs, validateProxyHealthcheck(config, name)...)
+			case HealthcheckTypeHTTP, HealthcheckTypeTCP:
+				errs = append(errs, validateProbeHealthcheck(name, proc)...)
+			default:
+				errs = append(errs, fmt.Sprintf("processes.%s.healthcheck.type: must be %q, %q, %q, or %q, got %q", name,
+					HealthcheckTypeCmd, HealthcheckTypeProxy, HealthcheckTypeHTTP, HealthcheckTypeTCP, proc.Healthcheck.Type))
+			}
+			if t := proc.Healthcheck.TypeOrDefault(); proc.Healthcheck.HTTP != nil && t != HealthcheckTypeHTTP {
+				errs = append(errs, fmt.Sprintf("processes.%s.healthcheck.http: cannot be combined with type %q", name, t))
+			}
+			if t := proc.Healthcheck.TypeOrDefault(); proc.Healthcheck.TCP != nil && t != HealthcheckTypeTCP {
+				errs = append(errs, fmt.Sprintf("processes.%s.healthcheck.tcp: cannot be combined with type %q", name, t))
 			}
 			if proc.Healthcheck.Retries < 0 {
 				errs = append(errs, fmt.Sprintf("processes.%s.healthcheck.retries: must be non-negative", name))
@@ -52,6 +231,135 @@ func Validate(config *Config) error {
 		}
 	}
 
+	// Validate tasks. Task output is logged under the task's name, so it
+	// can't be a process's name too.
+	for name, task := range config.Tasks {
+		if task.Cmd == "" {
+			errs = append(errs, fmt.Sprintf("tasks.%s.cmd: command is required", name))
+		}
+		if err := validateShell(task.Shell); err != nil {
+			errs = append(errs, fmt.Sprintf("tasks.%s.shell: %v", name, err))
+		}
+		if _, ok := config.Processes[name]; ok {
+			errs = append(errs, fmt.Sprintf("tasks.%s: a process has the same name", name))
+		}
+	}
+
+	// Validate supervisor limits if present
+	if config.Supervisor != nil {
+		if config.Supervisor.StartConcurrency < 0 {
+			errs = append(errs, fmt.Sprintf("supervisor.start_concurrency: must be non-negative, got %d", config.Supervisor.StartConcurrency))
+		}
+		if config.Supervisor.StopConcurrency < 0 {
+			errs = append(errs, fmt.Sprintf("supervisor.stop_concurrency: must be non-negative, got %d", config.Supervisor.StopConcurrency))
+		}
+		if config.Supervisor.CrashLimit < 0 {
+			errs = append(errs, fmt.Sprintf("supervisor.crash_limit: must be non-negative, got %d", config.Supervisor.CrashLimit))
+		}
+		if window := config.Supervisor.CrashWindow; window != "" {
+			if d, err := time.ParseDuration(window); err != nil {
+				errs = append(errs, fmt.Sprintf("supervisor.crash_window: invalid duration %q", window))
+			} else if d < 0 {
+				errs = append(errs, fmt.Sprintf("supervisor.crash_window: must be non-negative, got %q", window))
+			}
+		}
+	}
+
+	// Validate log buffer sizes if present
+	if config.Logs != nil {
+		if config.Logs.BufferSize < 0 {
+			errs = append(errs, fmt.Sprintf("logs.buffer_size: must be non-negative, got %d", config.Logs.BufferSize))
+		}
+		if config.Logs.ProcessBufferSize < 0 {
+			errs = append(errs, fmt.Sprintf("logs.process_buffer_size: must be non-negative, got %d", config.Logs.ProcessBufferSize))
+		}
+		if _, err := ParseSize(config.Logs.MaxMemory); err != nil {
+			errs = append(errs, fmt.Sprintf("logs.max_memory: %v", err))
+		}
+	}
+
+	// Validate the storage backend if present
+	if config.Storage != nil {
+		switch config.Storage.Backend {
+		case "", StorageBackendMemory, StorageBackendSQLite:
+		default:
+			errs = append(errs, fmt.Sprintf("storage.backend: must be %q or %q, got %q", StorageBackendMemory, StorageBackendSQLite, config.Storage.Backend))
+		}
+		if config.Storage.MaxLogs < 0 {
+			errs = append(errs, fmt.Sprintf("storage.max_logs: must be non-negative, got %d", config.Storage.MaxLogs))
+		}
+		if config.Storage.MaxRequests < 0 {
+			errs = append(errs, fmt.Sprintf("storage.max_requests: must be non-negative, got %d", config.Storage.MaxRequests))
+		}
+		if config.Storage.BackendOrDefault() == StorageBackendMemory {
+			if config.Storage.Path != "" {
+				errs = append(errs, "storage.path: only valid with backend: sqlite")
+			}
+			if config.Storage.MaxLogs != 0 || config.Storage.MaxRequests != 0 {
+				errs = append(errs, "storage: max_logs and max_requests are only valid with backend: sqlite; size the memory backend with logs.buffer_size")
+			}
+		}
+	}
+
+	// Validate notifications if present
+	if config.Notifications != nil {
+		n := config.Notifications
+		if !n.Desktop && n.Command == "" && n.Webhook == "" {
+			errs = append(errs, "notifications: set at least one of desktop, command, or webhook")
+		}
+		if n.Webhook != "" {
+			if u, err := url.Parse(n.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, fmt.Sprintf("notifications.webhook: must be an http(s) URL, got %q", n.Webhook))
+			}
+		}
+		for _, event := range n.Events {
+			switch event {
+			case NotifyEventCrashed, NotifyEventFailed, NotifyEventUnhealthy, NotifyEventHealthy:
+			default:
+				errs = append(errs, fmt.Sprintf("notifications.events: must be %q, %q, %q, or %q, got %q",
+					NotifyEventCrashed, NotifyEventFailed, NotifyEventUnhealthy, NotifyEventHealthy, event))
+			}
+		}
+	}
+
+	// Validate idle shutdown if present
+	if config.Daemon != nil {
+		if config.Daemon.IdleTimeout != "" {
+			if d, err := time.ParseDuration(config.Daemon.IdleTimeout); err != nil {
+				errs = append(errs, fmt.Sprintf("daemon.idle_timeout: invalid duration %q", config.Daemon.IdleTimeout))
+			} else if d <= 0 {
+				errs = append(errs, "daemon.idle_timeout: must be positive")
+			}
+		}
+		switch config.Daemon.IdleAction {
+		case "", IdleActionStop:
+		case IdleActionSleep:
+			if config.Proxy == nil || !config.Proxy.Enabled {
+				errs = append(errs, "daemon.idle_action: \"sleep\" requires the proxy to be enabled")
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("daemon.idle_action: must be %q or %q, got %q", IdleActionStop, IdleActionSleep, config.Daemon.IdleAction))
+		}
+
+		// prox must still be able to use what it creates
+		for _, m := range []struct {
+			field, value string
+			owner        os.FileMode
+		}{
+			{"file_mode", config.Daemon.FileMode, 0600},
+			{"dir_mode", config.Daemon.DirMode, 0700},
+		} {
+			if m.value == "" {
+				continue
+			}
+			if mode, err := parseMode(m.value); err != nil {
+				errs = append(errs, fmt.Sprintf("daemon.%s: %v", m.field, err))
+			} else if mode&m.owner != m.owner {
+				errs = append(errs, fmt.Sprintf("daemon.%s: must give the owner at least %04o, got %04o", m.field, m.owner, mode))
+			}
+		}
+	}
+
 	// Validate proxy config if present
 	if config.Proxy != nil {
 		// Validate HTTP port if set
@@ -75,6 +383,67 @@ func Validate(config *Config) error {
 		if config.Proxy.Domain != "" && !domainRegex.MatchString(config.Proxy.Domain) {
 			errs = append(errs, fmt.Sprintf("proxy.domain: invalid domain format %q", config.Proxy.Domain))
 		}
+
+		if config.Proxy.APIEnabled() {
+			subdomain := config.Proxy.APISubdomain()
+			if err := validateServiceName(subdomain); err != nil {
+				errs = append(errs, fmt.Sprintf("proxy.api.subdomain: invalid subdomain %q: %s", subdomain, err.Error()))
+			} else if _, ok := config.Services[subdomain]; ok {
+				errs = append(errs, fmt.Sprintf("proxy.api.subdomain: %q is reserved for the API but is also a service", subdomain))
+			}
+		}
+
+		switch config.Proxy.DNS {
+		case "", ProxyDNSHosts, ProxyDNSBuiltin:
+		default:
+			errs = append(errs, fmt.Sprintf("proxy.dns: must be %q or %q, got %q", ProxyDNSHosts, ProxyDNSBuiltin, config.Proxy.DNS))
+		}
+		if config.Proxy.DNSPort < 0 || config.Proxy.DNSPort > 65535 {
+			errs = append(errs, fmt.Sprintf("proxy.dns_port: must be between 0 and 65535, got %d", config.Proxy.DNSPort))
+		}
+
+		if access := config.Proxy.AccessLog; access != nil {
+			switch access.Format {
+			case "", AccessLogFormatText, AccessLogFormatJSON:
+			default:
+				errs = append(errs, fmt.Sprintf("proxy.access_log.format: must be %q or %q, got %q", AccessLogFormatText, AccessLogFormatJSON, access.Format))
+			}
+			process := access.ProcessOrDefault()
+			if strings.ContainsAny(process, " \t\n/\\") {
+				errs = append(errs, fmt.Sprintf("proxy.access_log.process: cannot contain whitespace or path separators, got %q", process))
+			} else if _, ok := config.Processes[process]; ok && access.Enabled {
+				errs = append(errs, fmt.Sprintf("proxy.access_log.process: %q is also a process; set process to another name", process))
+			}
+		}
+
+		if history := config.Proxy.History; history != nil {
+			if history.Max < 0 {
+				errs = append(errs, fmt.Sprintf("proxy.history.max: must be non-negative, got %d", history.Max))
+			}
+			if history.Persist && config.Storage.BackendOrDefault() == StorageBackendSQLite {
+				errs = append(errs, "proxy.history.persist: the sqlite storage backend already keeps the request history")
+			}
+		}
+
+		if capture := config.Proxy.Capture; capture != nil {
+			if _, err := ParseSize(capture.MaxBodySize); err != nil {
+				errs = append(errs, fmt.Sprintf("proxy.capture.max_body_size: %v", err))
+			}
+			for i, rule := range capture.Include {
+				errs = append(errs, validateCaptureRule(fmt.Sprintf("proxy.capture.include[%d]", i), rule)...)
+			}
+			for i, rule := range capture.Exclude {
+				errs = append(errs, validateCaptureRule(fmt.Sprintf("proxy.capture.exclude[%d]", i), rule)...)
+			}
+		}
+
+		if wait := config.Proxy.RestartWait; wait != "" {
+			if d, err := time.ParseDuration(wait); err != nil {
+				errs = append(errs, fmt.Sprintf("proxy.restart_wait: invalid duration %q", wait))
+			} else if d < 0 {
+				errs = append(errs, fmt.Sprintf("proxy.restart_wait: must be non-negative, got %q", wait))
+			}
+		}
 	}
 
 	// Validate certs config if present
@@ -82,6 +451,11 @@ func Validate(config *Config) error {
 		if config.Certs.Dir == "" {
 			errs = append(errs, "certs.dir: directory path is required")
 		}
+		switch config.Certs.Provider {
+		case "", CertProviderMkcert, CertProviderSelfSigned:
+		default:
+			errs = append(errs, fmt.Sprintf("certs.provider: must be %q or %q, got %q", CertProviderMkcert, CertProviderSelfSigned, config.Certs.Provider))
+		}
 	}
 
 	// Validate that HTTPS requires certs when enabled
@@ -93,15 +467,84 @@ func Validate(config *Config) error {
 
 	// Validate services config if present
 	for name, svc := range config.Services {
-		if svc.Port <= 0 || svc.Port > 65535 {
+		_, wildcard := WildcardPrefix(name)
+		// Services linked to a process with a port may omit their own port
+		portFromProcess := svc.Port == 0 && svc.Process != "" && config.Processes[svc.Process].Port != ""
+		if svc.PortRange != "" {
+			if !wildcard {
+				errs = append(errs, fmt.Sprintf("services.%s.port_range: only wildcard services can have a port range", name))
+			} else if svc.Port != 0 {
+				errs = append(errs, fmt.Sprintf("services.%s.port_range: cannot be combined with port", name))
+			} else if _, _, err := ParsePortRange(svc.PortRange); err != nil {
+				errs = append(errs, fmt.Sprintf("services.%s.port_range: %s", name, err.Error()))
+			}
+		} else if !portFromProcess && (svc.Port <= 0 || svc.Port > 65535) {
 			errs = append(errs, fmt.Sprintf("services.%s.port: must be between 1 and 65535, got %d", name, svc.Port))
 		}
 		if err := validateServiceName(name); err != nil {
 			errs = append(errs, fmt.Sprintf("services.%s: %s", name, err.Error()))
 		}
-		if err := validateHost(svc.Host); err != nil {
+		host := svc.Host
+		if strings.Contains(host, MatchPlaceholder) {
+			if !wildcard {
+				errs = append(errs, fmt.Sprintf("services.%s.host: only wildcard services can use %s", name, MatchPlaceholder))
+			}
+			host = strings.ReplaceAll(host, MatchPlaceholder, "x")
+		}
+		if err := validateHost(host); err != nil {
 			errs = append(errs, fmt.Sprintf("services.%s.host: %s", name, err.Error()))
 		}
+		if svc.Process != "" {
+			if _, ok := config.Processes[svc.Process]; !ok {
+				errs = append(errs, fmt.Sprintf("services.%s.process: unknown process %q", name, svc.Process))
+			}
+		}
+		switch svc.Protocol {
+		case "", ServiceProtocolHTTP, ServiceProtocolH2C:
+		default:
+			errs = append(errs, fmt.Sprintf("services.%s.protocol: must be %q or %q, got %q", name, ServiceProtocolHTTP, ServiceProtocolH2C, svc.Protocol))
+		}
+		if svc.SLO != nil {
+			if d, err := time.ParseDuration(svc.SLO.P95); err != nil {
+				errs = append(errs, fmt.Sprintf("services.%s.slo.p95: invalid duration %q", name, svc.SLO.P95))
+			} else if d <= 0 {
+				errs = append(errs, fmt.Sprintf("services.%s.slo.p95: must be positive", name))
+			}
+		}
+		if svc.Schema != nil {
+			if svc.Schema.File == "" {
+				errs = append(errs, fmt.Sprintf("services.%s.schema.file: schema file path is required", name))
+			}
+			if svc.Schema.Sample < 0 || svc.Schema.Sample > 1 {
+				errs = append(errs, fmt.Sprintf("services.%s.schema.sample: must be between 0 and 1, got %g", name, svc.Schema.Sample))
+			}
+		}
+		if svc.Rewrite != nil {
+			errs = append(errs, validateRewrite(name, svc.Rewrite)...)
+		}
+	}
+
+	// Validate redact patterns
+	if config.Redact != nil {
+		for i, pattern := range config.Redact.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				errs = append(errs, fmt.Sprintf("redact.patterns[%d]: invalid regex %q", i, pattern))
+			}
+		}
+	}
+
+	// Validate problem matchers
+	for name, pattern := range config.ProblemMatchers {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("problem_matchers.%s: invalid regex %q", name, pattern))
+			continue
+		}
+		for _, group := range ProblemMatcherGroups {
+			if re.SubexpIndex(group) < 0 {
+				errs = append(errs, fmt.Sprintf("problem_matchers.%s: missing named group (?P<%s>...)", name, group))
+			}
+		}
 	}
 
 	// Validate that services require proxy to be enabled
@@ -109,18 +552,150 @@ func Validate(config *Config) error {
 		errs = append(errs, "services: proxy must be enabled when services are defined")
 	}
 
-	if len(errs) > 0 {
-		return fmt.Errorf("%w: %s", domain.ErrInvalidConfig, strings.Join(errs, "; "))
+	sort.Strings(errs)
+	return errs
+}
+
+// validateProxyHealthcheck checks a process's proxy healthcheck
+func validateProxyHealthcheck(config *Config, process string) []string {
+	var errs []string
+	hc := config.Processes[process].Healthcheck
+	prefix := fmt.Sprintf("processes.%s.healthcheck", process)
+
+	if hc.Cmd != "" {
+		errs = append(errs, fmt.Sprintf("%s.cmd: cannot be combined with type %q", prefix, HealthcheckTypeProxy))
+	}
+	if config.Proxy == nil || !config.Proxy.Enabled {
+		errs = append(errs, fmt.Sprintf("%s.type: proxy checks require the proxy to be enabled", prefix))
+	}
+	if hc.Service != "" {
+		if _, wildcard := WildcardPrefix(hc.Service); wildcard {
+			errs = append(errs, fmt.Sprintf("%s.service: cannot be a wildcard service", prefix))
+		} else if _, ok := config.Services[hc.Service]; !ok {
+			errs = append(errs, fmt.Sprintf("%s.service: unknown service %q", prefix, hc.Service))
+		}
+	} else if config.processService(process) == "" {
+		errs = append(errs, fmt.Sprintf("%s.service: required when no service is linked to the process", prefix))
 	}
+	if hc.Path != "" && !strings.HasPrefix(hc.Path, "/") {
+		errs = append(errs, fmt.Sprintf("%s.path: must start with /, got %q", prefix, hc.Path))
+	}
+	return errs
+}
 
-	return nil
+// validateProbeHealthcheck checks a process's http or tcp healthcheck
+func validateProbeHealthcheck(process string, proc ProcessConfig) []string {
+	var errs []string
+	hc := proc.Healthcheck
+	prefix := fmt.Sprintf("processes.%s.healthcheck", process)
+	kind := hc.TypeOrDefault()
+
+	if hc.Cmd != "" {
+		errs = append(errs, fmt.Sprintf("%s.cmd: cannot be combined with type %q", prefix, kind))
+	}
+	switch kind {
+	case HealthcheckTypeHTTP:
+		if hc.HTTP == nil || hc.HTTP.URL == "" {
+			return append(errs, prefix+".http.url: required for http checks")
+		}
+		// Variables such as $PORT are filled in when the check runs
+		u, err := url.Parse(os.Expand(hc.HTTP.URL, func(string) string { return "1" }))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Sprintf("%s.http.url: must be an http(s) URL, got %q", prefix, hc.HTTP.URL))
+		}
+		if status := hc.HTTP.ExpectedStatus; status != 0 && (status < 100 || status > 599) {
+			errs = append(errs, fmt.Sprintf("%s.http.expected_status: must be between 100 and 599, got %d", prefix, status))
+		}
+	case HealthcheckTypeTCP:
+		if hc.TCP == nil || hc.TCP.Port == 0 {
+			if proc.Port == "" && proc.Env["PORT"] == "" && proc.EnvFile == "" {
+				errs = append(errs, prefix+".tcp.port: required when the process has no port")
+			}
+		} else if hc.TCP.Port < 0 || hc.TCP.Port > 65535 {
+			errs = append(errs, fmt.Sprintf("%s.tcp.port: must be between 0 and 65535, got %d", prefix, hc.TCP.Port))
+		}
+	}
+	return errs
+}
+
+// validateSynthetic checks the settings of a type: synthetic process; nil
+// settings use the defaults
+func validateSynthetic(prefix string, sc *SyntheticConfig) []string {
+	if sc == nil {
+		return nil
+	}
+	var errs []string
+	if sc.Rate < 0 {
+		errs = append(errs, fmt.Sprintf("%s.rate: must be non-negative, got %d", prefix, sc.Rate))
+	}
+	if sc.ExitAfter != "" {
+		if d, err := time.ParseDuration(sc.ExitAfter); err != nil {
+			errs = append(errs, fmt.Sprintf("%s.exit_after: invalid duration %q", prefix, sc.ExitAfter))
+		} else if d <= 0 {
+			errs = append(errs, fmt.Sprintf("%s.exit_after: must be positive", prefix))
+		}
+	}
+	if sc.CrashProbability < 0 || sc.CrashProbability > 1 {
+		errs = append(errs, fmt.Sprintf("%s.crash_probability: must be between 0 and 1, got %g", prefix, sc.CrashProbability))
+	}
+	return errs
+}
+
+// validateWatch checks a process's watch settings
+func validateWatch(prefix string, proc ProcessConfig) []string {
+	var errs []string
+	for i, pattern := range proc.Watch {
+		if err := watch.ValidatePattern(pattern); err != nil {
+			errs = append(errs, fmt.Sprintf("%s.watch[%d]: %v", prefix, i, err))
+		}
+	}
+	for i, pattern := range proc.WatchIgnore {
+		if err := watch.ValidatePattern(pattern); err != nil {
+			errs = append(errs, fmt.Sprintf("%s.watch_ignore[%d]: %v", prefix, i, err))
+		}
+	}
+	if len(proc.WatchIgnore) > 0 && len(proc.Watch) == 0 {
+		errs = append(errs, fmt.Sprintf("%s.watch_ignore: only valid with watch", prefix))
+	}
+	if proc.WatchDebounce != "" {
+		if len(proc.Watch) == 0 {
+			errs = append(errs, fmt.Sprintf("%s.watch_debounce: only valid with watch", prefix))
+		}
+		if d, err := time.ParseDuration(proc.WatchDebounce); err != nil {
+			errs = append(errs, fmt.Sprintf("%s.watch_debounce: invalid duration %q", prefix, proc.WatchDebounce))
+		} else if d <= 0 {
+			errs = append(errs, fmt.Sprintf("%s.watch_debounce: must be positive", prefix))
+		}
+	}
+	return errs
 }
 
-// validateServiceName checks if a service name is valid as a subdomain
+// servesProcess reports whether any service is linked to the process
+func servesProcess(services map[string]ServiceConfig, process string) bool {
+	for _, svc := range services {
+		if svc.Process == process {
+			return true
+		}
+	}
+	return false
+}
+
+// validateServiceName checks if a service name is valid as a subdomain, or
+// as a wildcard: "*" for the fallback service, or a prefix followed by "*"
 func validateServiceName(name string) error {
 	if name == "" {
 		return fmt.Errorf("service name cannot be empty")
 	}
+	if prefix, ok := WildcardPrefix(name); ok {
+		if prefix == "" {
+			return nil
+		}
+		if strings.Contains(prefix, "*") {
+			return fmt.Errorf("service name can only have one wildcard, at the end")
+		}
+		// The prefix is the start of a subdomain, so it may end with a hyphen
+		return validateServiceName(prefix + "x")
+	}
 	// Service names become subdomains, so they must be valid DNS labels
 	// - Only lowercase alphanumeric and hyphens
 	// - Cannot start or end with hyphen
@@ -168,3 +743,94 @@ func validateHost(host string) error {
 	}
 	return nil
 }
+
+// headerNameRegex matches an HTTP header name (an RFC 9110 token)
+var headerNameRegex = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// validateRewrite checks a service's rewrite rules
+func validateRewrite(service string, rw *RewriteConfig) []string {
+	var errs []string
+	switch rw.Host {
+	case "", RewriteHostPreserve, RewriteHostTarget:
+	default:
+		errs = append(errs, fmt.Sprintf("services.%s.rewrite.host: must be %q or %q, got %q", service, RewriteHostPreserve, RewriteHostTarget, rw.Host))
+	}
+	for field, headers := range map[string]*HeaderRewrite{"request_headers": rw.RequestHeaders, "response_headers": rw.ResponseHeaders} {
+		if headers == nil {
+			continue
+		}
+		for name, value := range headers.Set {
+			if !headerNameRegex.MatchString(name) {
+				errs = append(errs, fmt.Sprintf("services.%s.rewrite.%s.set: invalid header name %q", service, field, name))
+			} else if strings.ContainsAny(value, "\r\n") {
+				errs = append(errs, fmt.Sprintf("services.%s.rewrite.%s.set: value of %s must not contain line breaks", service, field, name))
+			}
+		}
+		for _, name := range headers.Remove {
+			if !headerNameRegex.MatchString(name) {
+				errs = append(errs, fmt.Sprintf("services.%s.rewrite.%s.remove: invalid header name %q", service, field, name))
+			}
+		}
+	}
+	return errs
+}
+
+// validateCaptureRule checks a capture include or exclude rule
+func validateCaptureRule(field string, rule CaptureRule) []string {
+	var errs []string
+	if rule.Subdomain == "" && rule.Path == "" && len(rule.Methods) == 0 && rule.ContentType == "" && !rule.WebSocket {
+		errs = append(errs, fmt.Sprintf("%s: must set subdomain, path, methods, content_type, or websocket", field))
+	}
+	if _, err := path.Match(rule.Subdomain, ""); err != nil {
+		errs = append(errs, fmt.Sprintf("%s.subdomain: invalid pattern %q", field, rule.Subdomain))
+	}
+	if _, err := regexp.Compile(rule.Path); err != nil {
+		errs = append(errs, fmt.Sprintf("%s.path: invalid regex %q", field, rule.Path))
+	}
+	for _, method := range rule.Methods {
+		if method == "" || strings.ContainsAny(method, " \t/") {
+			errs = append(errs, fmt.Sprintf("%s.methods: invalid method %q", field, method))
+		}
+	}
+	if ct := rule.ContentType; ct != "" && !strings.Contains(ct, "/") {
+		errs = append(errs, fmt.Sprintf("%s.content_type: must be a media type such as \"application/json\" or \"image/*\", got %q", field, ct))
+	}
+	return errs
+}
+
+// validateShell checks a shell command line. It is split on whitespace
+// rather than parsed, so quotes would end up inside the arguments.
+func validateShell(shell string) error {
+	if shell == "" {
+		return nil
+	}
+	if strings.TrimSpace(shell) == "" {
+		return fmt.Errorf("must not be blank")
+	}
+	if strings.ContainsAny(shell, "'\"\\") {
+		return fmt.Errorf("quotes aren't supported, the shell is split on spaces (e.g. \"/bin/zsh -l\"), got %q", shell)
+	}
+	return nil
+}
+
+// validateWaitFor checks that a wait_for entry is a tcp://host:port or
+// http(s):// URL
+func validateWaitFor(target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q", target)
+	}
+	switch u.Scheme {
+	case "tcp":
+		if u.Hostname() == "" || u.Port() == "" {
+			return fmt.Errorf("tcp target must be tcp://host:port, got %q", target)
+		}
+	case "http", "https":
+		if u.Host == "" {
+			return fmt.Errorf("missing host in %q", target)
+		}
+	default:
+		return fmt.Errorf("unsupported scheme in %q (use tcp://, http://, or https://)", target)
+	}
+	return nil
+}
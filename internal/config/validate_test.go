@@ -3,6 +3,7 @@ package config
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -406,6 +407,15 @@ func TestValidateServiceName(t *testing.T) {
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "lowercase letters, numbers, and hyphens")
 	})
+
+	t.Run("wildcard names", func(t *testing.T) {
+		for _, name := range []string{"*", "pr-*", "preview*"} {
+			assert.NoError(t, validateServiceName(name), "name %q should be valid", name)
+		}
+		for _, name := range []string{"-*", "*-pr", "pr-*-*", "PR-*"} {
+			assert.Error(t, validateServiceName(name), "name %q should be invalid", name)
+		}
+	})
 }
 
 func TestValidateHost(t *testing.T) {
@@ -574,4 +584,1138 @@ func TestValidateServiceHost(t *testing.T) {
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "services.app.host")
 	})
+
+	t.Run("service protocol", func(t *testing.T) {
+		cfg := baseConfig()
+		cfg.Proxy = &ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.dev"}
+		cfg.Services = map[string]ServiceConfig{
+			"grpc": {Port: 50051, Host: "localhost", Protocol: ServiceProtocolH2C},
+		}
+		assert.NoError(t, Validate(cfg))
+
+		cfg.Services["grpc"] = ServiceConfig{Port: 50051, Host: "localhost", Protocol: "grpc"}
+		assert.Equal(t, []string{
+			`services.grpc.protocol: must be "http" or "h2c", got "grpc"`,
+		}, validationErrors(cfg))
+	})
+}
+
+func TestValidateWildcardServices(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"web": {Cmd: "npm run dev"},
+		},
+		Proxy: &ProxyConfig{
+			Enabled:  true,
+			HTTPPort: 6788,
+			Domain:   "local.dev",
+		},
+	}
+
+	t.Run("port range and templated host", func(t *testing.T) {
+		cfg.Services = map[string]ServiceConfig{
+			"pr-*":      {PortRange: "4000-4099", Host: "localhost"},
+			"preview-*": {Port: 8080, Host: "{match}.preview.internal"},
+			"*":         {Port: 3000, Host: "localhost"},
+		}
+		assert.NoError(t, Validate(cfg))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cfg.Services = map[string]ServiceConfig{
+			"app":  {PortRange: "4000-4099", Host: "{match}.internal"},
+			"pr-*": {Port: 4000, PortRange: "4000-4099", Host: "localhost"},
+			"qa-*": {PortRange: "5000-4000", Host: "localhost"},
+		}
+		errs := validationErrors(cfg)
+		assert.Equal(t, []string{
+			"services.app.host: only wildcard services can use {match}",
+			"services.app.port_range: only wildcard services can have a port range",
+			"services.pr-*.port_range: cannot be combined with port",
+			`services.qa-*.port_range: must be within 1-65535 with start <= end, got "5000-4000"`,
+		}, errs)
+	})
+}
+
+func TestServiceConfig_ForMatch(t *testing.T) {
+	svc := ServiceConfig{PortRange: "4000-4009", Host: "{match}.internal"}
+
+	target, ok := svc.ForMatch("7")
+	require.True(t, ok)
+	assert.Equal(t, 4007, target.Port)
+	assert.Equal(t, "7.internal", target.Host)
+
+	for _, match := range []string{"10", "-1", "x"} {
+		_, ok := svc.ForMatch(match)
+		assert.False(t, ok, match)
+	}
+
+	_, ok = ServiceConfig{Port: 80, Host: "{match}.internal"}.ForMatch("bad_host")
+	assert.False(t, ok)
+}
+
+func TestValidateProxyHealthcheck(t *testing.T) {
+	baseConfig := func(hc *HealthcheckConfig) *Config {
+		return &Config{
+			API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+			Processes: map[string]ProcessConfig{
+				"web":    {Cmd: "npm run dev", Healthcheck: hc},
+				"worker": {Cmd: "./worker"},
+			},
+			Proxy: &ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.dev"},
+			Services: map[string]ServiceConfig{
+				"app":  {Port: 3000, Host: "localhost", Process: "web"},
+				"jobs": {Port: 4000, Host: "localhost", Process: "worker"},
+				"pr-*": {PortRange: "5000-5099", Host: "localhost", Process: "web"},
+			},
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		assert.NoError(t, Validate(baseConfig(&HealthcheckConfig{Type: "proxy"})))
+		assert.NoError(t, Validate(baseConfig(&HealthcheckConfig{Type: "proxy", Service: "jobs", Path: "/health"})))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cfg := baseConfig(&HealthcheckConfig{Type: "proxy", Cmd: "true", Service: "pr-*", Path: "health"})
+		cfg.Processes["worker"] = ProcessConfig{Cmd: "./worker", Healthcheck: &HealthcheckConfig{Type: "grpc"}}
+		assert.Equal(t, []string{
+			`processes.web.healthcheck.cmd: cannot be combined with type "proxy"`,
+			`processes.web.healthcheck.path: must start with /, got "health"`,
+			"processes.web.healthcheck.service: cannot be a wildcard service",
+			`processes.worker.healthcheck.type: must be "cmd", "proxy", "http", or "tcp", got "grpc"`,
+		}, validationErrors(cfg))
+	})
+
+	t.Run("no linked service", func(t *testing.T) {
+		cfg := baseConfig(nil)
+		cfg.Processes["worker"] = ProcessConfig{Cmd: "./worker", Healthcheck: &HealthcheckConfig{Type: "proxy"}}
+		delete(cfg.Services, "jobs")
+		assert.Equal(t, []string{
+			"processes.worker.healthcheck.service: required when no service is linked to the process",
+		}, validationErrors(cfg))
+	})
+}
+
+func TestValidateProbeHealthcheck(t *testing.T) {
+	baseConfig := func(procs map[string]ProcessConfig) *Config {
+		return &Config{API: APIConfig{Port: 5555, Host: "127.0.0.1"}, Processes: procs}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		assert.NoError(t, Validate(baseConfig(map[string]ProcessConfig{
+			"web":    {Cmd: "npm run dev", Port: "auto", Healthcheck: &HealthcheckConfig{HTTP: &HealthcheckHTTPConfig{URL: "http://localhost:$PORT/health", ExpectedStatus: 204}}},
+			"db":     {Cmd: "postgres", Healthcheck: &HealthcheckConfig{Type: "tcp", TCP: &HealthcheckTCPConfig{Port: 5432}}},
+			"worker": {Cmd: "./worker", Port: "auto", Healthcheck: &HealthcheckConfig{Type: "tcp"}},
+		})))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"processes.a.healthcheck.http.url: required for http checks",
+			`processes.b.healthcheck.cmd: cannot be combined with type "http"`,
+			`processes.b.healthcheck.http.expected_status: must be between 100 and 599, got 42`,
+			`processes.b.healthcheck.http.url: must be an http(s) URL, got "localhost:3000/health"`,
+			"processes.c.healthcheck.tcp.port: required when the process has no port",
+			`processes.d.healthcheck.tcp: cannot be combined with type "cmd"`,
+			"processes.e.healthcheck.tcp.port: must be between 0 and 65535, got 70000",
+		}, validationErrors(baseConfig(map[string]ProcessConfig{
+			"a": {Cmd: "./a", Healthcheck: &HealthcheckConfig{Type: "http"}},
+			"b": {Cmd: "./b", Healthcheck: &HealthcheckConfig{Cmd: "true", HTTP: &HealthcheckHTTPConfig{URL: "localhost:3000/health", ExpectedStatus: 42}}},
+			"c": {Cmd: "./c", Healthcheck: &HealthcheckConfig{TCP: &HealthcheckTCPConfig{Host: "localhost"}}},
+			"d": {Cmd: "./d", Healthcheck: &HealthcheckConfig{Type: "cmd", Cmd: "true", TCP: &HealthcheckTCPConfig{Port: 80}}},
+			"e": {Cmd: "./e", Healthcheck: &HealthcheckConfig{TCP: &HealthcheckTCPConfig{Port: 70000}}},
+		})))
+	})
+}
+
+func TestValidateServiceSLO(t *testing.T) {
+	baseConfig := func() *Config {
+		return &Config{
+			API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+			Processes: map[string]ProcessConfig{
+				"web": {Cmd: "npm run dev"},
+			},
+			Proxy: &ProxyConfig{
+				Enabled:  true,
+				HTTPPort: 6788,
+				Domain:   "local.dev",
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		p95     string
+		wantErr bool
+	}{
+		{"valid milliseconds", "300ms", false},
+		{"valid seconds", "1s", false},
+		{"empty", "", true},
+		{"invalid", "fast", true},
+		{"zero", "0s", true},
+		{"negative", "-5ms", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := baseConfig()
+			cfg.Services = map[string]ServiceConfig{
+				"app": {Port: 3000, Host: "localhost", SLO: &SLOConfig{P95: tt.p95}},
+			}
+			err := Validate(cfg)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "services.app.slo.p95")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateProxyAPI(t *testing.T) {
+	baseConfig := func(api *ProxyAPIConfig) *Config {
+		return &Config{
+			API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+			Processes: map[string]ProcessConfig{
+				"web": {Cmd: "npm run dev"},
+			},
+			Proxy: &ProxyConfig{
+				Enabled:  true,
+				HTTPPort: 6788,
+				Domain:   "local.dev",
+				API:      api,
+			},
+			Services: map[string]ServiceConfig{
+				"app": {Port: 3000, Host: "localhost"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		api     *ProxyAPIConfig
+		wantErr string
+	}{
+		{"not configured", nil, ""},
+		{"default subdomain", &ProxyAPIConfig{Enabled: true}, ""},
+		{"custom subdomain", &ProxyAPIConfig{Enabled: true, Subdomain: "control"}, ""},
+		{"disabled ignores conflicts", &ProxyAPIConfig{Subdomain: "app"}, ""},
+		{"invalid subdomain", &ProxyAPIConfig{Enabled: true, Subdomain: "Control"}, "proxy.api.subdomain: invalid subdomain"},
+		{"conflicts with service", &ProxyAPIConfig{Enabled: true, Subdomain: "app"}, "proxy.api.subdomain: \"app\" is reserved"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(baseConfig(tt.api))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateAPITLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		tls     APITLSConfig
+		wantErr string
+	}{
+		{"generated", APITLSConfig{Enabled: true, Hosts: []string{"devbox.internal"}}, ""},
+		{"configured", APITLSConfig{Enabled: true, Cert: "api.pem", Key: "api-key.pem"}, ""},
+		{"cert without key", APITLSConfig{Enabled: true, Cert: "api.pem"}, "api.tls: cert and key must be set together"},
+		{"hosts with cert", APITLSConfig{Enabled: true, Cert: "api.pem", Key: "api-key.pem", Hosts: []string{"devbox"}}, "api.tls.hosts: only valid for a generated certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&Config{
+				API:       APIConfig{Port: 5555, Host: "0.0.0.0", TLS: &tt.tls},
+				Processes: map[string]ProcessConfig{"web": {Cmd: "npm run dev"}},
+			})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateServiceSchema(t *testing.T) {
+	baseConfig := func() *Config {
+		return &Config{
+			API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+			Processes: map[string]ProcessConfig{
+				"web": {Cmd: "npm run dev"},
+			},
+			Proxy: &ProxyConfig{
+				Enabled:  true,
+				HTTPPort: 6788,
+				Domain:   "local.dev",
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		schema  SchemaConfig
+		wantErr string
+	}{
+		{"file only", SchemaConfig{File: "api.json"}, ""},
+		{"with sample", SchemaConfig{File: "api.json", Sample: 0.1}, ""},
+		{"full sample", SchemaConfig{File: "api.json", Sample: 1}, ""},
+		{"missing file", SchemaConfig{Sample: 0.5}, "services.app.schema.file"},
+		{"negative sample", SchemaConfig{File: "api.json", Sample: -0.1}, "services.app.schema.sample"},
+		{"sample above one", SchemaConfig{File: "api.json", Sample: 2}, "services.app.schema.sample"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := baseConfig()
+			schema := tt.schema
+			cfg.Services = map[string]ServiceConfig{
+				"app": {Port: 3000, Host: "localhost", Schema: &schema},
+			}
+			err := Validate(cfg)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateServiceRewrite(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"web": {Cmd: "npm run dev"},
+		},
+		Proxy: &ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.dev"},
+	}
+
+	cfg.Services = map[string]ServiceConfig{
+		"app": {Port: 3000, Host: "localhost", Rewrite: &RewriteConfig{
+			Host:            RewriteHostTarget,
+			RequestHeaders:  &HeaderRewrite{Set: map[string]string{"Authorization": "Bearer dev"}, Remove: []string{"Cookie"}},
+			ResponseHeaders: &HeaderRewrite{Remove: []string{"Content-Security-Policy"}},
+		}},
+	}
+	assert.NoError(t, Validate(cfg))
+
+	cfg.Services = map[string]ServiceConfig{
+		"app": {Port: 3000, Host: "localhost", Rewrite: &RewriteConfig{
+			Host:            "backend",
+			RequestHeaders:  &HeaderRewrite{Set: map[string]string{"X-Token": "a\r\nX-Admin: 1"}},
+			ResponseHeaders: &HeaderRewrite{Remove: []string{"Bad Header"}},
+		}},
+	}
+	assert.Equal(t, []string{
+		`services.app.rewrite.host: must be "preserve" or "target", got "backend"`,
+		"services.app.rewrite.request_headers.set: value of X-Token must not contain line breaks",
+		`services.app.rewrite.response_headers.remove: invalid header name "Bad Header"`,
+	}, validationErrors(cfg))
+}
+
+func TestValidateProxyAccessLog(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"web": {Cmd: "npm run dev"},
+		},
+		Proxy: &ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.dev",
+			AccessLog: &AccessLogConfig{Enabled: true, Format: AccessLogFormatJSON}},
+	}
+	assert.NoError(t, Validate(cfg))
+	assert.Equal(t, "proxy", cfg.Proxy.AccessLog.ProcessOrDefault())
+
+	cfg.Proxy.AccessLog = &AccessLogConfig{Enabled: true, Format: "apache", Process: "web"}
+	assert.Equal(t, []string{
+		`proxy.access_log.format: must be "text" or "json", got "apache"`,
+		`proxy.access_log.process: "web" is also a process; set process to another name`,
+	}, validationErrors(cfg))
+
+	cfg.Proxy.AccessLog = &AccessLogConfig{Process: "proxy logs"}
+	assert.Equal(t, []string{
+		`proxy.access_log.process: cannot contain whitespace or path separators, got "proxy logs"`,
+	}, validationErrors(cfg))
+}
+
+func TestValidateProxyDNS(t *testing.T) {
+	cfg := &Config{
+		API:       APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{"web": {Cmd: "npm run dev"}},
+		Proxy:     &ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.dev", DNS: ProxyDNSBuiltin},
+	}
+	assert.NoError(t, Validate(cfg))
+	assert.True(t, cfg.Proxy.DNSBuiltin())
+	assert.Equal(t, 5354, cfg.Proxy.DNSPortOrDefault())
+
+	cfg.Proxy.DNS = "mdns"
+	cfg.Proxy.DNSPort = 70000
+	assert.Equal(t, []string{
+		`proxy.dns: must be "hosts" or "builtin", got "mdns"`,
+		"proxy.dns_port: must be between 0 and 65535, got 70000",
+	}, validationErrors(cfg))
+}
+
+func TestValidateTasks(t *testing.T) {
+	cfg := &Config{
+		API:       APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{"web": {Cmd: "npm run dev"}},
+		Tasks:     map[string]TaskConfig{"migrate": {Cmd: "bin/rails db:migrate"}},
+	}
+	assert.NoError(t, Validate(cfg))
+
+	cfg.Tasks = map[string]TaskConfig{"web": {Shell: " "}}
+	assert.Equal(t, []string{
+		"tasks.web.cmd: command is required",
+		"tasks.web.shell: must not be blank",
+		"tasks.web: a process has the same name",
+	}, validationErrors(cfg))
+}
+
+func TestIsLocalhostDomain(t *testing.T) {
+	assert.True(t, IsLocalhostDomain("localhost"))
+	assert.True(t, IsLocalhostDomain("prox.localhost"))
+	assert.True(t, IsLocalhostDomain("App.Localhost."))
+	assert.False(t, IsLocalhostDomain("local.myapp.dev"))
+	assert.False(t, IsLocalhostDomain("notlocalhost"))
+}
+
+func TestValidateServiceProcess(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"web": {Cmd: "npm run dev"},
+		},
+		Proxy: &ProxyConfig{
+			Enabled:  true,
+			HTTPPort: 6788,
+			Domain:   "local.dev",
+		},
+	}
+
+	t.Run("known process passes", func(t *testing.T) {
+		cfg.Services = map[string]ServiceConfig{
+			"app": {Port: 3000, Host: "localhost", Process: "web"},
+		}
+		assert.NoError(t, Validate(cfg))
+	})
+
+	t.Run("unknown process fails", func(t *testing.T) {
+		cfg.Services = map[string]ServiceConfig{
+			"app": {Port: 3000, Host: "localhost", Process: "missing"},
+		}
+		err := Validate(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "services.app.process")
+	})
+}
+
+func TestValidateProcessPort(t *testing.T) {
+	tests := []struct {
+		name    string
+		port    string
+		wantErr bool
+	}{
+		{"unset", "", false},
+		{"auto", "auto", false},
+		{"fixed", "8080", false},
+		{"zero", "0", true},
+		{"too large", "70000", true},
+		{"not a number", "random", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+				Processes: map[string]ProcessConfig{
+					"web": {Cmd: "npm run dev", Port: tt.port},
+				},
+			}
+			err := Validate(cfg)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "processes.web.port")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateServicePortFromProcess(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"api": {Cmd: "./server", Port: PortAuto},
+			"web": {Cmd: "npm run dev"},
+		},
+		Proxy: &ProxyConfig{
+			Enabled:  true,
+			HTTPPort: 6788,
+			Domain:   "local.dev",
+		},
+	}
+
+	t.Run("process with port", func(t *testing.T) {
+		cfg.Services = map[string]ServiceConfig{
+			"api": {Host: "localhost", Process: "api"},
+		}
+		assert.NoError(t, Validate(cfg))
+	})
+
+	t.Run("process without port", func(t *testing.T) {
+		cfg.Services = map[string]ServiceConfig{
+			"web": {Host: "localhost", Process: "web"},
+		}
+		err := Validate(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "services.web.port")
+	})
+}
+
+func TestValidateProcessWaitFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		waitFor []string
+		timeout string
+		wantErr string
+	}{
+		{"tcp", []string{"tcp://localhost:5432"}, "", ""},
+		{"http", []string{"http://localhost:9200/_cluster/health"}, "", ""},
+		{"https with timeout", []string{"https://example.com/health"}, "2m", ""},
+		{"tcp without port", []string{"tcp://localhost"}, "", "processes.web.wait_for[0]"},
+		{"unsupported scheme", []string{"tcp://localhost:5432", "redis://localhost:6379"}, "", "processes.web.wait_for[1]"},
+		{"bare host", []string{"localhost:5432"}, "", "processes.web.wait_for[0]"},
+		{"invalid timeout", []string{"tcp://localhost:5432"}, "soon", "processes.web.wait_timeout"},
+		{"zero timeout", []string{"tcp://localhost:5432"}, "0s", "processes.web.wait_timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+				Processes: map[string]ProcessConfig{
+					"web": {Cmd: "npm run dev", WaitFor: tt.waitFor, WaitTimeout: tt.timeout},
+				},
+			}
+			err := Validate(cfg)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateProcessDependsOn(t *testing.T) {
+	tests := []struct {
+		name      string
+		dependsOn []string
+		wantErr   string
+	}{
+		{"configured process", []string{"db"}, ""},
+		{"unknown process", []string{"db", "cache"}, `processes.web.depends_on[1]: unknown process "cache"`},
+		{"itself", []string{"web"}, "processes.web.depends_on[0]: a process can't depend on itself"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+				Processes: map[string]ProcessConfig{
+					"db":  {Cmd: "postgres"},
+					"web": {Cmd: "npm run dev", DependsOn: tt.dependsOn},
+				},
+			}
+			err := Validate(cfg)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateProcessReady(t *testing.T) {
+	tests := []struct {
+		name    string
+		proc    ProcessConfig
+		wantErr string
+	}{
+		{"tcp", ProcessConfig{Ready: "tcp://localhost:3000"}, ""},
+		{"http with port", ProcessConfig{Port: "auto", Ready: "http://localhost:$PORT/health", ReadyTimeout: "2m"}, ""},
+		{"braced port", ProcessConfig{Port: "3000", Ready: "tcp://127.0.0.1:${PORT}"}, ""},
+		{"port without a port", ProcessConfig{Ready: "http://localhost:$PORT/health"}, "processes.web.ready: uses $PORT"},
+		{"unsupported scheme", ProcessConfig{Port: "auto", Ready: "redis://localhost:$PORT"}, `processes.web.ready: unsupported scheme in "redis://localhost:$PORT"`},
+		{"timeout without ready", ProcessConfig{ReadyTimeout: "10s"}, "processes.web.ready_timeout: only valid with ready"},
+		{"invalid timeout", ProcessConfig{Ready: "tcp://localhost:3000", ReadyTimeout: "soon"}, "processes.web.ready_timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.proc.Cmd = "npm run dev"
+			cfg := &Config{
+				API:       APIConfig{Port: 5555, Host: "127.0.0.1"},
+				Processes: map[string]ProcessConfig{"web": tt.proc},
+			}
+			err := Validate(cfg)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestProcessConfig_ReadyTarget(t *testing.T) {
+	assert.Equal(t, "http://localhost:4000/health", ProcessConfig{Ready: "http://localhost:$PORT/health"}.ReadyTarget(4000))
+	assert.Equal(t, "tcp://localhost:4000", ProcessConfig{Ready: "tcp://localhost:${PORT}"}.ReadyTarget(4000))
+	assert.Equal(t, "tcp://localhost:3000", ProcessConfig{Ready: "tcp://localhost:3000"}.ReadyTarget(4000))
+}
+
+func TestValidateProblemMatchers(t *testing.T) {
+	tests := []struct {
+		name     string
+		matchers map[string]string
+		use      []string
+		wantErr  string
+	}{
+		{"builtin", nil, []string{"go", "tsc"}, ""},
+		{"defined", map[string]string{"rspec": `^rspec (?P<file>\S+):(?P<line>\d+)`}, []string{"rspec"}, ""},
+		{"unknown", nil, []string{"cobol"}, `processes.build.problem_matchers: unknown matcher "cobol"`},
+		{"invalid regex", map[string]string{"rspec": `(?P<file>`}, nil, "problem_matchers.rspec: invalid regex"},
+		{"missing line", map[string]string{"rspec": `^(?P<file>\S+)`}, nil, "problem_matchers.rspec: missing named group (?P<line>...)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				API:             APIConfig{Port: 5555, Host: "127.0.0.1"},
+				Processes:       map[string]ProcessConfig{"build": {Cmd: "make watch", ProblemMatchers: tt.use}},
+				ProblemMatchers: tt.matchers,
+			}
+			err := Validate(cfg)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_ProblemMatcherPatterns(t *testing.T) {
+	cfg := &Config{
+		Processes: map[string]ProcessConfig{
+			"build": {ProblemMatchers: []string{"go", "gcc"}},
+			"web":   {},
+		},
+		ProblemMatchers: map[string]string{"go": `^(?P<file>\S+\.go) line (?P<line>\d+)`},
+	}
+	patterns := cfg.ProblemMatcherPatterns()
+	assert.Equal(t, map[string][]string{
+		"build": {`^(?P<file>\S+\.go) line (?P<line>\d+)`, BuiltinProblemMatchers["gcc"]},
+	}, patterns)
+}
+
+func TestValidateRedactPatterns(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"web": {Cmd: "npm run dev"},
+		},
+		Redact: &RedactConfig{Patterns: []string{`acct_[0-9]+`}},
+	}
+	assert.NoError(t, Validate(cfg))
+
+	cfg.Redact.Patterns = append(cfg.Redact.Patterns, `user-(`)
+	err := Validate(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "redact.patterns[1]")
+}
+
+func TestValidateSupervisorConcurrency(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"web": {Cmd: "npm run dev"},
+		},
+		Supervisor: &SupervisorConfig{StartConcurrency: 2, StopConcurrency: 0},
+	}
+	assert.NoError(t, Validate(cfg))
+
+	cfg.Supervisor.StopConcurrency = -1
+	err := Validate(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "supervisor.stop_concurrency")
+}
+
+func TestValidateSupervisorCrashLoop(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"web": {Cmd: "npm run dev"},
+		},
+		Supervisor: &SupervisorConfig{CrashLimit: 3, CrashWindow: "30s"},
+	}
+	assert.NoError(t, Validate(cfg))
+	assert.Equal(t, 3, cfg.Supervisor.CrashLimitOrDefault())
+	assert.Equal(t, 30*time.Second, cfg.Supervisor.CrashWindowDuration())
+
+	cfg.Supervisor.CrashWindow = "0"
+	assert.NoError(t, Validate(cfg))
+	assert.Zero(t, cfg.Supervisor.CrashWindowDuration())
+
+	var unset *SupervisorConfig
+	assert.Equal(t, 5, unset.CrashLimitOrDefault())
+	assert.Equal(t, time.Minute, unset.CrashWindowDuration())
+
+	cfg.Supervisor = &SupervisorConfig{CrashLimit: -1, CrashWindow: "soon"}
+	err := Validate(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "supervisor.crash_limit: must be non-negative, got -1")
+	assert.Contains(t, err.Error(), `supervisor.crash_window: invalid duration "soon"`)
+}
+
+func TestValidateLogBufferSizes(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"web": {Cmd: "npm run dev", LogBuffer: 5000},
+		},
+		Logs: &LogsConfig{BufferSize: 2000, ProcessBufferSize: 100},
+	}
+	assert.NoError(t, Validate(cfg))
+
+	cfg.Logs.ProcessBufferSize = -1
+	cfg.Processes["web"] = ProcessConfig{Cmd: "npm run dev", LogBuffer: -1}
+	err := Validate(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "logs.process_buffer_size")
+	assert.Contains(t, err.Error(), "processes.web.log_buffer")
+}
+
+func TestValidateMaxLinesPerSec(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"web": {Cmd: "npm run dev", MaxLinesPerSec: 200, CollapseRepeats: true},
+		},
+	}
+	assert.NoError(t, Validate(cfg))
+
+	cfg.Processes["web"] = ProcessConfig{Cmd: "npm run dev", MaxLinesPerSec: -1}
+	err := Validate(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "processes.web.max_lines_per_sec: must be non-negative")
+}
+
+func TestValidateLogFormat(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"web": {Cmd: "npm run dev", LogFormat: "json"},
+			"api": {Cmd: "go run ./cmd/api", LogFormat: "logfmt"},
+		},
+	}
+	assert.NoError(t, Validate(cfg))
+
+	cfg.Processes["web"] = ProcessConfig{Cmd: "npm run dev", LogFormat: "yaml"}
+	err := Validate(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `processes.web.log_format: must be "json" or "logfmt", got "yaml"`)
+}
+
+func TestValidateSynthetic(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"noise": {Type: "synthetic"},
+			"load": {Type: "synthetic", Synthetic: &SyntheticConfig{
+				Rate: 5000, ExitAfter: "30s", CrashProbability: 0.1,
+			}},
+		},
+	}
+	assert.NoError(t, Validate(cfg))
+
+	cfg.Processes = map[string]ProcessConfig{
+		"noise": {Type: "synthetic", Cmd: "echo hi", Stdin: true, Synthetic: &SyntheticConfig{
+			Rate: -1, ExitAfter: "soon", CrashProbability: 1.5,
+		}},
+		"web":    {Cmd: "npm run dev", Synthetic: &SyntheticConfig{Rate: 1}},
+		"worker": {Type: "docker", Cmd: "run"},
+	}
+	err := Validate(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `processes.noise.cmd: cannot be combined with type "synthetic"`)
+	assert.Contains(t, err.Error(), `processes.noise.stdin: cannot be combined with type "synthetic"`)
+	assert.Contains(t, err.Error(), "processes.noise.synthetic.rate: must be non-negative, got -1")
+	assert.Contains(t, err.Error(), `processes.noise.synthetic.exit_after: invalid duration "soon"`)
+	assert.Contains(t, err.Error(), "processes.noise.synthetic.crash_probability: must be between 0 and 1, got 1.5")
+	assert.Contains(t, err.Error(), "processes.web.synthetic: only valid with type: synthetic")
+	assert.Contains(t, err.Error(), `processes.worker.type: must be "synthetic" or unset, got "docker"`)
+}
+
+func TestValidateCertsProvider(t *testing.T) {
+	cfg := &Config{
+		API:       APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{"web": {Cmd: "npm run dev"}},
+		Certs:     &CertsConfig{Dir: "~/.prox/certs", Provider: CertProviderSelfSigned},
+	}
+	assert.NoError(t, Validate(cfg))
+
+	cfg.Certs.Provider = "letsencrypt"
+	err := Validate(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "certs.provider")
+}
+
+func TestValidateEnvPrompt(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"web": {Cmd: "npm run dev", EnvPrompt: []string{"STRIPE_KEY"}},
+		},
+	}
+	assert.NoError(t, Validate(cfg))
+
+	cfg.Processes["web"] = ProcessConfig{Cmd: "npm run dev", EnvPrompt: []string{"STRIPE_KEY=sk_test"}}
+	err := Validate(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "processes.web.env_prompt")
+}
+
+func TestValidateDaemonIdle(t *testing.T) {
+	base := func() *Config {
+		return &Config{
+			API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+			Processes: map[string]ProcessConfig{
+				"web": {Cmd: "npm run dev"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		daemon  DaemonConfig
+		proxy   bool
+		wantErr string
+	}{
+		{name: "stop", daemon: DaemonConfig{IdleTimeout: "2h"}},
+		{name: "sleep with proxy", daemon: DaemonConfig{IdleTimeout: "30m", IdleAction: "sleep"}, proxy: true},
+		{name: "invalid duration", daemon: DaemonConfig{IdleTimeout: "soon"}, wantErr: "daemon.idle_timeout: invalid duration"},
+		{name: "negative duration", daemon: DaemonConfig{IdleTimeout: "-1h"}, wantErr: "daemon.idle_timeout: must be positive"},
+		{name: "unknown action", daemon: DaemonConfig{IdleTimeout: "1h", IdleAction: "hibernate"}, wantErr: "daemon.idle_action: must be"},
+		{name: "sleep without proxy", daemon: DaemonConfig{IdleTimeout: "1h", IdleAction: "sleep"}, wantErr: "requires the proxy"},
+		{name: "file and dir modes", daemon: DaemonConfig{FileMode: "0640", DirMode: "0750"}},
+		{name: "invalid file mode", daemon: DaemonConfig{FileMode: "rw-------"}, wantErr: "daemon.file_mode: invalid mode"},
+		{name: "file mode too large", daemon: DaemonConfig{FileMode: "01777"}, wantErr: "daemon.file_mode: invalid mode"},
+		{name: "file mode owner can't write", daemon: DaemonConfig{FileMode: "0400"}, wantErr: "daemon.file_mode: must give the owner at least 0600"},
+		{name: "dir mode owner can't list", daemon: DaemonConfig{DirMode: "0600"}, wantErr: "daemon.dir_mode: must give the owner at least 0700"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base()
+			cfg.Daemon = &tt.daemon
+			if tt.proxy {
+				cfg.Proxy = &ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.dev"}
+			}
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateLazyProcess(t *testing.T) {
+	tests := []struct {
+		name    string
+		proc    ProcessConfig
+		process string // Process linked to the "app" service
+		wantErr string
+	}{
+		{name: "lazy", proc: ProcessConfig{Cmd: "npm run dev", Lazy: true}, process: "web"},
+		{name: "lazy with idle timeout", proc: ProcessConfig{Cmd: "npm run dev", Lazy: true, IdleTimeout: "10m"}, process: "web"},
+		{name: "no service", proc: ProcessConfig{Cmd: "npm run dev", Lazy: true}, wantErr: "processes.web.lazy: no service has process: web"},
+		{name: "idle timeout without lazy", proc: ProcessConfig{Cmd: "npm run dev", IdleTimeout: "10m"}, process: "web", wantErr: "processes.web.idle_timeout: only valid with lazy: true"},
+		{name: "invalid idle timeout", proc: ProcessConfig{Cmd: "npm run dev", Lazy: true, IdleTimeout: "later"}, process: "web", wantErr: "processes.web.idle_timeout: invalid duration"},
+		{name: "negative idle timeout", proc: ProcessConfig{Cmd: "npm run dev", Lazy: true, IdleTimeout: "-1m"}, process: "web", wantErr: "processes.web.idle_timeout: must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				API:       APIConfig{Port: 5555, Host: "127.0.0.1"},
+				Processes: map[string]ProcessConfig{"web": tt.proc},
+			}
+			if tt.process != "" {
+				cfg.Proxy = &ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.dev"}
+				cfg.Services = map[string]ServiceConfig{"app": {Port: 3000, Host: "localhost", Process: tt.process}}
+			}
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateStorage(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage StorageConfig
+		wantErr string
+	}{
+		{name: "memory", storage: StorageConfig{Backend: "memory"}},
+		{name: "sqlite", storage: StorageConfig{Backend: "sqlite", Path: "prox.db", MaxLogs: 500, MaxRequests: 50}},
+		{name: "unknown backend", storage: StorageConfig{Backend: "postgres"}, wantErr: `storage.backend: must be "memory" or "sqlite", got "postgres"`},
+		{name: "negative max logs", storage: StorageConfig{Backend: "sqlite", MaxLogs: -1}, wantErr: "storage.max_logs: must be non-negative"},
+		{name: "path without sqlite", storage: StorageConfig{Path: "prox.db"}, wantErr: "storage.path: only valid with backend: sqlite"},
+		{name: "limits without sqlite", storage: StorageConfig{MaxRequests: 50}, wantErr: "storage: max_logs and max_requests are only valid with backend: sqlite"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				API:       APIConfig{Port: 5555, Host: "127.0.0.1"},
+				Processes: map[string]ProcessConfig{"web": {Cmd: "npm run dev"}},
+				Storage:   &tt.storage,
+			}
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateProxyHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		history ProxyHistoryConfig
+		storage *StorageConfig
+		wantErr string
+	}{
+		{name: "persist", history: ProxyHistoryConfig{Persist: true, Max: 200}},
+		{name: "max only", history: ProxyHistoryConfig{Max: 5000}},
+		{name: "negative max", history: ProxyHistoryConfig{Max: -1}, wantErr: "proxy.history.max: must be non-negative"},
+		{name: "persist with sqlite", history: ProxyHistoryConfig{Persist: true}, storage: &StorageConfig{Backend: "sqlite"}, wantErr: "proxy.history.persist: the sqlite storage backend already keeps the request history"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				API:       APIConfig{Port: 5555, Host: "127.0.0.1"},
+				Processes: map[string]ProcessConfig{"web": {Cmd: "npm run dev"}},
+				Proxy:     &ProxyConfig{History: &tt.history},
+				Storage:   tt.storage,
+			}
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateProxyRestartWait(t *testing.T) {
+	tests := []struct {
+		wait    string
+		wantErr string
+	}{
+		{wait: "10s"},
+		{wait: "0"},
+		{wait: "soon", wantErr: `proxy.restart_wait: invalid duration "soon"`},
+		{wait: "-1s", wantErr: `proxy.restart_wait: must be non-negative, got "-1s"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wait, func(t *testing.T) {
+			cfg := &Config{
+				API:       APIConfig{Port: 5555, Host: "127.0.0.1"},
+				Processes: map[string]ProcessConfig{"web": {Cmd: "npm run dev"}},
+				Proxy:     &ProxyConfig{RestartWait: tt.wait},
+			}
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	assert.Equal(t, 30*time.Second, (*ProxyConfig)(nil).RestartWaitDuration())
+	assert.Equal(t, time.Duration(0), (&ProxyConfig{RestartWait: "0"}).RestartWaitDuration())
+}
+
+func TestValidateTheme(t *testing.T) {
+	tests := []struct {
+		name    string
+		theme   ThemeConfig
+		wantErr string
+	}{
+		{name: "full", theme: ThemeConfig{Background: "light", Clock: "12h", Colors: map[string]string{"web": "cyan", "api": "#ff8800", "worker": "208"}, NoColor: true}},
+		{name: "bad background", theme: ThemeConfig{Background: "solarized"}, wantErr: `theme.background: must be "dark" or "light", got "solarized"`},
+		{name: "bad clock", theme: ThemeConfig{Clock: "relative"}, wantErr: `theme.clock: must be "24h" or "12h", got "relative"`},
+		{name: "bad color", theme: ThemeConfig{Colors: map[string]string{"web": "teal"}}, wantErr: "theme.colors.web: must be a color name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				API:       APIConfig{Port: 5555, Host: "127.0.0.1"},
+				Processes: map[string]ProcessConfig{"web": {Cmd: "npm run dev"}},
+				Theme:     &tt.theme,
+			}
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateNotifications(t *testing.T) {
+	tests := []struct {
+		name          string
+		notifications NotificationsConfig
+		wantErr       string
+	}{
+		{name: "desktop", notifications: NotificationsConfig{Desktop: true}},
+		{name: "all", notifications: NotificationsConfig{Desktop: true, Command: "true", Webhook: "http://localhost:9000/hook", Events: []string{"process_healthy"}}},
+		{name: "nothing to send", notifications: NotificationsConfig{Events: []string{"process_crashed"}}, wantErr: "notifications: set at least one of desktop, command, or webhook"},
+		{name: "bad webhook", notifications: NotificationsConfig{Webhook: "hooks.example.com"}, wantErr: `notifications.webhook: must be an http(s) URL, got "hooks.example.com"`},
+		{name: "unknown event", notifications: NotificationsConfig{Desktop: true, Events: []string{"process_started"}}, wantErr: `notifications.events: must be "process_crashed", "process_failed", "process_unhealthy", or "process_healthy", got "process_started"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				API:           APIConfig{Port: 5555, Host: "127.0.0.1"},
+				Processes:     map[string]ProcessConfig{"web": {Cmd: "npm run dev"}},
+				Notifications: &tt.notifications,
+			}
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateWatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		proc    ProcessConfig
+		wantErr string
+	}{
+		{name: "watch", proc: ProcessConfig{Cmd: "go run .", Watch: []string{"**/*.go"}, WatchIgnore: []string{"*_test.go"}, WatchDebounce: "500ms"}},
+		{name: "bad pattern", proc: ProcessConfig{Cmd: "go run .", Watch: []string{"*.go", "src/[.go"}}, wantErr: "processes.web.watch[1]: syntax error in pattern"},
+		{name: "absolute pattern", proc: ProcessConfig{Cmd: "go run .", Watch: []string{"/src/*.go"}}, wantErr: "processes.web.watch[0]: must be relative to the config file's directory"},
+		{name: "outside pattern", proc: ProcessConfig{Cmd: "go run .", Watch: []string{"*.go"}, WatchIgnore: []string{"../vendor"}}, wantErr: "processes.web.watch_ignore[0]: can't reach outside the config file's directory"},
+		{name: "ignore without watch", proc: ProcessConfig{Cmd: "go run .", WatchIgnore: []string{"tmp"}}, wantErr: "processes.web.watch_ignore: only valid with watch"},
+		{name: "debounce without watch", proc: ProcessConfig{Cmd: "go run .", WatchDebounce: "1s"}, wantErr: "processes.web.watch_debounce: only valid with watch"},
+		{name: "invalid debounce", proc: ProcessConfig{Cmd: "go run .", Watch: []string{"*.go"}, WatchDebounce: "soon"}, wantErr: `processes.web.watch_debounce: invalid duration "soon"`},
+		{name: "negative debounce", proc: ProcessConfig{Cmd: "go run .", Watch: []string{"*.go"}, WatchDebounce: "-1s"}, wantErr: "processes.web.watch_debounce: must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				API:       APIConfig{Port: 5555, Host: "127.0.0.1"},
+				Processes: map[string]ProcessConfig{"web": tt.proc},
+			}
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateLogsMaxMemory(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"web": {Cmd: "npm run dev"},
+		},
+		Logs: &LogsConfig{MaxMemory: "64MB"},
+	}
+	assert.NoError(t, Validate(cfg))
+	assert.Equal(t, int64(64*1024*1024), cfg.Logs.MaxMemoryBytes())
+
+	cfg.Logs.MaxMemory = "lots"
+	err := Validate(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "logs.max_memory: invalid size")
+	assert.Zero(t, cfg.Logs.MaxMemoryBytes())
+}
+
+func TestValidateCaptureRules(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555, Host: "127.0.0.1"},
+		Processes: map[string]ProcessConfig{
+			"web": {Cmd: "npm run dev"},
+		},
+		Proxy: &ProxyConfig{
+			Enabled:  true,
+			HTTPPort: 6788,
+			Domain:   "local.myapp.dev",
+			Capture: &CaptureConfig{
+				Enabled: true,
+				Include: []CaptureRule{{Subdomain: "api", Methods: []string{"POST"}}},
+				Exclude: []CaptureRule{{Path: "^/static/"}, {ContentType: "image/*"}, {WebSocket: true}},
+			},
+		},
+	}
+	assert.NoError(t, Validate(cfg))
+
+	cfg.Proxy.Capture.MaxBodySize = "big"
+	cfg.Proxy.Capture.Include = []CaptureRule{{}, {Subdomain: "pr-["}}
+	cfg.Proxy.Capture.Exclude = []CaptureRule{{Path: "("}, {ContentType: "image"}, {Methods: []string{"GET POST"}}}
+	err := Validate(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "proxy.capture.max_body_size: invalid size")
+	assert.Contains(t, err.Error(), "proxy.capture.include[0]: must set subdomain, path, methods, content_type, or websocket")
+	assert.Contains(t, err.Error(), "proxy.capture.include[1].subdomain: invalid pattern")
+	assert.Contains(t, err.Error(), "proxy.capture.exclude[0].path: invalid regex")
+	assert.Contains(t, err.Error(), "proxy.capture.exclude[1].content_type: must be a media type")
+	assert.Contains(t, err.Error(), "proxy.capture.exclude[2].methods: invalid method")
 }
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Finding levels
+const (
+	FindingError   = "error"   // The config won't work as written
+	FindingWarning = "warning" // Possibly a problem, e.g. a command not on PATH here
+)
+
+// Finding is a problem in a configuration reported by Verify
+type Finding struct {
+	Level   string `json:"level"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// shellBuiltins are command words that aren't looked up on PATH
+var shellBuiltins = map[string]bool{
+	".": true, ":": true, "[": true, "cd": true, "echo": true, "eval": true,
+	"exec": true, "exit": true, "export": true, "false": true, "printf": true,
+	"read": true, "set": true, "source": true, "test": true, "trap": true,
+	"true": true, "ulimit": true, "umask": true, "unset": true, "wait": true,
+}
+
+// Verify checks the configuration file at path more thoroughly than loading
+// it: besides validation errors, it reports env files that don't exist,
+// command executables that aren't on PATH, and colliding ports. Findings are
+// sorted by field. An error is returned only if the file can't be read.
+func Verify(path string) ([]Finding, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config *Config
+	if IsProcfile(path) {
+		config, err = parseProcfile(data, filepath.Dir(path))
+	} else {
+		config, err = parse(data)
+	}
+	if err != nil {
+		return []Finding{{Level: FindingError, Field: "config", Message: err.Error()}}, nil
+	}
+
+	var findings []Finding
+	for _, e := range validationErrors(config) {
+		field, message, _ := strings.Cut(e, ": ")
+		findings = append(findings, Finding{Level: FindingError, Field: field, Message: message})
+	}
+
+	configDir := filepath.Dir(path)
+	findings = append(findings, verifyEnvFiles(config, configDir)...)
+	findings = append(findings, verifyCommands(config, configDir)...)
+	findings = append(findings, verifyPorts(config)...)
+
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Field < findings[j].Field })
+	return findings, nil
+}
+
+// verifyEnvFiles reports env files that don't exist
+func verifyEnvFiles(config *Config, configDir string) []Finding {
+	var findings []Finding
+	check := func(field, path string) {
+		if path == "" {
+			return
+		}
+		if _, err := os.Stat(resolvePath(path, configDir)); err != nil {
+			findings = append(findings, Finding{Level: FindingError, Field: field, Message: fmt.Sprintf("env file not found: %s", path)})
+		}
+	}
+
+	check("env_file", config.EnvFile)
+	for name, proc := range config.Processes {
+		check(fmt.Sprintf("processes.%s.env_file", name), proc.EnvFile)
+	}
+	for name, task := range config.Tasks {
+		check(fmt.Sprintf("tasks.%s.env_file", name), task.EnvFile)
+	}
+	return findings
+}
+
+// verifyCommands reports commands whose executable can't be found. This is
+// best-effort: only the first word of a command is checked, and commands
+// starting with shell syntax are skipped, as are commands run through a
+// configured shell or direnv, which may set up PATH themselves.
+func verifyCommands(config *Config, configDir string) []Finding {
+	var findings []Finding
+	check := func(field, cmd string) {
+		executable := commandExecutable(cmd)
+		if executable == "" {
+			return
+		}
+		if strings.Contains(executable, "/") {
+			info, err := os.Stat(resolvePath(executable, configDir))
+			if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+				findings = append(findings, Finding{Level: FindingWarning, Field: field, Message: fmt.Sprintf("%s is not an executable file", executable)})
+			}
+			return
+		}
+		if _, err := exec.LookPath(executable); err != nil {
+			findings = append(findings, Finding{Level: FindingWarning, Field: field, Message: fmt.Sprintf("%s not found on PATH", executable)})
+		}
+	}
+
+	check("shell", config.Shell)
+	for name, proc := range config.Processes {
+		check(fmt.Sprintf("processes.%s.shell", name), proc.Shell)
+		if config.DirenvFor(proc) {
+			check(fmt.Sprintf("processes.%s.direnv", name), "direnv")
+		} else if proc.Shell == "" && config.Shell == "" && proc.Type != ProcessTypeSynthetic {
+			check(fmt.Sprintf("processes.%s.cmd", name), proc.Cmd)
+		}
+		if proc.Healthcheck != nil {
+			check(fmt.Sprintf("processes.%s.healthcheck.cmd", name), proc.Healthcheck.Cmd)
+		}
+	}
+	for name, task := range config.Tasks {
+		check(fmt.Sprintf("tasks.%s.shell", name), task.Shell)
+		if config.DirenvFor(task.ProcessConfig()) {
+			check(fmt.Sprintf("tasks.%s.direnv", name), "direnv")
+		} else if task.Shell == "" && config.Shell == "" {
+			check(fmt.Sprintf("tasks.%s.cmd", name), task.Cmd)
+		}
+	}
+	return findings
+}
+
+// commandExecutable returns the executable a shell command runs, skipping
+// leading variable assignments, or "" if it can't tell
+func commandExecutable(cmd string) string {
+	for _, word := range strings.Fields(cmd) {
+		if name, _, ok := strings.Cut(word, "="); ok && name != "" && !strings.ContainsAny(name, "/$") {
+			continue
+		}
+		if shellBuiltins[word] || strings.ContainsAny(word, "$`'\"(){}<>|&;*?~") {
+			return ""
+		}
+		return word
+	}
+	return ""
+}
+
+// localHosts are the service hosts that reach this machine
+var localHosts = map[string]bool{"localhost": true, "127.0.0.1": true, "::1": true, "0.0.0.0": true}
+
+// ListenPorts returns the fixed ports prox up binds or starts processes on,
+// mapped to the field declaring each. Processes without a fixed port, or
+// declaring a port already taken, are left out.
+func (c *Config) ListenPorts() map[int]string {
+	ports := map[int]string{c.API.Port: "api.port"}
+	if c.Proxy != nil && c.Proxy.Enabled {
+		if c.Proxy.HTTPPort > 0 {
+			ports[c.Proxy.HTTPPort] = "proxy.http_port"
+		}
+		if c.Proxy.HTTPSPort > 0 {
+			ports[c.Proxy.HTTPSPort] = "proxy.https_port"
+		}
+	}
+	names := make([]string, 0, len(c.Processes))
+	for name := range c.Processes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		port := c.Processes[name].FixedPort()
+		if _, taken := ports[port]; port > 0 && !taken {
+			ports[port] = fmt.Sprintf("processes.%s.port", name)
+		}
+	}
+	return ports
+}
+
+// verifyPorts reports ports that more than one listener would bind, and
+// services pointing at a port declared by a process other than theirs
+func verifyPorts(config *Config) []Finding {
+	var findings []Finding
+
+	// Ports prox itself listens on
+	owners := map[int]string{config.API.Port: "api.port"}
+	if config.Proxy != nil && config.Proxy.Enabled {
+		if config.Proxy.HTTPPort > 0 {
+			owners[config.Proxy.HTTPPort] = "proxy.http_port"
+		}
+		if config.Proxy.HTTPSPort > 0 {
+			owners[config.Proxy.HTTPSPort] = "proxy.https_port"
+		}
+	}
+
+	// Check processes in name order so the reported owner is stable
+	names := make([]string, 0, len(config.Processes))
+	for name := range config.Processes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	processPorts := make(map[int]string)
+	for _, name := range names {
+		port := config.Processes[name].FixedPort()
+		if port <= 0 {
+			continue
+		}
+		field := fmt.Sprintf("processes.%s.port", name)
+		if owner, ok := owners[port]; ok {
+			findings = append(findings, Finding{Level: FindingError, Field: field, Message: fmt.Sprintf("port %d is also used by %s", port, owner)})
+			continue
+		}
+		owners[port] = field
+		processPorts[port] = name
+	}
+
+	for name, svc := range config.Services {
+		// Ports on other hosts can't collide with local ones
+		if svc.Port <= 0 || !localHosts[svc.Host] {
+			continue
+		}
+		field := fmt.Sprintf("services.%s.port", name)
+		if owner, ok := owners[svc.Port]; ok && !strings.HasPrefix(owner, "processes.") {
+			findings = append(findings, Finding{Level: FindingError, Field: field, Message: fmt.Sprintf("port %d is prox's own %s", svc.Port, owner)})
+			continue
+		}
+		if svc.Process == "" {
+			continue
+		}
+		// The proxy follows the port of a linked process that has one
+		if linked := config.Processes[svc.Process]; linked.Port != "" {
+			if linked.FixedPort() != svc.Port {
+				findings = append(findings, Finding{Level: FindingWarning, Field: field, Message: fmt.Sprintf("ignored: requests go to the port of process %s", svc.Process)})
+			}
+		} else if process, ok := processPorts[svc.Port]; ok {
+			findings = append(findings, Finding{Level: FindingError, Field: field, Message: fmt.Sprintf("port %d is declared by process %s, but the service is served by %s", svc.Port, process, svc.Process)})
+		}
+	}
+	return findings
+}
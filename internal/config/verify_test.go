@@ -0,0 +1,168 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfig writes a prox.yaml with the given contents to a temp dir
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prox.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func TestVerify_Clean(t *testing.T) {
+	path := writeConfig(t, `
+env_file: .env
+processes:
+  web:
+    cmd: PORT=3000 sh -c "echo hi"
+    port: 3000
+  noise:
+    type: synthetic
+proxy:
+  http_port: 6788
+  domain: local.dev
+services:
+  app: 3000
+`)
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), ".env"), []byte("FOO=bar\n"), 0644))
+
+	findings, err := Verify(path)
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestVerify_ReportsAllFindings(t *testing.T) {
+	path := writeConfig(t, `
+processes:
+  web:
+    cmd: prox-no-such-command --serve
+    port: 3000
+    env_file: web.env
+  api:
+    cmd: ./bin/api
+    port: 3000
+  worker:
+    cmd: sh worker.sh
+    wait_timeout: soon
+proxy:
+  http_port: 6788
+  domain: local.dev
+services:
+  app:
+    port: 3000
+    process: worker
+  self: 6788
+`)
+
+	findings, err := Verify(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Finding{
+		{Level: FindingWarning, Field: "processes.api.cmd", Message: "./bin/api is not an executable file"},
+		{Level: FindingWarning, Field: "processes.web.cmd", Message: "prox-no-such-command not found on PATH"},
+		{Level: FindingError, Field: "processes.web.env_file", Message: "env file not found: web.env"},
+		{Level: FindingError, Field: "processes.web.port", Message: "port 3000 is also used by processes.api.port"},
+		{Level: FindingError, Field: "processes.worker.wait_timeout", Message: `invalid duration "soon"`},
+		{Level: FindingError, Field: "services.app.port", Message: "port 3000 is declared by process api, but the service is served by worker"},
+		{Level: FindingError, Field: "services.self.port", Message: "port 6788 is prox's own proxy.http_port"},
+	}, findings)
+}
+
+func TestVerify_LinkedServicePort(t *testing.T) {
+	path := writeConfig(t, `
+processes:
+  web:
+    cmd: sh serve.sh
+    port: 3000
+proxy:
+  http_port: 6788
+  domain: local.dev
+services:
+  app:
+    port: 4000
+    process: web
+`)
+
+	findings, err := Verify(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Finding{
+		{Level: FindingWarning, Field: "services.app.port", Message: "ignored: requests go to the port of process web"},
+	}, findings)
+}
+
+func TestVerify_Shell(t *testing.T) {
+	path := writeConfig(t, `
+shell: /nonexistent/zsh -l
+processes:
+  web:
+    cmd: prox-no-such-command --serve
+  api:
+    cmd: prox-no-such-command --serve
+    shell: sh
+    direnv: true
+`)
+
+	findings, err := Verify(path)
+	require.NoError(t, err)
+	// Commands run through a configured shell or direnv aren't checked
+	names := make([]string, len(findings))
+	for i, f := range findings {
+		names[i] = f.Field
+	}
+	assert.Contains(t, names, "shell")
+	assert.NotContains(t, names, "processes.web.cmd")
+	assert.NotContains(t, names, "processes.api.cmd")
+	assert.NotContains(t, names, "processes.api.shell")
+}
+
+func TestVerify_UnparseableConfig(t *testing.T) {
+	findings, err := Verify(writeConfig(t, "processes: [\n"))
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, FindingError, findings[0].Level)
+	assert.Equal(t, "config", findings[0].Field)
+
+	_, err = Verify(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestConfig_ListenPorts(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{Port: 5555},
+		Processes: map[string]ProcessConfig{
+			"api":    {Cmd: "run", Port: "3000"},
+			"web":    {Cmd: "run", Port: "3000"},
+			"worker": {Cmd: "run", Port: "auto"},
+		},
+		Proxy: &ProxyConfig{Enabled: true, HTTPSPort: 6789},
+	}
+	assert.Equal(t, map[int]string{
+		5555: "api.port",
+		6789: "proxy.https_port",
+		3000: "processes.api.port",
+	}, cfg.ListenPorts())
+}
+
+func TestCommandExecutable(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"npm run dev", "npm"},
+		{"FOO=1 BAR=2 ./bin/server --port 3000", "./bin/server"},
+		{"cd web && npm start", ""},
+		{"$HOME/bin/server", ""},
+		{"\"my server\" --flag", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, commandExecutable(tt.cmd), "cmd: %q", tt.cmd)
+	}
+}
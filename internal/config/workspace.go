@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/domain"
+)
+
+// Workspace groups several prox projects so they can be operated together
+type Workspace struct {
+	Projects []WorkspaceProject // Sorted by name
+}
+
+// WorkspaceProject is a single member project of a workspace
+type WorkspaceProject struct {
+	Name   string // Name used to prefix status rows and log lines
+	Dir    string // Project directory, where prox keeps its .prox state
+	Config string // Path to the project's prox config file
+}
+
+// rawWorkspace is used for initial YAML parsing.
+// Each project maps a name to a project directory or config file path.
+type rawWorkspace struct {
+	Projects map[string]string `yaml:"projects"`
+}
+
+// LoadWorkspace loads a workspace file. Project paths are resolved relative
+// to the directory containing the workspace file.
+func LoadWorkspace(path string) (*Workspace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", domain.ErrConfigNotFound, path)
+		}
+		return nil, fmt.Errorf("reading workspace file: %w", err)
+	}
+
+	baseDir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("resolving workspace directory: %w", err)
+	}
+
+	return ParseWorkspace(data, baseDir)
+}
+
+// ParseWorkspace parses a workspace from YAML bytes, resolving relative
+// project paths against baseDir. A project path ending in .yaml or .yml is
+// a config file; anything else is a directory containing prox.yaml.
+func ParseWorkspace(data []byte, baseDir string) (*Workspace, error) {
+	var raw rawWorkspace
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing yaml: %w", err)
+	}
+
+	var errs []string
+	if len(raw.Projects) == 0 {
+		errs = append(errs, "projects: at least one project must be defined")
+	}
+
+	ws := &Workspace{}
+	for name, path := range raw.Projects {
+		if err := ValidateProcessName(name); err != nil {
+			errs = append(errs, fmt.Sprintf("projects.%s: project name cannot contain whitespace or path separators", name))
+			continue
+		}
+		if path == "" {
+			errs = append(errs, fmt.Sprintf("projects.%s: path is required", name))
+			continue
+		}
+
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		project := WorkspaceProject{Name: name, Dir: path, Config: filepath.Join(path, constants.DefaultConfigFile)}
+		if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+			project.Dir = filepath.Dir(path)
+			project.Config = path
+		}
+		ws.Projects = append(ws.Projects, project)
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, fmt.Errorf("%w: %s", domain.ErrInvalidConfig, strings.Join(errs, "; "))
+	}
+
+	sort.Slice(ws.Projects, func(i, j int) bool {
+		return ws.Projects[i].Name < ws.Projects[j].Name
+	})
+	return ws, nil
+}
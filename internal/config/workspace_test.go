@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/prox/internal/domain"
+)
+
+func TestParseWorkspace(t *testing.T) {
+	ws, err := ParseWorkspace([]byte(`
+projects:
+  web: ../web-frontend/prox.yaml
+  api: ../api-service
+  tools: /opt/tools
+`), "/src/workspace")
+	require.NoError(t, err)
+	require.Len(t, ws.Projects, 3)
+
+	// Projects are sorted by name
+	assert.Equal(t, WorkspaceProject{Name: "api", Dir: "/src/api-service", Config: "/src/api-service/prox.yaml"}, ws.Projects[0])
+	assert.Equal(t, WorkspaceProject{Name: "tools", Dir: "/opt/tools", Config: "/opt/tools/prox.yaml"}, ws.Projects[1])
+	assert.Equal(t, WorkspaceProject{Name: "web", Dir: "/src/web-frontend", Config: "/src/web-frontend/prox.yaml"}, ws.Projects[2])
+}
+
+func TestParseWorkspace_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"no projects", "projects: {}", "projects: at least one project"},
+		{"empty path", "projects:\n  api: \"\"", "projects.api: path is required"},
+		{"bad name", "projects:\n  \"my api\": ../api", "projects.my api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWorkspace([]byte(tt.yaml), "/src")
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, domain.ErrInvalidConfig))
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prox-workspace.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("projects:\n  api: api\n"), 0600))
+
+	ws, err := LoadWorkspace(path)
+	require.NoError(t, err)
+	require.Len(t, ws.Projects, 1)
+	assert.Equal(t, filepath.Join(dir, "api"), ws.Projects[0].Dir)
+
+	_, err = LoadWorkspace(filepath.Join(dir, "missing.yaml"))
+	assert.True(t, errors.Is(err, domain.ErrConfigNotFound))
+}
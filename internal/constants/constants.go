@@ -8,6 +8,9 @@ const (
 	// DefaultConfigFile is the default configuration filename
 	DefaultConfigFile = "prox.yaml"
 
+	// DefaultWorkspaceFile is the default workspace filename for prox ws commands
+	DefaultWorkspaceFile = "prox-workspace.yaml"
+
 	// DefaultAPIHost is the default host for the API server
 	DefaultAPIHost = "127.0.0.1"
 
@@ -17,11 +20,30 @@ const (
 	// DefaultAPIAddress is the default API address for client connections
 	DefaultAPIAddress = "http://127.0.0.1:5555"
 
+	// APITokenEnv is the environment variable that gives CLI commands the
+	// API token, in place of ~/.prox/token
+	APITokenEnv = "PROX_TOKEN"
+
 	// DefaultProxyPort is the default port for the HTTPS reverse proxy
 	DefaultProxyPort = 6789
 
+	// DefaultProxyAPISubdomain is the reserved subdomain for serving the API through the proxy
+	DefaultProxyAPISubdomain = "prox"
+
+	// DefaultDNSPort is the default port of the built-in DNS responder. It's
+	// unprivileged so prox doesn't need root to bind it.
+	DefaultDNSPort = 5354
+
+	// DefaultAccessLogProcess is the process name proxy access log lines are
+	// logged under
+	DefaultAccessLogProcess = "proxy"
+
 	// DefaultCertsDir is the default directory for storing certificates
 	DefaultCertsDir = "~/.prox/certs"
+
+	// DefaultShell is the shell process commands run through unless the
+	// config sets shell
+	DefaultShell = "sh"
 )
 
 // Timeout and duration defaults
@@ -31,6 +53,100 @@ const (
 
 	// DefaultShutdownTimeout is the default timeout for graceful shutdown
 	DefaultShutdownTimeout = 10 * time.Second
+
+	// DefaultReadyTimeout is the maximum time to wait for a new process
+	// instance to become ready during a blue-green restart
+	DefaultReadyTimeout = 20 * time.Second
+
+	// ReadyPollInterval is how often readiness is checked during a blue-green
+	// restart, and how often ready probes are run
+	ReadyPollInterval = 250 * time.Millisecond
+
+	// DefaultProcessReadyTimeout is how long a process's ready probe may fail
+	// before the process is reported as not ready
+	DefaultProcessReadyTimeout = 60 * time.Second
+
+	// DaemonStartTimeout is how long prox up --detach waits for the daemon
+	// to report that its API is listening
+	DaemonStartTimeout = 30 * time.Second
+
+	// DefaultUpWaitTimeout is how long prox up --wait waits for processes to
+	// become ready
+	DefaultUpWaitTimeout = 2 * time.Minute
+
+	// UpWaitPollInterval is how often prox up --wait checks the processes
+	UpWaitPollInterval = 500 * time.Millisecond
+
+	// DefaultWaitForTimeout is the maximum time to wait for a process's
+	// wait_for dependencies before giving up on starting it
+	DefaultWaitForTimeout = 60 * time.Second
+
+	// WaitForPollInterval is how often wait_for dependencies are probed
+	WaitForPollInterval = time.Second
+
+	// WaitForProbeTimeout is the timeout for a single wait_for probe
+	WaitForProbeTimeout = 2 * time.Second
+
+	// WorkspaceStartupTimeout is how long prox ws up waits for each project's
+	// daemon to write its state file
+	WorkspaceStartupTimeout = 10 * time.Second
+
+	// WorkspaceStartupPollInterval is how often prox ws up checks for the state file
+	WorkspaceStartupPollInterval = 100 * time.Millisecond
+
+	// SSHTunnelTimeout is how long to wait for an --ssh port forward to come up
+	SSHTunnelTimeout = 10 * time.Second
+
+	// SSHTunnelPollInterval is how often the --ssh port forward is checked
+	SSHTunnelPollInterval = 100 * time.Millisecond
+
+	// LazyStartTimeout is how long a proxied request waits for a lazy process
+	// to start and become ready
+	LazyStartTimeout = 30 * time.Second
+
+	// DefaultProxyRestartWait is how long a proxied request waits for a
+	// restarting process to come back before it is forwarded anyway
+	DefaultProxyRestartWait = 30 * time.Second
+
+	// DefaultCrashLimit is how many times a process may crash within
+	// DefaultCrashWindow before it is marked failed and no longer restarted
+	DefaultCrashLimit = 5
+
+	// DefaultCrashWindow is the window crashes are counted in
+	DefaultCrashWindow = time.Minute
+
+	// DefaultWatchDebounce is how long a watched process waits after a file
+	// change for more changes before restarting
+	DefaultWatchDebounce = 300 * time.Millisecond
+
+	// WatchdogInterval is how often tracked stream goroutines are checked for leaks
+	WatchdogInterval = 30 * time.Second
+
+	// WatchdogStallTimeout is how long a stream subscriber may go without
+	// draining its channel before it is considered leaked
+	WatchdogStallTimeout = 2 * time.Minute
+
+	// SSEKeepaliveInterval is how often a quiet event stream sends a comment,
+	// so clients don't take it for a dead connection
+	SSEKeepaliveInterval = 20 * time.Second
+)
+
+// Supervisor event configuration
+const (
+	// SupervisorEventHistory is the number of recent supervisor events kept
+	// for GET /events backfill
+	SupervisorEventHistory = 100
+)
+
+// Notification configuration
+const (
+	// NotificationCooldown is how long repeats of a notification for the same
+	// process and event are suppressed, so a crash loop doesn't flood the
+	// desktop
+	NotificationCooldown = time.Minute
+
+	// NotificationTimeout bounds a notification command or webhook request
+	NotificationTimeout = 10 * time.Second
 )
 
 // Log configuration
@@ -41,6 +157,22 @@ const (
 	// MaxLogLines is the maximum number of log lines that can be requested
 	// to prevent memory exhaustion (DoS protection)
 	MaxLogLines = 10000
+
+	// DefaultSyntheticRate is the lines per second a synthetic process
+	// writes when its rate isn't set
+	DefaultSyntheticRate = 10
+
+	// SyntheticTickInterval is the shortest interval at which a synthetic
+	// process writes; faster rates write several lines per tick
+	SyntheticTickInterval = 10 * time.Millisecond
+
+	// LogCloseFlushTimeout is how long closing the log manager waits for
+	// subscribers to receive the entries already sent to them
+	LogCloseFlushTimeout = 250 * time.Millisecond
+
+	// LogCloseFlushInterval is how often subscribers are checked while the
+	// log manager waits for them
+	LogCloseFlushInterval = 5 * time.Millisecond
 )
 
 // Proxy request configuration
@@ -51,6 +183,31 @@ const (
 	// MaxProxyRequests is the maximum number of proxy requests that can be requested
 	// to prevent memory exhaustion (DoS protection)
 	MaxProxyRequests = 1000
+
+	// HealthcheckUserAgent identifies proxy healthcheck requests, which are
+	// neither recorded nor counted as activity
+	HealthcheckUserAgent = "prox-healthcheck"
+
+	// DefaultProxyStatsWindow is the rolling window used for per-service latency stats
+	DefaultProxyStatsWindow = 5 * time.Minute
+
+	// ProxyStatsEventErrorWindow is how long after a process event 5xx
+	// responses are attributed to it in the stats
+	ProxyStatsEventErrorWindow = 30 * time.Second
+
+	// DefaultProxyStatsCheckInterval is how often latency budgets are evaluated
+	DefaultProxyStatsCheckInterval = 10 * time.Second
+
+	// DefaultSLOViolationPeriod is how long a service must stay over its latency
+	// budget before a violation event is emitted
+	DefaultSLOViolationPeriod = 1 * time.Minute
+
+	// DefaultDrainTimeout is the maximum time to wait for in-flight proxy
+	// requests to finish when draining a process
+	DefaultDrainTimeout = 15 * time.Second
+
+	// DrainPollInterval is how often in-flight requests are checked while draining
+	DrainPollInterval = 50 * time.Millisecond
 )
 
 // Buffer sizes
@@ -58,6 +215,10 @@ const (
 	// DefaultLogBufferSize is the default size for log buffers
 	DefaultLogBufferSize = 1000
 
+	// DefaultLogProcessBufferSize is the default number of log entries
+	// reserved for each process, beyond the reach of other processes' output
+	DefaultLogProcessBufferSize = 200
+
 	// DefaultSubscriptionBuffer is the default size for subscription buffers
 	DefaultSubscriptionBuffer = 100
 
@@ -69,6 +230,35 @@ const (
 
 	// DefaultProxyRequestBufferSize is the default number of proxy requests to keep in memory
 	DefaultProxyRequestBufferSize = 1000
+
+	// DefaultStorageMaxLogs is the default number of log entries kept by
+	// the sqlite storage backend
+	DefaultStorageMaxLogs = 100_000
+
+	// DefaultStorageMaxRequests is the default number of proxy requests
+	// kept by the sqlite storage backend
+	DefaultStorageMaxRequests = 10_000
+
+	// DefaultStorageFileName is the default name of the sqlite storage
+	// backend's database, in the state directory
+	DefaultStorageFileName = "history.db"
+)
+
+// Log query budgets
+const (
+	// DefaultLogQueryMaxScanned is the most log entries a single query may examine
+	DefaultLogQueryMaxScanned = 100_000
+
+	// DefaultLogQueryMaxDuration is the longest a single log query may run
+	DefaultLogQueryMaxDuration = 200 * time.Millisecond
+
+	// DefaultLogQueryScanRate is the sustained rate, in entries per second,
+	// at which queries may scan log entries across all clients
+	DefaultLogQueryScanRate = 500_000
+
+	// DefaultLogQueryScanBurst is how many entries may be scanned in a burst
+	// before queries are held to DefaultLogQueryScanRate
+	DefaultLogQueryScanBurst = 1_000_000
 )
 
 // Request capture configuration
@@ -80,8 +270,19 @@ const (
 	// Bodies larger than this are stored on disk
 	DefaultCaptureInlineThreshold = 64 * 1024
 
+	// MaxSchemaViolations caps the schema violations recorded per response
+	MaxSchemaViolations = 10
+
 	// CaptureDirectory is the directory name for storing captured body files
 	CaptureDirectory = ".prox/capture"
+
+	// RequestHistoryFile is where the proxy request history is kept when
+	// proxy.history.persist is set
+	RequestHistoryFile = ".prox/requests.jsonl"
+
+	// MaxSessionSize caps the uncompressed size of a saved request session
+	// that can be loaded (256MB)
+	MaxSessionSize = 256 * 1024 * 1024
 )
 
 // Proxy timeouts
@@ -125,16 +326,6 @@ const (
 
 // ANSI color codes for terminal output
 var (
-	// ProcessColors are the colors used for process names in terminal output
-	ProcessColors = []string{
-		"\033[36m", // cyan
-		"\033[33m", // yellow
-		"\033[32m", // green
-		"\033[35m", // magenta
-		"\033[34m", // blue
-		"\033[31m", // red
-	}
-
 	// ColorReset resets the terminal color
 	ColorReset = "\033[0m"
 
@@ -146,4 +337,10 @@ var (
 	ColorStatusRedirect = "\033[36m" // cyan (3xx)
 	ColorStatusClient   = "\033[33m" // yellow (4xx)
 	ColorStatusServer   = "\033[31m" // red (5xx)
+
+	// Syntax highlighting colors for captured bodies
+	ColorSyntaxKey     = "\033[34m" // blue (JSON keys, XML tags, form names)
+	ColorSyntaxString  = "\033[32m" // green
+	ColorSyntaxNumber  = "\033[33m" // yellow
+	ColorSyntaxLiteral = "\033[35m" // magenta (true, false, null)
 )
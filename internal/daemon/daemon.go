@@ -5,7 +5,11 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"strconv"
 	"syscall"
+
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/fsperm"
 )
 
 const (
@@ -20,33 +24,64 @@ func IsDaemonChild() bool {
 
 // Daemonize re-executes the current process as a daemon.
 //
-// IMPORTANT: In the parent process, this function calls os.Exit(0) and never returns.
-// Only the child process continues execution (where IsDaemonChild() returns true).
+// IMPORTANT: In the parent process, this function calls os.Exit(0) once the
+// daemon has started and never returns. If the daemon fails to start it
+// returns the daemon's error instead. Only the child process continues
+// execution (where IsDaemonChild() returns true).
 //
 // The function:
 //  1. Re-executes the current binary with the same arguments
 //  2. Sets _PROX_DAEMON=1 environment variable to mark the child
 //  3. Detaches the child from the terminal (new session)
-//  4. Prints the child PID and exits the parent with status 0
+//  4. Waits for the child to report on its readiness pipe (see Readiness)
+//  5. Prints the child PID and exits the parent with status 0
 //
-// Note: There is a small race window where the parent exits before confirming
-// the child successfully initialized. This is acceptable because:
-//   - Client commands use discoverDaemon() which reads state and retries
-//   - The window is very small (child starts immediately)
-//   - A pipe-based confirmation would add significant complexity
-func Daemonize() error {
+// Go can't fork without exec, so the daemon is always a fresh process; its
+// new session leaves it without a controlling terminal.
+func Daemonize(dir string) error {
+	child, err := StartDaemon(dir)
+	if err != nil {
+		return err
+	}
+	if err := child.WaitReady(constants.DaemonStartTimeout); err != nil {
+		return err
+	}
+
+	// Return the child's PID
+	fmt.Printf("prox started (pid %d)\n", child.Cmd.Process.Pid)
+
+	// Parent exits successfully
+	os.Exit(0)
+
+	return nil // Unreachable, but needed for compiler
+}
+
+// StartDaemon re-executes the current binary as a detached daemon child for
+// dir, like Daemonize, but returns to the caller, which waits for the child
+// to start with WaitReady and may wait on its command to learn when the
+// daemon exits.
+func StartDaemon(dir string) (*Child, error) {
 	// Get the current executable path
 	executable, err := os.Executable()
 	if err != nil {
-		return fmt.Errorf("getting executable path: %w", err)
+		return nil, fmt.Errorf("getting executable path: %w", err)
+	}
+
+	// The child reports its startup on a pipe, passed as its first extra
+	// file (descriptor 3)
+	readR, readW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating readiness pipe: %w", err)
 	}
+	defer readW.Close()
 
 	// Prepare environment with daemon marker
-	env := append(os.Environ(), DaemonEnvVar+"=1")
+	env := append(os.Environ(), DaemonEnvVar+"=1", ReadyEnvVar+"=3")
 
 	// Create command with same args
 	cmd := exec.Command(executable, os.Args[1:]...)
 	cmd.Env = env
+	cmd.ExtraFiles = []*os.File{readW}
 
 	// Detach from terminal - create new session
 	cmd.SysProcAttr = &syscall.SysProcAttr{
@@ -60,16 +95,10 @@ func Daemonize() error {
 
 	// Start the daemon process
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("starting daemon process: %w", err)
+		readR.Close()
+		return nil, fmt.Errorf("starting daemon process: %w", err)
 	}
-
-	// Return the child's PID
-	fmt.Printf("prox started (pid %d)\n", cmd.Process.Pid)
-
-	// Parent exits successfully
-	os.Exit(0)
-
-	return nil // Unreachable, but needed for compiler
+	return &Child{Cmd: cmd, ready: readR, logPath: LogPath(dir)}, nil
 }
 
 // SetupLogging redirects stdout and stderr to the daemon log file.
@@ -80,7 +109,7 @@ func SetupLogging(dir string) (*os.File, error) {
 	}
 
 	logPath := LogPath(dir)
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, fsperm.File())
 	if err != nil {
 		return nil, fmt.Errorf("opening log file: %w", err)
 	}
@@ -112,6 +141,16 @@ func FindAvailablePort(host string) (int, error) {
 	return tcpAddr.Port, nil
 }
 
+// IsPortAvailable reports whether a TCP listener can be opened on host:port.
+func IsPortAvailable(host string, port int) bool {
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
 // IsRunning checks if a prox instance is running in the given directory.
 // Returns true if running, false otherwise.
 //
@@ -150,6 +189,22 @@ func GetRunningState(dir string) (*State, error) {
 	return state, nil
 }
 
+// StaleFiles returns the state and PID files left in dir by a prox instance
+// that stopped without removing them, e.g. after a crash. It returns nil
+// while an instance is running.
+func StaleFiles(dir string) []string {
+	if IsRunning(dir) {
+		return nil
+	}
+	var stale []string
+	for _, path := range []string{StatePath(dir), PIDPath(dir)} {
+		if _, err := os.Stat(path); err == nil {
+			stale = append(stale, path)
+		}
+	}
+	return stale
+}
+
 // CleanupStaleFiles removes stale state files if the process is not running.
 // This handles crash recovery scenarios.
 func CleanupStaleFiles(dir string) error {
@@ -1,8 +1,12 @@
 package daemon
 
 import (
+	"errors"
+	"net"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestIsDaemonChild(t *testing.T) {
@@ -43,6 +47,23 @@ func TestIsDaemonChild(t *testing.T) {
 	})
 }
 
+func TestIsPortAvailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	if IsPortAvailable("127.0.0.1", port) {
+		t.Errorf("expected port %d to be in use", port)
+	}
+
+	listener.Close()
+	if !IsPortAvailable("127.0.0.1", port) {
+		t.Errorf("expected port %d to be available after close", port)
+	}
+}
+
 func TestFindAvailablePort(t *testing.T) {
 	t.Run("finds available port on localhost", func(t *testing.T) {
 		port, err := FindAvailablePort("127.0.0.1")
@@ -251,6 +272,32 @@ func TestCleanupStaleFiles(t *testing.T) {
 	})
 }
 
+func TestStaleFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	if stale := StaleFiles(tmpDir); len(stale) != 0 {
+		t.Errorf("expected no stale files, got %v", stale)
+	}
+
+	// State left by a process that is gone
+	state := &State{PID: 4000000, Port: 5555, Host: "127.0.0.1", ConfigFile: "prox.yaml"}
+	if err := state.Write(tmpDir); err != nil {
+		t.Fatalf("Write state failed: %v", err)
+	}
+	if stale := StaleFiles(tmpDir); len(stale) != 1 || stale[0] != StatePath(tmpDir) {
+		t.Errorf("expected the state file to be stale, got %v", stale)
+	}
+
+	// Nothing is stale while an instance holds the PID file
+	pf := NewPIDFile(PIDPath(tmpDir))
+	if err := pf.Create(); err != nil {
+		t.Fatalf("Create PID file failed: %v", err)
+	}
+	defer pf.Release()
+	if stale := StaleFiles(tmpDir); len(stale) != 0 {
+		t.Errorf("expected no stale files while running, got %v", stale)
+	}
+}
+
 func TestSetupLogging(t *testing.T) {
 	t.Run("creates log file", func(t *testing.T) {
 		tmpDir := t.TempDir()
@@ -286,3 +333,66 @@ func TestSetupLogging(t *testing.T) {
 		}
 	})
 }
+
+func TestChild_WaitReady(t *testing.T) {
+	// newChild returns a child waiting on a pipe and the readiness reporting
+	// on it, as StartDaemon and ChildReadiness set them up
+	newChild := func(t *testing.T) (*Child, *Readiness) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("pipe failed: %v", err)
+		}
+		return &Child{ready: r, logPath: "prox.log"}, &Readiness{pipe: w}
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		child, readiness := newChild(t)
+		readiness.Ready()
+		readiness.Failed(errors.New("ignored after the first report"))
+		if err := child.WaitReady(time.Second); err != nil {
+			t.Errorf("expected ready, got %v", err)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		child, readiness := newChild(t)
+		readiness.Failed(nil)
+		readiness.Failed(errors.New("failed to load config: no such file"))
+		err := child.WaitReady(time.Second)
+		if err == nil || err.Error() != "failed to load config: no such file" {
+			t.Errorf("expected the child's error, got %v", err)
+		}
+	})
+
+	t.Run("exited without reporting", func(t *testing.T) {
+		child, readiness := newChild(t)
+		readiness.pipe.Close()
+		err := child.WaitReady(time.Second)
+		if err == nil || !strings.Contains(err.Error(), "exited during startup (see prox.log)") {
+			t.Errorf("expected an exit error, got %v", err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		child, readiness := newChild(t)
+		defer readiness.pipe.Close()
+		err := child.WaitReady(10 * time.Millisecond)
+		if err == nil || !strings.Contains(err.Error(), "did not finish starting") {
+			t.Errorf("expected a timeout error, got %v", err)
+		}
+	})
+}
+
+func TestChildReadiness_NotDaemonChild(t *testing.T) {
+	original := os.Getenv(ReadyEnvVar)
+	defer os.Setenv(ReadyEnvVar, original)
+	os.Unsetenv(ReadyEnvVar)
+
+	readiness := ChildReadiness()
+	if readiness != nil {
+		t.Fatal("expected no readiness outside a daemon child")
+	}
+	// Reporting on nil readiness does nothing
+	readiness.Ready()
+	readiness.Failed(errors.New("boom"))
+}
@@ -11,4 +11,6 @@ var (
 	ErrNotRunning = errors.New("prox is not running")
 	// ErrPIDFileLocked is returned when the PID file is locked by another process
 	ErrPIDFileLocked = errors.New("PID file is locked by another process")
+	// ErrNoLastRun is returned when no last run snapshot exists
+	ErrNoLastRun = errors.New("no last run recorded")
 )
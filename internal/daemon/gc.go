@@ -0,0 +1,130 @@
+package daemon
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/charliek/prox/internal/constants"
+)
+
+// GCItem is what prox gc reclaims from one directory prox has run in
+type GCItem struct {
+	Dir     string   // The directory prox ran in
+	Reason  string   // Why the instance is dead
+	Deleted bool     // The directory is gone, so only its registration is left
+	Paths   []string // Files and directories to remove
+	Bytes   int64    // Disk space used by Paths
+}
+
+// gcFiles are the state files, relative to the .prox directory, that are
+// only of use while the daemon runs. Runtime overrides, TUI preferences,
+// last-run snapshots, and postmortems are kept.
+var gcFiles = []string{
+	StateFileName,
+	PIDFileName,
+	LogFileName,
+	StreamsDirName,
+	filepath.Base(constants.CaptureDirectory),
+}
+
+// FindGarbage returns what can be reclaimed from dirs, sorted by directory.
+// Running instances, and stopped ones with nothing to remove, are left out.
+func FindGarbage(dirs []string) []GCItem {
+	var items []GCItem
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			items = append(items, GCItem{Dir: dir, Reason: "directory deleted", Deleted: true})
+			continue
+		}
+		if IsRunning(dir) {
+			continue
+		}
+
+		item := GCItem{Dir: dir, Reason: "not running"}
+		if state, err := LoadState(dir); err == nil {
+			item.Reason = fmt.Sprintf("stale PID %d", state.PID)
+		}
+		for _, name := range gcFiles {
+			path := filepath.Join(StateDir(dir), name)
+			size, err := diskUsage(path)
+			if err != nil {
+				continue
+			}
+			item.Paths = append(item.Paths, path)
+			item.Bytes += size
+		}
+		if len(item.Paths) > 0 {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Dir < items[j].Dir })
+	return items
+}
+
+// Remove deletes the item's files, re-checking first that the instance
+// hasn't been started since it was found
+func (g GCItem) Remove() error {
+	if !g.Deleted && IsRunning(g.Dir) {
+		return ErrAlreadyRunning
+	}
+	for _, path := range g.Paths {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("removing %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// FindStateDirs returns the directories under roots that contain a .prox
+// directory. Hidden directories and node_modules aren't searched, and
+// unreadable directories are skipped.
+func FindStateDirs(roots []string) ([]string, error) {
+	var dirs []string
+	for _, root := range roots {
+		if _, err := os.Stat(root); err != nil {
+			return nil, fmt.Errorf("reading root: %w", err)
+		}
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			name := d.Name()
+			if name == StateDirName {
+				if abs, err := filepath.Abs(filepath.Dir(path)); err == nil {
+					dirs = append(dirs, abs)
+				}
+				return filepath.SkipDir
+			}
+			if path != root && (name[0] == '.' || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+	return dirs, nil
+}
+
+// diskUsage returns the size of a file, or of all files under a directory
+func diskUsage(path string) (int64, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	if !info.IsDir() {
+		return info.Size(), nil
+	}
+	var total int64
+	err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
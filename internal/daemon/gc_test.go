@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".prox", InstancesFileName)
+
+	r, err := LoadRegistry(path)
+	require.NoError(t, err)
+	assert.Empty(t, r.Instances)
+
+	started := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	r.Add("/src/b", "/src/b/prox.yaml", started)
+	r.Add("/src/a", "/src/a/prox.yaml", started)
+	r.Add("/src/b", "/src/b/prox.yaml", started.Add(time.Hour))
+	require.NoError(t, r.Write(path))
+
+	loaded, err := LoadRegistry(path)
+	require.NoError(t, err)
+	require.Len(t, loaded.Instances, 2)
+	assert.Equal(t, "/src/a", loaded.Instances[0].Dir)
+	assert.Equal(t, started.Add(time.Hour), loaded.Instances[1].LastStarted)
+
+	loaded.Remove("/src/a")
+	assert.Equal(t, []Instance{{Dir: "/src/b", ConfigFile: "/src/b/prox.yaml", LastStarted: started.Add(time.Hour)}}, loaded.Instances)
+}
+
+func TestFindGarbage(t *testing.T) {
+	root := t.TempDir()
+
+	// A crashed daemon that left its state, log, and captured bodies behind
+	dead := filepath.Join(root, "dead")
+	state := &State{PID: 4000000, Port: 5555, Host: "127.0.0.1", ConfigFile: "prox.yaml"}
+	require.NoError(t, state.Write(dead))
+	require.NoError(t, os.WriteFile(LogPath(dead), make([]byte, 100), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(StateDir(dead), "capture"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(StateDir(dead), "capture", "abc_resp"), make([]byte, 50), 0600))
+	require.NoError(t, os.WriteFile(RuntimePath(dead), []byte("{}"), 0600))
+
+	// A stopped instance with nothing left to remove
+	clean := filepath.Join(root, "clean")
+	require.NoError(t, EnsureStateDir(clean))
+
+	gone := filepath.Join(root, "gone")
+
+	items := FindGarbage([]string{gone, clean, dead})
+	require.Len(t, items, 2)
+
+	assert.Equal(t, dead, items[0].Dir)
+	assert.Equal(t, "stale PID 4000000", items[0].Reason)
+	assert.False(t, items[0].Deleted)
+	assert.Len(t, items[0].Paths, 3)
+	stateInfo, err := os.Stat(StatePath(dead))
+	require.NoError(t, err)
+	assert.Equal(t, 150+stateInfo.Size(), items[0].Bytes)
+
+	assert.Equal(t, GCItem{Dir: gone, Reason: "directory deleted", Deleted: true}, items[1])
+
+	require.NoError(t, items[0].Remove())
+	assert.NoFileExists(t, LogPath(dead))
+	assert.NoDirExists(t, filepath.Join(StateDir(dead), "capture"))
+	assert.FileExists(t, RuntimePath(dead), "runtime overrides should be kept")
+	assert.Empty(t, FindGarbage([]string{dead}))
+}
+
+func TestFindGarbage_SkipsRunning(t *testing.T) {
+	dir := t.TempDir()
+	state := &State{PID: os.Getpid(), Port: 5555, Host: "127.0.0.1", ConfigFile: "prox.yaml"}
+	require.NoError(t, state.Write(dir))
+
+	assert.Empty(t, FindGarbage([]string{dir}))
+}
+
+func TestFindStateDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a", "b/c", "node_modules/d", ".git/e"} {
+		require.NoError(t, EnsureStateDir(filepath.Join(root, dir)))
+	}
+
+	dirs, err := FindStateDirs([]string{root})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "a"), filepath.Join(root, "b/c")}, dirs)
+
+	_, err = FindStateDirs([]string{filepath.Join(root, "missing")})
+	assert.Error(t, err)
+}
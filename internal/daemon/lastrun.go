@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// LastRun is a snapshot of the stack taken as the daemon shuts down or
+// crashes, kept in .prox/last-run.json so the reason a stack died outlives
+// the daemon
+type LastRun struct {
+	StartedAt time.Time        `json:"started_at"`
+	EndedAt   time.Time        `json:"ended_at"`
+	Reason    string           `json:"reason"`          // e.g. "interrupt received", "idle timeout"
+	Panic     string           `json:"panic,omitempty"` // Panic value and stack when the daemon crashed
+	Processes []LastRunProcess `json:"processes"`
+	Logs      LastRunLogs      `json:"logs"`
+}
+
+// LastRunProcess is the state of one process when the daemon ended
+type LastRunProcess struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	PID       int    `json:"pid,omitempty"`
+	Restarts  int    `json:"restarts"`
+	Health    string `json:"health"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// LastRunLogs describes the log buffer when the daemon ended
+type LastRunLogs struct {
+	Entries     int `json:"entries"`
+	BufferSize  int `json:"buffer_size"`
+	Subscribers int `json:"subscribers"`
+}
+
+// Crashed reports whether the run ended in a panic
+func (r *LastRun) Crashed() bool {
+	return r.Panic != ""
+}
+
+// Write saves the snapshot for the given directory, replacing the previous one
+func (r *LastRun) Write(dir string) error {
+	if err := EnsureStateDir(dir); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling last run: %w", err)
+	}
+	if err := writeFileAtomic(LastRunPath(dir), data); err != nil {
+		return fmt.Errorf("writing last run: %w", err)
+	}
+	return nil
+}
+
+// LoadLastRun reads the last run snapshot for the given directory, returning
+// ErrNoLastRun if none has been written
+func LoadLastRun(dir string) (*LastRun, error) {
+	data, err := os.ReadFile(LastRunPath(dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoLastRun
+		}
+		return nil, fmt.Errorf("reading last run: %w", err)
+	}
+
+	var run LastRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("unmarshaling last run: %w", err)
+	}
+	return &run, nil
+}
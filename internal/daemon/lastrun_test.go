@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLastRun_WriteAndLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if _, err := LoadLastRun(tmpDir); err != ErrNoLastRun {
+		t.Fatalf("LoadLastRun() without a file error = %v, want ErrNoLastRun", err)
+	}
+
+	ended := time.Date(2025, 1, 1, 12, 30, 0, 0, time.UTC)
+	run := &LastRun{
+		StartedAt: ended.Add(-time.Hour),
+		EndedAt:   ended,
+		Reason:    "interrupt received",
+		Processes: []LastRunProcess{
+			{Name: "api", Status: "running", PID: 1234, Restarts: 2, Health: "healthy"},
+			{Name: "worker", Status: "stopped", Health: "unknown", LastError: "exit status 1"},
+		},
+		Logs: LastRunLogs{Entries: 812, BufferSize: 1000, Subscribers: 1},
+	}
+	if err := run.Write(tmpDir); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	loaded, err := LoadLastRun(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadLastRun() error = %v", err)
+	}
+	if !loaded.EndedAt.Equal(ended) || loaded.Reason != "interrupt received" {
+		t.Errorf("loaded = %+v, want ended %v with reason", loaded, ended)
+	}
+	if len(loaded.Processes) != 2 || loaded.Processes[1].LastError != "exit status 1" {
+		t.Errorf("Processes = %+v", loaded.Processes)
+	}
+	if loaded.Logs.Entries != 812 {
+		t.Errorf("Logs.Entries = %d, want 812", loaded.Logs.Entries)
+	}
+	if loaded.Crashed() {
+		t.Error("Crashed() = true for a graceful shutdown")
+	}
+
+	// A later run replaces the snapshot
+	crash := &LastRun{EndedAt: ended.Add(time.Hour), Reason: "panic", Panic: "runtime error: nil map"}
+	if err := crash.Write(tmpDir); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	loaded, err = LoadLastRun(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadLastRun() error = %v", err)
+	}
+	if !loaded.Crashed() || len(loaded.Processes) != 0 {
+		t.Errorf("loaded = %+v, want the crash snapshot", loaded)
+	}
+}
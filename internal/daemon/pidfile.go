@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+
+	"github.com/charliek/prox/internal/fsperm"
 )
 
 // PIDFile manages a PID file with file locking.
@@ -26,7 +28,7 @@ func NewPIDFile(path string) *PIDFile {
 // Returns ErrPIDFileLocked if another process holds the lock.
 func (p *PIDFile) Create() error {
 	// Open file for writing, create if not exists
-	f, err := os.OpenFile(p.path, os.O_RDWR|os.O_CREATE, 0600)
+	f, err := os.OpenFile(p.path, os.O_RDWR|os.O_CREATE, fsperm.File())
 	if err != nil {
 		return fmt.Errorf("opening PID file: %w", err)
 	}
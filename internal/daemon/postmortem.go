@@ -0,0 +1,102 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"text/tabwriter"
+	"time"
+)
+
+// Postmortem is a dump of the daemon's state for debugging a stack that hung
+// or misbehaved: its processes, the proxy requests still in flight, the log
+// buffer, and the stacks of all goroutines. It is written as text to
+// .prox/postmortem-<time>.txt when the daemon gets SIGQUIT or on request.
+type Postmortem struct {
+	Time       time.Time
+	Reason     string // e.g. "quit received", "requested via API"
+	StartedAt  time.Time
+	Processes  []LastRunProcess
+	Requests   []PostmortemRequest
+	Logs       LastRunLogs
+	Goroutines []byte // Stacks of all goroutines, as from GoroutineStacks
+}
+
+// PostmortemRequest is a proxy request in flight when the dump was taken
+type PostmortemRequest struct {
+	ID        string
+	Method    string
+	URL       string
+	Subdomain string
+	Start     time.Time
+}
+
+// GoroutineStacks returns the stacks of all goroutines
+func GoroutineStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// Text returns the dump in its text form
+func (p *Postmortem) Text() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "prox postmortem\n\n")
+	fmt.Fprintf(&buf, "Time:       %s\n", p.Time.Format(time.RFC3339Nano))
+	fmt.Fprintf(&buf, "Reason:     %s\n", p.Reason)
+	if !p.StartedAt.IsZero() {
+		fmt.Fprintf(&buf, "Started:    %s (up %s)\n", p.StartedAt.Format(time.RFC3339), p.Time.Sub(p.StartedAt).Round(time.Second))
+	}
+	fmt.Fprintf(&buf, "PID:        %d\n", os.Getpid())
+	fmt.Fprintf(&buf, "Goroutines: %d\n", runtime.NumGoroutine())
+
+	fmt.Fprintf(&buf, "\n== Processes (%d)\n\n", len(p.Processes))
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATUS\tPID\tRESTARTS\tHEALTH\tLAST ERROR")
+	for _, proc := range p.Processes {
+		pid := "-"
+		if proc.PID > 0 {
+			pid = fmt.Sprintf("%d", proc.PID)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n", proc.Name, proc.Status, pid, proc.Restarts, proc.Health, proc.LastError)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(&buf, "\n== In-flight proxy requests (%d)\n\n", len(p.Requests))
+	tw = tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tAGE\tSUBDOMAIN\tMETHOD\tURL")
+	for _, req := range p.Requests {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", req.ID, p.Time.Sub(req.Start).Round(time.Millisecond), req.Subdomain, req.Method, req.URL)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(&buf, "\n== Log buffer\n\n")
+	fmt.Fprintf(&buf, "Entries:     %d\n", p.Logs.Entries)
+	fmt.Fprintf(&buf, "Buffer size: %d\n", p.Logs.BufferSize)
+	fmt.Fprintf(&buf, "Subscribers: %d\n", p.Logs.Subscribers)
+
+	fmt.Fprintf(&buf, "\n== Goroutines\n\n")
+	buf.Write(p.Goroutines)
+
+	return buf.Bytes()
+}
+
+// Write saves the dump for the given directory and returns its path. Unlike
+// the last run snapshot, every dump is kept.
+func (p *Postmortem) Write(dir string) (string, error) {
+	if err := EnsureStateDir(dir); err != nil {
+		return "", err
+	}
+
+	path := PostmortemPath(dir, p.Time)
+	if err := writeFileAtomic(path, p.Text()); err != nil {
+		return "", fmt.Errorf("writing postmortem: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostmortem_Write(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	now := time.Date(2025, 1, 1, 12, 30, 0, 0, time.UTC)
+	dump := &Postmortem{
+		Time:      now,
+		Reason:    "quit received",
+		StartedAt: now.Add(-time.Hour),
+		Processes: []LastRunProcess{
+			{Name: "api", Status: "running", PID: 1234, Restarts: 2, Health: "healthy"},
+			{Name: "worker", Status: "stopped", Health: "unknown", LastError: "exit status 1"},
+		},
+		Requests: []PostmortemRequest{
+			{ID: "abc123", Method: "GET", URL: "/slow", Subdomain: "app", Start: now.Add(-3 * time.Second)},
+		},
+		Logs:       LastRunLogs{Entries: 812, BufferSize: 1000, Subscribers: 1},
+		Goroutines: GoroutineStacks(),
+	}
+
+	path, err := dump.Write(tmpDir)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if want := filepath.Join(tmpDir, ".prox", "postmortem-20250101-123000.000.txt"); path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading dump: %v", err)
+	}
+	text := string(data)
+	for _, want := range []string{
+		"Reason:     quit received",
+		"up 1h0m0s",
+		"== Processes (2)",
+		"exit status 1",
+		"== In-flight proxy requests (1)",
+		"abc123  3s",
+		"Entries:     812",
+		"goroutine ",
+		"TestPostmortem_Write",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("dump missing %q:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ReadyEnvVar names the file descriptor of the pipe a daemon child reports
+// its startup on
+const ReadyEnvVar = "_PROX_DAEMON_READY"
+
+// Messages written on the readiness pipe. A failure is followed by the
+// error's text.
+const (
+	readyMessage  = "ready\n"
+	failedMessage = "failed\n"
+)
+
+// Child is a daemon child started by StartDaemon
+type Child struct {
+	Cmd     *exec.Cmd
+	ready   *os.File // Read end of the readiness pipe
+	logPath string
+}
+
+// WaitReady blocks until the child reports that it started, returning the
+// error it reports if it failed to. It also returns an error if the child
+// exits without reporting or doesn't report within timeout; the child keeps
+// running in that case.
+func (c *Child) WaitReady(timeout time.Duration) error {
+	defer c.ready.Close()
+
+	// The read ends once the child writes its report and closes the pipe,
+	// or exits
+	type result struct {
+		msg string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		msg, err := io.ReadAll(c.ready)
+		done <- result{string(msg), err}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-time.After(timeout):
+		return fmt.Errorf("prox did not finish starting within %s (see %s)", timeout, c.logPath)
+	}
+
+	switch {
+	case r.err != nil:
+		return fmt.Errorf("reading daemon startup status: %w", r.err)
+	case r.msg == readyMessage:
+		return nil
+	case strings.HasPrefix(r.msg, failedMessage):
+		return errors.New(strings.TrimPrefix(r.msg, failedMessage))
+	default:
+		return fmt.Errorf("prox exited during startup (see %s)", c.logPath)
+	}
+}
+
+// Readiness reports a daemon child's startup to the parent waiting in
+// WaitReady. Only the first report is sent. A nil Readiness, as in a process
+// that isn't a daemon child, reports nothing.
+type Readiness struct {
+	pipe *os.File
+	once sync.Once
+}
+
+// ChildReadiness returns the readiness pipe passed to this daemon child, or
+// nil if there is none. Call it before starting any process: it marks the
+// pipe close-on-exec so processes started later don't hold it open, which
+// would keep the parent waiting after the child exits.
+func ChildReadiness() *Readiness {
+	fd, err := strconv.Atoi(os.Getenv(ReadyEnvVar))
+	if err != nil || fd < 3 {
+		return nil
+	}
+	syscall.CloseOnExec(fd)
+	return &Readiness{pipe: os.NewFile(uintptr(fd), "prox-ready")}
+}
+
+// Ready tells the parent the daemon started
+func (r *Readiness) Ready() {
+	r.report(readyMessage)
+}
+
+// Failed tells the parent the daemon failed to start and why. A nil error
+// reports nothing, so it can be deferred with a function's result.
+func (r *Readiness) Failed(err error) {
+	if err != nil {
+		r.report(failedMessage + err.Error())
+	}
+}
+
+// report writes msg and closes the pipe, once
+func (r *Readiness) report(msg string) {
+	if r == nil {
+		return
+	}
+	r.once.Do(func() {
+		_, _ = io.WriteString(r.pipe, msg)
+		_ = r.pipe.Close()
+	})
+}
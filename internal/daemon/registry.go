@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/charliek/prox/internal/fsperm"
+)
+
+// InstancesFileName is the name of the registry of directories prox has run
+// in, kept in ~/.prox so prox gc can find them
+const InstancesFileName = "instances.json"
+
+// Instance is a directory prox up has run in
+type Instance struct {
+	Dir         string    `json:"dir"`
+	ConfigFile  string    `json:"config_file"`
+	LastStarted time.Time `json:"last_started"`
+}
+
+// Registry lists the directories prox has run in. Two prox up runs
+// registering at the same moment may lose one of the entries; prox gc --root
+// finds those directories anyway.
+type Registry struct {
+	Instances []Instance `json:"instances"`
+}
+
+// RegistryPath returns the path to the instance registry in the user's home
+// directory
+func RegistryPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("finding home directory: %w", err)
+	}
+	return filepath.Join(home, StateDirName, InstancesFileName), nil
+}
+
+// LoadRegistry reads the registry at path. A missing file yields an empty
+// registry.
+func LoadRegistry(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Registry{}, nil
+		}
+		return nil, fmt.Errorf("reading instance registry: %w", err)
+	}
+
+	var r Registry
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("unmarshaling instance registry: %w", err)
+	}
+	return &r, nil
+}
+
+// Write saves the registry to path, replacing the file atomically
+func (r *Registry) Write(path string) error {
+	if err := fsperm.MkdirAll(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("creating registry directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling instance registry: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("writing instance registry: %w", err)
+	}
+	return nil
+}
+
+// Add records a run in dir, replacing any earlier entry for it
+func (r *Registry) Add(dir, configFile string, startedAt time.Time) {
+	r.Remove(dir)
+	r.Instances = append(r.Instances, Instance{Dir: dir, ConfigFile: configFile, LastStarted: startedAt})
+	sort.Slice(r.Instances, func(i, j int) bool { return r.Instances[i].Dir < r.Instances[j].Dir })
+}
+
+// Remove drops the entry for dir, if any
+func (r *Registry) Remove(dir string) {
+	kept := r.Instances[:0]
+	for _, inst := range r.Instances {
+		if inst.Dir != dir {
+			kept = append(kept, inst)
+		}
+	}
+	r.Instances = kept
+}
+
+// RegisterInstance records a run in dir in the user's registry
+func RegisterInstance(dir, configFile string, startedAt time.Time) error {
+	path, err := RegistryPath()
+	if err != nil {
+		return err
+	}
+	r, err := LoadRegistry(path)
+	if err != nil {
+		return err
+	}
+	r.Add(dir, configFile, startedAt)
+	return r.Write(path)
+}
@@ -0,0 +1,104 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charliek/prox/internal/fsperm"
+)
+
+// RuntimeOverrides records changes made to a running stack that the config
+// file doesn't capture, so the next `prox up` brings the stack back the way
+// it was left. It is kept in .prox/runtime.json across daemon restarts.
+type RuntimeOverrides struct {
+	Stopped []string       `json:"stopped,omitempty"` // Processes stopped by hand
+	Scale   map[string]int `json:"scale,omitempty"`   // Instances of processes, as set by prox up --scale
+	Added   []AddedProcess `json:"added,omitempty"`   // Processes added with prox run, by name
+}
+
+// AddedProcess is a process that isn't in the config, added at runtime with
+// prox run (POST /processes)
+type AddedProcess struct {
+	Name string            `json:"name"`
+	Cmd  string            `json:"cmd"`
+	Env  map[string]string `json:"env,omitempty"`
+}
+
+// IsEmpty returns true if there is nothing to override
+func (o *RuntimeOverrides) IsEmpty() bool {
+	return len(o.Stopped) == 0 && len(o.Scale) == 0 && len(o.Added) == 0
+}
+
+// LoadRuntimeOverrides reads the runtime overrides for the given directory.
+// A missing file yields empty overrides.
+func LoadRuntimeOverrides(dir string) (*RuntimeOverrides, error) {
+	data, err := os.ReadFile(RuntimePath(dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &RuntimeOverrides{}, nil
+		}
+		return nil, fmt.Errorf("reading runtime overrides: %w", err)
+	}
+
+	var overrides RuntimeOverrides
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("unmarshaling runtime overrides: %w", err)
+	}
+	return &overrides, nil
+}
+
+// Write saves the overrides for the given directory, removing the file when
+// there is nothing to override. The file is replaced atomically so a crash
+// mid-write never leaves it truncated.
+func (o *RuntimeOverrides) Write(dir string) error {
+	if o.IsEmpty() {
+		return RemoveRuntimeOverrides(dir)
+	}
+
+	if err := EnsureStateDir(dir); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling runtime overrides: %w", err)
+	}
+
+	if err := writeFileAtomic(RuntimePath(dir), data); err != nil {
+		return fmt.Errorf("writing runtime overrides: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data via a temporary file
+// and rename, so a crash mid-write never leaves it truncated
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(fsperm.File()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// RemoveRuntimeOverrides removes the runtime overrides file
+func RemoveRuntimeOverrides(dir string) error {
+	if err := os.Remove(RuntimePath(dir)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing runtime overrides: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestRuntimeOverrides_WriteAndLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	overrides, err := LoadRuntimeOverrides(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadRuntimeOverrides() without a file error = %v", err)
+	}
+	if len(overrides.Stopped) != 0 {
+		t.Errorf("expected empty overrides, got %+v", overrides)
+	}
+
+	overrides.Stopped = []string{"api", "worker"}
+	overrides.Scale = map[string]int{"worker": 3}
+	overrides.Added = []AddedProcess{{Name: "tunnel", Cmd: "ngrok http 3000", Env: map[string]string{"REGION": "eu"}}}
+	if err := overrides.Write(tmpDir); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	loaded, err := LoadRuntimeOverrides(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadRuntimeOverrides() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, overrides) {
+		t.Errorf("loaded %+v, want %+v", loaded, overrides)
+	}
+
+	// Empty overrides remove the file
+	if err := (&RuntimeOverrides{}).Write(tmpDir); err != nil {
+		t.Fatalf("Write() empty error = %v", err)
+	}
+	if _, err := os.Stat(RuntimePath(tmpDir)); !os.IsNotExist(err) {
+		t.Errorf("expected runtime file to be removed, stat error = %v", err)
+	}
+}
+
+func TestLoadRuntimeOverrides_Invalid(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := EnsureStateDir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(RuntimePath(tmpDir), []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadRuntimeOverrides(tmpDir); err == nil {
+		t.Error("expected error for invalid runtime overrides")
+	}
+}
+
+func TestCleanupStateDir_KeepsRuntimeOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	overrides := &RuntimeOverrides{Stopped: []string{"web"}}
+	if err := overrides.Write(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CleanupStateDir(tmpDir); err != nil {
+		t.Fatalf("CleanupStateDir() error = %v", err)
+	}
+	if _, err := os.Stat(RuntimePath(tmpDir)); err != nil {
+		t.Errorf("runtime overrides should survive daemon cleanup: %v", err)
+	}
+}
@@ -3,9 +3,13 @@ package daemon
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
+
+	"github.com/charliek/prox/internal/fsperm"
 )
 
 const (
@@ -17,6 +21,24 @@ const (
 	PIDFileName = "prox.pid"
 	// LogFileName is the name of the daemon log file
 	LogFileName = "prox.log"
+	// RuntimeFileName is the name of the runtime overrides file. Unlike the
+	// other state files it outlives the daemon.
+	RuntimeFileName = "runtime.json"
+	// TUIPrefsFileName is the name of the TUI preferences file, which also
+	// outlives the daemon
+	TUIPrefsFileName = "tui.json"
+	// CLIPrefsFileName is the name of the CLI preferences file, which also
+	// outlives the daemon
+	CLIPrefsFileName = "cli.json"
+	// LastRunFileName is the name of the snapshot written when the daemon
+	// shuts down or crashes
+	LastRunFileName = "last-run.json"
+	// PostmortemFilePrefix starts the names of the state dumps written on
+	// SIGQUIT or by prox debug trigger-dump, which outlive the daemon
+	PostmortemFilePrefix = "postmortem-"
+	// StreamsDirName is the name of the directory holding the process
+	// output sockets
+	StreamsDirName = "streams"
 )
 
 // State holds the runtime state of a running prox instance.
@@ -31,6 +53,17 @@ type State struct {
 	Host       string    `json:"host"`
 	StartedAt  time.Time `json:"started_at"`
 	ConfigFile string    `json:"config_file"`
+	TLS        bool      `json:"tls,omitempty"`       // The API is served over HTTPS
+	CertFile   string    `json:"cert_file,omitempty"` // Certificate the API serves, trusted by local clients
+}
+
+// URL returns the base URL of the instance's API
+func (s *State) URL() string {
+	scheme := "http"
+	if s.TLS {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(s.Host, strconv.Itoa(s.Port)))
 }
 
 // Write writes the state to the state file in the given directory
@@ -49,7 +82,7 @@ func (s *State) Write(dir string) error {
 	}
 
 	stateDir := filepath.Join(dir, StateDirName)
-	if err := os.MkdirAll(stateDir, 0700); err != nil {
+	if err := fsperm.MkdirAll(stateDir); err != nil {
 		return fmt.Errorf("creating state directory: %w", err)
 	}
 
@@ -59,7 +92,7 @@ func (s *State) Write(dir string) error {
 	}
 
 	statePath := filepath.Join(stateDir, StateFileName)
-	f, err := os.OpenFile(statePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	f, err := os.OpenFile(statePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fsperm.File())
 	if err != nil {
 		return fmt.Errorf("opening state file: %w", err)
 	}
@@ -86,6 +119,12 @@ func LoadState(dir string) (*State, error) {
 		return nil, fmt.Errorf("reading state file: %w", err)
 	}
 
+	return ParseState(data)
+}
+
+// ParseState parses state file contents, such as a state file read from a
+// remote host
+func ParseState(data []byte) (*State, error) {
 	var state State
 	if err := json.Unmarshal(data, &state); err != nil {
 		return nil, fmt.Errorf("unmarshaling state: %w", err)
@@ -133,10 +172,40 @@ func LogPath(dir string) string {
 	return filepath.Join(StateDir(dir), LogFileName)
 }
 
+// RuntimePath returns the full path to the runtime overrides file
+func RuntimePath(dir string) string {
+	return filepath.Join(StateDir(dir), RuntimeFileName)
+}
+
+// TUIPrefsPath returns the path to the TUI preferences file
+func TUIPrefsPath(dir string) string {
+	return filepath.Join(StateDir(dir), TUIPrefsFileName)
+}
+
+// CLIPrefsPath returns the path to the CLI preferences file
+func CLIPrefsPath(dir string) string {
+	return filepath.Join(StateDir(dir), CLIPrefsFileName)
+}
+
+// LastRunPath returns the full path to the last run snapshot
+func LastRunPath(dir string) string {
+	return filepath.Join(StateDir(dir), LastRunFileName)
+}
+
+// PostmortemPath returns the full path to the state dump taken at t
+func PostmortemPath(dir string, t time.Time) string {
+	return filepath.Join(StateDir(dir), PostmortemFilePrefix+t.UTC().Format("20060102-150405.000")+".txt")
+}
+
+// StreamsDir returns the path to the directory of process output sockets
+func StreamsDir(dir string) string {
+	return filepath.Join(StateDir(dir), StreamsDirName)
+}
+
 // EnsureStateDir creates the .prox directory if it doesn't exist
 func EnsureStateDir(dir string) error {
 	stateDir := StateDir(dir)
-	if err := os.MkdirAll(stateDir, 0700); err != nil {
+	if err := fsperm.MkdirAll(stateDir); err != nil {
 		return fmt.Errorf("creating state directory: %w", err)
 	}
 	return nil
@@ -152,6 +152,36 @@ func TestLoadState_NotFound(t *testing.T) {
 	}
 }
 
+func TestParseState(t *testing.T) {
+	state, err := ParseState([]byte(`{"pid": 42, "port": 5555, "host": "127.0.0.1", "config_file": "prox.yaml"}`))
+	if err != nil {
+		t.Fatalf("ParseState failed: %v", err)
+	}
+	if state.PID != 42 || state.Port != 5555 || state.Host != "127.0.0.1" {
+		t.Errorf("unexpected state: %+v", state)
+	}
+
+	if _, err := ParseState([]byte("not json")); err == nil {
+		t.Error("expected error for invalid state")
+	}
+}
+
+func TestState_URL(t *testing.T) {
+	tests := []struct {
+		state State
+		want  string
+	}{
+		{State{Host: "127.0.0.1", Port: 5555}, "http://127.0.0.1:5555"},
+		{State{Host: "0.0.0.0", Port: 5555, TLS: true}, "https://0.0.0.0:5555"},
+		{State{Host: "::1", Port: 5555}, "http://[::1]:5555"},
+	}
+	for _, tt := range tests {
+		if got := tt.state.URL(); got != tt.want {
+			t.Errorf("URL() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
 func TestStateDir(t *testing.T) {
 	t.Run("returns correct path with dir", func(t *testing.T) {
 		dir := "/some/path"
@@ -7,10 +7,21 @@ var (
 	ErrProcessNotFound       = errors.New("process not found")
 	ErrProcessAlreadyRunning = errors.New("process already running")
 	ErrProcessNotRunning     = errors.New("process not running")
+	ErrProcessExists         = errors.New("process already exists")
 	ErrInvalidPattern        = errors.New("invalid filter pattern")
 	ErrShutdownInProgress    = errors.New("shutdown in progress")
 	ErrConfigNotFound        = errors.New("config file not found")
 	ErrInvalidConfig         = errors.New("invalid configuration")
+	ErrNoProxyServices       = errors.New("process has no proxy services")
+	ErrPortNotAuto           = errors.New("process does not use port: auto")
+	ErrProcessNotReady       = errors.New("process did not become ready")
+	ErrDependencyUnavailable = errors.New("dependency unavailable")
+	ErrRuleNotFound          = errors.New("proxy rule not found")
+	ErrInvalidSignal         = errors.New("invalid signal")
+	ErrTaskNotFound          = errors.New("task not found")
+	ErrTaskRunning           = errors.New("task already running")
+	ErrStdinDisabled         = errors.New("process does not accept input (set stdin: true)")
+	ErrProcessFailed         = errors.New("process crashed too often (start it with prox start)")
 )
 
 // Error codes for API responses
@@ -18,8 +29,20 @@ const (
 	ErrCodeProcessNotFound       = "PROCESS_NOT_FOUND"
 	ErrCodeProcessAlreadyRunning = "PROCESS_ALREADY_RUNNING"
 	ErrCodeProcessNotRunning     = "PROCESS_NOT_RUNNING"
+	ErrCodeProcessExists         = "PROCESS_EXISTS"
 	ErrCodeInvalidPattern        = "INVALID_PATTERN"
 	ErrCodeShutdownInProgress    = "SHUTDOWN_IN_PROGRESS"
+	ErrCodeNoProxyServices       = "NO_PROXY_SERVICES"
+	ErrCodePortNotAuto           = "PORT_NOT_AUTO"
+	ErrCodeProcessNotReady       = "PROCESS_NOT_READY"
+	ErrCodeDependencyUnavailable = "DEPENDENCY_UNAVAILABLE"
+	ErrCodeRuleNotFound          = "RULE_NOT_FOUND"
+	ErrCodeInvalidConfig         = "INVALID_CONFIG"
+	ErrCodeInvalidSignal         = "INVALID_SIGNAL"
+	ErrCodeTaskNotFound          = "TASK_NOT_FOUND"
+	ErrCodeTaskRunning           = "TASK_RUNNING"
+	ErrCodeStdinDisabled         = "STDIN_DISABLED"
+	ErrCodeProcessFailed         = "PROCESS_FAILED"
 
 	// Proxy-related error codes (API-only, no sentinel errors as they
 	// are only used for HTTP response formatting in the API layer)
@@ -27,6 +50,13 @@ const (
 	ErrCodeStreamingNotSupported = "STREAMING_NOT_SUPPORTED"
 	ErrCodeRequestNotFound       = "REQUEST_NOT_FOUND"
 	ErrCodeMissingRequestID      = "MISSING_REQUEST_ID"
+	ErrCodeInvalidSession        = "INVALID_SESSION"
+	ErrCodeInvalidRequest        = "INVALID_REQUEST"
+	ErrCodeForbiddenOrigin       = "FORBIDDEN_ORIGIN"
+	ErrCodeUnsupportedMediaType  = "UNSUPPORTED_MEDIA_TYPE"
+
+	// Returned when Accept-Version names an API version the server lacks
+	ErrCodeUnsupportedVersion = "UNSUPPORTED_API_VERSION"
 )
 
 // ErrorCode returns the API error code for a domain error
@@ -38,10 +68,34 @@ func ErrorCode(err error) string {
 		return ErrCodeProcessAlreadyRunning
 	case errors.Is(err, ErrProcessNotRunning):
 		return ErrCodeProcessNotRunning
+	case errors.Is(err, ErrProcessExists):
+		return ErrCodeProcessExists
 	case errors.Is(err, ErrInvalidPattern):
 		return ErrCodeInvalidPattern
 	case errors.Is(err, ErrShutdownInProgress):
 		return ErrCodeShutdownInProgress
+	case errors.Is(err, ErrNoProxyServices):
+		return ErrCodeNoProxyServices
+	case errors.Is(err, ErrPortNotAuto):
+		return ErrCodePortNotAuto
+	case errors.Is(err, ErrProcessNotReady):
+		return ErrCodeProcessNotReady
+	case errors.Is(err, ErrDependencyUnavailable):
+		return ErrCodeDependencyUnavailable
+	case errors.Is(err, ErrRuleNotFound):
+		return ErrCodeRuleNotFound
+	case errors.Is(err, ErrInvalidConfig):
+		return ErrCodeInvalidConfig
+	case errors.Is(err, ErrInvalidSignal):
+		return ErrCodeInvalidSignal
+	case errors.Is(err, ErrTaskNotFound):
+		return ErrCodeTaskNotFound
+	case errors.Is(err, ErrTaskRunning):
+		return ErrCodeTaskRunning
+	case errors.Is(err, ErrStdinDisabled):
+		return ErrCodeStdinDisabled
+	case errors.Is(err, ErrProcessFailed):
+		return ErrCodeProcessFailed
 	default:
 		return "INTERNAL_ERROR"
 	}
@@ -18,6 +18,16 @@ func TestErrorCode(t *testing.T) {
 		{"process not running", ErrProcessNotRunning, ErrCodeProcessNotRunning},
 		{"invalid pattern", ErrInvalidPattern, ErrCodeInvalidPattern},
 		{"shutdown in progress", ErrShutdownInProgress, ErrCodeShutdownInProgress},
+		{"no proxy services", ErrNoProxyServices, ErrCodeNoProxyServices},
+		{"port not auto", ErrPortNotAuto, ErrCodePortNotAuto},
+		{"process not ready", ErrProcessNotReady, ErrCodeProcessNotReady},
+		{"dependency unavailable", ErrDependencyUnavailable, ErrCodeDependencyUnavailable},
+		{"rule not found", ErrRuleNotFound, ErrCodeRuleNotFound},
+		{"invalid config", ErrInvalidConfig, ErrCodeInvalidConfig},
+		{"task not found", ErrTaskNotFound, ErrCodeTaskNotFound},
+		{"task running", ErrTaskRunning, ErrCodeTaskRunning},
+		{"stdin disabled", ErrStdinDisabled, ErrCodeStdinDisabled},
+		{"process failed", ErrProcessFailed, ErrCodeProcessFailed},
 		{"unknown error", errors.New("some error"), "INTERNAL_ERROR"},
 	}
 	for _, tt := range tests {
@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxPatternLength is the maximum allowed length for filter patterns
+// to prevent potential DoS attacks from excessively complex patterns
+const MaxPatternLength = 256
+
+// MaxPatterns is the maximum number of patterns in one filter
+const MaxPatterns = 16
+
+// Filter is a LogFilter compiled for matching many entries: patterns are
+// validated and compiled, and process names put in sets, once up front. The
+// API, CLI, TUI, and log manager all filter with it, so a new filter
+// criterion is added to LogFilter and Filter alone.
+type Filter struct {
+	spec      LogFilter
+	processes map[string]bool // Nil matches every process
+	excluded  map[string]bool
+	regexes   []*regexp.Regexp
+	patterns  []string // Substrings, lowercased with IgnoreCase
+}
+
+// Compile validates the filter and compiles it for matching. Errors wrap
+// ErrInvalidPattern.
+func (f LogFilter) Compile() (*Filter, error) {
+	// Validate pattern count and length to prevent DoS
+	if len(f.Patterns) > MaxPatterns {
+		return nil, fmt.Errorf("%w: at most %d patterns are allowed", ErrInvalidPattern, MaxPatterns)
+	}
+	for _, pattern := range f.Patterns {
+		if len(pattern) > MaxPatternLength {
+			return nil, fmt.Errorf("%w: pattern exceeds maximum length of %d characters", ErrInvalidPattern, MaxPatternLength)
+		}
+	}
+
+	if f.Level != "" && NormalizeLogLevel(f.Level) != f.Level {
+		return nil, fmt.Errorf("%w: unknown level %q (want one of %s)", ErrInvalidPattern, f.Level, strings.Join(LogLevels, ", "))
+	}
+
+	if f.Stream != "" && f.Stream != StreamStdout && f.Stream != StreamStderr {
+		return nil, fmt.Errorf("%w: unknown stream %q (want stdout or stderr)", ErrInvalidPattern, f.Stream)
+	}
+
+	c := &Filter{spec: f, processes: nameSet(f.Processes), excluded: nameSet(f.ExcludeProcesses)}
+	for _, pattern := range f.Patterns {
+		switch {
+		case f.IsRegex:
+			if f.IgnoreCase {
+				pattern = "(?i)" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidPattern, err)
+			}
+			c.regexes = append(c.regexes, re)
+		case f.IgnoreCase:
+			c.patterns = append(c.patterns, strings.ToLower(pattern))
+		default:
+			c.patterns = append(c.patterns, pattern)
+		}
+	}
+	return c, nil
+}
+
+// MustCompile is like Compile but panics if the filter is invalid. It suits
+// filters without user-supplied patterns or levels.
+func (f LogFilter) MustCompile() *Filter {
+	c, err := f.Compile()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// nameSet returns a set of names, or nil if there are none
+func nameSet(names []string) map[string]bool {
+	if len(names) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// Spec returns the LogFilter the filter was compiled from
+func (c *Filter) Spec() LogFilter {
+	return c.spec
+}
+
+// Matches returns true if the entry matches every criterion of the filter
+func (c *Filter) Matches(entry LogEntry) bool {
+	if c.processes != nil && !c.processes[entry.Process] {
+		return false
+	}
+	if c.excluded[entry.Process] {
+		return false
+	}
+	if !c.spec.MatchesTime(entry.Timestamp) || !c.spec.MatchesLevel(entry.Level) {
+		return false
+	}
+	if c.spec.Stream != "" && entry.Stream != c.spec.Stream {
+		return false
+	}
+	if c.spec.AfterID > 0 && entry.ID <= c.spec.AfterID {
+		return false
+	}
+
+	// Every pattern must match
+	for _, re := range c.regexes {
+		if !re.MatchString(entry.Line) {
+			return false
+		}
+	}
+	if len(c.patterns) > 0 {
+		line := entry.Line
+		if c.spec.IgnoreCase {
+			line = strings.ToLower(line)
+		}
+		for _, pattern := range c.patterns {
+			if !strings.Contains(line, pattern) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Apply returns the entries matching the filter, in order
+func (c *Filter) Apply(entries []LogEntry) []LogEntry {
+	result := make([]LogEntry, 0, len(entries))
+	for _, entry := range entries {
+		if c.Matches(entry) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
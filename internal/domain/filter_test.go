@@ -0,0 +1,258 @@
+package domain
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeEntryWithProcess(process, line string) LogEntry {
+	return LogEntry{
+		Timestamp: time.Now(),
+		Process:   process,
+		Stream:    StreamStdout,
+		Line:      line,
+	}
+}
+
+func TestFilter_MatchesProcess(t *testing.T) {
+	filter, err := LogFilter{
+		Processes: []string{"web", "api"},
+	}.Compile()
+	require.NoError(t, err)
+
+	assert.True(t, filter.Matches(makeEntryWithProcess("web", "hello")))
+	assert.True(t, filter.Matches(makeEntryWithProcess("api", "hello")))
+	assert.False(t, filter.Matches(makeEntryWithProcess("worker", "hello")))
+}
+
+func TestFilter_MatchesSubstring(t *testing.T) {
+	filter, err := LogFilter{
+		Patterns: []string{"ERROR"},
+	}.Compile()
+	require.NoError(t, err)
+
+	assert.True(t, filter.Matches(makeEntryWithProcess("web", "ERROR: something went wrong")))
+	assert.True(t, filter.Matches(makeEntryWithProcess("web", "An ERROR occurred")))
+	assert.False(t, filter.Matches(makeEntryWithProcess("web", "All good")))
+	assert.False(t, filter.Matches(makeEntryWithProcess("web", "error lowercase")))
+}
+
+func TestFilter_MatchesRegex(t *testing.T) {
+	filter, err := LogFilter{
+		Patterns: []string{"(?i)error|warn"},
+		IsRegex:  true,
+	}.Compile()
+	require.NoError(t, err)
+
+	assert.True(t, filter.Matches(makeEntryWithProcess("web", "ERROR: something")))
+	assert.True(t, filter.Matches(makeEntryWithProcess("web", "error lowercase")))
+	assert.True(t, filter.Matches(makeEntryWithProcess("web", "WARN: something")))
+	assert.False(t, filter.Matches(makeEntryWithProcess("web", "All good")))
+}
+
+func TestFilter_MatchesAllPatterns(t *testing.T) {
+	filter, err := LogFilter{
+		Patterns: []string{"timeout", "db"},
+	}.Compile()
+	require.NoError(t, err)
+
+	assert.True(t, filter.Matches(makeEntryWithProcess("web", "db query timeout after 5s")))
+	assert.False(t, filter.Matches(makeEntryWithProcess("web", "http timeout")))
+	assert.False(t, filter.Matches(makeEntryWithProcess("web", "db connected")))
+
+	filter, err = LogFilter{
+		Patterns: []string{`^\[api\]`, `status=5\d\d`},
+		IsRegex:  true,
+	}.Compile()
+	require.NoError(t, err)
+
+	assert.True(t, filter.Matches(makeEntryWithProcess("api", "[api] GET /users status=503")))
+	assert.False(t, filter.Matches(makeEntryWithProcess("api", "[api] GET /users status=200")))
+}
+
+func TestFilter_TooManyPatterns(t *testing.T) {
+	_, err := LogFilter{
+		Patterns: make([]string, MaxPatterns+1),
+	}.Compile()
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+}
+
+func TestFilter_InvalidRegex(t *testing.T) {
+	_, err := LogFilter{
+		Patterns: []string{"[invalid"},
+		IsRegex:  true,
+	}.Compile()
+	require.Error(t, err)
+}
+
+func TestFilter_MatchesLevel(t *testing.T) {
+	filter, err := LogFilter{Level: "warn"}.Compile()
+	require.NoError(t, err)
+
+	entry := makeEntryWithProcess("web", `{"level":"error","msg":"db down"}`)
+	entry.Level = "error"
+	assert.True(t, filter.Matches(entry))
+
+	entry.Level = "info"
+	assert.False(t, filter.Matches(entry))
+
+	// Lines without a parsed level are left out
+	assert.False(t, filter.Matches(makeEntryWithProcess("web", "ERROR plain text")))
+}
+
+func TestFilter_UnknownLevel(t *testing.T) {
+	_, err := LogFilter{Level: "loud"}.Compile()
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+}
+
+func TestFilter_MatchesStream(t *testing.T) {
+	filter, err := LogFilter{Stream: StreamStderr}.Compile()
+	require.NoError(t, err)
+
+	entry := makeEntryWithProcess("web", "panic: nil map")
+	entry.Stream = StreamStderr
+	assert.True(t, filter.Matches(entry))
+
+	entry.Stream = StreamStdout
+	assert.False(t, filter.Matches(entry))
+
+	_, err = LogFilter{Stream: "stdin"}.Compile()
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+}
+
+func TestParseStreamFilter(t *testing.T) {
+	for input, want := range map[string]Stream{"": "", "both": "", "stdout": StreamStdout, "stderr": StreamStderr} {
+		got, err := ParseStreamFilter(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+	_, err := ParseStreamFilter("err")
+	assert.Error(t, err)
+}
+
+func TestFilter_CombinedFilters(t *testing.T) {
+	filter, err := LogFilter{
+		Processes: []string{"web"},
+		Patterns:  []string{"ERROR"},
+	}.Compile()
+	require.NoError(t, err)
+
+	// Matches both
+	assert.True(t, filter.Matches(makeEntryWithProcess("web", "ERROR: fail")))
+
+	// Wrong process
+	assert.False(t, filter.Matches(makeEntryWithProcess("api", "ERROR: fail")))
+
+	// Wrong pattern
+	assert.False(t, filter.Matches(makeEntryWithProcess("web", "All good")))
+}
+
+func TestFilter_ExcludeProcesses(t *testing.T) {
+	filter, err := LogFilter{ExcludeProcesses: []string{"worker"}}.Compile()
+	require.NoError(t, err)
+
+	assert.True(t, filter.Matches(makeEntryWithProcess("web", "hello")))
+	assert.False(t, filter.Matches(makeEntryWithProcess("worker", "hello")))
+}
+
+func TestFilter_AfterID(t *testing.T) {
+	filter, err := LogFilter{AfterID: 5}.Compile()
+	require.NoError(t, err)
+
+	entry := makeEntryWithProcess("web", "hello")
+	entry.ID = 5
+	assert.False(t, filter.Matches(entry))
+	entry.ID = 6
+	assert.True(t, filter.Matches(entry))
+}
+
+func TestFilter_IgnoreCase(t *testing.T) {
+	filter, err := LogFilter{Patterns: []string{"Error", "DB"}, IgnoreCase: true}.Compile()
+	require.NoError(t, err)
+
+	assert.True(t, filter.Matches(makeEntryWithProcess("web", "ERROR: db down")))
+	assert.False(t, filter.Matches(makeEntryWithProcess("web", "error: cache down")))
+
+	filter, err = LogFilter{Patterns: []string{"^warn"}, IsRegex: true, IgnoreCase: true}.Compile()
+	require.NoError(t, err)
+
+	assert.True(t, filter.Matches(makeEntryWithProcess("web", "WARN: slow query")))
+	assert.False(t, filter.Matches(makeEntryWithProcess("web", "not a WARN")))
+}
+
+func TestFilter_Apply(t *testing.T) {
+	entries := []LogEntry{
+		makeEntryWithProcess("web", "request 1"),
+		makeEntryWithProcess("api", "ERROR: failed"),
+		makeEntryWithProcess("web", "ERROR: timeout"),
+	}
+
+	spec := LogFilter{Patterns: []string{"ERROR"}}
+	filter := spec.MustCompile()
+	assert.Equal(t, spec, filter.Spec())
+	assert.Equal(t, []LogEntry{entries[1], entries[2]}, filter.Apply(entries))
+	assert.Len(t, LogFilter{}.MustCompile().Apply(entries), 3)
+
+	assert.Panics(t, func() { LogFilter{Level: "loud"}.MustCompile() })
+}
+
+// benchmarkEntries returns n entries spread over a few processes
+func benchmarkEntries(n int) []LogEntry {
+	processes := []string{"web", "api", "worker", "db"}
+	entries := make([]LogEntry, n)
+	for i := range entries {
+		entries[i] = LogEntry{
+			Timestamp: time.Now(),
+			Process:   processes[i%len(processes)],
+			Stream:    StreamStdout,
+			Line:      fmt.Sprintf("GET /api/users/%d status=%d duration=%dms", i, 200+i%4*100, i%250),
+			Level:     LogLevels[i%len(LogLevels)],
+		}
+	}
+	return entries
+}
+
+func BenchmarkFilter_Matches(b *testing.B) {
+	entries := benchmarkEntries(10_000)
+	filters := map[string]LogFilter{
+		"processes":   {Processes: []string{"web", "api"}},
+		"substring":   {Patterns: []string{"status=500"}},
+		"ignore case": {Patterns: []string{"STATUS=500"}, IgnoreCase: true},
+		"regex":       {Patterns: []string{`status=5\d\d`}, IsRegex: true},
+		"combined":    {Processes: []string{"web"}, Patterns: []string{"users", "status=5"}, Level: "warn"},
+	}
+	for name, spec := range filters {
+		b.Run(name, func(b *testing.B) {
+			filter := spec.MustCompile()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				filter.Matches(entries[i%len(entries)])
+			}
+		})
+	}
+}
+
+func BenchmarkFilter_Compile(b *testing.B) {
+	spec := LogFilter{Processes: []string{"web", "api"}, Patterns: []string{`status=5\d\d`, `users/\d+`}, IsRegex: true}
+	for i := 0; i < b.N; i++ {
+		if _, err := spec.Compile(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestLogParams_Filter(t *testing.T) {
+	params := LogParams{Process: "web,api", Patterns: []string{"timeout"}, Regex: true, Level: "warn", Stream: StreamStderr, Lines: 50}
+	assert.Equal(t, LogFilter{
+		Processes: []string{"web", "api"},
+		Patterns:  []string{"timeout"},
+		IsRegex:   true,
+		Level:     "warn",
+		Stream:    StreamStderr,
+	}, params.Filter())
+	assert.True(t, LogParams{Lines: 50}.Filter().IsEmpty())
+}
@@ -16,13 +16,32 @@ func (s HealthStatus) String() string {
 	return string(s)
 }
 
-// HealthConfig defines health check configuration
+// HealthConfig defines health check configuration. The check runs Cmd; or
+// when URL is set, requests URL with the Host header set to Host and passes
+// on ExpectedStatus, or on a 2xx or 3xx response when it's zero; or when TCP
+// is set, connects to that host:port. URL and TCP may refer to the process's
+// environment, e.g. $PORT.
 type HealthConfig struct {
-	Cmd         string        `yaml:"cmd"`
-	Interval    time.Duration `yaml:"interval"`
-	Timeout     time.Duration `yaml:"timeout"`
-	Retries     int           `yaml:"retries"`
-	StartPeriod time.Duration `yaml:"start_period"`
+	Cmd            string        `yaml:"cmd"`
+	URL            string        `yaml:"url,omitempty"`
+	Host           string        `yaml:"host,omitempty"`
+	ExpectedStatus int           `yaml:"expected_status,omitempty"`
+	TCP            string        `yaml:"tcp,omitempty"`
+	Interval       time.Duration `yaml:"interval"`
+	Timeout        time.Duration `yaml:"timeout"`
+	Retries        int           `yaml:"retries"`
+	StartPeriod    time.Duration `yaml:"start_period"`
+}
+
+// IsEnabled reports whether a check is configured
+func (c *HealthConfig) IsEnabled() bool {
+	return c != nil && (c.Cmd != "" || c.URL != "" || c.TCP != "")
+}
+
+// IsDirect reports whether a check is configured that tests the process
+// itself, rather than its route through the proxy
+func (c *HealthConfig) IsDirect() bool {
+	return c.IsEnabled() && c.Host == ""
 }
 
 // WithDefaults returns a copy of the config with default values applied
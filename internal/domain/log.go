@@ -1,6 +1,11 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
 
 // Stream represents the output stream type
 type Stream string
@@ -15,37 +20,146 @@ func (s Stream) String() string {
 	return string(s)
 }
 
+// StreamBoth selects both streams where a stream is chosen to filter by
+const StreamBoth = "both"
+
+// ParseStreamFilter returns the stream a --stream flag or stream parameter
+// selects: stdout, stderr, or both, returned as "" since it filters nothing
+func ParseStreamFilter(s string) (Stream, error) {
+	switch s {
+	case "", StreamBoth:
+		return "", nil
+	case string(StreamStdout), string(StreamStderr):
+		return Stream(s), nil
+	}
+	return "", fmt.Errorf("unknown stream %q (want stdout, stderr, or both)", s)
+}
+
+// Log formats a process's output can be parsed as
+const (
+	LogFormatJSON   = "json"   // One JSON object per line
+	LogFormatLogfmt = "logfmt" // key=value pairs, e.g. level=info msg="listening"
+)
+
 // LogEntry represents a single log line from a process
 type LogEntry struct {
+	ID        uint64    `json:"id,omitempty"` // Set by the log store; increases with each entry written
 	Timestamp time.Time `json:"timestamp"`
 	Process   string    `json:"process"`
 	Stream    Stream    `json:"stream"`
 	Line      string    `json:"line"`
+
+	// Set for processes with a log_format when the line parses in it
+	Level  string            `json:"level,omitempty"`  // Normalized level, one of LogLevels
+	Fields map[string]string `json:"fields,omitempty"` // Fields parsed from the line, e.g. "msg" and "ts"
+}
+
+// LogLevels are the normalized log levels, in increasing severity
+var LogLevels = []string{"trace", "debug", "info", "warn", "error", "fatal"}
+
+// logLevelAliases maps other spellings of levels to LogLevels
+var logLevelAliases = map[string]string{
+	"trc":         "trace",
+	"dbg":         "debug",
+	"inf":         "info",
+	"information": "info",
+	"notice":      "info",
+	"wrn":         "warn",
+	"warning":     "warn",
+	"err":         "error",
+	"crit":        "fatal",
+	"critical":    "fatal",
+	"panic":       "fatal",
+	"dpanic":      "fatal",
+	"emerg":       "fatal",
+	"alert":       "fatal",
+}
+
+// NormalizeLogLevel returns the LogLevels entry for a level as loggers spell
+// it, e.g. "WARNING" is "warn", or "" if it isn't a known level
+func NormalizeLogLevel(level string) string {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if alias, ok := logLevelAliases[level]; ok {
+		return alias
+	}
+	if logLevelRank(level) < 0 {
+		return ""
+	}
+	return level
+}
+
+// logLevelRank returns the index of a normalized level in LogLevels, or -1
+func logLevelRank(level string) int {
+	for i, l := range LogLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
 }
 
 // LogFilter defines criteria for filtering log entries
 type LogFilter struct {
-	Processes []string // Filter to specific process names
-	Pattern   string   // Filter by pattern match
-	IsRegex   bool     // If true, Pattern is a regex; otherwise substring match
+	Processes        []string // Filter to specific process names
+	ExcludeProcesses []string // Leave out these process names
+	Patterns         []string // Filter by pattern match; every pattern must match
+	IsRegex          bool     // If true, Patterns are regexes; otherwise substring matches
+	IgnoreCase       bool     // If true, Patterns match regardless of case
+
+	Level  string // Only entries at this normalized level or more severe (empty means no bound)
+	Stream Stream // Only entries from this stream (empty means both)
+
+	Since time.Time // Only entries at or after this time (zero means no bound)
+	Until time.Time // Only entries at or before this time (zero means no bound)
+
+	AfterID uint64 // Only entries with a greater ID, for paging (zero means no bound)
 }
 
 // IsEmpty returns true if no filters are set
 func (f LogFilter) IsEmpty() bool {
-	return len(f.Processes) == 0 && f.Pattern == ""
+	return len(f.Processes) == 0 && len(f.ExcludeProcesses) == 0 && len(f.Patterns) == 0 &&
+		f.Level == "" && f.Stream == "" && f.Since.IsZero() && f.Until.IsZero() && f.AfterID == 0
 }
 
-// MatchesProcess returns true if the process name matches the filter
-func (f LogFilter) MatchesProcess(name string) bool {
-	if len(f.Processes) == 0 {
+// MatchesLevel returns true if level is at least as severe as the filter's.
+// Entries without a level never match a level filter.
+func (f LogFilter) MatchesLevel(level string) bool {
+	if f.Level == "" {
 		return true
 	}
-	for _, p := range f.Processes {
-		if p == name {
-			return true
-		}
+	rank := logLevelRank(level)
+	return rank >= 0 && rank >= logLevelRank(f.Level)
+}
+
+// MatchesTime returns true if the timestamp falls within the filter's time range
+func (f LogFilter) MatchesTime(t time.Time) bool {
+	if !f.Since.IsZero() && t.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && t.After(f.Until) {
+		return false
 	}
-	return false
+	return true
+}
+
+// MatchesProcess returns true if the process name matches the filter
+func (f LogFilter) MatchesProcess(name string) bool {
+	if slices.Contains(f.ExcludeProcesses, name) {
+		return false
+	}
+	return len(f.Processes) == 0 || slices.Contains(f.Processes, name)
+}
+
+// LogThrottle limits how much of a process's output is logged, so a runaway
+// process can't push the other processes out of the buffer or flood the TUI
+type LogThrottle struct {
+	MaxLinesPerSec  int  // Lines kept per second; the rest are dropped and counted (0 = unlimited)
+	CollapseRepeats bool // Keep one of a run of identical lines, noting how often it repeated
+}
+
+// IsEmpty returns true if the throttle limits nothing
+func (t LogThrottle) IsEmpty() bool {
+	return t.MaxLinesPerSec <= 0 && !t.CollapseRepeats
 }
 
 // LogStats contains statistics about the log buffer
@@ -53,4 +167,6 @@ type LogStats struct {
 	TotalEntries int
 	BufferSize   int
 	Subscribers  int
+	MemoryBytes  int64 // Approximate memory the buffered entries use (0 if the store doesn't track it)
+	MaxMemory    int64 // Cap on MemoryBytes (0 = no cap)
 }
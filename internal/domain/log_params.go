@@ -1,19 +1,55 @@
 package domain
 
+import (
+	"strings"
+	"time"
+)
+
 // LogParams holds parameters for log retrieval and streaming.
 // This type is shared between the TUI and CLI packages.
 //
 // Fields:
 //   - Process: Filter logs to a specific process name. Empty string means all processes.
 //   - Lines: Number of historical log lines to return. 0 means use server default.
-//   - Pattern: Text pattern for filtering log lines. Empty string means no filtering.
-//   - Regex: If true, Pattern is treated as a regular expression. If false, Pattern
-//     is treated as a literal substring match. Has no effect when Pattern is empty.
+//   - Patterns: Text patterns for filtering log lines; a line must match all of
+//     them. Empty means no filtering.
+//   - Regex: If true, Patterns are treated as regular expressions. If false, they
+//     are treated as literal substring matches. Has no effect when Patterns is empty.
+//   - Level: Return only logs at this level or more severe, e.g. "warn".
+//     Empty means no filtering.
+//   - Stream: Return only logs from this stream, stdout or stderr. Empty
+//     means both.
+//   - Since: Return only logs at or after this time. Zero means no bound.
+//   - Until: Return only logs at or before this time. Zero means no bound.
+//   - Backfill: When streaming, number of buffered log lines to send before
+//     new ones. 0 means the stream starts with new lines.
 type LogParams struct {
-	Process string
-	Lines   int
-	Pattern string
-	Regex   bool
+	Process  string
+	Lines    int
+	Patterns []string
+	Regex    bool
+	Level    string
+	Stream   Stream
+	Since    time.Time
+	Until    time.Time
+	Backfill int
+}
+
+// Filter returns the log filter the parameters describe, for checking them
+// before they are sent
+func (p LogParams) Filter() LogFilter {
+	filter := LogFilter{
+		Patterns: p.Patterns,
+		IsRegex:  p.Regex,
+		Level:    p.Level,
+		Stream:   p.Stream,
+		Since:    p.Since,
+		Until:    p.Until,
+	}
+	if p.Process != "" {
+		filter.Processes = strings.Split(p.Process, ",")
+	}
+	return filter
 }
 
 // ProxyRequestParams holds parameters for proxy request retrieval and streaming.
@@ -24,11 +60,28 @@ type LogParams struct {
 //   - Method: Filter to requests with a specific HTTP method. Empty string means all.
 //   - MinStatus: Filter to requests with status code >= this value. 0 means no minimum.
 //   - MaxStatus: Filter to requests with status code <= this value. 0 means no maximum.
+//   - Until: Filter to requests at or before this time. Zero means no bound.
 //   - Limit: Maximum number of requests to return. 0 means use server default.
 type ProxyRequestParams struct {
 	Subdomain string
 	Method    string
 	MinStatus int
 	MaxStatus int
+	Until     time.Time
 	Limit     int
 }
+
+// EventParams holds parameters for streaming supervisor events.
+//
+// Fields:
+//   - Process: Filter to events of a specific process name. Empty string means all.
+//   - Types: Filter to these event types, e.g. "process_crashed". Empty means all.
+//   - Backfill: Number of recent events to send before new ones. 0 means the
+//     stream starts with new events.
+//   - Follow: If false, the stream ends after the backfill.
+type EventParams struct {
+	Process  string
+	Types    []string
+	Backfill int
+	Follow   bool
+}
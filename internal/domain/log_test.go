@@ -2,6 +2,7 @@ package domain
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -29,12 +30,27 @@ func TestLogFilter_IsEmpty(t *testing.T) {
 		},
 		{
 			name:   "with pattern",
-			filter: LogFilter{Pattern: "error"},
+			filter: LogFilter{Patterns: []string{"error"}},
 			want:   false,
 		},
 		{
 			name:   "with both",
-			filter: LogFilter{Processes: []string{"web"}, Pattern: "error"},
+			filter: LogFilter{Processes: []string{"web"}, Patterns: []string{"error"}},
+			want:   false,
+		},
+		{
+			name:   "with level",
+			filter: LogFilter{Level: "error"},
+			want:   false,
+		},
+		{
+			name:   "with time range",
+			filter: LogFilter{Since: time.Now()},
+			want:   false,
+		},
+		{
+			name:   "with after ID",
+			filter: LogFilter{AfterID: 3},
 			want:   false,
 		},
 	}
@@ -70,6 +86,18 @@ func TestLogFilter_MatchesProcess(t *testing.T) {
 			process: "worker",
 			want:    false,
 		},
+		{
+			name:    "does not match left out process",
+			filter:  LogFilter{ExcludeProcesses: []string{"worker"}},
+			process: "worker",
+			want:    false,
+		},
+		{
+			name:    "leaving out wins over including",
+			filter:  LogFilter{Processes: []string{"web"}, ExcludeProcesses: []string{"web"}},
+			process: "web",
+			want:    false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -77,3 +105,40 @@ func TestLogFilter_MatchesProcess(t *testing.T) {
 		})
 	}
 }
+
+func TestLogFilter_MatchesTime(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	filter := LogFilter{Since: base, Until: base.Add(time.Minute)}
+
+	assert.True(t, LogFilter{}.MatchesTime(base))
+	assert.True(t, filter.MatchesTime(base))
+	assert.True(t, filter.MatchesTime(base.Add(time.Minute)))
+	assert.False(t, filter.MatchesTime(base.Add(-time.Second)))
+	assert.False(t, filter.MatchesTime(base.Add(time.Minute+time.Second)))
+}
+
+func TestNormalizeLogLevel(t *testing.T) {
+	tests := map[string]string{
+		"info":    "info",
+		"INFO":    "info",
+		"Warning": "warn",
+		"err":     "error",
+		"panic":   "fatal",
+		" debug ": "debug",
+		"verbose": "",
+		"":        "",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, NormalizeLogLevel(in), "NormalizeLogLevel(%q)", in)
+	}
+}
+
+func TestLogFilter_MatchesLevel(t *testing.T) {
+	filter := LogFilter{Level: "warn"}
+
+	assert.True(t, LogFilter{}.MatchesLevel(""))
+	assert.True(t, filter.MatchesLevel("warn"))
+	assert.True(t, filter.MatchesLevel("fatal"))
+	assert.False(t, filter.MatchesLevel("info"))
+	assert.False(t, filter.MatchesLevel(""), "entries without a level don't match")
+}
@@ -17,6 +17,9 @@ const (
 	ProcessStateStopping ProcessState = "stopping"
 	// ProcessStateCrashed indicates the process exited unexpectedly or failed to start
 	ProcessStateCrashed ProcessState = "crashed"
+	// ProcessStateFailed indicates the process crashed too often in a short
+	// time, and isn't restarted until it is started by hand
+	ProcessStateFailed ProcessState = "failed"
 )
 
 // String returns the string representation of ProcessState
@@ -29,31 +32,86 @@ func (s ProcessState) IsRunning() bool {
 	return s == ProcessStateRunning
 }
 
-// IsStopped returns true if the process is stopped or crashed
+// IsStopped returns true if the process is stopped, crashed, or failed
 func (s ProcessState) IsStopped() bool {
-	return s == ProcessStateStopped || s == ProcessStateCrashed
+	return s == ProcessStateStopped || s == ProcessStateCrashed || s == ProcessStateFailed
 }
 
+// RestartReason says why a process was last restarted
+type RestartReason string
+
+const (
+	// RestartReasonRequest is a restart asked for through prox restart, the
+	// API, or the TUI
+	RestartReasonRequest RestartReason = "requested"
+	// RestartReasonWatch is a restart after files the process watches changed
+	RestartReasonWatch RestartReason = "file change"
+	// RestartReasonReload is a restart after a config reload changed the
+	// process's definition
+	RestartReasonReload RestartReason = "config reload"
+	// RestartReasonUpdate is a restart applying a definition changed
+	// through the API
+	RestartReasonUpdate RestartReason = "definition update"
+)
+
 // ProcessConfig defines the configuration for a single process
 type ProcessConfig struct {
-	Name        string
-	Cmd         string
-	Env         map[string]string
-	EnvFile     string
-	Healthcheck *HealthConfig
+	Name         string
+	Cmd          string
+	Shell        []string // Shell command line Cmd runs through, e.g. ["/bin/zsh", "-l"] (nil = sh)
+	Direnv       bool     // Run the shell through direnv exec, loading .envrc
+	Env          map[string]string
+	EnvFile      string
+	CleanEnv     bool // Start with only Env rather than prox's environment plus Env (inherit_env: false)
+	Port         int  // Port injected as $PORT (0 if none)
+	AutoPort     bool // Port is dynamically allocated
+	Healthcheck  *HealthConfig
+	WaitFor      []string         // External dependencies (tcp:// or http(s):// URLs) to wait for before starting
+	WaitTimeout  time.Duration    // Maximum time to wait for dependencies (0 = default)
+	DependsOn    []string         // Processes to start first (nil = inferred from WaitFor)
+	Ready        string           // tcp:// or http(s):// probe that must pass before the process counts as running
+	ReadyTimeout time.Duration    // Time after which a process that isn't ready is reported (0 = default)
+	Lazy         bool             // Started by the first proxy request rather than at startup
+	IdleTimeout  time.Duration    // Stop a lazy process after this long without requests (0 = never)
+	LogBuffer    int              // Log entries reserved for this process (0 = default)
+	LogThrottle  LogThrottle      // Limits on how much of the process's output is logged
+	LogFormat    string           // LogFormatJSON or LogFormatLogfmt to parse lines into fields ("" = plain text)
+	Stdin        bool             // Give the process a stdin that prox attach writes to (false = no input)
+	Synthetic    *SyntheticConfig // Generate output in prox instead of running Cmd (nil = run Cmd)
+}
+
+// SyntheticConfig configures a synthetic process, which prox runs itself to
+// write generated log lines, for demos and for load-testing the log pipeline
+type SyntheticConfig struct {
+	Rate             int           // Lines per second
+	ExitAfter        time.Duration // Exit with status 0 after this long (0 = run until stopped)
+	CrashProbability float64       // Chance each second of exiting with status 1
 }
 
 // ProcessInfo represents the runtime state of a process
 type ProcessInfo struct {
-	Name          string            `json:"name"`
-	State         ProcessState      `json:"status"`
-	PID           int               `json:"pid"`
-	StartedAt     time.Time         `json:"started_at,omitempty"`
-	RestartCount  int               `json:"restarts"`
-	Health        HealthStatus      `json:"health"`
-	HealthDetails *HealthState      `json:"healthcheck,omitempty"`
-	Cmd           string            `json:"cmd,omitempty"`
-	Env           map[string]string `json:"env,omitempty"`
+	Name           string            `json:"name"`
+	State          ProcessState      `json:"status"`
+	PID            int               `json:"pid"`
+	Port           int               `json:"port,omitempty"`
+	StartedAt      time.Time         `json:"started_at,omitempty"`
+	RestartCount   int               `json:"restarts"`
+	RestartReason  RestartReason     `json:"restart_reason,omitempty"` // Why the process last restarted ("" if it hasn't)
+	Health         HealthStatus      `json:"health"`
+	HealthDetails  *HealthState      `json:"healthcheck,omitempty"`
+	Cmd            string            `json:"cmd,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	Lazy           bool              `json:"lazy,omitempty"`
+	LastError      string            `json:"last_error,omitempty"`      // Why the last start failed
+	PendingRestart bool              `json:"pending_restart,omitempty"` // An update to the definition waits for a restart
+}
+
+// StartFailure records why a process failed to start. It is kept until the
+// process next starts successfully.
+type StartFailure struct {
+	Process string
+	Error   string
+	Time    time.Time
 }
 
 // UptimeSeconds returns the number of seconds the process has been running
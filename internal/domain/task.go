@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// TaskState is the state of a task's last run
+type TaskState string
+
+const (
+	// TaskStateIdle indicates the task hasn't run since prox started
+	TaskStateIdle TaskState = "idle"
+	// TaskStateRunning indicates the task is running
+	TaskStateRunning TaskState = "running"
+	// TaskStateSucceeded indicates the task's last run exited with status 0
+	TaskStateSucceeded TaskState = "succeeded"
+	// TaskStateFailed indicates the task's last run exited with a non-zero
+	// status, or failed to start
+	TaskStateFailed TaskState = "failed"
+)
+
+// String returns the string representation of TaskState
+func (s TaskState) String() string {
+	return string(s)
+}
+
+// TaskInfo describes a task and its last run
+type TaskInfo struct {
+	Name        string
+	Cmd         string
+	Description string
+	State       TaskState
+	PID         int       // PID of the running task (0 when not running)
+	ExitCode    int       // Exit status of the last run; negative for a signal
+	StartedAt   time.Time // Zero if the task hasn't run
+	FinishedAt  time.Time // Zero if the task hasn't finished
+	Error       string    // Why the last run failed to start
+}
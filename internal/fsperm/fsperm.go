@@ -0,0 +1,143 @@
+// Package fsperm sets the permissions of the files and directories prox
+// creates for itself: state, the API token, logs, and captures. They are
+// private to the user by default, which matters on shared dev servers;
+// daemon.file_mode and daemon.dir_mode loosen or keep that.
+//
+// Modes are applied explicitly rather than through the umask, which
+// processes prox starts would inherit.
+package fsperm
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/charliek/prox/internal/constants"
+)
+
+var (
+	mu       sync.RWMutex
+	fileMode os.FileMode = constants.FilePermissionPrivate
+	dirMode  os.FileMode = constants.DirPermissionPrivate
+)
+
+// SetModes sets the modes of files and directories created from now on
+func SetModes(file, dir os.FileMode) {
+	mu.Lock()
+	defer mu.Unlock()
+	fileMode, dirMode = file.Perm(), dir.Perm()
+}
+
+// File returns the mode for new files
+func File() os.FileMode {
+	mu.RLock()
+	defer mu.RUnlock()
+	return fileMode
+}
+
+// Dir returns the mode for new directories
+func Dir() os.FileMode {
+	mu.RLock()
+	defer mu.RUnlock()
+	return dirMode
+}
+
+// MkdirAll creates dir and any missing parents with Dir()
+func MkdirAll(dir string) error {
+	return os.MkdirAll(dir, Dir())
+}
+
+// WriteFile writes data to path, creating it with File() and resetting the
+// mode of a file that already exists
+func WriteFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, File()); err != nil {
+		return err
+	}
+	return os.Chmod(path, File())
+}
+
+// Fix is a permission change made by Harden
+type Fix struct {
+	Path string
+	From os.FileMode
+	To   os.FileMode
+}
+
+func (f Fix) String() string {
+	return fmt.Sprintf("%s: %04o -> %04o", f.Path, f.From, f.To)
+}
+
+// Harden removes permission bits beyond File() and Dir() from dir and
+// everything in it except the skipped paths, and returns what it changed.
+// Symlinks and sockets are left alone, and a missing dir is not an error.
+// Errors for single entries don't stop the walk; they are joined into the
+// returned error.
+func Harden(dir string, skip ...string) ([]Fix, error) {
+	file, directory := File(), Dir()
+
+	var fixes []Fix
+	var errs []error
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			errs = append(errs, err)
+			return nil
+		}
+
+		if slices.Contains(skip, path) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		allowed := file
+		switch {
+		case d.IsDir():
+			allowed = directory
+		case !d.Type().IsRegular():
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		mode := info.Mode().Perm()
+		if mode&^allowed == 0 {
+			return nil
+		}
+		if err := os.Chmod(path, mode&allowed); err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		fixes = append(fixes, Fix{Path: path, From: mode, To: mode & allowed})
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return fixes, errors.Join(errs...)
+}
+
+// OthersCanWrite reports whether users other than the owner may write to
+// path, through its group or world permission bits. A missing path can't be
+// written and returns false.
+func OthersCanWrite(path string) (bool, os.FileMode, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, 0, nil
+		}
+		return false, 0, err
+	}
+	mode := info.Mode().Perm()
+	return mode&0022 != 0, mode, nil
+}
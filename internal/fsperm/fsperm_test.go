@@ -0,0 +1,119 @@
+package fsperm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withModes sets the modes for a test and restores the defaults after
+func withModes(t *testing.T, file, dir os.FileMode) {
+	t.Helper()
+	prevFile, prevDir := File(), Dir()
+	SetModes(file, dir)
+	t.Cleanup(func() { SetModes(prevFile, prevDir) })
+}
+
+func mode(t *testing.T, path string) os.FileMode {
+	t.Helper()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	return info.Mode().Perm()
+}
+
+func TestWriteFile_ResetsMode(t *testing.T) {
+	withModes(t, 0600, 0700)
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0644))
+	require.NoError(t, os.Chmod(path, 0644))
+
+	require.NoError(t, WriteFile(path, []byte("new")))
+	assert.Equal(t, os.FileMode(0600), mode(t, path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+}
+
+func TestMkdirAll_UsesDirMode(t *testing.T) {
+	withModes(t, 0640, 0750)
+	dir := filepath.Join(t.TempDir(), "a", "b")
+	require.NoError(t, MkdirAll(dir))
+	// The umask can only remove bits
+	assert.Zero(t, mode(t, dir)&^0750)
+}
+
+func TestHarden(t *testing.T) {
+	withModes(t, 0600, 0700)
+	root := t.TempDir()
+	require.NoError(t, os.Chmod(root, 0777))
+	sub := filepath.Join(root, "captures")
+	require.NoError(t, os.Mkdir(sub, 0700))
+	loose := filepath.Join(sub, "req.json")
+	require.NoError(t, os.WriteFile(loose, nil, 0600))
+	require.NoError(t, os.Chmod(loose, 0666))
+	private := filepath.Join(root, "state.json")
+	require.NoError(t, os.WriteFile(private, nil, 0600))
+	skipped := filepath.Join(root, "certs")
+	require.NoError(t, os.Mkdir(skipped, 0700))
+	cert := filepath.Join(skipped, "ca.pem")
+	require.NoError(t, os.WriteFile(cert, nil, 0600))
+	require.NoError(t, os.Chmod(cert, 0644))
+
+	fixes, err := Harden(root, skipped)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Fix{
+		{Path: root, From: 0777, To: 0700},
+		{Path: loose, From: 0666, To: 0600},
+	}, fixes)
+	assert.Equal(t, os.FileMode(0700), mode(t, root))
+	assert.Equal(t, os.FileMode(0600), mode(t, loose))
+	assert.Equal(t, os.FileMode(0644), mode(t, cert))
+
+	fixes, err = Harden(root, skipped)
+	require.NoError(t, err)
+	assert.Empty(t, fixes)
+}
+
+func TestHarden_KeepsLooserConfiguredModes(t *testing.T) {
+	withModes(t, 0640, 0750)
+	root := t.TempDir()
+	require.NoError(t, os.Chmod(root, 0750))
+	path := filepath.Join(root, "prox.log")
+	require.NoError(t, os.WriteFile(path, nil, 0600))
+	require.NoError(t, os.Chmod(path, 0644))
+
+	fixes, err := Harden(root)
+	require.NoError(t, err)
+	assert.Equal(t, []Fix{{Path: path, From: 0644, To: 0640}}, fixes)
+}
+
+func TestHarden_MissingDir(t *testing.T) {
+	fixes, err := Harden(filepath.Join(t.TempDir(), "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, fixes)
+}
+
+func TestOthersCanWrite(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Chmod(dir, 0700))
+	writable, _, err := OthersCanWrite(dir)
+	require.NoError(t, err)
+	assert.False(t, writable)
+
+	require.NoError(t, os.Chmod(dir, 0770))
+	writable, mode, err := OthersCanWrite(dir)
+	require.NoError(t, err)
+	assert.True(t, writable)
+	assert.Equal(t, os.FileMode(0770), mode)
+
+	writable, _, err = OthersCanWrite(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.False(t, writable)
+}
+
+func TestFixString(t *testing.T) {
+	assert.Equal(t, "/tmp/x: 0666 -> 0600", Fix{Path: "/tmp/x", From: 0666, To: 0600}.String())
+}
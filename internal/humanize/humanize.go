@@ -0,0 +1,226 @@
+// Package humanize formats durations, byte sizes, counts, and timestamps for
+// people, so the CLI, TUI, and API describe the same value the same way.
+//
+// Values are rounded to the smallest unit shown, carrying into the next unit
+// when rounding reaches it: 59.6s is "1m0s", not "59s" or "60s".
+package humanize
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Style selects between compact and spelled-out output
+type Style int
+
+const (
+	// Short is compact output for tables and log lines, e.g. "3m12s" or
+	// "1.5 MiB"
+	Short Style = iota
+	// Long spells units out, e.g. "3 minutes 12 seconds" or
+	// "1.5 MiB (1,572,864 bytes)"
+	Long
+)
+
+// Unit is the singular and plural name of a unit in the Long style
+type Unit struct {
+	One   string
+	Other string
+}
+
+// name returns the unit's name for n of it
+func (u Unit) name(n float64) string {
+	if n == 1 {
+		return u.One
+	}
+	return u.Other
+}
+
+// Locale holds the words and separators a Formatter uses
+type Locale struct {
+	Decimal string // Decimal separator, e.g. "."
+	Group   string // Thousands separator in counts, e.g. ","
+
+	Millisecond Unit
+	Second      Unit
+	Minute      Unit
+	Hour        Unit
+	Day         Unit
+	Byte        Unit
+}
+
+// English is the default locale
+var English = Locale{
+	Decimal:     ".",
+	Group:       ",",
+	Millisecond: Unit{"millisecond", "milliseconds"},
+	Second:      Unit{"second", "seconds"},
+	Minute:      Unit{"minute", "minutes"},
+	Hour:        Unit{"hour", "hours"},
+	Day:         Unit{"day", "days"},
+	Byte:        Unit{"byte", "bytes"},
+}
+
+// Formatter formats values in a style and locale
+type Formatter struct {
+	Style  Style
+	Locale Locale
+}
+
+// New returns a formatter for style in English
+func New(style Style) Formatter {
+	return Formatter{Style: style, Locale: English}
+}
+
+// Default is the formatter used by the package-level functions
+var Default = New(Short)
+
+// Duration formats d with the Default formatter
+func Duration(d time.Duration) string {
+	return Default.Duration(d)
+}
+
+// Bytes formats a byte size with the Default formatter
+func Bytes(n int64) string {
+	return Default.Bytes(n)
+}
+
+// Count formats n of a noun with the Default formatter
+func Count(n int, one, other string) string {
+	return Default.Count(n, one, other)
+}
+
+// Duration formats d using the two largest units that matter: milliseconds
+// below a second, tenths of a second below 10s, then seconds, minutes and
+// seconds, hours and minutes, and days and hours.
+func (f Formatter) Duration(d time.Duration) string {
+	if d < 0 {
+		return "-" + f.Duration(-d)
+	}
+
+	if d < time.Second {
+		if r := d.Round(time.Millisecond); r < time.Second {
+			return f.unit(float64(r/time.Millisecond), "ms", f.Locale.Millisecond)
+		}
+	}
+	if d < 10*time.Second {
+		if r := d.Round(100 * time.Millisecond); r < 10*time.Second {
+			return f.unit(r.Seconds(), "s", f.Locale.Second)
+		}
+	}
+	if d < time.Minute {
+		if r := d.Round(time.Second); r < time.Minute {
+			return f.unit(r.Seconds(), "s", f.Locale.Second)
+		}
+	}
+	if d < time.Hour {
+		if r := d.Round(time.Second); r < time.Hour {
+			return f.pair(int64(r/time.Minute), "m", f.Locale.Minute, int64(r%time.Minute/time.Second), "s", f.Locale.Second)
+		}
+	}
+	const day = 24 * time.Hour
+	if d < day {
+		if r := d.Round(time.Minute); r < day {
+			return f.pair(int64(r/time.Hour), "h", f.Locale.Hour, int64(r%time.Hour/time.Minute), "m", f.Locale.Minute)
+		}
+	}
+	r := d.Round(time.Hour)
+	return f.pair(int64(r/day), "d", f.Locale.Day, int64(r%day/time.Hour), "h", f.Locale.Hour)
+}
+
+// Bytes formats a byte size with a binary unit, e.g. "1.5 MiB". The Long
+// style adds the exact size, e.g. "1.5 MiB (1,572,864 bytes)".
+func (f Formatter) Bytes(n int64) string {
+	if n < 0 {
+		return "-" + f.Bytes(-n)
+	}
+	exact := f.Count(int(n), f.Locale.Byte.One, f.Locale.Byte.Other)
+	if n < 1024 {
+		if f.Style == Long {
+			return exact
+		}
+		return strconv.FormatInt(n, 10) + " B"
+	}
+
+	value := float64(n)
+	exp := -1
+	for exp < len(binaryPrefixes)-1 && roundSize(value) >= 1024 {
+		value /= 1024
+		exp++
+	}
+	size := f.decimal(roundSize(value)) + " " + binaryPrefixes[exp] + "iB"
+	if f.Style == Long {
+		return size + " (" + exact + ")"
+	}
+	return size
+}
+
+// binaryPrefixes are the prefixes of KiB through EiB
+var binaryPrefixes = []string{"K", "M", "G", "T", "P", "E"}
+
+// roundSize rounds to tenths below 10 and to integers above
+func roundSize(v float64) float64 {
+	if v < 9.95 {
+		return math.Round(v*10) / 10
+	}
+	return math.Round(v)
+}
+
+// Count formats n with its thousands grouped followed by the noun, e.g.
+// "1 error" or "1,204 errors"
+func (f Formatter) Count(n int, one, other string) string {
+	noun := other
+	if n == 1 {
+		noun = one
+	}
+	return f.number(int64(n)) + " " + noun
+}
+
+// unit formats a single value in a duration, e.g. "850ms" or "1.5 seconds"
+func (f Formatter) unit(v float64, short string, long Unit) string {
+	if f.Style == Long {
+		return f.decimal(v) + " " + long.name(v)
+	}
+	return f.decimal(v) + short
+}
+
+// pair formats a duration in two units, e.g. "3m0s" or "3 minutes". The
+// Long style leaves out a zero second unit.
+func (f Formatter) pair(a int64, shortA string, longA Unit, b int64, shortB string, longB Unit) string {
+	if f.Style == Long {
+		s := f.number(a) + " " + longA.name(float64(a))
+		if b != 0 {
+			s += " " + f.number(b) + " " + longB.name(float64(b))
+		}
+		return s
+	}
+	return strconv.FormatInt(a, 10) + shortA + strconv.FormatInt(b, 10) + shortB
+}
+
+// decimal formats v with at most one decimal place, dropping ".0"
+func (f Formatter) decimal(v float64) string {
+	s := strconv.FormatFloat(math.Round(v*10)/10, 'f', 1, 64)
+	s = strings.TrimSuffix(s, ".0")
+	return strings.Replace(s, ".", f.Locale.Decimal, 1)
+}
+
+// number formats n with its thousands grouped, e.g. "1,572,864"
+func (f Formatter) number(n int64) string {
+	if n < 0 {
+		return "-" + f.number(-n)
+	}
+	s := strconv.FormatInt(n, 10)
+	if f.Locale.Group == "" {
+		return s
+	}
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteString(f.Locale.Group)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,104 @@
+package humanize
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		d     time.Duration
+		short string
+		long  string
+	}{
+		{0, "0ms", "0 milliseconds"},
+		{850 * time.Millisecond, "850ms", "850 milliseconds"},
+		{999600 * time.Microsecond, "1s", "1 second"},
+		{1500 * time.Millisecond, "1.5s", "1.5 seconds"},
+		{9960 * time.Millisecond, "10s", "10 seconds"},
+		{45 * time.Second, "45s", "45 seconds"},
+		{59600 * time.Millisecond, "1m0s", "1 minute"},
+		{90 * time.Second, "1m30s", "1 minute 30 seconds"},
+		{3*time.Minute + 12*time.Second, "3m12s", "3 minutes 12 seconds"},
+		{time.Hour, "1h0m", "1 hour"},
+		{2*time.Hour + 5*time.Minute + 40*time.Second, "2h6m", "2 hours 6 minutes"},
+		{23*time.Hour + 59*time.Minute + 45*time.Second, "1d0h", "1 day"},
+		{76 * time.Hour, "3d4h", "3 days 4 hours"},
+		{-90 * time.Second, "-1m30s", "-1 minute 30 seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.d.String(), func(t *testing.T) {
+			assert.Equal(t, tt.short, New(Short).Duration(tt.d))
+			assert.Equal(t, tt.long, New(Long).Duration(tt.d))
+		})
+	}
+}
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		n     int64
+		short string
+		long  string
+	}{
+		{0, "0 B", "0 bytes"},
+		{1, "1 B", "1 byte"},
+		{1023, "1023 B", "1,023 bytes"},
+		{1024, "1 KiB", "1 KiB (1,024 bytes)"},
+		{1536, "1.5 KiB", "1.5 KiB (1,536 bytes)"},
+		{1572864, "1.5 MiB", "1.5 MiB (1,572,864 bytes)"},
+		{20 * 1024 * 1024, "20 MiB", "20 MiB (20,971,520 bytes)"},
+		{1048575, "1 MiB", "1 MiB (1,048,575 bytes)"},
+		{3 << 30, "3 GiB", "3 GiB (3,221,225,472 bytes)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.short, func(t *testing.T) {
+			assert.Equal(t, tt.short, New(Short).Bytes(tt.n))
+			assert.Equal(t, tt.long, New(Long).Bytes(tt.n))
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	assert.Equal(t, "0 errors", Count(0, "error", "errors"))
+	assert.Equal(t, "1 error", Count(1, "error", "errors"))
+	assert.Equal(t, "1,204 errors", Count(1204, "error", "errors"))
+	assert.Equal(t, "-1,204 errors", Count(-1204, "error", "errors"))
+}
+
+func TestLocale(t *testing.T) {
+	german := Formatter{Style: Long, Locale: Locale{
+		Decimal:     ",",
+		Group:       ".",
+		Millisecond: Unit{"Millisekunde", "Millisekunden"},
+		Second:      Unit{"Sekunde", "Sekunden"},
+		Minute:      Unit{"Minute", "Minuten"},
+		Hour:        Unit{"Stunde", "Stunden"},
+		Day:         Unit{"Tag", "Tage"},
+		Byte:        Unit{"Byte", "Bytes"},
+	}}
+	assert.Equal(t, "1,5 Sekunden", german.Duration(1500*time.Millisecond))
+	assert.Equal(t, "2 Minuten 1 Sekunde", german.Duration(121*time.Second))
+	assert.Equal(t, "1,5 KiB (1.536 Bytes)", german.Bytes(1536))
+}
+
+func TestTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 1, 8, 0, time.UTC)
+	ts := time.Date(2024, 5, 1, 12, 1, 5, 123e6, time.UTC)
+
+	assert.Equal(t, "12:01:05.123", Time(ts, "15:04:05.000", TimeUTC, now))
+	assert.Equal(t, ts.Local().Format("15:04:05"), Time(ts, "15:04:05", TimeLocal, now))
+	assert.Equal(t, "2.9s ago", Time(ts, "15:04:05", TimeRelative, now))
+	assert.Equal(t, "in 1m30s", Time(now.Add(90*time.Second), "15:04:05", TimeRelative, now))
+}
+
+func TestParseTimeMode(t *testing.T) {
+	for s, want := range map[string]TimeMode{"": TimeLocal, "local": TimeLocal, "UTC": TimeUTC, "relative": TimeRelative} {
+		mode, err := ParseTimeMode(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, want, mode, s)
+	}
+	_, err := ParseTimeMode("gmt")
+	assert.EqualError(t, err, `must be local, utc, or relative, got "gmt"`)
+}
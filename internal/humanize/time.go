@@ -0,0 +1,51 @@
+package humanize
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeMode selects how timestamps are shown
+type TimeMode string
+
+const (
+	// TimeLocal shows timestamps in the local time zone
+	TimeLocal TimeMode = "local"
+	// TimeUTC shows timestamps in UTC
+	TimeUTC TimeMode = "utc"
+	// TimeRelative shows how long ago a timestamp was, e.g. "3s ago"
+	TimeRelative TimeMode = "relative"
+)
+
+// TimeModes lists the valid time modes
+var TimeModes = []TimeMode{TimeLocal, TimeUTC, TimeRelative}
+
+// ParseTimeMode parses a time mode name. An empty name is TimeLocal.
+func ParseTimeMode(s string) (TimeMode, error) {
+	if s == "" {
+		return TimeLocal, nil
+	}
+	for _, mode := range TimeModes {
+		if strings.EqualFold(s, string(mode)) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("must be local, utc, or relative, got %q", s)
+}
+
+// Time formats t with layout in the mode's time zone, or, for TimeRelative,
+// as its distance from now with the Default formatter, e.g. "3s ago"
+func Time(t time.Time, layout string, mode TimeMode, now time.Time) string {
+	switch mode {
+	case TimeUTC:
+		return t.UTC().Format(layout)
+	case TimeRelative:
+		if d := now.Sub(t); d < 0 {
+			return "in " + Duration(-d)
+		}
+		return Duration(now.Sub(t)) + " ago"
+	default:
+		return t.Local().Format(layout)
+	}
+}
@@ -0,0 +1,97 @@
+// Package idle tracks daemon activity (API calls, proxy requests, attached
+// clients) so an unused daemon can shut down or put its processes to sleep.
+package idle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Tracker records when the daemon was last used. All methods are safe to
+// call on a nil Tracker, which records nothing.
+type Tracker struct {
+	mu     sync.Mutex
+	last   time.Time
+	active int // Activities in progress (e.g. open log streams)
+
+	now func() time.Time
+}
+
+// NewTracker creates a tracker that considers the daemon used as of now
+func NewTracker() *Tracker {
+	return &Tracker{last: time.Now(), now: time.Now}
+}
+
+// Touch records a moment of activity
+func (t *Tracker) Touch() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.last = t.now()
+	t.mu.Unlock()
+}
+
+// Begin records the start of an activity that lasts until the returned
+// function is called. The daemon is never idle while an activity is open.
+func (t *Tracker) Begin() func() {
+	if t == nil {
+		return func() {}
+	}
+
+	t.mu.Lock()
+	t.active++
+	t.last = t.now()
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			t.active--
+			t.last = t.now()
+			t.mu.Unlock()
+		})
+	}
+}
+
+// IdleFor returns how long the daemon has gone unused, or 0 while an
+// activity is open
+func (t *Tracker) IdleFor() time.Duration {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.active > 0 {
+		return 0
+	}
+	return t.now().Sub(t.last)
+}
+
+// Wait blocks until the daemon has been idle for timeout, returning true, or
+// until ctx is cancelled, returning false
+func (t *Tracker) Wait(ctx context.Context, timeout time.Duration) bool {
+	if t == nil {
+		<-ctx.Done()
+		return false
+	}
+
+	for {
+		idle := t.IdleFor()
+		if idle >= timeout {
+			return true
+		}
+
+		// Activity may happen while waiting, so re-check when the timeout
+		// would have expired
+		timer := time.NewTimer(timeout - idle)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package idle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a manually advanced clock for tests
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestTracker() (*Tracker, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	t := NewTracker()
+	t.now = clock.now
+	t.last = clock.t
+	return t, clock
+}
+
+func TestTracker_Touch(t *testing.T) {
+	tracker, clock := newTestTracker()
+
+	clock.advance(10 * time.Minute)
+	assert.Equal(t, 10*time.Minute, tracker.IdleFor())
+
+	tracker.Touch()
+	assert.Equal(t, time.Duration(0), tracker.IdleFor())
+
+	clock.advance(time.Minute)
+	assert.Equal(t, time.Minute, tracker.IdleFor())
+}
+
+func TestTracker_Begin(t *testing.T) {
+	tracker, clock := newTestTracker()
+
+	end := tracker.Begin()
+	clock.advance(time.Hour)
+	assert.Equal(t, time.Duration(0), tracker.IdleFor(), "never idle while an activity is open")
+
+	end()
+	end() // idempotent
+	clock.advance(time.Minute)
+	assert.Equal(t, time.Minute, tracker.IdleFor())
+}
+
+func TestTracker_Wait(t *testing.T) {
+	tracker := NewTracker()
+
+	start := time.Now()
+	assert.True(t, tracker.Wait(context.Background(), 50*time.Millisecond))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestTracker_WaitExtendedByActivity(t *testing.T) {
+	tracker := NewTracker()
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		tracker.Touch()
+	}()
+
+	start := time.Now()
+	assert.True(t, tracker.Wait(context.Background(), 50*time.Millisecond))
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+}
+
+func TestTracker_WaitCancelled(t *testing.T) {
+	tracker := NewTracker()
+	end := tracker.Begin()
+	defer end()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.False(t, tracker.Wait(ctx, 10*time.Millisecond))
+}
+
+func TestTracker_Nil(t *testing.T) {
+	var tracker *Tracker
+	tracker.Touch()
+	tracker.Begin()()
+	assert.Equal(t, time.Duration(0), tracker.IdleFor())
+}
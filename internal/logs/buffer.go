@@ -1,69 +1,228 @@
 package logs
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/charliek/prox/internal/domain"
 )
 
-// RingBuffer is a fixed-size circular buffer for log entries
+// BufferConfig sizes a RingBuffer
+type BufferConfig struct {
+	Shared     int   // Entries any process may use past its reservation
+	PerProcess int   // Entries reserved for each process (0 = none)
+	MaxBytes   int64 // Approximate memory the entries may use (0 = no cap)
+}
+
+// entryOverhead approximates the memory of an entry apart from its strings:
+// the entry itself, its timestamp, and its place in a partition
+const entryOverhead = 96
+
+// entrySize approximates the memory an entry uses
+func entrySize(entry domain.LogEntry) int64 {
+	size := entryOverhead + len(entry.Process) + len(entry.Line) + len(entry.Level)
+	for k, v := range entry.Fields {
+		size += len(k) + len(v) + 32
+	}
+	return int64(size)
+}
+
+// RingBuffer holds recent log entries, partitioned by process. Each process
+// has a reservation of entries no other process can evict; entries past it
+// use a pool shared by all processes. When the pool is full, the oldest
+// entry of any process using the pool is evicted, so a process flooding
+// output only evicts its own history once others are within their
+// reservations. With a memory cap, the oldest entries of any process are
+// evicted while the entries' approximate size is over it.
 type RingBuffer struct {
-	mu       sync.RWMutex
-	entries  []domain.LogEntry
-	head     int // next write position
-	count    int // current number of entries
-	capacity int // max entries
+	mu         sync.RWMutex
+	partitions map[string]*partition
+	perProcess int
+	reserved   map[string]int // reservations overriding perProcess
+	shared     int
+	sharedUsed int    // entries held past their process's reservation
+	count      int    // current number of entries
+	seq        uint64 // ID of the last entry written
+	maxBytes   int64  // cap on bytes (0 = none)
+	bytes      int64  // approximate memory of the current entries
 }
 
-// NewRingBuffer creates a new ring buffer with the given capacity
+// partition holds one process's entries, oldest first
+type partition struct {
+	entries []bufferedEntry
+}
+
+// bufferedEntry is an entry with its write order across all processes
+type bufferedEntry struct {
+	seq   uint64
+	entry domain.LogEntry
+}
+
+// NewRingBuffer creates a buffer holding the last capacity entries of all
+// processes, without per-process reservations
 func NewRingBuffer(capacity int) *RingBuffer {
-	if capacity <= 0 {
-		capacity = 1000
+	return NewPartitionedBuffer(BufferConfig{Shared: capacity})
+}
+
+// NewPartitionedBuffer creates a buffer with a reservation for each process
+// and a shared pool
+func NewPartitionedBuffer(config BufferConfig) *RingBuffer {
+	if config.PerProcess < 0 {
+		config.PerProcess = 0
+	}
+	if config.Shared < 0 || (config.Shared == 0 && config.PerProcess == 0) {
+		config.Shared = 1000
 	}
 	return &RingBuffer{
-		entries:  make([]domain.LogEntry, capacity),
-		capacity: capacity,
+		partitions: make(map[string]*partition),
+		perProcess: config.PerProcess,
+		reserved:   make(map[string]int),
+		shared:     config.Shared,
+		maxBytes:   max(config.MaxBytes, 0),
+	}
+}
+
+// reservation returns the entries reserved for a process. b.mu must be held.
+func (b *RingBuffer) reservation(process string) int {
+	if n, ok := b.reserved[process]; ok {
+		return n
+	}
+	return b.perProcess
+}
+
+// overflow returns how many of a partition's entries use the shared pool.
+// b.mu must be held.
+func (b *RingBuffer) overflow(process string, p *partition) int {
+	if over := len(p.entries) - b.reservation(process); over > 0 {
+		return over
 	}
+	return 0
 }
 
-// Write adds a new entry to the buffer
-func (b *RingBuffer) Write(entry domain.LogEntry) {
+// SetReservation sets the entries reserved for a process; n <= 0 restores
+// the default. Entries that no longer fit are evicted, oldest first.
+func (b *RingBuffer) SetReservation(process string, n int) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	b.entries[b.head] = entry
-	b.head = (b.head + 1) % b.capacity
+	if n > 0 {
+		b.reserved[process] = n
+	} else {
+		delete(b.reserved, process)
+	}
 
-	if b.count < b.capacity {
-		b.count++
+	b.sharedUsed = 0
+	for name, p := range b.partitions {
+		b.sharedUsed += b.overflow(name, p)
+	}
+	for b.sharedUsed > b.shared {
+		b.evictOldest("", false)
 	}
 }
 
-// Read returns all entries in chronological order
-func (b *RingBuffer) Read() []domain.LogEntry {
-	b.mu.RLock()
-	defer b.mu.RUnlock()
+// Write adds a new entry to the buffer and returns its ID
+func (b *RingBuffer) Write(entry domain.LogEntry) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	if b.count == 0 {
-		return nil
+	p, ok := b.partitions[entry.Process]
+	if !ok {
+		p = &partition{}
+		b.partitions[entry.Process] = p
 	}
 
-	result := make([]domain.LogEntry, b.count)
+	// Past its reservation, the entry needs room in the shared pool
+	if len(p.entries) >= b.reservation(entry.Process) && b.sharedUsed >= b.shared {
+		b.evictOldest(entry.Process, false)
+	}
+
+	b.seq++
+	entry.ID = b.seq
+	before := b.overflow(entry.Process, p)
+	p.entries = append(p.entries, bufferedEntry{seq: b.seq, entry: entry})
+	b.sharedUsed += b.overflow(entry.Process, p) - before
+	b.count++
+	b.bytes += entrySize(entry)
 
-	// Calculate start position
-	start := 0
-	if b.count == b.capacity {
-		start = b.head // oldest entry is at head when full
+	// The newest entry is kept even when it alone is over the cap
+	for b.maxBytes > 0 && b.bytes > b.maxBytes && b.count > 1 {
+		b.evictOldest(entry.Process, true)
 	}
+	return entry.ID
+}
 
-	for i := 0; i < b.count; i++ {
-		idx := (start + i) % b.capacity
-		result[i] = b.entries[idx]
+// evictOldest removes the oldest entry among the processes using the shared
+// pool, plus the writing process if given, or among all processes if
+// anyProcess is set. b.mu must be held.
+func (b *RingBuffer) evictOldest(writer string, anyProcess bool) {
+	var victim string
+	var oldest *partition
+	for name, p := range b.partitions {
+		if len(p.entries) == 0 || (!anyProcess && name != writer && b.overflow(name, p) == 0) {
+			continue
+		}
+		if oldest == nil || p.entries[0].seq < oldest.entries[0].seq {
+			victim, oldest = name, p
+		}
+	}
+	if oldest == nil {
+		return
 	}
 
+	before := b.overflow(victim, oldest)
+	b.bytes -= entrySize(oldest.entries[0].entry)
+	oldest.entries = oldest.entries[1:]
+	b.sharedUsed += b.overflow(victim, oldest) - before
+	b.count--
+	if len(oldest.entries) == 0 && victim != writer {
+		delete(b.partitions, victim)
+	}
+}
+
+// merged returns the entries of the given partitions in write order. The
+// buffer's lock must be held.
+func merged(parts []*partition) []domain.LogEntry {
+	if len(parts) == 1 {
+		result := make([]domain.LogEntry, len(parts[0].entries))
+		for i, e := range parts[0].entries {
+			result[i] = e.entry
+		}
+		return result
+	}
+
+	var all []bufferedEntry
+	for _, p := range parts {
+		all = append(all, p.entries...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].seq < all[j].seq })
+
+	result := make([]domain.LogEntry, len(all))
+	for i, e := range all {
+		result[i] = e.entry
+	}
 	return result
 }
 
+// all returns every partition. b.mu must be held.
+func (b *RingBuffer) all() []*partition {
+	parts := make([]*partition, 0, len(b.partitions))
+	for _, p := range b.partitions {
+		parts = append(parts, p)
+	}
+	return parts
+}
+
+// Read returns all entries in chronological order
+func (b *RingBuffer) Read() []domain.LogEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.count == 0 {
+		return nil
+	}
+	return merged(b.all())
+}
+
 // ReadLast returns the last n entries in chronological order
 func (b *RingBuffer) ReadLast(n int) []domain.LogEntry {
 	b.mu.RLock()
@@ -73,46 +232,125 @@ func (b *RingBuffer) ReadLast(n int) []domain.LogEntry {
 		return nil
 	}
 
-	if n > b.count {
-		n = b.count
+	entries := merged(b.all())
+	if n < len(entries) {
+		entries = entries[len(entries)-n:]
 	}
+	return entries
+}
 
-	result := make([]domain.LogEntry, n)
+// Count returns the current number of entries in the buffer
+func (b *RingBuffer) Count() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.count
+}
 
-	// Calculate start position for last n entries
-	// Start position = (head - n + capacity) % capacity when count == capacity
-	// Or (count - n) when count < capacity
-	var start int
-	if b.count == b.capacity {
-		start = (b.head - n + b.capacity) % b.capacity
-	} else {
-		start = b.count - n
+// ProcessCount returns the number of buffered entries of a process
+func (b *RingBuffer) ProcessCount(process string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if p, ok := b.partitions[process]; ok {
+		return len(p.entries)
 	}
+	return 0
+}
 
-	for i := 0; i < n; i++ {
-		idx := (start + i) % b.capacity
-		result[i] = b.entries[idx]
-	}
+// Capacity returns the maximum number of entries the buffer holds for the
+// processes it has seen: the shared pool plus their reservations
+func (b *RingBuffer) Capacity() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 
-	return result
+	capacity := b.shared
+	for name := range b.partitions {
+		capacity += b.reservation(name)
+	}
+	for name, n := range b.reserved {
+		if _, ok := b.partitions[name]; !ok {
+			capacity += n
+		}
+	}
+	return capacity
 }
 
-// Count returns the current number of entries in the buffer
-func (b *RingBuffer) Count() int {
+// MemoryBytes returns the approximate memory the buffered entries use
+func (b *RingBuffer) MemoryBytes() int64 {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
-	return b.count
+	return b.bytes
 }
 
-// Capacity returns the maximum capacity of the buffer
-func (b *RingBuffer) Capacity() int {
-	return b.capacity
+// MaxMemory returns the cap on MemoryBytes, or 0 if there is none
+func (b *RingBuffer) MaxMemory() int64 {
+	return b.maxBytes
+}
+
+// Close clears the buffer
+func (b *RingBuffer) Close() error {
+	b.Clear()
+	return nil
 }
 
 // Clear removes all entries from the buffer
 func (b *RingBuffer) Clear() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	b.head = 0
+	b.partitions = make(map[string]*partition)
+	b.sharedUsed = 0
 	b.count = 0
+	b.bytes = 0
+}
+
+// Candidates returns the buffered entries that can match the filter's
+// process and time range after filter.AfterID, oldest first, along with the
+// index used to find them. Entries are assumed to be written in timestamp
+// order, so time bounds are found by binary search, as is AfterID.
+func (b *RingBuffer) Candidates(filter domain.LogFilter) ([]domain.LogEntry, string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	index := IndexScan
+	var parts []*partition
+	if len(filter.Processes) > 0 {
+		index = IndexProcess
+		seen := make(map[string]bool, len(filter.Processes))
+		for _, name := range filter.Processes {
+			if p, ok := b.partitions[name]; ok && !seen[name] {
+				seen[name] = true
+				parts = append(parts, p)
+			}
+		}
+	} else {
+		parts = b.all()
+	}
+
+	var entries []domain.LogEntry
+	if len(parts) > 0 {
+		entries = merged(parts)
+	}
+	n := len(entries)
+
+	lo, hi := 0, n
+	if !filter.Since.IsZero() {
+		lo = sort.Search(n, func(i int) bool { return !entries[i].Timestamp.Before(filter.Since) })
+	}
+	if !filter.Until.IsZero() {
+		hi = sort.Search(n, func(i int) bool { return entries[i].Timestamp.After(filter.Until) })
+	}
+	if filter.AfterID > 0 {
+		after := sort.Search(n, func(i int) bool { return entries[i].ID > filter.AfterID })
+		lo = max(lo, after)
+	}
+	if !filter.Since.IsZero() || !filter.Until.IsZero() {
+		if index == IndexProcess {
+			index = IndexProcessTime
+		} else {
+			index = IndexTime
+		}
+	}
+	if hi <= lo {
+		return nil, index
+	}
+	return entries[lo:hi], index
 }
@@ -1,6 +1,7 @@
 package logs
 
 import (
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -164,3 +165,92 @@ func TestRingBuffer_DefaultCapacity(t *testing.T) {
 	b2 := NewRingBuffer(-5)
 	assert.Equal(t, 1000, b2.Capacity())
 }
+
+func makeProcessEntry(process, line string) domain.LogEntry {
+	entry := makeEntry(line)
+	entry.Process = process
+	return entry
+}
+
+func TestRingBuffer_PartitionKeepsQuietProcessHistory(t *testing.T) {
+	b := NewPartitionedBuffer(BufferConfig{Shared: 5, PerProcess: 2})
+
+	b.Write(makeProcessEntry("api", "api-1"))
+	b.Write(makeProcessEntry("api", "api-2"))
+	for i := 0; i < 50; i++ {
+		b.Write(makeProcessEntry("web", "web"))
+	}
+
+	assert.Equal(t, 2, b.ProcessCount("api"), "flooding process should not evict another's reservation")
+	assert.Equal(t, 7, b.ProcessCount("web"), "flooding process keeps its reservation plus the shared pool")
+	assert.Equal(t, 9, b.Count())
+	assert.Equal(t, 9, b.Capacity())
+
+	entries, _ := b.Candidates(domain.LogFilter{Processes: []string{"api"}})
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "api-1", entries[0].Line)
+}
+
+func TestRingBuffer_PartitionEvictsOldestOverflow(t *testing.T) {
+	b := NewPartitionedBuffer(BufferConfig{Shared: 2, PerProcess: 1})
+
+	b.Write(makeProcessEntry("a", "a1"))
+	b.Write(makeProcessEntry("a", "a2")) // shared
+	b.Write(makeProcessEntry("b", "b1"))
+	b.Write(makeProcessEntry("b", "b2")) // shared; pool is now full
+	b.Write(makeProcessEntry("b", "b3")) // a is using the pool and has the oldest entry
+
+	var lines []string
+	for _, e := range b.Read() {
+		lines = append(lines, e.Line)
+	}
+	assert.Equal(t, []string{"a2", "b1", "b2", "b3"}, lines)
+
+	// A process at its reservation evicts its own oldest entry when the pool
+	// holds only newer entries
+	b.Write(makeProcessEntry("a", "a3"))
+	lines = nil
+	for _, e := range b.Read() {
+		lines = append(lines, e.Line)
+	}
+	assert.Equal(t, []string{"b1", "b2", "b3", "a3"}, lines)
+}
+
+func TestRingBuffer_SetReservation(t *testing.T) {
+	b := NewPartitionedBuffer(BufferConfig{Shared: 2, PerProcess: 1})
+
+	b.SetReservation("api", 10)
+	for i := 0; i < 20; i++ {
+		b.Write(makeProcessEntry("api", "api"))
+	}
+	assert.Equal(t, 12, b.ProcessCount("api"))
+
+	// Shrinking the reservation evicts what no longer fits
+	b.SetReservation("api", 0)
+	assert.Equal(t, 3, b.ProcessCount("api"))
+	assert.Equal(t, 3, b.Count())
+}
+
+func TestRingBuffer_MaxBytes(t *testing.T) {
+	line := strings.Repeat("x", 100)
+	size := entrySize(makeProcessEntry("api", line))
+	b := NewPartitionedBuffer(BufferConfig{Shared: 100, PerProcess: 10, MaxBytes: 3 * size})
+
+	b.Write(makeProcessEntry("api", line))
+	for i := 0; i < 5; i++ {
+		b.Write(makeProcessEntry("web", line))
+	}
+
+	// The cap evicts the oldest entries, reservations or not
+	assert.Equal(t, 3, b.Count())
+	assert.Equal(t, 0, b.ProcessCount("api"))
+	assert.Equal(t, 3*size, b.MemoryBytes())
+	assert.Equal(t, 3*size, b.MaxMemory())
+
+	// An entry over the cap on its own is still kept
+	b.Write(makeProcessEntry("web", strings.Repeat("y", int(4*size))))
+	assert.Equal(t, 1, b.Count())
+
+	b.Clear()
+	assert.Zero(t, b.MemoryBytes())
+}
@@ -0,0 +1,109 @@
+package logs
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+)
+
+var (
+	// errorLineRegex matches lines that report a failure
+	errorLineRegex = regexp.MustCompile(`(?i)\b(error|errors|err|exception|fatal|panic|panicked|fail|failed|failure|traceback)\b`)
+
+	// Variable parts of error lines, replaced in order so that ids are
+	// masked before their digits would be
+	uuidRegex   = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	hexIDRegex  = regexp.MustCompile(`\b(0x[0-9a-fA-F]+|[0-9a-fA-F]*[0-9][0-9a-fA-F]*[a-fA-F][0-9a-fA-F]*|[0-9a-fA-F]*[a-fA-F][0-9a-fA-F]*[0-9][0-9a-fA-F]*)\b`)
+	numberRegex = regexp.MustCompile(`\d+`)
+	spaceRegex  = regexp.MustCompile(`\s+`)
+)
+
+// ErrorCluster groups error lines that differ only in numbers and ids
+type ErrorCluster struct {
+	Signature string    `json:"signature"` // Normalized line shared by the cluster
+	Example   string    `json:"example"`   // First line in the cluster
+	Count     int       `json:"count"`
+	Processes []string  `json:"processes"` // Sorted
+	First     time.Time `json:"first"`
+	Last      time.Time `json:"last"`
+}
+
+// IsErrorLine reports whether a log line reports an error, failure, or panic
+func IsErrorLine(line string) bool {
+	return errorLineRegex.MatchString(line)
+}
+
+// NormalizeErrorLine replaces the variable parts of a line (UUIDs, hex ids,
+// and numbers) with placeholders so that repeats of the same error compare
+// equal
+func NormalizeErrorLine(line string) string {
+	line = uuidRegex.ReplaceAllString(line, "<id>")
+	line = hexIDRegex.ReplaceAllStringFunc(line, func(s string) string {
+		// Short hex-looking words such as "dead" or "a1" are more likely
+		// text than ids
+		if len(s) < 6 && !strings.HasPrefix(s, "0x") {
+			return s
+		}
+		return "<id>"
+	})
+	line = numberRegex.ReplaceAllString(line, "<n>")
+	return strings.TrimSpace(spaceRegex.ReplaceAllString(line, " "))
+}
+
+// ClusterErrors groups the error lines among entries by their normalized
+// form, across processes. Clusters are ordered most frequent first, then by
+// most recent occurrence.
+func ClusterErrors(entries []domain.LogEntry) []ErrorCluster {
+	clusters := make(map[string]*ErrorCluster)
+	processes := make(map[string]map[string]bool)
+
+	for _, entry := range entries {
+		if !IsErrorLine(entry.Line) {
+			continue
+		}
+
+		sig := NormalizeErrorLine(entry.Line)
+		c, ok := clusters[sig]
+		if !ok {
+			c = &ErrorCluster{
+				Signature: sig,
+				Example:   entry.Line,
+				First:     entry.Timestamp,
+				Last:      entry.Timestamp,
+			}
+			clusters[sig] = c
+			processes[sig] = make(map[string]bool)
+		}
+		c.Count++
+		if entry.Timestamp.Before(c.First) {
+			c.First = entry.Timestamp
+			c.Example = entry.Line
+		}
+		if entry.Timestamp.After(c.Last) {
+			c.Last = entry.Timestamp
+		}
+		processes[sig][entry.Process] = true
+	}
+
+	result := make([]ErrorCluster, 0, len(clusters))
+	for sig, c := range clusters {
+		for name := range processes[sig] {
+			c.Processes = append(c.Processes, name)
+		}
+		sort.Strings(c.Processes)
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		if !result[i].Last.Equal(result[j].Last) {
+			return result[i].Last.After(result[j].Last)
+		}
+		return result[i].Signature < result[j].Signature
+	})
+	return result
+}
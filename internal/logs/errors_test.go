@@ -0,0 +1,83 @@
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsErrorLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"ERROR: connection refused", true},
+		{"level=error msg=\"query failed\"", true},
+		{"panic: runtime error: index out of range", true},
+		{"Traceback (most recent call last):", true},
+		{"request failed with status 500", true},
+		{"GET /api/users 200 12ms", false},
+		{"terrible weather today", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsErrorLine(tt.line))
+		})
+	}
+}
+
+func TestNormalizeErrorLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"numbers", "timeout after 5000ms on port 5432", "timeout after <n>ms on port <n>"},
+		{"uuid", "order 3f2b8c1e-4a5d-4e6f-8a9b-0c1d2e3f4a5b not found", "order <id> not found"},
+		{"hex id", "commit a3f9c2d1e8 failed", "commit <id> failed"},
+		{"pointer", "nil pointer at 0xc000123abc", "nil pointer at <id>"},
+		{"short hex words kept", "dead beef failed", "dead beef failed"},
+		{"whitespace", "  error:   bad   input  ", "error: bad input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeErrorLine(tt.line))
+		})
+	}
+}
+
+func TestClusterErrors(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	entry := func(offset time.Duration, process, line string) domain.LogEntry {
+		return domain.LogEntry{Timestamp: base.Add(offset), Process: process, Stream: domain.StreamStderr, Line: line}
+	}
+
+	clusters := ClusterErrors([]domain.LogEntry{
+		entry(0, "api", "error: dial tcp 127.0.0.1:5432: connection refused"),
+		entry(time.Minute, "web", "GET / 200 3ms"),
+		entry(2*time.Minute, "worker", "error: dial tcp 127.0.0.1:5433: connection refused"),
+		entry(3*time.Minute, "api", "panic: job 42 failed"),
+		entry(4*time.Minute, "api", "error: dial tcp 127.0.0.1:5432: connection refused"),
+	})
+
+	require.Len(t, clusters, 2)
+
+	assert.Equal(t, 3, clusters[0].Count)
+	assert.Equal(t, "error: dial tcp <n>.<n>.<n>.<n>:<n>: connection refused", clusters[0].Signature)
+	assert.Equal(t, "error: dial tcp 127.0.0.1:5432: connection refused", clusters[0].Example)
+	assert.Equal(t, []string{"api", "worker"}, clusters[0].Processes)
+	assert.Equal(t, base, clusters[0].First)
+	assert.Equal(t, base.Add(4*time.Minute), clusters[0].Last)
+
+	assert.Equal(t, 1, clusters[1].Count)
+	assert.Equal(t, "panic: job <n> failed", clusters[1].Signature)
+}
+
+func TestClusterErrors_NoErrors(t *testing.T) {
+	clusters := ClusterErrors([]domain.LogEntry{{Process: "web", Line: "listening on :3000"}})
+	assert.Empty(t, clusters)
+}
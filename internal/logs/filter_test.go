@@ -18,67 +18,6 @@ func makeEntryWithProcess(process, line string) domain.LogEntry {
 	}
 }
 
-func TestFilter_MatchesProcess(t *testing.T) {
-	filter, err := NewFilter(domain.LogFilter{
-		Processes: []string{"web", "api"},
-	})
-	require.NoError(t, err)
-
-	assert.True(t, filter.Matches(makeEntryWithProcess("web", "hello")))
-	assert.True(t, filter.Matches(makeEntryWithProcess("api", "hello")))
-	assert.False(t, filter.Matches(makeEntryWithProcess("worker", "hello")))
-}
-
-func TestFilter_MatchesSubstring(t *testing.T) {
-	filter, err := NewFilter(domain.LogFilter{
-		Pattern: "ERROR",
-	})
-	require.NoError(t, err)
-
-	assert.True(t, filter.Matches(makeEntryWithProcess("web", "ERROR: something went wrong")))
-	assert.True(t, filter.Matches(makeEntryWithProcess("web", "An ERROR occurred")))
-	assert.False(t, filter.Matches(makeEntryWithProcess("web", "All good")))
-	assert.False(t, filter.Matches(makeEntryWithProcess("web", "error lowercase")))
-}
-
-func TestFilter_MatchesRegex(t *testing.T) {
-	filter, err := NewFilter(domain.LogFilter{
-		Pattern: "(?i)error|warn",
-		IsRegex: true,
-	})
-	require.NoError(t, err)
-
-	assert.True(t, filter.Matches(makeEntryWithProcess("web", "ERROR: something")))
-	assert.True(t, filter.Matches(makeEntryWithProcess("web", "error lowercase")))
-	assert.True(t, filter.Matches(makeEntryWithProcess("web", "WARN: something")))
-	assert.False(t, filter.Matches(makeEntryWithProcess("web", "All good")))
-}
-
-func TestFilter_InvalidRegex(t *testing.T) {
-	_, err := NewFilter(domain.LogFilter{
-		Pattern: "[invalid",
-		IsRegex: true,
-	})
-	require.Error(t, err)
-}
-
-func TestFilter_CombinedFilters(t *testing.T) {
-	filter, err := NewFilter(domain.LogFilter{
-		Processes: []string{"web"},
-		Pattern:   "ERROR",
-	})
-	require.NoError(t, err)
-
-	// Matches both
-	assert.True(t, filter.Matches(makeEntryWithProcess("web", "ERROR: fail")))
-
-	// Wrong process
-	assert.False(t, filter.Matches(makeEntryWithProcess("api", "ERROR: fail")))
-
-	// Wrong pattern
-	assert.False(t, filter.Matches(makeEntryWithProcess("web", "All good")))
-}
-
 func TestFilterEntries(t *testing.T) {
 	entries := []domain.LogEntry{
 		makeEntryWithProcess("web", "request 1"),
@@ -103,7 +42,7 @@ func TestFilterEntries(t *testing.T) {
 
 	t.Run("filter by pattern", func(t *testing.T) {
 		result, err := FilterEntries(entries, domain.LogFilter{
-			Pattern: "ERROR",
+			Patterns: []string{"ERROR"},
 		})
 		require.NoError(t, err)
 		assert.Len(t, result, 2)
@@ -112,7 +51,7 @@ func TestFilterEntries(t *testing.T) {
 	t.Run("combined filters", func(t *testing.T) {
 		result, err := FilterEntries(entries, domain.LogFilter{
 			Processes: []string{"web"},
-			Pattern:   "ERROR",
+			Patterns:  []string{"ERROR"},
 		})
 		require.NoError(t, err)
 		assert.Len(t, result, 1)
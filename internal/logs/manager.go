@@ -1,13 +1,28 @@
 package logs
 
 import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/metrics"
 )
 
 // ManagerConfig holds configuration for the log manager
 type ManagerConfig struct {
-	BufferSize         int // Number of entries to keep in ring buffer
-	SubscriptionBuffer int // Buffer size for subscription channels
+	BufferSize         int         // Entries shared by all processes past their reservation
+	ProcessBufferSize  int         // Entries reserved for each process (0 = none)
+	MaxMemory          int64       // Approximate memory the buffered entries may use (0 = no cap)
+	SubscriptionBuffer int         // Buffer size for subscription channels
+	QueryBudget        QueryBudget // Per-query scan limits (zero value uses defaults)
+	ScanRate           int         // Entries per second all queries may scan (0 uses default)
+	ScanBurst          int         // Entries that may be scanned in a burst (0 uses default)
+
+	// Store holds the history; nil uses a RingBuffer sized by BufferSize,
+	// ProcessBufferSize, and MaxMemory
+	Store Store
 }
 
 // DefaultManagerConfig returns the default configuration
@@ -15,13 +30,33 @@ func DefaultManagerConfig() ManagerConfig {
 	return ManagerConfig{
 		BufferSize:         1000,
 		SubscriptionBuffer: 100,
+		QueryBudget:        DefaultQueryBudget(),
+		ScanRate:           constants.DefaultLogQueryScanRate,
+		ScanBurst:          constants.DefaultLogQueryScanBurst,
 	}
 }
 
 // Manager manages log storage and subscriptions
 type Manager struct {
-	buffer        *RingBuffer
+	// mu is held for reading while an entry is stored and broadcast, and for
+	// writing by SubscribeWithBackfill, so no entry falls between its
+	// backfill and its subscription, and by Close, so it waits for writes in
+	// progress
+	mu        sync.RWMutex
+	closed    bool          // Set by Close; guarded by mu
+	done      chan struct{} // Closed by Close
+	closeOnce sync.Once
+
+	store         Store
 	subscriptions *SubscriptionManager
+	budget        QueryBudget
+	limiter       *scanLimiter
+	throttles     throttles
+
+	// Log throughput for /metrics
+	lines     *metrics.CounterVec
+	bytes     *metrics.CounterVec
+	throttled *metrics.CounterVec
 }
 
 // NewManager creates a new log manager
@@ -32,44 +67,104 @@ func NewManager(config ManagerConfig) *Manager {
 	if config.SubscriptionBuffer <= 0 {
 		config.SubscriptionBuffer = DefaultManagerConfig().SubscriptionBuffer
 	}
+	if config.QueryBudget == (QueryBudget{}) {
+		config.QueryBudget = DefaultManagerConfig().QueryBudget
+	}
+	if config.ScanRate <= 0 {
+		config.ScanRate = DefaultManagerConfig().ScanRate
+	}
+	if config.ScanBurst <= 0 {
+		config.ScanBurst = DefaultManagerConfig().ScanBurst
+	}
+
+	if config.Store == nil {
+		config.Store = NewPartitionedBuffer(BufferConfig{
+			Shared:     config.BufferSize,
+			PerProcess: config.ProcessBufferSize,
+			MaxBytes:   config.MaxMemory,
+		})
+	}
 
 	return &Manager{
-		buffer:        NewRingBuffer(config.BufferSize),
+		store:         config.Store,
 		subscriptions: NewSubscriptionManager(config.SubscriptionBuffer),
+		done:          make(chan struct{}),
+		budget:        config.QueryBudget,
+		limiter:       newScanLimiter(config.ScanRate, config.ScanBurst),
+		lines:         metrics.NewCounterVec("prox_log_lines_total", "Log lines written by processes.", "process", "stream"),
+		bytes:         metrics.NewCounterVec("prox_log_bytes_total", "Bytes of log lines written by processes.", "process", "stream"),
+		throttled:     metrics.NewCounterVec("prox_log_lines_throttled_total", "Log lines dropped or collapsed by a process's log throttle.", "process"),
 	}
 }
 
-// Write adds a log entry to the buffer and broadcasts to subscribers
+// Write adds a log entry to the store and broadcasts to subscribers. Entries
+// written after Close are dropped, as are those the process's throttle
+// holds back.
 func (m *Manager) Write(entry domain.LogEntry) {
-	m.buffer.Write(entry)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return
+	}
+	m.lines.Inc(entry.Process, string(entry.Stream))
+	m.bytes.Add(float64(len(entry.Line)), entry.Process, string(entry.Stream))
+
+	reports, keep := m.throttles.admit(entry)
+	for _, report := range reports {
+		m.append(report)
+	}
+	if !keep {
+		m.throttled.Inc(entry.Process)
+		return
+	}
+	m.append(entry)
+}
+
+// append stores an entry and broadcasts it to subscribers
+func (m *Manager) append(entry domain.LogEntry) {
+	entry.ID = m.store.Write(entry)
 	m.subscriptions.Broadcast(entry)
 }
 
+// SetProcessBufferSize sets the entries reserved for a process's history;
+// n <= 0 restores the default reservation
+func (m *Manager) SetProcessBufferSize(process string, n int) {
+	m.store.SetReservation(process, n)
+}
+
+// SetProcessThrottle sets how a process's output is throttled, starting
+// the count afresh; an empty throttle keeps all of it
+func (m *Manager) SetProcessThrottle(process string, throttle domain.LogThrottle) {
+	m.throttles.set(process, throttle)
+}
+
 // Query retrieves log entries matching the filter
 // Returns the entries and the total count before limiting
 func (m *Manager) Query(filter domain.LogFilter, limit int) ([]domain.LogEntry, int, error) {
-	entries := m.buffer.Read()
+	entries := m.store.Read()
 	return FilterEntriesLimit(entries, filter, limit)
 }
 
-// QueryLast retrieves the last n log entries matching the filter
+// QueryLast retrieves the last n log entries matching the filter.
+// It is subject to the query budget; use Search to learn whether the
+// result was truncated.
 func (m *Manager) QueryLast(filter domain.LogFilter, n int) ([]domain.LogEntry, int, error) {
-	entries := m.buffer.Read()
-	filtered, err := FilterEntries(entries, filter)
+	result, err := m.Search(filter, n)
 	if err != nil {
 		return nil, 0, err
 	}
-
-	total := len(filtered)
-	if n > 0 && len(filtered) > n {
-		filtered = filtered[len(filtered)-n:]
-	}
-
-	return filtered, total, nil
+	return result.Entries, result.Total, nil
 }
 
-// Subscribe creates a subscription for log entries matching the filter
+// Subscribe creates a subscription for log entries matching the filter. The
+// channel is closed by Unsubscribe or Close; after Close, Subscribe returns
+// domain.ErrShutdownInProgress.
 func (m *Manager) Subscribe(filter domain.LogFilter) (string, <-chan domain.LogEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return "", nil, domain.ErrShutdownInProgress
+	}
 	return m.subscriptions.Subscribe(filter)
 }
 
@@ -78,16 +173,56 @@ func (m *Manager) Unsubscribe(id string) {
 	m.subscriptions.Unsubscribe(id)
 }
 
+// SubscriptionStalledSince returns when a subscriber stopped draining its
+// channel, or the zero time if it is keeping up
+func (m *Manager) SubscriptionStalledSince(id string) time.Time {
+	return m.subscriptions.StalledSince(id)
+}
+
 // Stats returns statistics about the log manager
 func (m *Manager) Stats() domain.LogStats {
-	return domain.LogStats{
-		TotalEntries: m.buffer.Count(),
-		BufferSize:   m.buffer.Capacity(),
+	stats := domain.LogStats{
+		TotalEntries: m.store.Count(),
+		BufferSize:   m.store.Capacity(),
 		Subscribers:  m.subscriptions.Count(),
 	}
+	if store, ok := m.store.(memoryStore); ok {
+		stats.MemoryBytes = store.MemoryBytes()
+		stats.MaxMemory = store.MaxMemory()
+	}
+	return stats
+}
+
+// WriteMetrics writes log throughput in the Prometheus text format
+func (m *Manager) WriteMetrics(w io.Writer) {
+	m.lines.Write(w)
+	m.bytes.Write(w)
+	m.throttled.Write(w)
 }
 
-// Close closes the manager and all subscriptions
+// Close shuts the manager down. It stops accepting writes and subscriptions,
+// waits for writes in progress to reach subscribers, and gives subscribers
+// that are keeping up until constants.LogCloseFlushTimeout to receive what
+// they have been sent. Then it closes every subscription channel and the
+// store. Done is closed first, so a subscriber whose channel closes can
+// tell shutdown from Unsubscribe.
+//
+// Close may be called more than once; every call returns once the manager
+// is shut down. It is safe to call concurrently with the other methods.
 func (m *Manager) Close() {
-	m.subscriptions.Close()
+	m.closeOnce.Do(func() {
+		m.mu.Lock()
+		m.closed = true
+		close(m.done)
+		m.mu.Unlock()
+
+		m.subscriptions.Flush(constants.LogCloseFlushTimeout)
+		m.subscriptions.Close()
+		_ = m.store.Close()
+	})
+}
+
+// Done returns a channel that is closed when Close is called
+func (m *Manager) Done() <-chan struct{} {
+	return m.done
 }
@@ -1,6 +1,7 @@
 package logs
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -117,6 +118,77 @@ func TestManager_SubscribeWithFilter(t *testing.T) {
 	}
 }
 
+func TestManager_SubscribeWithBackfill(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 10, SubscriptionBuffer: 10})
+	defer m.Close()
+
+	for _, line := range []string{"one", "two", "three"} {
+		m.Write(makeEntryWithProcess("web", line))
+	}
+	m.Write(makeEntryWithProcess("api", "other"))
+
+	_, ch, result, err := m.SubscribeWithBackfill(domain.LogFilter{Processes: []string{"web"}}, 2)
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 2)
+	assert.Equal(t, "two", result.Entries[0].Line)
+	assert.Equal(t, "three", result.Entries[1].Line)
+
+	m.Write(makeEntryWithProcess("web", "four"))
+	select {
+	case msg := <-ch:
+		assert.Equal(t, "four", msg.Line)
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("expected to receive message")
+	}
+
+	_, _, _, err = m.SubscribeWithBackfill(domain.LogFilter{Patterns: []string{"("}, IsRegex: true}, 2)
+	assert.Error(t, err)
+}
+
+func TestManager_SubscribeWithBackfill_NoGap(t *testing.T) {
+	const size = 100000
+	m := NewManager(ManagerConfig{BufferSize: size, SubscriptionBuffer: size})
+	defer m.Close()
+
+	// Subscribe while another goroutine is writing; every line must arrive
+	// exactly once, in the backfill or on the channel
+	stop := make(chan struct{})
+	written := make(chan int)
+	go func() {
+		i := 0
+		for ; i < size; i++ {
+			select {
+			case <-stop:
+				written <- i
+				return
+			default:
+			}
+			m.Write(makeEntry(fmt.Sprintf("%d", i)))
+		}
+		written <- i
+	}()
+	for m.Stats().TotalEntries < 100 {
+		time.Sleep(time.Millisecond)
+	}
+	id, ch, result, err := m.SubscribeWithBackfill(domain.LogFilter{}, size)
+	require.NoError(t, err)
+	close(stop)
+	total := <-written
+	m.Unsubscribe(id)
+
+	var lines []string
+	for _, e := range result.Entries {
+		lines = append(lines, e.Line)
+	}
+	for e := range ch {
+		lines = append(lines, e.Line)
+	}
+	require.Len(t, lines, total)
+	for i, line := range lines {
+		require.Equal(t, fmt.Sprintf("%d", i), line)
+	}
+}
+
 func TestManager_Unsubscribe(t *testing.T) {
 	m := NewManager(ManagerConfig{BufferSize: 10, SubscriptionBuffer: 10})
 	defer m.Close()
@@ -149,6 +221,28 @@ func TestManager_Stats(t *testing.T) {
 	assert.Equal(t, 2, stats.Subscribers)
 }
 
+func TestManager_Store(t *testing.T) {
+	store := NewRingBuffer(5)
+	m := NewManager(ManagerConfig{BufferSize: 100, Store: store})
+
+	for i := 0; i < 10; i++ {
+		m.Write(makeEntryWithProcess("web", fmt.Sprintf("line %d", i)))
+	}
+
+	stats := m.Stats()
+	assert.Equal(t, 5, stats.TotalEntries)
+	assert.Equal(t, 5, stats.BufferSize, "the store's capacity is used instead of BufferSize")
+
+	result, err := m.Search(domain.LogFilter{Processes: []string{"web"}}, 2)
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 2)
+	assert.Equal(t, "line 9", result.Entries[1].Line)
+
+	// Closing the manager closes the store
+	m.Close()
+	assert.Equal(t, 0, store.Count())
+}
+
 func TestManager_Concurrent(t *testing.T) {
 	m := NewManager(ManagerConfig{BufferSize: 1000, SubscriptionBuffer: 100})
 	defer m.Close()
@@ -203,3 +297,150 @@ func TestManager_DefaultConfig(t *testing.T) {
 	stats := m.Stats()
 	assert.Equal(t, 1000, stats.BufferSize)
 }
+
+func TestManager_CloseFlushesSubscribers(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 100, SubscriptionBuffer: 100})
+	_, ch, err := m.Subscribe(domain.LogFilter{})
+	require.NoError(t, err)
+
+	for i := 0; i < 50; i++ {
+		m.Write(makeEntry(fmt.Sprintf("line %d", i)))
+	}
+
+	// A subscriber that is slow but keeping up gets every entry before its
+	// channel closes
+	received := make(chan int)
+	go func() {
+		n := 0
+		for range ch {
+			n++
+			time.Sleep(time.Millisecond)
+		}
+		received <- n
+	}()
+
+	m.Close()
+	select {
+	case <-m.Done():
+	default:
+		t.Fatal("Done not closed by Close")
+	}
+	select {
+	case n := <-received:
+		assert.Equal(t, 50, n)
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription channel not closed")
+	}
+}
+
+func TestManager_CloseDoesNotWaitForStalledSubscribers(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 100, SubscriptionBuffer: 2})
+	_, ch, err := m.Subscribe(domain.LogFilter{})
+	require.NoError(t, err)
+
+	// Fill the channel and then some, so the subscriber is stalled
+	for i := 0; i < 5; i++ {
+		m.Write(makeEntry("line"))
+	}
+
+	start := time.Now()
+	m.Close()
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	// Entries already sent are still received before the channel closes
+	n := 0
+	for range ch {
+		n++
+	}
+	assert.Equal(t, 2, n)
+}
+
+func TestManager_AfterClose(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 100})
+	m.Write(makeEntry("before"))
+	m.Close()
+	m.Close() // Closing again is a no-op
+
+	m.Write(makeEntry("after"))
+	assert.Equal(t, 0, m.Stats().TotalEntries, "buffer is released and writes are dropped")
+
+	_, _, err := m.Subscribe(domain.LogFilter{})
+	assert.ErrorIs(t, err, domain.ErrShutdownInProgress)
+	_, _, _, err = m.SubscribeWithBackfill(domain.LogFilter{}, 10)
+	assert.ErrorIs(t, err, domain.ErrShutdownInProgress)
+	m.Unsubscribe("sub-missing")
+}
+
+func TestManager_ConcurrentClose(t *testing.T) {
+	for run := 0; run < 20; run++ {
+		m := NewManager(ManagerConfig{BufferSize: 100, SubscriptionBuffer: 10})
+
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+
+		// Writers keep writing through Close
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						m.Write(makeEntry("line"))
+					}
+				}
+			}()
+		}
+
+		// Subscribers come and go; every channel they get must be closed,
+		// by Unsubscribe or by Close
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; ; j++ {
+					var id string
+					var ch <-chan domain.LogEntry
+					var err error
+					if j%2 == 0 {
+						id, ch, err = m.Subscribe(domain.LogFilter{})
+					} else {
+						id, ch, _, err = m.SubscribeWithBackfill(domain.LogFilter{}, 5)
+					}
+					if err != nil {
+						assert.ErrorIs(t, err, domain.ErrShutdownInProgress)
+						return
+					}
+					if i%2 == 0 {
+						m.Unsubscribe(id)
+					}
+					for range ch {
+					}
+				}
+			}(i)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		closed := make(chan struct{})
+		go func() {
+			m.Close()
+			close(closed)
+		}()
+		m.Close()
+		<-closed
+		close(stop)
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("goroutines blocked after Close")
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/charliek/prox/internal/domain"
+)
+
+// levelKeys are the fields loggers commonly put the level in, in the order
+// they are looked up
+var levelKeys = []string{"level", "lvl", "severity", "log.level"}
+
+// numericLevels maps the numeric levels of pino and bunyan to LogLevels
+var numericLevels = map[string]string{
+	"10": "trace",
+	"20": "debug",
+	"30": "info",
+	"40": "warn",
+	"50": "error",
+	"60": "fatal",
+}
+
+// ParseLine parses a line in format (domain.LogFormatJSON or
+// domain.LogFormatLogfmt) into its fields and normalized level. A line that
+// isn't in the format, such as a panic among JSON lines, returns nil fields
+// and is kept as plain text.
+func ParseLine(format, line string) (map[string]string, string) {
+	var fields map[string]string
+	switch format {
+	case domain.LogFormatJSON:
+		fields = parseJSONLine(line)
+	case domain.LogFormatLogfmt:
+		fields = parseLogfmtLine(line)
+	}
+	if fields == nil {
+		return nil, ""
+	}
+
+	level := ""
+	for _, key := range levelKeys {
+		if v, ok := fields[key]; ok {
+			if n, ok := numericLevels[v]; ok {
+				v = n
+			}
+			level = domain.NormalizeLogLevel(v)
+			break
+		}
+	}
+	return fields, level
+}
+
+// parseJSONLine returns the top-level fields of a JSON object, with nested
+// values as compact JSON, or nil if line isn't an object
+func parseJSONLine(line string) map[string]string {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &raw); err != nil {
+		return nil
+	}
+
+	fields := make(map[string]string, len(raw))
+	for key, value := range raw {
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			fields[key] = s
+			continue
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, value); err != nil {
+			return nil
+		}
+		fields[key] = compact.String()
+	}
+	return fields
+}
+
+// parseLogfmtLine returns the key=value pairs of a logfmt line, or nil if
+// any part of the line isn't a pair. Values may be double-quoted with
+// backslash escapes.
+func parseLogfmtLine(line string) map[string]string {
+	fields := make(map[string]string)
+	s := strings.TrimSpace(line)
+	for s != "" {
+		eq := strings.IndexAny(s, "= \t\"")
+		if eq <= 0 || s[eq] != '=' {
+			return nil
+		}
+		key := s[:eq]
+		s = s[eq+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					switch s[i] {
+					case 'n':
+						b.WriteByte('\n')
+						continue
+					case 't':
+						b.WriteByte('\t')
+						continue
+					}
+				}
+				b.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return nil // Unterminated quote
+			}
+			value = b.String()
+			s = s[i+1:]
+			if s != "" && s[0] != ' ' && s[0] != '\t' {
+				return nil
+			}
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end < 0 {
+				end = len(s)
+			}
+			value = s[:end]
+			if strings.Contains(value, `"`) {
+				return nil
+			}
+			s = s[end:]
+		}
+
+		fields[key] = value
+		s = strings.TrimLeft(s, " \t")
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
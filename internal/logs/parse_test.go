@@ -0,0 +1,103 @@
+package logs
+
+import (
+	"testing"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		line   string
+		fields map[string]string
+		level  string
+	}{
+		{
+			name:   "json",
+			format: domain.LogFormatJSON,
+			line:   `{"level":"WARN","msg":"slow query","ts":"2024-01-15T10:00:00Z","ms":812}`,
+			fields: map[string]string{"level": "WARN", "msg": "slow query", "ts": "2024-01-15T10:00:00Z", "ms": "812"},
+			level:  "warn",
+		},
+		{
+			name:   "json nested values",
+			format: domain.LogFormatJSON,
+			line:   `{"severity":"error","err":{"code": 42},"tags":["a", "b"],"ok":false,"id":null}`,
+			fields: map[string]string{"severity": "error", "err": `{"code":42}`, "tags": `["a","b"]`, "ok": "false", "id": ""},
+			level:  "error",
+		},
+		{
+			name:   "json numeric level",
+			format: domain.LogFormatJSON,
+			line:   `{"level":50,"msg":"boom"}`,
+			fields: map[string]string{"level": "50", "msg": "boom"},
+			level:  "error",
+		},
+		{
+			name:   "json without level",
+			format: domain.LogFormatJSON,
+			line:   `{"msg":"hello"}`,
+			fields: map[string]string{"msg": "hello"},
+		},
+		{
+			name:   "json plain text",
+			format: domain.LogFormatJSON,
+			line:   "panic: runtime error",
+		},
+		{
+			name:   "json array",
+			format: domain.LogFormatJSON,
+			line:   `[1, 2]`,
+		},
+		{
+			name:   "logfmt",
+			format: domain.LogFormatLogfmt,
+			line:   `ts=2024-01-15T10:00:00Z lvl=info msg="listening on :3000" port=3000`,
+			fields: map[string]string{"ts": "2024-01-15T10:00:00Z", "lvl": "info", "msg": "listening on :3000", "port": "3000"},
+			level:  "info",
+		},
+		{
+			name:   "logfmt escapes",
+			format: domain.LogFormatLogfmt,
+			line:   `level=error msg="bad \"input\"\nretrying" empty=`,
+			fields: map[string]string{"level": "error", "msg": "bad \"input\"\nretrying", "empty": ""},
+			level:  "error",
+		},
+		{
+			name:   "logfmt plain text",
+			format: domain.LogFormatLogfmt,
+			line:   "Server started on port 3000",
+		},
+		{
+			name:   "logfmt partly pairs",
+			format: domain.LogFormatLogfmt,
+			line:   "GET /users?id=1 status=200",
+		},
+		{
+			name:   "logfmt unterminated quote",
+			format: domain.LogFormatLogfmt,
+			line:   `msg="oops`,
+		},
+		{
+			name:   "unknown level",
+			format: domain.LogFormatLogfmt,
+			line:   "level=verbose msg=hi",
+			fields: map[string]string{"level": "verbose", "msg": "hi"},
+		},
+		{
+			name:   "no format",
+			format: "",
+			line:   `{"level":"info"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, level := ParseLine(tt.format, tt.line)
+			assert.Equal(t, tt.fields, fields)
+			assert.Equal(t, tt.level, level)
+		})
+	}
+}
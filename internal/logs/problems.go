@@ -0,0 +1,126 @@
+package logs
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+)
+
+// ansiRegex matches terminal color and style sequences, which compilers add
+// to their output when they think they write to a terminal
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// Problem is a compiler or test failure found in a process's output
+type Problem struct {
+	Timestamp time.Time
+	Process   string
+	File      string
+	Line      int
+	Column    int    // 0 if the matcher doesn't capture one
+	Severity  string // e.g. "error" or "warning"; empty if not captured
+	Message   string // The whole line if the matcher doesn't capture one
+}
+
+// ProblemMatcher finds problems in log lines using each process's problem
+// matcher regexes. A nil ProblemMatcher matches nothing.
+type ProblemMatcher struct {
+	matchers map[string][]*regexp.Regexp
+}
+
+// NewProblemMatcher compiles the problem matcher regexes of each process,
+// given by process name. Each regex must have "file" and "line" named groups,
+// and may have "column", "severity", and "message" groups.
+func NewProblemMatcher(patterns map[string][]string) (*ProblemMatcher, error) {
+	m := &ProblemMatcher{matchers: make(map[string][]*regexp.Regexp)}
+	for process, list := range patterns {
+		for _, pattern := range list {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%w: problem matcher %q: %v", domain.ErrInvalidPattern, pattern, err)
+			}
+			if re.SubexpIndex("file") < 0 || re.SubexpIndex("line") < 0 {
+				return nil, fmt.Errorf("%w: problem matcher %q needs file and line groups", domain.ErrInvalidPattern, pattern)
+			}
+			m.matchers[process] = append(m.matchers[process], re)
+		}
+	}
+	return m, nil
+}
+
+// Processes returns the names of the processes that have problem matchers,
+// sorted
+func (m *ProblemMatcher) Processes() []string {
+	if m == nil {
+		return nil
+	}
+	names := make([]string, 0, len(m.matchers))
+	for name := range m.matchers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Match returns the problem reported by a log entry, if its process's
+// matchers find one. The first matching regex wins.
+func (m *ProblemMatcher) Match(entry domain.LogEntry) (Problem, bool) {
+	if m == nil {
+		return Problem{}, false
+	}
+	matchers := m.matchers[entry.Process]
+	if len(matchers) == 0 {
+		return Problem{}, false
+	}
+
+	line := strings.TrimRight(ansiRegex.ReplaceAllString(entry.Line, ""), "\r")
+	for _, re := range matchers {
+		groups := re.FindStringSubmatch(line)
+		if groups == nil {
+			continue
+		}
+		lineNum, err := strconv.Atoi(group(re, groups, "line"))
+		if err != nil {
+			continue
+		}
+		column, _ := strconv.Atoi(group(re, groups, "column"))
+		message := strings.TrimSpace(group(re, groups, "message"))
+		if message == "" {
+			message = strings.TrimSpace(line)
+		}
+		return Problem{
+			Timestamp: entry.Timestamp,
+			Process:   entry.Process,
+			File:      group(re, groups, "file"),
+			Line:      lineNum,
+			Column:    column,
+			Severity:  strings.ToLower(group(re, groups, "severity")),
+			Message:   message,
+		}, true
+	}
+	return Problem{}, false
+}
+
+// FindProblems returns the problems reported by entries, in order
+func (m *ProblemMatcher) FindProblems(entries []domain.LogEntry) []Problem {
+	var problems []Problem
+	for _, entry := range entries {
+		if p, ok := m.Match(entry); ok {
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+// group returns the text captured by a named group, or "" if the regex has no
+// such group or it didn't participate in the match
+func group(re *regexp.Regexp, groups []string, name string) string {
+	if i := re.SubexpIndex(name); i >= 0 {
+		return groups[i]
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblemMatcher_Builtin(t *testing.T) {
+	tests := []struct {
+		matcher string
+		line    string
+		want    Problem
+	}{
+		{"go", "./main.go:12:5: undefined: handler", Problem{File: "./main.go", Line: 12, Column: 5, Message: "undefined: handler"}},
+		{"go", "    server_test.go:48: expected 200, got 500", Problem{File: "server_test.go", Line: 48, Message: "expected 200, got 500"}},
+		{"tsc", "src/app.ts(7,3): error TS2322: Type 'string' is not assignable to type 'number'.", Problem{File: "src/app.ts", Line: 7, Column: 3, Severity: "error", Message: "TS2322: Type 'string' is not assignable to type 'number'."}},
+		{"gcc", "src/main.c:3:10: fatal error: stdio.hh: No such file or directory", Problem{File: "src/main.c", Line: 3, Column: 10, Severity: "error", Message: "stdio.hh: No such file or directory"}},
+		{"gcc", "\x1b[1msrc/util.c:20:1: \x1b[35mwarning: \x1b[0mcontrol reaches end of non-void function", Problem{File: "src/util.c", Line: 20, Column: 1, Severity: "warning", Message: "control reaches end of non-void function"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			m, err := NewProblemMatcher(map[string][]string{"build": {config.BuiltinProblemMatchers[tt.matcher]}})
+			require.NoError(t, err)
+
+			got, ok := m.Match(domain.LogEntry{Process: "build", Line: tt.line})
+			require.True(t, ok)
+			tt.want.Process = "build"
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestProblemMatcher_Match(t *testing.T) {
+	m, err := NewProblemMatcher(map[string][]string{
+		"test": {`^FAIL (?P<file>\S+) line (?P<line>\d+)$`},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test"}, m.Processes())
+
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	got, ok := m.Match(domain.LogEntry{Timestamp: at, Process: "test", Line: "FAIL spec/user.rb line 9"})
+	require.True(t, ok)
+	assert.Equal(t, Problem{Timestamp: at, Process: "test", File: "spec/user.rb", Line: 9, Message: "FAIL spec/user.rb line 9"}, got)
+
+	// Other processes' output isn't matched
+	_, ok = m.Match(domain.LogEntry{Process: "web", Line: "FAIL spec/user.rb line 9"})
+	assert.False(t, ok)
+	_, ok = m.Match(domain.LogEntry{Process: "test", Line: "PASS spec/user.rb"})
+	assert.False(t, ok)
+
+	var nilMatcher *ProblemMatcher
+	_, ok = nilMatcher.Match(domain.LogEntry{Process: "test", Line: "FAIL spec/user.rb line 9"})
+	assert.False(t, ok)
+}
+
+func TestNewProblemMatcher_Invalid(t *testing.T) {
+	_, err := NewProblemMatcher(map[string][]string{"build": {`(?P<file>\S+`}})
+	assert.ErrorIs(t, err, domain.ErrInvalidPattern)
+
+	_, err = NewProblemMatcher(map[string][]string{"build": {`^(?P<file>\S+): (?P<message>.*)$`}})
+	assert.ErrorIs(t, err, domain.ErrInvalidPattern)
+}
+
+func TestProblemMatcher_FindProblems(t *testing.T) {
+	m, err := NewProblemMatcher(map[string][]string{"build": {config.BuiltinProblemMatchers["go"]}})
+	require.NoError(t, err)
+
+	problems := m.FindProblems([]domain.LogEntry{
+		{Process: "build", Line: "# example.com/app"},
+		{Process: "build", Line: "./a.go:1:1: first"},
+		{Process: "build", Line: "./b.go:2:2: second"},
+	})
+	require.Len(t, problems, 2)
+	assert.Equal(t, "first", problems[0].Message)
+	assert.Equal(t, "second", problems[1].Message)
+}
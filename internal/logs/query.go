@@ -0,0 +1,228 @@
+package logs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/domain"
+)
+
+// Index names reported in QueryResult.Index
+const (
+	IndexScan        = "scan"         // Every buffered entry was a candidate
+	IndexProcess     = "process"      // Only the requested processes' entries
+	IndexTime        = "time"         // Only entries within the time range
+	IndexProcessTime = "process+time" // Only the requested processes' entries within the time range
+)
+
+// QueryBudget limits the work a single log query may do
+type QueryBudget struct {
+	MaxScanned  int           // Most entries to examine (0 means no limit)
+	MaxDuration time.Duration // Longest the query may run (0 means no limit)
+}
+
+// DefaultQueryBudget returns the default per-query budget
+func DefaultQueryBudget() QueryBudget {
+	return QueryBudget{
+		MaxScanned:  constants.DefaultLogQueryMaxScanned,
+		MaxDuration: constants.DefaultLogQueryMaxDuration,
+	}
+}
+
+// QueryResult is the outcome of a budgeted log query
+type QueryResult struct {
+	Entries   []domain.LogEntry // Matching entries, oldest first
+	Total     int               // Matching entries found before limiting
+	Scanned   int               // Entries examined
+	Truncated bool              // The budget ran out before every candidate was examined
+	Index     string            // Index used to narrow the candidates
+}
+
+// deadlineCheckInterval is how many entries are scanned between clock checks
+const deadlineCheckInterval = 256
+
+// scanLimiter is a token bucket shared by all queries. Each scanned entry
+// costs one token, so a burst of expensive queries from one client leaves
+// less budget for the next rather than stalling the daemon.
+type scanLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // Tokens added per second
+	burst  float64 // Bucket capacity
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newScanLimiter(rate, burst int) *scanLimiter {
+	return &scanLimiter{
+		rate:   float64(rate),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// available refills the bucket and returns the whole tokens in it
+func (l *scanLimiter) available() int {
+	if l == nil {
+		return -1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	return int(l.tokens)
+}
+
+// take removes n tokens from the bucket
+func (l *scanLimiter) take(n int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens -= float64(n)
+	if l.tokens < 0 {
+		l.tokens = 0
+	}
+}
+
+// Search returns the last n entries matching the filter, scanning from the
+// newest entry back. Scanning stops when the query budget or the shared scan
+// allowance runs out; the result then holds the newest matches found so far
+// and is marked Truncated.
+func (m *Manager) Search(filter domain.LogFilter, n int) (QueryResult, error) {
+	f, err := filter.Compile()
+	if err != nil {
+		return QueryResult{}, err
+	}
+
+	candidates, index := m.store.Candidates(filter)
+	return m.search(f, candidates, index, n), nil
+}
+
+// SubscribeWithBackfill subscribes to new entries matching the filter, like
+// Subscribe, and returns the last n buffered entries matching it, like
+// Search. Every entry is either in the backfill or sent to the subscription,
+// never both.
+func (m *Manager) SubscribeWithBackfill(filter domain.LogFilter, n int) (string, <-chan domain.LogEntry, QueryResult, error) {
+	f, err := filter.Compile()
+	if err != nil {
+		return "", nil, QueryResult{}, err
+	}
+
+	// Writes wait while the buffer is copied and the subscription added, so
+	// each lands either in the copy or on the subscription
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return "", nil, QueryResult{}, domain.ErrShutdownInProgress
+	}
+	candidates, index := m.store.Candidates(filter)
+	id, ch, err := m.subscriptions.Subscribe(filter)
+	m.mu.Unlock()
+	if err != nil {
+		return "", nil, QueryResult{}, err
+	}
+	return id, ch, m.search(f, candidates, index, n), nil
+}
+
+// allowance returns the most entries a query may scan (-1 means no limit)
+// and the time it must finish by (zero means no limit)
+func (m *Manager) allowance() (int, time.Time) {
+	allowance := -1
+	if m.budget.MaxScanned > 0 {
+		allowance = m.budget.MaxScanned
+	}
+	if tokens := m.limiter.available(); tokens >= 0 && (allowance < 0 || tokens < allowance) {
+		allowance = tokens
+	}
+	var deadline time.Time
+	if m.budget.MaxDuration > 0 {
+		deadline = time.Now().Add(m.budget.MaxDuration)
+	}
+	return allowance, deadline
+}
+
+// search returns the last n candidates matching f within the query budget
+func (m *Manager) search(f *domain.Filter, candidates []domain.LogEntry, index string, n int) QueryResult {
+	result := QueryResult{Index: index}
+	allowance, deadline := m.allowance()
+
+	var matches []domain.LogEntry
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if allowance >= 0 && result.Scanned >= allowance {
+			result.Truncated = true
+			break
+		}
+		if !deadline.IsZero() && result.Scanned > 0 && result.Scanned%deadlineCheckInterval == 0 && time.Now().After(deadline) {
+			result.Truncated = true
+			break
+		}
+		result.Scanned++
+
+		if f.Matches(candidates[i]) {
+			result.Total++
+			if n <= 0 || len(matches) < n {
+				matches = append(matches, candidates[i])
+			}
+		}
+	}
+	m.limiter.take(result.Scanned)
+
+	// Matches were collected newest first
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+	result.Entries = matches
+	return result
+}
+
+// Page is a page of log entries read forward from a cursor
+type Page struct {
+	Entries []domain.LogEntry // Matching entries, oldest first
+	Next    uint64            // ID to read the following page after
+	More    bool              // Entries after Next were stored when the page was read
+}
+
+// Page returns up to n entries matching the filter with IDs greater than
+// after, oldest first. IDs increase with each entry written, so reading
+// pages from each Next in turn sees every entry once, even while entries are
+// written, unless they are evicted before their page is read. Scanning stops
+// at the query budget like Search; the page may then hold fewer than n
+// entries while More is set.
+func (m *Manager) Page(filter domain.LogFilter, after uint64, n int) (Page, error) {
+	if after > filter.AfterID {
+		filter.AfterID = after
+	}
+	f, err := filter.Compile()
+	if err != nil {
+		return Page{}, err
+	}
+
+	candidates, _ := m.store.Candidates(filter)
+	page := Page{Next: filter.AfterID}
+	allowance, deadline := m.allowance()
+	scanned := 0
+	for _, entry := range candidates {
+		if (n > 0 && len(page.Entries) >= n) || (allowance >= 0 && scanned >= allowance) ||
+			(!deadline.IsZero() && scanned > 0 && scanned%deadlineCheckInterval == 0 && time.Now().After(deadline)) {
+			page.More = true
+			break
+		}
+		scanned++
+		page.Next = entry.ID
+		if f.Matches(entry) {
+			page.Entries = append(page.Entries, entry)
+		}
+	}
+	m.limiter.take(scanned)
+	return page, nil
+}
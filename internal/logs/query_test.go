@@ -0,0 +1,242 @@
+package logs
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTimed writes n entries per process, alternating processes, one second apart
+func writeTimed(m *Manager, base time.Time, n int, processes ...string) {
+	i := 0
+	for round := 0; round < n; round++ {
+		for _, p := range processes {
+			m.Write(domain.LogEntry{
+				Timestamp: base.Add(time.Duration(i) * time.Second),
+				Process:   p,
+				Stream:    domain.StreamStdout,
+				Line:      fmt.Sprintf("%s line %d", p, round),
+			})
+			i++
+		}
+	}
+}
+
+func lines(entries []domain.LogEntry) []string {
+	result := make([]string, len(entries))
+	for i, e := range entries {
+		result[i] = e.Line
+	}
+	return result
+}
+
+func TestManager_Search_ProcessIndex(t *testing.T) {
+	// 30 entries into 20 slots keeps api and worker rounds 3-9 and web rounds 4-9
+	m := NewManager(ManagerConfig{BufferSize: 20})
+	defer m.Close()
+	writeTimed(m, time.Now(), 10, "web", "api", "worker")
+
+	result, err := m.Search(domain.LogFilter{Processes: []string{"api"}}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, IndexProcess, result.Index)
+	assert.Equal(t, []string{"api line 7", "api line 8", "api line 9"}, lines(result.Entries))
+	assert.Equal(t, 7, result.Total)
+	// Only the api entries were examined
+	assert.Equal(t, 7, result.Scanned)
+	assert.False(t, result.Truncated)
+
+	result, err = m.Search(domain.LogFilter{Processes: []string{"worker", "api", "api"}}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 14, result.Total)
+	assert.Equal(t, "api line 3", result.Entries[0].Line)
+	assert.Equal(t, "worker line 3", result.Entries[1].Line)
+	assert.Equal(t, "worker line 9", result.Entries[13].Line)
+
+	result, err = m.Search(domain.LogFilter{Processes: []string{"missing"}}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, result.Entries)
+	assert.Equal(t, 0, result.Scanned)
+}
+
+func TestManager_Search_TimeRange(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 100})
+	defer m.Close()
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	writeTimed(m, base, 10, "web", "api")
+
+	filter := domain.LogFilter{Since: base.Add(4 * time.Second), Until: base.Add(7 * time.Second)}
+	result, err := m.Search(filter, 0)
+	require.NoError(t, err)
+	assert.Equal(t, IndexTime, result.Index)
+	assert.Equal(t, []string{"web line 2", "api line 2", "web line 3", "api line 3"}, lines(result.Entries))
+	assert.Equal(t, 4, result.Scanned)
+
+	filter.Processes = []string{"web"}
+	result, err = m.Search(filter, 0)
+	require.NoError(t, err)
+	assert.Equal(t, IndexProcessTime, result.Index)
+	assert.Equal(t, []string{"web line 2", "web line 3"}, lines(result.Entries))
+	assert.Equal(t, 2, result.Scanned)
+
+	result, err = m.Search(domain.LogFilter{Since: base.Add(time.Hour)}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, result.Entries)
+}
+
+func TestManager_Search_MaxScanned(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 100, QueryBudget: QueryBudget{MaxScanned: 5}})
+	defer m.Close()
+	writeTimed(m, time.Now(), 10, "web")
+
+	result, err := m.Search(domain.LogFilter{Patterns: []string{"line"}}, 0)
+	require.NoError(t, err)
+	assert.True(t, result.Truncated)
+	assert.Equal(t, IndexScan, result.Index)
+	assert.Equal(t, 5, result.Scanned)
+	// Partial results are the newest matches
+	assert.Equal(t, []string{"web line 5", "web line 6", "web line 7", "web line 8", "web line 9"}, lines(result.Entries))
+
+	// A narrower query fits in the budget
+	result, err = m.Search(domain.LogFilter{Patterns: []string{"line"}, Since: time.Now().Add(time.Hour)}, 0)
+	require.NoError(t, err)
+	assert.False(t, result.Truncated)
+}
+
+func TestManager_Search_ScanLimiter(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 100, ScanRate: 10, ScanBurst: 15})
+	defer m.Close()
+	writeTimed(m, time.Now(), 10, "web")
+
+	now := time.Now()
+	m.limiter.now = func() time.Time { return now }
+
+	result, err := m.Search(domain.LogFilter{}, 0)
+	require.NoError(t, err)
+	assert.False(t, result.Truncated)
+	assert.Equal(t, 10, result.Scanned)
+
+	// Only 5 tokens remain in the bucket
+	result, err = m.Search(domain.LogFilter{}, 0)
+	require.NoError(t, err)
+	assert.True(t, result.Truncated)
+	assert.Equal(t, 5, result.Scanned)
+
+	// The bucket is empty until it refills
+	result, err = m.Search(domain.LogFilter{}, 0)
+	require.NoError(t, err)
+	assert.True(t, result.Truncated)
+	assert.Empty(t, result.Entries)
+
+	now = now.Add(time.Second)
+	result, err = m.Search(domain.LogFilter{}, 0)
+	require.NoError(t, err)
+	assert.False(t, result.Truncated)
+	assert.Equal(t, 10, result.Scanned)
+}
+
+func TestManager_Search_InvalidPattern(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 10})
+	defer m.Close()
+
+	_, err := m.Search(domain.LogFilter{Patterns: []string{"["}, IsRegex: true}, 0)
+	assert.ErrorIs(t, err, domain.ErrInvalidPattern)
+}
+
+func TestManager_Page(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 100})
+	defer m.Close()
+	writeTimed(m, time.Now(), 5, "web", "api")
+
+	page, err := m.Page(domain.LogFilter{Processes: []string{"web"}}, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web line 0", "web line 1"}, lines(page.Entries))
+	assert.True(t, page.More)
+	assert.Equal(t, page.Entries[1].ID, page.Next)
+
+	page, err = m.Page(domain.LogFilter{Processes: []string{"web"}}, page.Next, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web line 2", "web line 3", "web line 4"}, lines(page.Entries))
+	assert.False(t, page.More)
+
+	// The last page's cursor picks up entries written since
+	next := page.Next
+	writeTimed(m, time.Now(), 1, "web")
+	page, err = m.Page(domain.LogFilter{}, next, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api line 4", "web line 0"}, lines(page.Entries))
+
+	page, err = m.Page(domain.LogFilter{}, page.Next, 10)
+	require.NoError(t, err)
+	assert.Empty(t, page.Entries)
+	assert.False(t, page.More)
+}
+
+func TestManager_Page_MaxScanned(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 100, QueryBudget: QueryBudget{MaxScanned: 4}})
+	defer m.Close()
+	writeTimed(m, time.Now(), 5, "web", "api")
+
+	// Pages resume where the budget ran out
+	var got []string
+	var after uint64
+	for {
+		page, err := m.Page(domain.LogFilter{Patterns: []string{"api"}}, after, 0)
+		require.NoError(t, err)
+		got = append(got, lines(page.Entries)...)
+		after = page.Next
+		if !page.More {
+			break
+		}
+	}
+	assert.Equal(t, []string{"api line 0", "api line 1", "api line 2", "api line 3", "api line 4"}, got)
+}
+
+func TestManager_Page_ConcurrentWrites(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 10000})
+	defer m.Close()
+
+	const writers, perWriter = 4, 500
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				m.Write(domain.LogEntry{Timestamp: time.Now(), Process: fmt.Sprintf("p%d", w), Line: fmt.Sprint(i)})
+			}
+		}(w)
+	}
+
+	// Paging while entries are written sees each exactly once
+	seen := make(map[uint64]bool)
+	var after uint64
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for finished := false; ; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+		page, err := m.Page(domain.LogFilter{}, after, 50)
+		require.NoError(t, err)
+		for _, e := range page.Entries {
+			assert.Greater(t, e.ID, after)
+			assert.False(t, seen[e.ID], "entry %d seen twice", e.ID)
+			seen[e.ID] = true
+		}
+		after = page.Next
+		if finished && !page.More {
+			break
+		}
+	}
+	assert.Len(t, seen, writers*perWriter)
+}
@@ -0,0 +1,87 @@
+package logs
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/charliek/prox/internal/domain"
+)
+
+// Masks substituted for redacted values
+const (
+	RedactEmailMask  = "[EMAIL]"
+	RedactTokenMask  = "[TOKEN]"
+	RedactIPMask     = "[IP]"
+	RedactCustomMask = "[REDACTED]"
+)
+
+var (
+	emailRegex  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	bearerRegex = regexp.MustCompile(`(?i)\b(bearer)(\s+)[A-Za-z0-9\-._~+/]+=*`)
+	// ipCandidateRegex finds runs of hex digits, dots, and colons that may be
+	// IP addresses; each candidate is confirmed with net.ParseIP so times like
+	// 10:32:01 are left alone.
+	ipCandidateRegex = regexp.MustCompile(`[0-9A-Fa-f:.]*[:.][0-9A-Fa-f:.]*`)
+)
+
+// Redactor masks sensitive values (emails, bearer tokens, IP addresses, and
+// user-defined patterns) in rendered log output. It never modifies stored logs.
+// A nil Redactor leaves lines unchanged.
+type Redactor struct {
+	custom []*regexp.Regexp
+}
+
+// NewRedactor creates a redactor with the built-in rules plus the given
+// user-defined regular expressions.
+func NewRedactor(patterns []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, pattern := range patterns {
+		if len(pattern) > domain.MaxPatternLength {
+			return nil, fmt.Errorf("%w: redact pattern exceeds maximum length of %d characters", domain.ErrInvalidPattern, domain.MaxPatternLength)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: redact pattern %q: %v", domain.ErrInvalidPattern, pattern, err)
+		}
+		r.custom = append(r.custom, re)
+	}
+	return r, nil
+}
+
+// Redact returns the line with sensitive values masked
+func (r *Redactor) Redact(line string) string {
+	if r == nil || line == "" {
+		return line
+	}
+
+	// User-defined patterns run first so they can match values that the
+	// built-in rules would otherwise partially mask
+	for _, re := range r.custom {
+		line = re.ReplaceAllString(line, RedactCustomMask)
+	}
+
+	line = bearerRegex.ReplaceAllString(line, "${1}${2}"+RedactTokenMask)
+	line = emailRegex.ReplaceAllString(line, RedactEmailMask)
+	line = ipCandidateRegex.ReplaceAllStringFunc(line, redactIP)
+	return line
+}
+
+// redactIP masks a candidate if it is an IP address, ignoring trailing
+// punctuation such as the period ending a sentence and IPv4 ports
+func redactIP(candidate string) string {
+	trimmed := strings.TrimRight(candidate, ".:")
+	if net.ParseIP(trimmed) != nil {
+		return RedactIPMask + candidate[len(trimmed):]
+	}
+
+	// IPv4 with port, e.g. 192.168.1.20:5432
+	if i := strings.LastIndex(trimmed, ":"); i > 0 {
+		host := trimmed[:i]
+		if strings.Contains(host, ".") && net.ParseIP(host) != nil {
+			return RedactIPMask + candidate[i:]
+		}
+	}
+	return candidate
+}
@@ -0,0 +1,37 @@
+package logs
+
+import "github.com/charliek/prox/internal/domain"
+
+// Store holds the log history of a Manager. RingBuffer, the default, keeps
+// recent entries in memory; other stores may keep them on disk. A store must
+// be safe for concurrent use.
+type Store interface {
+	// Write adds an entry and returns its ID, which is greater than the ID
+	// of every entry written before it (0 if the entry couldn't be stored)
+	Write(entry domain.LogEntry) uint64
+	// Read returns all entries in chronological order
+	Read() []domain.LogEntry
+	// Candidates returns the entries that can match the filter's processes,
+	// time range, and AfterID in chronological order, and the Index* constant naming
+	// how they were found
+	Candidates(filter domain.LogFilter) ([]domain.LogEntry, string)
+	// SetReservation sets the entries kept for a process regardless of other
+	// processes' output; n <= 0 restores the default. Stores without
+	// per-process retention ignore it.
+	SetReservation(process string, n int)
+	// Count returns the number of entries held
+	Count() int
+	// Capacity returns the most entries the store holds
+	Capacity() int
+	// Close releases the store when its manager closes
+	Close() error
+}
+
+// memoryStore is a Store that tracks the memory its entries use, such as
+// RingBuffer
+type memoryStore interface {
+	// MemoryBytes returns the approximate memory the entries use
+	MemoryBytes() int64
+	// MaxMemory returns the cap on MemoryBytes, or 0 if there is none
+	MaxMemory() int64
+}
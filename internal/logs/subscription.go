@@ -4,7 +4,9 @@ import (
 	"log"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/domain"
 )
 
@@ -14,13 +16,16 @@ var subscriptionIDCounter uint64
 type Subscription struct {
 	id     string
 	ch     chan domain.LogEntry
-	filter *Filter
+	filter *domain.Filter
 	closed atomic.Bool
+	// stalledSince is when the channel first filled up without being
+	// drained (unix nanoseconds), or 0 if the subscriber is keeping up
+	stalledSince atomic.Int64
 }
 
 // newSubscription creates a new subscription
 func newSubscription(filter domain.LogFilter, bufferSize int) (*Subscription, error) {
-	f, err := NewFilter(filter)
+	f, err := filter.Compile()
 	if err != nil {
 		return nil, err
 	}
@@ -76,14 +81,25 @@ func (s *Subscription) Send(entry domain.LogEntry) bool {
 
 	select {
 	case s.ch <- entry:
+		s.stalledSince.Store(0)
 		return true
 	default:
 		// Channel full, drop message - log for debugging slow clients
+		s.stalledSince.CompareAndSwap(0, time.Now().UnixNano())
 		log.Printf("Subscription %s: dropped message from process %s (channel full)", s.id, entry.Process)
 		return false
 	}
 }
 
+// StalledSince returns when the subscriber stopped draining its channel,
+// or the zero time if it is keeping up
+func (s *Subscription) StalledSince() time.Time {
+	if ns := s.stalledSince.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
 // Close closes the subscription
 func (s *Subscription) Close() {
 	if s.closed.CompareAndSwap(false, true) {
@@ -137,6 +153,18 @@ func (m *SubscriptionManager) Unsubscribe(id string) {
 	}
 }
 
+// StalledSince returns when the subscription stopped draining its channel,
+// or the zero time if it is keeping up or does not exist
+func (m *SubscriptionManager) StalledSince(id string) time.Time {
+	m.mu.RLock()
+	sub, ok := m.subscriptions[id]
+	m.mu.RUnlock()
+	if !ok {
+		return time.Time{}
+	}
+	return sub.StalledSince()
+}
+
 // Broadcast sends an entry to all subscribers
 func (m *SubscriptionManager) Broadcast(entry domain.LogEntry) {
 	m.mu.RLock()
@@ -154,6 +182,35 @@ func (m *SubscriptionManager) Count() int {
 	return len(m.subscriptions)
 }
 
+// Flush waits until every subscriber that is keeping up has received the
+// entries sent to it, or until timeout. Stalled subscribers aren't waited
+// for.
+func (m *SubscriptionManager) Flush(timeout time.Duration) {
+	ticker := time.NewTicker(constants.LogCloseFlushInterval)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+	for m.pending() {
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return
+		}
+	}
+}
+
+// pending reports whether a subscriber that is keeping up has entries it
+// hasn't received
+func (m *SubscriptionManager) pending() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, sub := range m.subscriptions {
+		if len(sub.ch) > 0 && sub.StalledSince().IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
 // Close closes all subscriptions
 func (m *SubscriptionManager) Close() {
 	m.mu.Lock()
@@ -189,3 +189,32 @@ func TestSubscriptionManager_Concurrent(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestSubscriptionManager_StalledSince(t *testing.T) {
+	m := NewSubscriptionManager(2)
+
+	id, ch, err := m.Subscribe(domain.LogFilter{})
+	require.NoError(t, err)
+	assert.True(t, m.StalledSince(id).IsZero())
+
+	// Fill the channel; the next entry is dropped and marks the stall
+	m.Broadcast(makeEntry("one"))
+	m.Broadcast(makeEntry("two"))
+	assert.True(t, m.StalledSince(id).IsZero())
+
+	before := time.Now()
+	m.Broadcast(makeEntry("three"))
+	stalled := m.StalledSince(id)
+	assert.False(t, stalled.Before(before))
+
+	// Further drops keep the original stall time
+	m.Broadcast(makeEntry("four"))
+	assert.Equal(t, stalled, m.StalledSince(id))
+
+	// Draining clears it on the next successful send
+	<-ch
+	m.Broadcast(makeEntry("five"))
+	assert.True(t, m.StalledSince(id).IsZero())
+
+	assert.True(t, m.StalledSince("missing").IsZero())
+}
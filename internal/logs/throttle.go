@@ -0,0 +1,122 @@
+package logs
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+)
+
+// repeatReportInterval is how often a run of identical lines that is still
+// going on is reported
+const repeatReportInterval = time.Second
+
+// throttleState is the throttle of one process and what it has let through.
+// Lines are timed by their timestamps.
+type throttleState struct {
+	domain.LogThrottle
+
+	// Rate limit: the second being counted, the lines kept in it, and the
+	// lines dropped since the last report
+	second  time.Time
+	kept    int
+	dropped int
+
+	// Repeats: the last line kept, and how often it repeated since it was
+	// kept or last reported
+	last     domain.LogEntry
+	hasLast  bool
+	repeats  int
+	reported time.Time
+}
+
+// throttles holds the throttles of the processes that have one
+type throttles struct {
+	mu     sync.Mutex
+	states map[string]*throttleState
+}
+
+// set sets a process's throttle, starting it afresh; an empty throttle
+// removes it
+func (t *throttles) set(process string, throttle domain.LogThrottle) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if throttle.IsEmpty() {
+		delete(t.states, process)
+		return
+	}
+	if t.states == nil {
+		t.states = make(map[string]*throttleState)
+	}
+	t.states[process] = &throttleState{LogThrottle: throttle}
+}
+
+// admit decides whether an entry is kept. reports are entries to write
+// before it, saying how many lines were dropped or repeated before it.
+func (t *throttles) admit(entry domain.LogEntry) (reports []domain.LogEntry, keep bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.states[entry.Process]
+	if s == nil {
+		return nil, true
+	}
+	now := entry.Timestamp
+
+	if s.CollapseRepeats && s.hasLast && entry.Line == s.last.Line && entry.Stream == s.last.Stream {
+		s.repeats++
+		if now.Sub(s.reported) >= repeatReportInterval {
+			reports = append(reports, s.repeatReport(now))
+		}
+		return reports, false
+	}
+	if s.repeats > 0 {
+		reports = append(reports, s.repeatReport(now))
+	}
+
+	if s.MaxLinesPerSec > 0 {
+		if second := now.Truncate(time.Second); !second.Equal(s.second) {
+			s.second = second
+			s.kept = 0
+		}
+		if s.kept >= s.MaxLinesPerSec {
+			s.dropped++
+			return reports, false
+		}
+		s.kept++
+		if s.dropped > 0 {
+			reports = append(reports, domain.LogEntry{
+				Timestamp: now,
+				Process:   entry.Process,
+				Stream:    domain.StreamStderr,
+				Line:      fmt.Sprintf("dropped %d lines over max_lines_per_sec (%d)", s.dropped, s.MaxLinesPerSec),
+			})
+			s.dropped = 0
+		}
+	}
+
+	if s.CollapseRepeats {
+		s.last = entry
+		s.hasLast = true
+		s.reported = now
+	}
+	return reports, true
+}
+
+// repeatReport returns the entry reporting the repeats of the last line
+// since it was last reported, and starts counting again
+func (s *throttleState) repeatReport(now time.Time) domain.LogEntry {
+	line := fmt.Sprintf("last line repeated %d times", s.repeats)
+	if s.repeats == 1 {
+		line = "last line repeated once"
+	}
+	report := domain.LogEntry{
+		Timestamp: now,
+		Process:   s.last.Process,
+		Stream:    s.last.Stream,
+		Line:      line,
+	}
+	s.repeats = 0
+	s.reported = now
+	return report
+}
@@ -0,0 +1,85 @@
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// throttledLines returns the lines of a process's entries in m
+func throttledLines(t *testing.T, m *Manager, process string) []string {
+	t.Helper()
+	entries, _, err := m.Query(domain.LogFilter{Processes: []string{process}}, 0)
+	require.NoError(t, err)
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = e.Line
+	}
+	return lines
+}
+
+func TestManager_ThrottleRate(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 100})
+	defer m.Close()
+	m.SetProcessThrottle("web", domain.LogThrottle{MaxLinesPerSec: 2})
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, line := range []string{"a", "b", "c", "d", "e"} {
+		m.Write(domain.LogEntry{Timestamp: start.Add(time.Duration(i) * time.Millisecond), Process: "web", Stream: domain.StreamStdout, Line: line})
+	}
+	m.Write(domain.LogEntry{Timestamp: start.Add(time.Second), Process: "web", Stream: domain.StreamStdout, Line: "f"})
+	// Other processes aren't throttled
+	for range 5 {
+		m.Write(domain.LogEntry{Timestamp: start, Process: "api", Stream: domain.StreamStdout, Line: "x"})
+	}
+
+	assert.Equal(t, []string{"a", "b", "dropped 3 lines over max_lines_per_sec (2)", "f"}, throttledLines(t, m, "web"))
+	assert.Len(t, throttledLines(t, m, "api"), 5)
+}
+
+func TestManager_ThrottleCollapseRepeats(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 100})
+	defer m.Close()
+	m.SetProcessThrottle("web", domain.LogThrottle{CollapseRepeats: true})
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	write := func(at time.Duration, line string) {
+		m.Write(domain.LogEntry{Timestamp: start.Add(at), Process: "web", Stream: domain.StreamStderr, Line: line})
+	}
+	write(0, "retrying")
+	for i := 1; i <= 3; i++ {
+		write(time.Duration(i)*time.Millisecond, "retrying")
+	}
+	write(10*time.Millisecond, "connected")
+	// A long run is reported every second while it lasts
+	write(20*time.Millisecond, "tick")
+	write(500*time.Millisecond, "tick")
+	write(1100*time.Millisecond, "tick")
+	write(1200*time.Millisecond, "tick")
+	write(1300*time.Millisecond, "done")
+
+	assert.Equal(t, []string{
+		"retrying",
+		"last line repeated 3 times",
+		"connected",
+		"tick",
+		"last line repeated 2 times",
+		"last line repeated once",
+		"done",
+	}, throttledLines(t, m, "web"))
+}
+
+func TestManager_ThrottleRemoved(t *testing.T) {
+	m := NewManager(ManagerConfig{BufferSize: 100})
+	defer m.Close()
+	m.SetProcessThrottle("web", domain.LogThrottle{CollapseRepeats: true})
+	m.SetProcessThrottle("web", domain.LogThrottle{})
+
+	for range 3 {
+		m.Write(makeEntryWithProcess("web", "same"))
+	}
+	assert.Len(t, throttledLines(t, m, "web"), 3)
+}
@@ -0,0 +1,201 @@
+// Package metrics implements the counters and histograms prox exposes at
+// /metrics, written in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultLatencyBuckets are histogram buckets in seconds suited to requests
+// to local services
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Sample is one value of a metric with its label values, in the order of the
+// metric's label names
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// labelSeparator joins label values into map keys; it can't appear in
+// valid UTF-8
+const labelSeparator = "\xff"
+
+// CounterVec is a counter partitioned by labels. It is safe for concurrent
+// use.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec creates a counter with the given label names
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{name: name, help: help, labels: labels, values: make(map[string]float64)}
+}
+
+// Inc adds one to the counter for the label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds delta to the counter for the label values
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	key := strings.Join(labelValues, labelSeparator)
+	c.mu.Lock()
+	c.values[key] += delta
+	c.mu.Unlock()
+}
+
+// Write writes the counter in the text format
+func (c *CounterVec) Write(w io.Writer) {
+	c.mu.Lock()
+	samples := make([]Sample, 0, len(c.values))
+	for key, v := range c.values {
+		samples = append(samples, Sample{Labels: strings.Split(key, labelSeparator), Value: v})
+	}
+	c.mu.Unlock()
+
+	WriteMetric(w, c.name, c.help, "counter", c.labels, samples)
+}
+
+// HistogramVec is a histogram partitioned by labels. It is safe for
+// concurrent use.
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogram
+}
+
+// histogram holds the observations for one set of label values
+type histogram struct {
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec creates a histogram with the given upper bucket bounds,
+// in increasing order, and label names
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, values: make(map[string]*histogram)}
+}
+
+// Observe records a value for the label values
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, labelSeparator)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hist, ok := h.values[key]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hist
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		hist.counts[i]++
+	}
+	hist.count++
+	hist.sum += v
+}
+
+// Write writes the histogram in the text format
+func (h *HistogramVec) Write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	bucketLabels := append(append([]string{}, h.labels...), "le")
+	for _, key := range keys {
+		hist := h.values[key]
+		values := strings.Split(key, labelSeparator)
+		bucketValues := append(append([]string{}, values...), "")
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += hist.counts[i]
+			bucketValues[len(values)] = formatFloat(bound)
+			writeSample(w, h.name+"_bucket", bucketLabels, bucketValues, float64(cumulative))
+		}
+		bucketValues[len(values)] = "+Inf"
+		writeSample(w, h.name+"_bucket", bucketLabels, bucketValues, float64(hist.count))
+		writeSample(w, h.name+"_sum", h.labels, values, hist.sum)
+		writeSample(w, h.name+"_count", h.labels, values, float64(hist.count))
+	}
+}
+
+// WriteMetric writes a metric and its samples in the text format, sorted by
+// label values. Use it for values computed when scraped, such as gauges.
+func WriteMetric(w io.Writer, name, help, kind string, labels []string, samples []Sample) {
+	writeHeader(w, name, help, kind)
+	sort.Slice(samples, func(i, j int) bool {
+		return strings.Join(samples[i].Labels, labelSeparator) < strings.Join(samples[j].Labels, labelSeparator)
+	})
+	for _, s := range samples {
+		writeSample(w, name, labels, s.Labels, s.Value)
+	}
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// labelEscaper escapes label values
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeSample writes one sample line
+func writeSample(w io.Writer, name string, labels, values []string, v float64) {
+	var sb strings.Builder
+	sb.WriteString(name)
+	if len(labels) > 0 {
+		sb.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+			fmt.Fprintf(&sb, "%s=\"%s\"", label, labelEscaper.Replace(value))
+		}
+		sb.WriteByte('}')
+	}
+	sb.WriteByte(' ')
+	sb.WriteString(formatFloat(v))
+	sb.WriteByte('\n')
+	_, _ = io.WriteString(w, sb.String())
+}
+
+// formatFloat formats a sample value or bucket bound
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
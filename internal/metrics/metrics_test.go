@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterVec(t *testing.T) {
+	c := NewCounterVec("prox_test_total", "A test counter.", "name", "kind")
+	c.Inc("b", "x")
+	c.Inc("a", "y")
+	c.Add(2.5, "a", "y")
+	c.Inc("q\"uote", "x")
+
+	var sb strings.Builder
+	c.Write(&sb)
+	assert.Equal(t, `# HELP prox_test_total A test counter.
+# TYPE prox_test_total counter
+prox_test_total{name="a",kind="y"} 3.5
+prox_test_total{name="b",kind="x"} 1
+prox_test_total{name="q\"uote",kind="x"} 1
+`, sb.String())
+}
+
+func TestHistogramVec(t *testing.T) {
+	h := NewHistogramVec("prox_test_seconds", "A test histogram.", []float64{0.1, 1}, "name")
+	h.Observe(0.05, "a")
+	h.Observe(0.1, "a")
+	h.Observe(0.5, "a")
+	h.Observe(3, "a")
+
+	var sb strings.Builder
+	h.Write(&sb)
+	assert.Equal(t, `# HELP prox_test_seconds A test histogram.
+# TYPE prox_test_seconds histogram
+prox_test_seconds_bucket{name="a",le="0.1"} 2
+prox_test_seconds_bucket{name="a",le="1"} 3
+prox_test_seconds_bucket{name="a",le="+Inf"} 4
+prox_test_seconds_sum{name="a"} 3.65
+prox_test_seconds_count{name="a"} 4
+`, sb.String())
+}
+
+func TestWriteMetric_NoLabels(t *testing.T) {
+	var sb strings.Builder
+	WriteMetric(&sb, "prox_test", "A test gauge.", "gauge", nil, []Sample{{Value: 1}})
+	assert.Equal(t, "# HELP prox_test A test gauge.\n# TYPE prox_test gauge\nprox_test 1\n", sb.String())
+}
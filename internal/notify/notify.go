@@ -0,0 +1,204 @@
+// Package notify tells the developer when a process crashes or fails its
+// health check, with a desktop notification, a shell command, or a webhook,
+// as configured in the notifications section.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/supervisor"
+)
+
+// title is the title of desktop notifications
+const title = "prox"
+
+// Notification is a single notification, as POSTed to the webhook
+type Notification struct {
+	Event     string    `json:"event"`
+	Process   string    `json:"process"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Notifier sends notifications for supervisor events
+type Notifier struct {
+	cfg    *config.NotificationsConfig
+	events []string
+	logf   func(format string, args ...interface{})
+	client *http.Client
+
+	// desktop shows a desktop notification, replaced in tests
+	desktop func(ctx context.Context, title, message string) error
+
+	// cooldown is how long repeats of a notification are suppressed
+	cooldown time.Duration
+
+	mu   sync.Mutex
+	sent map[string]time.Time // Last notification by process and event
+}
+
+// New creates a notifier for cfg. Notifications that fail to send are
+// reported through logf.
+func New(cfg *config.NotificationsConfig, logf func(format string, args ...interface{})) *Notifier {
+	return &Notifier{
+		cfg:      cfg,
+		events:   cfg.EventsOrDefault(),
+		logf:     logf,
+		client:   &http.Client{Timeout: constants.NotificationTimeout},
+		desktop:  showDesktop,
+		cooldown: constants.NotificationCooldown,
+		sent:     make(map[string]time.Time),
+	}
+}
+
+// Run sends notifications for events until the channel is closed
+func (n *Notifier) Run(events <-chan supervisor.SupervisorEvent) {
+	for event := range events {
+		n.Notify(event)
+	}
+}
+
+// Notify sends a notification for an event, unless the event isn't one to
+// notify for or the same notification was sent within the cooldown
+func (n *Notifier) Notify(event supervisor.SupervisorEvent) {
+	if !slices.Contains(n.events, string(event.Type)) || !n.due(event) {
+		return
+	}
+	notification := Notification{
+		Event:     string(event.Type),
+		Process:   event.Process,
+		Message:   message(event),
+		Timestamp: event.Timestamp,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.NotificationTimeout)
+	defer cancel()
+	if n.cfg.Desktop {
+		if err := n.desktop(ctx, title, notification.Message); err != nil {
+			n.logf("desktop notification failed: %v", err)
+		}
+	}
+	if n.cfg.Command != "" {
+		if err := runCommand(ctx, n.cfg.Command, notification); err != nil {
+			n.logf("notification command failed: %v", err)
+		}
+	}
+	if n.cfg.Webhook != "" {
+		if err := n.post(ctx, notification); err != nil {
+			n.logf("notification webhook failed: %v", err)
+		}
+	}
+}
+
+// due reports whether a notification for an event may be sent, recording
+// it as sent if so
+func (n *Notifier) due(event supervisor.SupervisorEvent) bool {
+	key := event.Process + "\x00" + string(event.Type)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if last, ok := n.sent[key]; ok && event.Timestamp.Sub(last) < n.cooldown {
+		return false
+	}
+	n.sent[key] = event.Timestamp
+	return true
+}
+
+// message describes an event for a notification
+func message(event supervisor.SupervisorEvent) string {
+	switch event.Type {
+	case supervisor.EventTypeProcessCrashed:
+		return event.Process + " crashed"
+	case supervisor.EventTypeProcessFailed:
+		return event.Process + " crashed too often and won't be restarted"
+	case supervisor.EventTypeProcessUnhealthy:
+		if details := event.Info.HealthDetails; details != nil && details.LastOutput != "" {
+			output, _, _ := strings.Cut(strings.TrimSpace(details.LastOutput), "\n")
+			return fmt.Sprintf("%s is unhealthy: %s", event.Process, output)
+		}
+		return event.Process + " is unhealthy"
+	case supervisor.EventTypeProcessHealthy:
+		return event.Process + " is healthy again"
+	default:
+		return fmt.Sprintf("%s: %s", event.Process, event.Type)
+	}
+}
+
+// runCommand runs the notification command through the shell, describing
+// the notification in $PROX_EVENT, $PROX_PROCESS, and $PROX_MESSAGE
+func runCommand(ctx context.Context, command string, notification Notification) error {
+	cmd := exec.CommandContext(ctx, constants.DefaultShell, "-c", command)
+	cmd.Env = append(os.Environ(),
+		"PROX_EVENT="+notification.Event,
+		"PROX_PROCESS="+notification.Process,
+		"PROX_MESSAGE="+notification.Message,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// post POSTs a notification to the webhook as JSON
+func (n *Notifier) post(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", n.cfg.Webhook, resp.Status)
+	}
+	return nil
+}
+
+// showDesktop shows a desktop notification with osascript on macOS and
+// notify-send on Linux
+func showDesktop(ctx context.Context, title, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptQuote(message), appleScriptQuote(title))
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	case "linux":
+		cmd = exec.CommandContext(ctx, "notify-send", title, message)
+	default:
+		return fmt.Errorf("desktop notifications aren't supported on %s", runtime.GOOS)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %w: %s", cmd.Args[0], err, msg)
+		}
+		return fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return nil
+}
+
+// appleScriptQuote quotes a string for AppleScript
+func appleScriptQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/supervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestNotifier returns a notifier recording desktop notifications and
+// logged failures
+func newTestNotifier(cfg *config.NotificationsConfig) (*Notifier, *[]string, *[]string) {
+	var shown, logged []string
+	n := New(cfg, func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	})
+	n.desktop = func(ctx context.Context, title, message string) error {
+		shown = append(shown, message)
+		return nil
+	}
+	return n, &shown, &logged
+}
+
+func TestNotifier_Events(t *testing.T) {
+	n, shown, _ := newTestNotifier(&config.NotificationsConfig{Desktop: true})
+	now := time.Now()
+
+	n.Notify(supervisor.SupervisorEvent{Type: supervisor.EventTypeProcessStarted, Process: "web", Timestamp: now})
+	n.Notify(supervisor.SupervisorEvent{Type: supervisor.EventTypeProcessCrashed, Process: "web", Timestamp: now})
+	n.Notify(supervisor.SupervisorEvent{Type: supervisor.EventTypeProcessHealthy, Process: "web", Timestamp: now})
+	n.Notify(supervisor.SupervisorEvent{
+		Type:      supervisor.EventTypeProcessUnhealthy,
+		Process:   "api",
+		Timestamp: now,
+		Info:      domain.ProcessInfo{HealthDetails: &domain.HealthState{LastOutput: "connection refused\nretrying"}},
+	})
+	assert.Equal(t, []string{"web crashed", "api is unhealthy: connection refused"}, *shown)
+}
+
+func TestNotifier_Cooldown(t *testing.T) {
+	n, shown, _ := newTestNotifier(&config.NotificationsConfig{Desktop: true})
+	now := time.Now()
+
+	crash := func(process string, at time.Time) {
+		n.Notify(supervisor.SupervisorEvent{Type: supervisor.EventTypeProcessCrashed, Process: process, Timestamp: at})
+	}
+	crash("web", now)
+	crash("web", now.Add(time.Second))
+	crash("api", now.Add(time.Second))
+	crash("web", now.Add(n.cooldown))
+	assert.Equal(t, []string{"web crashed", "api crashed", "web crashed"}, *shown)
+}
+
+func TestNotifier_Command(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	n, _, logged := newTestNotifier(&config.NotificationsConfig{
+		Command: fmt.Sprintf(`printf '%%s|%%s|%%s' "$PROX_EVENT" "$PROX_PROCESS" "$PROX_MESSAGE" > %s`, out),
+	})
+	n.Notify(supervisor.SupervisorEvent{Type: supervisor.EventTypeProcessCrashed, Process: "web", Timestamp: time.Now()})
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "process_crashed|web|web crashed", string(data))
+	assert.Empty(t, *logged)
+
+	n.cfg.Command = "echo boom >&2; exit 1"
+	n.Notify(supervisor.SupervisorEvent{Type: supervisor.EventTypeProcessCrashed, Process: "api", Timestamp: time.Now()})
+	require.Len(t, *logged, 1)
+	assert.Contains(t, (*logged)[0], "notification command failed: exit status 1: boom")
+}
+
+func TestNotifier_Webhook(t *testing.T) {
+	received := make(chan Notification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification Notification
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&notification))
+		received <- notification
+	}))
+	defer server.Close()
+
+	n, _, logged := newTestNotifier(&config.NotificationsConfig{Webhook: server.URL})
+	ts := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	n.Notify(supervisor.SupervisorEvent{Type: supervisor.EventTypeProcessUnhealthy, Process: "api", Timestamp: ts})
+
+	notification := <-received
+	assert.Equal(t, Notification{Event: "process_unhealthy", Process: "api", Message: "api is unhealthy", Timestamp: ts}, notification)
+	assert.Empty(t, *logged)
+}
+
+func TestNotifier_WebhookFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	n, _, logged := newTestNotifier(&config.NotificationsConfig{Webhook: server.URL})
+	n.Notify(supervisor.SupervisorEvent{Type: supervisor.EventTypeProcessCrashed, Process: "web", Timestamp: time.Now()})
+	require.Len(t, *logged, 1)
+	assert.Contains(t, (*logged)[0], "500 Internal Server Error")
+}
+
+func TestAppleScriptQuote(t *testing.T) {
+	assert.Equal(t, `"web said \"hi\" \\ bye"`, appleScriptQuote(`web said "hi" \ bye`))
+}
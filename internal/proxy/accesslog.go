@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
+)
+
+// accessLogLine is a request in the json access log format
+type accessLogLine struct {
+	ID         string `json:"id"`
+	Method     string `json:"method"`
+	Subdomain  string `json:"subdomain"`
+	URL        string `json:"url"`
+	Status     int    `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+	Bytes      int64  `json:"bytes"`
+	RemoteAddr string `json:"remote_addr"`
+}
+
+// AccessLogEntry formats a recorded request as a log line under process.
+// The line's level follows the status: error for 5xx, warn for 4xx, and
+// info otherwise, so prox logs --level can pick out failed requests.
+func AccessLogEntry(record RequestRecord, process, format string) domain.LogEntry {
+	var line string
+	if format == config.AccessLogFormatJSON {
+		data, _ := json.Marshal(accessLogLine{
+			ID:         record.ID,
+			Method:     record.Method,
+			Subdomain:  record.Subdomain,
+			URL:        record.URL,
+			Status:     record.StatusCode,
+			DurationMs: record.Duration.Milliseconds(),
+			Bytes:      record.ResponseBytes,
+			RemoteAddr: record.RemoteAddr,
+		})
+		line = string(data)
+	} else {
+		line = fmt.Sprintf("%s %s %s %s %d %s %s", record.ID, record.Method, record.Subdomain, record.URL,
+			record.StatusCode, humanize.Duration(record.Duration), humanize.Bytes(record.ResponseBytes))
+	}
+
+	level := "info"
+	switch {
+	case record.StatusCode >= 500:
+		level = "error"
+	case record.StatusCode >= 400:
+		level = "warn"
+	}
+	return domain.LogEntry{
+		Timestamp: record.Timestamp,
+		Process:   process,
+		Stream:    domain.StreamStdout,
+		Line:      line,
+		Level:     level,
+	}
+}
+
+// WriteAccessLog passes each request recorded from now on to write as an
+// access log line, until ctx is done. Requests aren't recorded while
+// recording is paused, so they aren't logged either.
+func (s *Service) WriteAccessLog(ctx context.Context, cfg *config.AccessLogConfig, write func(domain.LogEntry)) {
+	sub := s.requestManager.Subscribe(RequestFilter{})
+	defer s.requestManager.Unsubscribe(sub.ID)
+
+	process, format := cfg.ProcessOrDefault(), cfg.FormatOrDefault()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case record, ok := <-sub.Ch:
+			if !ok {
+				return
+			}
+			write(AccessLogEntry(record, process, format))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+)
+
+func TestAccessLogEntry(t *testing.T) {
+	ts := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	record := RequestRecord{
+		ID:            "a1b2c3d",
+		Timestamp:     ts,
+		Method:        "GET",
+		URL:           "/users",
+		Subdomain:     "api",
+		StatusCode:    200,
+		Duration:      45 * time.Millisecond,
+		ResponseBytes: 1843,
+		RemoteAddr:    "127.0.0.1:5000",
+	}
+
+	entry := AccessLogEntry(record, "proxy", config.AccessLogFormatText)
+	assert.Equal(t, domain.LogEntry{
+		Timestamp: ts,
+		Process:   "proxy",
+		Stream:    domain.StreamStdout,
+		Line:      "a1b2c3d GET api /users 200 45ms 1.8 KiB",
+		Level:     "info",
+	}, entry)
+
+	entry = AccessLogEntry(record, "proxy", config.AccessLogFormatJSON)
+	var line map[string]any
+	require.NoError(t, json.Unmarshal([]byte(entry.Line), &line))
+	assert.Equal(t, "a1b2c3d", line["id"])
+	assert.Equal(t, float64(200), line["status"])
+	assert.Equal(t, float64(45), line["duration_ms"])
+	assert.Equal(t, float64(1843), line["bytes"])
+
+	record.StatusCode = 404
+	assert.Equal(t, "warn", AccessLogEntry(record, "proxy", config.AccessLogFormatText).Level)
+	record.StatusCode = 502
+	assert.Equal(t, "error", AccessLogEntry(record, "proxy", config.AccessLogFormatText).Level)
+}
+
+func TestService_WriteAccessLog(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	cfg := &config.ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.myapp.dev"}
+	services := map[string]config.ServiceConfig{
+		"app": {Port: backend.Listener.Addr().(*net.TCPAddr).Port, Host: "localhost"},
+	}
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+	router := svc.createRouter()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	entries := make(chan domain.LogEntry, 1)
+	done := make(chan struct{})
+	go func() {
+		svc.WriteAccessLog(ctx, &config.AccessLogConfig{Enabled: true, Process: "edge"}, func(entry domain.LogEntry) {
+			entries <- entry
+		})
+		close(done)
+	}()
+
+	subscribers := func() int {
+		svc.requestManager.subMu.RLock()
+		defer svc.requestManager.subMu.RUnlock()
+		return len(svc.requestManager.subs)
+	}
+	// Wait for the subscription before sending the request
+	require.Eventually(t, func() bool { return subscribers() == 1 }, time.Second, 5*time.Millisecond)
+
+	req := httptest.NewRequest("GET", "/users", nil)
+	req.Host = "app.local.myapp.dev"
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	select {
+	case entry := <-entries:
+		assert.Equal(t, "edge", entry.Process)
+		assert.Contains(t, entry.Line, " GET app /users 200 ")
+	case <-time.After(time.Second):
+		t.Fatal("request wasn't written to the access log")
+	}
+
+	cancel()
+	<-done
+	assert.Equal(t, 0, subscribers())
+}
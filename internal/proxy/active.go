@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"sort"
+	"time"
+)
+
+// ActiveRequest is a proxied request that hasn't been answered yet
+type ActiveRequest struct {
+	ID        string
+	Method    string
+	URL       string
+	Subdomain string
+	Start     time.Time
+}
+
+// trackActive adds a request to the in-flight requests until the returned
+// function is called
+func (s *Service) trackActive(info *RequestInfo, method, url string) func() {
+	s.activeMu.Lock()
+	s.active[info] = ActiveRequest{
+		ID:        info.ID,
+		Method:    method,
+		URL:       url,
+		Subdomain: info.Subdomain,
+		Start:     info.Start,
+	}
+	s.activeMu.Unlock()
+
+	return func() {
+		s.activeMu.Lock()
+		delete(s.active, info)
+		s.activeMu.Unlock()
+	}
+}
+
+// ActiveRequests returns the proxied requests in flight, oldest first.
+// Upgraded WebSocket connections count until they close.
+func (s *Service) ActiveRequests() []ActiveRequest {
+	s.activeMu.Lock()
+	requests := make([]ActiveRequest, 0, len(s.active))
+	for _, req := range s.active {
+		requests = append(requests, req)
+	}
+	s.activeMu.Unlock()
+
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Start.Before(requests[j].Start) })
+	return requests
+}
@@ -18,6 +18,7 @@ import (
 
 	"github.com/charliek/prox/internal/config"
 	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/fsperm"
 )
 
 // CaptureManager handles request/response body capture with hybrid memory/disk storage.
@@ -28,11 +29,19 @@ type CaptureManager struct {
 	inlineThreshold int64
 	captureDir      string
 	workDir         string
+	filter          *captureFilter // Which requests are captured (nil = all)
 }
 
 // NewCaptureManager creates a new capture manager.
 // If cfg is nil or capture is not enabled, returns a manager that does nothing.
 func NewCaptureManager(cfg *config.CaptureConfig, workDir string) (*CaptureManager, error) {
+	return newCaptureManager(cfg, workDir, false)
+}
+
+// newCaptureManager creates a capture manager, keeping the files captured by
+// the previous run when keep is set so a persisted history can still load
+// their bodies
+func newCaptureManager(cfg *config.CaptureConfig, workDir string, keep bool) (*CaptureManager, error) {
 	cm := &CaptureManager{
 		workDir:         workDir,
 		maxBodySize:     constants.DefaultCaptureMaxBodySize,
@@ -46,6 +55,14 @@ func NewCaptureManager(cfg *config.CaptureConfig, workDir string) (*CaptureManag
 
 	cm.enabled = true
 
+	if len(cfg.Include) > 0 || len(cfg.Exclude) > 0 {
+		filter, err := newCaptureFilter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		cm.filter = filter
+	}
+
 	// Parse max body size if configured
 	if cfg.MaxBodySize != "" {
 		size, err := config.ParseSize(cfg.MaxBodySize)
@@ -61,12 +78,14 @@ func NewCaptureManager(cfg *config.CaptureConfig, workDir string) (*CaptureManag
 	cm.captureDir = filepath.Join(workDir, constants.CaptureDirectory)
 
 	// Clean up any existing capture files from previous run
-	if err := cm.Cleanup(); err != nil && !os.IsNotExist(err) {
-		return nil, err
+	if !keep {
+		if err := cm.Cleanup(); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
 	}
 
 	// Create capture directory
-	if err := os.MkdirAll(cm.captureDir, constants.DirPermissionPrivate); err != nil {
+	if err := fsperm.MkdirAll(cm.captureDir); err != nil {
 		return nil, err
 	}
 
@@ -140,7 +159,7 @@ func (cm *CaptureManager) CaptureResponse(requestID string, crw *capturingRespon
 	} else {
 		// Store on disk
 		filePath := filepath.Join(cm.captureDir, requestID+"_res.bin")
-		if err := os.WriteFile(filePath, data, constants.FilePermissionPrivate); err == nil {
+		if err := fsperm.WriteFile(filePath, data); err == nil {
 			body.FilePath = filePath
 		} else {
 			// Fall back to inline if disk write fails
@@ -183,6 +202,25 @@ func (cm *CaptureManager) CleanupRequest(requestID string) {
 	_ = os.Remove(filepath.Join(cm.captureDir, requestID+"_res.bin"))
 }
 
+// Prune removes the captured body files of requests keep returns false for.
+func (cm *CaptureManager) Prune(keep func(requestID string) bool) error {
+	if !cm.enabled || cm.captureDir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(cm.captureDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		requestID, _, ok := strings.Cut(strings.TrimSuffix(name, ".bin"), "_")
+		if !ok || !keep(requestID) {
+			_ = os.Remove(filepath.Join(cm.captureDir, name))
+		}
+	}
+	return nil
+}
+
 // Cleanup removes the entire capture directory.
 func (cm *CaptureManager) Cleanup() error {
 	if cm.captureDir == "" {
@@ -255,7 +293,7 @@ func (cb *captureBuffer) finalize() error {
 	if cb.cm.captureDir != "" {
 		// Store on disk
 		filePath := filepath.Join(cb.cm.captureDir, cb.requestID+cb.suffix+".bin")
-		if err := os.WriteFile(filePath, data, constants.FilePermissionPrivate); err != nil {
+		if err := fsperm.WriteFile(filePath, data); err != nil {
 			// Fall back to inline if disk write fails, but return error for caller awareness
 			cb.body.Data = data
 			return fmt.Errorf("failed to write capture file %s: %w", filePath, err)
@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/charliek/prox/internal/config"
+)
+
+// captureFilter decides which requests are captured, from the include and
+// exclude rules of proxy.capture. Rules without a content type are decided
+// before the request is forwarded, so requests they skip cost nothing;
+// content types are matched against the response once it has been written.
+type captureFilter struct {
+	include []captureRule
+	exclude []captureRule
+}
+
+// captureRule is a compiled config.CaptureRule
+type captureRule struct {
+	subdomain   string
+	path        *regexp.Regexp
+	methods     []string
+	contentType string // Media type, or a prefix such as "image/" for "image/*"
+	websocket   bool
+}
+
+// newCaptureFilter compiles the rules of a capture config
+func newCaptureFilter(cfg *config.CaptureConfig) (*captureFilter, error) {
+	f := &captureFilter{}
+	for i, rule := range cfg.Include {
+		compiled, err := compileCaptureRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("proxy.capture.include[%d]: %w", i, err)
+		}
+		f.include = append(f.include, compiled)
+	}
+	for i, rule := range cfg.Exclude {
+		compiled, err := compileCaptureRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("proxy.capture.exclude[%d]: %w", i, err)
+		}
+		f.exclude = append(f.exclude, compiled)
+	}
+	return f, nil
+}
+
+// compileCaptureRule compiles a rule's path regex and normalizes its fields
+func compileCaptureRule(rule config.CaptureRule) (captureRule, error) {
+	compiled := captureRule{
+		subdomain:   rule.Subdomain,
+		contentType: strings.TrimSuffix(strings.ToLower(rule.ContentType), "*"),
+		websocket:   rule.WebSocket,
+	}
+	if rule.Path != "" {
+		re, err := regexp.Compile(rule.Path)
+		if err != nil {
+			return captureRule{}, fmt.Errorf("invalid path regex %q: %w", rule.Path, err)
+		}
+		compiled.path = re
+	}
+	for _, method := range rule.Methods {
+		compiled.methods = append(compiled.methods, strings.ToUpper(method))
+	}
+	return compiled, nil
+}
+
+// matchesRequest reports whether the request matches every field of the rule
+// but its content type
+func (c captureRule) matchesRequest(r *http.Request, subdomain string) bool {
+	if c.subdomain != "" {
+		if ok, _ := path.Match(c.subdomain, subdomain); !ok {
+			return false
+		}
+	}
+	if c.path != nil && !c.path.MatchString(r.URL.Path) {
+		return false
+	}
+	if len(c.methods) > 0 && !slices.Contains(c.methods, r.Method) {
+		return false
+	}
+	return !c.websocket || isWebSocketUpgrade(r)
+}
+
+// matchesContentType reports whether a response Content-Type matches the
+// rule's content type
+func (c captureRule) matchesContentType(contentType string) bool {
+	if c.contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasSuffix(c.contentType, "/") {
+		return strings.HasPrefix(mediaType, c.contentType)
+	}
+	return mediaType == c.contentType
+}
+
+// mayCapture reports whether a request can be captured, before its response
+// is known. When it returns false, the request isn't captured.
+func (f *captureFilter) mayCapture(r *http.Request, subdomain string) bool {
+	if f == nil {
+		return true
+	}
+	for _, rule := range f.exclude {
+		if rule.contentType == "" && rule.matchesRequest(r, subdomain) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, rule := range f.include {
+		if rule.matchesRequest(r, subdomain) {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldCapture reports whether a request is captured, given the
+// Content-Type of its response
+func (f *captureFilter) shouldCapture(r *http.Request, subdomain, contentType string) bool {
+	if f == nil {
+		return true
+	}
+	for _, rule := range f.exclude {
+		if rule.matchesRequest(r, subdomain) && rule.matchesContentType(contentType) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, rule := range f.include {
+		if rule.matchesRequest(r, subdomain) && rule.matchesContentType(contentType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/prox/internal/config"
+)
+
+func TestCaptureFilter(t *testing.T) {
+	filter, err := newCaptureFilter(&config.CaptureConfig{
+		Include: []config.CaptureRule{
+			{Subdomain: "api"},
+			{Subdomain: "pr-*", Methods: []string{"post"}},
+		},
+		Exclude: []config.CaptureRule{
+			{Path: "^/static/"},
+			{ContentType: "image/*"},
+			{WebSocket: true},
+		},
+	})
+	require.NoError(t, err)
+
+	request := func(method, target string) *http.Request {
+		return httptest.NewRequest(method, target, nil)
+	}
+	websocket := request("GET", "/ws")
+	websocket.Header.Set("Upgrade", "websocket")
+	websocket.Header.Set("Connection", "Upgrade")
+
+	tests := []struct {
+		name        string
+		r           *http.Request
+		subdomain   string
+		contentType string
+		may         bool
+		should      bool
+	}{
+		{"included subdomain", request("GET", "/users"), "api", "application/json; charset=utf-8", true, true},
+		{"other subdomain", request("GET", "/users"), "web", "application/json", false, false},
+		{"glob and method", request("POST", "/users"), "pr-12", "application/json", true, true},
+		{"glob with other method", request("GET", "/users"), "pr-12", "application/json", false, false},
+		{"excluded path", request("GET", "/static/app.js"), "api", "text/javascript", false, false},
+		{"excluded content type", request("GET", "/avatar"), "api", "image/png", true, false},
+		{"websocket", websocket, "api", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.may, filter.mayCapture(tt.r, tt.subdomain))
+			assert.Equal(t, tt.should, filter.shouldCapture(tt.r, tt.subdomain, tt.contentType))
+		})
+	}
+
+	// Without rules, everything is captured
+	var none *captureFilter
+	assert.True(t, none.mayCapture(request("GET", "/"), "web"))
+	assert.True(t, none.shouldCapture(request("GET", "/"), "web", "image/png"))
+}
+
+func TestService_CaptureRules(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/logo.png" {
+			w.Header().Set("Content-Type", "image/png")
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	cfg := &config.ProxyConfig{
+		Enabled:  true,
+		HTTPPort: 6788,
+		Domain:   "local.myapp.dev",
+		Capture: &config.CaptureConfig{
+			Enabled: true,
+			Exclude: []config.CaptureRule{{Path: "^/static/"}, {ContentType: "image/*"}},
+		},
+	}
+	services := map[string]config.ServiceConfig{
+		"app": {Port: backend.Listener.Addr().(*net.TCPAddr).Port, Host: "localhost"},
+	}
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+	router := svc.createRouter()
+
+	for _, path := range []string{"/api", "/static/app.js", "/logo.png"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Host = "app.local.myapp.dev"
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Excluded requests are still recorded, without their headers and bodies
+	records := svc.RequestManager().Recent(RequestFilter{})
+	require.Len(t, records, 3)
+	captured := map[string]bool{}
+	for _, record := range records {
+		captured[record.URL] = record.Details != nil
+	}
+	assert.Equal(t, map[string]bool{"/api": true, "/static/app.js": false, "/logo.png": false}, captured)
+}
@@ -0,0 +1,60 @@
+package certs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charliek/prox/internal/constants"
+)
+
+// Files of the API server's certificate in the certs directory
+const (
+	apiCertFile = "api.pem"
+	apiKeyFile  = "api-key.pem"
+)
+
+// EnsureAPICert returns a self-signed certificate for the API server, kept
+// in certsDir and valid for hosts, which may be host names or IP addresses.
+// It is generated when it doesn't exist, has expired, or doesn't cover every
+// host, as when the machine's addresses change. Clients trust it by passing
+// the certificate file to prox --ca.
+func EnsureAPICert(certsDir string, hosts []string) (*CertPaths, error) {
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no hosts for the API certificate")
+	}
+	dir := expandPath(certsDir)
+	paths := &CertPaths{
+		CertFile: filepath.Join(dir, apiCertFile),
+		KeyFile:  filepath.Join(dir, apiKeyFile),
+	}
+
+	now := time.Now()
+	if _, err := os.Stat(paths.KeyFile); err == nil && certCovers(paths.CertFile, hosts, now) {
+		return paths, nil
+	}
+
+	if err := os.MkdirAll(dir, constants.DirPermissionPrivate); err != nil {
+		return nil, fmt.Errorf("creating certs directory: %w", err)
+	}
+	if err := generateSelfSignedFor(paths, hosts, now); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// certCovers reports whether the certificate at path is unexpired and valid
+// for every host
+func certCovers(path string, hosts []string, now time.Time) bool {
+	cert, err := readCert(path)
+	if err != nil || now.After(cert.NotAfter) {
+		return false
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -1,5 +1,7 @@
 // Package certs provides certificate management for the HTTPS reverse proxy.
-// It integrates with mkcert to generate locally-trusted development certificates.
+// It integrates with mkcert to generate locally-trusted development
+// certificates, or generates self-signed certificates where mkcert isn't
+// available.
 package certs
 
 import (
@@ -8,14 +10,18 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/charliek/prox/internal/config"
 	"github.com/charliek/prox/internal/constants"
 )
 
-// Manager handles certificate generation and management using mkcert.
+// Manager handles certificate generation and management using mkcert or,
+// with the self-signed provider, on its own.
 type Manager struct {
 	certsDir string
 	domain   string
+	provider string
 }
 
 // CertPaths contains the paths to the certificate and key files.
@@ -24,14 +30,30 @@ type CertPaths struct {
 	KeyFile  string
 }
 
-// NewManager creates a new certificate manager.
-func NewManager(certsDir, domain string) *Manager {
+// NewManager creates a new certificate manager. provider is one of the
+// config.CertProvider values; empty means mkcert.
+func NewManager(certsDir, domain, provider string) *Manager {
+	if provider == "" {
+		provider = config.CertProviderMkcert
+	}
 	return &Manager{
 		certsDir: expandPath(certsDir),
 		domain:   domain,
+		provider: provider,
 	}
 }
 
+// Provider returns the certificate provider.
+func (m *Manager) Provider() string {
+	return m.provider
+}
+
+// SelfSigned reports whether certificates are self-signed, and so not
+// trusted by browsers.
+func (m *Manager) SelfSigned() bool {
+	return m.provider == config.CertProviderSelfSigned
+}
+
 // CheckMkcert verifies that mkcert is installed and accessible.
 func (m *Manager) CheckMkcert() error {
 	_, err := exec.LookPath("mkcert")
@@ -86,13 +108,13 @@ func (m *Manager) InstallCA() error {
 }
 
 // EnsureCerts ensures that valid certificates exist for the configured domain.
-// If certificates don't exist, they will be generated.
+// If certificates don't exist or have expired, they will be generated.
 // Returns the paths to the certificate and key files.
 func (m *Manager) EnsureCerts() (*CertPaths, error) {
 	paths := m.getCertPaths()
 
 	// Check if certificates already exist
-	if m.certsExist(paths) {
+	if m.certsExist(paths) && !certExpired(paths.CertFile, time.Now()) {
 		return paths, nil
 	}
 
@@ -109,6 +131,12 @@ func (m *Manager) GetCertPaths() *CertPaths {
 	return m.getCertPaths()
 }
 
+// Expiry returns when the domain's certificate expires. It returns an error
+// if the certificate hasn't been generated yet or can't be read.
+func (m *Manager) Expiry() (time.Time, error) {
+	return certNotAfter(m.getCertPaths().CertFile)
+}
+
 // RegenerateCerts forces regeneration of certificates even if they exist.
 func (m *Manager) RegenerateCerts() (*CertPaths, error) {
 	paths := m.getCertPaths()
@@ -128,6 +156,11 @@ func (m *Manager) RegenerateCerts() (*CertPaths, error) {
 func (m *Manager) getCertPaths() *CertPaths {
 	// Sanitize domain for filename (replace dots with underscores)
 	safeDomain := strings.ReplaceAll(m.domain, ".", "_")
+	// Self-signed certificates are kept apart, so switching providers
+	// doesn't keep using the other provider's certificate
+	if m.SelfSigned() {
+		safeDomain += "-self-signed"
+	}
 	return &CertPaths{
 		CertFile: filepath.Join(m.certsDir, fmt.Sprintf("%s.pem", safeDomain)),
 		KeyFile:  filepath.Join(m.certsDir, fmt.Sprintf("%s-key.pem", safeDomain)),
@@ -145,8 +178,10 @@ func (m *Manager) certsExist(paths *CertPaths) bool {
 }
 
 func (m *Manager) generateCerts(paths *CertPaths) error {
-	if err := m.CheckMkcert(); err != nil {
-		return err
+	if !m.SelfSigned() {
+		if err := m.CheckMkcert(); err != nil {
+			return err
+		}
 	}
 
 	// Ensure the certs directory exists
@@ -154,6 +189,10 @@ func (m *Manager) generateCerts(paths *CertPaths) error {
 		return fmt.Errorf("creating certs directory: %w", err)
 	}
 
+	if m.SelfSigned() {
+		return generateSelfSigned(paths, m.domain, time.Now())
+	}
+
 	// Generate wildcard certificate for the domain
 	// mkcert -cert-file <cert> -key-file <key> "*.domain" "domain"
 	wildcardDomain := fmt.Sprintf("*.%s", m.domain)
@@ -1,16 +1,20 @@
 package certs
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/charliek/prox/internal/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestNewManager(t *testing.T) {
-	m := NewManager("~/.prox/certs", "local.myapp.dev")
+	m := NewManager("~/.prox/certs", "local.myapp.dev", "")
 	assert.NotNil(t, m)
 	assert.Contains(t, m.certsDir, ".prox/certs")
 	assert.Equal(t, "local.myapp.dev", m.domain)
@@ -35,7 +39,7 @@ func TestExpandPath(t *testing.T) {
 }
 
 func TestGetCertPaths(t *testing.T) {
-	m := NewManager("/tmp/certs", "local.myapp.dev")
+	m := NewManager("/tmp/certs", "local.myapp.dev", "")
 	paths := m.GetCertPaths()
 
 	assert.Equal(t, "/tmp/certs/local_myapp_dev.pem", paths.CertFile)
@@ -48,7 +52,7 @@ func TestCertsExist(t *testing.T) {
 	require.NoError(t, err)
 	defer os.RemoveAll(tmpDir)
 
-	m := NewManager(tmpDir, "test.dev")
+	m := NewManager(tmpDir, "test.dev", "")
 	paths := m.getCertPaths()
 
 	t.Run("returns false when no certs exist", func(t *testing.T) {
@@ -79,7 +83,7 @@ func TestCertsExist(t *testing.T) {
 }
 
 func TestCheckMkcert(t *testing.T) {
-	m := NewManager("/tmp/certs", "test.dev")
+	m := NewManager("/tmp/certs", "test.dev", "")
 
 	// This test depends on whether mkcert is installed
 	// We just verify the function doesn't panic
@@ -88,3 +92,82 @@ func TestCheckMkcert(t *testing.T) {
 		assert.Contains(t, err.Error(), "mkcert")
 	}
 }
+
+func TestEnsureCerts_SelfSigned(t *testing.T) {
+	m := NewManager(t.TempDir(), "local.myapp.dev", config.CertProviderSelfSigned)
+	assert.True(t, m.SelfSigned())
+
+	paths, err := m.EnsureCerts()
+	require.NoError(t, err)
+	assert.Equal(t, "local_myapp_dev-self-signed.pem", filepath.Base(paths.CertFile))
+
+	pair, err := tls.LoadX509KeyPair(paths.CertFile, paths.KeyFile)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*.local.myapp.dev", "local.myapp.dev"}, cert.DNSNames)
+	assert.NoError(t, cert.VerifyHostname("app.local.myapp.dev"))
+
+	info, err := os.Stat(paths.KeyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestEnsureCerts_RegeneratesExpired(t *testing.T) {
+	m := NewManager(t.TempDir(), "test.dev", config.CertProviderSelfSigned)
+	paths := m.GetCertPaths()
+	require.NoError(t, os.MkdirAll(filepath.Dir(paths.CertFile), 0700))
+
+	// A certificate issued two years ago has expired
+	require.NoError(t, generateSelfSigned(paths, "test.dev", time.Now().Add(-2*selfSignedValidity)))
+	assert.True(t, certExpired(paths.CertFile, time.Now()))
+
+	_, err := m.EnsureCerts()
+	require.NoError(t, err)
+	assert.False(t, certExpired(paths.CertFile, time.Now()))
+}
+
+func TestExpiry(t *testing.T) {
+	m := NewManager(t.TempDir(), "test.dev", config.CertProviderSelfSigned)
+	_, err := m.Expiry()
+	assert.Error(t, err, "no certificate yet")
+
+	_, err = m.EnsureCerts()
+	require.NoError(t, err)
+	notAfter, err := m.Expiry()
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(selfSignedValidity), notAfter, time.Hour)
+}
+
+func TestEnsureAPICert(t *testing.T) {
+	dir := t.TempDir()
+	paths, err := EnsureAPICert(dir, []string{"localhost", "127.0.0.1"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "api.pem"), paths.CertFile)
+
+	// The certificate is its own CA, as clients given it with --ca trust it
+	cert, err := readCert(paths.CertFile)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "127.0.0.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"localhost"}, cert.DNSNames)
+
+	// Kept while it covers the hosts
+	before, err := os.ReadFile(paths.CertFile)
+	require.NoError(t, err)
+	_, err = EnsureAPICert(dir, []string{"127.0.0.1"})
+	require.NoError(t, err)
+	after, err := os.ReadFile(paths.CertFile)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	// Regenerated for a new host
+	_, err = EnsureAPICert(dir, []string{"localhost", "devbox.internal", "10.0.0.5"})
+	require.NoError(t, err)
+	cert, err = readCert(paths.CertFile)
+	require.NoError(t, err)
+	assert.NoError(t, cert.VerifyHostname("devbox.internal"))
+	assert.NoError(t, cert.VerifyHostname("10.0.0.5"))
+}
@@ -0,0 +1,115 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"time"
+
+	"github.com/charliek/prox/internal/constants"
+)
+
+// selfSignedValidity is how long a self-signed certificate is valid. It is
+// regenerated once it expires.
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// generateSelfSigned writes a self-signed wildcard certificate for domain
+// and its key to paths
+func generateSelfSigned(paths *CertPaths, domain string, now time.Time) error {
+	return generateSelfSignedFor(paths, []string{"*." + domain, domain}, now)
+}
+
+// generateSelfSignedFor writes a self-signed certificate for names, which
+// may be host names or IP addresses, and its key to paths. The first name
+// is the certificate's common name.
+func generateSelfSignedFor(paths *CertPaths, names []string, now time.Time) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("generating key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return fmt.Errorf("generating serial number: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"prox development certificate"}, CommonName: names[0]},
+		NotBefore:             now.Add(-time.Hour), // Allow for clock skew
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("creating certificate for %s: %w", names[0], err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("encoding key: %w", err)
+	}
+
+	if err := writePEM(paths.KeyFile, "EC PRIVATE KEY", keyDER, constants.FilePermissionPrivate); err != nil {
+		return err
+	}
+	return writePEM(paths.CertFile, "CERTIFICATE", der, constants.FilePermissionDefault)
+}
+
+// writePEM writes a single PEM block to path
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// certExpired reports whether the certificate at path has expired. A
+// certificate that can't be read is left for loading it to report.
+func certExpired(path string, now time.Time) bool {
+	notAfter, err := certNotAfter(path)
+	return err == nil && now.After(notAfter)
+}
+
+// certNotAfter returns when the PEM certificate at path expires
+func certNotAfter(path string) (time.Time, error) {
+	cert, err := readCert(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+// readCert reads the PEM certificate at path
+func readCert(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM certificate", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cert, nil
+}
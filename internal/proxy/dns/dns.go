@@ -0,0 +1,180 @@
+// Package dns provides a small DNS responder that resolves the proxy domain
+// and every name under it to the loopback address, so subdomains work
+// without /etc/hosts entries. It answers only what a stub resolver asks for
+// a local development domain: A and AAAA queries over UDP.
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// DNS record types and response codes used by the responder
+const (
+	typeA    = 1
+	typeAAAA = 28
+	classIN  = 1
+
+	rcodeSuccess = 0
+	rcodeFormat  = 1
+	rcodeNotImpl = 4
+	rcodeRefused = 5
+)
+
+const (
+	headerLen     = 12
+	maxMessageLen = 512 // Largest UDP message without EDNS
+)
+
+// answerTTL is how long resolvers may cache an answer, in seconds. Answers
+// never change, but a short TTL lets a stopped responder fall out of caches.
+const answerTTL = 60
+
+// Server answers DNS queries for a domain.
+type Server struct {
+	domain string
+	conn   net.PacketConn
+	logger *slog.Logger
+}
+
+// Listen starts listening for DNS queries for domain on the UDP address addr.
+// Call Serve to answer them.
+func Listen(addr, domain string, logger *slog.Logger) (*Server, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		domain: strings.ToLower(strings.TrimSuffix(domain, ".")),
+		conn:   conn,
+		logger: logger,
+	}, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// Serve answers queries until the server is closed.
+func (s *Server) Serve() error {
+	buf := make([]byte, maxMessageLen)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		resp := s.respond(buf[:n])
+		if resp == nil {
+			continue
+		}
+		if _, err := s.conn.WriteTo(resp, addr); err != nil {
+			s.logger.Debug("dns: failed to write response", "addr", addr, "error", err)
+		}
+	}
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	return s.conn.Close()
+}
+
+// respond builds the response to a query, or returns nil for messages that
+// can't be answered at all (too short to hold a header, or not a query).
+func (s *Server) respond(query []byte) []byte {
+	if len(query) < headerLen {
+		return nil
+	}
+	flags := binary.BigEndian.Uint16(query[2:4])
+	if flags&0x8000 != 0 {
+		return nil // A response, not a query
+	}
+	opcode := (flags >> 11) & 0xF
+	if opcode != 0 {
+		return header(query, rcodeNotImpl, 0, 0)
+	}
+	if binary.BigEndian.Uint16(query[4:6]) != 1 {
+		return header(query, rcodeFormat, 0, 0)
+	}
+
+	name, end, ok := parseName(query, headerLen)
+	if !ok || end+4 > len(query) {
+		return header(query, rcodeFormat, 0, 0)
+	}
+	qtype := binary.BigEndian.Uint16(query[end : end+2])
+	qclass := binary.BigEndian.Uint16(query[end+2 : end+4])
+	question := query[headerLen : end+4]
+
+	if qclass != classIN || !s.inZone(name) {
+		return append(header(query, rcodeRefused, 1, 0), question...)
+	}
+
+	var rdata []byte
+	switch qtype {
+	case typeA:
+		rdata = net.IPv4(127, 0, 0, 1).To4()
+	case typeAAAA:
+		rdata = net.IPv6loopback
+	}
+	if rdata == nil {
+		// The name exists but has no records of this type
+		return append(header(query, rcodeSuccess, 1, 0), question...)
+	}
+
+	resp := append(header(query, rcodeSuccess, 1, 1), question...)
+	resp = append(resp, 0xC0, headerLen) // Name: pointer to the question's
+	resp = binary.BigEndian.AppendUint16(resp, qtype)
+	resp = binary.BigEndian.AppendUint16(resp, classIN)
+	resp = binary.BigEndian.AppendUint32(resp, answerTTL)
+	resp = binary.BigEndian.AppendUint16(resp, uint16(len(rdata)))
+	return append(resp, rdata...)
+}
+
+// inZone reports whether name is the server's domain or under it
+func (s *Server) inZone(name string) bool {
+	return name == s.domain || strings.HasSuffix(name, "."+s.domain)
+}
+
+// header builds a response header for query with the given response code
+// and counts. The response is authoritative and echoes the query's ID and
+// recursion desired bit.
+func header(query []byte, rcode, questions, answers uint16) []byte {
+	h := make([]byte, headerLen)
+	copy(h[0:2], query[0:2])
+	flags := uint16(0x8000 | 0x0400) // QR, AA
+	flags |= binary.BigEndian.Uint16(query[2:4]) & 0x0100
+	flags |= rcode
+	binary.BigEndian.PutUint16(h[2:4], flags)
+	binary.BigEndian.PutUint16(h[4:6], questions)
+	binary.BigEndian.PutUint16(h[6:8], answers)
+	return h
+}
+
+// parseName reads the uncompressed name starting at off, returning it in
+// lower case without the trailing dot and the offset just past it. Queries
+// hold a single question, so names in them are never compressed.
+func parseName(msg []byte, off int) (string, int, bool) {
+	var labels []string
+	for {
+		if off >= len(msg) {
+			return "", 0, false
+		}
+		n := int(msg[off])
+		off++
+		if n == 0 {
+			break
+		}
+		if n > 63 || off+n > len(msg) {
+			return "", 0, false
+		}
+		labels = append(labels, strings.ToLower(string(msg[off:off+n])))
+		off += n
+	}
+	return strings.Join(labels, "."), off, true
+}
@@ -0,0 +1,89 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startServer(t *testing.T, domain string) *Server {
+	t.Helper()
+	srv, err := Listen("127.0.0.1:0", domain, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	go srv.Serve()
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func TestServer_Resolves(t *testing.T) {
+	srv := startServer(t, "Local.MyApp.dev.")
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", srv.Addr().String())
+		},
+	}
+	ctx := context.Background()
+
+	for _, name := range []string{"local.myapp.dev", "app.local.myapp.dev", "API.v2.local.myapp.dev"} {
+		addrs, err := resolver.LookupHost(ctx, name)
+		require.NoError(t, err, name)
+		assert.ElementsMatch(t, []string{"127.0.0.1", "::1"}, addrs, name)
+	}
+
+	_, err := resolver.LookupHost(ctx, "example.com")
+	assert.Error(t, err, "names outside the domain are refused")
+	_, err = resolver.LookupHost(ctx, "notlocal.myapp.dev")
+	assert.Error(t, err)
+}
+
+// query builds a DNS query for one question
+func query(name string, qtype uint16) []byte {
+	msg := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	start := 0
+	for i := 0; i <= len(name); i++ {
+		if i == len(name) || name[i] == '.' {
+			msg = append(msg, byte(i-start))
+			msg = append(msg, name[start:i]...)
+			start = i + 1
+		}
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	return binary.BigEndian.AppendUint16(msg, classIN)
+}
+
+func TestServer_Respond(t *testing.T) {
+	srv := &Server{domain: "local.dev"}
+	rcode := func(resp []byte) uint16 { return binary.BigEndian.Uint16(resp[2:4]) & 0xF }
+	answers := func(resp []byte) uint16 { return binary.BigEndian.Uint16(resp[6:8]) }
+
+	resp := srv.respond(query("app.local.dev", typeA))
+	require.NotNil(t, resp)
+	assert.Equal(t, []byte{0x12, 0x34}, resp[:2], "ID is echoed")
+	assert.Equal(t, uint16(0x8500), binary.BigEndian.Uint16(resp[2:4]), "QR, AA, and RD are set")
+	assert.Equal(t, uint16(1), answers(resp))
+	assert.Equal(t, []byte{127, 0, 0, 1}, resp[len(resp)-4:])
+
+	// Names in the domain have no other records
+	resp = srv.respond(query("app.local.dev", 16)) // TXT
+	assert.Equal(t, uint16(rcodeSuccess), rcode(resp))
+	assert.Equal(t, uint16(0), answers(resp))
+
+	assert.Equal(t, uint16(rcodeRefused), rcode(srv.respond(query("app.other.dev", typeA))))
+
+	// Malformed messages
+	assert.Nil(t, srv.respond([]byte{1, 2, 3}))
+	truncated := query("app.local.dev", typeA)
+	assert.Equal(t, uint16(rcodeFormat), rcode(srv.respond(truncated[:len(truncated)-3])))
+	notQuery := query("app.local.dev", typeA)
+	notQuery[2] |= 0x10 // Opcode 2 (status)
+	assert.Equal(t, uint16(rcodeNotImpl), rcode(srv.respond(notQuery)))
+}
@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/domain"
+)
+
+// ServicesForProcess returns the names of the services served by the given
+// process, sorted by name.
+func (s *Service) ServicesForProcess(process string) []string {
+	s.routeMu.Lock()
+	defer s.routeMu.Unlock()
+
+	var names []string
+	for name, svc := range s.services {
+		if svc.Process == process {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RecordProcessEvent records a lifecycle event of a process in the stats of
+// the services it serves: those linked to it, and unlinked ones pointing at
+// its port.
+func (s *Service) RecordProcessEvent(process string, port int, eventType string, at time.Time) {
+	s.routeMu.Lock()
+	var subdomains []string
+	for name, svc := range s.services {
+		if _, wildcard := config.WildcardPrefix(name); wildcard {
+			continue
+		}
+		if svc.Process == process || (svc.Process == "" && port > 0 && svc.Port == port) {
+			subdomains = append(subdomains, name)
+		}
+	}
+	s.routeMu.Unlock()
+
+	if len(subdomains) > 0 {
+		s.statsTracker.RecordProcessEvent(subdomains, ProcessEvent{Process: process, Type: eventType, Time: at})
+	}
+}
+
+// DrainProcess stops routing new requests to the services served by the
+// process and waits for in-flight requests to finish. New requests receive
+// 503 Service Unavailable until ResumeProcess is called.
+// Returns domain.ErrNoProxyServices if the process serves no services, or the
+// context error if in-flight requests did not finish in time.
+func (s *Service) DrainProcess(ctx context.Context, process string) error {
+	services := s.ServicesForProcess(process)
+	if len(services) == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrNoProxyServices, process)
+	}
+
+	s.routeMu.Lock()
+	for _, name := range services {
+		s.draining[name] = true
+	}
+	s.routeMu.Unlock()
+
+	s.logger.Info("draining proxy services", "process", process, "services", services)
+
+	ticker := time.NewTicker(constants.DrainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if s.inFlight(services) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for in-flight requests: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// ResumeProcess resumes routing requests to the services served by the process.
+func (s *Service) ResumeProcess(process string) {
+	services := s.ServicesForProcess(process)
+
+	s.routeMu.Lock()
+	defer s.routeMu.Unlock()
+	for _, name := range services {
+		delete(s.draining, name)
+	}
+}
+
+// IsDraining returns true if the service is currently draining.
+func (s *Service) IsDraining(service string) bool {
+	s.routeMu.Lock()
+	defer s.routeMu.Unlock()
+	return s.draining[service]
+}
+
+// beginRequest marks a request as in flight for the service. It returns false
+// if the service is draining and the request should be rejected.
+func (s *Service) beginRequest(service string) bool {
+	s.routeMu.Lock()
+	defer s.routeMu.Unlock()
+	if s.draining[service] {
+		return false
+	}
+	s.inflight[service]++
+	return true
+}
+
+// endRequest marks an in-flight request for the service as finished.
+func (s *Service) endRequest(service string) {
+	s.routeMu.Lock()
+	defer s.routeMu.Unlock()
+	s.inflight[service]--
+	if s.inflight[service] <= 0 {
+		delete(s.inflight, service)
+	}
+}
+
+// inFlight returns the number of in-flight requests across the services.
+func (s *Service) inFlight(services []string) int {
+	s.routeMu.Lock()
+	defer s.routeMu.Unlock()
+	total := 0
+	for _, name := range services {
+		total += s.inflight[name]
+	}
+	return total
+}
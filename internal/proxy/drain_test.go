@@ -0,0 +1,193 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDrainTestService(t *testing.T, backendPort int) *Service {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	cfg := &config.ProxyConfig{
+		Enabled:  true,
+		HTTPPort: 6788,
+		Domain:   "local.myapp.dev",
+	}
+	services := map[string]config.ServiceConfig{
+		"app":   {Port: backendPort, Host: "localhost", Process: "web"},
+		"admin": {Port: backendPort, Host: "localhost", Process: "web"},
+		"other": {Port: backendPort, Host: "localhost"},
+	}
+
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+	return svc
+}
+
+func TestServicesForProcess(t *testing.T) {
+	svc := newDrainTestService(t, 3000)
+
+	assert.Equal(t, []string{"admin", "app"}, svc.ServicesForProcess("web"))
+	assert.Empty(t, svc.ServicesForProcess("worker"))
+}
+
+func TestService_RecordProcessEvent(t *testing.T) {
+	svc := newDrainTestService(t, 3000)
+	now := time.Now()
+
+	svc.RecordProcessEvent("web", 3000, ProcessEventCrashed, now)
+	events := svc.StatsTracker().eventsSince(now.Add(-time.Minute))
+	assert.Len(t, events["app"], 1)
+	assert.Len(t, events["admin"], 1)
+	// Services without a process are matched by port
+	assert.Len(t, events["other"], 1)
+
+	svc.RecordProcessEvent("worker", 0, ProcessEventCrashed, now)
+	events = svc.StatsTracker().eventsSince(now.Add(-time.Minute))
+	assert.Len(t, events["other"], 1)
+}
+
+func TestDrainProcess_NoServices(t *testing.T) {
+	svc := newDrainTestService(t, 3000)
+
+	err := svc.DrainProcess(context.Background(), "worker")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, domain.ErrNoProxyServices)
+}
+
+func TestDrainProcess_RejectsNewRequests(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	svc := newDrainTestService(t, backend.Listener.Addr().(*net.TCPAddr).Port)
+	router := svc.createRouter()
+
+	require.NoError(t, svc.DrainProcess(context.Background(), "web"))
+	assert.True(t, svc.IsDraining("app"))
+	assert.False(t, svc.IsDraining("other"))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "app.local.myapp.dev"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// Services of other processes are unaffected
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Host = "other.local.myapp.dev"
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Resuming restores routing
+	svc.ResumeProcess("web")
+	assert.False(t, svc.IsDraining("app"))
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Host = "app.local.myapp.dev"
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestDrainProcess_WaitsForInFlight(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	svc := newDrainTestService(t, backend.Listener.Addr().(*net.TCPAddr).Port)
+	router := svc.createRouter()
+
+	done := make(chan int)
+	go func() {
+		req := httptest.NewRequest("GET", "/slow", nil)
+		req.Host = "app.local.myapp.dev"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		done <- w.Code
+	}()
+	<-started
+
+	drained := make(chan error)
+	go func() {
+		drained <- svc.DrainProcess(context.Background(), "web")
+	}()
+
+	select {
+	case <-drained:
+		t.Fatal("drain returned while a request was in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-done)
+	require.NoError(t, <-drained)
+}
+
+func TestDrainProcess_Timeout(t *testing.T) {
+	svc := newDrainTestService(t, 3000)
+	require.True(t, svc.beginRequest("app"))
+	defer svc.endRequest("app")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := svc.DrainProcess(ctx, "web")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestService_ActiveRequests(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	svc := newDrainTestService(t, backend.Listener.Addr().(*net.TCPAddr).Port)
+	router := svc.createRouter()
+	assert.Empty(t, svc.ActiveRequests())
+
+	done := make(chan struct{})
+	go func() {
+		req := httptest.NewRequest("POST", "/slow?x=1", nil)
+		req.Host = "app.local.myapp.dev"
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		close(done)
+	}()
+	<-started
+
+	active := svc.ActiveRequests()
+	require.Len(t, active, 1)
+	assert.Equal(t, "POST", active[0].Method)
+	assert.Equal(t, "/slow?x=1", active[0].URL)
+	assert.Equal(t, "app", active[0].Subdomain)
+	assert.NotEmpty(t, active[0].ID)
+
+	close(release)
+	<-done
+	assert.Empty(t, svc.ActiveRequests())
+}
@@ -0,0 +1,248 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HARVersion is the version of the HTTP Archive format ExportHAR writes
+const HARVersion = "1.2"
+
+// HAR is an HTTP Archive, the format browser dev tools import and export
+// request history in. Only the fields prox can fill are included.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root of an HTTP Archive
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator names the application that wrote the archive
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is one request and its response
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"` // Milliseconds
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+// HARRequest is the request of an entry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARResponse is the response of an entry
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARNameValue is a header, cookie, or query parameter
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is a captured request body
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARContent is a captured response body
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"` // "base64" for binary bodies
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARTimings splits an entry's time into phases. The proxy only measures
+// the whole request, so it is all reported as waiting.
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harUnknown is the value HAR uses for sizes that weren't measured
+const harUnknown = -1
+
+// truncatedComment marks bodies that were cut off at the capture size limit
+const truncatedComment = "truncated by prox capture"
+
+// ExportHAR converts the requests matching filter into an HTTP Archive,
+// oldest first. Captured headers and bodies are included when available;
+// bodies stored on disk are read in, and cm may be nil when capture is
+// disabled. origin returns the scheme and host requests to a subdomain were
+// made to, e.g. "https://api.local.myapp.dev:6789".
+func ExportHAR(rm *RequestManager, cm *CaptureManager, filter RequestFilter, origin func(subdomain string) string) *HAR {
+	recent := rm.Recent(filter)
+
+	har := &HAR{Log: HARLog{
+		Version: HARVersion,
+		Creator: HARCreator{Name: "prox"},
+		Entries: make([]HAREntry, 0, len(recent)),
+	}}
+	for i := len(recent) - 1; i >= 0; i-- {
+		har.Log.Entries = append(har.Log.Entries, harEntry(recent[i], cm, origin))
+	}
+	return har
+}
+
+// harEntry converts one request record
+func harEntry(record RequestRecord, cm *CaptureManager, origin func(subdomain string) string) HAREntry {
+	ms := float64(record.Duration) / float64(time.Millisecond)
+	entry := HAREntry{
+		StartedDateTime: record.Timestamp.Format(time.RFC3339Nano),
+		Time:            ms,
+		Request: HARRequest{
+			Method:      record.Method,
+			URL:         origin(record.Subdomain) + record.URL,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []HARNameValue{},
+			Headers:     []HARNameValue{},
+			QueryString: []HARNameValue{},
+			HeadersSize: harUnknown,
+			BodySize:    harUnknown,
+		},
+		Response: HARResponse{
+			Status:      record.StatusCode,
+			StatusText:  http.StatusText(record.StatusCode),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []HARNameValue{},
+			Headers:     []HARNameValue{},
+			Content:     HARContent{Size: harUnknown},
+			HeadersSize: harUnknown,
+			BodySize:    harUnknown,
+		},
+		Timings: HARTimings{Wait: ms},
+	}
+	if record.Imported {
+		entry.Comment = "imported from a saved session"
+	}
+	if u, err := url.Parse(record.URL); err == nil {
+		entry.Request.QueryString = harNameValues(u.Query())
+	}
+
+	details := record.Details
+	if details == nil {
+		return entry
+	}
+	entry.Request.Headers = harNameValues(details.RequestHeaders)
+	entry.Response.Headers = harNameValues(details.ResponseHeaders)
+	entry.Response.RedirectURL = http.Header(details.ResponseHeaders).Get("Location")
+
+	if body := details.RequestBody; body != nil {
+		entry.Request.BodySize = body.Size
+		entry.Request.PostData = &HARPostData{MimeType: body.ContentType}
+		// HAR has no encoding for request bodies, so binary ones are left out
+		if body.IsBinary {
+			entry.Request.PostData.Comment = fmt.Sprintf("binary body of %d bytes omitted", body.Size)
+		} else {
+			entry.Request.PostData.Text = string(harBodyData(cm, body))
+		}
+		if body.Truncated {
+			entry.Request.PostData.Comment = truncatedComment
+		}
+	} else {
+		entry.Request.BodySize = 0
+	}
+
+	if body := details.ResponseBody; body != nil {
+		entry.Response.BodySize = body.Size
+		content := HARContent{Size: body.Size, MimeType: body.ContentType}
+		if data := harBodyData(cm, body); data != nil {
+			if body.IsBinary {
+				content.Text = base64.StdEncoding.EncodeToString(data)
+				content.Encoding = "base64"
+			} else {
+				content.Text = string(data)
+			}
+		}
+		if body.Truncated {
+			content.Comment = truncatedComment
+		}
+		entry.Response.Content = content
+	}
+	return entry
+}
+
+// harBodyData returns a captured body's data, which may be on disk
+func harBodyData(cm *CaptureManager, body *CapturedBody) []byte {
+	if cm == nil {
+		return body.Data
+	}
+	// A body whose file is gone is exported without data
+	data, _ := cm.LoadBody(body)
+	return data
+}
+
+// harNameValues flattens headers or query parameters into name/value pairs,
+// sorted by name
+func harNameValues(values map[string][]string) []HARNameValue {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := []HARNameValue{}
+	for _, name := range names {
+		for _, value := range values[name] {
+			pairs = append(pairs, HARNameValue{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
+
+// Origin returns the scheme and host of the proxy for a subdomain, e.g.
+// "https://api.local.myapp.dev:6789", preferring HTTPS when it's served
+func (s *Service) Origin(subdomain string) string {
+	scheme, port, defaultPort := "http", s.cfg.HTTPPort, 80
+	if s.cfg.HTTPSPort > 0 {
+		scheme, port, defaultPort = "https", s.cfg.HTTPSPort, 443
+	}
+	host := s.cfg.Domain
+	if subdomain != "" {
+		host = subdomain + "." + host
+	}
+	if port != defaultPort {
+		host = fmt.Sprintf("%s:%d", host, port)
+	}
+	return scheme + "://" + strings.ToLower(host)
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportHAR(t *testing.T) {
+	dir := t.TempDir()
+	bodyPath := filepath.Join(dir, "bbb_res.bin")
+	require.NoError(t, os.WriteFile(bodyPath, []byte{0xff, 0x00}, 0600))
+
+	cm := &CaptureManager{enabled: true, captureDir: dir}
+	rm := NewRequestManager(10)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	rm.Record(RequestRecord{ID: "aaa", Timestamp: start, Method: "GET", URL: "/users?page=2&sort=name", Subdomain: "api", StatusCode: 200, Duration: 1500 * time.Microsecond})
+	rm.Record(RequestRecord{
+		ID:         "bbb",
+		Timestamp:  start.Add(time.Second),
+		Method:     "POST",
+		URL:        "/upload",
+		Subdomain:  "app",
+		StatusCode: 302,
+		Duration:   20 * time.Millisecond,
+		Details: &RequestDetails{
+			RequestHeaders:  map[string][]string{"Content-Type": {"application/json"}, "Accept": {"a", "b"}},
+			ResponseHeaders: map[string][]string{"Location": {"/done"}},
+			RequestBody:     &CapturedBody{Size: 4096, Data: []byte(`{"name":`), ContentType: "application/json", Truncated: true},
+			ResponseBody:    &CapturedBody{Size: 2, FilePath: bodyPath, ContentType: "image/png", IsBinary: true},
+		},
+	})
+
+	har := ExportHAR(rm, cm, RequestFilter{}, func(subdomain string) string { return "https://" + subdomain + ".local.dev" })
+	assert.Equal(t, HARVersion, har.Log.Version)
+	assert.Equal(t, "prox", har.Log.Creator.Name)
+	require.Len(t, har.Log.Entries, 2)
+
+	// Oldest first, with an absolute URL and parsed query string
+	first := har.Log.Entries[0]
+	assert.Equal(t, "2025-01-01T12:00:00Z", first.StartedDateTime)
+	assert.Equal(t, 1.5, first.Time)
+	assert.Equal(t, "https://api.local.dev/users?page=2&sort=name", first.Request.URL)
+	assert.Equal(t, []HARNameValue{{"page", "2"}, {"sort", "name"}}, first.Request.QueryString)
+	assert.Empty(t, first.Request.Headers)
+	assert.Nil(t, first.Request.PostData)
+	assert.Equal(t, "OK", first.Response.StatusText)
+
+	// Captured headers and bodies, read from disk when stored there
+	second := har.Log.Entries[1]
+	assert.Equal(t, []HARNameValue{{"Accept", "a"}, {"Accept", "b"}, {"Content-Type", "application/json"}}, second.Request.Headers)
+	require.NotNil(t, second.Request.PostData)
+	assert.Equal(t, `{"name":`, second.Request.PostData.Text)
+	assert.Equal(t, truncatedComment, second.Request.PostData.Comment)
+	assert.Equal(t, int64(4096), second.Request.BodySize)
+	assert.Equal(t, "/done", second.Response.RedirectURL)
+	assert.Equal(t, "base64", second.Response.Content.Encoding)
+	assert.Equal(t, "/wA=", second.Response.Content.Text)
+	assert.Equal(t, "image/png", second.Response.Content.MimeType)
+
+	// Filters apply
+	har = ExportHAR(rm, cm, RequestFilter{Subdomain: "app"}, func(subdomain string) string { return "" })
+	require.Len(t, har.Log.Entries, 1)
+	assert.Equal(t, "/upload", har.Log.Entries[0].Request.URL)
+}
+
+func TestService_Origin(t *testing.T) {
+	tests := []struct {
+		cfg  config.ProxyConfig
+		want string
+	}{
+		{config.ProxyConfig{HTTPPort: 6788, Domain: "local.dev"}, "http://api.local.dev:6788"},
+		{config.ProxyConfig{HTTPPort: 80, Domain: "local.dev"}, "http://api.local.dev"},
+		{config.ProxyConfig{HTTPPort: 6788, HTTPSPort: 6789, Domain: "local.dev"}, "https://api.local.dev:6789"},
+		{config.ProxyConfig{HTTPSPort: 443, Domain: "local.dev"}, "https://api.local.dev"},
+	}
+	for _, tt := range tests {
+		cfg := tt.cfg
+		s := &Service{cfg: &cfg}
+		assert.Equal(t, tt.want, s.Origin("api"))
+	}
+}
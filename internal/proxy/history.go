@@ -0,0 +1,217 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/charliek/prox/internal/fsperm"
+)
+
+// fileRequestStore is a RequestStore that keeps the last max records in
+// memory and appends each added or updated record to a JSON lines file, so
+// the history survives a restart. The file is compacted to the records kept
+// once it holds twice as many lines.
+type fileRequestStore struct {
+	memory RequestStore
+	max    int
+	path   string
+
+	mu    sync.Mutex
+	file  *os.File
+	lines int   // Lines in the file
+	err   error // First error writing the file
+}
+
+// openFileRequestStore opens the history at path, loading the last max
+// records written to it
+func openFileRequestStore(path string, max int) (*fileRequestStore, error) {
+	if max <= 0 {
+		max = 1
+	}
+	records, err := loadRequestHistory(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > max {
+		records = records[len(records)-max:]
+	}
+
+	s := &fileRequestStore{
+		memory: NewMemoryRequestStore(max),
+		max:    max,
+		path:   path,
+	}
+	for _, record := range records {
+		s.memory.Add(record)
+	}
+	if err := fsperm.MkdirAll(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// loadRequestHistory reads the records in a history file, oldest first. A
+// record written more than once is kept in the place it was first written,
+// with its last version. Lines that don't parse, such as one cut short by a
+// crash, are skipped.
+func loadRequestHistory(path string) ([]RequestRecord, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []RequestRecord
+	index := make(map[string]int)
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var record RequestRecord
+			if json.Unmarshal(line, &record) == nil && record.ID != "" {
+				if i, ok := index[record.ID]; ok {
+					records[i] = record
+				} else {
+					index[record.ID] = len(records)
+					records = append(records, record)
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *fileRequestStore) Add(record RequestRecord) (uint64, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seq, evicted := s.memory.Add(record)
+	s.write(record)
+	return seq, evicted
+}
+
+func (s *fileRequestStore) Update(id string, fn func(*RequestRecord)) (RequestRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.memory.Update(id, fn)
+	if ok {
+		s.write(record)
+	}
+	return record, ok
+}
+
+func (s *fileRequestStore) Recent(limit int, match func(RequestRecord) bool) []RequestRecord {
+	return s.memory.Recent(limit, match)
+}
+
+func (s *fileRequestStore) After(seq uint64, limit int, match func(RequestRecord) bool) []RequestRecord {
+	return s.memory.After(seq, limit, match)
+}
+
+func (s *fileRequestStore) Get(id string) (RequestRecord, bool) {
+	return s.memory.Get(id)
+}
+
+func (s *fileRequestStore) Count() int {
+	return s.memory.Count()
+}
+
+// write appends a record to the file, compacting it once it has grown to
+// twice the records kept. s.mu must be held.
+func (s *fileRequestStore) write(record RequestRecord) {
+	if s.file == nil {
+		return
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		s.fail(err)
+		return
+	}
+	if s.lines++; s.lines >= 2*s.max {
+		if err := s.compact(); err != nil {
+			s.fail(err)
+		}
+	}
+}
+
+// compact rewrites the file with just the records kept, oldest first, and
+// reopens it for appending. s.mu must be held, except while opening.
+func (s *fileRequestStore) compact() error {
+	all := func(RequestRecord) bool { return true }
+	records := s.memory.After(0, 0, all)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := fsperm.WriteFile(tmp, buf.Bytes()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, fsperm.File())
+	if err != nil {
+		return err
+	}
+	s.file = f
+	s.lines = len(records)
+	return nil
+}
+
+// fail records the first error writing the file. Store methods have no
+// error result, so the history carries on in memory.
+func (s *fileRequestStore) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Err returns the first error writing the history file, if any
+func (s *fileRequestStore) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close closes the history file
+func (s *fileRequestStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileRequestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".prox", "requests.jsonl")
+
+	store, err := openFileRequestStore(path, 3)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		store.Add(RequestRecord{ID: fmt.Sprintf("req%d", i), Method: "GET", URL: fmt.Sprintf("/%d", i)})
+	}
+	_, ok := store.Update("req4", func(r *RequestRecord) { r.StatusCode = 201 })
+	require.True(t, ok)
+	require.NoError(t, store.Err())
+	require.NoError(t, store.Close())
+
+	// A line cut short by a crash is skipped
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"id":"req5","meth`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	store, err = openFileRequestStore(path, 3)
+	require.NoError(t, err)
+	defer store.Close()
+
+	all := func(RequestRecord) bool { return true }
+	var ids []string
+	for _, record := range store.After(0, 0, all) {
+		ids = append(ids, record.ID)
+	}
+	assert.Equal(t, []string{"req2", "req3", "req4"}, ids)
+	record, ok := store.Get("req4")
+	require.True(t, ok)
+	assert.Equal(t, 201, record.StatusCode)
+
+	// Loading compacts the file to the records kept
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(data), "\n"))
+
+	// New records continue the sequence after the loaded ones
+	seq, _ := store.Add(RequestRecord{ID: "req6"})
+	assert.Equal(t, uint64(4), seq)
+}
+
+func TestFileRequestStore_Compacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	store, err := openFileRequestStore(path, 2)
+	require.NoError(t, err)
+	defer store.Close()
+
+	for i := 0; i < 10; i++ {
+		store.Add(RequestRecord{ID: fmt.Sprintf("req%d", i)})
+	}
+	require.NoError(t, store.Err())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, strings.Count(string(data), "\n"), 4)
+}
+
+func TestService_PersistedHistory(t *testing.T) {
+	workDir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.ProxyConfig{
+		Enabled:  true,
+		HTTPPort: 6789,
+		Domain:   "local.myapp.dev",
+		Capture:  &config.CaptureConfig{Enabled: true},
+		History:  &config.ProxyHistoryConfig{Persist: true, Max: 1},
+	}
+
+	svc, err := NewService(cfg, nil, nil, logger, workDir)
+	require.NoError(t, err)
+	captureDir := filepath.Join(workDir, constants.CaptureDirectory)
+	body := filepath.Join(captureDir, "kept123_res.bin")
+	require.NoError(t, os.WriteFile(body, []byte("large body"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(captureDir, "gone123_res.bin"), []byte("old"), 0600))
+	svc.RequestManager().Record(RequestRecord{
+		ID:        "kept123",
+		Timestamp: time.Now(),
+		Method:    "GET",
+		URL:       "/report",
+		Details:   &RequestDetails{ResponseBody: &CapturedBody{Size: 10, FilePath: body}},
+	})
+	require.NoError(t, svc.Shutdown(context.Background()))
+
+	svc, err = NewService(cfg, nil, nil, logger, workDir)
+	require.NoError(t, err)
+	defer svc.Shutdown(context.Background())
+
+	record, ok := svc.RequestManager().GetByID("kept123")
+	require.True(t, ok)
+	data, err := svc.CaptureManager().LoadBody(record.Details.ResponseBody)
+	require.NoError(t, err)
+	assert.Equal(t, "large body", string(data))
+	assert.NoFileExists(t, filepath.Join(captureDir, "gone123_res.bin"))
+}
@@ -3,8 +3,13 @@
 package hosts
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"os/exec"
 	"runtime"
 	"strings"
 )
@@ -21,6 +26,10 @@ type Manager struct {
 	hostsPath string
 	domain    string
 	services  []string
+
+	// sudoWrite writes the hosts file with elevated privileges when Sync or
+	// Clean can't write it directly (nil disables the fallback)
+	sudoWrite func(path string, content []byte) error
 }
 
 // NewManager creates a new hosts file manager.
@@ -29,6 +38,7 @@ func NewManager(domain string, services []string) *Manager {
 		hostsPath: getHostsPath(),
 		domain:    domain,
 		services:  services,
+		sudoWrite: sudoTee,
 	}
 }
 
@@ -60,6 +70,11 @@ func (m *Manager) Check() (bool, bool, error) {
 	return true, upToDate, nil
 }
 
+// Path returns the hosts file path.
+func (m *Manager) Path() string {
+	return m.hostsPath
+}
+
 // GetEntries returns the hostnames that would be added to /etc/hosts.
 func (m *Manager) GetEntries() []string {
 	entries := make([]string, 0, len(m.services)+1)
@@ -87,12 +102,7 @@ func (m *Manager) Add() error {
 		return fmt.Errorf("reading hosts file: %w", err)
 	}
 
-	// Remove existing block if present
-	newContent := m.removeManagedBlock(string(content))
-
-	// Add new block
-	block := m.generateBlock()
-	newContent = strings.TrimRight(newContent, "\n") + "\n\n" + block + "\n"
+	newContent := m.withBlock(string(content))
 
 	// Write back with original permissions
 	if err := os.WriteFile(m.hostsPath, []byte(newContent), perm); err != nil {
@@ -117,10 +127,7 @@ func (m *Manager) Remove() error {
 		return fmt.Errorf("reading hosts file: %w", err)
 	}
 
-	newContent := m.removeManagedBlock(string(content))
-
-	// Clean up any extra newlines at the end
-	newContent = strings.TrimRight(newContent, "\n") + "\n"
+	newContent := m.withoutBlock(string(content))
 
 	// Write back with original permissions
 	if err := os.WriteFile(m.hostsPath, []byte(newContent), perm); err != nil {
@@ -130,6 +137,84 @@ func (m *Manager) Remove() error {
 	return nil
 }
 
+// Sync adds the managed block, or brings it up to date with the configured
+// services. It returns false when the hosts file was already up to date. When
+// the file can't be written directly, it is written with sudo, which prompts
+// for a password on the terminal.
+func (m *Manager) Sync() (bool, error) {
+	exists, upToDate, err := m.Check()
+	if err != nil {
+		return false, err
+	}
+	if exists && upToDate {
+		return false, nil
+	}
+	return true, m.update(m.withBlock)
+}
+
+// Clean removes the managed block, falling back to sudo like Sync. It
+// returns false when there was no block to remove.
+func (m *Manager) Clean() (bool, error) {
+	content, err := os.ReadFile(m.hostsPath)
+	if err != nil {
+		return false, fmt.Errorf("reading hosts file: %w", err)
+	}
+	if m.extractManagedBlock(string(content)) == "" {
+		return false, nil
+	}
+	return true, m.update(m.withoutBlock)
+}
+
+// update rewrites the hosts file with transform applied to its content,
+// keeping its permissions
+func (m *Manager) update(transform func(content string) string) error {
+	info, err := os.Stat(m.hostsPath)
+	if err != nil {
+		return fmt.Errorf("stat hosts file: %w", err)
+	}
+
+	content, err := os.ReadFile(m.hostsPath)
+	if err != nil {
+		return fmt.Errorf("reading hosts file: %w", err)
+	}
+	newContent := []byte(transform(string(content)))
+
+	err = os.WriteFile(m.hostsPath, newContent, info.Mode().Perm())
+	if errors.Is(err, fs.ErrPermission) && m.sudoWrite != nil {
+		err = m.sudoWrite(m.hostsPath, newContent)
+	}
+	if err != nil {
+		return fmt.Errorf("writing hosts file: %w", err)
+	}
+	return nil
+}
+
+// withBlock returns content with the managed block replaced by, or appended
+// as, an up-to-date one.
+func (m *Manager) withBlock(content string) string {
+	newContent := m.removeManagedBlock(content)
+	return strings.TrimRight(newContent, "\n") + "\n\n" + m.generateBlock() + "\n"
+}
+
+// withoutBlock returns content with the managed block removed.
+func (m *Manager) withoutBlock(content string) string {
+	// Clean up any extra newlines at the end
+	return strings.TrimRight(m.removeManagedBlock(content), "\n") + "\n"
+}
+
+// sudoTee overwrites path with content using `sudo tee`, keeping the
+// file's ownership and permissions. sudo prompts on the terminal if needed.
+func sudoTee(path string, content []byte) error {
+	cmd := exec.Command("sudo", "tee", path)
+	cmd.Stdin = bytes.NewReader(content)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sudo tee %s: %w", path, err)
+	}
+	return nil
+}
+
 // generateBlock creates the managed block content.
 func (m *Manager) generateBlock() string {
 	entries := m.GetEntries()
@@ -176,3 +176,64 @@ func TestGenerateRemoveCommand(t *testing.T) {
 	assert.Contains(t, cmd, "sudo")
 	assert.Contains(t, cmd, "sed")
 }
+
+func TestSyncAndClean(t *testing.T) {
+	hostsPath := filepath.Join(t.TempDir(), "hosts")
+	initial := "127.0.0.1 localhost\n"
+	require.NoError(t, os.WriteFile(hostsPath, []byte(initial), 0644))
+
+	m := NewManagerWithPath(hostsPath, "local.myapp.dev", []string{"app", "api"})
+
+	changed, err := m.Sync()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	_, upToDate, err := m.Check()
+	require.NoError(t, err)
+	assert.True(t, upToDate)
+
+	changed, err = m.Sync()
+	require.NoError(t, err)
+	assert.False(t, changed, "an up-to-date block is left alone")
+
+	m.services = []string{"app"}
+	changed, err = m.Sync()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	content, err := os.ReadFile(hostsPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "api.local.myapp.dev")
+	assert.Equal(t, 1, strings.Count(string(content), BlockBegin))
+
+	changed, err = m.Clean()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	content, err = os.ReadFile(hostsPath)
+	require.NoError(t, err)
+	assert.Equal(t, initial, string(content))
+
+	changed, err = m.Clean()
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestSync_SudoFallback(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write read-only files")
+	}
+
+	hostsPath := filepath.Join(t.TempDir(), "hosts")
+	require.NoError(t, os.WriteFile(hostsPath, []byte("127.0.0.1 localhost\n"), 0444))
+
+	m := NewManagerWithPath(hostsPath, "local.myapp.dev", []string{"app"})
+	var written []byte
+	m.sudoWrite = func(path string, content []byte) error {
+		assert.Equal(t, hostsPath, path)
+		written = content
+		return nil
+	}
+
+	changed, err := m.Sync()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Contains(t, string(written), "app.local.myapp.dev")
+}
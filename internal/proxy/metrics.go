@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/charliek/prox/internal/metrics"
+)
+
+// requestMetrics counts proxied requests and their latency for /metrics.
+// Unlike the request history, they cover every request, including ones made
+// while recording is paused.
+type requestMetrics struct {
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{
+		requests: metrics.NewCounterVec("prox_proxy_requests_total",
+			"Proxied requests by subdomain and status class.", "subdomain", "status"),
+		duration: metrics.NewHistogramVec("prox_proxy_request_duration_seconds",
+			"Latency of proxied requests by subdomain and status class. WebSocket connections are not included.",
+			metrics.DefaultLatencyBuckets, "subdomain", "status"),
+	}
+}
+
+// statusClass returns the class of a status code, e.g. "2xx"
+func statusClass(code int) string {
+	return fmt.Sprintf("%dxx", code/100)
+}
+
+// metricsMiddleware counts every proxied request with the status code it was
+// answered with, labelled with the service it was routed to. Requests for
+// unknown subdomains are counted with an empty subdomain, and requests
+// matching a wildcard service with its name, e.g. "pr-*", so arbitrary hosts
+// can't add labels.
+func (s *Service) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := RequestInfoFrom(r.Context())
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		upgraded := false
+		rw.hijack = func(c net.Conn, brw *bufio.ReadWriter) (net.Conn, *bufio.ReadWriter) {
+			upgraded = true
+			rw.statusCode = http.StatusSwitchingProtocols
+			return c, brw
+		}
+
+		next.ServeHTTP(rw, r)
+
+		status := statusClass(rw.statusCode)
+		s.metrics.requests.Inc(info.Route, status)
+		// The duration of an upgraded connection is its lifetime
+		if !upgraded {
+			s.metrics.duration.Observe(time.Since(info.Start).Seconds(), info.Route, status)
+		}
+	})
+}
+
+// WriteMetrics writes the proxy's metrics in the Prometheus text format
+func (s *Service) WriteMetrics(w io.Writer) {
+	s.metrics.requests.Write(w)
+	s.metrics.duration.Write(w)
+
+	recording := 0.0
+	if s.Recording() {
+		recording = 1
+	}
+	metrics.WriteMetric(w, "prox_proxy_recording", "Whether proxied requests are recorded in the request history.",
+		"gauge", nil, []metrics.Sample{{Value: recording}})
+}
@@ -0,0 +1,312 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+)
+
+// Middleware wraps the handler for proxied requests. A middleware may answer
+// a request itself, e.g. to reject it, instead of calling next.
+type Middleware func(next http.Handler) http.Handler
+
+// RequestInfo describes a proxied request as it passes through the
+// middleware chain. Middlewares get it with RequestInfoFrom.
+type RequestInfo struct {
+	ID        string               // Request ID in the request history
+	Start     time.Time            // When the proxy received the request
+	Subdomain string               // Subdomain the request was sent to
+	Route     string               // Name of the service the subdomain matched, e.g. "pr-*", once routed
+	Service   config.ServiceConfig // Service serving the subdomain, once routed
+
+	// Set when recording was paused as the request arrived, so it is neither
+	// captured nor recorded
+	unrecorded bool
+
+	// Set for prox's own proxy healthchecks, which are neither recorded nor
+	// counted as activity
+	healthcheck bool
+
+	// Set by the capture middleware, and recorded with the request
+	details          *RequestDetails
+	schemaViolations []string
+}
+
+// requestInfoKey is the context key for a request's RequestInfo
+type requestInfoKey struct{}
+
+// RequestInfoFrom returns the RequestInfo of a proxied request, or nil when
+// ctx isn't from a request passing through the proxy
+func RequestInfoFrom(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*RequestInfo)
+	return info
+}
+
+// Use adds middlewares to the chain for proxied requests. They run in the
+// order added, after a request is routed to its service (so
+// RequestInfoFrom reports the service) and before prox's own checks. Requests
+// they answer are still recorded. Must be called before Start.
+func (s *Service) Use(middlewares ...Middleware) {
+	s.middlewares = append(s.middlewares, middlewares...)
+}
+
+// handler builds the middleware chain for proxied requests, outermost first
+func (s *Service) handler() http.Handler {
+	chain := []Middleware{s.metricsMiddleware, s.recordMiddleware, s.routeMiddleware}
+	chain = append(chain, s.middlewares...)
+	chain = append(chain,
+		s.idleMiddleware,
+		s.wakeMiddleware,
+		s.restartWaitMiddleware,
+		s.lazyStartMiddleware,
+		s.drainMiddleware,
+		s.captureMiddleware,
+	)
+
+	var h http.Handler = http.HandlerFunc(s.forward)
+	for i := len(chain) - 1; i >= 0; i-- {
+		h = chain[i](h)
+	}
+	return h
+}
+
+// recordMiddleware records every request in the request history with the
+// status code it was answered with, unless recording is paused. WebSocket
+// upgrades are tracked for the lifetime of their connection (see
+// websocket.go).
+func (s *Service) recordMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := RequestInfoFrom(r.Context())
+		if !s.Recording() || info.healthcheck {
+			info.unrecorded = true
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if isWebSocketUpgrade(r) {
+			s.recordWebSocket(next, w, r)
+			return
+		}
+
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rw, r)
+		s.recordRequest(r, info.Subdomain, rw.statusCode, rw.bytes, info.Start, info.ID, info.details, info.schemaViolations)
+	})
+}
+
+// routeMiddleware resolves the service for the request's subdomain
+func (s *Service) routeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := RequestInfoFrom(r.Context())
+		if info.Subdomain == "" {
+			http.Error(w, "No subdomain specified", http.StatusNotFound)
+			return
+		}
+
+		route, svc, ok := s.lookupService(info.Subdomain)
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown service: %s", info.Subdomain), http.StatusNotFound)
+			return
+		}
+		info.Route = route
+		info.Service = svc
+		next.ServeHTTP(w, r)
+	})
+}
+
+// idleMiddleware counts requests as daemon activity while they are served
+func (s *Service) idleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if RequestInfoFrom(r.Context()).healthcheck {
+			next.ServeHTTP(w, r)
+			return
+		}
+		end := s.idle.Begin()
+		defer end()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// wakeMiddleware cold-starts processes put to sleep while the daemon was idle
+func (s *Service) wakeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.wake != nil && !RequestInfoFrom(r.Context()).healthcheck {
+			if err := s.wake(r.Context()); err != nil {
+				s.logger.Warn("waking processes", "error", err)
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, fmt.Sprintf("Service failed to wake: %s", RequestInfoFrom(r.Context()).Subdomain), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// restartWaitMiddleware holds the request while its process is restarting,
+// for up to proxy.restart_wait, so a restart doesn't show up in the browser
+// as a failed request. A request still waiting then is forwarded anyway.
+// Healthchecks aren't held.
+func (s *Service) restartWaitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := RequestInfoFrom(r.Context())
+		wait := s.cfg.RestartWaitDuration()
+		if s.waitRestart == nil || info.Service.Process == "" || info.healthcheck || wait <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), wait)
+		err := s.waitRestart(ctx, info.Service.Process)
+		cancel()
+		if r.Context().Err() != nil {
+			return // The client gave up
+		}
+		if err != nil {
+			s.logger.Warn("forwarding request to restarting process", "process", info.Service.Process, "waited", wait)
+		}
+
+		// The process may have been given a new port when it restarted
+		if _, current, ok := s.lookupService(info.Subdomain); ok {
+			info.Service = current
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// lazyStartMiddleware holds the request until a lazy process has started.
+// Healthchecks don't start processes or keep them from idling.
+func (s *Service) lazyStartMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := RequestInfoFrom(r.Context())
+		if s.useProcess == nil || info.Service.Process == "" || info.healthcheck {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		release, err := s.useProcess(r.Context(), info.Service.Process)
+		if err != nil {
+			s.logger.Warn("starting lazy process", "process", info.Service.Process, "error", err)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, fmt.Sprintf("Service failed to start: %s", info.Subdomain), http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+
+		// The process may have been given a new port when it started
+		if _, current, ok := s.lookupService(info.Subdomain); ok {
+			info.Service = current
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// drainMiddleware rejects new requests while the service is draining, and
+// counts in-flight requests otherwise
+func (s *Service) drainMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := RequestInfoFrom(r.Context())
+		if !s.beginRequest(info.Route) {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, fmt.Sprintf("Service draining: %s", info.Subdomain), http.StatusServiceUnavailable)
+			return
+		}
+		defer s.endRequest(info.Route)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// captureMiddleware captures request and response headers and bodies, and
+// checks captured responses against the service's schema. Requests the
+// capture rules leave out are recorded without them.
+func (s *Service) captureMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := RequestInfoFrom(r.Context())
+		if s.captureManager == nil || !s.captureManager.Enabled() || info.unrecorded ||
+			!s.captureManager.filter.mayCapture(r, info.Subdomain) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var reqBody *CapturedBody
+		var reqHeaders http.Header
+		reqBody, r.Body, reqHeaders = s.captureManager.CaptureRequest(info.ID, r)
+
+		crw := newCapturingResponseWriter(w, s.captureManager.maxBodySize)
+		next.ServeHTTP(crw, r)
+
+		if !s.captureManager.filter.shouldCapture(r, info.Subdomain, crw.Header().Get("Content-Type")) {
+			s.captureManager.CleanupRequest(info.ID)
+			return
+		}
+		resBody, resHeaders := s.captureManager.CaptureResponse(info.ID, crw)
+		info.details = &RequestDetails{
+			RequestHeaders:  reqHeaders,
+			ResponseHeaders: resHeaders,
+			RequestBody:     reqBody,
+			ResponseBody:    resBody,
+		}
+		info.schemaViolations = s.checkResponseSchema(info.Route, crw.StatusCode(), resBody)
+	})
+}
+
+// forward proxies the request to its service, at the end of the chain
+func (s *Service) forward(w http.ResponseWriter, r *http.Request) {
+	info := RequestInfoFrom(r.Context())
+	target := &url.URL{
+		Scheme: "http",
+		Host:   fmt.Sprintf("%s:%d", info.Service.Host, info.Service.Port),
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	// Use shared transport for connection pooling
+	proxy.Transport = s.transport
+	if info.Service.Protocol == config.ServiceProtocolH2C {
+		proxy.Transport = s.h2c
+	}
+
+	// Determine if request came via HTTPS
+	proto := "http"
+	if r.TLS != nil {
+		proto = "https"
+	}
+
+	// The rewrite applies unless its rule was disabled at runtime
+	rewrite := info.Service.Rewrite
+	if !s.rewriteEnabled(info.Route) {
+		rewrite = nil
+	}
+
+	// Customize the director to preserve the original request info
+	originalDirector := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		originalDirector(req)
+		// Preserve the original host header for applications that need it
+		req.Header.Set("X-Forwarded-Host", r.Host)
+		req.Header.Set("X-Forwarded-Proto", proto)
+		req.Header.Set("X-Real-IP", getClientIP(r))
+		// Rewrites apply last, so they can replace the headers above too
+		rewriteRequest(req, rewrite, target.Host)
+	}
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		rewriteResponse(resp, rewrite)
+		return nil
+	}
+
+	// Custom error handler - log detailed error but return generic message to client
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		s.logger.Error("proxy error",
+			"subdomain", info.Subdomain,
+			"target", target.String(),
+			"error", err,
+		)
+		http.Error(w, "Backend unavailable", http.StatusBadGateway)
+	}
+
+	proxy.ServeHTTP(w, r)
+}
@@ -0,0 +1,216 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/constants"
+)
+
+func TestService_Use(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-User")))
+	}))
+	defer backend.Close()
+	backendPort := backend.Listener.Addr().(*net.TCPAddr).Port
+
+	cfg := &config.ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.myapp.dev"}
+	services := map[string]config.ServiceConfig{
+		"app": {Port: backendPort, Host: "localhost", Process: "web"},
+	}
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+
+	// An auth middleware that rejects requests without a token, and a
+	// second one that sees the request after the first let it through
+	var order []string
+	svc.Use(
+		func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				info := RequestInfoFrom(r.Context())
+				order = append(order, "auth:"+info.Subdomain+":"+info.Service.Process)
+				if r.Header.Get("Authorization") == "" {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		},
+		func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, "user")
+				r.Header.Set("X-User", "alice")
+				next.ServeHTTP(w, r)
+			})
+		},
+	)
+	router := svc.createRouter()
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "app.local.myapp.dev"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, []string{"auth:app:web"}, order)
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Host = "app.local.myapp.dev"
+	req.Header.Set("Authorization", "Bearer token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", w.Body.String())
+	assert.Equal(t, []string{"auth:app:web", "auth:app:web", "user"}, order)
+
+	// Requests answered by a middleware are recorded like any other
+	records := svc.RequestManager().Recent(RequestFilter{})
+	require.Len(t, records, 2)
+	assert.ElementsMatch(t, []int{http.StatusUnauthorized, http.StatusOK}, []int{records[0].StatusCode, records[1].StatusCode})
+	for _, record := range records {
+		if record.StatusCode == http.StatusOK {
+			assert.Equal(t, int64(len("alice")), record.ResponseBytes)
+		}
+	}
+
+	// Middlewares only see routed requests
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Host = "missing.local.myapp.dev"
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Len(t, order, 3)
+}
+
+func TestRequestInfoFrom_OutsideProxy(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	assert.Nil(t, RequestInfoFrom(req.Context()))
+}
+
+func TestService_SetRecording(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	cfg := &config.ProxyConfig{
+		Enabled:  true,
+		HTTPPort: 6788,
+		Domain:   "local.myapp.dev",
+		Capture:  &config.CaptureConfig{Enabled: true},
+	}
+	services := map[string]config.ServiceConfig{
+		"app": {Port: backend.Listener.Addr().(*net.TCPAddr).Port, Host: "localhost"},
+	}
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+	router := svc.createRouter()
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = "app.local.myapp.dev"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.True(t, svc.Recording())
+	svc.SetRecording(false)
+	assert.False(t, svc.Recording())
+
+	// Requests are still proxied, but not recorded
+	w := get()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Equal(t, 0, svc.RequestManager().Count())
+
+	svc.SetRecording(true)
+	get()
+	require.Equal(t, 1, svc.RequestManager().Count())
+	assert.NotNil(t, svc.RequestManager().Recent(RequestFilter{})[0].Details, "recorded requests are captured again")
+}
+
+func TestService_HealthcheckRequestsNotRecorded(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	cfg := &config.ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.myapp.dev"}
+	services := map[string]config.ServiceConfig{
+		"app": {Port: backend.Listener.Addr().(*net.TCPAddr).Port, Host: "localhost", Process: "web"},
+	}
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+	svc.SetProcessStarter(func(ctx context.Context, process string) (func(), error) {
+		t.Error("healthcheck requests must not start processes")
+		return func() {}, nil
+	})
+	router := svc.createRouter()
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "app.local.myapp.dev"
+	req.Header.Set("User-Agent", constants.HealthcheckUserAgent)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, svc.RequestManager().Count())
+}
+
+func TestService_Metrics(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	cfg := &config.ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.myapp.dev"}
+	services := map[string]config.ServiceConfig{
+		"app": {Port: backend.Listener.Addr().(*net.TCPAddr).Port, Host: "localhost"},
+	}
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+	router := svc.createRouter()
+
+	get := func(host, path string) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Host = host
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	get("app.local.myapp.dev", "/")
+	get("app.local.myapp.dev", "/missing")
+	// Counted while recording is paused too
+	svc.SetRecording(false)
+	get("app.local.myapp.dev", "/")
+	get("nope.local.myapp.dev", "/")
+
+	var sb strings.Builder
+	svc.WriteMetrics(&sb)
+	out := sb.String()
+	assert.Contains(t, out, `prox_proxy_requests_total{subdomain="app",status="2xx"} 2`)
+	assert.Contains(t, out, `prox_proxy_requests_total{subdomain="app",status="4xx"} 1`)
+	assert.Contains(t, out, `prox_proxy_requests_total{subdomain="",status="4xx"} 1`, "unknown subdomains share a label")
+	assert.Contains(t, out, `prox_proxy_request_duration_seconds_count{subdomain="app",status="2xx"} 2`)
+	assert.Contains(t, out, "prox_proxy_recording 0")
+}
@@ -11,14 +11,16 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/charliek/prox/internal/config"
 	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/idle"
 	"github.com/charliek/prox/internal/proxy/certs"
 )
 
@@ -32,13 +34,57 @@ type Service struct {
 	httpServer  *http.Server
 	httpsServer *http.Server
 	transport   *http.Transport
+	h2c         *http.Transport // For services whose backends speak HTTP/2 over cleartext
 	mu          sync.RWMutex
 
-	// Request tracking
-	requestManager *RequestManager
+	// Request tracking, which can be paused without stopping the proxy
+	requestManager  *RequestManager
+	recordingPaused atomic.Bool
 
 	// Request/response capture
 	captureManager *CaptureManager
+
+	// Request history kept across restarts (nil unless proxy.history.persist)
+	history *fileRequestStore
+
+	// Draining state, in-flight request counts, disabled rewrite rules, and
+	// service targets. routeMu guards services since targets can change at runtime.
+	routeMu     sync.Mutex
+	draining    map[string]bool
+	inflight    map[string]int
+	rewritesOff map[string]bool
+
+	// Proxied requests in flight, for postmortem dumps
+	activeMu sync.Mutex
+	active   map[*RequestInfo]ActiveRequest
+
+	// Per-service latency stats and budget tracking
+	statsTracker *StatsTracker
+	statsCancel  context.CancelFunc
+
+	// Request counters and latency histograms for /metrics
+	metrics *requestMetrics
+
+	// Response schemas by service name, checked against captured responses
+	schemas map[string]*responseSchema
+
+	// Control API served at a reserved subdomain (nil when not exposed)
+	apiSubdomain string
+	apiHandler   http.Handler
+
+	// Idle shutdown: proxied requests count as activity, and wake starts
+	// sleeping processes before a request is forwarded (both optional)
+	idle *idle.Tracker
+	wake func(ctx context.Context) error
+
+	// Starts lazy processes on their first request (optional)
+	useProcess func(ctx context.Context, process string) (func(), error)
+
+	// Holds requests for a restarting process until it is back (optional)
+	waitRestart func(ctx context.Context, process string) error
+
+	// Additional middlewares for proxied requests (see Use)
+	middlewares []Middleware
 }
 
 // NewService creates a new proxy service.
@@ -53,7 +99,7 @@ func NewService(cfg *config.ProxyConfig, services map[string]config.ServiceConfi
 	var certsMgr *certs.Manager
 	// Only create cert manager if HTTPS is enabled and certs are configured
 	if certsCfg != nil && cfg != nil && cfg.HTTPSPort > 0 {
-		certsMgr = certs.NewManager(certsCfg.Dir, cfg.Domain)
+		certsMgr = certs.NewManager(certsCfg.Dir, cfg.Domain, certsCfg.Provider)
 	}
 
 	// Create shared transport for connection pooling
@@ -66,32 +112,85 @@ func NewService(cfg *config.ProxyConfig, services map[string]config.ServiceConfi
 		MaxIdleConns:          constants.DefaultProxyMaxIdleConns,
 		IdleConnTimeout:       constants.DefaultProxyIdleConnTimeout,
 	}
+	h2c := transport.Clone()
+	h2c.Protocols = new(http.Protocols)
+	h2c.Protocols.SetUnencryptedHTTP2(true)
 
 	// Create capture manager if capture is configured
 	var captureCfg *config.CaptureConfig
 	if cfg != nil {
 		captureCfg = cfg.Capture
 	}
-	captureMgr, err := NewCaptureManager(captureCfg, workDir)
+	captureMgr, err := newCaptureManager(captureCfg, workDir, cfg.HistoryPersisted())
 	if err != nil {
 		return nil, fmt.Errorf("creating capture manager: %w", err)
 	}
 
-	requestMgr := NewRequestManager(constants.DefaultProxyRequestBufferSize)
+	requestMgr := NewRequestManager(cfg.HistoryMaxOrDefault())
+
+	// Load the request history kept by the previous run, dropping the
+	// captured bodies of requests it no longer holds
+	var history *fileRequestStore
+	if cfg.HistoryPersisted() {
+		history, err = openFileRequestStore(filepath.Join(workDir, constants.RequestHistoryFile), cfg.HistoryMaxOrDefault())
+		if err != nil {
+			return nil, fmt.Errorf("loading request history: %w", err)
+		}
+		requestMgr.SetStore(history)
+		err = captureMgr.Prune(func(id string) bool {
+			record, ok := history.Get(id)
+			return ok && record.Details != nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			_ = history.Close()
+			return nil, fmt.Errorf("pruning captured bodies: %w", err)
+		}
+	}
 
 	// Set up eviction callback to clean up captured body files
 	if captureMgr.Enabled() {
 		requestMgr.SetEvictionCallback(captureMgr.CleanupRequest)
 	}
 
+	// Collect latency budgets for services that declare one
+	budgets := make(map[string]time.Duration)
+	for name, svc := range services {
+		if budget := svc.P95Budget(); budget > 0 {
+			budgets[name] = budget
+		}
+	}
+
+	schemas, err := loadResponseSchemas(services, workDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(schemas) > 0 && !captureMgr.Enabled() && logger != nil {
+		logger.Warn("response schemas are configured but capture is disabled; responses will not be validated")
+	}
+
+	// Copy services so runtime target changes don't modify the loaded config
+	routes := make(map[string]config.ServiceConfig, len(services))
+	for name, svc := range services {
+		routes[name] = svc
+	}
+
 	return &Service{
 		cfg:            cfg,
-		services:       services,
+		services:       routes,
 		certs:          certsMgr,
 		logger:         logger,
 		transport:      transport,
+		h2c:            h2c,
 		requestManager: requestMgr,
 		captureManager: captureMgr,
+		history:        history,
+		draining:       make(map[string]bool),
+		inflight:       make(map[string]int),
+		rewritesOff:    make(map[string]bool),
+		active:         make(map[*RequestInfo]ActiveRequest),
+		statsTracker:   NewStatsTracker(requestMgr, budgets),
+		metrics:        newRequestMetrics(),
+		schemas:        schemas,
 	}, nil
 }
 
@@ -127,6 +226,15 @@ func (s *Service) Start(ctx context.Context) error {
 		}
 	}
 
+	// Watch latency budgets in the background
+	if s.statsTracker.HasBudgets() {
+		statsCtx, cancel := context.WithCancel(ctx)
+		s.mu.Lock()
+		s.statsCancel = cancel
+		s.mu.Unlock()
+		go s.statsTracker.Run(statsCtx)
+	}
+
 	return nil
 }
 
@@ -140,6 +248,11 @@ func (s *Service) startHTTP(router http.Handler) error {
 		WriteTimeout: constants.DefaultProxyWriteTimeout,
 		IdleTimeout:  constants.DefaultProxyIdleTimeout,
 	}
+	// Accept HTTP/2 without TLS too, so gRPC clients can reach h2c services
+	// over plain HTTP
+	server.Protocols = new(http.Protocols)
+	server.Protocols.SetHTTP1(true)
+	server.Protocols.SetUnencryptedHTTP2(true)
 
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -232,11 +345,27 @@ func (s *Service) Shutdown(ctx context.Context) error {
 
 	shutdownErrs := s.stopServers(ctx)
 
+	// Stop latency budget checks
+	s.mu.Lock()
+	if s.statsCancel != nil {
+		s.statsCancel()
+		s.statsCancel = nil
+	}
+	s.mu.Unlock()
+
 	// Close the request manager to clean up subscriptions
 	s.requestManager.Close()
 
-	// Clean up captured body files
-	if s.captureManager != nil {
+	// Close the persisted history, keeping the captured bodies it refers
+	// to, or clean up captured body files
+	if s.history != nil {
+		if err := s.history.Err(); err != nil {
+			s.logger.Error("failed to write request history", "error", err)
+		}
+		if err := s.history.Close(); err != nil {
+			s.logger.Error("failed to close request history", "error", err)
+		}
+	} else if s.captureManager != nil {
 		if err := s.captureManager.Cleanup(); err != nil {
 			s.logger.Error("failed to cleanup capture files", "error", err)
 		}
@@ -287,119 +416,101 @@ func (s *Service) RequestManager() *RequestManager {
 	return s.requestManager
 }
 
+// Recording reports whether proxied requests are being recorded
+func (s *Service) Recording() bool {
+	return !s.recordingPaused.Load()
+}
+
+// SetRecording pauses or resumes recording proxied requests, e.g. to keep a
+// load test out of the request history. Requests are still proxied while
+// recording is paused; they just aren't recorded or captured.
+func (s *Service) SetRecording(enabled bool) {
+	if s.recordingPaused.Swap(!enabled) == !enabled {
+		return
+	}
+	if enabled {
+		s.logger.Info("request recording resumed")
+	} else {
+		s.logger.Info("request recording paused")
+	}
+}
+
 // CaptureManager returns the capture manager for loading captured bodies.
 func (s *Service) CaptureManager() *CaptureManager {
 	return s.captureManager
 }
 
-// createRouter creates the HTTP handler that routes requests based on subdomain.
-func (s *Service) createRouter() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		startTime := time.Now()
-
-		// Generate request ID early for capture
-		requestID := generateRequestID(startTime, r.Method, r.URL.String())
-
-		// Extract subdomain from host
-		subdomain := s.extractSubdomain(r.Host)
-		if subdomain == "" {
-			s.recordRequest(r, subdomain, http.StatusNotFound, startTime, requestID, nil)
-			http.Error(w, "No subdomain specified", http.StatusNotFound)
-			return
-		}
-
-		// Look up service
-		svc, ok := s.services[subdomain]
-		if !ok {
-			s.recordRequest(r, subdomain, http.StatusNotFound, startTime, requestID, nil)
-			http.Error(w, fmt.Sprintf("Unknown service: %s", subdomain), http.StatusNotFound)
-			return
-		}
-
-		// Create reverse proxy
-		target := &url.URL{
-			Scheme: "http",
-			Host:   fmt.Sprintf("%s:%d", svc.Host, svc.Port),
-		}
+// StatsTracker returns the tracker for per-service latency stats.
+func (s *Service) StatsTracker() *StatsTracker {
+	return s.statsTracker
+}
 
-		proxy := httputil.NewSingleHostReverseProxy(target)
+// SetAPIHandler serves the control API at the given reserved subdomain.
+// Must be called before Start.
+func (s *Service) SetAPIHandler(subdomain string, handler http.Handler) {
+	s.apiSubdomain = subdomain
+	s.apiHandler = handler
+}
 
-		// Use shared transport for connection pooling
-		proxy.Transport = s.transport
+// SetIdleTracker records proxied requests as daemon activity.
+// Must be called before Start.
+func (s *Service) SetIdleTracker(tracker *idle.Tracker) {
+	s.idle = tracker
+}
 
-		// Capture request body and headers if capture is enabled
-		var reqBody *CapturedBody
-		var reqHeaders http.Header
-		if s.captureManager != nil && s.captureManager.Enabled() {
-			reqBody, r.Body, reqHeaders = s.captureManager.CaptureRequest(requestID, r)
-		} else {
-			reqHeaders = cloneHeaders(r.Header)
-		}
+// SetWakeFunc sets a function called before each proxied request to start
+// processes put to sleep while the daemon was idle. It should return quickly
+// when nothing is asleep. Must be called before Start.
+func (s *Service) SetWakeFunc(wake func(ctx context.Context) error) {
+	s.wake = wake
+}
 
-		// Determine if request came via HTTPS
-		proto := "http"
-		if r.TLS != nil {
-			proto = "https"
-		}
+// SetProcessStarter sets a function called before each proxied request with
+// the process that serves the service. It starts the process if it is lazy
+// and stopped, blocking until it is ready, and returns a function to call
+// when the request finishes. Must be called before Start.
+func (s *Service) SetProcessStarter(use func(ctx context.Context, process string) (func(), error)) {
+	s.useProcess = use
+}
 
-		// Customize the director to preserve the original request info
-		originalDirector := proxy.Director
-		proxy.Director = func(req *http.Request) {
-			originalDirector(req)
-			// Preserve the original host header for applications that need it
-			req.Header.Set("X-Forwarded-Host", r.Host)
-			req.Header.Set("X-Forwarded-Proto", proto)
-			req.Header.Set("X-Real-IP", getClientIP(r))
-		}
+// SetRestartWaiter sets a function called before each proxied request with
+// the process that serves the service. While the process is restarting, it
+// blocks until the process is ready again; requests wait up to
+// proxy.restart_wait for it. Must be called before Start.
+func (s *Service) SetRestartWaiter(wait func(ctx context.Context, process string) error) {
+	s.waitRestart = wait
+}
 
-		// Choose response writer based on capture mode
-		var rw http.ResponseWriter
-		var crw *capturingResponseWriter
-		if s.captureManager != nil && s.captureManager.Enabled() {
-			crw = newCapturingResponseWriter(w, s.captureManager.maxBodySize)
-			rw = crw
-		} else {
-			rw = &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-		}
+// serveAPI handles a request for the control API subdomain. API requests are
+// not recorded, so clients polling the API don't flood the request history.
+func (s *Service) serveAPI(w http.ResponseWriter, r *http.Request) {
+	// Log streams outlive the proxy's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	s.apiHandler.ServeHTTP(w, r)
+}
 
-		// Custom error handler - log detailed error but return generic message to client
-		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-			s.logger.Error("proxy error",
-				"subdomain", subdomain,
-				"target", target.String(),
-				"error", err,
-			)
-			if crw != nil {
-				crw.WriteHeader(http.StatusBadGateway)
-			} else if basicRw, ok := rw.(*responseWriter); ok {
-				basicRw.statusCode = http.StatusBadGateway
-			}
-			http.Error(w, "Backend unavailable", http.StatusBadGateway)
+// createRouter creates the HTTP handler that routes requests based on
+// subdomain. Requests for the control API are served directly; the rest go
+// through the middleware chain (see middleware.go).
+func (s *Service) createRouter() http.Handler {
+	proxied := s.handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		info := &RequestInfo{
+			// Generate request ID early for capture
+			ID:          generateRequestID(startTime, r.Method, r.URL.String()),
+			Start:       startTime,
+			Subdomain:   s.extractSubdomain(r.Host),
+			healthcheck: r.UserAgent() == constants.HealthcheckUserAgent,
 		}
 
-		// Serve the request
-		proxy.ServeHTTP(rw, r)
-
-		// Build request details if capture is enabled
-		var details *RequestDetails
-		var statusCode int
-		if crw != nil {
-			statusCode = crw.StatusCode()
-			resBody, resHeaders := s.captureManager.CaptureResponse(requestID, crw)
-			details = &RequestDetails{
-				RequestHeaders:  reqHeaders,
-				ResponseHeaders: resHeaders,
-				RequestBody:     reqBody,
-				ResponseBody:    resBody,
-			}
-		} else if basicRw, ok := rw.(*responseWriter); ok {
-			statusCode = basicRw.statusCode
-		} else {
-			statusCode = http.StatusOK
+		if s.apiHandler != nil && info.Subdomain != "" && info.Subdomain == s.apiSubdomain {
+			s.serveAPI(w, r)
+			return
 		}
 
-		// Record the request (single recording point for all cases)
-		s.recordRequest(r, subdomain, statusCode, startTime, requestID, details)
+		defer s.trackActive(info, r.Method, r.URL.String())()
+		proxied.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)))
 	})
 }
 
@@ -430,17 +541,22 @@ func (s *Service) extractSubdomain(host string) string {
 }
 
 // recordRequest records a request in the request manager.
-func (s *Service) recordRequest(r *http.Request, subdomain string, statusCode int, startTime time.Time, requestID string, details *RequestDetails) {
+func (s *Service) recordRequest(r *http.Request, subdomain string, statusCode int, responseBytes int64, startTime time.Time, requestID string, details *RequestDetails, schemaViolations []string) {
 	record := RequestRecord{
-		ID:         requestID,
-		Timestamp:  startTime,
-		Method:     r.Method,
-		URL:        r.URL.String(),
-		Subdomain:  subdomain,
-		StatusCode: statusCode,
-		Duration:   time.Since(startTime),
-		RemoteAddr: getClientIP(r),
-		Details:    details,
+		ID:               requestID,
+		Timestamp:        startTime,
+		Method:           r.Method,
+		URL:              r.URL.String(),
+		Subdomain:        subdomain,
+		StatusCode:       statusCode,
+		Duration:         time.Since(startTime),
+		RemoteAddr:       getClientIP(r),
+		ResponseBytes:    responseBytes,
+		Details:          details,
+		SchemaViolations: schemaViolations,
+	}
+	if isWebSocketUpgrade(r) {
+		record.Type = RequestTypeWebSocket
 	}
 	s.requestManager.Record(record)
 }
@@ -469,6 +585,10 @@ func getClientIP(r *http.Request) string {
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64 // Response body bytes written
+
+	// hijack, if set, is called with a hijacked connection and may wrap it
+	hijack func(net.Conn, *bufio.ReadWriter) (net.Conn, *bufio.ReadWriter)
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -476,6 +596,12 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
 // Flush implements http.Flusher for streaming responses (SSE).
 func (rw *responseWriter) Flush() {
 	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
@@ -486,7 +612,11 @@ func (rw *responseWriter) Flush() {
 // Hijack implements http.Hijacker for WebSocket support.
 func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if h, ok := rw.ResponseWriter.(http.Hijacker); ok {
-		return h.Hijack()
+		conn, brw, err := h.Hijack()
+		if err == nil && rw.hijack != nil {
+			conn, brw = rw.hijack(conn, brw)
+		}
+		return conn, brw, err
 	}
 	return nil, nil, errors.New("hijacking not supported")
 }
@@ -3,17 +3,21 @@ package proxy
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/idle"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -271,3 +275,235 @@ func TestCreateRouter_XForwardedProto(t *testing.T) {
 		assert.Equal(t, "https", receivedProto.Load())
 	})
 }
+
+func TestCreateRouter_H2CBackend(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// A backend that speaks both HTTP/1.1 and h2c, reporting the protocol
+	// each request arrived over in a trailer, as gRPC reports its status
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Proto")
+		w.WriteHeader(http.StatusOK)
+		w.Header().Set("X-Proto", r.Proto)
+	}))
+	backend.Config.Protocols = new(http.Protocols)
+	backend.Config.Protocols.SetHTTP1(true)
+	backend.Config.Protocols.SetUnencryptedHTTP2(true)
+	backend.Start()
+	defer backend.Close()
+	backendPort := backend.Listener.Addr().(*net.TCPAddr).Port
+
+	cfg := &config.ProxyConfig{
+		Enabled:  true,
+		HTTPPort: 6788,
+		Domain:   "local.myapp.dev",
+	}
+	services := map[string]config.ServiceConfig{
+		"app":  {Port: backendPort, Host: "127.0.0.1"},
+		"grpc": {Port: backendPort, Host: "127.0.0.1", Protocol: config.ServiceProtocolH2C},
+	}
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+	router := svc.createRouter()
+
+	for service, proto := range map[string]string{"app": "HTTP/1.1", "grpc": "HTTP/2.0"} {
+		req := httptest.NewRequest("POST", "/helloworld.Greeter/SayHello", nil)
+		req.Host = service + ".local.myapp.dev:6788"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, service)
+		assert.Equal(t, proto, w.Result().Trailer.Get("X-Proto"), service)
+	}
+}
+
+func TestCreateRouter_APISubdomain(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	cfg := &config.ProxyConfig{
+		Enabled:  true,
+		HTTPPort: 6788,
+		Domain:   "local.myapp.dev",
+	}
+	services := map[string]config.ServiceConfig{
+		"app": {Port: 3000, Host: "localhost"},
+	}
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+
+	var apiPath string
+	svc.SetAPIHandler("prox", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiPath = r.URL.Path
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	router := svc.createRouter()
+
+	req := httptest.NewRequest("GET", "/api/v1/status", nil)
+	req.Host = "prox.local.myapp.dev:6788"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Equal(t, "/api/v1/status", apiPath)
+	// API requests are not recorded in the request history
+	assert.Empty(t, svc.RequestManager().Recent(RequestFilter{}))
+}
+
+func TestCreateRouter_WakeBeforeProxying(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(backendURL.Port())
+	require.NoError(t, err)
+
+	cfg := &config.ProxyConfig{
+		Enabled:  true,
+		HTTPPort: 6788,
+		Domain:   "local.myapp.dev",
+	}
+	services := map[string]config.ServiceConfig{
+		"app": {Port: port, Host: "127.0.0.1"},
+	}
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+
+	tracker := idle.NewTracker()
+	wakes := 0
+	wakeErr := error(nil)
+	svc.SetIdleTracker(tracker)
+	svc.SetWakeFunc(func(ctx context.Context) error {
+		wakes++
+		return wakeErr
+	})
+	router := svc.createRouter()
+
+	serve := func() int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = "app.local.myapp.dev:6788"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, serve())
+	assert.Equal(t, 1, wakes)
+	assert.Less(t, tracker.IdleFor(), 20*time.Millisecond, "proxied requests count as activity")
+
+	wakeErr = errors.New("process exited during startup")
+	assert.Equal(t, http.StatusServiceUnavailable, serve())
+	assert.Equal(t, 2, wakes)
+}
+
+func TestCreateRouter_StartsLazyProcess(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(backendURL.Port())
+	require.NoError(t, err)
+
+	cfg := &config.ProxyConfig{
+		Enabled:  true,
+		HTTPPort: 6788,
+		Domain:   "local.myapp.dev",
+	}
+	services := map[string]config.ServiceConfig{
+		// Nothing listens on the configured port until the process starts
+		"app": {Port: 1, Host: "127.0.0.1", Process: "web"},
+	}
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+
+	var started []string
+	released := 0
+	startErr := error(nil)
+	svc.SetProcessStarter(func(ctx context.Context, process string) (func(), error) {
+		started = append(started, process)
+		if startErr != nil {
+			return nil, startErr
+		}
+		// Starting the process assigns it a new port
+		svc.SetProcessPort(process, port)
+		return func() { released++ }, nil
+	})
+	router := svc.createRouter()
+
+	serve := func() int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = "app.local.myapp.dev:6788"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(), "request is proxied to the port assigned at start")
+	assert.Equal(t, []string{"web"}, started)
+	assert.Equal(t, 1, released)
+
+	startErr = errors.New("healthcheck did not pass")
+	assert.Equal(t, http.StatusServiceUnavailable, serve())
+	assert.Equal(t, 1, released)
+}
+
+func TestCreateRouter_WaitsForRestart(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(backendURL.Port())
+	require.NoError(t, err)
+
+	cfg := &config.ProxyConfig{
+		Enabled:     true,
+		HTTPPort:    6788,
+		Domain:      "local.myapp.dev",
+		RestartWait: "50ms",
+	}
+	services := map[string]config.ServiceConfig{
+		// Nothing listens on the configured port until the restart is done
+		"app": {Port: 1, Host: "127.0.0.1", Process: "web"},
+	}
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+
+	restarting := true
+	svc.SetRestartWaiter(func(ctx context.Context, process string) error {
+		assert.Equal(t, "web", process)
+		if restarting {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		// The restarted process listens on a new port
+		svc.SetProcessPort(process, port)
+		return nil
+	})
+	router := svc.createRouter()
+
+	serve := func() int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = "app.local.myapp.dev:6788"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Still restarting after restart_wait: forwarded anyway
+	assert.Equal(t, http.StatusBadGateway, serve())
+
+	restarting = false
+	assert.Equal(t, http.StatusOK, serve(), "request is proxied once the process is back")
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,10 +21,37 @@ type RequestRecord struct {
 	Duration   time.Duration `json:"duration"`
 	RemoteAddr string        `json:"remote_addr"`
 
+	// ResponseBytes is the size of the response body sent to the client
+	ResponseBytes int64 `json:"response_bytes,omitempty"`
+
 	// Details contains captured headers and bodies (nil when capture is disabled)
 	Details *RequestDetails `json:"details,omitempty"`
+
+	// SchemaViolations lists where the response body failed the service's
+	// JSON Schema (nil when not validated or valid)
+	SchemaViolations []string `json:"schema_violations,omitempty"`
+
+	// Imported is set on requests loaded from a saved session rather than
+	// proxied by this instance
+	Imported bool `json:"imported,omitempty"`
+
+	// Type is RequestTypeWebSocket for WebSocket upgrade requests, and empty
+	// for plain HTTP requests
+	Type string `json:"type,omitempty"`
+
+	// WebSocket tracks the connection of an upgraded WebSocket request (nil
+	// when the request wasn't upgraded). Duration is the connection's
+	// lifetime once it closes.
+	WebSocket *WebSocketStats `json:"websocket,omitempty"`
+
+	// Seq is set by the request store, and increases with each record
+	// stored. Pages of requests are read by it.
+	Seq uint64 `json:"-"`
 }
 
+// RequestTypeWebSocket is the type of WebSocket upgrade requests
+const RequestTypeWebSocket = "websocket"
+
 // RequestDetails contains captured request/response headers and bodies.
 type RequestDetails struct {
 	RequestHeaders  map[string][]string `json:"request_headers,omitempty"`
@@ -56,6 +84,7 @@ type RequestFilter struct {
 	MinStatus int
 	MaxStatus int
 	Since     time.Time
+	Until     time.Time // Only requests at or before this time (zero means no bound)
 	Limit     int
 }
 
@@ -64,40 +93,56 @@ type RequestSubscription struct {
 	ID     string
 	Filter RequestFilter
 	Ch     chan RequestRecord
+
+	// stalledSince is when Ch first filled up without being drained
+	// (unix nanoseconds), or 0 if the subscriber is keeping up
+	stalledSince atomic.Int64
+}
+
+// StalledSince returns when the subscriber stopped draining its channel,
+// or the zero time if it is keeping up
+func (s *RequestSubscription) StalledSince() time.Time {
+	if ns := s.stalledSince.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
 }
 
-// EvictionCallback is called when a request is evicted from the ring buffer.
+// EvictionCallback is called when a request is evicted from the store.
 // It receives the request ID for cleanup purposes.
 type EvictionCallback func(id string)
 
-// RequestManager tracks proxied requests in a ring buffer and supports subscriptions.
+// RequestManager tracks proxied requests in a RequestStore and supports subscriptions.
 type RequestManager struct {
-	mu       sync.RWMutex
-	buffer   []RequestRecord
-	head     int
-	count    int
-	capacity int
+	mu    sync.RWMutex
+	store RequestStore
 
 	subMu  sync.RWMutex
 	subs   map[string]*RequestSubscription
 	nextID int
 
-	// onEvict is called when a request is evicted from the buffer
+	// onEvict is called when a request is evicted from the store
 	onEvict EvictionCallback
 }
 
-// NewRequestManager creates a new request manager with the specified buffer capacity.
+// NewRequestManager creates a new request manager keeping the last capacity
+// requests in memory.
 func NewRequestManager(capacity int) *RequestManager {
-	if capacity <= 0 {
-		capacity = 1
-	}
 	return &RequestManager{
-		buffer:   make([]RequestRecord, capacity),
-		capacity: capacity,
-		subs:     make(map[string]*RequestSubscription),
+		store: NewMemoryRequestStore(capacity),
+		subs:  make(map[string]*RequestSubscription),
 	}
 }
 
+// SetStore replaces the request store, such as with one kept on disk. It
+// should be called before requests are recorded; requests in the previous
+// store are not carried over.
+func (m *RequestManager) SetStore(store RequestStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store = store
+}
+
 // SetEvictionCallback sets the callback to be invoked when requests are evicted.
 func (m *RequestManager) SetEvictionCallback(fn EvictionCallback) {
 	m.mu.Lock()
@@ -105,83 +150,97 @@ func (m *RequestManager) SetEvictionCallback(fn EvictionCallback) {
 	m.onEvict = fn
 }
 
-// Record adds a new request record to the buffer and notifies subscribers.
+// requestStore returns the current store and eviction callback
+func (m *RequestManager) requestStore() (RequestStore, EvictionCallback) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.store, m.onEvict
+}
+
+// Record adds a new request record to the store and notifies subscribers.
 // If the record doesn't have an ID, one is generated.
 func (m *RequestManager) Record(record RequestRecord) {
 	if record.ID == "" {
 		record.ID = generateRequestID(record.Timestamp, record.Method, record.URL)
 	}
 
-	var evictedID string
-	var onEvict EvictionCallback
+	record.Seq = m.add(record)
 
-	m.mu.Lock()
-	// Check if we're about to overwrite an existing record
-	if m.count == m.capacity {
-		evicted := m.buffer[m.head]
-		if evicted.ID != "" && evicted.Details != nil {
-			evictedID = evicted.ID
-			onEvict = m.onEvict
-		}
-	}
+	// Notify subscribers
+	m.notifySubscribers(record)
+}
 
-	m.buffer[m.head] = record
-	m.head = (m.head + 1) % m.capacity
-	if m.count < m.capacity {
-		m.count++
+// add stores a record, cleaning up after the records it evicts, and returns
+// its sequence number
+func (m *RequestManager) add(record RequestRecord) uint64 {
+	store, onEvict := m.requestStore()
+	seq, evicted := store.Add(record)
+	if onEvict != nil {
+		for _, id := range evicted {
+			onEvict(id)
+		}
 	}
-	m.mu.Unlock()
+	return seq
+}
 
-	// Call eviction callback outside of lock
-	if evictedID != "" && onEvict != nil {
-		onEvict(evictedID)
+// Update applies fn to the stored request with the given ID and notifies
+// subscribers of the updated record. It returns false if the request is no
+// longer stored.
+func (m *RequestManager) Update(id string, fn func(*RequestRecord)) bool {
+	store, _ := m.requestStore()
+	updated, found := store.Update(id, fn)
+	if found {
+		m.notifySubscribers(updated)
 	}
-
-	// Notify subscribers
-	m.notifySubscribers(record)
+	return found
 }
 
 // Recent returns the most recent requests matching the filter.
 func (m *RequestManager) Recent(filter RequestFilter) []RequestRecord {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	store, _ := m.requestStore()
+	return store.Recent(filter.Limit, func(record RequestRecord) bool {
+		return m.matchesFilter(record, filter)
+	})
+}
+
+// RequestPage is a page of requests read forward from a cursor
+type RequestPage struct {
+	Records []RequestRecord // Matching records, oldest first
+	Next    uint64          // Sequence number to read the following page after
+	More    bool            // Matching records after Next were stored when the page was read
+}
 
+// Page returns up to filter.Limit requests matching the filter with
+// sequence numbers greater than after, oldest first. Sequence numbers
+// increase with each request recorded, so reading pages from each Next in
+// turn sees every request once, even while requests are recorded, unless
+// they are evicted before their page is read.
+func (m *RequestManager) Page(filter RequestFilter, after uint64) RequestPage {
+	store, _ := m.requestStore()
 	limit := filter.Limit
-	if limit <= 0 || limit > m.count {
-		limit = m.count
+	if limit > 0 {
+		limit++ // One more tells whether there are more
 	}
-
-	result := make([]RequestRecord, 0, limit)
-
-	// Iterate from newest to oldest
-	for i := 0; i < m.count && len(result) < limit; i++ {
-		idx := (m.head - 1 - i + m.capacity) % m.capacity
-		record := m.buffer[idx]
-
-		if m.matchesFilter(record, filter) {
-			result = append(result, record)
-		}
+	records := store.After(after, limit, func(record RequestRecord) bool {
+		return m.matchesFilter(record, filter)
+	})
+
+	page := RequestPage{Records: records, Next: after}
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		page.Records = records[:filter.Limit]
+		page.More = true
 	}
-
-	return result
+	if n := len(page.Records); n > 0 {
+		page.Next = page.Records[n-1].Seq
+	}
+	return page
 }
 
 // GetByID returns a request record by its ID.
 // Returns the record and true if found, or an empty record and false if not found.
 func (m *RequestManager) GetByID(id string) (RequestRecord, bool) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	// Search from newest to oldest for better typical case
-	for i := 0; i < m.count; i++ {
-		idx := (m.head - 1 - i + m.capacity) % m.capacity
-		record := m.buffer[idx]
-		if record.ID == id {
-			return record, true
-		}
-	}
-
-	return RequestRecord{}, false
+	store, _ := m.requestStore()
+	return store.Get(id)
 }
 
 // Subscribe creates a subscription for real-time request updates.
@@ -211,11 +270,10 @@ func (m *RequestManager) Unsubscribe(id string) {
 	}
 }
 
-// Count returns the number of requests currently in the buffer.
+// Count returns the number of requests currently stored.
 func (m *RequestManager) Count() int {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return m.count
+	store, _ := m.requestStore()
+	return store.Count()
 }
 
 // Close closes all subscription channels and cleans up resources.
@@ -237,8 +295,10 @@ func (m *RequestManager) notifySubscribers(record RequestRecord) {
 		if m.matchesFilter(record, sub.Filter) {
 			select {
 			case sub.Ch <- record:
+				sub.stalledSince.Store(0)
 			default:
 				// Channel full, drop the message
+				sub.stalledSince.CompareAndSwap(0, time.Now().UnixNano())
 			}
 		}
 	}
@@ -260,5 +320,8 @@ func (m *RequestManager) matchesFilter(record RequestRecord, filter RequestFilte
 	if !filter.Since.IsZero() && record.Timestamp.Before(filter.Since) {
 		return false
 	}
+	if !filter.Until.IsZero() && record.Timestamp.After(filter.Until) {
+		return false
+	}
 	return true
 }
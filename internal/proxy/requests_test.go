@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -50,6 +51,15 @@ func TestRequestManager_Recent(t *testing.T) {
 		assert.Len(t, records, 3)
 	})
 
+	t.Run("until bounds the window", func(t *testing.T) {
+		all := m.Recent(RequestFilter{})
+		until := all[2].Timestamp
+		records := m.Recent(RequestFilter{Until: until, Limit: 2})
+		require.Len(t, records, 2)
+		assert.Equal(t, all[2].Timestamp, records[0].Timestamp)
+		assert.Equal(t, all[3].Timestamp, records[1].Timestamp)
+	})
+
 	t.Run("returns newest first", func(t *testing.T) {
 		records := m.Recent(RequestFilter{})
 		for i := 1; i < len(records); i++ {
@@ -142,6 +152,30 @@ func TestRequestManager_Subscribe(t *testing.T) {
 	m.Unsubscribe(sub.ID)
 }
 
+func TestRequestManager_Update(t *testing.T) {
+	m := NewRequestManager(10)
+	m.Record(RequestRecord{ID: "abc1234", Method: "GET", StatusCode: 101})
+
+	sub := m.Subscribe(RequestFilter{})
+	defer m.Unsubscribe(sub.ID)
+
+	ok := m.Update("abc1234", func(r *RequestRecord) { r.Duration = time.Minute })
+	assert.True(t, ok)
+
+	record, _ := m.GetByID("abc1234")
+	assert.Equal(t, time.Minute, record.Duration)
+	assert.Equal(t, 1, m.Count())
+
+	select {
+	case updated := <-sub.Ch:
+		assert.Equal(t, time.Minute, updated.Duration)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for update")
+	}
+
+	assert.False(t, m.Update("missing", func(r *RequestRecord) {}))
+}
+
 func TestRequestManager_Unsubscribe(t *testing.T) {
 	m := NewRequestManager(10)
 
@@ -220,3 +254,86 @@ func TestRequestManager_Record_PreservesExistingID(t *testing.T) {
 	require.Len(t, records, 1)
 	assert.Equal(t, "custom1", records[0].ID, "expected existing ID to be preserved")
 }
+
+func TestRequestSubscription_StalledSince(t *testing.T) {
+	m := NewRequestManager(200)
+	sub := m.Subscribe(RequestFilter{})
+	defer m.Unsubscribe(sub.ID)
+
+	for i := 0; i < cap(sub.Ch); i++ {
+		m.Record(RequestRecord{Subdomain: "api", Method: "GET"})
+	}
+	assert.True(t, sub.StalledSince().IsZero())
+
+	m.Record(RequestRecord{Subdomain: "api", Method: "GET"})
+	assert.False(t, sub.StalledSince().IsZero())
+
+	<-sub.Ch
+	m.Record(RequestRecord{Subdomain: "api", Method: "GET"})
+	assert.True(t, sub.StalledSince().IsZero())
+}
+
+func TestRequestManager_SetStore(t *testing.T) {
+	m := NewRequestManager(10)
+	m.Record(RequestRecord{Timestamp: time.Now(), Method: "GET", URL: "/old"})
+
+	store := NewMemoryRequestStore(2)
+	m.SetStore(store)
+	assert.Equal(t, 0, m.Count(), "requests aren't carried over")
+
+	var evicted []string
+	m.SetEvictionCallback(func(id string) { evicted = append(evicted, id) })
+
+	for i, url := range []string{"/a", "/b", "/c"} {
+		m.Record(RequestRecord{
+			ID:        url,
+			Timestamp: time.Now().Add(time.Duration(i) * time.Second),
+			Method:    "GET",
+			URL:       url,
+			Details:   &RequestDetails{},
+		})
+	}
+
+	assert.Equal(t, 2, store.Count())
+	assert.Equal(t, []string{"/a"}, evicted)
+	records := m.Recent(RequestFilter{})
+	require.Len(t, records, 2)
+	assert.Equal(t, "/c", records[0].URL)
+
+	assert.True(t, m.Update("/b", func(r *RequestRecord) { r.StatusCode = 502 }))
+	record, ok := store.Get("/b")
+	require.True(t, ok)
+	assert.Equal(t, 502, record.StatusCode)
+}
+
+func TestRequestManager_Page(t *testing.T) {
+	m := NewRequestManager(4)
+	for i, sub := range []string{"api", "web", "api", "api", "web", "api"} {
+		m.Record(RequestRecord{Timestamp: time.Now(), Subdomain: sub, Method: "GET", URL: fmt.Sprintf("/%d", i)})
+	}
+
+	// The first two requests were evicted
+	page := m.Page(RequestFilter{Subdomain: "api", Limit: 1}, 0)
+	require.Len(t, page.Records, 1)
+	assert.Equal(t, "/2", page.Records[0].URL)
+	assert.Equal(t, uint64(3), page.Next)
+	assert.True(t, page.More)
+
+	page = m.Page(RequestFilter{Subdomain: "api", Limit: 5}, page.Next)
+	require.Len(t, page.Records, 2)
+	assert.Equal(t, "/3", page.Records[0].URL)
+	assert.Equal(t, "/5", page.Records[1].URL)
+	assert.False(t, page.More)
+
+	// Nothing new keeps the cursor in place
+	next := page.Next
+	page = m.Page(RequestFilter{Limit: 5}, next)
+	assert.Empty(t, page.Records)
+	assert.Equal(t, next, page.Next)
+
+	m.Record(RequestRecord{Timestamp: time.Now(), Subdomain: "web", Method: "GET", URL: "/6"})
+	page = m.Page(RequestFilter{Limit: 5}, next)
+	require.Len(t, page.Records, 1)
+	assert.Equal(t, "/6", page.Records[0].URL)
+	assert.Equal(t, next+1, page.Records[0].Seq)
+}
@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/charliek/prox/internal/config"
+)
+
+// rewriteRequest applies a service's rewrite rules to a request on its way
+// to the service. target is the service's host and port.
+func rewriteRequest(req *http.Request, rw *config.RewriteConfig, target string) {
+	if rw == nil {
+		return
+	}
+	if rw.HostMode() == config.RewriteHostTarget {
+		req.Host = target
+	}
+	rewriteHeaders(req.Header, rw.RequestHeaders)
+}
+
+// rewriteResponse applies a service's rewrite rules to its response
+func rewriteResponse(resp *http.Response, rw *config.RewriteConfig) {
+	if rw == nil {
+		return
+	}
+	rewriteHeaders(resp.Header, rw.ResponseHeaders)
+}
+
+// rewriteHeaders removes and then sets headers as a rewrite asks
+func rewriteHeaders(h http.Header, rw *config.HeaderRewrite) {
+	if rw == nil {
+		return
+	}
+	for _, name := range rw.Remove {
+		h.Del(name)
+	}
+	for name, value := range rw.Set {
+		h.Set(name, value)
+	}
+}
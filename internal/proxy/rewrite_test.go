@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charliek/prox/internal/config"
+)
+
+func TestCreateRouter_Rewrite(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	var got http.Header
+	var gotHost string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		gotHost = r.Host
+		w.Header().Set("Content-Security-Policy", "default-src 'self'")
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	backendPort := backend.Listener.Addr().(*net.TCPAddr).Port
+	targetHost := "localhost:" + strconv.Itoa(backendPort)
+
+	cfg := &config.ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.myapp.dev"}
+	services := map[string]config.ServiceConfig{
+		"app": {Port: backendPort, Host: "localhost"},
+		"api": {Port: backendPort, Host: "localhost", Rewrite: &config.RewriteConfig{
+			Host: config.RewriteHostTarget,
+			RequestHeaders: &config.HeaderRewrite{
+				Set:    map[string]string{"Authorization": "Bearer dev-token"},
+				Remove: []string{"Cookie"},
+			},
+			ResponseHeaders: &config.HeaderRewrite{
+				Set:    map[string]string{"Set-Cookie": "c=3"},
+				Remove: []string{"Content-Security-Policy", "Set-Cookie"},
+			},
+		}},
+	}
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+	router := svc.createRouter()
+
+	send := func(subdomain string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = subdomain + ".local.myapp.dev:6788"
+		req.Header.Set("Cookie", "session=abc")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+
+	t.Run("without rewrite rules", func(t *testing.T) {
+		w := send("app")
+		assert.Equal(t, "app.local.myapp.dev:6788", gotHost)
+		assert.Equal(t, "session=abc", got.Get("Cookie"))
+		assert.Empty(t, got.Get("Authorization"))
+		assert.Equal(t, "default-src 'self'", w.Header().Get("Content-Security-Policy"))
+	})
+
+	t.Run("with rewrite rules", func(t *testing.T) {
+		w := send("api")
+		assert.Equal(t, targetHost, gotHost)
+		assert.Empty(t, got.Get("Cookie"))
+		assert.Equal(t, "Bearer dev-token", got.Get("Authorization"))
+		assert.Equal(t, "api.local.myapp.dev:6788", got.Get("X-Forwarded-Host"), "X-Forwarded-Host keeps the client's host")
+		assert.Empty(t, w.Header().Get("Content-Security-Policy"))
+		assert.Equal(t, []string{"c=3"}, w.Header().Values("Set-Cookie"))
+	})
+	t.Run("with the rewrite rule disabled", func(t *testing.T) {
+		_, err := svc.SetRuleEnabled("rewrite:api", false)
+		require.NoError(t, err)
+		defer func() { _, _ = svc.SetRuleEnabled("rewrite:api", true) }()
+
+		w := send("api")
+		assert.Equal(t, "api.local.myapp.dev:6788", gotHost)
+		assert.Equal(t, "session=abc", got.Get("Cookie"))
+		assert.Empty(t, got.Get("Authorization"))
+		assert.Equal(t, "default-src 'self'", w.Header().Get("Content-Security-Policy"))
+	})
+}
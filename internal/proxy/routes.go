@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/charliek/prox/internal/config"
+)
+
+// lookupService returns the current routing target for a subdomain, and the
+// name of the service it matched. A service named after the subdomain takes
+// precedence, then the wildcard service with the longest matching prefix,
+// e.g. "pr-*" for "pr-42", then the fallback service "*".
+func (s *Service) lookupService(subdomain string) (string, config.ServiceConfig, bool) {
+	s.routeMu.Lock()
+	defer s.routeMu.Unlock()
+	if svc, ok := s.services[subdomain]; ok {
+		return subdomain, svc, true
+	}
+
+	route, match := "", ""
+	for name := range s.services {
+		prefix, ok := config.WildcardPrefix(name)
+		if !ok || len(subdomain) <= len(prefix) || !strings.HasPrefix(subdomain, prefix) {
+			continue
+		}
+		if route == "" || len(name) > len(route) {
+			route, match = name, subdomain[len(prefix):]
+		}
+	}
+	if route == "" {
+		return "", config.ServiceConfig{}, false
+	}
+	svc, ok := s.services[route].ForMatch(match)
+	if !ok {
+		return "", config.ServiceConfig{}, false
+	}
+	return route, svc, true
+}
+
+// SetProcessPort points every service served by the process at the given
+// port. The switch is atomic: requests that already started keep their
+// original target, while new requests use the new port.
+// Returns the sorted names of the services that were updated.
+func (s *Service) SetProcessPort(process string, port int) []string {
+	s.routeMu.Lock()
+	defer s.routeMu.Unlock()
+
+	var updated []string
+	for name, svc := range s.services {
+		if svc.Process != process || svc.Port == port {
+			continue
+		}
+		svc.Port = port
+		s.services[name] = svc
+		updated = append(updated, name)
+		s.logger.Info("proxy service target updated", "service", name, "process", process, "port", port)
+	}
+	sort.Strings(updated)
+	return updated
+}
@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetProcessPort(t *testing.T) {
+	svc := newDrainTestService(t, 3000)
+
+	updated := svc.SetProcessPort("web", 4000)
+	assert.Equal(t, []string{"admin", "app"}, updated)
+
+	for name, wantPort := range map[string]int{"app": 4000, "admin": 4000, "other": 3000} {
+		_, target, ok := svc.lookupService(name)
+		assert.True(t, ok)
+		assert.Equal(t, wantPort, target.Port, name)
+	}
+
+	// Setting the same port again is a no-op
+	assert.Empty(t, svc.SetProcessPort("web", 4000))
+
+	// Unknown process updates nothing
+	assert.Empty(t, svc.SetProcessPort("missing", 5000))
+}
+
+func TestLookupService_Wildcards(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	services := map[string]config.ServiceConfig{
+		"app":     {Port: 3000, Host: "localhost"},
+		"pr-*":    {PortRange: "4000-4099", Host: "localhost"},
+		"pr-app*": {Port: 5000, Host: "localhost"},
+		"preview-*": {
+			Port: 8080,
+			Host: "{match}.preview.internal",
+		},
+		"*": {Port: 9000, Host: "localhost"},
+	}
+	svc, err := NewService(&config.ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.myapp.dev"}, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+
+	tests := []struct {
+		subdomain string
+		route     string
+		host      string
+		port      int
+	}{
+		{"app", "app", "localhost", 3000},
+		{"pr-0", "pr-*", "localhost", 4000},
+		{"pr-42", "pr-*", "localhost", 4042},
+		{"pr-app1", "pr-app*", "localhost", 5000},
+		{"preview-feature-x", "preview-*", "feature-x.preview.internal", 8080},
+		{"pr-", "*", "localhost", 9000},
+		{"docs", "*", "localhost", 9000},
+	}
+	for _, tt := range tests {
+		route, target, ok := svc.lookupService(tt.subdomain)
+		require.True(t, ok, tt.subdomain)
+		assert.Equal(t, tt.route, route, tt.subdomain)
+		assert.Equal(t, tt.host, target.Host, tt.subdomain)
+		assert.Equal(t, tt.port, target.Port, tt.subdomain)
+	}
+
+	// Matches outside the port range aren't routed to the fallback
+	for _, subdomain := range []string{"pr-100", "pr-abc"} {
+		_, _, ok := svc.lookupService(subdomain)
+		assert.False(t, ok, subdomain)
+	}
+}
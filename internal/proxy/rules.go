@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+)
+
+// RuleKind identifies a dynamic proxy behavior that can be toggled at runtime
+type RuleKind string
+
+const (
+	// RuleKindRewrite applies a service's configured rewrite (see
+	// services.<name>.rewrite) while enabled. Rewrite rules start enabled.
+	RuleKindRewrite RuleKind = "rewrite"
+)
+
+// Rule is a dynamic proxy behavior applied to a service.
+// Rule IDs have the form "<kind>:<service>".
+type Rule struct {
+	ID          string
+	Kind        RuleKind
+	Service     string
+	Description string
+	Enabled     bool
+}
+
+// ruleID builds the ID for a rule of the given kind on a service
+func ruleID(kind RuleKind, service string) string {
+	return string(kind) + ":" + service
+}
+
+// Rules returns all dynamic proxy rules, sorted by ID. Every service with a
+// rewrite configured has a rewrite rule.
+func (s *Service) Rules() []Rule {
+	s.routeMu.Lock()
+	defer s.routeMu.Unlock()
+
+	var rules []Rule
+	for name, svc := range s.services {
+		if svc.Rewrite == nil {
+			continue
+		}
+		rules = append(rules, Rule{
+			ID:          ruleID(RuleKindRewrite, name),
+			Kind:        RuleKindRewrite,
+			Service:     name,
+			Description: describeRewrite(svc.Rewrite),
+			Enabled:     !s.rewritesOff[name],
+		})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+// SetRuleEnabled enables or disables a rule by ID and returns its new state.
+// Returns domain.ErrRuleNotFound if no rule has the given ID.
+func (s *Service) SetRuleEnabled(id string, enabled bool) (Rule, error) {
+	kind, service, _ := strings.Cut(id, ":")
+	if RuleKind(kind) != RuleKindRewrite {
+		return Rule{}, fmt.Errorf("%w: %s", domain.ErrRuleNotFound, id)
+	}
+
+	s.routeMu.Lock()
+	if svc, ok := s.services[service]; !ok || svc.Rewrite == nil {
+		s.routeMu.Unlock()
+		return Rule{}, fmt.Errorf("%w: %s", domain.ErrRuleNotFound, id)
+	}
+	if enabled {
+		delete(s.rewritesOff, service)
+	} else {
+		s.rewritesOff[service] = true
+	}
+	s.routeMu.Unlock()
+
+	s.logger.Info("proxy rule updated", "rule", id, "enabled", enabled)
+
+	for _, rule := range s.Rules() {
+		if rule.ID == id {
+			return rule, nil
+		}
+	}
+	return Rule{}, fmt.Errorf("%w: %s", domain.ErrRuleNotFound, id)
+}
+
+// rewriteEnabled returns false if the service's rewrite rule is disabled.
+func (s *Service) rewriteEnabled(service string) bool {
+	s.routeMu.Lock()
+	defer s.routeMu.Unlock()
+	return !s.rewritesOff[service]
+}
+
+// describeRewrite summarizes a rewrite for the rules list, e.g.
+// "host: target; request: set X-Env, remove Cookie"
+func describeRewrite(rw *config.RewriteConfig) string {
+	var parts []string
+	if rw.HostMode() == config.RewriteHostTarget {
+		parts = append(parts, "host: target")
+	}
+	if h := describeHeaderRewrite(rw.RequestHeaders); h != "" {
+		parts = append(parts, "request: "+h)
+	}
+	if h := describeHeaderRewrite(rw.ResponseHeaders); h != "" {
+		parts = append(parts, "response: "+h)
+	}
+	if len(parts) == 0 {
+		return "No changes"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// describeHeaderRewrite lists the headers a header rewrite sets and removes
+func describeHeaderRewrite(rw *config.HeaderRewrite) string {
+	if rw == nil {
+		return ""
+	}
+	var parts []string
+	if len(rw.Set) > 0 {
+		names := make([]string, 0, len(rw.Set))
+		for name := range rw.Set {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts = append(parts, "set "+strings.Join(names, ", "))
+	}
+	if len(rw.Remove) > 0 {
+		parts = append(parts, "remove "+strings.Join(rw.Remove, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
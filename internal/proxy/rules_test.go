@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRulesTestService creates a proxy service where api and web have
+// rewrites and app has none
+func newRulesTestService(t *testing.T) *Service {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	cfg := &config.ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.myapp.dev"}
+	services := map[string]config.ServiceConfig{
+		"app": {Port: 3000, Host: "localhost"},
+		"api": {Port: 3001, Host: "localhost", Rewrite: &config.RewriteConfig{
+			Host: config.RewriteHostTarget,
+			RequestHeaders: &config.HeaderRewrite{
+				Set:    map[string]string{"X-Env": "dev", "Authorization": "Bearer dev-token"},
+				Remove: []string{"Cookie"},
+			},
+		}},
+		"web": {Port: 3002, Host: "localhost", Rewrite: &config.RewriteConfig{
+			ResponseHeaders: &config.HeaderRewrite{Remove: []string{"Content-Security-Policy"}},
+		}},
+	}
+
+	svc, err := NewService(cfg, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+	return svc
+}
+
+func TestRules(t *testing.T) {
+	svc := newRulesTestService(t)
+
+	rules := svc.Rules()
+	require.Len(t, rules, 2)
+	assert.Equal(t, "rewrite:api", rules[0].ID)
+	assert.Equal(t, "host: target; request: set Authorization, X-Env, remove Cookie", rules[0].Description)
+	assert.Equal(t, "rewrite:web", rules[1].ID)
+	assert.Equal(t, "response: remove Content-Security-Policy", rules[1].Description)
+	for _, rule := range rules {
+		assert.Equal(t, RuleKindRewrite, rule.Kind)
+		assert.True(t, rule.Enabled)
+	}
+
+	// Without rewrites there are no rules
+	assert.Empty(t, newDrainTestService(t, 3000).Rules())
+}
+
+func TestSetRuleEnabled(t *testing.T) {
+	svc := newRulesTestService(t)
+
+	rule, err := svc.SetRuleEnabled("rewrite:api", false)
+	require.NoError(t, err)
+	assert.False(t, rule.Enabled)
+	assert.Equal(t, "api", rule.Service)
+	assert.False(t, svc.rewriteEnabled("api"))
+	assert.True(t, svc.rewriteEnabled("web"))
+
+	rule, err = svc.SetRuleEnabled("rewrite:api", true)
+	require.NoError(t, err)
+	assert.True(t, rule.Enabled)
+	assert.True(t, svc.rewriteEnabled("api"))
+}
+
+func TestSetRuleEnabled_NotFound(t *testing.T) {
+	svc := newRulesTestService(t)
+
+	for _, id := range []string{"rewrite:missing", "rewrite:app", "maintenance:api", "api", ""} {
+		_, err := svc.SetRuleEnabled(id, false)
+		assert.ErrorIs(t, err, domain.ErrRuleNotFound, id)
+	}
+}
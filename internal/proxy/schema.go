@@ -0,0 +1,348 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/constants"
+)
+
+// Schema is a compiled JSON Schema used to check captured response bodies.
+//
+// Only the commonly used validation keywords are supported: type, enum,
+// const, properties, required, additionalProperties, items, minItems,
+// maxItems, minLength, maxLength, pattern, minimum, and maximum. Other
+// keywords (including $ref and format) are ignored.
+type Schema struct {
+	types                []string
+	enum                 []any
+	constValue           any
+	hasConst             bool
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema // nil allows anything
+	noAdditional         bool    // additionalProperties: false
+	items                *Schema
+	minItems, maxItems   *int
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minimum, maximum     *float64
+}
+
+// CompileSchema parses a JSON Schema document.
+func CompileSchema(data []byte) (*Schema, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+	return compileSchema(doc, "$")
+}
+
+func compileSchema(doc any, path string) (*Schema, error) {
+	switch v := doc.(type) {
+	case bool:
+		// true accepts anything; false is modeled as an impossible type
+		if v {
+			return &Schema{}, nil
+		}
+		return &Schema{types: []string{}}, nil
+	case map[string]any:
+		return compileSchemaObject(v, path)
+	default:
+		return nil, fmt.Errorf("%s: schema must be an object or boolean", path)
+	}
+}
+
+func compileSchemaObject(m map[string]any, path string) (*Schema, error) {
+	s := &Schema{}
+
+	switch t := m["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []any:
+		s.types = []string{}
+		for _, item := range t {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s.type: must be a string or array of strings", path)
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, fmt.Errorf("%s.type: must be a string or array of strings", path)
+	}
+
+	if enum, ok := m["enum"].([]any); ok {
+		s.enum = enum
+	}
+	if c, ok := m["const"]; ok {
+		s.constValue = c
+		s.hasConst = true
+	}
+
+	if props, ok := m["properties"].(map[string]any); ok {
+		s.properties = make(map[string]*Schema, len(props))
+		for name, sub := range props {
+			compiled, err := compileSchema(sub, path+"."+name)
+			if err != nil {
+				return nil, err
+			}
+			s.properties[name] = compiled
+		}
+	}
+	if required, ok := m["required"].([]any); ok {
+		for _, item := range required {
+			if name, ok := item.(string); ok {
+				s.required = append(s.required, name)
+			}
+		}
+	}
+	switch ap := m["additionalProperties"].(type) {
+	case nil:
+	case bool:
+		s.noAdditional = !ap
+	default:
+		compiled, err := compileSchema(ap, path+".additionalProperties")
+		if err != nil {
+			return nil, err
+		}
+		s.additionalProperties = compiled
+	}
+
+	if items, ok := m["items"]; ok {
+		compiled, err := compileSchema(items, path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		s.items = compiled
+	}
+
+	s.minItems = intKeyword(m, "minItems")
+	s.maxItems = intKeyword(m, "maxItems")
+	s.minLength = intKeyword(m, "minLength")
+	s.maxLength = intKeyword(m, "maxLength")
+	s.minimum = floatKeyword(m, "minimum")
+	s.maximum = floatKeyword(m, "maximum")
+
+	if p, ok := m["pattern"].(string); ok {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s.pattern: invalid regex %q", path, p)
+		}
+		s.pattern = re
+	}
+
+	return s, nil
+}
+
+func intKeyword(m map[string]any, key string) *int {
+	if f, ok := m[key].(float64); ok {
+		n := int(f)
+		return &n
+	}
+	return nil
+}
+
+func floatKeyword(m map[string]any, key string) *float64 {
+	if f, ok := m[key].(float64); ok {
+		return &f
+	}
+	return nil
+}
+
+// Validate checks a decoded JSON value against the schema and returns a
+// description of each violation, at most constants.MaxSchemaViolations.
+func (s *Schema) Validate(value any) []string {
+	var violations []string
+	s.validate(value, "$", &violations)
+	return violations
+}
+
+func (s *Schema) validate(value any, path string, violations *[]string) {
+	if len(*violations) >= constants.MaxSchemaViolations {
+		return
+	}
+	report := func(format string, args ...any) {
+		if len(*violations) < constants.MaxSchemaViolations {
+			*violations = append(*violations, path+": "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	if s.types != nil && !matchesAnyType(value, s.types) {
+		if len(s.types) == 0 {
+			report("no value allowed")
+		} else {
+			report("expected %s, got %s", strings.Join(s.types, " or "), jsonTypeName(value))
+		}
+		return
+	}
+	if s.enum != nil && !containsValue(s.enum, value) {
+		report("value not in enum")
+	}
+	if s.hasConst && !reflect.DeepEqual(s.constValue, value) {
+		report("value does not match const")
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				report("missing required property %q", name)
+			}
+		}
+		// Visit properties in a stable order so violations are deterministic
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sub, ok := s.properties[name]; ok {
+				sub.validate(v[name], path+"."+name, violations)
+			} else if s.noAdditional {
+				report("unexpected property %q", name)
+			} else if s.additionalProperties != nil {
+				s.additionalProperties.validate(v[name], path+"."+name, violations)
+			}
+		}
+	case []any:
+		if s.minItems != nil && len(v) < *s.minItems {
+			report("expected at least %d items, got %d", *s.minItems, len(v))
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			report("expected at most %d items, got %d", *s.maxItems, len(v))
+		}
+		if s.items != nil {
+			for i, item := range v {
+				s.items.validate(item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength != nil && length < *s.minLength {
+			report("expected length >= %d, got %d", *s.minLength, length)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			report("expected length <= %d, got %d", *s.maxLength, length)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			report("does not match pattern %q", s.pattern.String())
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			report("expected >= %v, got %v", *s.minimum, v)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			report("expected <= %v, got %v", *s.maximum, v)
+		}
+	}
+}
+
+// jsonTypeName returns the JSON Schema type name of a decoded JSON value
+func jsonTypeName(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func matchesAnyType(value any, types []string) bool {
+	actual := jsonTypeName(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func containsValue(values []any, value any) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseSchema is a service's response schema and its sampling rate
+type responseSchema struct {
+	schema *Schema
+	sample float64
+}
+
+// loadResponseSchemas compiles the response schemas declared by services.
+// Relative schema paths are resolved against workDir.
+func loadResponseSchemas(services map[string]config.ServiceConfig, workDir string) (map[string]*responseSchema, error) {
+	schemas := make(map[string]*responseSchema)
+	for name, svc := range services {
+		if svc.Schema == nil {
+			continue
+		}
+		path := svc.Schema.File
+		if !filepath.IsAbs(path) && workDir != "" {
+			path = filepath.Join(workDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("services.%s.schema: %w", name, err)
+		}
+		schema, err := CompileSchema(data)
+		if err != nil {
+			return nil, fmt.Errorf("services.%s.schema: %s: %w", name, svc.Schema.File, err)
+		}
+		schemas[name] = &responseSchema{schema: schema, sample: svc.Schema.SampleRate()}
+	}
+	return schemas, nil
+}
+
+// checkResponseSchema validates a captured response body against the
+// service's schema. Only sampled, untruncated 2xx JSON responses are checked.
+func (s *Service) checkResponseSchema(service string, statusCode int, body *CapturedBody) []string {
+	rs, ok := s.schemas[service]
+	if !ok || body == nil || statusCode < 200 || statusCode > 299 {
+		return nil
+	}
+	if body.Truncated || body.IsBinary || !strings.Contains(body.ContentType, "json") {
+		return nil
+	}
+	if rs.sample < 1 && rand.Float64() >= rs.sample {
+		return nil
+	}
+
+	data, err := s.captureManager.LoadBody(body)
+	if err != nil {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return []string{"$: invalid JSON: " + err.Error()}
+	}
+	return rs.schema.Validate(value)
+}
@@ -0,0 +1,204 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["id", "name"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "integer", "minimum": 1},
+		"name": {"type": "string", "minLength": 1, "maxLength": 10},
+		"role": {"enum": ["admin", "user"]},
+		"email": {"type": "string", "pattern": "^[^@]+@[^@]+$"},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+		"score": {"type": ["number", "null"], "maximum": 100}
+	}
+}`
+
+func validateJSON(t *testing.T, schema *Schema, doc string) []string {
+	t.Helper()
+	var value any
+	require.NoError(t, json.Unmarshal([]byte(doc), &value))
+	return schema.Validate(value)
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := CompileSchema([]byte(userSchema))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{"valid minimal", `{"id": 1, "name": "ann"}`, nil},
+		{"valid full", `{"id": 2, "name": "bob", "role": "admin", "email": "b@x.dev", "tags": ["a"], "score": 9.5}`, nil},
+		{"null allowed by type list", `{"id": 1, "name": "ann", "score": null}`, nil},
+		{"wrong root type", `[]`, []string{"$: expected object, got array"}},
+		{"missing required", `{"id": 1}`, []string{`$: missing required property "name"`}},
+		{"integer required", `{"id": 1.5, "name": "ann"}`, []string{"$.id: expected integer, got number"}},
+		{"below minimum", `{"id": 0, "name": "ann"}`, []string{"$.id: expected >= 1, got 0"}},
+		{"above maximum", `{"id": 1, "name": "ann", "score": 101}`, []string{"$.score: expected <= 100, got 101"}},
+		{"too short", `{"id": 1, "name": ""}`, []string{"$.name: expected length >= 1, got 0"}},
+		{"too long", `{"id": 1, "name": "abcdefghijk"}`, []string{"$.name: expected length <= 10, got 11"}},
+		{"not in enum", `{"id": 1, "name": "ann", "role": "root"}`, []string{"$.role: value not in enum"}},
+		{"pattern mismatch", `{"id": 1, "name": "ann", "email": "nope"}`, []string{`$.email: does not match pattern "^[^@]+@[^@]+$"`}},
+		{"unexpected property", `{"id": 1, "name": "ann", "extra": true}`, []string{`$: unexpected property "extra"`}},
+		{"bad array item", `{"id": 1, "name": "ann", "tags": ["a", 2]}`, []string{"$.tags[1]: expected string, got integer"}},
+		{"too many items", `{"id": 1, "name": "ann", "tags": ["a", "b", "c"]}`, []string{"$.tags: expected at most 2 items, got 3"}},
+		{
+			"multiple violations",
+			`{"id": "x", "name": 5}`,
+			[]string{"$.id: expected integer, got string", "$.name: expected string, got integer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validateJSON(t, schema, tt.doc))
+		})
+	}
+}
+
+func TestSchemaValidate_BooleanSchemas(t *testing.T) {
+	schema, err := CompileSchema([]byte(`{"properties": {"any": true, "never": false}, "additionalProperties": {"type": "integer"}}`))
+	require.NoError(t, err)
+
+	assert.Empty(t, validateJSON(t, schema, `{"any": [1, "x"], "count": 3}`))
+	assert.Equal(t, []string{"$.never: no value allowed"}, validateJSON(t, schema, `{"never": 1}`))
+	assert.Equal(t, []string{"$.count: expected integer, got string"}, validateJSON(t, schema, `{"count": "3"}`))
+}
+
+func TestSchemaValidate_CapsViolations(t *testing.T) {
+	schema, err := CompileSchema([]byte(`{"type": "array", "items": {"type": "string"}}`))
+	require.NoError(t, err)
+
+	violations := validateJSON(t, schema, `[1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12]`)
+	assert.Len(t, violations, constants.MaxSchemaViolations)
+}
+
+func TestCompileSchema_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		errMsg string
+	}{
+		{"invalid json", `{`, "parsing schema"},
+		{"non-object schema", `{"properties": {"id": 5}}`, "$.id: schema must be an object or boolean"},
+		{"bad type", `{"type": 5}`, "$.type"},
+		{"bad pattern", `{"pattern": "("}`, "$.pattern: invalid regex"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileSchema([]byte(tt.schema))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestNewService_SchemaErrors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	workDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "bad.json"), []byte(`{"type": 5}`), 0o644))
+
+	t.Run("missing schema file", func(t *testing.T) {
+		services := map[string]config.ServiceConfig{
+			"api": {Port: 3000, Host: "localhost", Schema: &config.SchemaConfig{File: "missing.json"}},
+		}
+		_, err := NewService(nil, services, nil, logger, workDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "services.api.schema")
+	})
+
+	t.Run("invalid schema", func(t *testing.T) {
+		services := map[string]config.ServiceConfig{
+			"api": {Port: 3000, Host: "localhost", Schema: &config.SchemaConfig{File: "bad.json"}},
+		}
+		_, err := NewService(nil, services, nil, logger, workDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "bad.json")
+	})
+}
+
+func TestCreateRouter_SchemaValidation(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	workDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "user.json"), []byte(userSchema), 0o644))
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprint(w, "hello")
+			return
+		case "/error":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"error": "boom"}`)
+			return
+		case "/broken":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.URL.Path == "/valid" {
+			fmt.Fprint(w, `{"id": 1, "name": "ann"}`)
+		} else {
+			fmt.Fprint(w, `{"id": 0}`)
+		}
+	}))
+	defer backend.Close()
+	backendPort := backend.Listener.Addr().(*net.TCPAddr).Port
+
+	cfg := &config.ProxyConfig{
+		Enabled:  true,
+		HTTPPort: 6788,
+		Domain:   "local.myapp.dev",
+		Capture:  &config.CaptureConfig{Enabled: true},
+	}
+	services := map[string]config.ServiceConfig{
+		"app": {Port: backendPort, Host: "localhost", Schema: &config.SchemaConfig{File: "user.json"}},
+	}
+	svc, err := NewService(cfg, services, nil, logger, workDir)
+	require.NoError(t, err)
+	router := svc.createRouter()
+
+	violationsFor := func(path string) []string {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Host = "app.local.myapp.dev:6788"
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		records := svc.RequestManager().Recent(RequestFilter{Limit: 1})
+		require.Len(t, records, 1)
+		return records[0].SchemaViolations
+	}
+
+	assert.Empty(t, violationsFor("/valid"))
+	assert.Equal(t, []string{`$: missing required property "name"`, "$.id: expected >= 1, got 0"}, violationsFor("/invalid"))
+	assert.Len(t, violationsFor("/broken"), 1)
+	// Non-JSON and non-2xx responses are not validated
+	assert.Empty(t, violationsFor("/text"))
+	assert.Empty(t, violationsFor("/error"))
+
+	stats := svc.StatsTracker().Stats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, 2, stats[0].SchemaViolations)
+}
@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/charliek/prox/internal/constants"
+)
+
+// SessionVersion is the format version of saved request sessions
+const SessionVersion = 1
+
+// ErrInvalidSession is returned when a session archive can't be loaded
+var ErrInvalidSession = errors.New("invalid session archive")
+
+// Session is a portable copy of the request history, with captured bodies
+// inlined, so the requests can be loaded into another prox instance
+type Session struct {
+	Version  int             `json:"version"`
+	SavedAt  time.Time       `json:"saved_at"`
+	Requests []RequestRecord `json:"requests"` // Oldest first
+}
+
+// ExportSession copies the request history into a session. Bodies stored on
+// disk are read into the session; cm may be nil when capture is disabled.
+func ExportSession(rm *RequestManager, cm *CaptureManager) *Session {
+	recent := rm.Recent(RequestFilter{})
+
+	session := &Session{
+		Version:  SessionVersion,
+		SavedAt:  time.Now(),
+		Requests: make([]RequestRecord, 0, len(recent)),
+	}
+	for i := len(recent) - 1; i >= 0; i-- {
+		record := recent[i]
+		if record.Details != nil {
+			details := *record.Details
+			details.RequestBody = inlineBody(cm, details.RequestBody)
+			details.ResponseBody = inlineBody(cm, details.ResponseBody)
+			record.Details = &details
+		}
+		session.Requests = append(session.Requests, record)
+	}
+	return session
+}
+
+// inlineBody returns a copy of body with its data held inline
+func inlineBody(cm *CaptureManager, body *CapturedBody) *CapturedBody {
+	if body == nil {
+		return nil
+	}
+	inlined := *body
+	inlined.FilePath = ""
+	if cm != nil {
+		// A body whose file is gone is saved without data
+		inlined.Data, _ = cm.LoadBody(body)
+	}
+	return &inlined
+}
+
+// WriteSession writes a session as a gzip-compressed JSON archive
+func WriteSession(w io.Writer, session *Session) error {
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(session); err != nil {
+		return fmt.Errorf("encoding session: %w", err)
+	}
+	return zw.Close()
+}
+
+// ReadSession reads a session archive written by WriteSession
+func ReadSession(r io.Reader) (*Session, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSession, err)
+	}
+	defer zr.Close()
+
+	var session Session
+	limited := io.LimitReader(zr, constants.MaxSessionSize+1)
+	data, err := io.ReadAll(limited)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSession, err)
+	}
+	if len(data) > constants.MaxSessionSize {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidSession, constants.MaxSessionSize)
+	}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSession, err)
+	}
+	if session.Version != SessionVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSession, session.Version)
+	}
+	return &session, nil
+}
+
+// Import adds the requests of a loaded session to the history, marked as
+// imported, and returns how many were added. Requests already in the
+// history are skipped. Subscribers aren't notified since the requests
+// aren't live traffic.
+func (m *RequestManager) Import(records []RequestRecord) int {
+	added := 0
+	for _, record := range records {
+		if record.ID == "" {
+			record.ID = generateRequestID(record.Timestamp, record.Method, record.URL)
+		}
+		if _, exists := m.GetByID(record.ID); exists {
+			continue
+		}
+
+		record.Imported = true
+		if record.Details != nil {
+			// Bodies are only ever read from the archive, never from paths
+			// it names
+			details := *record.Details
+			details.RequestBody = importedBody(details.RequestBody)
+			details.ResponseBody = importedBody(details.ResponseBody)
+			record.Details = &details
+		}
+		m.add(record)
+		added++
+	}
+	return added
+}
+
+// importedBody returns a copy of an imported body without a file path
+func importedBody(body *CapturedBody) *CapturedBody {
+	if body == nil {
+		return nil
+	}
+	imported := *body
+	imported.FilePath = ""
+	return &imported
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	bodyPath := filepath.Join(dir, "abc_res.bin")
+	require.NoError(t, os.WriteFile(bodyPath, []byte(`{"large":true}`), 0600))
+
+	cm := &CaptureManager{enabled: true, captureDir: dir}
+	rm := NewRequestManager(10)
+	start := time.Now()
+	rm.Record(RequestRecord{ID: "aaa", Timestamp: start, Method: "GET", URL: "/first", StatusCode: 200})
+	rm.Record(RequestRecord{
+		ID:         "bbb",
+		Timestamp:  start.Add(time.Second),
+		Method:     "POST",
+		URL:        "/second",
+		StatusCode: 500,
+		Details: &RequestDetails{
+			RequestHeaders: map[string][]string{"Content-Type": {"application/json"}},
+			RequestBody:    &CapturedBody{Size: 2, Data: []byte("{}")},
+			ResponseBody:   &CapturedBody{Size: 14, FilePath: bodyPath},
+		},
+	})
+
+	session := ExportSession(rm, cm)
+	require.Len(t, session.Requests, 2)
+	assert.Equal(t, "aaa", session.Requests[0].ID, "requests should be oldest first")
+
+	// The on-disk body is inlined without touching the recorded request
+	body := session.Requests[1].Details.ResponseBody
+	assert.Equal(t, []byte(`{"large":true}`), body.Data)
+	assert.Empty(t, body.FilePath)
+	live, _ := rm.GetByID("bbb")
+	assert.Equal(t, bodyPath, live.Details.ResponseBody.FilePath)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSession(&buf, session))
+	loaded, err := ReadSession(&buf)
+	require.NoError(t, err)
+
+	other := NewRequestManager(10)
+	assert.Equal(t, 2, other.Import(loaded.Requests))
+	assert.Equal(t, 0, other.Import(loaded.Requests), "loading twice should skip known requests")
+
+	record, ok := other.GetByID("bbb")
+	require.True(t, ok)
+	assert.True(t, record.Imported)
+	assert.Equal(t, 500, record.StatusCode)
+	assert.Equal(t, []byte(`{"large":true}`), record.Details.ResponseBody.Data)
+	assert.Equal(t, []byte("{}"), record.Details.RequestBody.Data)
+}
+
+func TestRequestManager_ImportIgnoresFilePaths(t *testing.T) {
+	rm := NewRequestManager(10)
+	rm.Import([]RequestRecord{{
+		ID:      "ccc",
+		Details: &RequestDetails{ResponseBody: &CapturedBody{Size: 10, FilePath: "/etc/passwd"}},
+	}})
+
+	record, ok := rm.GetByID("ccc")
+	require.True(t, ok)
+	assert.Empty(t, record.Details.ResponseBody.FilePath)
+}
+
+func TestRequestManager_ImportDoesNotNotify(t *testing.T) {
+	rm := NewRequestManager(10)
+	sub := rm.Subscribe(RequestFilter{})
+	defer rm.Unsubscribe(sub.ID)
+
+	rm.Import([]RequestRecord{{ID: "ddd", Method: "GET", URL: "/"}})
+
+	select {
+	case record := <-sub.Ch:
+		t.Fatalf("unexpected notification for %s", record.ID)
+	default:
+	}
+}
+
+func TestReadSession_Invalid(t *testing.T) {
+	_, err := ReadSession(bytes.NewReader([]byte("not gzip")))
+	assert.ErrorIs(t, err, ErrInvalidSession)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(`{"version":99,"requests":[]}`))
+	require.NoError(t, zw.Close())
+	_, err = ReadSession(&buf)
+	assert.ErrorIs(t, err, ErrInvalidSession)
+}
@@ -0,0 +1,298 @@
+package proxy
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/charliek/prox/internal/constants"
+)
+
+// ServiceStats summarizes proxied traffic for a single service over the
+// rolling stats window.
+type ServiceStats struct {
+	Subdomain string
+	Count     int
+	Errors    int // Responses with a 5xx status code
+	P50       time.Duration
+	P95       time.Duration
+	P99       time.Duration
+
+	// SchemaViolations counts responses that failed the service's JSON Schema
+	SchemaViolations int
+
+	// Budget is the configured p95 latency budget (0 when none is configured)
+	Budget time.Duration
+	// OverBudget is true when the rolling p95 exceeds the budget
+	OverBudget bool
+
+	// Events are the lifecycle events of the service's process within the
+	// window, oldest first, so error spikes can be attributed to restarts
+	Events []ProcessEvent
+}
+
+// Process lifecycle event types recorded for stats
+const (
+	ProcessEventStarted = "started"
+	ProcessEventStopped = "stopped"
+	ProcessEventCrashed = "crashed"
+)
+
+// ProcessEvent is a lifecycle event of the process serving a service
+type ProcessEvent struct {
+	Process string
+	Type    string // ProcessEventStarted, ProcessEventStopped, or ProcessEventCrashed
+	Time    time.Time
+	// ErrorsAfter counts the service's 5xx responses in the
+	// constants.ProxyStatsEventErrorWindow following the event
+	ErrorsAfter int
+}
+
+// maxProcessEvents bounds the events kept per service, in case a process
+// crash-loops
+const maxProcessEvents = 100
+
+// ViolationCallback is called once when a service has stayed over its latency
+// budget for the sustained violation period.
+type ViolationCallback func(stats ServiceStats, since time.Time)
+
+// StatsTracker computes rolling per-service latency stats from the request
+// buffer and detects sustained latency budget violations.
+type StatsTracker struct {
+	requests *RequestManager
+	budgets  map[string]time.Duration
+	window   time.Duration
+	period   time.Duration
+
+	mu          sync.Mutex
+	violations  map[string]*violation
+	onViolation ViolationCallback
+
+	// eventsMu guards events separately, since stats are computed with mu held
+	eventsMu sync.Mutex
+	events   map[string][]ProcessEvent // By subdomain, oldest first
+}
+
+// violation tracks an ongoing budget violation for a service.
+type violation struct {
+	since    time.Time
+	reported bool
+}
+
+// NewStatsTracker creates a stats tracker over the given request manager.
+// budgets maps service subdomains to their p95 latency budget.
+func NewStatsTracker(requests *RequestManager, budgets map[string]time.Duration) *StatsTracker {
+	if budgets == nil {
+		budgets = make(map[string]time.Duration)
+	}
+	return &StatsTracker{
+		requests:   requests,
+		budgets:    budgets,
+		window:     constants.DefaultProxyStatsWindow,
+		period:     constants.DefaultSLOViolationPeriod,
+		violations: make(map[string]*violation),
+		events:     make(map[string][]ProcessEvent),
+	}
+}
+
+// RecordProcessEvent records a lifecycle event of the process serving the
+// services with the given subdomains. Events older than the window are
+// dropped.
+func (t *StatsTracker) RecordProcessEvent(subdomains []string, event ProcessEvent) {
+	t.eventsMu.Lock()
+	defer t.eventsMu.Unlock()
+
+	cutoff := event.Time.Add(-t.window)
+	for _, name := range subdomains {
+		events := append(t.events[name], event)
+		for len(events) > 0 && (events[0].Time.Before(cutoff) || len(events) > maxProcessEvents) {
+			events = events[1:]
+		}
+		t.events[name] = events
+	}
+}
+
+// SetViolationCallback sets the callback invoked on sustained budget violations.
+func (t *StatsTracker) SetViolationCallback(fn ViolationCallback) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onViolation = fn
+}
+
+// Window returns the rolling window used to compute stats.
+func (t *StatsTracker) Window() time.Duration {
+	return t.window
+}
+
+// HasBudgets returns true if any service has a latency budget configured.
+func (t *StatsTracker) HasBudgets() bool {
+	return len(t.budgets) > 0
+}
+
+// Stats returns per-service stats for requests within the rolling window,
+// sorted by subdomain. Services with a budget are included even without traffic.
+func (t *StatsTracker) Stats() []ServiceStats {
+	return t.statsAt(time.Now())
+}
+
+func (t *StatsTracker) statsAt(now time.Time) []ServiceStats {
+	records := t.requests.Recent(RequestFilter{Since: now.Add(-t.window)})
+
+	durations := make(map[string][]time.Duration)
+	errors := make(map[string]int)
+	schemaViolations := make(map[string]int)
+	for _, r := range records {
+		// The duration of an upgraded WebSocket is its connection's lifetime,
+		// not a response latency
+		if r.Subdomain == "" || r.WebSocket != nil {
+			continue
+		}
+		durations[r.Subdomain] = append(durations[r.Subdomain], r.Duration)
+		if r.StatusCode >= 500 {
+			errors[r.Subdomain]++
+		}
+		if len(r.SchemaViolations) > 0 {
+			schemaViolations[r.Subdomain]++
+		}
+	}
+	for name := range t.budgets {
+		if _, ok := durations[name]; !ok {
+			durations[name] = nil
+		}
+	}
+	// Services whose process restarted appear even without traffic
+	events := t.eventsSince(now.Add(-t.window))
+	for name, es := range events {
+		for i, e := range es {
+			es[i].ErrorsAfter = errorsBetween(records, name, e.Time, e.Time.Add(constants.ProxyStatsEventErrorWindow))
+		}
+		if _, ok := durations[name]; !ok {
+			durations[name] = nil
+		}
+	}
+
+	result := make([]ServiceStats, 0, len(durations))
+	for name, ds := range durations {
+		sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+		stats := ServiceStats{
+			Subdomain:        name,
+			Count:            len(ds),
+			Errors:           errors[name],
+			SchemaViolations: schemaViolations[name],
+			P50:              percentile(ds, 0.50),
+			P95:              percentile(ds, 0.95),
+			P99:              percentile(ds, 0.99),
+			Budget:           t.budgets[name],
+			Events:           events[name],
+		}
+		stats.OverBudget = stats.Budget > 0 && stats.Count > 0 && stats.P95 > stats.Budget
+		result = append(result, stats)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Subdomain < result[j].Subdomain })
+	return result
+}
+
+// eventsSince returns copies of the process events at or after since, by
+// subdomain
+func (t *StatsTracker) eventsSince(since time.Time) map[string][]ProcessEvent {
+	t.eventsMu.Lock()
+	defer t.eventsMu.Unlock()
+
+	result := make(map[string][]ProcessEvent, len(t.events))
+	for name, events := range t.events {
+		for _, e := range events {
+			if !e.Time.Before(since) {
+				result[name] = append(result[name], e)
+			}
+		}
+	}
+	return result
+}
+
+// errorsBetween counts the 5xx responses to a subdomain's requests that
+// started in [from, to)
+func errorsBetween(records []RequestRecord, subdomain string, from, to time.Time) int {
+	n := 0
+	for _, r := range records {
+		if r.Subdomain == subdomain && r.StatusCode >= 500 && !r.Timestamp.Before(from) && r.Timestamp.Before(to) {
+			n++
+		}
+	}
+	return n
+}
+
+// Check evaluates latency budgets and invokes the violation callback for
+// services that have been over budget for the sustained violation period.
+// Each violation is reported once; a service must recover before it can
+// be reported again.
+func (t *StatsTracker) Check() {
+	t.checkAt(time.Now())
+}
+
+func (t *StatsTracker) checkAt(now time.Time) {
+	if !t.HasBudgets() {
+		return
+	}
+
+	type report struct {
+		stats ServiceStats
+		since time.Time
+	}
+	var reports []report
+
+	t.mu.Lock()
+	for _, stats := range t.statsAt(now) {
+		if !stats.OverBudget {
+			delete(t.violations, stats.Subdomain)
+			continue
+		}
+		v, ok := t.violations[stats.Subdomain]
+		if !ok {
+			v = &violation{since: now}
+			t.violations[stats.Subdomain] = v
+		}
+		if !v.reported && now.Sub(v.since) >= t.period {
+			v.reported = true
+			reports = append(reports, report{stats: stats, since: v.since})
+		}
+	}
+	onViolation := t.onViolation
+	t.mu.Unlock()
+
+	// Call violation callback outside of lock
+	if onViolation != nil {
+		for _, r := range reports {
+			onViolation(r.stats, r.since)
+		}
+	}
+}
+
+// Run periodically checks latency budgets until ctx is cancelled.
+func (t *StatsTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(constants.DefaultProxyStatsCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.Check()
+		}
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
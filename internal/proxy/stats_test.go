@@ -0,0 +1,194 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recordDurations(m *RequestManager, subdomain string, ts time.Time, durations ...time.Duration) {
+	for i, d := range durations {
+		m.Record(RequestRecord{
+			Timestamp:  ts.Add(time.Duration(i) * time.Millisecond),
+			Method:     "GET",
+			URL:        "/",
+			Subdomain:  subdomain,
+			StatusCode: 200,
+			Duration:   d,
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	assert.Equal(t, 50*time.Millisecond, percentile(sorted, 0.50))
+	assert.Equal(t, 95*time.Millisecond, percentile(sorted, 0.95))
+	assert.Equal(t, 99*time.Millisecond, percentile(sorted, 0.99))
+	assert.Equal(t, time.Duration(0), percentile(nil, 0.95))
+	assert.Equal(t, 7*time.Millisecond, percentile([]time.Duration{7 * time.Millisecond}, 0.95))
+}
+
+func TestStatsTracker_Stats(t *testing.T) {
+	m := NewRequestManager(100)
+	now := time.Now()
+
+	recordDurations(m, "api", now, 10*time.Millisecond, 20*time.Millisecond, 500*time.Millisecond)
+	recordDurations(m, "app", now, 5*time.Millisecond)
+	m.Record(RequestRecord{Timestamp: now, Subdomain: "app", StatusCode: 502, Duration: time.Millisecond})
+	m.Record(RequestRecord{Timestamp: now, Subdomain: "app", StatusCode: 200, SchemaViolations: []string{"$.id: missing"}})
+	// Requests without a subdomain are not attributed to any service
+	m.Record(RequestRecord{Timestamp: now, StatusCode: 404})
+	// Requests outside the window are ignored
+	recordDurations(m, "api", now.Add(-time.Hour), 10*time.Second)
+
+	tracker := NewStatsTracker(m, map[string]time.Duration{
+		"api":  100 * time.Millisecond,
+		"idle": 100 * time.Millisecond,
+	})
+	stats := tracker.statsAt(now.Add(time.Second))
+	require.Len(t, stats, 3)
+
+	assert.Equal(t, "api", stats[0].Subdomain)
+	assert.Equal(t, 3, stats[0].Count)
+	assert.Equal(t, 20*time.Millisecond, stats[0].P50)
+	assert.Equal(t, 500*time.Millisecond, stats[0].P95)
+	assert.Equal(t, 100*time.Millisecond, stats[0].Budget)
+	assert.True(t, stats[0].OverBudget)
+
+	assert.Equal(t, "app", stats[1].Subdomain)
+	assert.Equal(t, 3, stats[1].Count)
+	assert.Equal(t, 1, stats[1].Errors)
+	assert.Equal(t, 1, stats[1].SchemaViolations)
+	assert.Equal(t, time.Duration(0), stats[1].Budget)
+	assert.False(t, stats[1].OverBudget)
+
+	// Budgeted services appear even without traffic
+	assert.Equal(t, "idle", stats[2].Subdomain)
+	assert.Equal(t, 0, stats[2].Count)
+	assert.False(t, stats[2].OverBudget)
+}
+
+func TestStatsTracker_ProcessEvents(t *testing.T) {
+	m := NewRequestManager(100)
+	now := time.Now()
+	crash := now.Add(-time.Minute)
+
+	// Errors before the crash and after the error window aren't attributed
+	m.Record(RequestRecord{Timestamp: crash.Add(-time.Second), Subdomain: "api", StatusCode: 502})
+	m.Record(RequestRecord{Timestamp: crash.Add(time.Second), Subdomain: "api", StatusCode: 502})
+	m.Record(RequestRecord{Timestamp: crash.Add(2 * time.Second), Subdomain: "api", StatusCode: 503})
+	m.Record(RequestRecord{Timestamp: crash.Add(3 * time.Second), Subdomain: "api", StatusCode: 200})
+	m.Record(RequestRecord{Timestamp: crash.Add(30 * time.Second), Subdomain: "api", StatusCode: 502})
+
+	tracker := NewStatsTracker(m, nil)
+	tracker.RecordProcessEvent([]string{"api", "admin"}, ProcessEvent{Process: "web", Type: ProcessEventStarted, Time: now.Add(-time.Hour)})
+	tracker.RecordProcessEvent([]string{"api", "admin"}, ProcessEvent{Process: "web", Type: ProcessEventCrashed, Time: crash})
+	tracker.RecordProcessEvent([]string{"api", "admin"}, ProcessEvent{Process: "web", Type: ProcessEventStarted, Time: crash.Add(5 * time.Second)})
+
+	stats := tracker.statsAt(now)
+	require.Len(t, stats, 2)
+
+	// Services with events appear even without traffic
+	assert.Equal(t, "admin", stats[0].Subdomain)
+	assert.Equal(t, 0, stats[0].Count)
+	require.Len(t, stats[0].Events, 2)
+
+	// The start an hour ago is outside the window
+	assert.Equal(t, "api", stats[1].Subdomain)
+	require.Len(t, stats[1].Events, 2)
+	assert.Equal(t, ProcessEventCrashed, stats[1].Events[0].Type)
+	assert.Equal(t, "web", stats[1].Events[0].Process)
+	assert.Equal(t, 2, stats[1].Events[0].ErrorsAfter)
+	assert.Equal(t, ProcessEventStarted, stats[1].Events[1].Type)
+	assert.Equal(t, 1, stats[1].Events[1].ErrorsAfter)
+}
+
+func TestStatsTracker_ProcessEventsBounded(t *testing.T) {
+	tracker := NewStatsTracker(NewRequestManager(10), nil)
+	now := time.Now()
+	for i := 0; i < maxProcessEvents+10; i++ {
+		tracker.RecordProcessEvent([]string{"api"}, ProcessEvent{Process: "web", Type: ProcessEventCrashed, Time: now.Add(time.Duration(i) * time.Millisecond)})
+	}
+
+	events := tracker.eventsSince(now.Add(-time.Hour))["api"]
+	require.Len(t, events, maxProcessEvents)
+	assert.Equal(t, now.Add(10*time.Millisecond), events[0].Time)
+}
+
+func TestStatsTracker_SustainedViolation(t *testing.T) {
+	m := NewRequestManager(100)
+	now := time.Now()
+	recordDurations(m, "api", now, 400*time.Millisecond, 450*time.Millisecond)
+
+	tracker := NewStatsTracker(m, map[string]time.Duration{"api": 300 * time.Millisecond})
+
+	var reported []ServiceStats
+	tracker.SetViolationCallback(func(stats ServiceStats, since time.Time) {
+		reported = append(reported, stats)
+	})
+
+	// First over-budget check starts the violation but does not report it
+	tracker.checkAt(now)
+	assert.Empty(t, reported)
+
+	// Still within the sustained period
+	tracker.checkAt(now.Add(tracker.period / 2))
+	assert.Empty(t, reported)
+
+	// Sustained violation is reported once
+	tracker.checkAt(now.Add(tracker.period))
+	require.Len(t, reported, 1)
+	assert.Equal(t, "api", reported[0].Subdomain)
+
+	tracker.checkAt(now.Add(tracker.period + time.Second))
+	assert.Len(t, reported, 1)
+}
+
+func TestStatsTracker_ViolationResetsOnRecovery(t *testing.T) {
+	m := NewRequestManager(100)
+	now := time.Now()
+	recordDurations(m, "api", now, 400*time.Millisecond)
+
+	tracker := NewStatsTracker(m, map[string]time.Duration{"api": 300 * time.Millisecond})
+
+	calls := 0
+	tracker.SetViolationCallback(func(stats ServiceStats, since time.Time) {
+		calls++
+	})
+
+	tracker.checkAt(now)
+	tracker.checkAt(now.Add(tracker.period))
+	assert.Equal(t, 1, calls)
+
+	// Once the slow requests leave the window the violation clears
+	later := now.Add(tracker.window + time.Minute)
+	tracker.checkAt(later)
+	assert.Empty(t, tracker.violations)
+
+	// A new sustained violation is reported again
+	recordDurations(m, "api", later, 500*time.Millisecond)
+	tracker.checkAt(later)
+	tracker.checkAt(later.Add(tracker.period))
+	assert.Equal(t, 2, calls)
+}
+
+func TestStatsTracker_NoBudgets(t *testing.T) {
+	m := NewRequestManager(10)
+	recordDurations(m, "api", time.Now(), time.Second)
+
+	tracker := NewStatsTracker(m, nil)
+	assert.False(t, tracker.HasBudgets())
+
+	called := false
+	tracker.SetViolationCallback(func(stats ServiceStats, since time.Time) {
+		called = true
+	})
+	tracker.checkAt(time.Now().Add(time.Hour))
+	assert.False(t, called)
+}
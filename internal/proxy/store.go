@@ -0,0 +1,139 @@
+package proxy
+
+import "sync"
+
+// RequestStore holds the request history of a RequestManager. The default
+// keeps the most recent requests in memory; other stores may keep them on
+// disk. A store must be safe for concurrent use.
+type RequestStore interface {
+	// Add stores a record and returns its sequence number, which is greater
+	// than that of every record added before it (0 if the record couldn't
+	// be stored), and the IDs of the records evicted to make room that had
+	// captured details
+	Add(record RequestRecord) (uint64, []string)
+	// Update applies fn to the record with the given ID and returns the
+	// result, or false if the record isn't stored
+	Update(id string, fn func(*RequestRecord)) (RequestRecord, bool)
+	// Recent returns up to limit records accepted by match, newest first;
+	// limit <= 0 returns them all
+	Recent(limit int, match func(RequestRecord) bool) []RequestRecord
+	// After returns up to limit records accepted by match with sequence
+	// numbers greater than seq, oldest first; limit <= 0 returns them all
+	After(seq uint64, limit int, match func(RequestRecord) bool) []RequestRecord
+	// Get returns the record with the given ID
+	Get(id string) (RequestRecord, bool)
+	// Count returns the number of records stored
+	Count() int
+}
+
+// memoryRequestStore is a RequestStore keeping the last capacity records in
+// a ring buffer
+type memoryRequestStore struct {
+	mu       sync.RWMutex
+	buffer   []RequestRecord
+	head     int
+	count    int
+	capacity int
+	seq      uint64 // Sequence number of the last record added
+}
+
+// NewMemoryRequestStore creates a store holding the last capacity requests
+// in memory
+func NewMemoryRequestStore(capacity int) RequestStore {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &memoryRequestStore{
+		buffer:   make([]RequestRecord, capacity),
+		capacity: capacity,
+	}
+}
+
+// Add stores a record, overwriting the oldest one when full
+func (s *memoryRequestStore) Add(record RequestRecord) (uint64, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var evicted []string
+	if s.count == s.capacity {
+		if old := s.buffer[s.head]; old.ID != "" && old.Details != nil {
+			evicted = []string{old.ID}
+		}
+	}
+
+	s.seq++
+	record.Seq = s.seq
+	s.buffer[s.head] = record
+	s.head = (s.head + 1) % s.capacity
+	if s.count < s.capacity {
+		s.count++
+	}
+	return record.Seq, evicted
+}
+
+// index returns the buffer index of the i'th newest record. s.mu must be
+// held.
+func (s *memoryRequestStore) index(i int) int {
+	return (s.head - 1 - i + s.capacity) % s.capacity
+}
+
+func (s *memoryRequestStore) Update(id string, fn func(*RequestRecord)) (RequestRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < s.count; i++ {
+		if idx := s.index(i); s.buffer[idx].ID == id {
+			fn(&s.buffer[idx])
+			return s.buffer[idx], true
+		}
+	}
+	return RequestRecord{}, false
+}
+
+func (s *memoryRequestStore) Recent(limit int, match func(RequestRecord) bool) []RequestRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if limit <= 0 || limit > s.count {
+		limit = s.count
+	}
+	result := make([]RequestRecord, 0, limit)
+	for i := 0; i < s.count && len(result) < limit; i++ {
+		if record := s.buffer[s.index(i)]; match(record) {
+			result = append(result, record)
+		}
+	}
+	return result
+}
+
+func (s *memoryRequestStore) After(seq uint64, limit int, match func(RequestRecord) bool) []RequestRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []RequestRecord
+	for i := s.count - 1; i >= 0 && (limit <= 0 || len(result) < limit); i-- {
+		if record := s.buffer[s.index(i)]; record.Seq > seq && match(record) {
+			result = append(result, record)
+		}
+	}
+	return result
+}
+
+func (s *memoryRequestStore) Get(id string) (RequestRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Search from newest to oldest for better typical case
+	for i := 0; i < s.count; i++ {
+		if record := s.buffer[s.index(i)]; record.ID == id {
+			return record, true
+		}
+	}
+	return RequestRecord{}, false
+}
+
+func (s *memoryRequestStore) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.count
+}
@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// WebSocketStats describes the connection of an upgraded WebSocket request
+type WebSocketStats struct {
+	Open     bool  `json:"open"`      // Connection is still open
+	BytesIn  int64 `json:"bytes_in"`  // Bytes sent by the client after the upgrade
+	BytesOut int64 `json:"bytes_out"` // Bytes sent by the service after the upgrade
+}
+
+// isWebSocketUpgrade reports whether r asks to upgrade to a WebSocket
+func isWebSocketUpgrade(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// countingConn counts the bytes read from and written to a hijacked
+// connection
+type countingConn struct {
+	net.Conn
+	read    atomic.Int64
+	written atomic.Int64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+// stats returns the connection's traffic so far
+func (c *countingConn) stats(open bool) *WebSocketStats {
+	return &WebSocketStats{
+		Open:     open,
+		BytesIn:  c.read.Load(),
+		BytesOut: c.written.Load(),
+	}
+}
+
+// recordWebSocket serves a WebSocket upgrade request. The request is recorded
+// when the connection is upgraded, so open connections show up in the
+// request history, and updated with its lifetime and traffic when it closes.
+// Requests the service doesn't upgrade are recorded like any other.
+func (s *Service) recordWebSocket(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	info := RequestInfoFrom(r.Context())
+	rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+	var conn *countingConn
+	rw.hijack = func(c net.Conn, brw *bufio.ReadWriter) (net.Conn, *bufio.ReadWriter) {
+		conn = &countingConn{Conn: c}
+		// The reverse proxy writes the 101 response on the connection itself
+		rw.statusCode = http.StatusSwitchingProtocols
+		s.requestManager.Record(RequestRecord{
+			ID:         info.ID,
+			Timestamp:  info.Start,
+			Method:     r.Method,
+			URL:        r.URL.String(),
+			Subdomain:  info.Subdomain,
+			StatusCode: http.StatusSwitchingProtocols,
+			RemoteAddr: getClientIP(r),
+			Type:       RequestTypeWebSocket,
+			WebSocket:  conn.stats(true),
+		})
+		return conn, brw
+	}
+
+	next.ServeHTTP(rw, r)
+
+	if conn == nil {
+		s.recordRequest(r, info.Subdomain, rw.statusCode, rw.bytes, info.Start, info.ID, info.details, info.schemaViolations)
+		return
+	}
+	s.requestManager.Update(info.ID, func(record *RequestRecord) {
+		record.Duration = time.Since(info.Start)
+		record.WebSocket = conn.stats(false)
+	})
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWebSocketTestService proxies app.local.myapp.dev to backend through a
+// real HTTP server, since upgrades need a connection to hijack
+func newWebSocketTestService(t *testing.T, backend *httptest.Server) (*Service, string) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	services := map[string]config.ServiceConfig{
+		"app": {Port: backend.Listener.Addr().(*net.TCPAddr).Port, Host: "localhost"},
+	}
+	svc, err := NewService(&config.ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.myapp.dev"}, services, nil, logger, t.TempDir())
+	require.NoError(t, err)
+
+	front := httptest.NewServer(svc.createRouter())
+	t.Cleanup(front.Close)
+	return svc, front.Listener.Addr().String()
+}
+
+// dialUpgrade sends a WebSocket upgrade request and returns the connection
+// and the response status
+func dialUpgrade(t *testing.T, addr string) (net.Conn, *bufio.Reader, int) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+	fmt.Fprintf(conn, "GET /socket HTTP/1.1\r\nHost: app.local.myapp.dev:6788\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	require.NoError(t, err)
+	return conn, r, resp.StatusCode
+}
+
+func TestWebSocket_RecordsConnectionLifetime(t *testing.T) {
+	// The backend upgrades and echoes whatever it receives
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+		_, _ = io.Copy(conn, brw)
+	}))
+	defer backend.Close()
+
+	svc, addr := newWebSocketTestService(t, backend)
+	rm := svc.RequestManager()
+
+	conn, r, status := dialUpgrade(t, addr)
+	assert.Equal(t, http.StatusSwitchingProtocols, status)
+
+	_, err := conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	echo := make([]byte, 5)
+	_, err = io.ReadFull(r, echo)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(echo))
+
+	// The open connection is in the history already
+	requests := rm.Recent(RequestFilter{})
+	require.Len(t, requests, 1)
+	assert.Equal(t, RequestTypeWebSocket, requests[0].Type)
+	assert.Equal(t, http.StatusSwitchingProtocols, requests[0].StatusCode)
+	require.NotNil(t, requests[0].WebSocket)
+	assert.True(t, requests[0].WebSocket.Open)
+
+	sub := rm.Subscribe(RequestFilter{})
+	defer rm.Unsubscribe(sub.ID)
+	conn.Close()
+
+	select {
+	case record := <-sub.Ch:
+		assert.Equal(t, requests[0].ID, record.ID)
+		require.NotNil(t, record.WebSocket)
+		assert.False(t, record.WebSocket.Open)
+		assert.Equal(t, int64(5), record.WebSocket.BytesIn)
+		assert.Equal(t, int64(5), record.WebSocket.BytesOut)
+		assert.Positive(t, record.Duration)
+	case <-time.After(5 * time.Second):
+		t.Fatal("closed connection was not reported")
+	}
+	assert.Equal(t, 1, rm.Count(), "the connection should be recorded once")
+}
+
+func TestWebSocket_RejectedUpgrade(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no sockets here", http.StatusBadRequest)
+	}))
+	defer backend.Close()
+
+	svc, addr := newWebSocketTestService(t, backend)
+
+	conn, _, status := dialUpgrade(t, addr)
+	defer conn.Close()
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	require.Eventually(t, func() bool { return svc.RequestManager().Count() == 1 }, 5*time.Second, 10*time.Millisecond)
+	record := svc.RequestManager().Recent(RequestFilter{})[0]
+	assert.Equal(t, RequestTypeWebSocket, record.Type)
+	assert.Equal(t, http.StatusBadRequest, record.StatusCode)
+	assert.Nil(t, record.WebSocket)
+}
+
+func TestIsWebSocketUpgrade(t *testing.T) {
+	tests := []struct {
+		upgrade    string
+		connection string
+		want       bool
+	}{
+		{"websocket", "Upgrade", true},
+		{"WebSocket", "keep-alive, upgrade", true},
+		{"websocket", "keep-alive", false},
+		{"h2c", "Upgrade", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Upgrade", tt.upgrade)
+		r.Header.Set("Connection", tt.connection)
+		assert.Equal(t, tt.want, isWebSocketUpgrade(r), "Upgrade: %q, Connection: %q", tt.upgrade, tt.connection)
+	}
+}
@@ -0,0 +1,5 @@
+package storage
+
+// The pure Go SQLite driver registers itself as "sqlite", so prox needs no
+// cgo to use it
+import _ "modernc.org/sqlite"
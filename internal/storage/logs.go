@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/logs"
+)
+
+// logFlushInterval is how often buffered log entries are written to the
+// database
+const logFlushInterval = 100 * time.Millisecond
+
+// logFlushBatch is how many buffered log entries are written without waiting
+// for the next tick
+const logFlushBatch = 1000
+
+// logStore is a logs.Store in the logs table. It keeps the last maxLogs
+// entries of all processes, without per-process reservations.
+//
+// Write only buffers entries, so a process logging thousands of lines a
+// second doesn't wait on the database. A goroutine writes the buffer in one
+// transaction each logFlushInterval, deleting entries past maxLogs as it
+// goes, and reads write it first so they see every entry written.
+type logStore struct {
+	s *SQLite
+
+	mu      sync.Mutex // Guards pending and lastID
+	pending []pendingLog
+	lastID  uint64 // ID of the last entry written; IDs are assigned by Write
+
+	flushMu sync.Mutex    // Serializes flushes, so entries are inserted in ID order
+	full    chan struct{} // Signals the flusher that logFlushBatch entries are pending
+	done    chan struct{} // Closed to stop the flusher
+	stopped chan struct{} // Closed when the flusher has stopped
+	stop    sync.Once
+}
+
+// pendingLog is a log entry waiting to be inserted, with its encoded fields
+type pendingLog struct {
+	entry  domain.LogEntry
+	fields sql.NullString
+}
+
+// logColumns are the columns scanLogs reads, in order
+const logColumns = "id, ts, process, stream, line, level, fields"
+
+// newLogStore creates the log store, continuing the IDs of the entries
+// already in the database, and starts its flusher
+func newLogStore(s *SQLite) (*logStore, error) {
+	var lastID int64
+	if err := s.db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM logs").Scan(&lastID); err != nil {
+		return nil, fmt.Errorf("reading log entries: %w", err)
+	}
+	l := &logStore{
+		s:       s,
+		lastID:  uint64(lastID),
+		full:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go l.flusher()
+	return l, nil
+}
+
+func (l *logStore) Write(entry domain.LogEntry) uint64 {
+	var fields sql.NullString
+	if len(entry.Fields) > 0 {
+		data, err := json.Marshal(entry.Fields)
+		if err != nil {
+			l.s.fail(fmt.Errorf("encoding log fields: %w", err))
+			return 0
+		}
+		fields = sql.NullString{String: string(data), Valid: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastID++
+	entry.ID = l.lastID
+	l.pending = append(l.pending, pendingLog{entry: entry, fields: fields})
+	if len(l.pending) >= logFlushBatch {
+		select {
+		case l.full <- struct{}{}:
+		default:
+		}
+	}
+	return entry.ID
+}
+
+// flusher writes the buffered entries each logFlushInterval, or sooner when
+// logFlushBatch are pending, until the store is stopped
+func (l *logStore) flusher() {
+	defer close(l.stopped)
+	ticker := time.NewTicker(logFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-l.full:
+		case <-l.done:
+			l.flush()
+			return
+		}
+		l.flush()
+	}
+}
+
+// flush inserts the buffered entries in one transaction and deletes those
+// more than maxLogs behind the newest. Entries it fails to insert are lost.
+func (l *logStore) flush() {
+	l.flushMu.Lock()
+	defer l.flushMu.Unlock()
+
+	l.mu.Lock()
+	batch := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := l.insert(batch); err != nil {
+		l.s.fail(fmt.Errorf("writing log entries: %w", err))
+	}
+}
+
+// insert writes a batch of entries and trims the table in one transaction
+func (l *logStore) insert(batch []pendingLog) error {
+	tx, err := l.s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO logs (id, ts, process, stream, line, level, fields) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, p := range batch {
+		e := p.entry
+		if _, err := stmt.Exec(e.ID, e.Timestamp.UnixNano(), e.Process, string(e.Stream), e.Line, e.Level, p.fields); err != nil {
+			return err
+		}
+	}
+
+	// IDs only grow, so everything more than maxLogs behind the newest goes
+	newest := batch[len(batch)-1].entry.ID
+	if _, err := tx.Exec("DELETE FROM logs WHERE id <= ?", int64(newest)-int64(l.s.maxLogs)); err != nil {
+		return fmt.Errorf("deleting old log entries: %w", err)
+	}
+	return tx.Commit()
+}
+
+// stopFlusher stops the flusher after it writes the buffered entries
+func (l *logStore) stopFlusher() {
+	l.stop.Do(func() { close(l.done) })
+	<-l.stopped
+}
+
+func (l *logStore) Read() []domain.LogEntry {
+	l.flush()
+	return l.query("SELECT " + logColumns + " FROM logs ORDER BY id")
+}
+
+// Candidates selects entries by process, time range, and ID with the
+// table's indexes, like logs.RingBuffer
+func (l *logStore) Candidates(filter domain.LogFilter) ([]domain.LogEntry, string) {
+	index := logs.IndexScan
+	var where []string
+	var args []any
+	if len(filter.Processes) > 0 {
+		index = logs.IndexProcess
+		where = append(where, "process IN (?"+strings.Repeat(", ?", len(filter.Processes)-1)+")")
+		for _, name := range filter.Processes {
+			args = append(args, name)
+		}
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "ts >= ?")
+		args = append(args, filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "ts <= ?")
+		args = append(args, filter.Until.UnixNano())
+	}
+	if filter.AfterID > 0 {
+		where = append(where, "id > ?")
+		args = append(args, filter.AfterID)
+	}
+	if !filter.Since.IsZero() || !filter.Until.IsZero() {
+		if index == logs.IndexProcess {
+			index = logs.IndexProcessTime
+		} else {
+			index = logs.IndexTime
+		}
+	}
+
+	l.flush()
+	query := "SELECT " + logColumns + " FROM logs"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	return l.query(query+" ORDER BY id", args...), index
+}
+
+// query returns the entries a query selects
+func (l *logStore) query(query string, args ...any) []domain.LogEntry {
+	rows, err := l.s.db.Query(query, args...)
+	if err != nil {
+		l.s.fail(fmt.Errorf("reading log entries: %w", err))
+		return nil
+	}
+	defer rows.Close()
+
+	var entries []domain.LogEntry
+	for rows.Next() {
+		var ts int64
+		var stream string
+		var fields sql.NullString
+		var entry domain.LogEntry
+		if err := rows.Scan(&entry.ID, &ts, &entry.Process, &stream, &entry.Line, &entry.Level, &fields); err != nil {
+			l.s.fail(fmt.Errorf("reading log entries: %w", err))
+			return entries
+		}
+		entry.Timestamp = time.Unix(0, ts)
+		entry.Stream = domain.Stream(stream)
+		if fields.Valid {
+			if err := json.Unmarshal([]byte(fields.String), &entry.Fields); err != nil {
+				l.s.fail(fmt.Errorf("decoding log fields: %w", err))
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		l.s.fail(fmt.Errorf("reading log entries: %w", err))
+	}
+	return entries
+}
+
+// SetReservation is ignored; entries are kept by age alone
+func (l *logStore) SetReservation(process string, n int) {}
+
+func (l *logStore) Count() int {
+	l.flush()
+	return l.s.count("logs")
+}
+
+func (l *logStore) Capacity() int {
+	return l.s.maxLogs
+}
+
+// Close writes the buffered entries, leaving the history in place; the
+// database is closed by its owner
+func (l *logStore) Close() error {
+	l.flush()
+	return nil
+}
+
+// count returns the number of rows in a table
+func (s *SQLite) count(table string) int {
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+		s.fail(fmt.Errorf("counting %s: %w", table, err))
+	}
+	return n
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/charliek/prox/internal/proxy"
+)
+
+// requestStore is a proxy.RequestStore in the requests table, keeping the
+// last maxRequests requests. The record column holds the whole record; the
+// other columns are copies for querying the database directly.
+type requestStore struct {
+	s *SQLite
+}
+
+func (r *requestStore) Add(record proxy.RequestRecord) (uint64, []string) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		r.s.fail(fmt.Errorf("encoding request: %w", err))
+		return 0, nil
+	}
+
+	tx, err := r.s.db.Begin()
+	if err != nil {
+		r.s.fail(fmt.Errorf("writing request: %w", err))
+		return 0, nil
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`INSERT OR REPLACE INTO requests (id, ts, method, url, subdomain, status_code, duration_ns, has_details, record)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.ID, record.Timestamp.UnixNano(), record.Method, record.URL, record.Subdomain,
+		record.StatusCode, int64(record.Duration), record.Details != nil, string(data),
+	)
+	if err != nil {
+		r.s.fail(fmt.Errorf("writing request: %w", err))
+		return 0, nil
+	}
+	seq, err := result.LastInsertId()
+	if err != nil {
+		r.s.fail(fmt.Errorf("writing request: %w", err))
+		return 0, nil
+	}
+
+	// Sequence numbers only grow, so everything more than maxRequests behind
+	// the newest goes
+	oldest := seq - int64(r.s.maxRequests)
+	evicted, err := r.evicted(tx, oldest)
+	if err != nil {
+		r.s.fail(fmt.Errorf("deleting old requests: %w", err))
+		return 0, nil
+	}
+	if _, err := tx.Exec("DELETE FROM requests WHERE seq <= ?", oldest); err != nil {
+		r.s.fail(fmt.Errorf("deleting old requests: %w", err))
+		return 0, nil
+	}
+	if err := tx.Commit(); err != nil {
+		r.s.fail(fmt.Errorf("writing request: %w", err))
+		return 0, nil
+	}
+	return uint64(seq), evicted
+}
+
+// evicted returns the IDs of requests with captured details up to seq
+func (r *requestStore) evicted(tx *sql.Tx, seq int64) ([]string, error) {
+	rows, err := tx.Query("SELECT id FROM requests WHERE seq <= ? AND has_details", seq)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *requestStore) Update(id string, fn func(*proxy.RequestRecord)) (proxy.RequestRecord, bool) {
+	tx, err := r.s.db.Begin()
+	if err != nil {
+		r.s.fail(fmt.Errorf("updating request: %w", err))
+		return proxy.RequestRecord{}, false
+	}
+	defer tx.Rollback()
+
+	record, ok := r.get(tx.QueryRow("SELECT seq, record FROM requests WHERE id = ?", id))
+	if !ok {
+		return proxy.RequestRecord{}, false
+	}
+	fn(&record)
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		r.s.fail(fmt.Errorf("encoding request: %w", err))
+		return proxy.RequestRecord{}, false
+	}
+	_, err = tx.Exec(
+		"UPDATE requests SET status_code = ?, duration_ns = ?, has_details = ?, record = ? WHERE id = ?",
+		record.StatusCode, int64(record.Duration), record.Details != nil, string(data), id,
+	)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		r.s.fail(fmt.Errorf("updating request: %w", err))
+		return proxy.RequestRecord{}, false
+	}
+	return record, true
+}
+
+func (r *requestStore) Recent(limit int, match func(proxy.RequestRecord) bool) []proxy.RequestRecord {
+	rows, err := r.s.db.Query("SELECT seq, record FROM requests ORDER BY seq DESC")
+	if err != nil {
+		r.s.fail(fmt.Errorf("reading requests: %w", err))
+		return nil
+	}
+	defer rows.Close()
+
+	var result []proxy.RequestRecord
+	for (limit <= 0 || len(result) < limit) && rows.Next() {
+		record, ok := r.get(rows)
+		if ok && match(record) {
+			result = append(result, record)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		r.s.fail(fmt.Errorf("reading requests: %w", err))
+	}
+	return result
+}
+
+func (r *requestStore) After(seq uint64, limit int, match func(proxy.RequestRecord) bool) []proxy.RequestRecord {
+	rows, err := r.s.db.Query("SELECT seq, record FROM requests WHERE seq > ? ORDER BY seq", seq)
+	if err != nil {
+		r.s.fail(fmt.Errorf("reading requests: %w", err))
+		return nil
+	}
+	defer rows.Close()
+
+	var result []proxy.RequestRecord
+	for (limit <= 0 || len(result) < limit) && rows.Next() {
+		record, ok := r.get(rows)
+		if ok && match(record) {
+			result = append(result, record)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		r.s.fail(fmt.Errorf("reading requests: %w", err))
+	}
+	return result
+}
+
+func (r *requestStore) Get(id string) (proxy.RequestRecord, bool) {
+	return r.get(r.s.db.QueryRow("SELECT seq, record FROM requests WHERE id = ?", id))
+}
+
+func (r *requestStore) Count() int {
+	return r.s.count("requests")
+}
+
+// get decodes the record a row selects with its sequence number
+func (r *requestStore) get(row interface{ Scan(...any) error }) (proxy.RequestRecord, bool) {
+	var seq uint64
+	var data string
+	if err := row.Scan(&seq, &data); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			r.s.fail(fmt.Errorf("reading request: %w", err))
+		}
+		return proxy.RequestRecord{}, false
+	}
+	var record proxy.RequestRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		r.s.fail(fmt.Errorf("decoding request: %w", err))
+		return proxy.RequestRecord{}, false
+	}
+	record.Seq = seq
+	return record, true
+}
@@ -0,0 +1,145 @@
+// Package storage keeps log and request history in a SQLite database for
+// storage.backend: sqlite, trading the speed of the in-memory defaults for
+// history that outlives prox and can be queried with any SQLite client.
+//
+// It uses database/sql with the pure Go driver registered as "sqlite" (see
+// driver.go).
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/charliek/prox/internal/fsperm"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/charliek/prox/internal/proxy"
+)
+
+// driverName is the database/sql driver the database is opened with
+const driverName = "sqlite"
+
+// schema creates the tables. Timestamps are unix nanoseconds; the record and
+// fields columns hold JSON.
+const schema = `
+CREATE TABLE IF NOT EXISTS logs (
+	id      INTEGER PRIMARY KEY AUTOINCREMENT,
+	ts      INTEGER NOT NULL,
+	process TEXT NOT NULL,
+	stream  TEXT NOT NULL,
+	line    TEXT NOT NULL,
+	level   TEXT NOT NULL DEFAULT '',
+	fields  TEXT
+);
+CREATE INDEX IF NOT EXISTS logs_process ON logs (process, id);
+CREATE INDEX IF NOT EXISTS logs_ts ON logs (ts);
+
+CREATE TABLE IF NOT EXISTS requests (
+	seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+	id          TEXT NOT NULL UNIQUE,
+	ts          INTEGER NOT NULL,
+	method      TEXT NOT NULL,
+	url         TEXT NOT NULL,
+	subdomain   TEXT NOT NULL,
+	status_code INTEGER NOT NULL,
+	duration_ns INTEGER NOT NULL,
+	has_details INTEGER NOT NULL,
+	record      TEXT NOT NULL
+);
+`
+
+// SQLiteConfig configures a SQLite database
+type SQLiteConfig struct {
+	Path        string // Database file, created if missing
+	MaxLogs     int    // Log entries kept; older ones are deleted
+	MaxRequests int    // Requests kept; older ones are deleted
+}
+
+// SQLite is a SQLite database holding log and request history
+type SQLite struct {
+	db          *sql.DB
+	logs        *logStore
+	maxLogs     int
+	maxRequests int
+
+	errMu sync.Mutex
+	err   error // First error of a method that can't return one
+}
+
+// OpenSQLite opens or creates the database at config.Path
+func OpenSQLite(config SQLiteConfig) (*SQLite, error) {
+	if err := fsperm.MkdirAll(filepath.Dir(config.Path)); err != nil {
+		return nil, fmt.Errorf("creating storage directory: %w", err)
+	}
+
+	db, err := sql.Open(driverName, config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", config.Path, err)
+	}
+	// A single connection serializes writers, which SQLite would otherwise
+	// make wait on its file lock
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range []string{
+		"PRAGMA journal_mode = WAL",
+		"PRAGMA synchronous = NORMAL",
+		"PRAGMA busy_timeout = 5000",
+		schema,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("initializing %s: %w", config.Path, err)
+		}
+	}
+	if err := os.Chmod(config.Path, fsperm.File()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("setting mode of %s: %w", config.Path, err)
+	}
+
+	s := &SQLite{
+		db:          db,
+		maxLogs:     config.MaxLogs,
+		maxRequests: config.MaxRequests,
+	}
+	if s.logs, err = newLogStore(s); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Logs returns the log store
+func (s *SQLite) Logs() logs.Store {
+	return s.logs
+}
+
+// Requests returns the request store
+func (s *SQLite) Requests() proxy.RequestStore {
+	return &requestStore{s}
+}
+
+// fail records the first error of a store method, which has no error result
+func (s *SQLite) fail(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Err returns the first error writing or reading history, if any. Store
+// methods can't return errors, so they carry on without the history they
+// failed to write or read.
+func (s *SQLite) Err() error {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.err
+}
+
+// Close writes the buffered log entries and closes the database
+func (s *SQLite) Close() error {
+	s.logs.stopFlusher()
+	return s.db.Close()
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/charliek/prox/internal/proxy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestDB(t *testing.T, path string) *SQLite {
+	t.Helper()
+	db, err := OpenSQLite(SQLiteConfig{Path: path, MaxLogs: 5, MaxRequests: 2})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Err())
+		db.Close()
+	})
+	return db
+}
+
+func TestLogStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store := openTestDB(t, path).Logs()
+
+	start := time.Now()
+	for i := 0; i < 8; i++ {
+		process := "web"
+		if i%2 == 1 {
+			process = "api"
+		}
+		id := store.Write(domain.LogEntry{
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+			Process:   process,
+			Stream:    domain.StreamStdout,
+			Line:      fmt.Sprintf("line %d", i),
+			Level:     "info",
+			Fields:    map[string]string{"n": fmt.Sprint(i)},
+		})
+		assert.Equal(t, uint64(i+1), id)
+	}
+
+	// Only the last MaxLogs entries are kept
+	assert.Equal(t, 5, store.Count())
+	assert.Equal(t, 5, store.Capacity())
+	entries := store.Read()
+	require.Len(t, entries, 5)
+	assert.Equal(t, "line 3", entries[0].Line)
+	assert.Equal(t, "7", entries[4].Fields["n"])
+	assert.True(t, entries[4].Timestamp.Equal(start.Add(7*time.Second)))
+
+	entries, index := store.Candidates(domain.LogFilter{Processes: []string{"web"}, Since: start.Add(5 * time.Second)})
+	assert.Equal(t, logs.IndexProcessTime, index)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "line 6", entries[0].Line)
+	assert.Equal(t, uint64(7), entries[0].ID)
+
+	entries, _ = store.Candidates(domain.LogFilter{AfterID: 6})
+	require.Len(t, entries, 2)
+	assert.Equal(t, "line 6", entries[0].Line)
+
+	// History outlives the database connection
+	reopened := openTestDB(t, path).Logs()
+	assert.Equal(t, 5, reopened.Count())
+}
+
+func TestLogStore_Batches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	db := openTestDB(t, path)
+	store := db.Logs()
+
+	for i := 0; i < 3; i++ {
+		store.Write(domain.LogEntry{Timestamp: time.Now(), Process: "web", Stream: domain.StreamStdout, Line: fmt.Sprintf("line %d", i)})
+	}
+
+	// Writes are buffered, and reach the database within a flush interval
+	// without a read asking for them
+	require.Eventually(t, func() bool { return db.count("logs") == 3 }, time.Second, 10*time.Millisecond)
+
+	// Entries still buffered when the database closes are written, and IDs
+	// carry on from them when it is reopened
+	store.Write(domain.LogEntry{Timestamp: time.Now(), Process: "web", Stream: domain.StreamStdout, Line: "last"})
+	require.NoError(t, db.Close())
+	reopened := openTestDB(t, path).Logs()
+	assert.Equal(t, 4, reopened.Count())
+	assert.Equal(t, uint64(5), reopened.Write(domain.LogEntry{Timestamp: time.Now(), Process: "web", Stream: domain.StreamStdout, Line: "next"}))
+}
+
+func TestRequestStore(t *testing.T) {
+	store := openTestDB(t, filepath.Join(t.TempDir(), "history.db")).Requests()
+
+	now := time.Now()
+	var evicted []string
+	for i, id := range []string{"a", "b", "c"} {
+		seq, ids := store.Add(proxy.RequestRecord{
+			ID:         id,
+			Timestamp:  now.Add(time.Duration(i) * time.Second),
+			Method:     "GET",
+			URL:        "/" + id,
+			StatusCode: 200,
+			Details:    &proxy.RequestDetails{RequestHeaders: map[string][]string{"Accept": {"*/*"}}},
+		})
+		assert.Equal(t, uint64(i+1), seq)
+		evicted = append(evicted, ids...)
+	}
+	assert.Equal(t, []string{"a"}, evicted)
+	assert.Equal(t, 2, store.Count())
+
+	records := store.Recent(0, func(proxy.RequestRecord) bool { return true })
+	require.Len(t, records, 2)
+	assert.Equal(t, "c", records[0].ID)
+	assert.Equal(t, []string{"*/*"}, records[0].Details.RequestHeaders["Accept"])
+	assert.Equal(t, uint64(3), records[0].Seq)
+
+	records = store.After(2, 0, func(proxy.RequestRecord) bool { return true })
+	require.Len(t, records, 1)
+	assert.Equal(t, "c", records[0].ID)
+
+	updated, ok := store.Update("b", func(r *proxy.RequestRecord) { r.StatusCode = 503 })
+	require.True(t, ok)
+	assert.Equal(t, 503, updated.StatusCode)
+	record, ok := store.Get("b")
+	require.True(t, ok)
+	assert.Equal(t, 503, record.StatusCode)
+
+	_, ok = store.Get("a")
+	assert.False(t, ok)
+	_, ok = store.Update("a", func(*proxy.RequestRecord) {})
+	assert.False(t, ok)
+}
@@ -0,0 +1,261 @@
+// Package streams publishes the raw stdout of each process on a unix socket
+// under .prox/streams, so external tools can consume output in real time
+// without polling the HTTP API.
+package streams
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/fsperm"
+	"github.com/charliek/prox/internal/logs"
+)
+
+// clientBuffer is the number of lines buffered for each connected reader.
+// Lines are dropped for readers that fall further behind, so a slow reader
+// never holds up prox.
+const clientBuffer = 1000
+
+// systemProcess is the pseudo-process of prox's own log lines
+const systemProcess = "system"
+
+// Publisher serves each process's stdout on a unix socket named after the
+// process. Every reader connected to a socket receives the lines written
+// from then on.
+type Publisher struct {
+	dir        string
+	logManager *logs.Manager
+	logf       func(format string, args ...interface{})
+
+	mu      sync.Mutex
+	streams map[string]*stream
+	subID   string
+	done    chan struct{}
+	closed  bool
+}
+
+// stream is the socket of a single process and its connected readers
+type stream struct {
+	listener net.Listener
+	path     string
+
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+// New creates a publisher for sockets in dir. Problems creating sockets are
+// reported through logf.
+func New(dir string, logManager *logs.Manager, logf func(format string, args ...interface{})) *Publisher {
+	return &Publisher{
+		dir:        dir,
+		logManager: logManager,
+		logf:       logf,
+		streams:    make(map[string]*stream),
+	}
+}
+
+// Dir returns the directory holding the sockets
+func (p *Publisher) Dir() string {
+	return p.dir
+}
+
+// Start creates sockets for the named processes and begins publishing their
+// output. Sockets for other processes are created when they first write to
+// stdout.
+func (p *Publisher) Start(names []string) error {
+	if err := fsperm.MkdirAll(p.dir); err != nil {
+		return fmt.Errorf("creating streams directory: %w", err)
+	}
+
+	id, entries, err := p.logManager.Subscribe(domain.LogFilter{})
+	if err != nil {
+		return fmt.Errorf("subscribing to logs: %w", err)
+	}
+
+	p.mu.Lock()
+	p.subID = id
+	p.done = make(chan struct{})
+	for _, name := range names {
+		p.streamLocked(name)
+	}
+	p.mu.Unlock()
+
+	go p.run(entries)
+	return nil
+}
+
+// run fans out stdout lines to the readers of each process's socket
+func (p *Publisher) run(entries <-chan domain.LogEntry) {
+	defer close(p.done)
+	for entry := range entries {
+		if entry.Stream != domain.StreamStdout || entry.Process == systemProcess {
+			continue
+		}
+
+		p.mu.Lock()
+		s := p.streamLocked(entry.Process)
+		p.mu.Unlock()
+		if s != nil {
+			s.broadcast([]byte(entry.Line + "\n"))
+		}
+	}
+}
+
+// streamLocked returns the stream for a process, creating its socket on
+// first use. It returns nil if the socket can't be created or the publisher
+// is closed. p.mu must be held.
+func (p *Publisher) streamLocked(name string) *stream {
+	if p.closed {
+		return nil
+	}
+	if s, ok := p.streams[name]; ok {
+		return s
+	}
+
+	s, err := listen(filepath.Join(p.dir, name))
+	if err != nil {
+		p.logf("not streaming output of %s: %v", name, err)
+	}
+	// A failed socket is remembered as nil so it isn't retried per line
+	p.streams[name] = s
+	return s
+}
+
+// listen creates the socket at path, replacing one left by an earlier run
+func listen(path string) (*stream, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, fsperm.File()); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	s := &stream{
+		listener: listener,
+		path:     path,
+		clients:  make(map[chan []byte]struct{}),
+	}
+	go s.accept()
+	return s, nil
+}
+
+// accept serves readers until the listener is closed
+func (s *stream) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go s.serve(conn)
+	}
+}
+
+// serve writes lines to a reader until it disconnects or the stream closes
+func (s *stream) serve(conn net.Conn) {
+	defer conn.Close()
+
+	lines := make(chan []byte, clientBuffer)
+	s.mu.Lock()
+	s.clients[lines] = struct{}{}
+	s.mu.Unlock()
+	defer s.remove(lines)
+
+	// Readers only read, so a read returning means they went away
+	gone := make(chan struct{})
+	go func() {
+		_, _ = conn.Read(make([]byte, 1))
+		close(gone)
+	}()
+
+	w := bufio.NewWriter(conn)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			if _, err := w.Write(line); err != nil {
+				return
+			}
+			// Flush once caught up, batching lines that arrive in bursts
+			if len(lines) == 0 {
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+// remove disconnects a reader's channel, unless the stream closed it already
+func (s *stream) remove(lines chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, lines)
+}
+
+// broadcast queues a line for every connected reader
+func (s *stream) broadcast(line []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for lines := range s.clients {
+		select {
+		case lines <- line:
+		default:
+			// Reader is too far behind; drop the line
+		}
+	}
+}
+
+// close stops accepting readers, disconnects the current ones, and removes
+// the socket
+func (s *stream) close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for lines := range s.clients {
+		close(lines)
+		delete(s.clients, lines)
+	}
+	s.mu.Unlock()
+	_ = os.Remove(s.path)
+}
+
+// Close stops publishing, disconnects all readers, and removes the sockets
+func (p *Publisher) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	subID, done := p.subID, p.done
+	streams := p.streams
+	p.streams = make(map[string]*stream)
+	p.mu.Unlock()
+
+	if subID != "" {
+		p.logManager.Unsubscribe(subID)
+		<-done
+	}
+	for _, s := range streams {
+		if s != nil {
+			s.close()
+		}
+	}
+}
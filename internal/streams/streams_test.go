@@ -0,0 +1,115 @@
+package streams
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPublisher(t *testing.T, names ...string) (*Publisher, *logs.Manager) {
+	t.Helper()
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100, SubscriptionBuffer: 100})
+	t.Cleanup(logMgr.Close)
+
+	// Socket paths are length limited, so keep the directory short
+	dir, err := os.MkdirTemp("", "streams")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	p := New(dir, logMgr, t.Logf)
+	require.NoError(t, p.Start(names))
+	t.Cleanup(p.Close)
+	return p, logMgr
+}
+
+func dial(t *testing.T, path string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	return conn, bufio.NewReader(conn)
+}
+
+// waitForReader waits until a reader that just connected is registered
+func waitForReader(t *testing.T, p *Publisher, name string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		p.mu.Lock()
+		s := p.streams[name]
+		p.mu.Unlock()
+		if s == nil {
+			return false
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.clients) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func readLine(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	return line
+}
+
+func TestPublisher_StreamsStdout(t *testing.T) {
+	p, logMgr := newTestPublisher(t, "web")
+
+	_, r := dial(t, filepath.Join(p.Dir(), "web"))
+	waitForReader(t, p, "web")
+
+	logMgr.Write(domain.LogEntry{Process: "web", Stream: domain.StreamStdout, Line: "ready"})
+	assert.Equal(t, "ready\n", readLine(t, r))
+
+	// Stderr and other processes' output aren't written to the socket
+	logMgr.Write(domain.LogEntry{Process: "web", Stream: domain.StreamStderr, Line: "warning"})
+	logMgr.Write(domain.LogEntry{Process: "api", Stream: domain.StreamStdout, Line: "listening"})
+	logMgr.Write(domain.LogEntry{Process: "web", Stream: domain.StreamStdout, Line: `{"level":"info"}`})
+
+	assert.Equal(t, "{\"level\":\"info\"}\n", readLine(t, r))
+}
+
+func TestPublisher_CreatesSocketsForNewProcesses(t *testing.T) {
+	p, logMgr := newTestPublisher(t)
+	path := filepath.Join(p.Dir(), "worker")
+
+	logMgr.Write(domain.LogEntry{Process: "worker", Stream: domain.StreamStdout, Line: "started"})
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, r := dial(t, path)
+	waitForReader(t, p, "worker")
+
+	logMgr.Write(domain.LogEntry{Process: "worker", Stream: domain.StreamStdout, Line: "job done"})
+	assert.Equal(t, "job done\n", readLine(t, r))
+}
+
+func TestPublisher_Close(t *testing.T) {
+	p, logMgr := newTestPublisher(t, "web")
+	path := filepath.Join(p.Dir(), "web")
+
+	_, r := dial(t, path)
+	waitForReader(t, p, "web")
+
+	logMgr.Write(domain.LogEntry{Process: "web", Stream: domain.StreamStdout, Line: "ready"})
+	assert.Equal(t, "ready\n", readLine(t, r))
+
+	p.Close()
+
+	_, err := r.ReadString('\n')
+	assert.Error(t, err, "readers should be disconnected")
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "socket should be removed")
+}
@@ -0,0 +1,53 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/daemon"
+	"github.com/charliek/prox/internal/domain"
+)
+
+// AddProcess registers a process that isn't in the config, e.g. from prox
+// run, and starts it. From then on it is managed like a configured process
+// (logs, restart, stop), and it is saved with the runtime overrides so the
+// next prox up brings it back. Reloads leave it alone, unless the config
+// gains a process of the same name, which replaces it.
+// Returns domain.ErrProcessExists if a process with the name exists.
+func (s *Supervisor) AddProcess(ctx context.Context, name string, procConfig config.ProcessConfig) error {
+	if err := config.ValidateProcessName(name); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidConfig, err)
+	}
+	if procConfig.Cmd == "" {
+		return fmt.Errorf("%w: cmd is required", domain.ErrInvalidConfig)
+	}
+
+	// Serialize with reloads, which replace the set of processes
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	s.mu.RLock()
+	running := s.state == "running"
+	_, managed := s.processes[name]
+	_, configured := s.config.Processes[name]
+	s.mu.RUnlock()
+	if !running {
+		return domain.ErrShutdownInProgress
+	}
+	if managed || configured {
+		return fmt.Errorf("%w: %s", domain.ErrProcessExists, name)
+	}
+
+	mp, err := s.createManagedProcess(name, procConfig)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.processes[name] = mp
+	s.mu.Unlock()
+
+	s.SystemLog("added process %s: %s", name, procConfig.Cmd)
+	s.setAddedOverride(name, &daemon.AddedProcess{Name: name, Cmd: procConfig.Cmd, Env: procConfig.Env})
+	return s.StartProcess(ctx, name)
+}
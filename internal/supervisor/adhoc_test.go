@@ -0,0 +1,58 @@
+package supervisor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupervisor_AddProcess(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	sup := New(makeTestConfig(map[string]string{"web": "sleep 30"}), logMgr, nil, DefaultSupervisorConfig())
+
+	ctx := context.Background()
+	_, err := sup.Start(ctx)
+	require.NoError(t, err)
+	defer sup.Stop(ctx)
+
+	require.NoError(t, sup.AddProcess(ctx, "tunnel", config.ProcessConfig{Cmd: "sleep 30", Env: map[string]string{"MODE": "test"}}))
+	info, err := sup.Process("tunnel")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+	assert.Equal(t, "test", info.Env["MODE"])
+
+	// Managed like configured processes
+	require.NoError(t, sup.StopProcess(ctx, "tunnel"))
+	require.NoError(t, sup.StartProcess(ctx, "tunnel"))
+
+	assert.ErrorIs(t, sup.AddProcess(ctx, "tunnel", config.ProcessConfig{Cmd: "sleep 30"}), domain.ErrProcessExists)
+	assert.ErrorIs(t, sup.AddProcess(ctx, "web", config.ProcessConfig{Cmd: "sleep 30"}), domain.ErrProcessExists)
+	assert.ErrorIs(t, sup.AddProcess(ctx, "bad name", config.ProcessConfig{Cmd: "sleep 30"}), domain.ErrInvalidConfig)
+	assert.ErrorIs(t, sup.AddProcess(ctx, "empty", config.ProcessConfig{}), domain.ErrInvalidConfig)
+
+	// Reloads leave ad-hoc processes running
+	tunnel, err := sup.Process("tunnel")
+	require.NoError(t, err)
+	result, err := sup.Reload(ctx, makeTestConfig(map[string]string{"web": "sleep 30"}))
+	require.NoError(t, err)
+	assert.False(t, result.HasChanges())
+	info, err = sup.Process("tunnel")
+	require.NoError(t, err)
+	assert.Equal(t, tunnel.PID, info.PID)
+
+	// ...unless the config gains a process with the same name
+	result, err = sup.Reload(ctx, makeTestConfig(map[string]string{"web": "sleep 30", "tunnel": "sleep 31"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tunnel"}, result.Changed)
+	info, err = sup.Process("tunnel")
+	require.NoError(t, err)
+	assert.Equal(t, "sleep 31", info.Cmd)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+}
@@ -0,0 +1,152 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/domain"
+)
+
+// SwitchFunc is called during a blue-green restart once the new instance is
+// ready, before the old instance is stopped. It receives the new instance's port.
+type SwitchFunc func(port int) error
+
+// BlueGreenRestart replaces a running port: auto process with a new instance
+// on a freshly allocated port. The new instance must pass its healthcheck (or
+// accept TCP connections when no healthcheck is configured) before switchFn
+// is called and the old instance is stopped. If the new instance fails to
+// become ready, it is stopped and the old instance keeps running.
+func (s *Supervisor) BlueGreenRestart(ctx context.Context, name string, switchFn SwitchFunc) error {
+	s.mu.RLock()
+	old, ok := s.processes[name]
+	procConfig, hasConfig := s.config.Processes[name]
+	supCtx := s.ctx
+	s.mu.RUnlock()
+
+	if !ok || !hasConfig {
+		return domain.ErrProcessNotFound
+	}
+	if !procConfig.AutoPort() {
+		return fmt.Errorf("%w: %s", domain.ErrPortNotAuto, name)
+	}
+	if old.State() != domain.ProcessStateRunning {
+		return fmt.Errorf("%w: %s", domain.ErrProcessNotRunning, name)
+	}
+
+	next, err := s.createManagedProcess(name, procConfig)
+	if err != nil {
+		return err
+	}
+	next.inheritRestarts(old)
+	next.recordRestart(domain.RestartReasonRequest)
+
+	s.SystemLog("blue-green restart of %s: starting new instance on port %d", name, next.Config().Port)
+	if err := next.Start(supCtx); err != nil {
+		return err
+	}
+
+	readyCtx, cancel := context.WithTimeout(ctx, constants.DefaultReadyTimeout)
+	defer cancel()
+
+	if err := waitReady(readyCtx, next); err != nil {
+		s.SystemLog("blue-green restart of %s failed, keeping old instance: %v", name, err)
+		s.stopInstance(ctx, next)
+		return err
+	}
+
+	if switchFn != nil {
+		if err := switchFn(next.Config().Port); err != nil {
+			s.stopInstance(ctx, next)
+			return fmt.Errorf("switching traffic: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.processes[name] = next
+	delete(s.pendingUpdates, name) // next was created from the current definition
+	s.mu.Unlock()
+
+	s.emit(SupervisorEvent{
+		Type:      EventTypeProcessStarted,
+		Process:   name,
+		Timestamp: time.Now(),
+		Info:      next.Info(),
+	})
+
+	s.SystemLog("blue-green restart of %s: switched to port %d, stopping old instance", name, next.Config().Port)
+	s.stopInstance(ctx, old)
+
+	return nil
+}
+
+// stopInstance stops a process instance that is not (or no longer) registered
+// with the supervisor, logging any error.
+func (s *Supervisor) stopInstance(ctx context.Context, mp *ManagedProcess) {
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.supConfig.ShutdownTimeout)
+	defer cancel()
+
+	if err := mp.Stop(stopCtx); err != nil && err != domain.ErrProcessNotRunning {
+		s.SystemLog("error stopping old instance of %s: %v", mp.Name(), err)
+	}
+}
+
+// waitReady blocks until the process passes its ready probe, if it has one.
+// Otherwise it waits for the process to pass its healthcheck, or to accept
+// TCP connections on its port when no healthcheck of the process itself is
+// configured. Proxy healthchecks aren't used: the proxy may not route to the process yet,
+// and the requests waiting on it would block the check.
+func waitReady(ctx context.Context, mp *ManagedProcess) error {
+	cfg := mp.Config()
+	if cfg.Ready != "" {
+		return mp.WaitReady(ctx)
+	}
+
+	var checker *HealthChecker
+	if cfg.Healthcheck.IsDirect() {
+		checker = NewHealthChecker(cfg.Name, *cfg.Healthcheck)
+		checker.env = mp.env
+	}
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(cfg.Port))
+
+	ticker := time.NewTicker(constants.ReadyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if mp.State() != domain.ProcessStateRunning {
+			return fmt.Errorf("%w: %s exited during startup", domain.ErrProcessNotReady, cfg.Name)
+		}
+
+		if checker != nil {
+			checker.runCheck(ctx)
+			if checker.Status() == domain.HealthStatusHealthy {
+				return nil
+			}
+		} else {
+			dialer := net.Dialer{Timeout: constants.ReadyPollInterval}
+			if conn, err := dialer.DialContext(ctx, "tcp", addr); err == nil {
+				conn.Close()
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %s: %v", domain.ErrProcessNotReady, cfg.Name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// allocatePort finds a free TCP port on localhost.
+func allocatePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
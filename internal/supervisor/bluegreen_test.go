@@ -0,0 +1,163 @@
+package supervisor
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startBlueGreenSupervisor(t *testing.T, healthCmd string) *Supervisor {
+	t.Helper()
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	t.Cleanup(logMgr.Close)
+
+	cfg := makeTestConfig(map[string]string{"worker": "sleep 30"})
+	cfg.Processes["api"] = config.ProcessConfig{
+		Cmd:  "sleep 30",
+		Port: config.PortAuto,
+		Healthcheck: &config.HealthcheckConfig{
+			Cmd:      healthCmd,
+			Interval: "1s",
+			Timeout:  "1s",
+			Retries:  1,
+		},
+	}
+
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	_, err := sup.Start(context.Background())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		sup.Stop(stopCtx)
+	})
+	return sup
+}
+
+func TestSupervisor_AutoPort(t *testing.T) {
+	sup := startBlueGreenSupervisor(t, "true")
+
+	info, err := sup.Process("api")
+	require.NoError(t, err)
+	assert.Greater(t, info.Port, 0)
+	assert.Equal(t, strconv.Itoa(info.Port), info.Env["PORT"])
+
+	worker, err := sup.Process("worker")
+	require.NoError(t, err)
+	assert.Equal(t, 0, worker.Port)
+	assert.NotContains(t, worker.Env, "PORT")
+}
+
+func TestSupervisor_BlueGreenRestart(t *testing.T) {
+	sup := startBlueGreenSupervisor(t, `test -n "$PORT"`)
+
+	before, err := sup.Process("api")
+	require.NoError(t, err)
+
+	var switchedTo int
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = sup.BlueGreenRestart(ctx, "api", func(port int) error {
+		switchedTo = port
+		return nil
+	})
+	require.NoError(t, err)
+
+	after, err := sup.Process("api")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, after.State)
+	assert.NotEqual(t, before.PID, after.PID)
+	assert.NotEqual(t, before.Port, after.Port)
+	assert.Equal(t, after.Port, switchedTo)
+	assert.Equal(t, before.RestartCount+1, after.RestartCount)
+}
+
+func TestSupervisor_BlueGreenRestart_NotReady(t *testing.T) {
+	sup := startBlueGreenSupervisor(t, "false")
+
+	before, err := sup.Process("api")
+	require.NoError(t, err)
+
+	switched := false
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err = sup.BlueGreenRestart(ctx, "api", func(port int) error {
+		switched = true
+		return nil
+	})
+	assert.ErrorIs(t, err, domain.ErrProcessNotReady)
+	assert.False(t, switched)
+
+	// The old instance keeps running
+	after, err := sup.Process("api")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, after.State)
+	assert.Equal(t, before.PID, after.PID)
+	assert.Equal(t, before.Port, after.Port)
+}
+
+func TestSupervisor_BlueGreenRestart_Errors(t *testing.T) {
+	sup := startBlueGreenSupervisor(t, "true")
+	ctx := context.Background()
+
+	t.Run("process not found", func(t *testing.T) {
+		err := sup.BlueGreenRestart(ctx, "missing", nil)
+		assert.ErrorIs(t, err, domain.ErrProcessNotFound)
+	})
+
+	t.Run("port not auto", func(t *testing.T) {
+		err := sup.BlueGreenRestart(ctx, "worker", nil)
+		assert.ErrorIs(t, err, domain.ErrPortNotAuto)
+	})
+
+	t.Run("process not running", func(t *testing.T) {
+		stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		require.NoError(t, sup.StopProcess(stopCtx, "api"))
+
+		err := sup.BlueGreenRestart(ctx, "api", nil)
+		assert.ErrorIs(t, err, domain.ErrProcessNotRunning)
+	})
+}
+
+func TestWaitReady_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	mp := NewManagedProcess(domain.ProcessConfig{Name: "web", Cmd: "sleep 30", Port: port}, nil, NewExecRunner(), logMgr)
+	require.NoError(t, mp.Start(context.Background()))
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		mp.Stop(stopCtx)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, waitReady(ctx, mp))
+}
+
+func TestAllocatePort(t *testing.T) {
+	port, err := allocatePort()
+	require.NoError(t, err)
+	assert.Greater(t, port, 0)
+
+	// The port is free to bind again
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	require.NoError(t, err)
+	listener.Close()
+}
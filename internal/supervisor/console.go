@@ -0,0 +1,104 @@
+package supervisor
+
+import (
+	"io"
+	"sync"
+)
+
+// attachBuffer is how many chunks of output an attached client may fall
+// behind by before output to it is dropped, so a slow client can't hold up
+// the process's logging
+const attachBuffer = 256
+
+// console connects the clients attached with prox attach to the input and
+// output of one instance of a process started with stdin: true
+type console struct {
+	stdin io.WriteCloser
+
+	mu      sync.Mutex
+	clients map[*Attachment]struct{}
+	closed  bool
+}
+
+// newConsole creates a console writing input to stdin
+func newConsole(stdin io.WriteCloser) *console {
+	return &console{stdin: stdin, clients: make(map[*Attachment]struct{})}
+}
+
+// Attachment is a client attached to a process. Output carries what the
+// process writes to stdout and stderr from the time of attaching, and is
+// closed when the process exits or the client detaches.
+type Attachment struct {
+	Output <-chan []byte
+
+	output  chan []byte
+	console *console
+}
+
+// Write writes input to the process's stdin
+func (a *Attachment) Write(p []byte) (int, error) {
+	return a.console.stdin.Write(p)
+}
+
+// Close detaches the client. It is safe to call more than once.
+func (a *Attachment) Close() {
+	a.console.detach(a)
+}
+
+// attach adds a client, or returns false once the process has exited
+func (c *console) attach() (*Attachment, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, false
+	}
+	output := make(chan []byte, attachBuffer)
+	a := &Attachment{Output: output, output: output, console: c}
+	c.clients[a] = struct{}{}
+	return a, true
+}
+
+// detach removes a client, closing its output
+func (c *console) detach(a *Attachment) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.clients[a]; ok {
+		delete(c.clients, a)
+		close(a.output)
+	}
+}
+
+// Write sends output to the attached clients, dropping it for clients that
+// have fallen behind. It never fails, so it can tee the process's output.
+func (c *console) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.clients) == 0 {
+		return len(p), nil
+	}
+	// The reader reuses its buffer
+	chunk := append([]byte(nil), p...)
+	for a := range c.clients {
+		select {
+		case a.output <- chunk:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// close detaches every client and closes the process's stdin, once the
+// process has exited
+func (c *console) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	for a := range c.clients {
+		delete(c.clients, a)
+		close(a.output)
+	}
+	_ = c.stdin.Close()
+}
@@ -0,0 +1,44 @@
+package supervisor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+)
+
+// recordCrashLocked counts a crash at now and reports whether the process
+// has now crashed crashLimit times within crashWindow, which marks it
+// failed. p.mu must be held.
+func (p *ManagedProcess) recordCrashLocked(now time.Time) bool {
+	if p.crashWindow <= 0 || p.crashLimit <= 0 {
+		return false
+	}
+	recent := p.crashes[:0]
+	for _, t := range p.crashes {
+		if now.Sub(t) < p.crashWindow {
+			recent = append(recent, t)
+		}
+	}
+	p.crashes = append(recent, now)
+	if len(p.crashes) < p.crashLimit {
+		return false
+	}
+
+	p.state = domain.ProcessStateFailed
+	p.logManager.Write(domain.LogEntry{
+		Timestamp: now,
+		Process:   p.config.Name,
+		Stream:    domain.StreamStderr,
+		Line: fmt.Sprintf("crashed %d times in %s; not restarting it until it is started with prox start",
+			len(p.crashes), p.crashWindow),
+	})
+	return true
+}
+
+// resetCrashes forgets the process's crashes, when it is started by hand
+func (p *ManagedProcess) resetCrashes() {
+	p.mu.Lock()
+	p.crashes = nil
+	p.mu.Unlock()
+}
@@ -0,0 +1,58 @@
+package supervisor
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+)
+
+// recordStartResult remembers why a process failed to start, or forgets an
+// earlier failure once it starts. A process that is already running didn't
+// fail to start.
+func (s *Supervisor) recordStartResult(name string, err error) {
+	if errors.Is(err, domain.ErrProcessAlreadyRunning) || errors.Is(err, domain.ErrProcessNotFound) {
+		return
+	}
+
+	s.failureMu.Lock()
+	defer s.failureMu.Unlock()
+	if err == nil {
+		delete(s.failures, name)
+		return
+	}
+	if s.failures == nil {
+		s.failures = make(map[string]domain.StartFailure)
+	}
+	s.failures[name] = domain.StartFailure{
+		Process: name,
+		Error:   err.Error(),
+		Time:    time.Now(),
+	}
+}
+
+// lastError returns why the process last failed to start, or "" if it
+// hasn't failed since it last started
+func (s *Supervisor) lastError(name string) string {
+	s.failureMu.Lock()
+	defer s.failureMu.Unlock()
+	return s.failures[name].Error
+}
+
+// StartFailures returns the processes whose last start failed, sorted by
+// name. It includes processes that couldn't be created at all, such as
+// those whose env file is missing.
+func (s *Supervisor) StartFailures() []domain.StartFailure {
+	s.failureMu.Lock()
+	defer s.failureMu.Unlock()
+
+	result := make([]domain.StartFailure, 0, len(s.failures))
+	for _, f := range s.failures {
+		result = append(result, f)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Process < result[j].Process
+	})
+	return result
+}
@@ -3,15 +3,24 @@ package supervisor
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
 	"os/exec"
 	"sync"
 	"time"
 
+	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/watchdog"
 )
 
 // HealthChecker runs periodic health checks for a process.
-// It executes a configured command at regular intervals and tracks the health status.
+// It executes a configured command, requests a URL, or connects to a TCP
+// address at regular intervals and tracks the health status.
 type HealthChecker struct {
 	mu sync.RWMutex
 
@@ -19,6 +28,11 @@ type HealthChecker struct {
 	config domain.HealthConfig
 	// process is the name of the process being checked (for logging)
 	process string
+	// env holds extra environment variables for the check command, URL,
+	// and address (e.g., $PORT)
+	env map[string]string
+	// client requests the check URL, for URL checks
+	client *http.Client
 
 	// status is the current health status (unknown, healthy, or unhealthy)
 	status domain.HealthStatus
@@ -32,6 +46,11 @@ type HealthChecker struct {
 	// ctx and cancel control the health check loop lifecycle
 	ctx    context.Context
 	cancel context.CancelFunc
+	// handle tracks the check loop with the watchdog (nil if untracked)
+	handle *watchdog.Handle
+	// onChange is called with the new status when a check changes it
+	// (optional)
+	onChange func(status domain.HealthStatus)
 }
 
 // NewHealthChecker creates a new health checker
@@ -39,11 +58,15 @@ func NewHealthChecker(process string, config domain.HealthConfig) *HealthChecker
 	// Apply defaults
 	config = config.WithDefaults()
 
-	return &HealthChecker{
+	h := &HealthChecker{
 		config:  config,
 		process: process,
 		status:  domain.HealthStatusUnknown,
 	}
+	if config.URL != "" {
+		h.client = newHealthcheckClient(config.Host)
+	}
+	return h
 }
 
 // Start starts the health checker
@@ -89,7 +112,9 @@ func (h *HealthChecker) Status() domain.HealthStatus {
 func (h *HealthChecker) run() {
 	h.mu.RLock()
 	ctx := h.ctx
+	handle := h.handle
 	h.mu.RUnlock()
+	defer handle.Done()
 
 	// Wait for start period
 	select {
@@ -121,28 +146,21 @@ func (h *HealthChecker) runCheck(ctx context.Context) {
 	checkCtx, cancel := context.WithTimeout(ctx, h.config.Timeout)
 	defer cancel()
 
-	// Run the command
-	cmd := exec.CommandContext(checkCtx, "sh", "-c", h.config.Cmd)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+	var output string
+	var err error
+	switch {
+	case h.config.URL != "":
+		output, err = h.requestURL(checkCtx)
+	case h.config.TCP != "":
+		output, err = h.dialTCP(checkCtx)
+	default:
+		output, err = h.runCmd(checkCtx)
+	}
 
 	h.mu.Lock()
-	defer h.mu.Unlock()
-
+	previous := h.status
 	h.lastCheck = time.Now()
 
-	// Combine stdout and stderr for output
-	output := stdout.String()
-	if stderr.Len() > 0 {
-		if output != "" {
-			output += "\n"
-		}
-		output += stderr.String()
-	}
-
 	// Truncate output if too long
 	if len(output) > 1000 {
 		output = output[:1000] + "..."
@@ -160,4 +178,105 @@ func (h *HealthChecker) runCheck(ctx context.Context) {
 		h.consecutiveFailures = 0
 		h.status = domain.HealthStatusHealthy
 	}
+	status, onChange := h.status, h.onChange
+	h.mu.Unlock()
+
+	if status != previous && onChange != nil {
+		onChange(status)
+	}
+}
+
+// runCmd runs the check command, returning its combined output
+func (h *HealthChecker) runCmd(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.config.Cmd)
+	if len(h.env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range h.env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	// Combine stdout and stderr for output
+	output := stdout.String()
+	if stderr.Len() > 0 {
+		if output != "" {
+			output += "\n"
+		}
+		output += stderr.String()
+	}
+	return output, err
+}
+
+// newHealthcheckClient creates the client for URL checks. Certificates
+// aren't verified: a check through the HTTPS proxy tests the route, not the
+// certificate, which may be self-signed.
+func newHealthcheckClient(host string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{ServerName: host, InsecureSkipVerify: true}, //nolint:gosec // Local proxy
+		},
+		// Report redirects rather than following them away from the proxy
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// expand replaces $VAR and ${VAR} in a check's URL or address with the
+// process's environment, falling back to prox's own
+func (h *HealthChecker) expand(s string) string {
+	return os.Expand(s, func(key string) string {
+		if v, ok := h.env[key]; ok {
+			return v
+		}
+		return os.Getenv(key)
+	})
+}
+
+// requestURL requests the check URL, returning the response status and the
+// start of the body. Responses other than the expected status, or 2xx and
+// 3xx when none is set, are errors.
+func (h *HealthChecker) requestURL(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.expand(h.config.URL), nil)
+	if err != nil {
+		return err.Error(), err
+	}
+	req.Host = h.config.Host
+	req.Header.Set("User-Agent", constants.HealthcheckUserAgent)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err.Error(), err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1000))
+	output := resp.Status
+	if len(body) > 0 {
+		output += "\n" + string(body)
+	}
+	if want := h.config.ExpectedStatus; want != 0 && resp.StatusCode != want {
+		return output, fmt.Errorf("unhealthy response: %s, expected %d", resp.Status, want)
+	}
+	if h.config.ExpectedStatus == 0 && resp.StatusCode >= 400 {
+		return output, fmt.Errorf("unhealthy response: %s", resp.Status)
+	}
+	return output, nil
+}
+
+// dialTCP connects to the check address, returning whether it accepted
+func (h *HealthChecker) dialTCP(ctx context.Context) (string, error) {
+	addr := h.expand(h.config.TCP)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err.Error(), err
+	}
+	conn.Close()
+	return "connected to " + addr, nil
 }
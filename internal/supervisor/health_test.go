@@ -2,11 +2,19 @@ package supervisor
 
 import (
 	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/domain"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHealthChecker_Healthy(t *testing.T) {
@@ -92,6 +100,71 @@ func TestHealthChecker_RecoveryAfterFailure(t *testing.T) {
 	checker.Stop()
 }
 
+func TestHealthChecker_URL(t *testing.T) {
+	status := http.StatusOK
+	var host, userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, userAgent = r.Host, r.UserAgent()
+		w.WriteHeader(status)
+		w.Write([]byte("body"))
+	}))
+	defer server.Close()
+
+	checker := NewHealthChecker("test", domain.HealthConfig{
+		URL:     server.URL + "/health",
+		Host:    "app.local.dev",
+		Retries: 1,
+	})
+
+	checker.runCheck(context.Background())
+	assert.Equal(t, domain.HealthStatusHealthy, checker.Status())
+	assert.Equal(t, "app.local.dev", host)
+	assert.Equal(t, constants.HealthcheckUserAgent, userAgent)
+	assert.Equal(t, "200 OK\nbody", checker.State().LastOutput)
+
+	status = http.StatusBadGateway
+	checker.runCheck(context.Background())
+	assert.Equal(t, domain.HealthStatusUnhealthy, checker.Status())
+}
+
+func TestHealthChecker_URLExpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+
+	checker := NewHealthChecker("test", domain.HealthConfig{
+		URL:            "http://127.0.0.1:$PORT/health",
+		ExpectedStatus: http.StatusNoContent,
+		Retries:        1,
+	})
+	checker.env = map[string]string{"PORT": port}
+	checker.runCheck(context.Background())
+	assert.Equal(t, domain.HealthStatusHealthy, checker.Status())
+
+	checker.config.ExpectedStatus = http.StatusOK
+	checker.runCheck(context.Background())
+	assert.Equal(t, domain.HealthStatusUnhealthy, checker.Status())
+	assert.Equal(t, "204 No Content", checker.State().LastOutput)
+}
+
+func TestHealthChecker_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	checker := NewHealthChecker("test", domain.HealthConfig{TCP: "127.0.0.1:$PORT", Retries: 1})
+	checker.env = map[string]string{"PORT": port}
+	checker.runCheck(context.Background())
+	assert.Equal(t, domain.HealthStatusHealthy, checker.Status())
+	assert.Equal(t, "connected to 127.0.0.1:"+port, checker.State().LastOutput)
+
+	ln.Close()
+	checker.runCheck(context.Background())
+	assert.Equal(t, domain.HealthStatusUnhealthy, checker.Status())
+}
+
 func TestHealthChecker_StartPeriod(t *testing.T) {
 	config := domain.HealthConfig{
 		Cmd:         "true",
@@ -118,3 +191,21 @@ func TestHealthChecker_StartPeriod(t *testing.T) {
 
 	checker.Stop()
 }
+
+func TestHealthChecker_OnChange(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "up")
+	checker := NewHealthChecker("test", domain.HealthConfig{Cmd: "test -f " + marker, Retries: 1})
+	var changes []domain.HealthStatus
+	checker.onChange = func(status domain.HealthStatus) { changes = append(changes, status) }
+
+	ctx := context.Background()
+	checker.runCheck(ctx)
+	require.NoError(t, os.WriteFile(marker, nil, 0o644))
+	checker.runCheck(ctx)
+	checker.runCheck(ctx)
+	require.NoError(t, os.Remove(marker))
+	checker.runCheck(ctx)
+
+	// Only transitions are reported
+	assert.Equal(t, []domain.HealthStatus{domain.HealthStatusUnhealthy, domain.HealthStatusHealthy, domain.HealthStatusUnhealthy}, changes)
+}
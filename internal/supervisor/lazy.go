@@ -0,0 +1,120 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/domain"
+)
+
+// UseProcess marks a lazy process as in use for the duration of a request.
+// If the process is stopped, it is started and UseProcess blocks until it is
+// ready (healthcheck passing, or port accepting connections), up to
+// constants.LazyStartTimeout; concurrent callers wait for the same start.
+// The returned release function must be called when the request finishes.
+// Processes that are not lazy are left alone.
+func (s *Supervisor) UseProcess(ctx context.Context, name string) (release func(), err error) {
+	s.mu.RLock()
+	mp, ok := s.processes[name]
+	s.mu.RUnlock()
+	if !ok || !mp.Config().Lazy {
+		return func() {}, nil
+	}
+
+	release = mp.usage.Begin()
+
+	mp.lazyMu.Lock()
+	defer mp.lazyMu.Unlock()
+	switch mp.State() {
+	case domain.ProcessStateRunning:
+		return release, nil
+	case domain.ProcessStateFailed:
+		// Only a start by hand clears a crash loop
+		release()
+		return nil, fmt.Errorf("starting lazy process %s: %w", name, domain.ErrProcessFailed)
+	}
+
+	s.SystemLog("starting lazy process %s for a proxy request", name)
+	start := time.Now()
+
+	startCtx, cancel := context.WithTimeout(ctx, constants.LazyStartTimeout)
+	defer cancel()
+
+	err = s.StartProcess(startCtx, name)
+	if err == nil || err == domain.ErrProcessAlreadyRunning {
+		err = s.waitAwake(startCtx, mp)
+	}
+	if err != nil {
+		release()
+		s.recordStartResult(name, err)
+		s.SystemLog("lazy process %s failed to start: %v", name, err)
+		return nil, fmt.Errorf("starting lazy process %s: %w", name, err)
+	}
+
+	s.SystemLog("lazy process %s ready after %s", name, time.Since(start).Round(time.Millisecond))
+	return release, nil
+}
+
+// startLazyIdleStoppers stops lazy processes again after their idle timeout
+// passes without requests, for as long as the supervisor runs
+func (s *Supervisor) startLazyIdleStoppers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, mp := range s.processes {
+		s.startLazyIdleStopperLocked(name, mp)
+	}
+}
+
+// startLazyIdleStopperLocked starts stopIdleLazy for a lazy process with an
+// idle timeout, unless one is already watching it. s.mu must be held.
+func (s *Supervisor) startLazyIdleStopperLocked(name string, mp *ManagedProcess) {
+	cfg := mp.Config()
+	if !cfg.Lazy || cfg.IdleTimeout <= 0 || s.lazyStoppers[name] {
+		return
+	}
+	if s.lazyStoppers == nil {
+		s.lazyStoppers = make(map[string]bool)
+	}
+	s.lazyStoppers[name] = true
+	go s.stopIdleLazy(s.ctx, name)
+}
+
+// stopIdleLazy stops a lazy process each time it goes unused for its idle
+// timeout. It returns once the process is no longer configured as lazy.
+func (s *Supervisor) stopIdleLazy(ctx context.Context, name string) {
+	for {
+		// Look the process up each time since a blue-green restart or a
+		// config reload replaces it
+		s.mu.Lock()
+		mp, ok := s.processes[name]
+		if !ok || !mp.Config().Lazy || mp.Config().IdleTimeout <= 0 || ctx.Err() != nil {
+			delete(s.lazyStoppers, name)
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+
+		timeout := mp.Config().IdleTimeout
+		if !mp.usage.Wait(ctx, timeout) {
+			continue
+		}
+
+		// Holding lazyMu keeps a request from using the process while it
+		// stops; a request that began meanwhile shows up as activity
+		mp.lazyMu.Lock()
+		if mp.State() == domain.ProcessStateRunning && mp.usage.IdleFor() >= timeout {
+			s.SystemLog("stopping lazy process %s after %s without requests", name, timeout)
+			if err := s.stopProcess(ctx, name); err != nil && err != domain.ErrProcessNotRunning {
+				s.SystemLog("error stopping %s: %v", name, err)
+			}
+		}
+		mp.lazyMu.Unlock()
+
+		// Start a fresh idle period so a stopped process isn't re-checked
+		// in a tight loop
+		mp.usage.Touch()
+	}
+}
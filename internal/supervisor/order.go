@@ -0,0 +1,199 @@
+package supervisor
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// dependencyBatches groups processes into batches for ordered startup. A
+// process depends on the managed processes in its depends_on, or without
+// one, on those whose port is a loopback wait_for target of it. Every batch
+// only depends on earlier batches; processes caught in a dependency cycle
+// share the final batch. Without dependencies there is a single batch.
+func dependencyBatches(processes map[string]*ManagedProcess) [][]*ManagedProcess {
+	deps := processDependencies(processes)
+
+	var batches [][]*ManagedProcess
+	placed := make(map[string]bool, len(processes))
+	for len(placed) < len(processes) {
+		var ready []string
+		for name := range processes {
+			if placed[name] {
+				continue
+			}
+			satisfied := true
+			for dep := range deps[name] {
+				if !placed[dep] {
+					satisfied = false
+					break
+				}
+			}
+			if satisfied {
+				ready = append(ready, name)
+			}
+		}
+		// A cycle leaves nothing ready; start what remains together
+		if len(ready) == 0 {
+			for name := range processes {
+				if !placed[name] {
+					ready = append(ready, name)
+				}
+			}
+		}
+
+		sort.Strings(ready)
+		batch := make([]*ManagedProcess, 0, len(ready))
+		for _, name := range ready {
+			placed[name] = true
+			batch = append(batch, processes[name])
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// processDependencies returns the processes each process depends on
+func processDependencies(processes map[string]*ManagedProcess) map[string]map[string]bool {
+	deps, _ := resolveDependencies(processes)
+	return deps
+}
+
+// resolveDependencies returns the processes each process depends on, along
+// with notes on the ones inferred from wait_for targets, sorted by process.
+// Each inferred dependency gets a note, as does each loopback target whose
+// port belongs to no managed process or to several, which adds none.
+func resolveDependencies(processes map[string]*ManagedProcess) (map[string]map[string]bool, []string) {
+	byPort := make(map[int][]string)
+	for name, mp := range processes {
+		if port := mp.Config().Port; port > 0 {
+			byPort[port] = append(byPort[port], name)
+		}
+	}
+
+	names := make([]string, 0, len(processes))
+	for name := range processes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	deps := make(map[string]map[string]bool, len(processes))
+	var notes []string
+	for _, name := range names {
+		cfg := processes[name].Config()
+		deps[name] = make(map[string]bool)
+		if cfg.DependsOn != nil {
+			for _, dep := range cfg.DependsOn {
+				if _, ok := processes[dep]; ok && dep != name {
+					deps[name][dep] = true
+				}
+			}
+			continue
+		}
+
+		for _, target := range cfg.WaitFor {
+			port, ok := loopbackTargetPort(target)
+			if !ok {
+				continue
+			}
+			owners := byPort[port]
+			switch {
+			case len(owners) == 0:
+				notes = append(notes, fmt.Sprintf("%s waits for %s, which no managed process listens on; not ordering it after another process", name, target))
+			case len(owners) > 1:
+				sorted := append([]string(nil), owners...)
+				sort.Strings(sorted)
+				notes = append(notes, fmt.Sprintf("%s waits for %s, which %s all listen on; not ordering it after them (use depends_on)", name, target, strings.Join(sorted, ", ")))
+			case owners[0] != name:
+				deps[name][owners[0]] = true
+				notes = append(notes, fmt.Sprintf("%s waits for %s, so it starts after %s", name, target, owners[0]))
+			}
+		}
+	}
+	return deps, notes
+}
+
+// dependencyTargets returns the processes that other processes wait for
+func dependencyTargets(processes map[string]*ManagedProcess) map[string]bool {
+	targets := make(map[string]bool)
+	for _, deps := range processDependencies(processes) {
+		for dep := range deps {
+			targets[dep] = true
+		}
+	}
+	return targets
+}
+
+// loopbackTargetPort returns the port of a wait_for target that points at
+// this machine
+func loopbackTargetPort(target string) (int, bool) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return 0, false
+	}
+	host := u.Hostname()
+	if host != "localhost" {
+		ip := net.ParseIP(host)
+		if ip == nil || !ip.IsLoopback() {
+			return 0, false
+		}
+	}
+
+	portStr := u.Port()
+	if portStr == "" {
+		switch u.Scheme {
+		case "http":
+			portStr = "80"
+		case "https":
+			portStr = "443"
+		default:
+			return 0, false
+		}
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return 0, false
+	}
+	return port, true
+}
+
+// runBatches calls fn for every process, one batch after another, with at
+// most limit calls in flight at once. A limit of 0 or less means unlimited.
+func runBatches(batches [][]*ManagedProcess, limit int, fn func(mp *ManagedProcess)) {
+	var sem chan struct{}
+	if limit > 0 {
+		sem = make(chan struct{}, limit)
+	}
+
+	for _, batch := range batches {
+		var wg sync.WaitGroup
+		for _, mp := range batch {
+			wg.Add(1)
+			if sem != nil {
+				sem <- struct{}{}
+			}
+			go func(mp *ManagedProcess) {
+				defer wg.Done()
+				if sem != nil {
+					defer func() { <-sem }()
+				}
+				fn(mp)
+			}(mp)
+		}
+		wg.Wait()
+	}
+}
+
+// reverseBatches returns the batches in reverse order, so dependents stop
+// before the processes they depend on
+func reverseBatches(batches [][]*ManagedProcess) [][]*ManagedProcess {
+	reversed := make([][]*ManagedProcess, len(batches))
+	for i, batch := range batches {
+		reversed[len(batches)-1-i] = batch
+	}
+	return reversed
+}
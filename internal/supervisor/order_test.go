@@ -0,0 +1,211 @@
+package supervisor
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batchNames(batches [][]*ManagedProcess) [][]string {
+	names := make([][]string, len(batches))
+	for i, batch := range batches {
+		for _, mp := range batch {
+			names[i] = append(names[i], mp.Name())
+		}
+	}
+	return names
+}
+
+func TestDependencyBatches(t *testing.T) {
+	processes := map[string]*ManagedProcess{
+		"db":     NewManagedProcess(domain.ProcessConfig{Name: "db", Port: 5432}, nil, nil, nil),
+		"cache":  NewManagedProcess(domain.ProcessConfig{Name: "cache", Port: 6379}, nil, nil, nil),
+		"api":    NewManagedProcess(domain.ProcessConfig{Name: "api", Port: 8000, WaitFor: []string{"tcp://localhost:5432", "tcp://127.0.0.1:6379"}}, nil, nil, nil),
+		"web":    NewManagedProcess(domain.ProcessConfig{Name: "web", WaitFor: []string{"http://localhost:8000/health"}}, nil, nil, nil),
+		"worker": NewManagedProcess(domain.ProcessConfig{Name: "worker", WaitFor: []string{"tcp://db.example.com:5432"}}, nil, nil, nil),
+	}
+
+	assert.Equal(t, [][]string{
+		{"cache", "db", "worker"},
+		{"api"},
+		{"web"},
+	}, batchNames(dependencyBatches(processes)))
+}
+
+func TestDependencyTargets(t *testing.T) {
+	processes := map[string]*ManagedProcess{
+		"db":  NewManagedProcess(domain.ProcessConfig{Name: "db", Port: 5432}, nil, nil, nil),
+		"api": NewManagedProcess(domain.ProcessConfig{Name: "api", Port: 8000, WaitFor: []string{"tcp://localhost:5432"}}, nil, nil, nil),
+		"web": NewManagedProcess(domain.ProcessConfig{Name: "web", Port: 3000}, nil, nil, nil),
+	}
+
+	assert.Equal(t, map[string]bool{"db": true}, dependencyTargets(processes))
+}
+
+func TestDependencyBatches_NoDependencies(t *testing.T) {
+	processes := map[string]*ManagedProcess{
+		"a": NewManagedProcess(domain.ProcessConfig{Name: "a"}, nil, nil, nil),
+		"b": NewManagedProcess(domain.ProcessConfig{Name: "b"}, nil, nil, nil),
+	}
+
+	assert.Equal(t, [][]string{{"a", "b"}}, batchNames(dependencyBatches(processes)))
+}
+
+func TestDependencyBatches_Cycle(t *testing.T) {
+	processes := map[string]*ManagedProcess{
+		"a":    NewManagedProcess(domain.ProcessConfig{Name: "a", Port: 3001, WaitFor: []string{"tcp://localhost:3002"}}, nil, nil, nil),
+		"b":    NewManagedProcess(domain.ProcessConfig{Name: "b", Port: 3002, WaitFor: []string{"tcp://localhost:3001"}}, nil, nil, nil),
+		"base": NewManagedProcess(domain.ProcessConfig{Name: "base"}, nil, nil, nil),
+	}
+
+	assert.Equal(t, [][]string{{"base"}, {"a", "b"}}, batchNames(dependencyBatches(processes)))
+}
+
+func TestResolveDependencies_UnmanagedPort(t *testing.T) {
+	processes := map[string]*ManagedProcess{
+		"db":  NewManagedProcess(domain.ProcessConfig{Name: "db", Port: 5432}, nil, nil, nil),
+		"api": NewManagedProcess(domain.ProcessConfig{Name: "api", WaitFor: []string{"tcp://localhost:5432", "tcp://localhost:9200"}}, nil, nil, nil),
+	}
+
+	deps, notes := resolveDependencies(processes)
+	assert.Equal(t, map[string]bool{"db": true}, deps["api"])
+	assert.Equal(t, []string{
+		"api waits for tcp://localhost:5432, so it starts after db",
+		"api waits for tcp://localhost:9200, which no managed process listens on; not ordering it after another process",
+	}, notes)
+	assert.Equal(t, [][]string{{"db"}, {"api"}}, batchNames(dependencyBatches(processes)))
+}
+
+func TestResolveDependencies_SharedPort(t *testing.T) {
+	processes := map[string]*ManagedProcess{
+		"blue":  NewManagedProcess(domain.ProcessConfig{Name: "blue", Port: 3000}, nil, nil, nil),
+		"green": NewManagedProcess(domain.ProcessConfig{Name: "green", Port: 3000}, nil, nil, nil),
+		"web":   NewManagedProcess(domain.ProcessConfig{Name: "web", WaitFor: []string{"http://localhost:3000"}}, nil, nil, nil),
+	}
+
+	deps, notes := resolveDependencies(processes)
+	assert.Empty(t, deps["web"])
+	assert.Equal(t, []string{
+		"web waits for http://localhost:3000, which blue, green all listen on; not ordering it after them (use depends_on)",
+	}, notes)
+}
+
+func TestDependencyBatches_DependsOn(t *testing.T) {
+	processes := map[string]*ManagedProcess{
+		"db":     NewManagedProcess(domain.ProcessConfig{Name: "db", Port: 5432}, nil, nil, nil),
+		"cache":  NewManagedProcess(domain.ProcessConfig{Name: "cache", Port: 6379}, nil, nil, nil),
+		"api":    NewManagedProcess(domain.ProcessConfig{Name: "api", WaitFor: []string{"tcp://localhost:5432"}, DependsOn: []string{"cache"}}, nil, nil, nil),
+		"worker": NewManagedProcess(domain.ProcessConfig{Name: "worker", DependsOn: []string{"api", "mailer"}}, nil, nil, nil),
+	}
+
+	// depends_on replaces the order inferred from wait_for, and processes
+	// that aren't being started are ignored
+	deps, notes := resolveDependencies(processes)
+	assert.Equal(t, map[string]bool{"cache": true}, deps["api"])
+	assert.Empty(t, notes)
+	assert.Equal(t, [][]string{
+		{"cache", "db"},
+		{"api"},
+		{"worker"},
+	}, batchNames(dependencyBatches(processes)))
+}
+
+func TestLoopbackTargetPort(t *testing.T) {
+	tests := []struct {
+		target string
+		port   int
+		ok     bool
+	}{
+		{"tcp://localhost:5432", 5432, true},
+		{"tcp://127.0.0.1:6379", 6379, true},
+		{"tcp://[::1]:9000", 9000, true},
+		{"http://localhost/health", 80, true},
+		{"https://localhost/health", 443, true},
+		{"tcp://db.internal:5432", 0, false},
+		{"tcp://localhost", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			port, ok := loopbackTargetPort(tt.target)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.port, port)
+		})
+	}
+}
+
+func TestRunBatches_Limit(t *testing.T) {
+	batch := make([]*ManagedProcess, 6)
+	for i := range batch {
+		batch[i] = NewManagedProcess(domain.ProcessConfig{}, nil, nil, nil)
+	}
+
+	var inFlight, peak, calls atomic.Int32
+	runBatches([][]*ManagedProcess{batch}, 2, func(mp *ManagedProcess) {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		inFlight.Add(-1)
+		calls.Add(1)
+	})
+
+	assert.Equal(t, int32(6), calls.Load())
+	assert.Equal(t, int32(2), peak.Load())
+}
+
+func TestRunBatches_Ordered(t *testing.T) {
+	first := NewManagedProcess(domain.ProcessConfig{Name: "first"}, nil, nil, nil)
+	second := NewManagedProcess(domain.ProcessConfig{Name: "second"}, nil, nil, nil)
+
+	var mu sync.Mutex
+	var order []string
+	runBatches([][]*ManagedProcess{{first}, {second}}, 0, func(mp *ManagedProcess) {
+		if mp.Name() == "first" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		mu.Lock()
+		order = append(order, mp.Name())
+		mu.Unlock()
+	})
+
+	assert.Equal(t, []string{"first", "second"}, order)
+}
+
+func TestSupervisor_StopConcurrency(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{
+		"test1": "sleep 30",
+		"test2": "sleep 30",
+		"test3": "sleep 30",
+	})
+
+	supConfig := DefaultSupervisorConfig()
+	supConfig.StartConcurrency = 1
+	supConfig.StopConcurrency = 1
+	sup := New(cfg, logMgr, nil, supConfig)
+
+	result, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, result.Started, 3)
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, sup.Stop(stopCtx))
+
+	for _, p := range sup.Processes() {
+		assert.True(t, p.State.IsStopped())
+	}
+}
@@ -0,0 +1,150 @@
+package supervisor
+
+import (
+	"sort"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/daemon"
+)
+
+// loadOverrides reads the runtime overrides saved by a previous run, dropping
+// processes that are no longer configured, and saves the overrides in effect
+// now, with this run's scale. With FreshStart, saved overrides are discarded
+// instead.
+func (s *Supervisor) loadOverrides() {
+	dir := s.supConfig.RuntimeStateDir
+	if dir == "" {
+		return
+	}
+
+	s.overrideMu.Lock()
+	defer s.overrideMu.Unlock()
+	s.stoppedByHand = make(map[string]bool)
+	s.addedByHand = make(map[string]daemon.AddedProcess)
+
+	if !s.supConfig.FreshStart {
+		if overrides, err := daemon.LoadRuntimeOverrides(dir); err != nil {
+			s.SystemLog("ignoring runtime overrides: %v", err)
+		} else {
+			s.applyOverridesLocked(overrides)
+		}
+	}
+	s.saveOverridesLocked()
+}
+
+// applyOverridesLocked takes on the saved overrides that still apply: added
+// processes whose name the config hasn't taken since, and processes stopped
+// by hand that still exist. overrideMu must be held.
+func (s *Supervisor) applyOverridesLocked(overrides *daemon.RuntimeOverrides) {
+	for _, added := range overrides.Added {
+		if _, ok := s.config.Processes[added.Name]; ok {
+			s.SystemLog("not restoring process %s added with prox run: the config now has a process of that name", added.Name)
+			continue
+		}
+		if config.ValidateProcessName(added.Name) != nil || added.Cmd == "" {
+			continue
+		}
+		s.addedByHand[added.Name] = added
+	}
+	for _, name := range overrides.Stopped {
+		_, configured := s.config.Processes[name]
+		_, added := s.addedByHand[name]
+		if configured || added {
+			s.stoppedByHand[name] = true
+		}
+	}
+}
+
+// restoreAddedProcesses creates the processes added with prox run before the
+// last shutdown, so they start along with the configured ones
+func (s *Supervisor) restoreAddedProcesses() {
+	s.overrideMu.Lock()
+	added := make([]daemon.AddedProcess, 0, len(s.addedByHand))
+	for _, proc := range s.addedByHand {
+		added = append(added, proc)
+	}
+	s.overrideMu.Unlock()
+
+	for _, proc := range added {
+		mp, err := s.createManagedProcess(proc.Name, config.ProcessConfig{Cmd: proc.Cmd, Env: proc.Env})
+		if err != nil {
+			s.SystemLog("error restoring process %s added with prox run: %v", proc.Name, err)
+			continue
+		}
+		s.mu.Lock()
+		s.processes[proc.Name] = mp
+		s.mu.Unlock()
+		s.SystemLog("restored process %s added with prox run: %s", proc.Name, proc.Cmd)
+	}
+}
+
+// stoppedOverride reports whether the process was stopped by hand and should
+// stay stopped when the supervisor starts
+func (s *Supervisor) stoppedOverride(name string) bool {
+	s.overrideMu.Lock()
+	defer s.overrideMu.Unlock()
+	return s.stoppedByHand[name]
+}
+
+// setStoppedOverride records that a process was stopped or started by hand
+func (s *Supervisor) setStoppedOverride(name string, stopped bool) {
+	if s.supConfig.RuntimeStateDir == "" {
+		return
+	}
+
+	s.overrideMu.Lock()
+	defer s.overrideMu.Unlock()
+	if s.stoppedByHand[name] == stopped {
+		return
+	}
+	if s.stoppedByHand == nil {
+		s.stoppedByHand = make(map[string]bool)
+	}
+	if stopped {
+		s.stoppedByHand[name] = true
+	} else {
+		delete(s.stoppedByHand, name)
+	}
+	s.saveOverridesLocked()
+}
+
+// setAddedOverride records a process added with prox run, or with a nil
+// definition, that one was replaced by a configured process
+func (s *Supervisor) setAddedOverride(name string, added *daemon.AddedProcess) {
+	if s.supConfig.RuntimeStateDir == "" {
+		return
+	}
+
+	s.overrideMu.Lock()
+	defer s.overrideMu.Unlock()
+	if s.addedByHand == nil {
+		s.addedByHand = make(map[string]daemon.AddedProcess)
+	}
+	if added != nil {
+		s.addedByHand[name] = *added
+	} else if _, ok := s.addedByHand[name]; ok {
+		delete(s.addedByHand, name)
+	} else {
+		return
+	}
+	s.saveOverridesLocked()
+}
+
+// saveOverridesLocked writes the overrides to disk. overrideMu must be held.
+func (s *Supervisor) saveOverridesLocked() {
+	overrides := daemon.RuntimeOverrides{Scale: s.supConfig.Scale}
+	for name := range s.stoppedByHand {
+		overrides.Stopped = append(overrides.Stopped, name)
+	}
+	sort.Strings(overrides.Stopped)
+	for _, added := range s.addedByHand {
+		overrides.Added = append(overrides.Added, added)
+	}
+	sort.Slice(overrides.Added, func(i, j int) bool {
+		return overrides.Added[i].Name < overrides.Added[j].Name
+	})
+
+	if err := overrides.Write(s.supConfig.RuntimeStateDir); err != nil {
+		s.SystemLog("error saving runtime overrides: %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+package supervisor
+
+import (
+	"github.com/charliek/prox/internal/logs"
+)
+
+// ProblemMatcher returns the matcher for the problem matchers configured on
+// processes, following config reloads. It is nil if the matchers can't be
+// compiled, which validation rules out for loaded configs.
+func (s *Supervisor) ProblemMatcher() *logs.ProblemMatcher {
+	s.mu.RLock()
+	cfg := s.config
+	s.mu.RUnlock()
+
+	s.problemMu.Lock()
+	defer s.problemMu.Unlock()
+	if cfg != s.problemsConfig {
+		s.problems, _ = logs.NewProblemMatcher(cfg.ProblemMatcherPatterns())
+		s.problemsConfig = cfg
+	}
+	return s.problems
+}
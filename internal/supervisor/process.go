@@ -3,7 +3,10 @@ package supervisor
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"sync"
 	"syscall"
@@ -11,7 +14,9 @@ import (
 
 	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/idle"
 	"github.com/charliek/prox/internal/logs"
+	"github.com/charliek/prox/internal/watchdog"
 )
 
 // outputDrainTimeout is the maximum time to wait for output readers to finish
@@ -27,15 +32,32 @@ type ManagedProcess struct {
 	env        map[string]string
 	runner     ProcessRunner
 	logManager *logs.Manager
+	watchdog   *watchdog.Watchdog // Tracks output readers and health checkers (optional)
 
-	state        domain.ProcessState
-	process      Process
-	startedAt    time.Time
-	restartCount int
+	// Lazy processes: lazyMu serializes request-triggered starts with idle
+	// stops, and usage tracks requests using the process
+	lazyMu sync.Mutex
+	usage  *idle.Tracker
+
+	state         domain.ProcessState
+	process       Process
+	startedAt     time.Time
+	restartCount  int
+	restartReason domain.RestartReason // Why the process last restarted
+
+	// Crash loop detection (see crashloop.go): crashLimit crashes within
+	// crashWindow mark the process failed. crashes holds the recent ones.
+	crashLimit  int
+	crashWindow time.Duration
+	crashes     []time.Time
 
 	// Health checker
 	healthChecker *HealthChecker
 
+	// console connects prox attach clients to the current instance, for
+	// processes with stdin: true
+	console *console
+
 	// Context for the current process instance
 	cancel context.CancelFunc
 
@@ -45,6 +67,20 @@ type ManagedProcess struct {
 
 	// outputWg tracks completion of output reader goroutines
 	outputWg sync.WaitGroup
+
+	// onCrash is called after the process exits unexpectedly, with whether
+	// it crashed often enough to be marked failed (optional)
+	onCrash func(failed bool)
+	// onHealthChange is called when a health check changes the process's
+	// health (optional)
+	onHealthChange func(status domain.HealthStatus)
+
+	// ready is closed once the current instance passes its ready probe; nil
+	// without a probe
+	ready chan struct{}
+	// onReady is called when the ready probe passes, or with the probe's
+	// error once the ready timeout expires (optional)
+	onReady func(err error)
 }
 
 // NewManagedProcess creates a new managed process
@@ -75,12 +111,15 @@ func (p *ManagedProcess) Info() domain.ProcessInfo {
 	defer p.mu.RUnlock()
 
 	info := domain.ProcessInfo{
-		Name:         p.config.Name,
-		State:        p.state,
-		RestartCount: p.restartCount,
-		Health:       domain.HealthStatusUnknown,
-		Cmd:          p.config.Cmd,
-		Env:          p.env,
+		Name:          p.config.Name,
+		State:         p.state,
+		Port:          p.config.Port,
+		RestartCount:  p.restartCount,
+		RestartReason: p.restartReason,
+		Health:        domain.HealthStatusUnknown,
+		Cmd:           p.config.Cmd,
+		Env:           p.env,
+		Lazy:          p.config.Lazy,
 	}
 
 	if p.process != nil {
@@ -136,23 +175,56 @@ func (p *ManagedProcess) Start(ctx context.Context) error {
 
 	p.process = proc
 	p.startedAt = time.Now()
-	p.state = domain.ProcessStateRunning
+	p.ready = nil
+	if p.config.Ready == "" {
+		p.state = domain.ProcessStateRunning
+	} else {
+		// Stay starting until the ready probe passes
+		p.ready = make(chan struct{})
+		go p.awaitReady(processCtx, p.ready, p.done)
+	}
 
-	// Start output readers with WaitGroup tracking
+	// Attached clients see the output as it is read, before it is split
+	// into lines, so prompts without a newline show up
+	p.console = nil
+	if input, ok := proc.(InputProcess); ok {
+		p.console = newConsole(input.Stdin())
+	}
+
+	// Start output readers with WaitGroup tracking. A reader still running
+	// after the process has exited and drained (e.g. a grandchild holds the
+	// pipe open) is leaked; the watchdog closes its pipe.
+	done := p.done
 	p.outputWg.Add(2)
-	go func() {
-		defer p.outputWg.Done()
-		p.readOutput(proc.Stdout(), domain.StreamStdout)
-	}()
-	go func() {
-		defer p.outputWg.Done()
-		p.readOutput(proc.Stderr(), domain.StreamStderr)
-	}()
+	for _, out := range []struct {
+		r      io.Reader
+		stream domain.Stream
+	}{{proc.Stdout(), domain.StreamStdout}, {proc.Stderr(), domain.StreamStderr}} {
+		handle := p.watchdog.Track(watchdog.KindOutputReader, p.config.Name+" "+string(out.stream),
+			func() bool { return watchdog.Closed(done) },
+			func() { closeReader(out.r) })
+		r := out.r
+		if p.console != nil {
+			r = io.TeeReader(r, p.console)
+		}
+		go func(r io.Reader, stream domain.Stream) {
+			defer p.outputWg.Done()
+			defer handle.Done()
+			p.readOutput(r, stream)
+		}(r, out.stream)
+	}
 
-	// Start health checker if configured
-	if p.config.Healthcheck != nil && p.config.Healthcheck.Cmd != "" {
-		p.healthChecker = NewHealthChecker(p.config.Name, *p.config.Healthcheck)
-		p.healthChecker.Start(processCtx)
+	// Start health checker if configured. A checker still running after the
+	// process exited on its own is leaked; the watchdog stops it.
+	if p.config.Healthcheck.IsEnabled() {
+		hc := NewHealthChecker(p.config.Name, *p.config.Healthcheck)
+		hc.env = p.env
+		hc.onChange = p.onHealthChange
+		hc.handle = p.watchdog.Track(watchdog.KindHealthChecker, p.config.Name,
+			func() bool { return watchdog.Closed(done) },
+			hc.Stop)
+		p.healthChecker = hc
+		hc.Start(processCtx)
 	}
 
 	// Monitor the process
@@ -161,11 +233,95 @@ func (p *ManagedProcess) Start(ctx context.Context) error {
 	return nil
 }
 
+// awaitReady runs the ready probe until it passes, then marks the process
+// running. Probing continues past the ready timeout, which only reports that
+// the process isn't ready yet.
+func (p *ManagedProcess) awaitReady(ctx context.Context, ready, done chan struct{}) {
+	timeout := p.config.ReadyTimeout
+	if timeout <= 0 {
+		timeout = constants.DefaultProcessReadyTimeout
+	}
+	start := time.Now()
+	notReady := time.After(timeout)
+
+	ticker := time.NewTicker(constants.ReadyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := probeTarget(ctx, p.config.Ready)
+		if err == nil {
+			p.mu.Lock()
+			if p.state != domain.ProcessStateStarting || p.done != done {
+				p.mu.Unlock()
+				return
+			}
+			p.state = domain.ProcessStateRunning
+			close(ready)
+			p.mu.Unlock()
+
+			p.logManager.Write(domain.LogEntry{
+				Timestamp: time.Now(),
+				Process:   p.config.Name,
+				Stream:    domain.StreamStdout,
+				Line:      fmt.Sprintf("ready after %s", time.Since(start).Round(time.Millisecond)),
+			})
+			if p.onReady != nil {
+				p.onReady(nil)
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-notReady:
+			p.logManager.Write(domain.LogEntry{
+				Timestamp: time.Now(),
+				Process:   p.config.Name,
+				Stream:    domain.StreamStderr,
+				Line:      fmt.Sprintf("not ready after %s: %v", timeout, err),
+			})
+			if p.onReady != nil {
+				p.onReady(fmt.Errorf("%w: %s not ready after %s: %v", domain.ErrProcessNotReady, p.config.Name, timeout, err))
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// WaitReady blocks until the process passes its ready probe, returning
+// immediately for processes without one. It returns domain.ErrProcessNotReady
+// if the process exits or ctx expires first.
+func (p *ManagedProcess) WaitReady(ctx context.Context) error {
+	p.mu.RLock()
+	ready, done := p.ready, p.done
+	p.mu.RUnlock()
+	if ready == nil {
+		return nil
+	}
+
+	select {
+	case <-ready:
+		return nil
+	default:
+	}
+	select {
+	case <-ready:
+		return nil
+	case <-done:
+		return fmt.Errorf("%w: %s exited during startup", domain.ErrProcessNotReady, p.config.Name)
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %s: %v", domain.ErrProcessNotReady, p.config.Name, ctx.Err())
+	}
+}
+
 // Stop stops the process gracefully
 func (p *ManagedProcess) Stop(ctx context.Context) error {
 	p.mu.Lock()
 
-	if p.state == domain.ProcessStateStopped || p.state == domain.ProcessStateCrashed {
+	if p.state.IsStopped() {
 		p.mu.Unlock()
 		return domain.ErrProcessNotRunning
 	}
@@ -244,17 +400,71 @@ func (p *ManagedProcess) Stop(ctx context.Context) error {
 	return nil
 }
 
-// Restart restarts the process
-func (p *ManagedProcess) Restart(ctx context.Context) error {
+// Signal sends sig to the running process, without stopping it unless the
+// process exits on the signal
+func (p *ManagedProcess) Signal(sig os.Signal) error {
+	p.mu.RLock()
+	proc := p.process
+	state := p.state
+	p.mu.RUnlock()
+
+	if proc == nil || (state != domain.ProcessStateRunning && state != domain.ProcessStateStarting) {
+		return domain.ErrProcessNotRunning
+	}
+	return proc.Signal(sig)
+}
+
+// Attach connects a client to the input and output of the running process.
+// It fails with ErrStdinDisabled unless the process has stdin: true.
+func (p *ManagedProcess) Attach() (*Attachment, error) {
+	if !p.config.Stdin {
+		return nil, domain.ErrStdinDisabled
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.process == nil || p.console == nil {
+		return nil, domain.ErrProcessNotRunning
+	}
+	attachment, ok := p.console.attach()
+	if !ok {
+		return nil, domain.ErrProcessNotRunning
+	}
+	return attachment, nil
+}
+
+// Restart restarts the process, recording why
+func (p *ManagedProcess) Restart(ctx context.Context, reason domain.RestartReason) error {
 	if err := p.Stop(ctx); err != nil && err != domain.ErrProcessNotRunning {
 		return err
 	}
+	p.recordRestart(reason)
+	return p.Start(ctx)
+}
 
+// recordRestart counts a restart and notes its reason in the process's log,
+// separating the output of the old instance from the new one's
+func (p *ManagedProcess) recordRestart(reason domain.RestartReason) {
 	p.mu.Lock()
 	p.restartCount++
+	p.restartReason = reason
 	p.mu.Unlock()
 
-	return p.Start(ctx)
+	p.logManager.Write(domain.LogEntry{
+		Timestamp: time.Now(),
+		Process:   p.config.Name,
+		Stream:    domain.StreamStdout,
+		Line:      fmt.Sprintf("restarting (%s)", reason),
+	})
+}
+
+// inheritRestarts carries the restart count and reason of the instance a
+// process replaces
+func (p *ManagedProcess) inheritRestarts(prev *ManagedProcess) {
+	info := prev.Info()
+	p.mu.Lock()
+	p.restartCount = info.RestartCount
+	p.restartReason = info.RestartReason
+	p.mu.Unlock()
 }
 
 // monitor watches for process exit
@@ -290,30 +500,11 @@ func (p *ManagedProcess) monitor() {
 		})
 	}
 
-	// Extract exit code from error
-	// For signal termination, we use negative signal number (e.g., -15 for SIGTERM)
-	exitCode := 0
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
-				if status.Signaled() {
-					// Process was killed by signal - use negative signal number
-					exitCode = -int(status.Signal())
-				} else {
-					exitCode = status.ExitStatus()
-				}
-			} else {
-				exitCode = exitErr.ExitCode()
-			}
-		} else {
-			exitCode = 1 // Generic error
-		}
-	}
+	exitCode := exitCodeOf(err)
 
 	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	if p.state == domain.ProcessStateStopping {
+	crashed := p.state != domain.ProcessStateStopping
+	if !crashed {
 		p.state = domain.ProcessStateStopped
 		// Log the stopped message with exit code
 		p.logManager.Write(domain.LogEntry{
@@ -332,43 +523,93 @@ func (p *ManagedProcess) monitor() {
 			Line:      fmt.Sprintf("exited unexpectedly (rc=%d)", exitCode),
 		})
 	}
+	failed := crashed && p.recordCrashLocked(time.Now())
 
 	p.process = nil
+	if p.console != nil {
+		p.console.close()
+	}
 	p.closeDone()
+	onCrash := p.onCrash
+	p.mu.Unlock()
+
+	if crashed && onCrash != nil {
+		onCrash(failed)
+	}
+}
+
+// exitCodeOf returns the exit code of a process from the error its Wait
+// returned. For signal termination, it is the negative signal number (e.g.,
+// -15 for SIGTERM).
+func exitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			if status.Signaled() {
+				// Process was killed by signal - use negative signal number
+				return -int(status.Signal())
+			}
+			return status.ExitStatus()
+		}
+		return exitErr.ExitCode()
+	}
+	if coder, ok := err.(interface{ ExitCode() int }); ok {
+		return coder.ExitCode()
+	}
+	return 1 // Generic error
 }
 
 // readOutput reads from a stream and writes to the log manager
 func (p *ManagedProcess) readOutput(r interface{}, stream domain.Stream) {
-	reader, ok := r.(interface{ Read([]byte) (int, error) })
+	reader, ok := r.(io.Reader)
 	if !ok || reader == nil {
 		return
 	}
+	logOutput(p.logManager, reader, p.config.Name, p.config.LogFormat, stream)
+}
 
+// logOutput writes each line read from a process's stream to the log
+// manager, under the process's name, until the stream ends
+func logOutput(logManager *logs.Manager, reader io.Reader, name, logFormat string, stream domain.Stream) {
 	scanner := bufio.NewScanner(reader)
 	// Increase buffer size for long lines
 	scanner.Buffer(make([]byte, constants.ScannerBufferSize), constants.ScannerMaxBufferSize)
 
 	for scanner.Scan() {
 		line := scanner.Text()
-		p.logManager.Write(domain.LogEntry{
+		entry := domain.LogEntry{
 			Timestamp: time.Now(),
-			Process:   p.config.Name,
+			Process:   name,
 			Stream:    stream,
 			Line:      line,
-		})
+		}
+		if logFormat != "" {
+			entry.Fields, entry.Level = logs.ParseLine(logFormat, line)
+		}
+		logManager.Write(entry)
 	}
 
-	// Log any scanner errors (e.g., I/O errors during output capture)
-	if err := scanner.Err(); err != nil {
-		p.logManager.Write(domain.LogEntry{
+	// Log any scanner errors (e.g., I/O errors during output capture).
+	// A pipe closed by the watchdog is not an error worth reporting.
+	if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
+		logManager.Write(domain.LogEntry{
 			Timestamp: time.Now(),
-			Process:   p.config.Name,
+			Process:   name,
 			Stream:    domain.StreamStderr,
 			Line:      fmt.Sprintf("output reader error: %v", err),
 		})
 	}
 }
 
+// closeReader closes an output pipe so a blocked reader returns
+func closeReader(r io.Reader) {
+	if c, ok := r.(io.Closer); ok {
+		_ = c.Close()
+	}
+}
+
 // closeDone safely closes the done channel using sync.Once to prevent double-close panic
 func (p *ManagedProcess) closeDone() {
 	p.doneOnce.Do(func() {
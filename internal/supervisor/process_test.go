@@ -92,6 +92,32 @@ func TestManagedProcess_OutputCapture(t *testing.T) {
 	assert.True(t, hasStderr, "stderr should be captured")
 }
 
+func TestManagedProcess_OutputLogFormat(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	mp := NewManagedProcess(domain.ProcessConfig{
+		Name:      "test",
+		Cmd:       `echo '{"level":"error","msg":"db down"}'; echo plain`,
+		LogFormat: domain.LogFormatJSON,
+	}, nil, NewExecRunner(), logMgr)
+
+	require.NoError(t, mp.Start(context.Background()))
+
+	var entries []domain.LogEntry
+	require.Eventually(t, func() bool {
+		entries, _, _ = logMgr.Query(domain.LogFilter{Processes: []string{"test"}, Patterns: []string{"d"}}, 0)
+		return len(entries) >= 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, "error", entries[0].Level)
+	assert.Equal(t, "db down", entries[0].Fields["msg"])
+
+	errorsOnly, _, _ := logMgr.Query(domain.LogFilter{Processes: []string{"test"}, Level: "warn"}, 0)
+	require.Len(t, errorsOnly, 1)
+	assert.Equal(t, `{"level":"error","msg":"db down"}`, errorsOnly[0].Line)
+}
+
 func TestManagedProcess_Restart(t *testing.T) {
 	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
 	defer logMgr.Close()
@@ -114,7 +140,7 @@ func TestManagedProcess_Restart(t *testing.T) {
 	restartCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err = mp.Restart(restartCtx)
+	err = mp.Restart(restartCtx, domain.RestartReasonRequest)
 	require.NoError(t, err)
 
 	assert.Equal(t, domain.ProcessStateRunning, mp.State())
@@ -216,3 +242,53 @@ func TestManagedProcess_CrashLogsExitCode(t *testing.T) {
 
 	assert.True(t, foundCrashedMessage, "should log 'exited unexpectedly (rc=42)' message when process exits with error code")
 }
+
+func TestManagedProcess_Attach(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	// A prompt without a newline reaches attached clients as it is written
+	mp := NewManagedProcess(domain.ProcessConfig{
+		Name:  "repl",
+		Cmd:   `while printf '> '; read line; do echo "got $line"; done`,
+		Stdin: true,
+	}, nil, NewExecRunner(), logMgr)
+
+	_, err := mp.Attach()
+	assert.ErrorIs(t, err, domain.ErrProcessNotRunning)
+
+	require.NoError(t, mp.Start(context.Background()))
+	first, err := mp.Attach()
+	require.NoError(t, err)
+	second, err := mp.Attach()
+	require.NoError(t, err)
+
+	_, err = first.Write([]byte("hi\n"))
+	require.NoError(t, err)
+	for _, a := range []*Attachment{first, second} {
+		var output string
+		for output != "> got hi\n> " {
+			select {
+			case chunk := <-a.Output:
+				output += string(chunk)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out with output %q", output)
+			}
+		}
+	}
+
+	// Detaching leaves the process running
+	first.Close()
+	first.Close()
+	assert.Equal(t, domain.ProcessStateRunning, mp.State())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, mp.Stop(ctx))
+	for range second.Output {
+	}
+
+	plain := NewManagedProcess(domain.ProcessConfig{Name: "plain", Cmd: "sleep 30"}, nil, NewExecRunner(), logMgr)
+	_, err = plain.Attach()
+	assert.ErrorIs(t, err, domain.ErrStdinDisabled)
+}
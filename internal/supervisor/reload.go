@@ -0,0 +1,235 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+)
+
+// ReloadResult describes the changes Reload applied
+type ReloadResult struct {
+	Added   []string         // Newly configured processes
+	Removed []string         // Processes no longer configured, now stopped
+	Changed []string         // Processes whose definition changed, restarted if they were running
+	Failed  map[string]error // Added or changed processes that failed to start
+
+	// Ignored lists changed config sections that only take effect when
+	// prox restarts, such as api or proxy
+	Ignored []string
+}
+
+// HasChanges reports whether the reload added, removed, or changed any process
+func (r ReloadResult) HasChanges() bool {
+	return len(r.Added) > 0 || len(r.Removed) > 0 || len(r.Changed) > 0
+}
+
+// Reload applies a re-read configuration to the running supervisor. It diffs
+// the process definitions against the current ones: removed processes are
+// stopped, added ones are started (lazy ones wait for their first request),
+// and changed ones are replaced, restarting those that were running. A
+// process that failed to be created at startup, e.g. because its env file
+// was missing, is retried. Scaled processes are compared instance by
+// instance, so a changed scale adds or removes instances. Changes outside the
+// processes section are reported in the result but not applied.
+func (s *Supervisor) Reload(ctx context.Context, cfg *config.Config) (ReloadResult, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	result := ReloadResult{Failed: make(map[string]error)}
+	cfg = cfg.Scaled(s.supConfig.Scale)
+
+	s.mu.RLock()
+	if s.state != "running" {
+		s.mu.RUnlock()
+		return result, domain.ErrShutdownInProgress
+	}
+	old := s.config
+	current := make(map[string]*ManagedProcess, len(s.processes))
+	for name, mp := range s.processes {
+		current[name] = mp
+	}
+	s.mu.RUnlock()
+
+	// Global settings every process is started with
+	globalsChanged := old.EnvFile != cfg.EnvFile || old.Shell != cfg.Shell || old.Direnv != cfg.Direnv
+	for name, procConfig := range cfg.Processes {
+		oldConfig, existed := old.Processes[name]
+		_, managed := current[name]
+		switch {
+		case !existed && managed:
+			// Replaces a process added at runtime by prox run
+			result.Changed = append(result.Changed, name)
+			s.setAddedOverride(name, nil)
+		case !existed:
+			result.Added = append(result.Added, name)
+		case !managed:
+			// Left out by prox up <process>, unless it failed to be created
+			if s.lastError(name) != "" {
+				result.Added = append(result.Added, name)
+			}
+		case globalsChanged || !sameProcessConfig(oldConfig, procConfig):
+			result.Changed = append(result.Changed, name)
+		}
+	}
+	for name := range old.Processes {
+		if _, ok := cfg.Processes[name]; !ok {
+			result.Removed = append(result.Removed, name)
+		}
+	}
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Strings(result.Changed)
+	result.Ignored = ignoredSections(old, cfg)
+
+	// Stop removed and changed processes, dependents first, remembering
+	// which changed ones to start again
+	toStop := make(map[string]*ManagedProcess)
+	wasRunning := make(map[string]bool)
+	for _, name := range append(append([]string{}, result.Removed...), result.Changed...) {
+		if mp, ok := current[name]; ok {
+			toStop[name] = mp
+			wasRunning[name] = !mp.State().IsStopped()
+		}
+	}
+	runBatches(reverseBatches(dependencyBatches(toStop)), s.supConfig.StopConcurrency, func(mp *ManagedProcess) {
+		if !wasRunning[mp.Name()] {
+			return
+		}
+		s.stopInstance(ctx, mp)
+		s.emit(SupervisorEvent{
+			Type:      EventTypeProcessStopped,
+			Process:   mp.Name(),
+			Timestamp: time.Now(),
+			Info:      mp.Info(),
+		})
+	})
+
+	s.mu.Lock()
+	s.config = cfg
+	for _, name := range result.Removed {
+		delete(s.processes, name)
+	}
+	// Updates made with UpdateProcess are replaced by the reloaded definitions
+	for _, name := range append(append([]string{}, result.Removed...), result.Changed...) {
+		delete(s.pendingUpdates, name)
+	}
+	s.mu.Unlock()
+
+	// Recompile problem matchers even if cfg is the config already in use
+	s.problemMu.Lock()
+	s.problemsConfig = nil
+	s.problemMu.Unlock()
+
+	for _, name := range result.Removed {
+		s.recordStartResult(name, nil)
+		s.setStoppedOverride(name, false)
+	}
+
+	// Replace changed processes and create added ones
+	toStart := make(map[string]*ManagedProcess)
+	for _, name := range append(append([]string{}, result.Added...), result.Changed...) {
+		mp, err := s.createManagedProcess(name, cfg.Processes[name])
+		s.mu.Lock()
+		if err != nil {
+			delete(s.processes, name)
+			s.mu.Unlock()
+			result.Failed[name] = err
+			s.recordStartResult(name, err)
+			continue
+		}
+		if prev, ok := current[name]; ok {
+			mp.inheritRestarts(prev)
+			if wasRunning[name] {
+				mp.recordRestart(domain.RestartReasonReload)
+			}
+		}
+		s.processes[name] = mp
+		s.startLazyIdleStopperLocked(name, mp)
+		s.mu.Unlock()
+
+		_, existed := current[name]
+		if !mp.Config().Lazy && (!existed || wasRunning[name]) {
+			toStart[name] = mp
+		}
+	}
+
+	started := StartResult{Failed: result.Failed}
+	s.startBatches(&started, toStart)
+	s.watchFiles()
+
+	s.logReload(result)
+	return result, nil
+}
+
+// sameProcessConfig reports whether two definitions of a process run it the
+// same way. Profiles only matter to prox up, problem matchers only to
+// reading its output, and watch settings only to the file watcher, so
+// changing them doesn't restart it.
+func sameProcessConfig(a, b config.ProcessConfig) bool {
+	a.Profiles, b.Profiles = nil, nil
+	a.ProblemMatchers, b.ProblemMatchers = nil, nil
+	a.Watch, b.Watch = nil, nil
+	a.WatchIgnore, b.WatchIgnore = nil, nil
+	a.WatchDebounce, b.WatchDebounce = "", ""
+	return reflect.DeepEqual(a, b)
+}
+
+// ignoredSections returns the config sections other than processes that
+// differ between old and cfg
+func ignoredSections(old, cfg *config.Config) []string {
+	sections := []struct {
+		name     string
+		was, now interface{}
+	}{
+		{"api", old.API, cfg.API},
+		{"proxy", old.Proxy, cfg.Proxy},
+		{"services", old.Services, cfg.Services},
+		{"certs", old.Certs, cfg.Certs},
+		{"redact", old.Redact, cfg.Redact},
+		{"supervisor", old.Supervisor, cfg.Supervisor},
+		{"daemon", old.Daemon, cfg.Daemon},
+		{"streams", old.Streams, cfg.Streams},
+		{"logs", old.Logs, cfg.Logs},
+		{"storage", old.Storage, cfg.Storage},
+	}
+
+	var ignored []string
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.was, section.now) {
+			ignored = append(ignored, section.name)
+		}
+	}
+	return ignored
+}
+
+// logReload writes a summary of a reload to the system log
+func (s *Supervisor) logReload(result ReloadResult) {
+	if !result.HasChanges() {
+		s.SystemLog("config reloaded, no process changes")
+	} else {
+		var parts []string
+		if len(result.Added) > 0 {
+			parts = append(parts, fmt.Sprintf("added %s", strings.Join(result.Added, ", ")))
+		}
+		if len(result.Removed) > 0 {
+			parts = append(parts, fmt.Sprintf("removed %s", strings.Join(result.Removed, ", ")))
+		}
+		if len(result.Changed) > 0 {
+			parts = append(parts, fmt.Sprintf("changed %s", strings.Join(result.Changed, ", ")))
+		}
+		s.SystemLog("config reloaded: %s", strings.Join(parts, "; "))
+	}
+
+	for name, err := range result.Failed {
+		s.SystemLog("%s failed to start after reload: %v", name, err)
+	}
+	if len(result.Ignored) > 0 {
+		s.SystemLog("config changes to %s take effect when prox restarts", strings.Join(result.Ignored, ", "))
+	}
+}
@@ -0,0 +1,129 @@
+package supervisor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupervisor_Reload(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	sup := New(makeTestConfig(map[string]string{
+		"keep":   "sleep 30",
+		"change": "sleep 30",
+		"remove": "sleep 30",
+	}), logMgr, nil, DefaultSupervisorConfig())
+
+	ctx := context.Background()
+	_, err := sup.Start(ctx)
+	require.NoError(t, err)
+	defer sup.Stop(ctx)
+
+	keep, err := sup.Process("keep")
+	require.NoError(t, err)
+
+	cfg := makeTestConfig(map[string]string{
+		"keep":   "sleep 30",
+		"change": "sleep 31",
+		"add":    "sleep 30",
+	})
+	cfg.Processes["lazy"] = config.ProcessConfig{Cmd: "sleep 30", Lazy: true}
+	cfg.API.Port = 5556
+	cfg.Storage = &config.StorageConfig{Backend: config.StorageBackendSQLite}
+
+	result, err := sup.Reload(ctx, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"add", "lazy"}, result.Added)
+	assert.Equal(t, []string{"remove"}, result.Removed)
+	assert.Equal(t, []string{"change"}, result.Changed)
+	assert.Empty(t, result.Failed)
+	assert.Equal(t, []string{"api", "storage"}, result.Ignored)
+
+	_, err = sup.Process("remove")
+	assert.ErrorIs(t, err, domain.ErrProcessNotFound)
+
+	info, err := sup.Process("keep")
+	require.NoError(t, err)
+	assert.Equal(t, keep.PID, info.PID, "unchanged process should keep running")
+
+	info, err = sup.Process("change")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+	assert.Equal(t, "sleep 31", info.Cmd)
+	assert.Equal(t, 1, info.RestartCount)
+	assert.Equal(t, domain.RestartReasonReload, info.RestartReason)
+
+	info, err = sup.Process("add")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+
+	info, err = sup.Process("lazy")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateStopped, info.State, "lazy process should wait for a request")
+
+	// Reloading the same config changes nothing
+	result, err = sup.Reload(ctx, cfg)
+	require.NoError(t, err)
+	assert.False(t, result.HasChanges())
+	assert.Empty(t, result.Ignored)
+
+	// Retagging a process with profiles doesn't restart it
+	keepConfig := cfg.Processes["keep"]
+	keepConfig.Profiles = []string{"backend"}
+	cfg.Processes["keep"] = keepConfig
+	result, err = sup.Reload(ctx, cfg)
+	require.NoError(t, err)
+	assert.False(t, result.HasChanges())
+
+	// Nor does adding problem matchers, which apply right away
+	assert.Empty(t, sup.ProblemMatcher().Processes())
+	keepConfig.ProblemMatchers = []string{"go"}
+	cfg.Processes["keep"] = keepConfig
+	result, err = sup.Reload(ctx, cfg)
+	require.NoError(t, err)
+	assert.False(t, result.HasChanges())
+	assert.Equal(t, []string{"keep"}, sup.ProblemMatcher().Processes())
+}
+
+func TestSupervisor_ReloadKeepsStoppedProcessesStopped(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	sup := New(makeTestConfig(map[string]string{"web": "sleep 30"}), logMgr, nil, DefaultSupervisorConfig())
+
+	ctx := context.Background()
+	_, err := sup.Start(ctx)
+	require.NoError(t, err)
+	defer sup.Stop(ctx)
+
+	stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	require.NoError(t, sup.StopProcess(stopCtx, "web"))
+
+	result, err := sup.Reload(ctx, makeTestConfig(map[string]string{"web": "sleep 31"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web"}, result.Changed)
+
+	info, err := sup.Process("web")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateStopped, info.State)
+	assert.Equal(t, "sleep 31", info.Cmd)
+}
+
+func TestSupervisor_ReloadNotRunning(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	sup := New(makeTestConfig(map[string]string{"web": "sleep 30"}), logMgr, nil, DefaultSupervisorConfig())
+
+	_, err := sup.Reload(context.Background(), makeTestConfig(nil))
+	assert.ErrorIs(t, err, domain.ErrShutdownInProgress)
+}
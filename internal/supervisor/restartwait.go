@@ -0,0 +1,62 @@
+package supervisor
+
+import (
+	"context"
+
+	"github.com/charliek/prox/internal/constants"
+)
+
+// beginRestart marks a process as restarting, so WaitRestart holds callers
+// until it is back. The returned function ends the restart: with the
+// restarted process, once it is ready; with nil, at once.
+func (s *Supervisor) beginRestart(name string) func(mp *ManagedProcess) {
+	done := make(chan struct{})
+	s.restartMu.Lock()
+	if s.restarting == nil {
+		s.restarting = make(map[string]chan struct{})
+	}
+	s.restarting[name] = done
+	s.restartMu.Unlock()
+
+	end := func() {
+		s.restartMu.Lock()
+		if s.restarting[name] == done {
+			delete(s.restarting, name)
+		}
+		s.restartMu.Unlock()
+		close(done)
+	}
+	return func(mp *ManagedProcess) {
+		if mp == nil {
+			end()
+			return
+		}
+		// A restarted process is back once it listens on its port, or
+		// passes its ready probe or healthcheck, so the first request after
+		// a restart isn't refused
+		go func() {
+			defer end()
+			ctx, cancel := context.WithTimeout(s.ctx, constants.LazyStartTimeout)
+			defer cancel()
+			_ = s.waitAwake(ctx, mp)
+		}()
+	}
+}
+
+// WaitRestart blocks while a process is being restarted, until it is ready
+// again or ctx ends, and returns at once otherwise. The proxy calls it so
+// that requests arriving during a restart wait instead of failing.
+func (s *Supervisor) WaitRestart(ctx context.Context, name string) error {
+	s.restartMu.Lock()
+	done, ok := s.restarting[name]
+	s.restartMu.Unlock()
+	if !ok {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
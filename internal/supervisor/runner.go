@@ -3,8 +3,8 @@
 //
 // # Security Model
 //
-// Commands are executed via "sh -c" to support shell features like pipes,
-// redirects, and variable expansion. This means configuration files have
+// Commands are executed via "sh -c", or the shell the config sets, to support
+// shell features like pipes, redirects, and variable expansion. This means configuration files have
 // the same trust level as Makefiles or Procfiles - they can execute arbitrary
 // code. Only use configuration files from trusted sources.
 package supervisor
@@ -15,8 +15,11 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 	"syscall"
 
+	"github.com/charliek/prox/internal/constants"
 	"github.com/charliek/prox/internal/domain"
 )
 
@@ -34,6 +37,13 @@ type Process interface {
 	Stderr() io.Reader
 }
 
+// InputProcess is a Process started with a stdin pipe, for processes with
+// stdin: true. Closing the pipe gives the process end of file.
+type InputProcess interface {
+	Process
+	Stdin() io.WriteCloser
+}
+
 // ExecRunner implements ProcessRunner using os/exec
 type ExecRunner struct{}
 
@@ -50,10 +60,15 @@ func NewExecRunner() *ExecRunner {
 func (r *ExecRunner) Start(ctx context.Context, config domain.ProcessConfig, env map[string]string) (Process, error) {
 	_ = ctx // Explicitly mark as unused - lifecycle managed via Signal()
 
-	cmd := exec.Command("sh", "-c", config.Cmd)
+	args := shellArgs(config)
+	cmd := exec.Command(args[0], args[1:]...)
 
-	// Set up environment
-	cmd.Env = os.Environ()
+	// Set up environment. A non-nil, empty Env keeps the process from
+	// inheriting prox's.
+	cmd.Env = []string{}
+	if !config.CleanEnv {
+		cmd.Env = os.Environ()
+	}
 	for k, v := range env {
 		cmd.Env = append(cmd.Env, k+"="+v)
 	}
@@ -77,6 +92,19 @@ func (r *ExecRunner) Start(ctx context.Context, config domain.ProcessConfig, env
 	cmd.Stdout = stdoutW
 	cmd.Stderr = stderrW
 
+	// Without stdin: true the process reads from /dev/null
+	var stdinR, stdinW *os.File
+	if config.Stdin {
+		if stdinR, stdinW, err = os.Pipe(); err != nil {
+			stdoutR.Close()
+			stdoutW.Close()
+			stderrR.Close()
+			stderrW.Close()
+			return nil, fmt.Errorf("creating stdin pipe: %w", err)
+		}
+		cmd.Stdin = stdinR
+	}
+
 	// Set process group so we can kill all children.
 	// Note: Pdeathsig is intentionally NOT set because it would kill grandchildren
 	// (like uvicorn/node) when the shell wrapper exits, preventing graceful shutdown.
@@ -91,7 +119,11 @@ func (r *ExecRunner) Start(ctx context.Context, config domain.ProcessConfig, env
 		stdoutW.Close()
 		stderrR.Close()
 		stderrW.Close()
-		return nil, fmt.Errorf("starting process: %w", err)
+		if stdinR != nil {
+			stdinR.Close()
+			stdinW.Close()
+		}
+		return nil, fmt.Errorf("starting process: %s: %w", quoteArgs(args), err)
 	}
 
 	// Close write ends in parent - child process has inherited them.
@@ -101,11 +133,46 @@ func (r *ExecRunner) Start(ctx context.Context, config domain.ProcessConfig, env
 	stdoutW.Close()
 	stderrW.Close()
 
-	return &execProcess{
+	proc := &execProcess{
 		cmd:    cmd,
 		stdout: stdoutR,
 		stderr: stderrR,
-	}, nil
+	}
+	if stdinR != nil {
+		stdinR.Close()
+		return &inputProcess{execProcess: proc, stdin: stdinW}, nil
+	}
+	return proc, nil
+}
+
+// shellArgs returns the command line that runs config.Cmd: the shell words,
+// then -c and the whole command as a single argument, prefixed with direnv
+// exec when enabled
+func shellArgs(config domain.ProcessConfig) []string {
+	shell := config.Shell
+	if len(shell) == 0 {
+		shell = []string{constants.DefaultShell}
+	}
+	var args []string
+	if config.Direnv {
+		args = append(args, "direnv", "exec", ".")
+	}
+	args = append(args, shell...)
+	return append(args, "-c", config.Cmd)
+}
+
+// quoteArgs formats a command line for error messages, quoting arguments
+// that contain spaces or quotes so it's clear how the command was split
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$") {
+			quoted[i] = strconv.Quote(arg)
+		} else {
+			quoted[i] = arg
+		}
+	}
+	return strings.Join(quoted, " ")
 }
 
 // execProcess wraps exec.Cmd to implement Process interface
@@ -148,3 +215,13 @@ func (p *execProcess) Stdout() io.Reader {
 func (p *execProcess) Stderr() io.Reader {
 	return p.stderr
 }
+
+// inputProcess is an execProcess with a stdin pipe
+type inputProcess struct {
+	*execProcess
+	stdin io.WriteCloser
+}
+
+func (p *inputProcess) Stdin() io.WriteCloser {
+	return p.stdin
+}
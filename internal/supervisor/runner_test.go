@@ -54,6 +54,26 @@ func TestExecRunner_Start(t *testing.T) {
 		proc.Wait()
 	})
 
+	t.Run("starts without prox's environment", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		t.Setenv("PROX_TEST_INHERITED", "inherited")
+
+		proc, err := runner.Start(ctx, domain.ProcessConfig{
+			Name:     "test",
+			Cmd:      "echo \"[$PROX_TEST_INHERITED][$TEST_VAR]\"",
+			CleanEnv: true,
+		}, map[string]string{"TEST_VAR": "test_value"})
+
+		require.NoError(t, err)
+
+		output, err := io.ReadAll(proc.Stdout())
+		require.NoError(t, err)
+		assert.Contains(t, string(output), "[][test_value]")
+
+		proc.Wait()
+	})
+
 	t.Run("captures stderr", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -124,6 +144,38 @@ func TestExecRunner_Start(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("runs through the configured shell", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// env sets a variable before running sh, so the shell words are
+		// passed through as separate arguments
+		proc, err := runner.Start(ctx, domain.ProcessConfig{
+			Name:  "test",
+			Cmd:   "echo shell:$SHELL_MARK",
+			Shell: []string{"env", "SHELL_MARK=zsh", "sh"},
+		}, nil)
+		require.NoError(t, err)
+
+		output, err := io.ReadAll(proc.Stdout())
+		require.NoError(t, err)
+		assert.Contains(t, string(output), "shell:zsh")
+		assert.NoError(t, proc.Wait())
+	})
+
+	t.Run("missing shell reports the command line", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_, err := runner.Start(ctx, domain.ProcessConfig{
+			Name:  "test",
+			Cmd:   "npm run dev",
+			Shell: []string{"/nonexistent/zsh", "-l"},
+		}, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `/nonexistent/zsh -l -c "npm run dev"`)
+	})
+
 	t.Run("command exits with error code", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -178,3 +230,20 @@ func TestExecRunner_Start(t *testing.T) {
 		<-done
 	})
 }
+
+func TestShellArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		config domain.ProcessConfig
+		want   []string
+	}{
+		{"default shell", domain.ProcessConfig{Cmd: "npm run dev"}, []string{"sh", "-c", "npm run dev"}},
+		{"login shell", domain.ProcessConfig{Cmd: "npm run dev", Shell: []string{"/bin/zsh", "-l"}}, []string{"/bin/zsh", "-l", "-c", "npm run dev"}},
+		{"direnv", domain.ProcessConfig{Cmd: "npm run dev", Direnv: true}, []string{"direnv", "exec", ".", "sh", "-c", "npm run dev"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, shellArgs(tt.config))
+		})
+	}
+}
@@ -1,8 +1,14 @@
 package supervisor
 
 import (
+	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
+
+	"github.com/charliek/prox/internal/domain"
 )
 
 // Signal definitions for cross-platform compatibility
@@ -10,3 +16,59 @@ var (
 	sigterm os.Signal = syscall.SIGTERM
 	sigkill os.Signal = syscall.SIGKILL
 )
+
+// signalNames are the signals that can be sent to a process, by name
+// without the SIG prefix
+var signalNames = map[string]syscall.Signal{
+	"HUP":   syscall.SIGHUP,
+	"INT":   syscall.SIGINT,
+	"QUIT":  syscall.SIGQUIT,
+	"KILL":  syscall.SIGKILL,
+	"USR1":  syscall.SIGUSR1,
+	"USR2":  syscall.SIGUSR2,
+	"ALRM":  syscall.SIGALRM,
+	"TERM":  syscall.SIGTERM,
+	"CONT":  syscall.SIGCONT,
+	"STOP":  syscall.SIGSTOP,
+	"TSTP":  syscall.SIGTSTP,
+	"TTIN":  syscall.SIGTTIN,
+	"TTOU":  syscall.SIGTTOU,
+	"WINCH": syscall.SIGWINCH,
+}
+
+// ParseSignal parses a signal name such as "SIGUSR2", "usr2", or "12"
+func ParseSignal(name string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(name); err == nil {
+		for _, sig := range signalNames {
+			if int(sig) == n {
+				return sig, nil
+			}
+		}
+		return 0, fmt.Errorf("%w: %s", domain.ErrInvalidSignal, name)
+	}
+	upper := strings.ToUpper(name)
+	if sig, ok := signalNames[strings.TrimPrefix(upper, "SIG")]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("%w: %s", domain.ErrInvalidSignal, name)
+}
+
+// SignalNames returns the names of the signals ParseSignal accepts, sorted
+func SignalNames() []string {
+	names := make([]string, 0, len(signalNames))
+	for name := range signalNames {
+		names = append(names, "SIG"+name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SignalName returns a signal's name, e.g. "SIGUSR2"
+func SignalName(sig syscall.Signal) string {
+	for name, s := range signalNames {
+		if s == sig {
+			return "SIG" + name
+		}
+	}
+	return "signal " + strconv.Itoa(int(sig))
+}
@@ -0,0 +1,117 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/domain"
+)
+
+// Sleep stops every running process so an idle daemon uses no resources,
+// remembering them so Wake can start them again. Processes stop in reverse
+// dependency order, like Stop. It returns the names of the processes stopped.
+func (s *Supervisor) Sleep(ctx context.Context) []string {
+	s.sleepMu.Lock()
+	defer s.sleepMu.Unlock()
+
+	s.mu.RLock()
+	running := make(map[string]*ManagedProcess)
+	for name, mp := range s.processes {
+		if state := mp.State(); state == domain.ProcessStateRunning || state == domain.ProcessStateStarting {
+			running[name] = mp
+		}
+	}
+	s.mu.RUnlock()
+
+	runBatches(reverseBatches(dependencyBatches(running)), s.supConfig.StopConcurrency, func(mp *ManagedProcess) {
+		if err := s.stopProcess(ctx, mp.Name()); err != nil && err != domain.ErrProcessNotRunning {
+			s.SystemLog("error stopping %s: %v", mp.Name(), err)
+		}
+	})
+
+	names := make([]string, 0, len(running))
+	for name := range running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	s.sleeping = append(s.sleeping, names...)
+	s.asleep.Store(true)
+	return names
+}
+
+// Asleep reports whether processes were stopped by Sleep and not yet woken
+func (s *Supervisor) Asleep() bool {
+	return s.asleep.Load()
+}
+
+// Wake starts the processes stopped by Sleep and waits until they are ready:
+// passing their healthcheck, or accepting connections on their port. It
+// returns immediately when the supervisor is not asleep; concurrent callers
+// wait for a single wake-up.
+func (s *Supervisor) Wake(ctx context.Context) error {
+	if !s.asleep.Load() {
+		return nil
+	}
+
+	s.sleepMu.Lock()
+	defer s.sleepMu.Unlock()
+	if !s.asleep.Load() {
+		return nil
+	}
+
+	start := time.Now()
+	s.mu.RLock()
+	sleeping := make(map[string]*ManagedProcess, len(s.sleeping))
+	for _, name := range s.sleeping {
+		if mp, ok := s.processes[name]; ok {
+			sleeping[name] = mp
+		}
+	}
+	s.mu.RUnlock()
+
+	readyCtx, cancel := context.WithTimeout(ctx, constants.DefaultReadyTimeout)
+	defer cancel()
+
+	var errMu sync.Mutex
+	var errs []error
+	runBatches(dependencyBatches(sleeping), s.supConfig.StartConcurrency, func(mp *ManagedProcess) {
+		err := s.StartProcess(readyCtx, mp.Name())
+		if err == nil || err == domain.ErrProcessAlreadyRunning {
+			err = s.waitAwake(readyCtx, mp)
+		}
+		if err != nil {
+			errMu.Lock()
+			errs = append(errs, fmt.Errorf("%s: %w", mp.Name(), err))
+			errMu.Unlock()
+		}
+	})
+
+	// Even on failure, the processes are no longer asleep: those that
+	// started keep running, and the rest can be started by hand
+	s.sleeping = nil
+	s.asleep.Store(false)
+
+	if len(errs) > 0 {
+		err := errors.Join(errs...)
+		s.SystemLog("woke processes with errors after %s: %v", time.Since(start).Round(time.Millisecond), err)
+		return err
+	}
+	s.SystemLog("woke %d processes in %s", len(sleeping), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// waitAwake waits for a woken process to become ready. Processes without a
+// port, a ready probe, or a healthcheck of the process itself are considered
+// ready once started.
+func (s *Supervisor) waitAwake(ctx context.Context, mp *ManagedProcess) error {
+	cfg := mp.Config()
+	if cfg.Port == 0 && cfg.Ready == "" && !cfg.Healthcheck.IsDirect() {
+		return nil
+	}
+	return waitReady(ctx, mp)
+}
@@ -3,25 +3,59 @@ package supervisor
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/daemon"
 	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/idle"
 	"github.com/charliek/prox/internal/logs"
+	"github.com/charliek/prox/internal/watch"
+	"github.com/charliek/prox/internal/watchdog"
 )
 
 // SupervisorConfig holds configuration for the supervisor
 type SupervisorConfig struct {
-	ShutdownTimeout time.Duration
-	ConfigDir       string // Directory containing the config file (for resolving relative paths)
+	ShutdownTimeout  time.Duration
+	ConfigDir        string // Directory containing the config file (for resolving relative paths)
+	StartConcurrency int    // Max processes starting at once (0 = unlimited)
+	StopConcurrency  int    // Max processes stopping at once (0 = unlimited)
+
+	// A process that crashes CrashLimit times within CrashWindow is marked
+	// failed, and isn't restarted until it is started by hand (0 = never)
+	CrashLimit  int
+	CrashWindow time.Duration
+
+	// RuntimeStateDir is the project directory whose .prox/runtime.json
+	// keeps processes stopped by hand stopped, and processes added with
+	// AddProcess and the Scale in effect, across restarts (empty disables
+	// it). FreshStart discards the saved overrides.
+	RuntimeStateDir string
+	FreshStart      bool
+
+	// PromptedEnv holds the values entered for env_prompt variables. They
+	// are only kept in memory, and given to the processes listing them that
+	// don't set them otherwise.
+	PromptedEnv map[string]string
+
+	// Scale overrides the scale of processes by name, as set by prox up
+	// --scale. It is applied again when the config is reloaded.
+	Scale map[string]int
 }
 
 // DefaultSupervisorConfig returns default configuration
 func DefaultSupervisorConfig() SupervisorConfig {
 	return SupervisorConfig{
 		ShutdownTimeout: 10 * time.Second,
+		CrashLimit:      constants.DefaultCrashLimit,
+		CrashWindow:     constants.DefaultCrashWindow,
 	}
 }
 
@@ -40,6 +74,8 @@ type Supervisor struct {
 	runner ProcessRunner
 	// logManager handles log collection and subscription
 	logManager *logs.Manager
+	// watchdog tracks stream goroutines and cleans up leaked ones
+	watchdog *watchdog.Watchdog
 
 	// startedAt records when the supervisor was started
 	startedAt time.Time
@@ -50,10 +86,61 @@ type Supervisor struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	// eventMu protects eventSubs from concurrent access
-	eventMu sync.RWMutex
+	// watcher restarts processes with watch patterns when their files
+	// change, protected by mu (nil until a process is watched)
+	watcher *watch.Watcher
+
+	// lazyStoppers tracks the lazy processes watched by stopIdleLazy,
+	// protected by mu
+	lazyStoppers map[string]bool
+
+	// restartMu protects restarting, which holds a channel for each process
+	// being restarted, closed once it is ready again (see WaitRestart)
+	restartMu  sync.Mutex
+	restarting map[string]chan struct{}
+
+	// reloadMu serializes config reloads
+	reloadMu sync.Mutex
+
+	// sleepMu serializes Sleep and Wake; sleeping lists the processes
+	// stopped by Sleep, and asleep is set until they are woken
+	sleepMu  sync.Mutex
+	sleeping []string
+	asleep   atomic.Bool
+
+	// failureMu protects failures, the last start error of each process
+	// that failed to start
+	failureMu sync.Mutex
+	failures  map[string]domain.StartFailure
+
+	// overrideMu protects stoppedByHand, the processes stopped through
+	// StopProcess, and addedByHand, the processes added with AddProcess,
+	// persisted as runtime overrides
+	overrideMu    sync.Mutex
+	stoppedByHand map[string]bool
+	addedByHand   map[string]daemon.AddedProcess
+
+	// pendingUpdates lists the processes updated with UpdateProcess whose
+	// current instance still runs the old definition, protected by mu
+	pendingUpdates map[string]bool
+
+	// problemMu protects problems, the problem matcher compiled from
+	// problemsConfig, rebuilt when the config changes
+	problemMu      sync.Mutex
+	problems       *logs.ProblemMatcher
+	problemsConfig *config.Config
+
+	// taskMu protects tasks, the last run of each task run with RunTask
+	taskMu sync.Mutex
+	tasks  map[string]*taskRun
+
+	// eventMu protects eventSubs and events from concurrent access
+	eventMu sync.Mutex
 	// eventSubs holds channels for subscribers to supervisor events
 	eventSubs []chan SupervisorEvent
+	// events holds the last constants.SupervisorEventHistory events, oldest
+	// first
+	events []SupervisorEvent
 }
 
 // SupervisorEvent represents a supervisor event
@@ -71,8 +158,13 @@ const (
 	EventTypeProcessStarted  EventType = "process_started"
 	EventTypeProcessStopped  EventType = "process_stopped"
 	EventTypeProcessCrashed  EventType = "process_crashed"
+	EventTypeProcessFailed   EventType = "process_failed" // Crashed too often; not restarted until started by hand
 	EventTypeSupervisorStart EventType = "supervisor_start"
 	EventTypeSupervisorStop  EventType = "supervisor_stop"
+
+	// Health check transitions of a process
+	EventTypeProcessHealthy   EventType = "process_healthy"
+	EventTypeProcessUnhealthy EventType = "process_unhealthy"
 )
 
 // New creates a new supervisor
@@ -80,6 +172,9 @@ func New(cfg *config.Config, logManager *logs.Manager, runner ProcessRunner, sup
 	if runner == nil {
 		runner = NewExecRunner()
 	}
+	if cfg != nil {
+		cfg = cfg.Scaled(supConfig.Scale)
+	}
 
 	s := &Supervisor{
 		config:     cfg,
@@ -87,6 +182,7 @@ func New(cfg *config.Config, logManager *logs.Manager, runner ProcessRunner, sup
 		processes:  make(map[string]*ManagedProcess),
 		runner:     runner,
 		logManager: logManager,
+		watchdog:   watchdog.New(),
 		state:      "stopped",
 	}
 
@@ -98,12 +194,17 @@ func (s *Supervisor) Start(ctx context.Context) (StartResult, error) {
 	return s.startWithFilter(ctx, nil)
 }
 
-// StartProcesses starts only the specified processes
+// StartProcesses starts only the specified processes. A scaled process's
+// name starts all of its instances.
 func (s *Supervisor) StartProcesses(ctx context.Context, names []string) (StartResult, error) {
 	nameSet := make(map[string]bool)
+	s.mu.RLock()
 	for _, name := range names {
-		nameSet[name] = true
+		for _, instance := range s.config.Instances(name) {
+			nameSet[instance] = true
+		}
 	}
+	s.mu.RUnlock()
 	return s.startWithFilter(ctx, nameSet)
 }
 
@@ -125,6 +226,8 @@ func (s *Supervisor) startWithFilter(ctx context.Context, filter map[string]bool
 	s.startedAt = time.Now()
 	s.mu.Unlock()
 
+	go s.watchdog.Run(s.ctx, constants.WatchdogInterval)
+
 	s.emit(SupervisorEvent{
 		Type:      EventTypeSupervisorStart,
 		Timestamp: time.Now(),
@@ -140,6 +243,7 @@ func (s *Supervisor) startWithFilter(ctx context.Context, filter map[string]bool
 		mp, err := s.createManagedProcess(name, procConfig)
 		if err != nil {
 			result.Failed[name] = err
+			s.recordStartResult(name, err)
 			continue
 		}
 
@@ -148,8 +252,17 @@ func (s *Supervisor) startWithFilter(ctx context.Context, filter map[string]bool
 		s.mu.Unlock()
 	}
 
-	// Start all processes concurrently
-	s.startProcessesConcurrently(&result)
+	// Start all processes concurrently. Lazy processes wait for their first
+	// request, and processes stopped by hand before the last shutdown stay
+	// stopped, unless they were named explicitly. Processes added with prox
+	// run come back too, unless only some processes were asked for.
+	s.loadOverrides()
+	if filter == nil {
+		s.restoreAddedProcesses()
+	}
+	s.startProcessesConcurrently(&result, filter != nil)
+	s.startLazyIdleStoppers()
+	s.watchFiles()
 
 	return result, nil
 }
@@ -157,7 +270,14 @@ func (s *Supervisor) startWithFilter(ctx context.Context, filter map[string]bool
 // createManagedProcess creates a new managed process from configuration.
 func (s *Supervisor) createManagedProcess(name string, procConfig config.ProcessConfig) (*ManagedProcess, error) {
 	// Load environment for this process
-	env, err := config.LoadProcessEnv(s.config.EnvFile, procConfig.EnvFile, procConfig.Env, s.supConfig.ConfigDir)
+	s.mu.RLock()
+	globalEnvFile, globalEnv := s.config.EnvFile, s.config.Env
+	healthcheck := s.config.ProcessHealthcheck(name)
+	shell := s.config.ShellFor(procConfig)
+	direnv := s.config.DirenvFor(procConfig)
+	dependsOn := s.config.DependenciesOf(procConfig)
+	s.mu.RUnlock()
+	env, err := config.LoadProcessEnv(globalEnvFile, globalEnv, procConfig.EnvFile, procConfig.Env, s.supConfig.ConfigDir)
 	if err != nil {
 		s.logManager.Write(domain.LogEntry{
 			Timestamp: time.Now(),
@@ -167,55 +287,171 @@ func (s *Supervisor) createManagedProcess(name string, procConfig config.Process
 		})
 		return nil, fmt.Errorf("failed to load environment: %w", err)
 	}
+	config.AddPromptedEnv(env, procConfig.EnvPrompt, s.supConfig.PromptedEnv)
 
 	domainConfig := domain.ProcessConfig{
-		Name:    name,
-		Cmd:     procConfig.Cmd,
-		Env:     env,
-		EnvFile: procConfig.EnvFile,
-	}
-	if procConfig.Healthcheck != nil {
-		domainConfig.Healthcheck = &domain.HealthConfig{
-			Cmd: procConfig.Healthcheck.Cmd,
+		Name:        name,
+		Cmd:         procConfig.Cmd,
+		Shell:       shell,
+		Direnv:      direnv,
+		Env:         env,
+		EnvFile:     procConfig.EnvFile,
+		CleanEnv:    !procConfig.InheritsEnv(),
+		Port:        procConfig.FixedPort(),
+		AutoPort:    procConfig.AutoPort(),
+		WaitFor:     procConfig.WaitFor,
+		WaitTimeout: procConfig.WaitTimeoutDuration(),
+		DependsOn:   dependsOn,
+		Lazy:        procConfig.Lazy,
+		IdleTimeout: procConfig.IdleTimeoutDuration(),
+		Healthcheck: healthcheck,
+		LogBuffer:   procConfig.LogBuffer,
+		LogThrottle: procConfig.LogThrottle(),
+		LogFormat:   procConfig.LogFormat,
+		Stdin:       procConfig.Stdin,
+		Synthetic:   procConfig.SyntheticProcess(),
+	}
+
+	// Allocate a port for port: auto processes and expose it as $PORT
+	if domainConfig.AutoPort {
+		port, err := allocatePort()
+		if err != nil {
+			return nil, fmt.Errorf("allocating port: %w", err)
+		}
+		domainConfig.Port = port
+	}
+	if domainConfig.Port > 0 {
+		if env == nil {
+			env = make(map[string]string)
 		}
+		env["PORT"] = strconv.Itoa(domainConfig.Port)
+		domainConfig.Env = env
+	}
+	if procConfig.Ready != "" {
+		domainConfig.Ready = procConfig.ReadyTarget(domainConfig.Port)
+		domainConfig.ReadyTimeout = procConfig.ReadyTimeoutDuration()
 	}
 
-	return NewManagedProcess(domainConfig, env, s.runner, s.logManager), nil
+	s.logManager.SetProcessBufferSize(name, domainConfig.LogBuffer)
+	s.logManager.SetProcessThrottle(name, domainConfig.LogThrottle)
+	runner := s.runner
+	if domainConfig.Synthetic != nil {
+		runner = syntheticRunner{}
+	}
+	mp := NewManagedProcess(domainConfig, env, runner, s.logManager)
+	mp.watchdog = s.watchdog
+	mp.crashLimit = s.supConfig.CrashLimit
+	mp.crashWindow = s.supConfig.CrashWindow
+	mp.onReady = func(err error) {
+		s.recordStartResult(name, err)
+	}
+	mp.onCrash = func(failed bool) {
+		s.emit(SupervisorEvent{
+			Type:      EventTypeProcessCrashed,
+			Process:   name,
+			Timestamp: time.Now(),
+			Info:      mp.Info(),
+		})
+		if failed {
+			s.emit(SupervisorEvent{
+				Type:      EventTypeProcessFailed,
+				Process:   name,
+				Timestamp: time.Now(),
+				Info:      mp.Info(),
+			})
+		}
+	}
+	mp.onHealthChange = func(status domain.HealthStatus) {
+		eventType := EventTypeProcessHealthy
+		if status == domain.HealthStatusUnhealthy {
+			eventType = EventTypeProcessUnhealthy
+		}
+		s.emit(SupervisorEvent{
+			Type:      eventType,
+			Process:   name,
+			Timestamp: time.Now(),
+			Info:      mp.Info(),
+		})
+	}
+	if domainConfig.Lazy {
+		mp.usage = idle.NewTracker()
+	}
+	return mp, nil
 }
 
 // startProcessesConcurrently starts all managed processes concurrently and updates the result.
-func (s *Supervisor) startProcessesConcurrently(result *StartResult) {
-	var wg sync.WaitGroup
-	var resultMu sync.Mutex
-
+// At most StartConcurrency processes start at once, and processes that wait
+// for another process's port start in a later batch than that process.
+// Lazy processes and processes stopped by hand are skipped unless the
+// processes were named explicitly.
+func (s *Supervisor) startProcessesConcurrently(result *StartResult, named bool) {
+	s.mu.RLock()
+	processes := make(map[string]*ManagedProcess, len(s.processes))
 	for name, mp := range s.processes {
-		wg.Add(1)
-		go func(name string, mp *ManagedProcess) {
-			defer wg.Done()
-			if err := mp.Start(s.ctx); err != nil {
-				s.logManager.Write(domain.LogEntry{
-					Timestamp: time.Now(),
-					Process:   name,
-					Stream:    domain.StreamStderr,
-					Line:      fmt.Sprintf("Failed to start: %v", err),
-				})
-				resultMu.Lock()
-				result.Failed[name] = err
-				resultMu.Unlock()
-			} else {
-				s.emit(SupervisorEvent{
-					Type:      EventTypeProcessStarted,
-					Process:   name,
-					Timestamp: time.Now(),
-					Info:      mp.Info(),
-				})
-				resultMu.Lock()
-				result.Started = append(result.Started, name)
-				resultMu.Unlock()
-			}
-		}(name, mp)
+		if !named && mp.Config().Lazy {
+			s.SystemLog("%s is lazy; it starts on the first proxy request", name)
+			continue
+		}
+		if !named && s.stoppedOverride(name) {
+			s.SystemLog("%s was stopped before the last shutdown; not starting it (use --fresh to start everything)", name)
+			continue
+		}
+		processes[name] = mp
 	}
-	wg.Wait()
+	s.mu.RUnlock()
+
+	s.startBatches(result, processes)
+}
+
+// startBatches starts the given processes in dependency order, at most
+// StartConcurrency at once, and adds each outcome to the result. The order
+// inferred from wait_for targets is logged. Processes
+// with a ready probe that others wait for must be ready before the next
+// batch starts.
+func (s *Supervisor) startBatches(result *StartResult, processes map[string]*ManagedProcess) {
+	var resultMu sync.Mutex
+	_, notes := resolveDependencies(processes)
+	for _, note := range notes {
+		s.SystemLog("%s", note)
+	}
+	dependedOn := dependencyTargets(processes)
+	runBatches(dependencyBatches(processes), s.supConfig.StartConcurrency, func(mp *ManagedProcess) {
+		name := mp.Name()
+		err := s.waitForDependencies(s.ctx, mp)
+		if err == nil {
+			err = mp.Start(s.ctx)
+		}
+		if err == nil && dependedOn[name] {
+			// Hold back the processes that wait for this one until it is
+			// ready. If it isn't, they start anyway and wait on their own.
+			if err := s.waitProcessReady(mp); err != nil {
+				s.SystemLog("starting processes that wait for %s anyway: %v", name, err)
+			}
+		}
+		s.recordStartResult(name, err)
+		if err != nil {
+			s.logManager.Write(domain.LogEntry{
+				Timestamp: time.Now(),
+				Process:   name,
+				Stream:    domain.StreamStderr,
+				Line:      fmt.Sprintf("Failed to start: %v", err),
+			})
+			resultMu.Lock()
+			result.Failed[name] = err
+			resultMu.Unlock()
+		} else {
+			s.setStoppedOverride(name, false)
+			s.emit(SupervisorEvent{
+				Type:      EventTypeProcessStarted,
+				Process:   name,
+				Timestamp: time.Now(),
+				Info:      mp.Info(),
+			})
+			resultMu.Lock()
+			result.Started = append(result.Started, name)
+			resultMu.Unlock()
+		}
+	})
 }
 
 // Stop stops all processes and the supervisor
@@ -226,49 +462,48 @@ func (s *Supervisor) Stop(ctx context.Context) error {
 		return nil
 	}
 	s.state = "stopping"
-	processes := make([]*ManagedProcess, 0, len(s.processes))
-	for _, mp := range s.processes {
-		processes = append(processes, mp)
+	processes := make(map[string]*ManagedProcess, len(s.processes))
+	for name, mp := range s.processes {
+		processes[name] = mp
 	}
 	s.mu.Unlock()
 
+	s.stopTasks()
+
 	// Create timeout context for shutdown
 	shutdownCtx, cancel := context.WithTimeout(ctx, s.supConfig.ShutdownTimeout)
 	defer cancel()
 
-	// Stop all processes concurrently
-	var wg sync.WaitGroup
-	for _, mp := range processes {
-		wg.Add(1)
-		go func(mp *ManagedProcess) {
-			defer wg.Done()
-			info := mp.Info()
-			if info.PID > 0 {
-				s.SystemLog("sending SIGTERM to %s (pid %d)", mp.Name(), info.PID)
-			}
-			if err := mp.Stop(shutdownCtx); err != nil && err != domain.ErrProcessNotRunning {
-				s.logManager.Write(domain.LogEntry{
-					Timestamp: time.Now(),
-					Process:   mp.Name(),
-					Stream:    domain.StreamStderr,
-					Line:      fmt.Sprintf("Error stopping: %v", err),
-				})
-			}
-			s.emit(SupervisorEvent{
-				Type:      EventTypeProcessStopped,
-				Process:   mp.Name(),
+	// Stop processes concurrently, up to StopConcurrency at once, with
+	// dependents stopping before the processes they wait for
+	batches := reverseBatches(dependencyBatches(processes))
+	runBatches(batches, s.supConfig.StopConcurrency, func(mp *ManagedProcess) {
+		info := mp.Info()
+		if info.PID > 0 {
+			s.SystemLog("sending SIGTERM to %s (pid %d)", mp.Name(), info.PID)
+		}
+		if err := mp.Stop(shutdownCtx); err != nil && err != domain.ErrProcessNotRunning {
+			s.logManager.Write(domain.LogEntry{
 				Timestamp: time.Now(),
-				Info:      mp.Info(),
+				Process:   mp.Name(),
+				Stream:    domain.StreamStderr,
+				Line:      fmt.Sprintf("Error stopping: %v", err),
 			})
-		}(mp)
-	}
-	wg.Wait()
+		}
+		s.emit(SupervisorEvent{
+			Type:      EventTypeProcessStopped,
+			Process:   mp.Name(),
+			Timestamp: time.Now(),
+			Info:      mp.Info(),
+		})
+	})
 
 	s.mu.Lock()
 	s.state = "stopped"
 	if s.cancel != nil {
 		s.cancel()
 	}
+	s.watcher = nil
 	s.mu.Unlock()
 
 	s.emit(SupervisorEvent{
@@ -279,14 +514,23 @@ func (s *Supervisor) Stop(ctx context.Context) error {
 	return nil
 }
 
+// Watchdog returns the watchdog tracking stream goroutines. Other
+// components (such as API stream handlers) register their goroutines with it.
+func (s *Supervisor) Watchdog() *watchdog.Watchdog {
+	return s.watchdog
+}
+
 // Processes returns info for all processes
 func (s *Supervisor) Processes() []domain.ProcessInfo {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	result := make([]domain.ProcessInfo, 0, len(s.processes))
-	for _, mp := range s.processes {
-		result = append(result, mp.Info())
+	for name, mp := range s.processes {
+		info := mp.Info()
+		info.LastError = s.lastError(name)
+		info.PendingRestart = s.pendingUpdates[name]
+		result = append(result, info)
 	}
 
 	// Sort by name for consistent ordering
@@ -307,7 +551,10 @@ func (s *Supervisor) Process(name string) (domain.ProcessInfo, error) {
 		return domain.ProcessInfo{}, domain.ErrProcessNotFound
 	}
 
-	return mp.Info(), nil
+	info := mp.Info()
+	info.LastError = s.lastError(name)
+	info.PendingRestart = s.pendingUpdates[name]
+	return info, nil
 }
 
 // StartProcess starts a specific process
@@ -321,11 +568,30 @@ func (s *Supervisor) StartProcess(ctx context.Context, name string) error {
 		return domain.ErrProcessNotFound
 	}
 
+	// Wait for external dependencies within the request timeout
+	if mp.State().IsStopped() {
+		var err error
+		if mp, err = s.applyPendingUpdate(name, mp); err != nil {
+			s.recordStartResult(name, err)
+			return err
+		}
+		if err := s.waitForDependencies(ctx, mp); err != nil {
+			s.recordStartResult(name, err)
+			return err
+		}
+	}
+
+	// Starting a process by hand clears a failed crash loop, giving it a
+	// fresh allowance of crashes
+	mp.resetCrashes()
+
 	// Use supervisor context for the process lifecycle.
 	// The passed ctx is only used for the API request timeout, but the process
 	// should continue running after the request completes.
 	err := mp.Start(supCtx)
+	s.recordStartResult(name, err)
 	if err == nil {
+		s.setStoppedOverride(name, false)
 		s.emit(SupervisorEvent{
 			Type:      EventTypeProcessStarted,
 			Process:   name,
@@ -336,8 +602,19 @@ func (s *Supervisor) StartProcess(ctx context.Context, name string) error {
 	return err
 }
 
-// StopProcess stops a specific process
+// StopProcess stops a specific process at a user's request. The process
+// stays stopped across restarts until it is started again.
 func (s *Supervisor) StopProcess(ctx context.Context, name string) error {
+	err := s.stopProcess(ctx, name)
+	if err == nil || err == domain.ErrProcessNotRunning {
+		s.setStoppedOverride(name, true)
+	}
+	return err
+}
+
+// stopProcess stops a specific process without recording it as stopped by
+// hand, for stops prox makes on its own (idle sleep, lazy idle timeout)
+func (s *Supervisor) stopProcess(ctx context.Context, name string) error {
 	s.mu.RLock()
 	mp, ok := s.processes[name]
 	s.mu.RUnlock()
@@ -362,22 +639,86 @@ func (s *Supervisor) StopProcess(ctx context.Context, name string) error {
 	return err
 }
 
-// RestartProcess restarts a specific process
+// SignalProcess sends sig to a running process, e.g. SIGUSR2 to trigger a
+// live reload, and records it in the system log
+func (s *Supervisor) SignalProcess(name string, sig syscall.Signal) error {
+	s.mu.RLock()
+	mp, ok := s.processes[name]
+	s.mu.RUnlock()
+
+	if !ok {
+		return domain.ErrProcessNotFound
+	}
+
+	if err := mp.Signal(sig); err != nil {
+		return err
+	}
+	s.SystemLog("sent %s to %s", SignalName(sig), name)
+	return nil
+}
+
+// AttachProcess connects a client to the input and output of a running
+// process with stdin: true, for prox attach
+func (s *Supervisor) AttachProcess(name string) (*Attachment, error) {
+	s.mu.RLock()
+	mp, ok := s.processes[name]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, domain.ErrProcessNotFound
+	}
+	return mp.Attach()
+}
+
+// RestartProcess restarts a specific process at a user's request, which
+// clears a failed crash loop like StartProcess
 func (s *Supervisor) RestartProcess(ctx context.Context, name string) error {
 	s.mu.RLock()
 	mp, ok := s.processes[name]
 	s.mu.RUnlock()
+	if ok {
+		mp.resetCrashes()
+	}
+	return s.restartProcess(ctx, name, domain.RestartReasonRequest)
+}
+
+// restartProcess restarts a specific process, recording why
+func (s *Supervisor) restartProcess(ctx context.Context, name string, reason domain.RestartReason) error {
+	s.mu.RLock()
+	mp, ok := s.processes[name]
+	s.mu.RUnlock()
 
 	if !ok {
 		return domain.ErrProcessNotFound
 	}
 
+	// Proxied requests wait for the process to come back instead of failing
+	restarted := s.beginRestart(name)
+	var back *ManagedProcess
+	defer func() { restarted(back) }()
+
 	// Create timeout context
 	restartCtx, cancel := context.WithTimeout(ctx, s.supConfig.ShutdownTimeout)
 	defer cancel()
 
-	err := mp.Restart(restartCtx)
+	// Restart with the definition set by UpdateProcess
+	if s.hasPendingUpdate(name) {
+		if err := mp.Stop(restartCtx); err != nil && err != domain.ErrProcessNotRunning {
+			return err
+		}
+		next, err := s.applyPendingUpdate(name, mp)
+		if err != nil {
+			s.recordStartResult(name, err)
+			return err
+		}
+		mp = next
+	}
+
+	err := mp.Restart(restartCtx, reason)
+	s.recordStartResult(name, err)
 	if err == nil {
+		back = mp
+		s.setStoppedOverride(name, false)
 		s.emit(SupervisorEvent{
 			Type:      EventTypeProcessStarted,
 			Process:   name,
@@ -431,19 +772,52 @@ func (st SupervisorStatus) UptimeSeconds() int64 {
 
 // Subscribe creates a channel for receiving supervisor events
 func (s *Supervisor) Subscribe() <-chan SupervisorEvent {
+	ch, _ := s.SubscribeWithHistory(0)
+	return ch
+}
+
+// SubscribeWithHistory creates a channel for receiving supervisor events,
+// like Subscribe, and returns up to the last n events emitted before it,
+// oldest first. Every event is either in the history or sent to the
+// channel, never both.
+func (s *Supervisor) SubscribeWithHistory(n int) (<-chan SupervisorEvent, []SupervisorEvent) {
 	ch := make(chan SupervisorEvent, 100)
 
 	s.eventMu.Lock()
+	defer s.eventMu.Unlock()
 	s.eventSubs = append(s.eventSubs, ch)
-	s.eventMu.Unlock()
 
-	return ch
+	history := s.events
+	if n < len(history) {
+		history = history[len(history)-n:]
+	}
+	return ch, slices.Clone(history)
 }
 
-// emit sends an event to all subscribers
+// Unsubscribe stops sending events to a channel returned by Subscribe and
+// closes it
+func (s *Supervisor) Unsubscribe(ch <-chan SupervisorEvent) {
+	s.eventMu.Lock()
+	defer s.eventMu.Unlock()
+
+	for i, sub := range s.eventSubs {
+		if sub == ch {
+			s.eventSubs = slices.Delete(s.eventSubs, i, i+1)
+			close(sub)
+			return
+		}
+	}
+}
+
+// emit records an event and sends it to all subscribers
 func (s *Supervisor) emit(event SupervisorEvent) {
-	s.eventMu.RLock()
-	defer s.eventMu.RUnlock()
+	s.eventMu.Lock()
+	defer s.eventMu.Unlock()
+
+	if len(s.events) >= constants.SupervisorEventHistory {
+		s.events = slices.Delete(s.events, 0, len(s.events)-constants.SupervisorEventHistory+1)
+	}
+	s.events = append(s.events, event)
 
 	for _, ch := range s.eventSubs {
 		select {
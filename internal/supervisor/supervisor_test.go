@@ -2,13 +2,23 @@ package supervisor
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/daemon"
 	"github.com/charliek/prox/internal/domain"
 	"github.com/charliek/prox/internal/logs"
+	"github.com/charliek/prox/internal/watchdog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -174,6 +184,172 @@ func TestSupervisor_Events(t *testing.T) {
 	sup.Stop(stopCtx)
 }
 
+func TestSupervisor_EventHistory(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	sup := New(makeTestConfig(map[string]string{"test": "sleep 30"}), logMgr, nil, DefaultSupervisorConfig())
+	_, err := sup.Start(context.Background())
+	require.NoError(t, err)
+
+	// Events before subscribing are in the history, later ones on the channel
+	events, history := sup.SubscribeWithHistory(10)
+	require.Len(t, history, 2)
+	assert.Equal(t, EventTypeSupervisorStart, history[0].Type)
+	assert.Equal(t, EventTypeProcessStarted, history[1].Type)
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, sup.Stop(stopCtx))
+	var types []EventType
+	for len(events) > 0 {
+		types = append(types, (<-events).Type)
+	}
+	assert.Equal(t, []EventType{EventTypeProcessStopped, EventTypeSupervisorStop}, types)
+
+	sup.Unsubscribe(events)
+	_, ok := <-events
+	assert.False(t, ok, "unsubscribing closes the channel")
+
+	// The history is bounded
+	for i := 0; i < constants.SupervisorEventHistory+10; i++ {
+		sup.emit(SupervisorEvent{Type: EventTypeProcessStarted, Process: fmt.Sprint(i)})
+	}
+	_, history = sup.SubscribeWithHistory(1000)
+	require.Len(t, history, constants.SupervisorEventHistory)
+	assert.Equal(t, fmt.Sprint(constants.SupervisorEventHistory+9), history[len(history)-1].Process)
+}
+
+func TestSupervisor_HealthEvents(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(nil)
+	cfg.Processes["api"] = config.ProcessConfig{
+		Cmd:         "sleep 30",
+		Healthcheck: &config.HealthcheckConfig{Cmd: "true", Interval: "1s", Timeout: "1s", StartPeriod: "10ms"},
+	}
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	events := sup.Subscribe()
+	_, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		sup.Stop(stopCtx)
+	}()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type == EventTypeProcessHealthy {
+				assert.Equal(t, "api", e.Process)
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected process healthy event")
+		}
+	}
+}
+
+func TestSupervisor_CrashEvent(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{
+		"test": "exit 1",
+	})
+
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	events := sup.Subscribe()
+
+	_, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		sup.Stop(stopCtx)
+	}()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type == EventTypeProcessCrashed {
+				assert.Equal(t, "test", e.Process)
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected process crashed event")
+		}
+	}
+}
+
+func TestSupervisor_CrashLoopFails(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{
+		"test": "exit 1",
+	})
+
+	supConfig := DefaultSupervisorConfig()
+	supConfig.CrashLimit = 2
+	sup := New(cfg, logMgr, nil, supConfig)
+	events := sup.Subscribe()
+
+	_, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		sup.Stop(stopCtx)
+	}()
+
+	// waitFor returns the next crashed or failed event
+	waitFor := func() EventType {
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case e := <-events:
+				if e.Type == EventTypeProcessCrashed || e.Type == EventTypeProcessFailed {
+					return e.Type
+				}
+			case <-timeout:
+				t.Fatal("expected a crash event")
+			}
+		}
+	}
+
+	assert.Equal(t, EventTypeProcessCrashed, waitFor())
+	info, err := sup.Process("test")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateCrashed, info.State)
+
+	// A file change restarts it, and the second crash marks it failed
+	sup.restartForChange("test", []string{"main.go"})
+	assert.Equal(t, EventTypeProcessCrashed, waitFor())
+	assert.Equal(t, EventTypeProcessFailed, waitFor())
+	info, err = sup.Process("test")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateFailed, info.State)
+
+	// Further changes leave it alone
+	sup.restartForChange("test", []string{"main.go"})
+	info, err = sup.Process("test")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateFailed, info.State)
+	assert.Equal(t, 1, info.RestartCount)
+
+	// Starting it by hand clears the crashes
+	require.NoError(t, sup.StartProcess(context.Background(), "test"))
+	assert.Equal(t, EventTypeProcessCrashed, waitFor())
+	info, err = sup.Process("test")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateCrashed, info.State)
+}
+
 func TestSupervisor_StartSelectedProcesses(t *testing.T) {
 	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
 	defer logMgr.Close()
@@ -210,6 +386,94 @@ func TestSupervisor_StartSelectedProcesses(t *testing.T) {
 	assert.ErrorIs(t, err, domain.ErrProcessNotFound)
 }
 
+func TestSupervisor_PromptedEnv(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{"api": "sleep 30"})
+	cfg.Processes["web"] = config.ProcessConfig{
+		Cmd:       "sleep 30",
+		Env:       map[string]string{"DB_PASSWORD": "from-config"},
+		EnvPrompt: []string{"STRIPE_KEY", "DB_PASSWORD"},
+	}
+
+	supConfig := DefaultSupervisorConfig()
+	supConfig.PromptedEnv = map[string]string{"STRIPE_KEY": "sk_test", "DB_PASSWORD": "entered"}
+	sup := New(cfg, logMgr, nil, supConfig)
+	_, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		sup.Stop(stopCtx)
+	}()
+
+	// Values go to the processes prompting for them, without overriding config
+	web, err := sup.Process("web")
+	require.NoError(t, err)
+	assert.Equal(t, "sk_test", web.Env["STRIPE_KEY"])
+	assert.Equal(t, "from-config", web.Env["DB_PASSWORD"])
+
+	api, err := sup.Process("api")
+	require.NoError(t, err)
+	assert.NotContains(t, api.Env, "STRIPE_KEY")
+}
+
+func TestSupervisor_LogBuffer(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 10})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{
+		"quiet": "echo hello; sleep 30",
+		"noisy": "sleep 0.2; seq 1 500; sleep 30",
+	})
+	proc := cfg.Processes["quiet"]
+	proc.LogBuffer = 5
+	cfg.Processes["quiet"] = proc
+
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	_, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		sup.Stop(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		entries, _, _ := logMgr.Query(domain.LogFilter{Processes: []string{"noisy"}, Patterns: []string{"500"}}, 0)
+		return len(entries) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The noisy process overflowed the shared pool, but quiet's line is reserved
+	entries, _, _ := logMgr.Query(domain.LogFilter{Processes: []string{"quiet"}, Patterns: []string{"hello"}}, 0)
+	assert.Len(t, entries, 1)
+}
+
+func TestSupervisor_LogFormat(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{"api": "echo 'level=error msg=boom'; sleep 30"})
+	proc := cfg.Processes["api"]
+	proc.LogFormat = domain.LogFormatLogfmt
+	cfg.Processes["api"] = proc
+
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	_, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		sup.Stop(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		entries, _, _ := logMgr.Query(domain.LogFilter{Processes: []string{"api"}, Level: "error"}, 0)
+		return len(entries) == 1 && entries[0].Fields["msg"] == "boom"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
 func TestSupervisor_SystemLog(t *testing.T) {
 	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
 	defer logMgr.Close()
@@ -271,3 +535,491 @@ func TestSupervisor_StopLogsSIGTERM(t *testing.T) {
 
 	assert.True(t, foundSIGTERMMessage, "Stop should log 'sending SIGTERM to test (pid X)' message")
 }
+
+func TestSupervisor_WatchdogCleansCrashedHealthChecker(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{})
+	cfg.Processes["flaky"] = config.ProcessConfig{
+		Cmd:         "sleep 0.1",
+		Healthcheck: &config.HealthcheckConfig{Cmd: "true"},
+	}
+
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	_, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	defer sup.Stop(context.Background())
+
+	stats := sup.Watchdog().Stats()
+	assert.Equal(t, 1, stats.Active[watchdog.KindHealthChecker])
+
+	// Once the process exits on its own, its health checker is leaked
+	require.Eventually(t, func() bool {
+		return sup.Watchdog().Sweep() > 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	stats = sup.Watchdog().Stats()
+	assert.Equal(t, 0, stats.Active[watchdog.KindHealthChecker])
+	assert.Equal(t, 1, stats.Leaked[watchdog.KindHealthChecker])
+	assert.Equal(t, 0, stats.Active[watchdog.KindOutputReader])
+}
+
+func TestSupervisor_SleepWake(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{
+		"web":    "sleep 30",
+		"worker": "sleep 30",
+	})
+
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	ctx := context.Background()
+	_, err := sup.Start(ctx)
+	require.NoError(t, err)
+	defer sup.Stop(ctx)
+
+	// Waking an awake supervisor is a no-op
+	require.NoError(t, sup.Wake(ctx))
+	assert.False(t, sup.Asleep())
+
+	names := sup.Sleep(ctx)
+	assert.Equal(t, []string{"web", "worker"}, names)
+	assert.True(t, sup.Asleep())
+	for _, p := range sup.Processes() {
+		assert.True(t, p.State.IsStopped(), "%s should be stopped", p.Name)
+	}
+	assert.Equal(t, "running", sup.Status().State, "the supervisor itself keeps running")
+
+	require.NoError(t, sup.Wake(ctx))
+	assert.False(t, sup.Asleep())
+	for _, p := range sup.Processes() {
+		assert.Equal(t, domain.ProcessStateRunning, p.State, "%s should be running", p.Name)
+	}
+}
+
+func TestSupervisor_LazyProcess(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{
+		"web": "sleep 30",
+	})
+	cfg.Processes["worker"] = config.ProcessConfig{Cmd: "sleep 30", Lazy: true, IdleTimeout: "100ms"}
+
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	ctx := context.Background()
+	_, err := sup.Start(ctx)
+	require.NoError(t, err)
+	defer sup.Stop(ctx)
+
+	info, err := sup.Process("worker")
+	require.NoError(t, err)
+	assert.True(t, info.Lazy)
+	assert.True(t, info.State.IsStopped(), "lazy processes are not started by Start")
+
+	// Non-lazy processes are left alone
+	release, err := sup.UseProcess(ctx, "web")
+	require.NoError(t, err)
+	release()
+
+	release, err = sup.UseProcess(ctx, "worker")
+	require.NoError(t, err)
+	info, err = sup.Process("worker")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+
+	// An open request keeps the process running past its idle timeout
+	time.Sleep(250 * time.Millisecond)
+	info, err = sup.Process("worker")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+
+	release()
+	require.Eventually(t, func() bool {
+		info, err := sup.Process("worker")
+		return err == nil && info.State.IsStopped()
+	}, 5*time.Second, 20*time.Millisecond, "idle lazy process should be stopped")
+
+	// The next request starts it again
+	release, err = sup.UseProcess(ctx, "worker")
+	require.NoError(t, err)
+	defer release()
+	info, err = sup.Process("worker")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+}
+
+func TestSupervisor_WaitRestart(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	// The test listens on the process's port, so it controls when the
+	// restarted process is back
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	cfg := makeTestConfig(nil)
+	cfg.Processes["web"] = config.ProcessConfig{Cmd: "sleep 30", Port: strconv.Itoa(port)}
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+
+	ctx := context.Background()
+	_, err = sup.Start(ctx)
+	require.NoError(t, err)
+	defer sup.Stop(ctx)
+
+	// Nothing to wait for without a restart
+	require.NoError(t, sup.WaitRestart(ctx, "web"))
+
+	require.NoError(t, sup.RestartProcess(ctx, "web"))
+	shortCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, sup.WaitRestart(shortCtx, "web"), context.DeadlineExceeded)
+
+	ln, err = net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	require.NoError(t, err)
+	defer ln.Close()
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	assert.NoError(t, sup.WaitRestart(waitCtx, "web"))
+}
+
+func TestSupervisor_StartFailures(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	target := closedPortURL(t)
+	cfg := makeTestConfig(map[string]string{
+		"ok": "sleep 30",
+	})
+	cfg.Processes["web"] = config.ProcessConfig{Cmd: "sleep 30", WaitFor: []string{target}, WaitTimeout: "100ms"}
+	cfg.Processes["worker"] = config.ProcessConfig{Cmd: "sleep 30", EnvFile: "missing.env"}
+
+	sup := New(cfg, logMgr, nil, SupervisorConfig{ShutdownTimeout: 5 * time.Second, ConfigDir: t.TempDir()})
+	ctx := context.Background()
+	result, err := sup.Start(ctx)
+	require.NoError(t, err)
+	defer sup.Stop(ctx)
+	require.Len(t, result.Failed, 2)
+
+	failures := sup.StartFailures()
+	require.Len(t, failures, 2)
+	assert.Equal(t, "web", failures[0].Process)
+	assert.Contains(t, failures[0].Error, "unavailable")
+	assert.Equal(t, "worker", failures[1].Process, "processes that couldn't be created are included")
+	assert.Contains(t, failures[1].Error, "environment")
+
+	info, err := sup.Process("web")
+	require.NoError(t, err)
+	assert.Equal(t, failures[0].Error, info.LastError)
+	ok, err := sup.Process("ok")
+	require.NoError(t, err)
+	assert.Empty(t, ok.LastError)
+
+	// A successful start clears the failure
+	listener, err := net.Listen("tcp", strings.TrimPrefix(target, "tcp://"))
+	require.NoError(t, err)
+	defer listener.Close()
+	require.NoError(t, sup.StartProcess(ctx, "web"))
+
+	info, err = sup.Process("web")
+	require.NoError(t, err)
+	assert.Empty(t, info.LastError)
+	failures = sup.StartFailures()
+	require.Len(t, failures, 1)
+	assert.Equal(t, "worker", failures[0].Process)
+}
+
+func TestSupervisor_RuntimeOverrides(t *testing.T) {
+	dir := t.TempDir()
+	cfg := makeTestConfig(map[string]string{
+		"web":    "sleep 30",
+		"worker": "sleep 30",
+	})
+	supConfig := DefaultSupervisorConfig()
+	supConfig.RuntimeStateDir = dir
+
+	run := func(supConfig SupervisorConfig, check func(sup *Supervisor)) {
+		logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+		defer logMgr.Close()
+
+		sup := New(cfg, logMgr, nil, supConfig)
+		ctx := context.Background()
+		_, err := sup.Start(ctx)
+		require.NoError(t, err)
+		defer sup.Stop(ctx)
+		check(sup)
+	}
+	state := func(sup *Supervisor, name string) domain.ProcessState {
+		info, err := sup.Process(name)
+		require.NoError(t, err)
+		return info.State
+	}
+
+	run(supConfig, func(sup *Supervisor) {
+		require.NoError(t, sup.StopProcess(context.Background(), "worker"))
+	})
+
+	overrides, err := daemon.LoadRuntimeOverrides(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"worker"}, overrides.Stopped, "shutting down doesn't count as stopping by hand")
+
+	run(supConfig, func(sup *Supervisor) {
+		assert.Equal(t, domain.ProcessStateRunning, state(sup, "web"))
+		assert.True(t, state(sup, "worker").IsStopped(), "a process stopped by hand stays stopped")
+
+		// Starting it again clears the override
+		require.NoError(t, sup.StartProcess(context.Background(), "worker"))
+	})
+
+	overrides, err = daemon.LoadRuntimeOverrides(dir)
+	require.NoError(t, err)
+	assert.Empty(t, overrides.Stopped)
+
+	// --fresh discards saved overrides
+	require.NoError(t, (&daemon.RuntimeOverrides{Stopped: []string{"web", "removed"}}).Write(dir))
+	fresh := supConfig
+	fresh.FreshStart = true
+	run(fresh, func(sup *Supervisor) {
+		assert.Equal(t, domain.ProcessStateRunning, state(sup, "web"))
+	})
+	overrides, err = daemon.LoadRuntimeOverrides(dir)
+	require.NoError(t, err)
+	assert.Empty(t, overrides.Stopped)
+}
+
+func TestSupervisor_RuntimeOverrides_AddedAndScaled(t *testing.T) {
+	dir := t.TempDir()
+	cfg := makeTestConfig(map[string]string{"web": "sleep 30"})
+	cfg.Processes["worker"] = config.ProcessConfig{Cmd: "sleep 30"}
+	supConfig := DefaultSupervisorConfig()
+	supConfig.RuntimeStateDir = dir
+	supConfig.Scale = map[string]int{"worker": 2}
+
+	run := func(cfg *config.Config, supConfig SupervisorConfig, check func(sup *Supervisor)) {
+		logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+		defer logMgr.Close()
+
+		sup := New(cfg, logMgr, nil, supConfig)
+		ctx := context.Background()
+		_, err := sup.Start(ctx)
+		require.NoError(t, err)
+		defer sup.Stop(ctx)
+		check(sup)
+	}
+
+	run(cfg, supConfig, func(sup *Supervisor) {
+		ctx := context.Background()
+		require.NoError(t, sup.AddProcess(ctx, "tunnel", config.ProcessConfig{Cmd: "sleep 30", Env: map[string]string{"MODE": "test"}}))
+		require.NoError(t, sup.AddProcess(ctx, "queue", config.ProcessConfig{Cmd: "sleep 30"}))
+		require.NoError(t, sup.StopProcess(ctx, "queue"))
+	})
+
+	overrides, err := daemon.LoadRuntimeOverrides(dir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"worker": 2}, overrides.Scale)
+	assert.Equal(t, []string{"queue"}, overrides.Stopped)
+	require.Len(t, overrides.Added, 2)
+	assert.Equal(t, daemon.AddedProcess{Name: "tunnel", Cmd: "sleep 30", Env: map[string]string{"MODE": "test"}}, overrides.Added[1])
+
+	// The next start brings added processes back, stopped ones stopped
+	restarted := supConfig
+	restarted.Scale = overrides.Scale
+	run(cfg, restarted, func(sup *Supervisor) {
+		info, err := sup.Process("tunnel")
+		require.NoError(t, err)
+		assert.Equal(t, domain.ProcessStateRunning, info.State)
+		assert.Equal(t, "test", info.Env["MODE"])
+
+		info, err = sup.Process("queue")
+		require.NoError(t, err)
+		assert.True(t, info.State.IsStopped())
+
+		info, err = sup.Process("worker-2")
+		require.NoError(t, err)
+		assert.Equal(t, domain.ProcessStateRunning, info.State)
+
+		// A configured process of the same name replaces an added one
+		reloaded := makeTestConfig(map[string]string{"web": "sleep 30", "tunnel": "sleep 31"})
+		reloaded.Processes["worker"] = config.ProcessConfig{Cmd: "sleep 30"}
+		_, err = sup.Reload(context.Background(), reloaded)
+		require.NoError(t, err)
+	})
+
+	overrides, err = daemon.LoadRuntimeOverrides(dir)
+	require.NoError(t, err)
+	require.Len(t, overrides.Added, 1)
+	assert.Equal(t, "queue", overrides.Added[0].Name)
+
+	// --fresh forgets them, keeping only this run's scale
+	fresh := supConfig
+	fresh.FreshStart = true
+	fresh.Scale = nil
+	run(cfg, fresh, func(sup *Supervisor) {
+		_, err := sup.Process("queue")
+		assert.ErrorIs(t, err, domain.ErrProcessNotFound)
+	})
+	_, err = os.Stat(daemon.RuntimePath(dir))
+	assert.True(t, os.IsNotExist(err), "nothing left to override")
+}
+
+func TestSupervisor_SignalProcess(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{
+		"web": `trap 'echo got usr1' USR1; echo ready; while true; do sleep 0.1; done`,
+	})
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	_, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		sup.Stop(stopCtx)
+	}()
+
+	hasLine := func(process, line string) func() bool {
+		return func() bool {
+			entries, _, _ := logMgr.Query(domain.LogFilter{Processes: []string{process}}, 0)
+			for _, e := range entries {
+				if e.Line == line {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	require.Eventually(t, hasLine("web", "ready"), 5*time.Second, 20*time.Millisecond)
+
+	require.NoError(t, sup.SignalProcess("web", syscall.SIGUSR1))
+	assert.Eventually(t, hasLine("web", "got usr1"), 5*time.Second, 20*time.Millisecond)
+	assert.Eventually(t, hasLine("system", "sent SIGUSR1 to web"), time.Second, 20*time.Millisecond)
+
+	info, err := sup.Process("web")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+
+	assert.ErrorIs(t, sup.SignalProcess("missing", syscall.SIGUSR1), domain.ErrProcessNotFound)
+
+	require.NoError(t, sup.StopProcess(context.Background(), "web"))
+	assert.ErrorIs(t, sup.SignalProcess("web", syscall.SIGUSR1), domain.ErrProcessNotRunning)
+}
+
+func TestParseSignal(t *testing.T) {
+	for _, name := range []string{"SIGUSR2", "USR2", "usr2", "SigUsr2", strconv.Itoa(int(syscall.SIGUSR2))} {
+		sig, err := ParseSignal(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, syscall.SIGUSR2, sig, name)
+	}
+	for _, name := range []string{"", "SIG", "USR3", "999", "SIGSEGV"} {
+		_, err := ParseSignal(name)
+		assert.ErrorIs(t, err, domain.ErrInvalidSignal, name)
+	}
+	assert.Equal(t, "SIGHUP", SignalName(syscall.SIGHUP))
+	assert.Contains(t, SignalNames(), "SIGUSR2")
+	assert.True(t, sort.StringsAreSorted(SignalNames()))
+}
+
+func TestSupervisor_Scale(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{
+		"web":    "sleep 30",
+		"worker": `echo "instance=$INSTANCE port=$PORT"; sleep 30`,
+	})
+	worker := cfg.Processes["worker"]
+	worker.Port = "4100"
+	worker.Scale = 2
+	cfg.Processes["worker"] = worker
+
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	ctx := context.Background()
+	_, err := sup.StartProcesses(ctx, []string{"worker"})
+	require.NoError(t, err)
+	defer sup.Stop(ctx)
+
+	var names []string
+	for _, info := range sup.Processes() {
+		names = append(names, info.Name)
+	}
+	assert.Equal(t, []string{"worker-1", "worker-2"}, names)
+
+	for i, name := range names {
+		want := fmt.Sprintf("instance=%d port=%d", i+1, 4100+i)
+		require.Eventually(t, func() bool {
+			entries, _, _ := logMgr.Query(domain.LogFilter{Processes: []string{name}, Patterns: []string{want}}, 0)
+			return len(entries) == 1
+		}, 5*time.Second, 10*time.Millisecond, name)
+	}
+
+	// Scaling up on reload adds an instance
+	worker.Scale = 3
+	cfg.Processes["worker"] = worker
+	result, err := sup.Reload(ctx, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"worker-3"}, result.Added)
+	assert.Empty(t, result.Changed)
+}
+
+func TestSupervisor_Watch(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "src"), 0755))
+
+	cfg := makeTestConfig(map[string]string{"web": "sleep 30"})
+	web := cfg.Processes["web"]
+	web.Watch = []string{"src/**/*.go"}
+	web.WatchIgnore = []string{"*_test.go"}
+	web.WatchDebounce = "50ms"
+	cfg.Processes["web"] = web
+
+	supConfig := DefaultSupervisorConfig()
+	supConfig.ConfigDir = dir
+	sup := New(cfg, logMgr, nil, supConfig)
+	ctx := context.Background()
+	_, err := sup.Start(ctx)
+	require.NoError(t, err)
+	defer sup.Stop(ctx)
+
+	restarts := func() int {
+		info, err := sup.Process("web")
+		require.NoError(t, err)
+		return info.RestartCount
+	}
+
+	// An ignored file leaves the process alone
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main_test.go"), nil, 0644))
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 0, restarts())
+
+	// Changes within the debounce time restart it once
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.go"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "util.go"), nil, 0644))
+	require.Eventually(t, func() bool { return restarts() == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		entries, _, _ := logMgr.Query(domain.LogFilter{Processes: []string{"system"}, Patterns: []string{"src/main.go and 1 more changed, restarting web"}}, 0)
+		return len(entries) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 1, restarts())
+	info, err := sup.Process("web")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RestartReasonWatch, info.RestartReason)
+	entries, _, _ := logMgr.Query(domain.LogFilter{Processes: []string{"web"}, Patterns: []string{"restarting (file change)"}}, 0)
+	assert.Len(t, entries, 1)
+
+	// A process stopped by hand stays stopped
+	require.NoError(t, sup.StopProcess(ctx, "web"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main"), 0644))
+	time.Sleep(200 * time.Millisecond)
+	info, err = sup.Process("web")
+	require.NoError(t, err)
+	assert.True(t, info.State.IsStopped())
+}
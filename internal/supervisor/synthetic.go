@@ -0,0 +1,167 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/domain"
+)
+
+// syntheticRunner runs synthetic processes inside prox. They write logfmt
+// lines at a steady rate, mostly at level info with a warn every 20th line
+// and an error (on stderr) every 100th, and exit or crash on a schedule.
+type syntheticRunner struct{}
+
+// Start starts a synthetic process; env is ignored
+func (syntheticRunner) Start(ctx context.Context, config domain.ProcessConfig, env map[string]string) (Process, error) {
+	_ = ctx // Like ExecRunner, the lifecycle is managed via Signal()
+	if config.Synthetic == nil {
+		return nil, fmt.Errorf("starting process: %s is not a synthetic process", config.Name)
+	}
+
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	p := &syntheticProcess{
+		stdout:  stdoutR,
+		stderr:  stderrR,
+		signals: make(chan os.Signal, 1),
+		done:    make(chan struct{}),
+	}
+	go p.run(*config.Synthetic, stdoutW, stderrW)
+	return p, nil
+}
+
+// syntheticProcess is a running synthetic process
+type syntheticProcess struct {
+	stdout  *io.PipeReader
+	stderr  *io.PipeReader
+	signals chan os.Signal
+
+	done chan struct{}
+	err  error // Set before done closes
+}
+
+// syntheticExitError reports a synthetic process's nonzero exit status,
+// negative for a signal like an exec'd process
+type syntheticExitError struct {
+	code int
+}
+
+func (e *syntheticExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// ExitCode returns the exit status
+func (e *syntheticExitError) ExitCode() int {
+	return e.code
+}
+
+// run writes lines until the process exits, crashes, or is signaled
+func (p *syntheticProcess) run(sc domain.SyntheticConfig, stdout, stderr *io.PipeWriter) {
+	var err error
+	defer func() {
+		stdout.Close()
+		stderr.Close()
+		p.err = err
+		close(p.done)
+	}()
+
+	interval := constants.SyntheticTickInterval
+	if sc.Rate > 0 {
+		interval = max(time.Second/time.Duration(sc.Rate), constants.SyntheticTickInterval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var exit <-chan time.Time
+	if sc.ExitAfter > 0 {
+		timer := time.NewTimer(sc.ExitAfter)
+		defer timer.Stop()
+		exit = timer.C
+	}
+	var crash <-chan time.Time
+	if sc.CrashProbability > 0 {
+		crashTicker := time.NewTicker(time.Second)
+		defer crashTicker.Stop()
+		crash = crashTicker.C
+	}
+
+	start := time.Now()
+	written := 0
+	for {
+		select {
+		case sig := <-p.signals:
+			code := 1
+			if s, ok := sig.(syscall.Signal); ok {
+				code = -int(s)
+			}
+			err = &syntheticExitError{code: code}
+			return
+		case <-exit:
+			fmt.Fprintf(stdout, "level=info msg=%q\n", "exiting after "+sc.ExitAfter.String())
+			return
+		case <-crash:
+			if rand.Float64() < sc.CrashProbability {
+				fmt.Fprintf(stderr, "level=fatal msg=%q\n", "synthetic crash")
+				err = &syntheticExitError{code: 1}
+				return
+			}
+		case now := <-ticker.C:
+			// Catch up to the rate, so it holds at rates above one line a tick
+			due := int(now.Sub(start).Seconds() * float64(sc.Rate))
+			for ; written < due; written++ {
+				if writeErr := writeSyntheticLine(stdout, stderr, written+1); writeErr != nil {
+					return // Output closed
+				}
+			}
+		}
+	}
+}
+
+// writeSyntheticLine writes the seq'th line
+func writeSyntheticLine(stdout, stderr io.Writer, seq int) error {
+	var err error
+	switch {
+	case seq%100 == 0:
+		_, err = fmt.Fprintf(stderr, "level=error msg=%q seq=%d\n", "request failed", seq)
+	case seq%20 == 0:
+		_, err = fmt.Fprintf(stdout, "level=warn msg=%q seq=%d\n", "slow request", seq)
+	default:
+		_, err = fmt.Fprintf(stdout, "level=info msg=%q seq=%d\n", "request handled", seq)
+	}
+	return err
+}
+
+// PID returns 0; a synthetic process has no OS process
+func (p *syntheticProcess) PID() int {
+	return 0
+}
+
+func (p *syntheticProcess) Wait() error {
+	<-p.done
+	return p.err
+}
+
+// Signal stops the process; every signal is treated as fatal
+func (p *syntheticProcess) Signal(sig os.Signal) error {
+	select {
+	case <-p.done:
+	case p.signals <- sig:
+	default: // A signal is already pending
+	}
+	return nil
+}
+
+func (p *syntheticProcess) Stdout() io.Reader {
+	return p.stdout
+}
+
+func (p *syntheticProcess) Stderr() io.Reader {
+	return p.stderr
+}
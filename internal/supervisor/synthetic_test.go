@@ -0,0 +1,89 @@
+package supervisor
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startSynthetic(t *testing.T, sc domain.SyntheticConfig) Process {
+	t.Helper()
+	proc, err := syntheticRunner{}.Start(context.Background(), domain.ProcessConfig{Name: "synth", Synthetic: &sc}, nil)
+	require.NoError(t, err)
+	go io.Copy(io.Discard, proc.Stderr())
+	return proc
+}
+
+func TestSyntheticProcess_Output(t *testing.T) {
+	proc := startSynthetic(t, domain.SyntheticConfig{Rate: 1000})
+
+	scanner := bufio.NewScanner(proc.Stdout())
+	var levels []string
+	for len(levels) < 25 && scanner.Scan() {
+		_, level := logs.ParseLine(domain.LogFormatLogfmt, scanner.Text())
+		levels = append(levels, level)
+	}
+	require.Len(t, levels, 25)
+	assert.Equal(t, "info", levels[0])
+	assert.Equal(t, "warn", levels[19], "every 20th line is a warning")
+
+	require.NoError(t, proc.Signal(sigterm))
+	go io.Copy(io.Discard, proc.Stdout())
+	err := proc.Wait()
+	var coder interface{ ExitCode() int }
+	require.ErrorAs(t, err, &coder)
+	assert.Equal(t, -15, coder.ExitCode())
+}
+
+func TestSyntheticProcess_ExitAfter(t *testing.T) {
+	proc := startSynthetic(t, domain.SyntheticConfig{Rate: 10, ExitAfter: 50 * time.Millisecond})
+	go io.Copy(io.Discard, proc.Stdout())
+
+	assert.NoError(t, proc.Wait())
+	assert.NoError(t, proc.Signal(sigterm), "signaling an exited process is a no-op")
+}
+
+func TestSyntheticProcess_Crash(t *testing.T) {
+	proc := startSynthetic(t, domain.SyntheticConfig{Rate: 10, CrashProbability: 1})
+	go io.Copy(io.Discard, proc.Stdout())
+
+	err := proc.Wait()
+	var coder interface{ ExitCode() int }
+	require.ErrorAs(t, err, &coder)
+	assert.Equal(t, 1, coder.ExitCode())
+}
+
+func TestSupervisor_SyntheticProcess(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 1000})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{"noise": ""})
+	proc := cfg.Processes["noise"]
+	proc.Type = "synthetic"
+	proc.LogFormat = domain.LogFormatLogfmt
+	cfg.Processes["noise"] = proc
+
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	_, err := sup.Start(context.Background())
+	require.NoError(t, err)
+
+	// The default rate is 10 lines a second
+	require.Eventually(t, func() bool {
+		entries, _, _ := logMgr.Query(domain.LogFilter{Processes: []string{"noise"}, Level: "info"}, 0)
+		return len(entries) >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, sup.Stop(ctx))
+
+	entries, _, _ := logMgr.Query(domain.LogFilter{Processes: []string{"noise"}, Patterns: []string{"stopped (rc=-15)"}}, 0)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,216 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
+)
+
+// taskRun is the last run of a task, protected by the supervisor's taskMu
+type taskRun struct {
+	info domain.TaskInfo
+	proc Process // nil unless running
+}
+
+// Tasks returns the configured tasks with their last runs, sorted by name
+func (s *Supervisor) Tasks() []domain.TaskInfo {
+	s.mu.RLock()
+	tasks := s.config.Tasks
+	s.mu.RUnlock()
+
+	names := make([]string, 0, len(tasks))
+	for name := range tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos := make([]domain.TaskInfo, 0, len(names))
+	for _, name := range names {
+		infos = append(infos, s.taskInfo(name, tasks[name]))
+	}
+	return infos
+}
+
+// Task returns a configured task with its last run
+func (s *Supervisor) Task(name string) (domain.TaskInfo, error) {
+	s.mu.RLock()
+	task, ok := s.config.Tasks[name]
+	s.mu.RUnlock()
+	if !ok {
+		return domain.TaskInfo{}, domain.ErrTaskNotFound
+	}
+	return s.taskInfo(name, task), nil
+}
+
+// taskInfo returns a task's definition with the state of its last run
+func (s *Supervisor) taskInfo(name string, task config.TaskConfig) domain.TaskInfo {
+	info := domain.TaskInfo{State: domain.TaskStateIdle}
+	s.taskMu.Lock()
+	if run := s.tasks[name]; run != nil {
+		info = run.info
+	}
+	s.taskMu.Unlock()
+	info.Name = name
+	info.Cmd = task.Cmd
+	info.Description = task.Description
+	return info
+}
+
+// RunTask starts a task and returns without waiting for it to finish. Its
+// output is logged under the task's name, and its exit status is recorded
+// for Task. Tasks aren't restarted or health checked, and stopping prox
+// sends them SIGTERM. A task that fails to start is returned failed, with
+// Error saying why.
+// Returns domain.ErrTaskRunning if the task's last run hasn't finished.
+func (s *Supervisor) RunTask(ctx context.Context, name string) (domain.TaskInfo, error) {
+	s.mu.RLock()
+	running := s.state == "running"
+	task, ok := s.config.Tasks[name]
+	globalEnvFile, globalEnv := s.config.EnvFile, s.config.Env
+	shell := s.config.ShellFor(task.ProcessConfig())
+	direnv := s.config.DirenvFor(task.ProcessConfig())
+	s.mu.RUnlock()
+	if !ok {
+		return domain.TaskInfo{}, domain.ErrTaskNotFound
+	}
+	if !running {
+		return domain.TaskInfo{}, domain.ErrShutdownInProgress
+	}
+
+	s.taskMu.Lock()
+	defer s.taskMu.Unlock()
+	if run := s.tasks[name]; run != nil && run.info.State == domain.TaskStateRunning {
+		return run.info, domain.ErrTaskRunning
+	}
+	run := &taskRun{info: domain.TaskInfo{
+		Name:        name,
+		Cmd:         task.Cmd,
+		Description: task.Description,
+		State:       domain.TaskStateRunning,
+		StartedAt:   time.Now(),
+	}}
+	if s.tasks == nil {
+		s.tasks = make(map[string]*taskRun)
+	}
+	s.tasks[name] = run
+
+	env, err := config.LoadProcessEnv(globalEnvFile, globalEnv, task.EnvFile, task.Env, s.supConfig.ConfigDir)
+	if err != nil {
+		err = fmt.Errorf("failed to load environment: %w", err)
+	} else {
+		s.SystemLog("running task %s: %s", name, task.Cmd)
+		run.proc, err = s.runner.Start(ctx, domain.ProcessConfig{
+			Name:     name,
+			Cmd:      task.Cmd,
+			Shell:    shell,
+			Direnv:   direnv,
+			Env:      env,
+			EnvFile:  task.EnvFile,
+			CleanEnv: !task.ProcessConfig().InheritsEnv(),
+		}, env)
+	}
+	if err != nil {
+		run.info.State = domain.TaskStateFailed
+		run.info.FinishedAt = time.Now()
+		run.info.Error = err.Error()
+		s.logManager.Write(domain.LogEntry{
+			Timestamp: time.Now(),
+			Process:   name,
+			Stream:    domain.StreamStderr,
+			Line:      fmt.Sprintf("Failed to start: %v", err),
+		})
+		return run.info, nil
+	}
+
+	run.info.PID = run.proc.PID()
+	go s.waitTask(run, run.proc)
+	return run.info, nil
+}
+
+// waitTask logs a running task's output until it exits, then records its
+// exit status
+func (s *Supervisor) waitTask(run *taskRun, proc Process) {
+	name := run.info.Name
+	var outputWg sync.WaitGroup
+	outputWg.Add(2)
+	readers := []io.Reader{proc.Stdout(), proc.Stderr()}
+	for i, stream := range []domain.Stream{domain.StreamStdout, domain.StreamStderr} {
+		go func(r io.Reader, stream domain.Stream) {
+			defer outputWg.Done()
+			if r != nil {
+				logOutput(s.logManager, r, name, "", stream)
+			}
+		}(readers[i], stream)
+	}
+
+	err := proc.Wait()
+
+	// Wait for the output to drain, as monitor does for processes, closing
+	// pipes a grandchild still holds open
+	outputDone := make(chan struct{})
+	go func() {
+		outputWg.Wait()
+		close(outputDone)
+	}()
+	select {
+	case <-outputDone:
+	case <-time.After(outputDrainTimeout):
+		s.logManager.Write(domain.LogEntry{
+			Timestamp: time.Now(),
+			Process:   name,
+			Stream:    domain.StreamStderr,
+			Line:      "output capture timed out (some logs may be missing)",
+		})
+		for _, r := range readers {
+			closeReader(r)
+		}
+	}
+
+	exitCode := exitCodeOf(err)
+	stream := domain.StreamStdout
+	if exitCode != 0 {
+		stream = domain.StreamStderr
+	}
+	s.logManager.Write(domain.LogEntry{
+		Timestamp: time.Now(),
+		Process:   name,
+		Stream:    stream,
+		Line:      fmt.Sprintf("exited (rc=%d)", exitCode),
+	})
+
+	s.taskMu.Lock()
+	run.proc = nil
+	run.info.PID = 0
+	run.info.ExitCode = exitCode
+	run.info.FinishedAt = time.Now()
+	run.info.State = domain.TaskStateSucceeded
+	if exitCode != 0 {
+		run.info.State = domain.TaskStateFailed
+	}
+	elapsed := run.info.FinishedAt.Sub(run.info.StartedAt)
+	s.taskMu.Unlock()
+
+	s.SystemLog("task %s exited (rc=%d) after %s", name, exitCode, humanize.Duration(elapsed))
+}
+
+// stopTasks sends SIGTERM to the running tasks, without waiting for them
+// to exit
+func (s *Supervisor) stopTasks() {
+	s.taskMu.Lock()
+	defer s.taskMu.Unlock()
+	for name, run := range s.tasks {
+		if run.proc == nil {
+			continue
+		}
+		s.SystemLog("sending SIGTERM to task %s (pid %d)", name, run.info.PID)
+		_ = run.proc.Signal(syscall.SIGTERM)
+	}
+}
@@ -0,0 +1,86 @@
+package supervisor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupervisor_RunTask(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{"web": "sleep 30"})
+	cfg.Tasks = map[string]config.TaskConfig{
+		"migrate": {Cmd: "echo migrating $TARGET; sleep 0.2; exit 3", Env: map[string]string{"TARGET": "dev"}},
+		"seed":    {Cmd: "true", Description: "Load sample data"},
+	}
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+
+	ctx := context.Background()
+	_, err := sup.RunTask(ctx, "migrate")
+	assert.ErrorIs(t, err, domain.ErrShutdownInProgress, "not started yet")
+
+	_, err = sup.Start(ctx)
+	require.NoError(t, err)
+	defer sup.Stop(ctx)
+
+	tasks := sup.Tasks()
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "migrate", tasks[0].Name)
+	assert.Equal(t, domain.TaskStateIdle, tasks[0].State)
+	assert.Equal(t, "Load sample data", tasks[1].Description)
+
+	info, err := sup.RunTask(ctx, "migrate")
+	require.NoError(t, err)
+	assert.Equal(t, domain.TaskStateRunning, info.State)
+	assert.Positive(t, info.PID)
+
+	_, err = sup.RunTask(ctx, "migrate")
+	assert.ErrorIs(t, err, domain.ErrTaskRunning)
+	_, err = sup.RunTask(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrTaskNotFound)
+
+	require.Eventually(t, func() bool {
+		info, err = sup.Task("migrate")
+		return err == nil && info.State != domain.TaskStateRunning
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, domain.TaskStateFailed, info.State)
+	assert.Equal(t, 3, info.ExitCode)
+	assert.Zero(t, info.PID)
+	assert.False(t, info.FinishedAt.Before(info.StartedAt))
+
+	entries, _, _ := logMgr.Query(domain.LogFilter{Processes: []string{"migrate"}}, 0)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "migrating dev", entries[0].Line)
+	assert.Equal(t, "exited (rc=3)", entries[1].Line)
+
+	// Tasks aren't processes
+	_, err = sup.Process("migrate")
+	assert.ErrorIs(t, err, domain.ErrProcessNotFound)
+}
+
+func TestSupervisor_RunTaskStartFailure(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	cfg := makeTestConfig(map[string]string{"web": "sleep 30"})
+	cfg.Tasks = map[string]config.TaskConfig{"seed": {Cmd: "true", EnvFile: "missing.env"}}
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+
+	ctx := context.Background()
+	_, err := sup.Start(ctx)
+	require.NoError(t, err)
+	defer sup.Stop(ctx)
+
+	info, err := sup.RunTask(ctx, "seed")
+	require.NoError(t, err)
+	assert.Equal(t, domain.TaskStateFailed, info.State)
+	assert.Contains(t, info.Error, "failed to load environment")
+}
@@ -0,0 +1,152 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+)
+
+// ProcessUpdate changes parts of a configured process's definition. Nil
+// fields are left as they are.
+type ProcessUpdate struct {
+	Cmd *string
+	// Env sets variables on top of the process's env; nil values unset them
+	Env map[string]*string
+	// Healthcheck replaces the process's healthcheck
+	Healthcheck *config.HealthcheckConfig
+	// RemoveHealthcheck drops the process's healthcheck
+	RemoveHealthcheck bool
+}
+
+// Apply returns proc with the update applied, leaving proc unchanged
+func (u ProcessUpdate) Apply(proc config.ProcessConfig) config.ProcessConfig {
+	if u.Cmd != nil {
+		proc.Cmd = *u.Cmd
+	}
+	if len(u.Env) > 0 {
+		env := make(map[string]string, len(proc.Env)+len(u.Env))
+		for k, v := range proc.Env {
+			env[k] = v
+		}
+		for k, v := range u.Env {
+			if v == nil {
+				delete(env, k)
+			} else {
+				env[k] = *v
+			}
+		}
+		proc.Env = env
+	}
+	if u.RemoveHealthcheck {
+		proc.Healthcheck = nil
+	} else if u.Healthcheck != nil {
+		hc := *u.Healthcheck
+		proc.Healthcheck = &hc
+	}
+	return proc
+}
+
+// UpdateProcess changes the definition of a configured process without
+// editing the config file. The change takes effect the next time the process
+// is started or restarted, or right away with restart, and lasts until prox
+// exits or the config is reloaded. It returns whether the definition changed.
+// Processes added with AddProcess can't be updated.
+func (s *Supervisor) UpdateProcess(ctx context.Context, name string, update ProcessUpdate, restart bool) (bool, error) {
+	changed, err := s.updateConfig(name, update)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	mp := s.processes[name]
+	s.mu.RUnlock()
+	if !restart || mp == nil || mp.State().IsStopped() {
+		return changed, nil
+	}
+	return changed, s.restartProcess(ctx, name, domain.RestartReasonUpdate)
+}
+
+// updateConfig validates and stores the updated definition, marking the
+// process for replacement when it next starts
+func (s *Supervisor) updateConfig(name string, update ProcessUpdate) (bool, error) {
+	// Serialize with reloads, which replace the config
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	s.mu.RLock()
+	running := s.state == "running"
+	old := s.config
+	_, managed := s.processes[name]
+	s.mu.RUnlock()
+	if !running {
+		return false, domain.ErrShutdownInProgress
+	}
+	procConfig, configured := old.Processes[name]
+	if !configured {
+		if managed {
+			return false, fmt.Errorf("%w: %s was added with prox run and can't be updated", domain.ErrInvalidConfig, name)
+		}
+		return false, domain.ErrProcessNotFound
+	}
+
+	updated := update.Apply(procConfig)
+	if sameProcessConfig(procConfig, updated) {
+		return false, nil
+	}
+
+	cfg := *old
+	cfg.Processes = make(map[string]config.ProcessConfig, len(old.Processes))
+	for n, p := range old.Processes {
+		cfg.Processes[n] = p
+	}
+	cfg.Processes[name] = updated
+	if err := config.Validate(&cfg); err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.config = &cfg
+	if s.pendingUpdates == nil {
+		s.pendingUpdates = make(map[string]bool)
+	}
+	s.pendingUpdates[name] = true
+	s.mu.Unlock()
+
+	s.SystemLog("updated process %s; takes effect when it restarts", name)
+	return true, nil
+}
+
+// hasPendingUpdate reports whether the process's definition was updated
+// since its current instance was created
+func (s *Supervisor) hasPendingUpdate(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pendingUpdates[name]
+}
+
+// applyPendingUpdate replaces a stopped process with an instance created from
+// its updated definition, if it has one, and returns the instance to start
+func (s *Supervisor) applyPendingUpdate(name string, mp *ManagedProcess) (*ManagedProcess, error) {
+	s.mu.RLock()
+	pending := s.pendingUpdates[name]
+	procConfig := s.config.Processes[name]
+	s.mu.RUnlock()
+	if !pending {
+		return mp, nil
+	}
+
+	next, err := s.createManagedProcess(name, procConfig)
+	if err != nil {
+		return nil, err
+	}
+	next.inheritRestarts(mp)
+
+	s.mu.Lock()
+	delete(s.pendingUpdates, name)
+	s.processes[name] = next
+	s.startLazyIdleStopperLocked(name, next)
+	s.mu.Unlock()
+	return next, nil
+}
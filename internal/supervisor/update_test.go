@@ -0,0 +1,130 @@
+package supervisor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestProcessUpdate_Apply(t *testing.T) {
+	proc := config.ProcessConfig{
+		Cmd:         "npm run dev",
+		Env:         map[string]string{"A": "1", "B": "2"},
+		Healthcheck: &config.HealthcheckConfig{Cmd: "curl -f localhost:3000"},
+	}
+
+	updated := ProcessUpdate{
+		Cmd: strPtr("npm run dev -- --inspect"),
+		Env: map[string]*string{"A": strPtr("one"), "B": nil, "C": strPtr("3")},
+	}.Apply(proc)
+	assert.Equal(t, "npm run dev -- --inspect", updated.Cmd)
+	assert.Equal(t, map[string]string{"A": "one", "C": "3"}, updated.Env)
+	assert.Equal(t, proc.Healthcheck, updated.Healthcheck)
+	// The original is left unchanged
+	assert.Equal(t, map[string]string{"A": "1", "B": "2"}, proc.Env)
+
+	updated = ProcessUpdate{RemoveHealthcheck: true}.Apply(proc)
+	assert.Nil(t, updated.Healthcheck)
+	assert.Equal(t, proc.Cmd, updated.Cmd)
+}
+
+func TestSupervisor_UpdateProcess(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	sup := New(makeTestConfig(map[string]string{"web": "sleep 30"}), logMgr, nil, DefaultSupervisorConfig())
+
+	ctx := context.Background()
+	_, err := sup.Start(ctx)
+	require.NoError(t, err)
+	defer sup.Stop(ctx)
+
+	before, err := sup.Process("web")
+	require.NoError(t, err)
+
+	// Applied on the next restart
+	changed, err := sup.UpdateProcess(ctx, "web", ProcessUpdate{Cmd: strPtr("sleep 31"), Env: map[string]*string{"MODE": strPtr("debug")}}, false)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	info, err := sup.Process("web")
+	require.NoError(t, err)
+	assert.Equal(t, before.PID, info.PID)
+	assert.Equal(t, "sleep 30", info.Cmd)
+	assert.True(t, info.PendingRestart)
+
+	require.NoError(t, sup.RestartProcess(ctx, "web"))
+	info, err = sup.Process("web")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+	assert.Equal(t, "sleep 31", info.Cmd)
+	assert.Equal(t, "debug", info.Env["MODE"])
+	assert.Equal(t, 1, info.RestartCount)
+	assert.Equal(t, domain.RestartReasonRequest, info.RestartReason)
+	assert.False(t, info.PendingRestart)
+
+	// Applied right away with restart
+	changed, err = sup.UpdateProcess(ctx, "web", ProcessUpdate{Cmd: strPtr("sleep 32")}, true)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	info, err = sup.Process("web")
+	require.NoError(t, err)
+	assert.Equal(t, "sleep 32", info.Cmd)
+	assert.Equal(t, 2, info.RestartCount)
+	assert.Equal(t, domain.RestartReasonUpdate, info.RestartReason)
+
+	// No-op updates change nothing
+	changed, err = sup.UpdateProcess(ctx, "web", ProcessUpdate{Cmd: strPtr("sleep 32")}, true)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	_, err = sup.UpdateProcess(ctx, "missing", ProcessUpdate{Cmd: strPtr("sleep 1")}, false)
+	assert.ErrorIs(t, err, domain.ErrProcessNotFound)
+	_, err = sup.UpdateProcess(ctx, "web", ProcessUpdate{Healthcheck: &config.HealthcheckConfig{Interval: "5s"}}, false)
+	assert.ErrorIs(t, err, domain.ErrInvalidConfig)
+
+	require.NoError(t, sup.AddProcess(ctx, "tunnel", config.ProcessConfig{Cmd: "sleep 30"}))
+	_, err = sup.UpdateProcess(ctx, "tunnel", ProcessUpdate{Cmd: strPtr("sleep 31")}, false)
+	assert.ErrorIs(t, err, domain.ErrInvalidConfig)
+
+	// Reloading the config file discards updates
+	result, err := sup.Reload(ctx, makeTestConfig(map[string]string{"web": "sleep 30"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web"}, result.Changed)
+	info, err = sup.Process("web")
+	require.NoError(t, err)
+	assert.Equal(t, "sleep 30", info.Cmd)
+}
+
+func TestSupervisor_UpdateStoppedProcess(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	sup := New(makeTestConfig(map[string]string{"web": "sleep 30"}), logMgr, nil, DefaultSupervisorConfig())
+
+	ctx := context.Background()
+	_, err := sup.Start(ctx)
+	require.NoError(t, err)
+	defer sup.Stop(ctx)
+
+	require.NoError(t, sup.StopProcess(ctx, "web"))
+	// Stopped processes aren't started by restart, and pick the update up
+	// when they next start
+	_, err = sup.UpdateProcess(ctx, "web", ProcessUpdate{Cmd: strPtr("sleep 31")}, true)
+	require.NoError(t, err)
+	info, err := sup.Process("web")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateStopped, info.State)
+
+	require.NoError(t, sup.StartProcess(ctx, "web"))
+	info, err = sup.Process("web")
+	require.NoError(t, err)
+	assert.Equal(t, "sleep 31", info.Cmd)
+	assert.False(t, info.PendingRestart)
+}
@@ -0,0 +1,117 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/charliek/prox/internal/constants"
+	"github.com/charliek/prox/internal/domain"
+)
+
+// waitForDependencies blocks until every wait_for target of the process is
+// reachable, or the process's wait timeout expires.
+func (s *Supervisor) waitForDependencies(ctx context.Context, mp *ManagedProcess) error {
+	cfg := mp.Config()
+	if len(cfg.WaitFor) == 0 {
+		return nil
+	}
+
+	timeout := cfg.WaitTimeout
+	if timeout <= 0 {
+		timeout = constants.DefaultWaitForTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	s.SystemLog("%s waiting for %s", cfg.Name, strings.Join(cfg.WaitFor, ", "))
+	start := time.Now()
+
+	for _, target := range cfg.WaitFor {
+		if err := waitForTarget(ctx, target); err != nil {
+			return fmt.Errorf("%w: %s not reachable after %s: %v",
+				domain.ErrDependencyUnavailable, target, time.Since(start).Round(time.Second), err)
+		}
+	}
+
+	s.SystemLog("%s dependencies ready after %s", cfg.Name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// waitProcessReady blocks until a started process passes its ready probe, or
+// its ready timeout expires
+func (s *Supervisor) waitProcessReady(mp *ManagedProcess) error {
+	timeout := mp.Config().ReadyTimeout
+	if timeout <= 0 {
+		timeout = constants.DefaultProcessReadyTimeout
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
+	defer cancel()
+	return mp.WaitReady(ctx)
+}
+
+// waitForTarget polls a single target until it is reachable. On timeout, the
+// last probe failure is returned so the caller can report why.
+func waitForTarget(ctx context.Context, target string) error {
+	ticker := time.NewTicker(constants.WaitForPollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		err := probeTarget(ctx, target)
+		if err == nil {
+			return nil
+		}
+		// Keep the last real failure rather than the cancellation it caused
+		if ctx.Err() == nil || lastErr == nil {
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			return lastErr
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeTarget checks a tcp:// target by dialing it, or an http(s):// target
+// by requesting it and expecting a non-error status.
+func probeTarget(ctx context.Context, target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		dialer := net.Dialer{Timeout: constants.WaitForProbeTimeout}
+		conn, err := dialer.DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return err
+		}
+		conn.Close()
+		return nil
+	case "http", "https":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return err
+		}
+		client := &http.Client{Timeout: constants.WaitForProbeTimeout}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+}
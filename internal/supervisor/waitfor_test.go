@@ -0,0 +1,231 @@
+package supervisor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/config"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closedPortURL returns a tcp:// URL for a port with nothing listening on it
+func closedPortURL(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+	return "tcp://" + addr
+}
+
+func TestProbeTarget(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	ctx := context.Background()
+
+	assert.NoError(t, probeTarget(ctx, "tcp://"+listener.Addr().String()))
+	assert.Error(t, probeTarget(ctx, closedPortURL(t)))
+	assert.NoError(t, probeTarget(ctx, healthy.URL+"/health"))
+
+	err = probeTarget(ctx, unhealthy.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected status 503")
+
+	assert.Error(t, probeTarget(ctx, "udp://localhost:53"))
+}
+
+func TestWaitForTarget_BecomesReachable(t *testing.T) {
+	target := closedPortURL(t)
+	addr := target[len("tcp://"):]
+
+	// Start listening after the first probe has failed
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { listener.Close() })
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, waitForTarget(ctx, target))
+}
+
+func TestSupervisor_WaitForUnavailable(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	target := closedPortURL(t)
+	cfg := makeTestConfig(map[string]string{"worker": "sleep 30"})
+	cfg.Processes["api"] = config.ProcessConfig{
+		Cmd:         "sleep 30",
+		WaitFor:     []string{target},
+		WaitTimeout: "500ms",
+	}
+
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	result, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		sup.Stop(stopCtx)
+	}()
+
+	require.Contains(t, result.Failed, "api")
+	assert.ErrorIs(t, result.Failed["api"], domain.ErrDependencyUnavailable)
+	assert.Contains(t, result.Failed["api"].Error(), target)
+	assert.Contains(t, result.Started, "worker")
+
+	info, err := sup.Process("api")
+	require.NoError(t, err)
+	assert.True(t, info.State.IsStopped())
+}
+
+func TestSupervisor_WaitForReachable(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	cfg := makeTestConfig(nil)
+	cfg.Processes["api"] = config.ProcessConfig{
+		Cmd:     "sleep 30",
+		WaitFor: []string{"tcp://" + listener.Addr().String()},
+	}
+
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	result, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		sup.Stop(stopCtx)
+	}()
+
+	assert.True(t, result.AllStarted())
+	info, err := sup.Process("api")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+}
+
+func TestSupervisor_ReadyProbe(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	target := closedPortURL(t)
+	cfg := makeTestConfig(nil)
+	cfg.Processes["api"] = config.ProcessConfig{
+		Cmd:          "sleep 30",
+		Ready:        target,
+		ReadyTimeout: "200ms",
+	}
+
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	result, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		sup.Stop(stopCtx)
+	}()
+	assert.True(t, result.AllStarted())
+
+	// Starting until the probe passes; the timeout only reports it
+	info, err := sup.Process("api")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateStarting, info.State)
+	require.Eventually(t, func() bool {
+		info, _ := sup.Process("api")
+		return info.LastError != ""
+	}, 2*time.Second, 50*time.Millisecond)
+	info, _ = sup.Process("api")
+	assert.Contains(t, info.LastError, "not ready after 200ms")
+	assert.Equal(t, domain.ProcessStateStarting, info.State)
+
+	listener, err := net.Listen("tcp", target[len("tcp://"):])
+	require.NoError(t, err)
+	defer listener.Close()
+
+	sup.mu.RLock()
+	mp := sup.processes["api"]
+	sup.mu.RUnlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, mp.WaitReady(ctx))
+
+	info, err = sup.Process("api")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+	assert.Empty(t, info.LastError)
+}
+
+func TestSupervisor_ReadyGatesDependents(t *testing.T) {
+	logMgr := logs.NewManager(logs.ManagerConfig{BufferSize: 100})
+	defer logMgr.Close()
+
+	target := closedPortURL(t)
+	addr := target[len("tcp://"):]
+	_, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	cfg := makeTestConfig(nil)
+	cfg.Processes["db"] = config.ProcessConfig{Cmd: "sleep 30", Port: port, Ready: "tcp://127.0.0.1:$PORT"}
+	cfg.Processes["api"] = config.ProcessConfig{Cmd: "sleep 30", WaitFor: []string{"tcp://localhost:" + port}}
+
+	// The db becomes ready a little after it starts
+	readyAt := make(chan time.Time, 1)
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			readyAt <- time.Time{}
+			return
+		}
+		readyAt <- time.Now()
+		t.Cleanup(func() { listener.Close() })
+	}()
+
+	sup := New(cfg, logMgr, nil, DefaultSupervisorConfig())
+	result, err := sup.Start(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		sup.Stop(stopCtx)
+	}()
+
+	assert.True(t, result.AllStarted())
+	ready := <-readyAt
+	require.False(t, ready.IsZero())
+
+	db, err := sup.Process("db")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateRunning, db.State)
+	api, err := sup.Process("api")
+	require.NoError(t, err)
+	assert.False(t, api.StartedAt.Before(ready), "api started before db was ready")
+}
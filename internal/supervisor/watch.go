@@ -0,0 +1,79 @@
+package supervisor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/watch"
+)
+
+// watchFiles gives the file watcher the watch patterns of the configured
+// processes, starting it under the config directory the first time a
+// process has some
+func (s *Supervisor) watchFiles() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != "running" {
+		return
+	}
+
+	var rules []watch.Rule
+	for name, proc := range s.config.Processes {
+		if len(proc.Watch) == 0 {
+			continue
+		}
+		rules = append(rules, watch.Rule{
+			Name:     name,
+			Patterns: proc.Watch,
+			Ignore:   proc.WatchIgnore,
+			Debounce: proc.WatchDebounceDuration(),
+		})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+
+	if s.watcher == nil {
+		if len(rules) == 0 {
+			return
+		}
+		root := s.supConfig.ConfigDir
+		if root == "" {
+			root = "."
+		}
+		w, err := watch.New(root, s.restartForChange)
+		if err != nil {
+			s.SystemLog("not watching files: %v", err)
+			return
+		}
+		s.watcher = w
+		go w.Run(s.ctx)
+	}
+	s.watcher.SetRules(rules)
+}
+
+// restartForChange restarts a watched process after its files changed. A
+// process that exited is started again, since the change may fix it, but one
+// prox stopped (by hand, asleep, or lazy and idle) stays stopped, as does
+// one that failed from crashing too often.
+func (s *Supervisor) restartForChange(name string, paths []string) {
+	s.mu.RLock()
+	mp, ok := s.processes[name]
+	ctx := s.ctx
+	s.mu.RUnlock()
+	if !ok || mp.State() == domain.ProcessStateStopped {
+		return
+	}
+	if mp.State() == domain.ProcessStateFailed {
+		s.SystemLog("%s changed, but %s crashed too often to restart; start it with prox start", paths[0], name)
+		return
+	}
+
+	changed := paths[0]
+	if len(paths) > 1 {
+		changed = fmt.Sprintf("%s and %d more", paths[0], len(paths)-1)
+	}
+	s.SystemLog("%s changed, restarting %s", changed, name)
+	if err := s.restartProcess(ctx, name, domain.RestartReasonWatch); err != nil {
+		s.SystemLog("error restarting %s: %v", name, err)
+	}
+}
@@ -0,0 +1,167 @@
+// Package theme holds how log output is colored and timestamped, shared by
+// the CLI and the TUI and set by the theme section of the config.
+package theme
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charliek/prox/internal/humanize"
+)
+
+// Backgrounds the theme's colors are chosen for
+const (
+	BackgroundDark  = "dark"  // Light text on a dark terminal (default)
+	BackgroundLight = "light" // Dark text on a light terminal
+)
+
+// Clocks timestamps are shown with
+const (
+	Clock24h = "24h" // 15:04:05 (default)
+	Clock12h = "12h" // 3:04:05PM
+)
+
+// colorNames are the names accepted for the 16 basic terminal colors
+var colorNames = map[string]int{
+	"black": 0, "red": 1, "green": 2, "yellow": 3,
+	"blue": 4, "magenta": 5, "cyan": 6, "white": 7,
+	"gray": 8, "grey": 8,
+	"bright-red": 9, "bright-green": 10, "bright-yellow": 11,
+	"bright-blue": 12, "bright-magenta": 13, "bright-cyan": 14, "bright-white": 15,
+}
+
+// lightPalette are the colors given to processes in turn on a light
+// background
+var lightPalette = []string{
+	"25",  // Blue
+	"90",  // Purple
+	"30",  // Teal
+	"130", // Brown
+	"28",  // Green
+	"124", // Red
+	"163", // Magenta
+	"61",  // Slate blue
+	"94",  // Olive
+}
+
+// Theme is how log output is colored and timestamped. The zero value, like
+// a nil Theme, is the default: colors for a dark background and local time
+// with a 24-hour clock, without colors when NO_COLOR is set.
+type Theme struct {
+	Light    bool              // Colors for a light background
+	NoColor  bool              // No colors at all
+	Clock    string            // Clock12h or Clock24h
+	TimeMode humanize.TimeMode // Local time, UTC, or relative to now
+	Colors   map[string]string // Colors set for processes, as ParseColor returns them
+}
+
+// New creates a theme from the config's theme section. Invalid values,
+// which validation rejects, are left at their defaults. Colors are off when
+// noColor is set or the NO_COLOR environment variable isn't empty.
+func New(background, clock string, colors map[string]string, noColor bool) *Theme {
+	t := &Theme{
+		Light:   background == BackgroundLight,
+		NoColor: noColor || os.Getenv("NO_COLOR") != "",
+		Clock:   clock,
+	}
+	for process, spec := range colors {
+		if color, err := ParseColor(spec); err == nil {
+			if t.Colors == nil {
+				t.Colors = make(map[string]string)
+			}
+			t.Colors[process] = color
+		}
+	}
+	return t
+}
+
+// ParseColor parses a color name (e.g. "cyan" or "bright-red"), a 256-color
+// palette index from 0 to 255, or a "#rrggbb" hex color. Names are returned
+// as their palette index, so every color is an index or a hex color.
+func ParseColor(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if n, ok := colorNames[s]; ok {
+		return strconv.Itoa(n), nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n > 255 {
+			return "", fmt.Errorf("palette index must be between 0 and 255, got %d", n)
+		}
+		return strconv.Itoa(n), nil
+	}
+	if strings.HasPrefix(s, "#") && len(s) == 7 {
+		if _, err := strconv.ParseUint(s[1:], 16, 32); err == nil {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("must be a color name, a palette index from 0 to 255, or #rrggbb, got %q", s)
+}
+
+// Palette returns the colors given to processes without a color of their
+// own, in turn: dark, the caller's own, on a dark background
+func (t *Theme) Palette(dark []string) []string {
+	if t != nil && t.Light {
+		return lightPalette
+	}
+	return dark
+}
+
+// ProcessColor returns the color set for a process, if any
+func (t *Theme) ProcessColor(process string) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	color, ok := t.Colors[process]
+	return color, ok
+}
+
+// Colored reports whether output should be colored
+func (t *Theme) Colored() bool {
+	if t == nil {
+		return os.Getenv("NO_COLOR") == ""
+	}
+	return !t.NoColor
+}
+
+// TimeLayout returns the layout of timestamps in log output
+func (t *Theme) TimeLayout() string {
+	if t != nil && t.Clock == Clock12h {
+		return "3:04:05PM"
+	}
+	return "15:04:05"
+}
+
+// FormatTime formats a log timestamp with the theme's clock and time mode
+func (t *Theme) FormatTime(ts, now time.Time) string {
+	var mode humanize.TimeMode
+	if t != nil {
+		mode = t.TimeMode
+	}
+	return humanize.Time(ts, t.TimeLayout(), mode, now)
+}
+
+// ANSI returns the escape sequence that sets the foreground to a color as
+// ParseColor returns it
+func ANSI(color string) string {
+	if strings.HasPrefix(color, "#") {
+		rgb, err := strconv.ParseUint(color[1:], 16, 32)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("\033[38;2;%d;%d;%dm", rgb>>16, rgb>>8&0xff, rgb&0xff)
+	}
+	n, err := strconv.Atoi(color)
+	switch {
+	case err != nil:
+		return ""
+	case n < 8:
+		return fmt.Sprintf("\033[%dm", 30+n)
+	case n < 16:
+		return fmt.Sprintf("\033[%dm", 90+n-8)
+	default:
+		return fmt.Sprintf("\033[38;5;%dm", n)
+	}
+}
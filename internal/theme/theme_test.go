@@ -0,0 +1,72 @@
+package theme
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "cyan", want: "6"},
+		{in: "Bright-Red", want: "9"},
+		{in: "grey", want: "8"},
+		{in: "208", want: "208"},
+		{in: "#FF8800", want: "#ff8800"},
+		{in: "256", wantErr: true},
+		{in: "#ff88", wantErr: true},
+		{in: "#gg8800", wantErr: true},
+		{in: "teal", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseColor(tt.in)
+		if tt.wantErr {
+			assert.Error(t, err, tt.in)
+			continue
+		}
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+}
+
+func TestANSI(t *testing.T) {
+	assert.Equal(t, "\033[36m", ANSI("6"))
+	assert.Equal(t, "\033[91m", ANSI("9"))
+	assert.Equal(t, "\033[38;5;208m", ANSI("208"))
+	assert.Equal(t, "\033[38;2;255;136;0m", ANSI("#ff8800"))
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	th := New(BackgroundLight, Clock12h, map[string]string{"web": "magenta", "api": "nope"}, false)
+	assert.True(t, th.Light)
+	assert.True(t, th.Colored())
+	assert.Equal(t, lightPalette, th.Palette([]string{"6"}))
+	assert.Equal(t, []string{"6"}, (*Theme)(nil).Palette([]string{"6"}))
+
+	color, ok := th.ProcessColor("web")
+	assert.True(t, ok)
+	assert.Equal(t, "5", color)
+	_, ok = th.ProcessColor("api")
+	assert.False(t, ok, "invalid colors are left out")
+
+	assert.False(t, New("", "", nil, true).Colored())
+	t.Setenv("NO_COLOR", "1")
+	assert.False(t, New("", "", nil, false).Colored())
+	assert.False(t, (*Theme)(nil).Colored())
+}
+
+func TestTheme_FormatTime(t *testing.T) {
+	ts := time.Date(2025, 1, 15, 14, 30, 5, 0, time.UTC)
+	now := ts.Add(90 * time.Second)
+
+	assert.Equal(t, "14:30:05", (&Theme{TimeMode: humanize.TimeUTC}).FormatTime(ts, now))
+	assert.Equal(t, "2:30:05PM", (&Theme{Clock: Clock12h, TimeMode: humanize.TimeUTC}).FormatTime(ts, now))
+	assert.Equal(t, "1m30s ago", (&Theme{TimeMode: humanize.TimeRelative}).FormatTime(ts, now))
+}
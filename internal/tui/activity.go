@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/logs"
+)
+
+// sparklineWidth is the number of seconds of log activity shown per process
+const sparklineWidth = 8
+
+// errorBadgeWindow is how long a process shows the error badge after it logs
+// an error line
+const errorBadgeWindow = 30 * time.Second
+
+// sparkLevels are the sparkline characters, from no lines to the busiest second
+var sparkLevels = []rune(" ▁▂▃▄▅▆▇█")
+
+// processActivity is the recent log activity of one process
+type processActivity struct {
+	counts    [sparklineWidth]int // Lines per second, oldest first
+	latest    int64               // Unix second of the last bucket
+	lastError time.Time           // Timestamp of the last error line
+}
+
+// activityTracker counts log lines per process per second, from the log
+// stream, for the process panel's sparklines and error badges
+type activityTracker map[string]*processActivity
+
+// record counts a log entry in the second it was logged
+func (t activityTracker) record(entry domain.LogEntry) {
+	a, ok := t[entry.Process]
+	if !ok {
+		a = &processActivity{}
+		t[entry.Process] = a
+	}
+
+	sec := entry.Timestamp.Unix()
+	a.advance(sec)
+	if age := a.latest - sec; age >= 0 && age < sparklineWidth {
+		a.counts[sparklineWidth-1-age]++
+	}
+	if logs.IsErrorLine(entry.Line) && entry.Timestamp.After(a.lastError) {
+		a.lastError = entry.Timestamp
+	}
+}
+
+// advance shifts the buckets so the last one is the given second
+func (a *processActivity) advance(sec int64) {
+	shift := sec - a.latest
+	if shift <= 0 {
+		return
+	}
+	if shift >= sparklineWidth {
+		a.counts = [sparklineWidth]int{}
+	} else {
+		copy(a.counts[:], a.counts[shift:])
+		for i := sparklineWidth - int(shift); i < sparklineWidth; i++ {
+			a.counts[i] = 0
+		}
+	}
+	a.latest = sec
+}
+
+// sparkline renders the named process's lines per second up to now, scaled
+// to its busiest second. It is blank for a process that has been silent.
+func (t activityTracker) sparkline(name string, now time.Time) string {
+	a, ok := t[name]
+	if !ok {
+		return strings.Repeat(" ", sparklineWidth)
+	}
+	a.advance(now.Unix())
+
+	peak := 0
+	for _, c := range a.counts {
+		peak = max(peak, c)
+	}
+
+	var sb strings.Builder
+	for _, c := range a.counts {
+		level := 0
+		if c > 0 {
+			// Round up so a single line still shows
+			level = (c*(len(sparkLevels)-1) + peak - 1) / peak
+		}
+		sb.WriteRune(sparkLevels[level])
+	}
+	return sb.String()
+}
+
+// recentError reports whether the named process logged an error line within
+// errorBadgeWindow of now
+func (t activityTracker) recentError(name string, now time.Time) bool {
+	a, ok := t[name]
+	return ok && !a.lastError.IsZero() && now.Sub(a.lastError) < errorBadgeWindow
+}
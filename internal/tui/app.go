@@ -13,9 +13,15 @@ import (
 	"github.com/charliek/prox/internal/supervisor"
 )
 
-// Run starts the TUI application
-func Run(sup *supervisor.Supervisor, logMgr *logs.Manager, reqMgr *proxy.RequestManager) error {
+// Run starts the TUI application. proxySvc may be nil when the proxy is not enabled,
+// redactor may be nil when --redact is not set, and prefs may be nil to keep the
+// default header order without saving changes.
+func Run(sup *supervisor.Supervisor, logMgr *logs.Manager, proxySvc *proxy.Service, redactor *logs.Redactor, prefs *Preferences) error {
 	model := NewModel(sup, logMgr)
+	model.proxyService = proxySvc
+	model.redactor = redactor
+	model.prefs = prefs
+	model.setProcesses(model.processes)
 	p := tea.NewProgram(model, tea.WithAltScreen())
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -37,6 +43,10 @@ func Run(sup *supervisor.Supervisor, logMgr *logs.Manager, reqMgr *proxy.Request
 	}
 
 	// Subscribe to proxy requests if available
+	var reqMgr *proxy.RequestManager
+	if proxySvc != nil {
+		reqMgr = proxySvc.RequestManager()
+	}
 	var reqSubID string
 	if reqMgr != nil {
 		sub := reqMgr.Subscribe(proxy.RequestFilter{})
@@ -58,7 +68,8 @@ func Run(sup *supervisor.Supervisor, logMgr *logs.Manager, reqMgr *proxy.Request
 	return runErr
 }
 
-// forwardLogs forwards log entries from the subscription channel to the TUI program.
+// forwardLogs forwards log entries from the subscription channel to the TUI program,
+// batching those that arrive while the program is busy.
 // It exits when the context is cancelled or the channel is closed.
 func forwardLogs(ctx context.Context, p *tea.Program, ch <-chan domain.LogEntry) {
 	for {
@@ -69,12 +80,13 @@ func forwardLogs(ctx context.Context, p *tea.Program, ch <-chan domain.LogEntry)
 			if !ok {
 				return
 			}
-			p.Send(LogEntryMsg(entry))
+			p.Send(LogBatchMsg(drain(entry, ch, maxBatch)))
 		}
 	}
 }
 
-// forwardProxyRequests forwards proxy requests from the subscription channel to the TUI program.
+// forwardProxyRequests forwards proxy requests from the subscription channel to the TUI program,
+// batching those that arrive while the program is busy.
 // It exits when the context is cancelled or the channel is closed.
 func forwardProxyRequests(ctx context.Context, p *tea.Program, ch <-chan proxy.RequestRecord) {
 	for {
@@ -85,7 +97,7 @@ func forwardProxyRequests(ctx context.Context, p *tea.Program, ch <-chan proxy.R
 			if !ok {
 				return
 			}
-			p.Send(ProxyRequestMsg(req))
+			p.Send(ProxyRequestBatchMsg(drain(req, ch, maxBatch)))
 		}
 	}
 }
@@ -94,15 +106,24 @@ func forwardProxyRequests(ctx context.Context, p *tea.Program, ch <-chan proxy.R
 // It consolidates all API operations needed by the TUI client.
 type TUIClient interface {
 	GetProcesses() (*api.ProcessListResponse, error)
+	GetProcess(name string) (*api.ProcessDetailResponse, error)
+	StartProcess(name string) error
+	StopProcess(name string) error
 	RestartProcess(name string) error
 	StreamLogsChannel(params domain.LogParams) (<-chan api.LogEntryResponse, error)
 	StreamProxyRequestsChannel(params domain.ProxyRequestParams) (<-chan api.ProxyRequestResponse, error)
 	GetProxyRequest(id string, includeBody bool) (*api.ProxyRequestDetailResponse, error)
+	GetProxyRules() (*api.ProxyRulesResponse, error)
+	SetProxyRuleEnabled(id string, enabled bool) (*api.ProxyRuleResponse, error)
+	GetProxyRecording() (*api.ProxyRecordingResponse, error)
 }
 
-// RunClient starts the TUI application in client mode (connected via API)
-func RunClient(client TUIClient) error {
+// RunClient starts the TUI application in client mode (connected via API).
+// redactor and prefs may be nil, as for Run.
+func RunClient(client TUIClient, redactor *logs.Redactor, prefs *Preferences) error {
 	model := NewClientModel(client)
+	model.redactor = redactor
+	model.prefs = prefs
 	p := tea.NewProgram(model, tea.WithAltScreen())
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -119,7 +140,8 @@ func RunClient(client TUIClient) error {
 	return err
 }
 
-// forwardClientLogs streams log entries from the API and sends them to the TUI program.
+// forwardClientLogs streams log entries from the API and sends them to the TUI program
+// in batches.
 // It exits when the context is cancelled or the channel is closed.
 func forwardClientLogs(ctx context.Context, p *tea.Program, client TUIClient) {
 	ch, err := client.StreamLogsChannel(domain.LogParams{})
@@ -149,30 +171,34 @@ func forwardClientLogs(ctx context.Context, p *tea.Program, client TUIClient) {
 				}))
 				return
 			}
-			// Convert API response to LogEntry
-			ts, parseErr := time.Parse(time.RFC3339Nano, entry.Timestamp)
-			if parseErr != nil {
-				ts = time.Now() // Fallback for malformed timestamps
-				// Log warning so server-side timestamp bugs are visible
-				p.Send(LogEntryMsg(domain.LogEntry{
+			var batch LogBatchMsg
+			for _, entry := range drain(entry, ch, maxBatch) {
+				// Convert API response to LogEntry
+				ts, parseErr := time.Parse(time.RFC3339Nano, entry.Timestamp)
+				if parseErr != nil {
+					ts = time.Now() // Fallback for malformed timestamps
+					// Log warning so server-side timestamp bugs are visible
+					batch = append(batch, domain.LogEntry{
+						Timestamp: ts,
+						Process:   "system",
+						Stream:    domain.StreamStderr,
+						Line:      "Warning: failed to parse log timestamp: " + parseErr.Error(),
+					})
+				}
+				batch = append(batch, domain.LogEntry{
 					Timestamp: ts,
-					Process:   "system",
-					Stream:    domain.StreamStderr,
-					Line:      "Warning: failed to parse log timestamp: " + parseErr.Error(),
-				}))
-			}
-			logEntry := domain.LogEntry{
-				Timestamp: ts,
-				Process:   entry.Process,
-				Stream:    domain.Stream(entry.Stream),
-				Line:      entry.Line,
+					Process:   entry.Process,
+					Stream:    domain.Stream(entry.Stream),
+					Line:      entry.Line,
+				})
 			}
-			p.Send(LogEntryMsg(logEntry))
+			p.Send(batch)
 		}
 	}
 }
 
-// forwardClientProxyRequests streams proxy requests from the API and sends them to the TUI program.
+// forwardClientProxyRequests streams proxy requests from the API and sends them to the TUI program
+// in batches.
 // It exits when the context is cancelled or the channel is closed.
 func forwardClientProxyRequests(ctx context.Context, p *tea.Program, client TUIClient) {
 	ch, err := client.StreamProxyRequestsChannel(domain.ProxyRequestParams{})
@@ -190,29 +216,43 @@ func forwardClientProxyRequests(ctx context.Context, p *tea.Program, client TUIC
 				// Channel closed - connection lost
 				return
 			}
-			// Convert API response to RequestRecord
-			ts, parseErr := time.Parse(time.RFC3339Nano, req.Timestamp)
-			if parseErr != nil {
-				ts = time.Now() // Fallback for malformed timestamps
-				// Log warning so server-side timestamp bugs are visible
-				p.Send(LogEntryMsg(domain.LogEntry{
-					Timestamp: ts,
-					Process:   "system",
-					Stream:    domain.StreamStderr,
-					Line:      "Warning: failed to parse proxy request timestamp: " + parseErr.Error(),
-				}))
-			}
-			record := proxy.RequestRecord{
-				ID:         req.ID,
-				Timestamp:  ts,
-				Method:     req.Method,
-				URL:        req.URL,
-				Subdomain:  req.Subdomain,
-				StatusCode: req.StatusCode,
-				Duration:   time.Duration(req.DurationMs) * time.Millisecond,
-				RemoteAddr: req.RemoteAddr,
+			var batch ProxyRequestBatchMsg
+			for _, req := range drain(req, ch, maxBatch) {
+				// Convert API response to RequestRecord
+				ts, parseErr := time.Parse(time.RFC3339Nano, req.Timestamp)
+				if parseErr != nil {
+					ts = time.Now() // Fallback for malformed timestamps
+					// Log warning so server-side timestamp bugs are visible
+					p.Send(LogEntryMsg(domain.LogEntry{
+						Timestamp: ts,
+						Process:   "system",
+						Stream:    domain.StreamStderr,
+						Line:      "Warning: failed to parse proxy request timestamp: " + parseErr.Error(),
+					}))
+				}
+				batch = append(batch, proxy.RequestRecord{
+					ID:               req.ID,
+					Timestamp:        ts,
+					Method:           req.Method,
+					URL:              req.URL,
+					Subdomain:        req.Subdomain,
+					StatusCode:       req.StatusCode,
+					Duration:         time.Duration(req.DurationMs) * time.Millisecond,
+					RemoteAddr:       req.RemoteAddr,
+					SchemaViolations: req.SchemaViolations,
+					Type:             req.Type,
+					WebSocket:        webSocketStats(req.WebSocket),
+				})
 			}
-			p.Send(ProxyRequestMsg(record))
+			p.Send(batch)
 		}
 	}
 }
+
+// webSocketStats converts the WebSocket connection of an API request response
+func webSocketStats(ws *api.WebSocketResponse) *proxy.WebSocketStats {
+	if ws == nil {
+		return nil
+	}
+	return &proxy.WebSocketStats{Open: ws.Open, BytesIn: ws.BytesIn, BytesOut: ws.BytesOut}
+}
@@ -1,8 +1,12 @@
 package tui
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -10,6 +14,8 @@ import (
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
+	"github.com/charliek/prox/internal/logs"
 	"github.com/charliek/prox/internal/proxy"
 )
 
@@ -22,6 +28,9 @@ const maxProxyRequests = 1000
 // maxErrorDisplayLen is the maximum length of error messages in the status bar
 const maxErrorDisplayLen = 60
 
+// filterPlaceholder is the text input's placeholder in the filter modes
+const filterPlaceholder = "Type to filter..."
+
 // HelpConfig configures the help view for different modes
 type HelpConfig struct {
 	// TitleSuffix is appended to "Prox - Process Manager" (e.g., "(Client Mode)")
@@ -35,7 +44,12 @@ type BaseModel struct {
 	// State
 	processes     []domain.ProcessInfo
 	logEntries    []domain.LogEntry
+	logLines      []string // Formatted logEntries, "" until first shown (see render.go)
 	proxyRequests []proxy.RequestRecord
+	rules         []proxy.Rule
+
+	// Set while the proxy isn't recording requests (see prox requests pause)
+	recordingPaused bool
 
 	// UI components
 	viewport  viewport.Model
@@ -49,14 +63,40 @@ type BaseModel struct {
 	filterProcesses map[string]bool // Which processes to show
 	soloProcess     string          // Single process to show (1-9 keys)
 	searchPattern   string          // Current search/filter pattern
+	stderrOnly      bool            // Show only stderr lines (e key)
 	searchMatches   []int           // Line indices matching search
 
+	// Recent log activity per process, for the process panel (see activity.go)
+	activity activityTracker
+
 	// Auto-scroll
 	followMode bool // Auto-scroll to bottom on new logs
 
-	// Last restart result for feedback
-	lastRestartProcess string
-	lastRestartError   error
+	// Throttled rendering (see render.go)
+	viewportStale  bool // Entries arrived since the viewport was rebuilt
+	frameScheduled bool // A frameMsg is on its way
+
+	// Last start, stop, or restart result for feedback
+	lastAction *ProcessActionMsg
+
+	// Processes view selection (see processes.go), and in client mode the
+	// details fetched for it
+	selectedProcess int
+	processDetail   *domain.ProcessInfo
+
+	// Time-travel scrubbing in the requests view (see scrub.go)
+	scrubbing bool
+	scrubID   string    // Request at the scrub cursor
+	scrubAt   time.Time // Timestamp of the cursor request, used if it is trimmed
+
+	// Rules view selection and last toggle result for feedback
+	selectedRule   int
+	lastRuleToggle *RuleToggleResultMsg
+
+	// Command palette (see palette.go): the highlighted suggestion and the
+	// error from the last command run
+	paletteSelected int
+	paletteError    string
 
 	// Request detail view
 	selectedRequestID string
@@ -71,23 +111,33 @@ type BaseModel struct {
 
 	// Help configuration
 	helpConfig HelpConfig
+
+	// Persisted header order and pins (see prefs.go), and the last error
+	// saving them
+	prefs      *Preferences
+	prefsError error
+
+	// Masks sensitive values in displayed lines; nil unless --redact is set
+	redactor *logs.Redactor
 }
 
 // newBaseModel creates a new BaseModel with the given help configuration
 func newBaseModel(helpConfig HelpConfig) BaseModel {
 	ti := textinput.New()
-	ti.Placeholder = "Type to filter..."
+	ti.Placeholder = filterPlaceholder
 	ti.CharLimit = 100
 	ti.Width = 40
 
 	return BaseModel{
 		processes:       make([]domain.ProcessInfo, 0),
 		logEntries:      make([]domain.LogEntry, 0),
+		logLines:        make([]string, 0),
 		proxyRequests:   make([]proxy.RequestRecord, 0),
 		textInput:       ti,
 		mode:            ModeNormal,
 		viewMode:        ViewModeLogs,
 		filterProcesses: make(map[string]bool),
+		activity:        make(activityTracker),
 		followMode:      true,
 		helpConfig:      helpConfig,
 	}
@@ -117,50 +167,143 @@ func (b *BaseModel) handleWindowSize(msg tea.WindowSizeMsg) {
 	}
 }
 
-// handleLogEntry handles a new log entry message
-func (b *BaseModel) handleLogEntry(entry domain.LogEntry) {
-	// Check if we're at/near bottom BEFORE adding new content
-	wasNearBottom := b.isNearBottom()
+// setProcesses replaces the process list, arranged by the user's preferences
+func (b *BaseModel) setProcesses(processes []domain.ProcessInfo) {
+	arranged := b.prefs.Arrange(processes)
+	// Process colors follow the order, so reordering restyles the log lines
+	if !sameProcessOrder(b.processes, arranged) {
+		b.clearLogLines()
+	}
+	b.processes = arranged
+	b.clampSelectedProcess()
+	if b.viewMode == ViewModeProcesses {
+		b.updateViewport()
+	}
+}
 
-	b.logEntries = append(b.logEntries, entry)
-	// Keep only last entries - create new slice to release memory from old entries
-	if len(b.logEntries) > maxLogEntries {
-		newEntries := make([]domain.LogEntry, maxLogEntries)
-		copy(newEntries, b.logEntries[len(b.logEntries)-maxLogEntries:])
-		b.logEntries = newEntries
+// moveSoloProcess moves the solo'd process one place left or right in the
+// header, which also changes its 1-9 key
+func (b *BaseModel) moveSoloProcess(delta int) {
+	if b.soloProcess == "" || b.prefs == nil {
+		return
 	}
+	if b.prefs.Move(b.processes, b.soloProcess, delta) {
+		b.savePrefs()
+	}
+}
+
+// togglePinSoloProcess pins the solo'd process to the front of the header,
+// or unpins it
+func (b *BaseModel) togglePinSoloProcess() {
+	if b.soloProcess == "" || b.prefs == nil {
+		return
+	}
+	b.prefs.TogglePin(b.soloProcess)
+	b.savePrefs()
+}
+
+// savePrefs re-arranges the header and persists the preferences
+func (b *BaseModel) savePrefs() {
+	b.setProcesses(b.processes)
+	b.prefsError = b.prefs.Save()
 	b.updateViewport()
+}
 
-	// If user was at bottom, re-enable follow mode and stay at bottom
-	if wasNearBottom {
-		b.followMode = true
-		b.viewport.GotoBottom()
-	} else if b.followMode {
-		b.viewport.GotoBottom()
+// handleLogEntries handles new log entries. The viewport is rebuilt by the
+// frame the returned command schedules.
+func (b *BaseModel) handleLogEntries(entries []domain.LogEntry) tea.Cmd {
+	for _, entry := range entries {
+		b.activity.record(entry)
+	}
+	b.logEntries = append(b.logEntries, entries...)
+	b.logLines = append(b.logLines, make([]string, len(entries))...)
+	// Keep only last entries - create new slices to release memory from old entries
+	if len(b.logEntries) > maxLogEntries {
+		trim := len(b.logEntries) - maxLogEntries
+		b.logEntries = append(make([]domain.LogEntry, 0, maxLogEntries), b.logEntries[trim:]...)
+		b.logLines = append(make([]string, 0, maxLogEntries), b.logLines[trim:]...)
 	}
+	return b.markStale()
 }
 
-// handleProxyRequest handles a new proxy request message
-func (b *BaseModel) handleProxyRequest(req proxy.RequestRecord) {
-	// Check if we're at/near bottom BEFORE adding new content
-	wasNearBottom := b.isNearBottom()
+// proxyRequestIndex returns the index of the request with the given ID, or
+// -1 if it isn't shown
+func (b *BaseModel) proxyRequestIndex(id string) int {
+	for i := len(b.proxyRequests) - 1; i >= 0; i-- {
+		if b.proxyRequests[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
 
-	b.proxyRequests = append(b.proxyRequests, req)
+// handleProxyRequests handles new proxy requests. The viewport is rebuilt by
+// the frame the returned command schedules.
+func (b *BaseModel) handleProxyRequests(reqs []proxy.RequestRecord) tea.Cmd {
+	for _, req := range reqs {
+		// WebSocket requests are sent again when their connection closes
+		if req.Type == proxy.RequestTypeWebSocket && req.ID != "" {
+			if i := b.proxyRequestIndex(req.ID); i >= 0 {
+				b.proxyRequests[i] = req
+				continue
+			}
+		}
+		b.proxyRequests = append(b.proxyRequests, req)
+	}
 	// Keep only last requests - create new slice to release memory from old requests
 	if len(b.proxyRequests) > maxProxyRequests {
 		newRequests := make([]proxy.RequestRecord, maxProxyRequests)
 		copy(newRequests, b.proxyRequests[len(b.proxyRequests)-maxProxyRequests:])
 		b.proxyRequests = newRequests
 	}
-	b.updateViewport()
+	return b.markStale()
+}
 
-	// If user was at bottom, re-enable follow mode and stay at bottom
-	if wasNearBottom {
-		b.followMode = true
-		b.viewport.GotoBottom()
-	} else if b.followMode {
-		b.viewport.GotoBottom()
+// handleRules replaces the rules list, keeping the selection in range
+func (b *BaseModel) handleRules(rules []proxy.Rule) {
+	b.rules = rules
+	if b.selectedRule >= len(b.rules) {
+		b.selectedRule = len(b.rules) - 1
+	}
+	if b.selectedRule < 0 {
+		b.selectedRule = 0
+	}
+	if b.viewMode == ViewModeRules {
+		b.updateViewport()
+	}
+}
+
+// selectedRuleItem returns the rule under the cursor in the rules view
+func (b *BaseModel) selectedRuleItem() (proxy.Rule, bool) {
+	if b.viewMode != ViewModeRules || b.selectedRule >= len(b.rules) {
+		return proxy.Rule{}, false
+	}
+	return b.rules[b.selectedRule], true
+}
+
+// statusInfo returns the feedback message for the most recent action
+func (b *BaseModel) statusInfo() string {
+	if b.prefsError != nil {
+		return "Saving preferences failed: " + truncateError(b.prefsError, maxErrorDisplayLen)
+	}
+	if b.lastRuleToggle != nil {
+		if b.lastRuleToggle.Err != nil {
+			return "Toggle failed: " + truncateError(b.lastRuleToggle.Err, maxErrorDisplayLen)
+		}
+		state := "disabled"
+		if b.lastRuleToggle.Enabled {
+			state = "enabled"
+		}
+		return fmt.Sprintf("Rule %s: %s", state, b.lastRuleToggle.ID)
+	}
+	if b.lastAction != nil {
+		text := processActionText[b.lastAction.Action]
+		if b.lastAction.Err != nil {
+			return text[1] + ": " + truncateError(b.lastAction.Err, maxErrorDisplayLen)
+		}
+		return text[0] + ": " + b.lastAction.Process
 	}
+	return ""
 }
 
 // handleFilterKey handles keys in filter mode
@@ -264,26 +407,52 @@ func (b *BaseModel) handleNavigationKey(msg tea.KeyMsg) bool {
 		switch b.viewMode {
 		case ViewModeLogs:
 			b.viewMode = ViewModeRequests
-		case ViewModeRequests:
+		case ViewModeRequests, ViewModeRules, ViewModeProcesses:
 			b.viewMode = ViewModeLogs
 		}
 		// In detail view, tab does nothing
 		b.updateViewport()
 		return true
 
+	case "p":
+		// Toggle the proxy rules view
+		switch b.viewMode {
+		case ViewModeRules:
+			b.viewMode = ViewModeLogs
+		case ViewModeLogs, ViewModeRequests, ViewModeProcesses:
+			b.viewMode = ViewModeRules
+		}
+		b.updateViewport()
+		return true
+
+	case "P":
+		// Toggle the processes view
+		switch b.viewMode {
+		case ViewModeProcesses:
+			b.viewMode = ViewModeLogs
+		case ViewModeLogs, ViewModeRequests, ViewModeRules:
+			b.viewMode = ViewModeProcesses
+		}
+		b.updateViewport()
+		return true
+
 	case "?":
 		b.mode = ModeHelp
 		return true
 
+	case ":":
+		b.openPalette()
+		return true
+
 	case "f":
-		if b.viewMode != ViewModeRequestDetail {
+		if b.viewMode != ViewModeRequestDetail && b.viewMode != ViewModeRules && b.viewMode != ViewModeProcesses {
 			b.mode = ModeFilter
 			b.textInput.Focus()
 		}
 		return true
 
 	case "/":
-		if b.viewMode != ViewModeRequestDetail {
+		if b.viewMode != ViewModeRequestDetail && b.viewMode != ViewModeRules && b.viewMode != ViewModeProcesses {
 			b.mode = ModeSearch
 			b.textInput.SetValue("")
 			b.textInput.Focus()
@@ -291,7 +460,7 @@ func (b *BaseModel) handleNavigationKey(msg tea.KeyMsg) bool {
 		return true
 
 	case "s":
-		if b.viewMode != ViewModeRequestDetail {
+		if b.viewMode != ViewModeRequestDetail && b.viewMode != ViewModeRules && b.viewMode != ViewModeProcesses {
 			b.mode = ModeStringFilter
 			b.textInput.SetValue("")
 			b.textInput.Focus()
@@ -315,7 +484,67 @@ func (b *BaseModel) handleNavigationKey(msg tea.KeyMsg) bool {
 		}
 		return true
 
+	case "e":
+		// Toggle showing only stderr (logs view only)
+		if b.viewMode == ViewModeLogs {
+			b.stderrOnly = !b.stderrOnly
+			b.updateViewport()
+		}
+		return true
+
+	case "<", ">":
+		// Reorder the solo'd process in the header (logs view only)
+		if b.viewMode == ViewModeLogs {
+			delta := 1
+			if msg.String() == "<" {
+				delta = -1
+			}
+			b.moveSoloProcess(delta)
+		}
+		return true
+
+	case "*":
+		// Pin the solo'd process first in the header (logs view only)
+		if b.viewMode == ViewModeLogs {
+			b.togglePinSoloProcess()
+		}
+		return true
+
+	case "t":
+		// Toggle time-travel scrubbing in requests view
+		if b.viewMode == ViewModeRequests {
+			if b.scrubbing {
+				b.stopScrub()
+			} else {
+				b.startScrub()
+			}
+		}
+		return true
+
+	case "left", "h":
+		if b.scrubbing && b.viewMode == ViewModeRequests {
+			b.scrubStep(-1)
+		}
+		return true
+
+	case "right", "l":
+		if b.scrubbing && b.viewMode == ViewModeRequests {
+			b.scrubStep(1)
+		}
+		return true
+
 	case "esc":
+		// While scrubbing, return to live requests
+		if b.scrubbing && b.viewMode == ViewModeRequests {
+			b.stopScrub()
+			return true
+		}
+		// In rules and processes views, go back to logs
+		if b.viewMode == ViewModeRules || b.viewMode == ViewModeProcesses {
+			b.viewMode = ViewModeLogs
+			b.updateViewport()
+			return true
+		}
 		// In detail view, go back to requests list
 		if b.viewMode == ViewModeRequestDetail {
 			b.viewMode = ViewModeRequests
@@ -328,16 +557,45 @@ func (b *BaseModel) handleNavigationKey(msg tea.KeyMsg) bool {
 		// Clear filters
 		b.soloProcess = ""
 		b.searchPattern = ""
+		b.stderrOnly = false
 		b.searchMatches = nil
 		b.updateViewport()
 		return true
 
 	case "up", "k":
+		if b.viewMode == ViewModeRules {
+			if b.selectedRule > 0 {
+				b.selectedRule--
+				b.updateViewport()
+			}
+			return true
+		}
+		if b.viewMode == ViewModeProcesses {
+			if b.selectedProcess > 0 {
+				b.selectedProcess--
+				b.updateViewport()
+			}
+			return true
+		}
 		b.viewport.LineUp(1)
 		b.followMode = false
 		return true
 
 	case "down", "j":
+		if b.viewMode == ViewModeRules {
+			if b.selectedRule < len(b.rules)-1 {
+				b.selectedRule++
+				b.updateViewport()
+			}
+			return true
+		}
+		if b.viewMode == ViewModeProcesses {
+			if b.selectedProcess < len(b.processes)-1 {
+				b.selectedProcess++
+				b.updateViewport()
+			}
+			return true
+		}
 		b.viewport.LineDown(1)
 		return true
 
@@ -379,8 +637,9 @@ func (b *BaseModel) updateSearchMatches() {
 	}
 
 	// Find matching lines
+	filter := domain.LogFilter{Patterns: []string{b.searchPattern}, IgnoreCase: true}.MustCompile()
 	for i, entry := range b.logEntries {
-		if containsIgnoreCase(entry.Line, b.searchPattern) {
+		if filter.Matches(entry) {
 			b.searchMatches = append(b.searchMatches, i)
 		}
 	}
@@ -399,19 +658,31 @@ func (b *BaseModel) updateViewport() {
 	var lines []string
 
 	switch b.viewMode {
+	case ViewModeRules:
+		lines = b.formatRules()
+	case ViewModeProcesses:
+		lines = b.formatProcesses()
 	case ViewModeRequestDetail:
 		lines = b.formatRequestDetail()
 	case ViewModeRequests:
+		if b.scrubbing {
+			lines = b.formatScrubRequests()
+			break
+		}
 		requests := b.filteredProxyRequests()
 		for _, req := range requests {
 			line := b.formatProxyRequest(req)
 			lines = append(lines, line)
 		}
 	default: // ViewModeLogs
-		entries := b.filteredEntries()
-		for _, entry := range entries {
-			line := b.formatLogEntry(entry)
-			lines = append(lines, line)
+		if len(b.logLines) != len(b.logEntries) {
+			b.logLines = make([]string, len(b.logEntries)) // Entries were replaced
+		}
+		filter := b.entryFilter()
+		for i, entry := range b.logEntries {
+			if filter.Matches(entry) {
+				lines = append(lines, b.logLine(i))
+			}
 		}
 	}
 
@@ -419,6 +690,31 @@ func (b *BaseModel) updateViewport() {
 	b.viewport.SetContent(content)
 }
 
+// formatRules formats the proxy rules view
+func (b *BaseModel) formatRules() []string {
+	if len(b.rules) == 0 {
+		return []string{dimStyle.Render("No proxy rules (is the proxy enabled, with a service rewrite configured?)")}
+	}
+
+	lines := []string{headerStyle.Render("Proxy Rules"), ""}
+	for i, rule := range b.rules {
+		cursor := "  "
+		if i == b.selectedRule {
+			cursor = "> "
+		}
+		state := dimStyle.Render("[ ] off")
+		if rule.Enabled {
+			state = httpSuccessStyle.Render("[x] ON ")
+		}
+		line := fmt.Sprintf("%s%s  %-12s %-10s %s",
+			cursor, state, string(rule.Kind), rule.Service, dimStyle.Render(rule.Description))
+		lines = append(lines, line)
+	}
+	lines = append(lines, "")
+	lines = append(lines, dimStyle.Render("Space/Enter: toggle rule | p/ESC: back to logs"))
+	return lines
+}
+
 // formatRequestDetail formats the request detail view
 func (b *BaseModel) formatRequestDetail() []string {
 	var lines []string
@@ -445,76 +741,35 @@ func (b *BaseModel) formatRequestDetail() []string {
 	lines = append(lines, "")
 	lines = append(lines, fmt.Sprintf("  Time:     %s", d.Timestamp))
 	lines = append(lines, fmt.Sprintf("  Method:   %s", d.Method))
-	lines = append(lines, fmt.Sprintf("  URL:      %s", d.URL))
+	lines = append(lines, fmt.Sprintf("  URL:      %s", b.redactor.Redact(d.URL)))
 	lines = append(lines, fmt.Sprintf("  Status:   %d", d.StatusCode))
-	lines = append(lines, fmt.Sprintf("  Duration: %dms", d.DurationMs))
-	lines = append(lines, fmt.Sprintf("  Remote:   %s", d.RemoteAddr))
-
-	// Request headers
-	if len(d.RequestHeaders) > 0 {
-		lines = append(lines, "")
-		lines = append(lines, headerStyle.Render("Request Headers"))
-		for name, values := range d.RequestHeaders {
-			for _, value := range values {
-				lines = append(lines, fmt.Sprintf("  %s: %s", dimStyle.Render(name), value))
-			}
-		}
-	}
-
-	// Response headers
-	if len(d.ResponseHeaders) > 0 {
-		lines = append(lines, "")
-		lines = append(lines, headerStyle.Render("Response Headers"))
-		for name, values := range d.ResponseHeaders {
-			for _, value := range values {
-				lines = append(lines, fmt.Sprintf("  %s: %s", dimStyle.Render(name), value))
-			}
+	lines = append(lines, "  Duration: "+humanize.Duration(time.Duration(d.DurationMs)*time.Millisecond))
+	lines = append(lines, fmt.Sprintf("  Remote:   %s", b.redactor.Redact(d.RemoteAddr)))
+	if ws := d.WebSocket; ws != nil {
+		state := "closed"
+		if ws.Open {
+			state = "open"
 		}
+		lines = append(lines, fmt.Sprintf("  Socket:   %s, %d bytes in, %d bytes out", state, ws.BytesIn, ws.BytesOut))
 	}
 
-	// Request body
-	if d.RequestBody != nil && d.RequestBody.Size > 0 {
+	// Schema violations found in the response body
+	if len(d.SchemaViolations) > 0 {
 		lines = append(lines, "")
-		bodyTitle := fmt.Sprintf("Request Body (%d bytes", d.RequestBody.Size)
-		if d.RequestBody.Truncated {
-			bodyTitle += ", truncated"
-		}
-		bodyTitle += ")"
-		lines = append(lines, headerStyle.Render(bodyTitle))
-		if d.RequestBody.Data != "" {
-			if d.RequestBody.IsBinary {
-				lines = append(lines, dimStyle.Render("[binary data]"))
-			} else {
-				// Split body into lines
-				bodyLines := strings.Split(d.RequestBody.Data, "\n")
-				for _, line := range bodyLines {
-					lines = append(lines, "  "+line)
-				}
-			}
+		lines = append(lines, headerStyle.Render("Schema Violations"))
+		for _, v := range d.SchemaViolations {
+			lines = append(lines, "  "+httpWarningStyle.Render(b.redactor.Redact(v)))
 		}
 	}
 
-	// Response body
-	if d.ResponseBody != nil && d.ResponseBody.Size > 0 {
+	if d.RequestHeaders == nil && d.ResponseHeaders == nil {
 		lines = append(lines, "")
-		bodyTitle := fmt.Sprintf("Response Body (%d bytes", d.ResponseBody.Size)
-		if d.ResponseBody.Truncated {
-			bodyTitle += ", truncated"
-		}
-		bodyTitle += ")"
-		lines = append(lines, headerStyle.Render(bodyTitle))
-		if d.ResponseBody.Data != "" {
-			if d.ResponseBody.IsBinary {
-				lines = append(lines, dimStyle.Render("[binary data]"))
-			} else {
-				// Split body into lines
-				bodyLines := strings.Split(d.ResponseBody.Data, "\n")
-				for _, line := range bodyLines {
-					lines = append(lines, "  "+line)
-				}
-			}
-		}
+		lines = append(lines, dimStyle.Render("Headers and bodies weren't captured; enable proxy.capture or run prox up --capture"))
 	}
+	lines = append(lines, b.formatDetailHeaders("Request Headers", d.RequestHeaders)...)
+	lines = append(lines, b.formatDetailHeaders("Response Headers", d.ResponseHeaders)...)
+	lines = append(lines, b.formatDetailBody("Request Body", d.RequestBody)...)
+	lines = append(lines, b.formatDetailBody("Response Body", d.ResponseBody)...)
 
 	// Footer hint
 	lines = append(lines, "")
@@ -523,32 +778,91 @@ func (b *BaseModel) formatRequestDetail() []string {
 	return lines
 }
 
-// filteredEntries returns log entries after applying filters
-func (b *BaseModel) filteredEntries() []domain.LogEntry {
-	var result []domain.LogEntry
+// formatDetailHeaders formats a section of headers, sorted by name
+func (b *BaseModel) formatDetailHeaders(title string, headers map[string][]string) []string {
+	if len(headers) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-	for _, entry := range b.logEntries {
-		// Process filter
-		if b.soloProcess != "" && entry.Process != b.soloProcess {
-			continue
+	lines := []string{"", headerStyle.Render(title)}
+	for _, name := range names {
+		for _, value := range headers[name] {
+			lines = append(lines, fmt.Sprintf("  %s: %s", dimStyle.Render(name), b.redactor.Redact(value)))
 		}
+	}
+	return lines
+}
 
-		// Check filterProcesses map
-		if show, ok := b.filterProcesses[entry.Process]; ok && !show {
-			continue
-		}
+// formatDetailBody formats a section for a captured body, pretty-printing
+// JSON
+func (b *BaseModel) formatDetailBody(title string, body *BodyData) []string {
+	if body == nil || body.Size == 0 {
+		return nil
+	}
+	title = fmt.Sprintf("%s (%d bytes", title, body.Size)
+	if body.Truncated {
+		title += ", truncated"
+	}
+	title += ")"
 
-		// String filter
-		if b.searchPattern != "" {
-			if !containsIgnoreCase(entry.Line, b.searchPattern) {
-				continue
-			}
-		}
+	lines := []string{"", headerStyle.Render(title)}
+	if body.Data == "" {
+		return lines
+	}
+	if body.IsBinary {
+		return append(lines, dimStyle.Render("[binary data]"))
+	}
+	for _, line := range strings.Split(prettyBody(body), "\n") {
+		lines = append(lines, "  "+b.redactor.Redact(line))
+	}
+	return lines
+}
 
-		result = append(result, entry)
+// prettyBody indents a JSON body. Other bodies, and JSON cut off by the
+// capture limit, are returned as is.
+func prettyBody(body *BodyData) string {
+	trimmed := strings.TrimSpace(body.Data)
+	if !strings.Contains(body.ContentType, "json") && !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return body.Data
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(trimmed), "", "  "); err != nil {
+		return body.Data
 	}
+	return buf.String()
+}
 
-	return result
+// filteredEntries returns log entries after applying filters
+func (b *BaseModel) filteredEntries() []domain.LogEntry {
+	return b.entryFilter().Apply(b.logEntries)
+}
+
+// entryFilter returns the process, stream, and string filters compiled for
+// matching log entries. The string filter is matched regardless of case.
+func (b *BaseModel) entryFilter() *domain.Filter {
+	var filter domain.LogFilter
+	if b.stderrOnly {
+		filter.Stream = domain.StreamStderr
+	}
+	if b.soloProcess != "" {
+		filter.Processes = []string{b.soloProcess}
+	}
+	for name, show := range b.filterProcesses {
+		if !show {
+			filter.ExcludeProcesses = append(filter.ExcludeProcesses, name)
+		}
+	}
+	if b.searchPattern != "" {
+		filter.Patterns = []string{b.searchPattern}
+		filter.IgnoreCase = true
+	}
+	// The text input's character limit keeps the pattern short enough to compile
+	return filter.MustCompile()
 }
 
 // filteredProxyRequests returns proxy requests after applying filters
@@ -578,6 +892,9 @@ func (b *BaseModel) getSelectedRequest() string {
 	if b.viewMode != ViewModeRequests {
 		return ""
 	}
+	if b.scrubbing {
+		return b.scrubID
+	}
 
 	requests := b.filteredProxyRequests()
 	if len(requests) == 0 {
@@ -596,7 +913,7 @@ func (b *BaseModel) getSelectedRequest() string {
 // formatProxyRequest formats a single proxy request for display
 func (b *BaseModel) formatProxyRequest(req proxy.RequestRecord) string {
 	// Format timestamp
-	ts := req.Timestamp.Format("15:04:05")
+	ts := displayTheme.FormatTime(req.Timestamp, time.Now())
 
 	// Format subdomain with padding
 	subdomain := fmt.Sprintf("%-10s", req.Subdomain)
@@ -620,23 +937,26 @@ func (b *BaseModel) formatProxyRequest(req proxy.RequestRecord) string {
 	}
 	status := statusStyle.Render(fmt.Sprintf("%3d", req.StatusCode))
 
-	// Format duration with overflow handling
-	durationMs := req.Duration.Milliseconds()
-	var duration string
-	if durationMs > 9999 {
-		duration = "9999+"
-	} else {
-		duration = fmt.Sprintf("%5d", durationMs)
-	}
+	duration := fmt.Sprintf("%6s", humanize.Duration(req.Duration))
 
-	return fmt.Sprintf("%s  %s  %s %s %sms  %s",
+	line := fmt.Sprintf("%s  %s  %s %s %s  %s",
 		dimStyle.Render(ts),
 		dimStyle.Render(subdomain),
 		method,
 		status,
 		dimStyle.Render(duration),
-		req.URL,
+		b.redactor.Redact(req.URL),
 	)
+	if len(req.SchemaViolations) > 0 {
+		line += "  " + httpWarningStyle.Render("[schema]")
+	}
+	switch {
+	case req.WebSocket != nil && req.WebSocket.Open:
+		line += "  " + httpSuccessStyle.Render("[ws open]")
+	case req.Type == proxy.RequestTypeWebSocket:
+		line += "  " + dimStyle.Render("[ws]")
+	}
+	return line
 }
 
 // formatLogEntry formats a single log entry for display
@@ -645,7 +965,7 @@ func (b *BaseModel) formatLogEntry(entry domain.LogEntry) string {
 	procStyle := getProcessStyle(entry.Process, b.processes)
 
 	// Format timestamp
-	ts := entry.Timestamp.Format("15:04:05")
+	ts := displayTheme.FormatTime(entry.Timestamp, time.Now())
 
 	// Format process name with padding
 	procName := fmt.Sprintf("%-10s", entry.Process)
@@ -660,29 +980,41 @@ func (b *BaseModel) formatLogEntry(entry domain.LogEntry) string {
 		streamIndicator = errorStyle.Render(" ERR ")
 	}
 
-	return fmt.Sprintf("%s %s%s %s", timestamp, prefix, streamIndicator, entry.Line)
+	return fmt.Sprintf("%s %s%s %s", timestamp, prefix, streamIndicator, b.redactor.Redact(entry.Line))
 }
 
 // processPanel renders the process status header
 func (b *BaseModel) processPanel() string {
 	var items []string
+	now := time.Now()
 
 	// Show processes panel in both views
 	for i, proc := range b.processes {
 		style := processStyle(proc.State)
 
+		// Log lines per second, and a badge for a recent error line
+		activity := " " + getProcessStyle(proc.Name, b.processes).Render(b.activity.sparkline(proc.Name, now))
+		if b.activity.recentError(proc.Name, now) {
+			activity += errorStyle.Render("!")
+		} else {
+			activity += " "
+		}
+
 		// Highlight if solo'd (only in logs view)
 		name := proc.Name
+		if b.prefs.IsPinned(proc.Name) {
+			name = "*" + name
+		}
 		if b.viewMode == ViewModeLogs && b.soloProcess == proc.Name {
-			name = fmt.Sprintf("[%s]", proc.Name)
+			name = fmt.Sprintf("[%s]", name)
 		}
 
 		// Show number key (only in logs view where 1-9 keys work)
 		if b.viewMode == ViewModeLogs {
 			key := fmt.Sprintf("%d:", i+1)
-			items = append(items, style.Render(key+name))
+			items = append(items, style.Render(key+name)+activity)
 		} else {
-			items = append(items, style.Render(name))
+			items = append(items, style.Render(name)+activity)
 		}
 	}
 
@@ -701,6 +1033,10 @@ func (b *BaseModel) statusBar(extraInfo string) string {
 		viewIndicator = "[Requests]"
 	case ViewModeRequestDetail:
 		viewIndicator = "[Request Detail]"
+	case ViewModeRules:
+		viewIndicator = "[Rules]"
+	case ViewModeProcesses:
+		viewIndicator = "[Processes]"
 	}
 
 	// Left side: mode/filter info
@@ -711,11 +1047,15 @@ func (b *BaseModel) statusBar(extraInfo string) string {
 		left = "Search: " + b.textInput.View()
 	case ModeStringFilter:
 		left = "String filter: " + b.textInput.View()
+	case ModePalette:
+		left = b.paletteStatus()
 	default:
 		if b.soloProcess != "" {
 			left = fmt.Sprintf("Showing: %s (ESC to clear)", b.soloProcess)
 		} else if b.searchPattern != "" {
 			left = fmt.Sprintf("Filter: %s (ESC to clear)", b.searchPattern)
+		} else if b.stderrOnly {
+			left = "Showing: stderr (e or ESC to clear)"
 		} else {
 			left = "Tab: switch view | ? for help"
 			if extraInfo != "" {
@@ -727,11 +1067,28 @@ func (b *BaseModel) statusBar(extraInfo string) string {
 	// Right side: follow mode and count
 	var visible, total int
 	var label string
-	if b.viewMode == ViewModeRequests {
+	switch b.viewMode {
+	case ViewModeRequests:
 		visible = len(b.filteredProxyRequests())
 		total = len(b.proxyRequests)
 		label = "requests"
-	} else {
+	case ViewModeRules:
+		for _, rule := range b.rules {
+			if rule.Enabled {
+				visible++
+			}
+		}
+		total = len(b.rules)
+		label = "rules on"
+	case ViewModeProcesses:
+		for _, proc := range b.processes {
+			if proc.State == domain.ProcessStateRunning {
+				visible++
+			}
+		}
+		total = len(b.processes)
+		label = "running"
+	default:
 		visible = len(b.filteredEntries())
 		total = len(b.logEntries)
 		label = "lines"
@@ -740,7 +1097,16 @@ func (b *BaseModel) statusBar(extraInfo string) string {
 	if !b.followMode {
 		followIndicator = "[PAUSED]"
 	}
+	if b.scrubbing && b.viewMode == ViewModeRequests {
+		followIndicator, visible, total = b.scrubStatus()
+	}
 	right = fmt.Sprintf("%s %s %d/%d %s", viewIndicator, followIndicator, visible, total, label)
+	if b.recordingPaused && b.viewMode == ViewModeRequests {
+		right = "[NOT RECORDING] " + right
+	}
+	if b.redactor != nil {
+		right = "[REDACT] " + right
+	}
 
 	// Calculate widths
 	leftWidth := b.width - len(right) - 4
@@ -774,8 +1140,13 @@ func (b *BaseModel) mainView(extraStatusInfo string) string {
 
 // helpView renders the help overlay based on current view mode
 func (b *BaseModel) helpView() string {
-	if b.viewMode == ViewModeRequests {
+	switch b.viewMode {
+	case ViewModeRequests:
 		return b.requestsHelpView()
+	case ViewModeRules:
+		return b.rulesHelpView()
+	case ViewModeProcesses:
+		return b.processesHelpView()
 	}
 	return b.logsHelpView()
 }
@@ -798,6 +1169,8 @@ func (b *BaseModel) logsHelpView() string {
 
 Views:
   Tab        Switch to Requests view
+  p          Switch to Rules view
+  P          Switch to Processes view
 
 Navigation:
   j/↓        Scroll down
@@ -809,6 +1182,9 @@ Navigation:
 
 Filtering:
   1-9        Solo process (toggle)
+  < / >      Move solo'd process left/right in the header
+  *          Pin/unpin solo'd process first in the header
+  e          Show only stderr (toggle)
   f          Filter mode (process selection)
   /          Pattern filter (regex)
   s          String filter (substring)
@@ -816,6 +1192,7 @@ Filtering:
 
 Other:
   r          Restart selected process (1-9 to select)
+  :          Command palette (restart, filter, view, open, clear)
   ?          Toggle help
   q/Ctrl+C   %s
 
@@ -843,6 +1220,8 @@ func (b *BaseModel) requestsHelpView() string {
 
 Views:
   Tab        Switch to Logs view
+  p          Switch to Rules view
+  P          Switch to Processes view
 
 Navigation:
   j/↓        Scroll down
@@ -856,11 +1235,100 @@ Request Details:
   Enter      View details for selected request
   ESC        Return to request list (or clear filters)
 
+Time Travel:
+  t          Freeze view at the latest request (toggle)
+  h/←        Step back to the previous request
+  l/→        Step forward to the next request
+  ESC        Return to live requests
+
 Filtering:
   s          String filter (URL/method/subdomain)
   ESC        Clear filters
 
 Other:
+  :          Command palette (restart, filter, view, open, clear)
+  ?          Toggle help
+  q/Ctrl+C   %s
+
+Press any key to close help...
+`, title, quitMsg)
+
+	return helpStyle.Render(help)
+}
+
+// rulesHelpView renders the help overlay for rules view
+func (b *BaseModel) rulesHelpView() string {
+	title := "Prox - Process Manager"
+	if b.helpConfig.TitleSuffix != "" {
+		title += " " + b.helpConfig.TitleSuffix
+	}
+	title += " [Rules View]"
+
+	quitMsg := "Quit"
+	if b.helpConfig.QuitMessage != "" {
+		quitMsg = b.helpConfig.QuitMessage
+	}
+
+	help := fmt.Sprintf(`
+%s
+
+Views:
+  Tab/p/ESC  Switch to Logs view
+  P          Switch to Processes view
+
+Rules:
+  j/↓        Select next rule
+  k/↑        Select previous rule
+  Space      Toggle selected rule
+  Enter      Toggle selected rule
+
+Rule kinds:
+  rewrite    Apply the service's configured rewrite (on by default)
+
+Other:
+  :          Command palette (restart, filter, view, open, clear)
+  ?          Toggle help
+  q/Ctrl+C   %s
+
+Press any key to close help...
+`, title, quitMsg)
+
+	return helpStyle.Render(help)
+}
+
+// processesHelpView renders the help overlay for processes view
+func (b *BaseModel) processesHelpView() string {
+	title := "Prox - Process Manager"
+	if b.helpConfig.TitleSuffix != "" {
+		title += " " + b.helpConfig.TitleSuffix
+	}
+	title += " [Processes View]"
+
+	quitMsg := "Quit"
+	if b.helpConfig.QuitMessage != "" {
+		quitMsg = b.helpConfig.QuitMessage
+	}
+
+	help := fmt.Sprintf(`
+%s
+
+Views:
+  Tab/P/ESC  Switch to Logs view
+  p          Switch to Rules view
+
+Processes:
+  j/↓        Select next process
+  k/↑        Select previous process
+  s          Start selected process
+  x          Stop selected process
+  r          Restart selected process
+  Enter      Show selected process's logs
+
+The selected process's command, health check, and environment are shown
+below the list.
+
+Other:
+  :          Command palette (restart, filter, view, open, clear)
   ?          Toggle help
   q/Ctrl+C   %s
 
@@ -891,14 +1359,16 @@ func truncateError(err error, maxLen int) string {
 // This is shared between Model (local mode) and ClientModel (API mode).
 func convertRequestRecordToDetail(req proxy.RequestRecord) *RequestDetailData {
 	detail := &RequestDetailData{
-		ID:         req.ID,
-		Timestamp:  req.Timestamp.Format("2006-01-02 15:04:05.000"),
-		Method:     req.Method,
-		URL:        req.URL,
-		Subdomain:  req.Subdomain,
-		StatusCode: req.StatusCode,
-		DurationMs: req.Duration.Milliseconds(),
-		RemoteAddr: req.RemoteAddr,
+		ID:               req.ID,
+		Timestamp:        req.Timestamp.Format("2006-01-02 15:04:05.000"),
+		Method:           req.Method,
+		URL:              req.URL,
+		Subdomain:        req.Subdomain,
+		StatusCode:       req.StatusCode,
+		DurationMs:       req.Duration.Milliseconds(),
+		RemoteAddr:       req.RemoteAddr,
+		SchemaViolations: req.SchemaViolations,
+		WebSocket:        req.WebSocket,
 	}
 
 	if req.Details != nil {
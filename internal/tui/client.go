@@ -1,8 +1,11 @@
 package tui
 
 import (
+	"time"
+
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/charliek/prox/internal/api"
 	"github.com/charliek/prox/internal/domain"
 	"github.com/charliek/prox/internal/proxy"
 )
@@ -63,6 +66,127 @@ func (m ClientModel) fetchProcesses() tea.Cmd {
 	}
 }
 
+// fetchRules returns a command to fetch proxy rules from the API.
+// Errors are ignored since the proxy may not be enabled.
+func (m ClientModel) fetchRules() tea.Cmd {
+	return func() tea.Msg {
+		resp, err := m.client.GetProxyRules()
+		if err != nil {
+			return RulesMsg(nil)
+		}
+		return RulesMsg(convertRules(resp.Rules))
+	}
+}
+
+// fetchRecording returns a command to fetch whether the proxy is recording
+// requests. Errors are ignored since the proxy may not be enabled.
+func (m ClientModel) fetchRecording() tea.Cmd {
+	return func() tea.Msg {
+		resp, err := m.client.GetProxyRecording()
+		if err != nil {
+			return RecordingMsg(true)
+		}
+		return RecordingMsg(resp.Enabled)
+	}
+}
+
+// restartProcess returns a command that restarts a process via the API
+func (m ClientModel) restartProcess(name string) tea.Cmd {
+	return m.processAction(name, actionRestart)
+}
+
+// processAction returns a command that starts, stops, or restarts a process
+// via the API
+func (m ClientModel) processAction(name, action string) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		switch action {
+		case actionStart:
+			err = m.client.StartProcess(name)
+		case actionStop:
+			err = m.client.StopProcess(name)
+		default:
+			err = m.client.RestartProcess(name)
+		}
+		return ProcessActionMsg{Process: name, Action: action, Err: err}
+	}
+}
+
+// fetchProcessDetail returns a command to fetch the details of the process
+// selected in the processes view, or nil outside it. Errors are ignored; the
+// view shows what the process list has.
+func (m ClientModel) fetchProcessDetail() tea.Cmd {
+	name, ok := m.selectedProcessName()
+	if !ok {
+		return nil
+	}
+	return func() tea.Msg {
+		resp, err := m.client.GetProcess(name)
+		if err != nil {
+			return nil
+		}
+		return ProcessDetailMsg(convertProcessDetail(resp))
+	}
+}
+
+// convertProcessDetail converts an API process detail response to process info
+func convertProcessDetail(resp *api.ProcessDetailResponse) domain.ProcessInfo {
+	info := domain.ProcessInfo{
+		Name:           resp.Name,
+		State:          domain.ProcessState(resp.Status),
+		PID:            resp.PID,
+		Port:           resp.Port,
+		RestartCount:   resp.Restarts,
+		RestartReason:  domain.RestartReason(resp.RestartReason),
+		Health:         domain.HealthStatus(resp.Health),
+		Cmd:            resp.Cmd,
+		Env:            resp.Env,
+		Lazy:           resp.Lazy,
+		LastError:      resp.LastError,
+		PendingRestart: resp.PendingRestart,
+	}
+	if hc := resp.Healthcheck; hc != nil {
+		info.HealthDetails = &domain.HealthState{
+			Enabled:             hc.Enabled,
+			Status:              info.Health,
+			LastOutput:          hc.LastOutput,
+			ConsecutiveFailures: hc.ConsecutiveFailures,
+		}
+		info.HealthDetails.LastCheck, _ = time.Parse(time.RFC3339, hc.LastCheck)
+	}
+	return info
+}
+
+// toggleSelectedRule returns a command that flips the selected proxy rule via the API
+func (m ClientModel) toggleSelectedRule() tea.Cmd {
+	rule, ok := m.selectedRuleItem()
+	if !ok {
+		return nil
+	}
+	return func() tea.Msg {
+		resp, err := m.client.SetProxyRuleEnabled(rule.ID, !rule.Enabled)
+		if err != nil {
+			return RuleToggleResultMsg{ID: rule.ID, Enabled: rule.Enabled, Err: err}
+		}
+		return RuleToggleResultMsg{ID: rule.ID, Enabled: resp.Enabled}
+	}
+}
+
+// convertRules converts API rule responses to proxy rules
+func convertRules(rules []api.ProxyRuleResponse) []proxy.Rule {
+	result := make([]proxy.Rule, len(rules))
+	for i, r := range rules {
+		result[i] = proxy.Rule{
+			ID:          r.ID,
+			Kind:        proxy.RuleKind(r.Kind),
+			Service:     r.Service,
+			Description: r.Description,
+			Enabled:     r.Enabled,
+		}
+	}
+	return result
+}
+
 // ClientErrorMsg is sent when an API error occurs
 type ClientErrorMsg struct {
 	Err error
@@ -82,13 +206,22 @@ func (m ClientModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.updateViewport()
 
 	case LogEntryMsg:
-		m.handleLogEntry(domain.LogEntry(msg))
+		cmds = append(cmds, m.handleLogEntries([]domain.LogEntry{domain.LogEntry(msg)}))
+
+	case LogBatchMsg:
+		cmds = append(cmds, m.handleLogEntries(msg))
 
 	case ProxyRequestMsg:
-		m.handleProxyRequest(proxy.RequestRecord(msg))
+		cmds = append(cmds, m.handleProxyRequests([]proxy.RequestRecord{proxy.RequestRecord(msg)}))
+
+	case ProxyRequestBatchMsg:
+		cmds = append(cmds, m.handleProxyRequests(msg))
+
+	case frameMsg:
+		m.handleFrame()
 
 	case ProcessesMsg:
-		m.processes = []domain.ProcessInfo(msg)
+		m.setProcesses([]domain.ProcessInfo(msg))
 		m.connectionError = nil // Clear error on successful fetch
 		// Update filter map with any new processes
 		for _, p := range m.processes {
@@ -103,14 +236,32 @@ func (m ClientModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// to avoid masking daemon failures.
 		m.connectionError = msg.Err
 
-	case RestartResultMsg:
-		m.lastRestartProcess = msg.Process
-		m.lastRestartError = msg.Err
-		cmds = append(cmds, restartResultClearCmd())
+	case ProcessActionMsg:
+		m.lastAction = &msg
+		cmds = append(cmds, m.fetchProcesses(), m.fetchProcessDetail(), processActionClearCmd())
+
+	case ProcessActionClearMsg:
+		m.lastAction = nil
+
+	case ProcessDetailMsg:
+		detail := domain.ProcessInfo(msg)
+		m.processDetail = &detail
+		if m.viewMode == ViewModeProcesses {
+			m.updateViewport()
+		}
+
+	case RulesMsg:
+		m.handleRules([]proxy.Rule(msg))
+
+	case RecordingMsg:
+		m.recordingPaused = !bool(msg)
 
-	case RestartResultClearMsg:
-		m.lastRestartProcess = ""
-		m.lastRestartError = nil
+	case RuleToggleResultMsg:
+		m.lastRuleToggle = &msg
+		cmds = append(cmds, m.fetchRules(), ruleToggleClearCmd())
+
+	case RuleToggleClearMsg:
+		m.lastRuleToggle = nil
 
 	case RequestDetailMsg:
 		m.detailLoading = false
@@ -128,8 +279,17 @@ func (m ClientModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case TickMsg:
-		// Refresh processes periodically
+		// Refresh processes periodically, and rules and the recording state
+		// while they are shown
 		cmds = append(cmds, m.fetchProcesses())
+		switch m.viewMode {
+		case ViewModeRules:
+			cmds = append(cmds, m.fetchRules())
+		case ViewModeRequests:
+			cmds = append(cmds, m.fetchRecording())
+		case ViewModeProcesses:
+			cmds = append(cmds, m.fetchProcessDetail())
+		}
 		cmds = append(cmds, tickCmd())
 	}
 
@@ -137,8 +297,8 @@ func (m ClientModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.viewport, cmd = m.viewport.Update(msg)
 	cmds = append(cmds, cmd)
 
-	// Handle text input if in filter/search mode
-	if m.mode == ModeFilter || m.mode == ModeSearch || m.mode == ModeStringFilter {
+	// Handle text input if in filter/search/palette mode
+	if m.mode == ModeFilter || m.mode == ModeSearch || m.mode == ModeStringFilter || m.mode == ModePalette {
 		m.textInput, cmd = m.textInput.Update(msg)
 		cmds = append(cmds, cmd)
 	}
@@ -162,6 +322,17 @@ func (m ClientModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case ModeHelp:
 		m.handleHelpKey(msg)
 		return m, nil
+	case ModePalette:
+		restart, cmd := m.handlePaletteKey(msg)
+		if restart != "" {
+			return m, m.restartProcess(restart)
+		}
+		return m, cmd
+	}
+
+	// Start, stop, and restart the process selected in the processes view
+	if name, action, ok := m.selectedProcessAction(msg.String()); ok {
+		return m, m.processAction(name, action)
 	}
 
 	// Normal mode keys
@@ -172,15 +343,23 @@ func (m ClientModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "r":
 		// Restart the solo'd process via API
 		if m.soloProcess != "" {
-			processName := m.soloProcess
-			return m, func() tea.Msg {
-				err := m.client.RestartProcess(processName)
-				return RestartResultMsg{Process: processName, Err: err}
-			}
+			return m, m.restartProcess(m.soloProcess)
 		}
 		return m, nil
 
+	case " ":
+		return m, m.toggleSelectedRule()
+
 	case "enter":
+		// In rules view, toggle the selected rule
+		if m.viewMode == ViewModeRules {
+			return m, m.toggleSelectedRule()
+		}
+		// In processes view, show the selected process's logs
+		if m.viewMode == ViewModeProcesses {
+			m.showSelectedProcessLogs()
+			return m, nil
+		}
 		// In requests view, show detail for selected request
 		if m.viewMode == ViewModeRequests {
 			requestID := m.getSelectedRequest()
@@ -197,9 +376,10 @@ func (m ClientModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	// Handle common navigation keys
+	// Handle common navigation keys. The processes view's selection may have
+	// changed, so load its details.
 	if m.handleNavigationKey(msg) {
-		return m, nil
+		return m, m.fetchProcessDetail()
 	}
 
 	return m, nil
@@ -215,14 +395,16 @@ func (m ClientModel) fetchRequestDetail(id string) tea.Cmd {
 
 		// Convert API response to RequestDetailData
 		detail := &RequestDetailData{
-			ID:         resp.ID,
-			Timestamp:  resp.Timestamp,
-			Method:     resp.Method,
-			URL:        resp.URL,
-			Subdomain:  resp.Subdomain,
-			StatusCode: resp.StatusCode,
-			DurationMs: resp.DurationMs,
-			RemoteAddr: resp.RemoteAddr,
+			ID:               resp.ID,
+			Timestamp:        resp.Timestamp,
+			Method:           resp.Method,
+			URL:              resp.URL,
+			Subdomain:        resp.Subdomain,
+			StatusCode:       resp.StatusCode,
+			DurationMs:       resp.DurationMs,
+			RemoteAddr:       resp.RemoteAddr,
+			SchemaViolations: resp.SchemaViolations,
+			WebSocket:        webSocketStats(resp.WebSocket),
 		}
 
 		if resp.Details != nil {
@@ -267,12 +449,8 @@ func (m ClientModel) View() string {
 		statusInfo := "Connected via API"
 		if m.connectionError != nil {
 			statusInfo = "Connection error (retrying...)"
-		} else if m.lastRestartProcess != "" {
-			if m.lastRestartError != nil {
-				statusInfo = "Restart failed: " + truncateError(m.lastRestartError, maxErrorDisplayLen)
-			} else {
-				statusInfo = "Restarted: " + m.lastRestartProcess
-			}
+		} else if info := m.statusInfo(); info != "" {
+			statusInfo = info
 		}
 		return m.mainView(statusInfo)
 	}
@@ -20,6 +20,7 @@ const (
 	ModeSearch
 	ModeStringFilter
 	ModeHelp
+	ModePalette
 )
 
 // ViewMode represents which content is being displayed
@@ -29,6 +30,8 @@ const (
 	ViewModeLogs ViewMode = iota
 	ViewModeRequests
 	ViewModeRequestDetail
+	ViewModeRules
+	ViewModeProcesses
 )
 
 // Model is the bubbletea model for the TUI
@@ -36,8 +39,9 @@ type Model struct {
 	BaseModel
 
 	// Dependencies
-	supervisor *supervisor.Supervisor
-	logManager *logs.Manager
+	supervisor   *supervisor.Supervisor
+	logManager   *logs.Manager
+	proxyService *proxy.Service // nil when the proxy is not enabled
 
 	// Subscription ID for log tracking
 	subID string
@@ -54,7 +58,7 @@ func NewModel(sup *supervisor.Supervisor, logMgr *logs.Manager) Model {
 	for _, p := range sup.Processes() {
 		base.filterProcesses[p.Name] = true
 	}
-	base.processes = sup.Processes()
+	base.setProcesses(sup.Processes())
 
 	return Model{
 		BaseModel:  base,
@@ -84,14 +88,36 @@ type ProcessesMsg []domain.ProcessInfo
 // TickMsg is sent periodically
 type TickMsg time.Time
 
-// RestartResultMsg is sent when a restart operation completes
-type RestartResultMsg struct {
+// ProcessActionMsg is sent when starting, stopping, or restarting a process
+// completes
+type ProcessActionMsg struct {
 	Process string
+	Action  string // actionStart, actionStop, or actionRestart
 	Err     error
 }
 
-// RestartResultClearMsg is sent to clear the restart result after a delay
-type RestartResultClearMsg struct{}
+// ProcessActionClearMsg is sent to clear the action result after a delay
+type ProcessActionClearMsg struct{}
+
+// ProcessDetailMsg is sent when the details of the process selected in the
+// processes view are loaded in client mode
+type ProcessDetailMsg domain.ProcessInfo
+
+// RecordingMsg is sent with whether the proxy is recording requests
+type RecordingMsg bool
+
+// RulesMsg is sent when the proxy rules should be refreshed
+type RulesMsg []proxy.Rule
+
+// RuleToggleResultMsg is sent when enabling or disabling a proxy rule completes
+type RuleToggleResultMsg struct {
+	ID      string
+	Enabled bool
+	Err     error
+}
+
+// RuleToggleClearMsg is sent to clear the rule toggle result after a delay
+type RuleToggleClearMsg struct{}
 
 // RequestDetailMsg is sent when request details are loaded
 type RequestDetailMsg struct {
@@ -107,18 +133,20 @@ type RequestDetailErrorMsg struct {
 
 // RequestDetailData holds the detailed information about a request for TUI display
 type RequestDetailData struct {
-	ID              string
-	Timestamp       string
-	Method          string
-	URL             string
-	Subdomain       string
-	StatusCode      int
-	DurationMs      int64
-	RemoteAddr      string
-	RequestHeaders  map[string][]string
-	ResponseHeaders map[string][]string
-	RequestBody     *BodyData
-	ResponseBody    *BodyData
+	ID               string
+	Timestamp        string
+	Method           string
+	URL              string
+	Subdomain        string
+	StatusCode       int
+	DurationMs       int64
+	RemoteAddr       string
+	RequestHeaders   map[string][]string
+	ResponseHeaders  map[string][]string
+	RequestBody      *BodyData
+	ResponseBody     *BodyData
+	SchemaViolations []string
+	WebSocket        *proxy.WebSocketStats // Set for upgraded WebSocket requests
 }
 
 // BodyData holds captured body information
@@ -130,13 +158,20 @@ type BodyData struct {
 	Data        string
 }
 
-// restartResultClearDelay is how long to show restart result before clearing
-const restartResultClearDelay = 3 * time.Second
+// resultClearDelay is how long to show an action's result before clearing
+const resultClearDelay = 3 * time.Second
+
+// processActionClearCmd returns a command that clears the action result after a delay
+func processActionClearCmd() tea.Cmd {
+	return tea.Tick(resultClearDelay, func(t time.Time) tea.Msg {
+		return ProcessActionClearMsg{}
+	})
+}
 
-// restartResultClearCmd returns a command that clears the restart result after a delay
-func restartResultClearCmd() tea.Cmd {
-	return tea.Tick(restartResultClearDelay, func(t time.Time) tea.Msg {
-		return RestartResultClearMsg{}
+// ruleToggleClearCmd returns a command that clears the rule toggle result after a delay
+func ruleToggleClearCmd() tea.Cmd {
+	return tea.Tick(resultClearDelay, func(t time.Time) tea.Msg {
+		return RuleToggleClearMsg{}
 	})
 }
 
@@ -1,12 +1,18 @@
 package tui
 
 import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/charliek/prox/internal/config"
 	"github.com/charliek/prox/internal/domain"
 	"github.com/charliek/prox/internal/logs"
 	"github.com/charliek/prox/internal/proxy"
@@ -69,12 +75,14 @@ func TestModel_HandleKey_EscClearsFilters(t *testing.T) {
 	model := newTestModel()
 	model.soloProcess = "test"
 	model.searchPattern = "pattern"
+	model.stderrOnly = true
 
 	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyEscape})
 	m := newModel.(Model)
 
 	assert.Empty(t, m.soloProcess)
 	assert.Empty(t, m.searchPattern)
+	assert.False(t, m.stderrOnly)
 }
 
 func TestModel_LogEntryMsg(t *testing.T) {
@@ -116,6 +124,55 @@ func TestModel_LogEntryLimit(t *testing.T) {
 	assert.Len(t, model.logEntries, 1000)
 }
 
+func TestModel_LogBatchThrottled(t *testing.T) {
+	model := newTestModel()
+	newModel, _ := model.Update(tea.WindowSizeMsg{Width: 120, Height: 20})
+	model = newModel.(Model)
+
+	batch := func(lines ...string) LogBatchMsg {
+		var entries LogBatchMsg
+		for _, line := range lines {
+			entries = append(entries, domain.LogEntry{Timestamp: time.Now(), Process: "web", Line: line})
+		}
+		return entries
+	}
+
+	// The first batch schedules a frame; the second waits for it
+	newModel, cmd := model.Update(batch("one", "two"))
+	model = newModel.(Model)
+	assert.NotNil(t, cmd)
+	assert.True(t, model.frameScheduled)
+	newModel, _ = model.Update(batch("three"))
+	model = newModel.(Model)
+	assert.Len(t, model.logEntries, 3)
+	assert.NotContains(t, model.viewport.View(), "three", "the viewport waits for the frame")
+
+	// The frame rebuilds the viewport once, formatting each line once
+	newModel, _ = model.Update(frameMsg{})
+	model = newModel.(Model)
+	assert.False(t, model.frameScheduled)
+	assert.Contains(t, model.viewport.View(), "three")
+	assert.Equal(t, 3, model.viewport.TotalLineCount())
+	assert.Contains(t, model.logLines[0], "one")
+
+	// Reordering the processes changes their colors, so lines are restyled
+	model.setProcesses([]domain.ProcessInfo{{Name: "api"}, {Name: "web"}})
+	assert.Empty(t, model.logLines[0])
+}
+
+func TestDrain(t *testing.T) {
+	ch := make(chan int, 10)
+	for i := 2; i <= 5; i++ {
+		ch <- i
+	}
+
+	assert.Equal(t, []int{1, 2, 3}, drain(1, ch, 3))
+	assert.Equal(t, []int{0, 4, 5}, drain(0, ch, 10), "stops when nothing is waiting")
+
+	close(ch)
+	assert.Equal(t, []int{7}, drain(7, ch, 10))
+}
+
 func TestFilteredEntries(t *testing.T) {
 	model := newTestModel()
 
@@ -147,6 +204,30 @@ func TestFilteredEntries(t *testing.T) {
 	for _, e := range entries {
 		assert.Contains(t, e.Line, "log 1")
 	}
+
+	// String filters ignore case, and hidden processes are left out
+	model.searchPattern = "LOG 1"
+	model.filterProcesses = map[string]bool{"web": true, "api": false}
+	entries = model.filteredEntries()
+	assert.Equal(t, []domain.LogEntry{{Process: "web", Line: "web log 1"}}, entries)
+}
+
+func TestStderrOnlyToggle(t *testing.T) {
+	model := newTestModel()
+	model.logEntries = []domain.LogEntry{
+		{Process: "web", Stream: domain.StreamStdout, Line: "listening"},
+		{Process: "web", Stream: domain.StreamStderr, Line: "panic"},
+	}
+
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	m := newModel.(Model)
+	assert.True(t, m.stderrOnly)
+	assert.Equal(t, []domain.LogEntry{model.logEntries[1]}, m.filteredEntries())
+
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	m = newModel.(Model)
+	assert.False(t, m.stderrOnly)
+	assert.Len(t, m.filteredEntries(), 2)
 }
 
 func TestContainsIgnoreCase(t *testing.T) {
@@ -420,27 +501,27 @@ func TestFormatProxyRequest_StatusCode0(t *testing.T) {
 	// "GET" should be followed by 4 spaces to make 7 chars total
 	assert.Contains(t, formatted, "GET    ") // 7 chars total
 
-	// Verify duration is 5 chars right-aligned (100ms = "  100")
-	assert.Contains(t, formatted, "  100")
+	// Verify duration is 6 chars right-aligned (100ms = " 100ms")
+	assert.Contains(t, formatted, " 100ms")
 }
 
-func TestFormatProxyRequest_DurationOverflow(t *testing.T) {
+func TestFormatProxyRequest_LongDuration(t *testing.T) {
 	model := newTestModel()
 
-	// Duration exceeding 9999ms should show "9999+"
+	// Durations of a second or more switch to larger units
 	req := proxy.RequestRecord{
 		Timestamp:  time.Now(),
 		Subdomain:  "api",
 		Method:     "POST",
 		URL:        "/slow-endpoint",
 		StatusCode: 200,
-		Duration:   15 * time.Second, // 15000ms > 9999ms
+		Duration:   15 * time.Second,
 	}
 
 	formatted := model.formatProxyRequest(req)
 
-	// Should contain "9999+" for overflow duration (5 chars total)
-	assert.Contains(t, formatted, "9999+")
+	// Should contain "15s" right-aligned in 6 chars
+	assert.Contains(t, formatted, "   15s")
 	assert.Contains(t, formatted, "api")
 	assert.Contains(t, formatted, "POST")
 
@@ -467,7 +548,7 @@ func TestFormatProxyRequest_Padding(t *testing.T) {
 		wantSub    string // Expected subdomain with padding (10 chars)
 		wantMethod string // Expected method with padding (7 chars)
 		wantStatus string // Expected status with padding (3 chars)
-		wantDur    string // Expected duration with padding (5 chars)
+		wantDur    string // Expected duration with padding (6 chars)
 	}{
 		{
 			name:       "short fields",
@@ -478,7 +559,7 @@ func TestFormatProxyRequest_Padding(t *testing.T) {
 			wantSub:    "a         ", // 1 + 9 spaces
 			wantMethod: "GET    ",    // 3 + 4 spaces
 			wantStatus: "200",        // already 3 chars
-			wantDur:    "    1",      // 4 spaces + 1
+			wantDur:    "   1ms",     // 3 spaces + 1ms
 		},
 		{
 			name:       "max length subdomain",
@@ -489,7 +570,7 @@ func TestFormatProxyRequest_Padding(t *testing.T) {
 			wantSub:    "webservice", // exactly 10 chars
 			wantMethod: "DELETE ",    // 6 + 1 space
 			wantStatus: "404",
-			wantDur:    " 9999", // 1 space + 4 digits
+			wantDur:    "   10s", // 9999ms rounds to 10s
 		},
 		{
 			name:       "single digit status",
@@ -500,7 +581,7 @@ func TestFormatProxyRequest_Padding(t *testing.T) {
 			wantSub:    "api       ",
 			wantMethod: "OPTIONS", // exactly 7 chars
 			wantStatus: "  0",     // 2 spaces + 0
-			wantDur:    "   50",   // 3 spaces + 50
+			wantDur:    "  50ms",  // 2 spaces + 50ms
 		},
 	}
 
@@ -523,3 +604,536 @@ func TestFormatProxyRequest_Padding(t *testing.T) {
 		})
 	}
 }
+
+func newTestModelWithProxy(t *testing.T) Model {
+	t.Helper()
+	model := newTestModel()
+	svc, err := proxy.NewService(
+		&config.ProxyConfig{Enabled: true, HTTPPort: 6788, Domain: "local.dev"},
+		map[string]config.ServiceConfig{
+			"api": {Port: 3000, Host: "localhost", Rewrite: &config.RewriteConfig{Host: config.RewriteHostTarget}},
+			"web": {Port: 3001, Host: "localhost", Rewrite: &config.RewriteConfig{Host: config.RewriteHostTarget}},
+		},
+		nil, slog.New(slog.NewTextHandler(io.Discard, nil)), t.TempDir())
+	if err != nil {
+		t.Fatalf("creating proxy service: %v", err)
+	}
+	model.proxyService = svc
+	return model
+}
+
+func TestRequestsView_RecordingPaused(t *testing.T) {
+	model := newTestModelWithProxy(t)
+	model.width = 120
+	model.viewMode = ViewModeRequests
+	model.proxyService.SetRecording(false)
+
+	// The recording state is picked up on the next tick
+	newModel, _ := model.Update(TickMsg(time.Now()))
+	m := newModel.(Model)
+	assert.True(t, m.recordingPaused)
+	assert.Contains(t, m.statusBar(""), "[NOT RECORDING]")
+
+	// Only the requests view shows it
+	m.viewMode = ViewModeLogs
+	assert.NotContains(t, m.statusBar(""), "[NOT RECORDING]")
+
+	model.proxyService.SetRecording(true)
+	newModel, _ = m.Update(TickMsg(time.Now()))
+	assert.False(t, newModel.(Model).recordingPaused)
+}
+
+func TestRulesViewSwitch(t *testing.T) {
+	model := newTestModel()
+
+	// p opens the Rules view
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	m := newModel.(Model)
+	assert.Equal(t, ViewModeRules, m.viewMode)
+
+	// p again returns to Logs view
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	m = newModel.(Model)
+	assert.Equal(t, ViewModeLogs, m.viewMode)
+
+	// Tab and ESC leave the Rules view
+	m.viewMode = ViewModeRules
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	assert.Equal(t, ViewModeLogs, newModel.(Model).viewMode)
+
+	m.viewMode = ViewModeRules
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ViewModeLogs, newModel.(Model).viewMode)
+}
+
+func TestRulesView_Selection(t *testing.T) {
+	model := newTestModelWithProxy(t)
+	model.viewMode = ViewModeRules
+	model.handleRules(model.proxyService.Rules())
+	assert.Equal(t, 0, model.selectedRule)
+
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	m := newModel.(Model)
+	assert.Equal(t, 1, m.selectedRule)
+
+	// Selection stops at the last rule
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	m = newModel.(Model)
+	assert.Equal(t, 1, m.selectedRule)
+
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'k'}})
+	m = newModel.(Model)
+	assert.Equal(t, 0, m.selectedRule)
+
+	// Shrinking the rule list keeps the selection in range
+	m.selectedRule = 1
+	m.handleRules(m.rules[:1])
+	assert.Equal(t, 0, m.selectedRule)
+}
+
+func TestRulesView_Toggle(t *testing.T) {
+	model := newTestModelWithProxy(t)
+	model.viewMode = ViewModeRules
+	model.handleRules(model.proxyService.Rules())
+	model.selectedRule = 1
+
+	newModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	if !assert.NotNil(t, cmd) {
+		return
+	}
+	msg := cmd()
+	result, ok := msg.(RuleToggleResultMsg)
+	assert.True(t, ok)
+	assert.Equal(t, "rewrite:web", result.ID)
+	assert.False(t, result.Enabled)
+	assert.NoError(t, result.Err)
+
+	newModel, _ = newModel.(Model).Update(msg)
+	m := newModel.(Model)
+	assert.False(t, m.rules[1].Enabled)
+	assert.True(t, m.rules[0].Enabled)
+	assert.Equal(t, "Rule disabled: rewrite:web", m.statusInfo())
+}
+
+func TestRulesView_ToggleOutsideRulesView(t *testing.T) {
+	model := newTestModelWithProxy(t)
+	model.handleRules(model.proxyService.Rules())
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	assert.Nil(t, cmd)
+	assert.True(t, model.proxyService.Rules()[0].Enabled)
+}
+
+func TestFormatWithRedactor(t *testing.T) {
+	model := newTestModel()
+	entry := domain.LogEntry{
+		Timestamp: time.Now(),
+		Process:   "web",
+		Stream:    domain.StreamStdout,
+		Line:      "login ok for alice@example.com",
+	}
+	req := proxy.RequestRecord{
+		Timestamp:  time.Now(),
+		Subdomain:  "api",
+		Method:     "GET",
+		URL:        "/users?email=alice@example.com",
+		StatusCode: 200,
+	}
+
+	assert.Contains(t, model.formatLogEntry(entry), "alice@example.com")
+
+	model.redactor, _ = logs.NewRedactor(nil)
+	assert.NotContains(t, model.formatLogEntry(entry), "alice@example.com")
+	assert.Contains(t, model.formatLogEntry(entry), logs.RedactEmailMask)
+	assert.Contains(t, model.formatProxyRequest(req), "/users?email="+logs.RedactEmailMask)
+}
+
+func TestFormatSchemaViolations(t *testing.T) {
+	model := newTestModel()
+	req := proxy.RequestRecord{
+		Timestamp:  time.Now(),
+		Subdomain:  "api",
+		Method:     "GET",
+		URL:        "/users/1",
+		StatusCode: 200,
+	}
+	assert.NotContains(t, model.formatProxyRequest(req), "[schema]")
+
+	req.SchemaViolations = []string{`$: missing required property "name"`}
+	assert.Contains(t, model.formatProxyRequest(req), "[schema]")
+
+	model.requestDetail = convertRequestRecordToDetail(req)
+	detail := strings.Join(model.formatRequestDetail(), "\n")
+	assert.Contains(t, detail, "Schema Violations")
+	assert.Contains(t, detail, `$: missing required property "name"`)
+}
+
+func TestFormatRequestDetail_HeadersAndBodies(t *testing.T) {
+	model := newTestModel()
+	req := proxy.RequestRecord{
+		ID:         "abc1234",
+		Timestamp:  time.Now(),
+		Method:     "POST",
+		URL:        "/users",
+		StatusCode: 201,
+	}
+
+	model.requestDetail = convertRequestRecordToDetail(req)
+	assert.Contains(t, strings.Join(model.formatRequestDetail(), "\n"), "weren't captured")
+
+	req.Details = &proxy.RequestDetails{
+		RequestHeaders:  map[string][]string{"X-Zeta": {"z"}, "Accept": {"*/*"}},
+		ResponseHeaders: map[string][]string{"Content-Type": {"application/json"}},
+		RequestBody:     &proxy.CapturedBody{Size: 9, ContentType: "text/plain", Data: []byte(`{"a": 1`)},
+		ResponseBody:    &proxy.CapturedBody{Size: 16, ContentType: "application/json", Data: []byte(`{"id":1,"tags":[]}`)},
+	}
+	model.requestDetail = convertRequestRecordToDetail(req)
+	detail := strings.Join(model.formatRequestDetail(), "\n")
+
+	assert.NotContains(t, detail, "weren't captured")
+	assert.Less(t, strings.Index(detail, "Accept"), strings.Index(detail, "X-Zeta"))
+	// Invalid JSON is shown as is, valid JSON is indented
+	assert.Contains(t, detail, `  {"a": 1`)
+	assert.Contains(t, detail, "  {\n    \"id\": 1,\n    \"tags\": []\n  }")
+}
+
+func TestHandleProxyRequest_ReplacesUpdatedWebSocket(t *testing.T) {
+	model := newTestModel()
+	model.viewMode = ViewModeRequests
+
+	open := proxy.RequestRecord{
+		ID:         "ws00001",
+		Method:     "GET",
+		URL:        "/socket",
+		StatusCode: 101,
+		Type:       proxy.RequestTypeWebSocket,
+		WebSocket:  &proxy.WebSocketStats{Open: true},
+	}
+	model.handleProxyRequests([]proxy.RequestRecord{open})
+	assert.Contains(t, model.formatProxyRequest(model.proxyRequests[0]), "[ws open]")
+
+	closed := open
+	closed.WebSocket = &proxy.WebSocketStats{BytesIn: 10, BytesOut: 20}
+	model.handleProxyRequests([]proxy.RequestRecord{closed})
+	assert.Len(t, model.proxyRequests, 1)
+	assert.False(t, model.proxyRequests[0].WebSocket.Open)
+	line := model.formatProxyRequest(model.proxyRequests[0])
+	assert.Contains(t, line, "[ws]")
+	assert.NotContains(t, line, "open")
+}
+
+func TestRequestsScrubber(t *testing.T) {
+	model := newTestModel()
+	model.viewMode = ViewModeRequests
+
+	base := time.Now()
+	// Recorded out of order; the scrubber steps in time order
+	for _, r := range []struct {
+		id     string
+		offset time.Duration
+	}{{"req0001", 0}, {"req0003", 2 * time.Second}, {"req0002", time.Second}} {
+		model.handleProxyRequests([]proxy.RequestRecord{{ID: r.id, Timestamp: base.Add(r.offset), Method: "GET", URL: "/" + r.id}})
+	}
+
+	key := func(m Model, msg tea.KeyMsg) Model {
+		newModel, _ := m.Update(msg)
+		return newModel.(Model)
+	}
+	runeKey := func(r rune) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}} }
+
+	// t freezes the view at the latest request
+	m := key(model, runeKey('t'))
+	assert.True(t, m.scrubbing)
+	assert.Equal(t, "req0003", m.getSelectedRequest())
+
+	// h steps back in time order and stops at the oldest request
+	m = key(m, runeKey('h'))
+	assert.Equal(t, "req0002", m.scrubID)
+	m = key(m, tea.KeyMsg{Type: tea.KeyLeft})
+	m = key(m, runeKey('h'))
+	assert.Equal(t, "req0001", m.scrubID)
+	indicator, pos, total := m.scrubStatus()
+	assert.Contains(t, indicator, "[AT ")
+	assert.Equal(t, 1, pos)
+	assert.Equal(t, 3, total)
+	assert.Len(t, m.formatScrubRequests(), 1)
+
+	// New requests do not move the cursor
+	m.handleProxyRequests([]proxy.RequestRecord{{ID: "req0004", Timestamp: base.Add(3 * time.Second)}})
+	assert.Equal(t, "req0001", m.scrubID)
+
+	m = key(m, runeKey('l'))
+	assert.Equal(t, "req0002", m.scrubID)
+	assert.Len(t, m.formatScrubRequests(), 2)
+
+	// ESC returns to live requests
+	m = key(m, tea.KeyMsg{Type: tea.KeyEsc})
+	assert.False(t, m.scrubbing)
+	assert.True(t, m.followMode)
+	assert.Equal(t, ViewModeRequests, m.viewMode)
+}
+
+func TestRequestsScrubber_TrimmedCursor(t *testing.T) {
+	model := newTestModel()
+	base := time.Now()
+	model.proxyRequests = []proxy.RequestRecord{
+		{ID: "a", Timestamp: base},
+		{ID: "c", Timestamp: base.Add(2 * time.Second)},
+	}
+
+	// A cursor whose request is gone falls back to the latest one before it
+	model.scrubID = "b"
+	model.scrubAt = base.Add(time.Second)
+	assert.Equal(t, 0, model.scrubIndex(model.timeOrderedRequests()))
+}
+
+// processNames returns the names of the processes in header order
+func processNames(processes []domain.ProcessInfo) []string {
+	names := make([]string, len(processes))
+	for i, p := range processes {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func TestPreferences_Arrange(t *testing.T) {
+	processes := []domain.ProcessInfo{{Name: "api"}, {Name: "cron"}, {Name: "web"}, {Name: "worker"}}
+
+	var nilPrefs *Preferences
+	assert.Equal(t, processes, nilPrefs.Arrange(processes))
+
+	// Pinned first, then the user's order, then the rest as given
+	prefs := &Preferences{Order: []string{"worker", "cron", "gone"}, Pinned: []string{"web"}}
+	assert.Equal(t, []string{"web", "worker", "cron", "api"}, processNames(prefs.Arrange(processes)))
+	assert.Equal(t, []string{"api", "cron", "web", "worker"}, processNames(processes), "input is not modified")
+}
+
+func TestProcessReorderKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".prox", "tui.json")
+	model := newTestModel()
+	model.prefs = NewPreferences(path)
+	model.setProcesses([]domain.ProcessInfo{{Name: "api"}, {Name: "cron"}, {Name: "web"}})
+
+	press := func(key rune) {
+		newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		model = newModel.(Model)
+	}
+
+	// Without a solo'd process nothing moves
+	press('>')
+	assert.Equal(t, []string{"api", "cron", "web"}, processNames(model.processes))
+
+	// Solo web (key 3) and move it left; it is now on key 2
+	press('3')
+	press('<')
+	assert.Equal(t, []string{"api", "web", "cron"}, processNames(model.processes))
+	press('<')
+	press('<') // already first
+	assert.Equal(t, []string{"web", "api", "cron"}, processNames(model.processes))
+
+	// Pin cron; it moves to the front and others can't move past it
+	press('3')
+	assert.Equal(t, "cron", model.soloProcess)
+	press('*')
+	assert.Equal(t, []string{"cron", "web", "api"}, processNames(model.processes))
+	assert.Contains(t, model.processPanel(), "*cron")
+	press('2')
+	press('<')
+	assert.Equal(t, []string{"cron", "web", "api"}, processNames(model.processes))
+	assert.NoError(t, model.prefsError)
+
+	// The order survives a refresh and a new session
+	model.setProcesses([]domain.ProcessInfo{{Name: "api"}, {Name: "cron"}, {Name: "web"}})
+	assert.Equal(t, []string{"cron", "web", "api"}, processNames(model.processes))
+
+	prefs, err := LoadPreferences(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cron"}, prefs.Pinned)
+	assert.Equal(t, []string{"cron", "web", "api"}, processNames(prefs.Arrange(model.processes)))
+
+	// Unpinning keeps cron's place in the user order
+	press('1')
+	press('*')
+	assert.Equal(t, []string{"web", "api", "cron"}, processNames(model.processes))
+}
+
+func TestActivityTracker(t *testing.T) {
+	tracker := make(activityTracker)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	logAt := func(offset time.Duration, line string) {
+		tracker.record(domain.LogEntry{Timestamp: start.Add(offset), Process: "web", Line: line})
+	}
+
+	assert.Equal(t, "        ", tracker.sparkline("web", start))
+	assert.False(t, tracker.recentError("web", start))
+
+	// Four lines in the first second, one in the third
+	for range 4 {
+		logAt(0, "GET / 200")
+	}
+	logAt(2*time.Second, "GET / 200")
+	assert.Equal(t, "     █ ▂", tracker.sparkline("web", start.Add(2*time.Second)))
+
+	// Older activity scrolls off the left as time passes
+	assert.Equal(t, "   █ ▂  ", tracker.sparkline("web", start.Add(4*time.Second)))
+	assert.Equal(t, "        ", tracker.sparkline("web", start.Add(time.Minute)))
+
+	// Lines that arrive late still land in their own second
+	logAt(59*time.Second, "GET / 200")
+	assert.Equal(t, "      █ ", tracker.sparkline("web", start.Add(time.Minute)))
+
+	logAt(time.Minute, "error: connection refused")
+	assert.True(t, tracker.recentError("web", start.Add(time.Minute+10*time.Second)))
+	assert.False(t, tracker.recentError("web", start.Add(time.Minute+errorBadgeWindow)))
+	assert.False(t, tracker.recentError("api", start.Add(time.Minute)))
+}
+
+func TestProcessPanel_Activity(t *testing.T) {
+	model := newTestModel()
+	model.setProcesses([]domain.ProcessInfo{{Name: "web", State: domain.ProcessStateRunning}})
+
+	model.handleLogEntries([]domain.LogEntry{{Timestamp: time.Now(), Process: "web", Line: "panic: nil map"}})
+	panel := model.processPanel()
+	assert.Contains(t, panel, "█")
+	assert.Contains(t, panel, "!")
+}
+
+func TestCommandPalette(t *testing.T) {
+	model := newTestModelWithProxy(t)
+	model.setProcesses([]domain.ProcessInfo{{Name: "web"}, {Name: "worker"}})
+	model.handleProxyRequests([]proxy.RequestRecord{
+		{ID: "1", Method: "GET", URL: "/", Subdomain: "api"},
+		{ID: "2", Method: "GET", URL: "/", Subdomain: "web"},
+		{ID: "3", Method: "GET", URL: "/users", Subdomain: "api"},
+	})
+
+	key := func(m Model, msg tea.KeyMsg) (Model, tea.Cmd) {
+		newModel, cmd := m.Update(msg)
+		return newModel.(Model), cmd
+	}
+	run := func(m Model, line string) (Model, tea.Cmd) {
+		m, _ = key(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{':'}})
+		m, _ = key(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(line)})
+		return key(m, tea.KeyMsg{Type: tea.KeyEnter})
+	}
+
+	m, _ := key(model, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{':'}})
+	assert.Equal(t, ModePalette, m.mode)
+	m, _ = key(m, tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, ModeNormal, m.mode)
+
+	// Commands match fuzzily, along with process and service names
+	m.textInput.SetValue("rwo")
+	assert.Equal(t, "restart worker", m.paletteSuggestions()[0])
+	m.textInput.SetValue("api")
+	assert.Equal(t, []string{"open api"}, m.paletteSuggestions())
+
+	m, _ = run(m, "vreq")
+	assert.Equal(t, ModeNormal, m.mode)
+	assert.Equal(t, ViewModeRequests, m.viewMode)
+
+	m, _ = run(m, "filter GET /users")
+	assert.Equal(t, "GET /users", m.searchPattern)
+
+	m, _ = run(m, "clear")
+	assert.Empty(t, m.searchPattern)
+
+	m, _ = run(m, "open web")
+	assert.Equal(t, ViewModeRequests, m.viewMode)
+	assert.Equal(t, "web", m.searchPattern)
+
+	// Restarts are run by the model
+	m, cmd := run(m, "restart wor")
+	assert.Equal(t, ModeNormal, m.mode)
+	assert.NotNil(t, cmd)
+
+	// Errors keep the palette open
+	m, cmd = run(m, "restart")
+	assert.Nil(t, cmd)
+	assert.Equal(t, ModePalette, m.mode)
+	assert.Equal(t, "restart needs a process", m.paletteError)
+	assert.Contains(t, m.statusBar(""), "restart needs a process")
+}
+
+func TestFuzzyScore(t *testing.T) {
+	_, ok := fuzzyScore("rsw", "restart web")
+	assert.True(t, ok)
+	_, ok = fuzzyScore("wr", "restart web")
+	assert.False(t, ok)
+
+	// Word starts beat scattered matches
+	atWord, _ := fuzzyScore("rw", "restart web")
+	scattered, _ := fuzzyScore("rw", "crown")
+	assert.Greater(t, atWord, scattered)
+}
+
+func TestProcessesView(t *testing.T) {
+	logMgr := logs.NewManager(logs.DefaultManagerConfig())
+	defer logMgr.Close()
+	cfg := &config.Config{Processes: map[string]config.ProcessConfig{
+		"api": {Cmd: "sleep 30"},
+		"web": {Cmd: "sleep 30", Env: map[string]string{"PORT": "3000", "API_TOKEN": "hunter2"}},
+	}}
+	sup := supervisor.New(cfg, logMgr, nil, supervisor.DefaultSupervisorConfig())
+	_, err := sup.Start(context.Background())
+	assert.NoError(t, err)
+	defer sup.Stop(context.Background())
+	model := NewModel(sup, logMgr)
+	key := func(m Model, r rune) (Model, tea.Cmd) {
+		newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		return newModel.(Model), cmd
+	}
+
+	m, _ := key(model, 'P')
+	assert.Equal(t, ViewModeProcesses, m.viewMode)
+
+	m, _ = key(m, 'j')
+	assert.Equal(t, 1, m.selectedProcess)
+	content := strings.Join(m.formatProcesses(), "\n")
+	assert.Contains(t, content, "> web")
+	assert.Contains(t, content, "Command:  sleep 30")
+	assert.Contains(t, content, "PORT=3000")
+	assert.Contains(t, content, "API_TOKEN=[REDACTED]")
+
+	// x stops and s starts the selected process
+	m, cmd := key(m, 'x')
+	if !assert.NotNil(t, cmd) {
+		return
+	}
+	msg := cmd()
+	assert.Equal(t, ProcessActionMsg{Process: "web", Action: actionStop}, msg)
+	newModel, _ := m.Update(msg)
+	m = newModel.(Model)
+	assert.Equal(t, "Stopped: web", m.statusInfo())
+	info, err := sup.Process("web")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ProcessStateStopped, info.State)
+
+	m, cmd = key(m, 's')
+	newModel, _ = m.Update(cmd())
+	m = newModel.(Model)
+	assert.Equal(t, "Started: web", m.statusInfo())
+	info, _ = sup.Process("web")
+	assert.Equal(t, domain.ProcessStateRunning, info.State)
+
+	// Enter shows the selected process's logs
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(Model)
+	assert.Equal(t, ViewModeLogs, m.viewMode)
+	assert.Equal(t, "web", m.soloProcess)
+}
+
+func TestProcessesView_SelectionInRange(t *testing.T) {
+	model := newTestModel()
+	model.viewMode = ViewModeProcesses
+	model.setProcesses([]domain.ProcessInfo{{Name: "api"}, {Name: "web"}})
+	model.selectedProcess = 1
+
+	model.setProcesses([]domain.ProcessInfo{{Name: "api"}})
+	assert.Equal(t, 0, model.selectedProcess)
+
+	model.setProcesses(nil)
+	_, ok := model.selectedProcessName()
+	assert.False(t, ok)
+	assert.Equal(t, []string{dimStyle.Render("No processes")}, model.formatProcesses())
+}
@@ -0,0 +1,338 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxPaletteSuggestions is the number of suggestions shown in the status bar
+const maxPaletteSuggestions = 5
+
+// paletteArg is the kind of argument a palette command takes
+type paletteArg int
+
+const (
+	paletteArgNone    paletteArg = iota
+	paletteArgProcess            // A process name
+	paletteArgService            // A proxied service name
+	paletteArgView               // logs, requests, or rules
+	paletteArgText               // Free text
+)
+
+// paletteCommand is a command that can be run from the command palette
+type paletteCommand struct {
+	name string
+	arg  paletteArg
+}
+
+// paletteCommands are the commands the palette offers, in suggestion order
+var paletteCommands = []paletteCommand{
+	{name: "restart", arg: paletteArgProcess},
+	{name: "filter", arg: paletteArgText},
+	{name: "view", arg: paletteArgView},
+	{name: "open", arg: paletteArgService},
+	{name: "clear", arg: paletteArgNone},
+}
+
+// paletteViews maps the arguments of the view command to view modes
+var paletteViews = map[string]ViewMode{
+	"logs":     ViewModeLogs,
+	"requests": ViewModeRequests,
+	"rules":    ViewModeRules,
+}
+
+// openPalette enters command palette mode with an empty command line
+func (b *BaseModel) openPalette() {
+	b.mode = ModePalette
+	b.paletteSelected = 0
+	b.paletteError = ""
+	b.textInput.Placeholder = "restart, filter, view, open, clear"
+	b.textInput.SetValue("")
+	b.textInput.Focus()
+}
+
+// closePalette returns to normal mode
+func (b *BaseModel) closePalette() {
+	b.mode = ModeNormal
+	b.paletteError = ""
+	b.textInput.Placeholder = filterPlaceholder
+	b.textInput.Blur()
+}
+
+// handlePaletteKey handles keys in command palette mode. Restarting differs
+// between the local and client models, so a restart command is returned as
+// the process to restart rather than run here.
+func (b *BaseModel) handlePaletteKey(msg tea.KeyMsg) (restart string, cmd tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		b.closePalette()
+		return "", nil
+
+	case "enter":
+		line := b.textInput.Value()
+		if suggestions := b.paletteSuggestions(); b.paletteSelected > 0 || !b.paletteRunnable(line) {
+			if len(suggestions) > 0 {
+				line = suggestions[min(b.paletteSelected, len(suggestions)-1)]
+			}
+		}
+		restart, err := b.runPaletteCommand(line)
+		if err != nil {
+			b.paletteError = err.Error()
+			return "", nil
+		}
+		b.closePalette()
+		return restart, nil
+
+	case "tab":
+		// Complete the selected suggestion, ready for an argument
+		if suggestions := b.paletteSuggestions(); len(suggestions) > 0 {
+			line := suggestions[min(b.paletteSelected, len(suggestions)-1)]
+			if !strings.Contains(line, " ") {
+				line += " "
+			}
+			b.textInput.SetValue(line)
+			b.textInput.CursorEnd()
+			b.paletteSelected = 0
+		}
+		return "", nil
+
+	case "up", "ctrl+p":
+		if b.paletteSelected > 0 {
+			b.paletteSelected--
+		}
+		return "", nil
+
+	case "down", "ctrl+n":
+		if b.paletteSelected < min(len(b.paletteSuggestions()), maxPaletteSuggestions)-1 {
+			b.paletteSelected++
+		}
+		return "", nil
+	}
+
+	b.textInput, cmd = b.textInput.Update(msg)
+	b.paletteSelected = 0
+	b.paletteError = ""
+	return "", cmd
+}
+
+// paletteCandidates returns every complete command line the palette can
+// suggest: each command with each of its possible arguments
+func (b *BaseModel) paletteCandidates() []string {
+	var candidates []string
+	for _, c := range paletteCommands {
+		switch c.arg {
+		case paletteArgProcess:
+			for _, name := range b.paletteProcesses() {
+				candidates = append(candidates, c.name+" "+name)
+			}
+		case paletteArgService:
+			for _, name := range b.paletteServices() {
+				candidates = append(candidates, c.name+" "+name)
+			}
+		case paletteArgView:
+			candidates = append(candidates, c.name+" logs", c.name+" requests", c.name+" rules")
+		default:
+			candidates = append(candidates, c.name)
+		}
+	}
+	return candidates
+}
+
+// paletteProcesses returns the names of the processes in the header
+func (b *BaseModel) paletteProcesses() []string {
+	names := make([]string, len(b.processes))
+	for i, p := range b.processes {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// paletteServices returns the subdomains of the proxied requests in the
+// requests view, sorted, since those are what open filters on
+func (b *BaseModel) paletteServices() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, req := range b.proxyRequests {
+		if req.Subdomain != "" && !seen[req.Subdomain] {
+			seen[req.Subdomain] = true
+			names = append(names, req.Subdomain)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// paletteSuggestions returns the candidates fuzzy matching the command line,
+// best match first
+func (b *BaseModel) paletteSuggestions() []string {
+	query := strings.ToLower(strings.TrimLeft(b.textInput.Value(), " "))
+	if query == "" {
+		var names []string
+		for _, c := range paletteCommands {
+			names = append(names, c.name)
+		}
+		return names
+	}
+
+	type match struct {
+		line  string
+		score int
+	}
+	var matches []match
+	for _, line := range b.paletteCandidates() {
+		if score, ok := fuzzyScore(query, line); ok {
+			matches = append(matches, match{line, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	suggestions := make([]string, len(matches))
+	for i, m := range matches {
+		suggestions[i] = m.line
+	}
+	return suggestions
+}
+
+// fuzzyScore reports whether the characters of query appear in candidate in
+// order, and scores the match: consecutive characters and characters
+// starting a word score higher
+func fuzzyScore(query, candidate string) (int, bool) {
+	candidate = strings.ToLower(candidate)
+	score, last := 0, -1
+	for _, r := range query {
+		i := strings.IndexRune(candidate[last+1:], r)
+		if i < 0 {
+			return 0, false
+		}
+		pos := last + 1 + i
+		score++
+		if pos == last+1 {
+			score += 2
+		}
+		if pos == 0 || candidate[pos-1] == ' ' {
+			score += 3
+		}
+		last = pos
+	}
+	return score, true
+}
+
+// parsePalette splits a command line into a known command and its argument
+func parsePalette(line string) (paletteCommand, string, bool) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	for _, c := range paletteCommands {
+		if c.name == strings.ToLower(name) {
+			return c, strings.TrimSpace(arg), true
+		}
+	}
+	return paletteCommand{}, "", false
+}
+
+// paletteRunnable reports whether a command line runs as typed, rather than
+// standing for the suggestion it matches
+func (b *BaseModel) paletteRunnable(line string) bool {
+	c, arg, ok := parsePalette(line)
+	if !ok {
+		return false
+	}
+	// A bare command runs as typed, so "restart" never picks a process
+	if arg == "" {
+		return true
+	}
+	switch c.arg {
+	case paletteArgProcess:
+		return slices.Contains(b.paletteProcesses(), arg)
+	case paletteArgService:
+		return slices.Contains(b.paletteServices(), arg)
+	case paletteArgView:
+		_, ok := paletteViews[strings.ToLower(arg)]
+		return ok
+	}
+	return true
+}
+
+// runPaletteCommand runs a command line, returning the process to restart
+// for a restart command
+func (b *BaseModel) runPaletteCommand(line string) (string, error) {
+	c, arg, ok := parsePalette(line)
+	if !ok {
+		return "", fmt.Errorf("unknown command: %s", strings.TrimSpace(line))
+	}
+
+	switch c.name {
+	case "restart":
+		// Without a name, restart the solo'd process like the r key
+		if arg == "" {
+			arg = b.soloProcess
+		}
+		if arg == "" {
+			return "", fmt.Errorf("restart needs a process")
+		}
+		if !slices.Contains(b.paletteProcesses(), arg) {
+			return "", fmt.Errorf("unknown process: %s", arg)
+		}
+		return arg, nil
+
+	case "filter":
+		if arg == "" {
+			return "", fmt.Errorf("filter needs a pattern")
+		}
+		if b.viewMode == ViewModeRequestDetail || b.viewMode == ViewModeRules {
+			b.viewMode = ViewModeLogs
+		}
+		b.searchPattern = arg
+
+	case "view":
+		view, ok := paletteViews[strings.ToLower(arg)]
+		if !ok {
+			return "", fmt.Errorf("unknown view: %q (logs, requests, rules)", arg)
+		}
+		b.viewMode = view
+
+	case "open":
+		// Show the service's requests
+		if arg == "" {
+			return "", fmt.Errorf("open needs a service")
+		}
+		if !slices.Contains(b.paletteServices(), arg) {
+			return "", fmt.Errorf("unknown service: %s", arg)
+		}
+		b.viewMode = ViewModeRequests
+		b.searchPattern = arg
+
+	case "clear":
+		b.soloProcess = ""
+		b.searchPattern = ""
+		b.searchMatches = nil
+	}
+
+	b.updateViewport()
+	return "", nil
+}
+
+// paletteStatus renders the command line and its suggestions for the status
+// bar
+func (b *BaseModel) paletteStatus() string {
+	status := ":" + b.textInput.View()
+	if b.paletteError != "" {
+		return status + "  " + errorStyle.Render(b.paletteError)
+	}
+
+	suggestions := b.paletteSuggestions()
+	if len(suggestions) > maxPaletteSuggestions {
+		suggestions = suggestions[:maxPaletteSuggestions]
+	}
+	for i, s := range suggestions {
+		if i == b.paletteSelected {
+			s = "[" + s + "]"
+		} else {
+			s = dimStyle.Render(s)
+		}
+		status += "  " + s
+	}
+	return status
+}
@@ -0,0 +1,130 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/fsperm"
+)
+
+// Preferences are per-project TUI settings that persist across sessions,
+// kept in .prox/tui.json
+type Preferences struct {
+	Order  []string `json:"order,omitempty"`  // Header order arranged by the user
+	Pinned []string `json:"pinned,omitempty"` // Processes shown first in the header
+
+	path string
+}
+
+// NewPreferences creates empty preferences that are saved to path
+func NewPreferences(path string) *Preferences {
+	return &Preferences{path: path}
+}
+
+// LoadPreferences reads the preferences saved at path. A missing file
+// yields empty preferences.
+func LoadPreferences(path string) (*Preferences, error) {
+	prefs := NewPreferences(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return prefs, nil
+		}
+		return prefs, fmt.Errorf("reading TUI preferences: %w", err)
+	}
+	if err := json.Unmarshal(data, prefs); err != nil {
+		return NewPreferences(path), fmt.Errorf("unmarshaling TUI preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// Save writes the preferences to the path they were loaded from. Preferences
+// without a path (e.g. in tests) are not saved.
+func (p *Preferences) Save() error {
+	if p == nil || p.path == "" {
+		return nil
+	}
+	if err := fsperm.MkdirAll(filepath.Dir(p.path)); err != nil {
+		return fmt.Errorf("creating TUI preferences directory: %w", err)
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling TUI preferences: %w", err)
+	}
+	if err := fsperm.WriteFile(p.path, data); err != nil {
+		return fmt.Errorf("writing TUI preferences: %w", err)
+	}
+	return nil
+}
+
+// IsPinned reports whether the named process is pinned
+func (p *Preferences) IsPinned(name string) bool {
+	return p != nil && slices.Contains(p.Pinned, name)
+}
+
+// TogglePin pins the named process, or unpins it if already pinned
+func (p *Preferences) TogglePin(name string) {
+	if i := slices.Index(p.Pinned, name); i >= 0 {
+		p.Pinned = slices.Delete(p.Pinned, i, i+1)
+		return
+	}
+	p.Pinned = append(p.Pinned, name)
+}
+
+// Arrange orders processes for the header: pinned processes first, then
+// the user's order, then the remaining processes in their given order
+func (p *Preferences) Arrange(processes []domain.ProcessInfo) []domain.ProcessInfo {
+	if p == nil || (len(p.Order) == 0 && len(p.Pinned) == 0) {
+		return processes
+	}
+
+	rank := func(name string) int {
+		if i := slices.Index(p.Order, name); i >= 0 {
+			return i
+		}
+		return len(p.Order)
+	}
+
+	arranged := slices.Clone(processes)
+	sort.SliceStable(arranged, func(i, j int) bool {
+		a, b := arranged[i].Name, arranged[j].Name
+		if pa, pb := p.IsPinned(a), p.IsPinned(b); pa != pb {
+			return pa
+		}
+		return rank(a) < rank(b)
+	})
+	return arranged
+}
+
+// Move swaps the named process with its neighbor in the arranged header,
+// one place left (delta -1) or right (delta 1). A process doesn't move past
+// the boundary between pinned and unpinned processes. It reports whether
+// the order changed.
+func (p *Preferences) Move(arranged []domain.ProcessInfo, name string, delta int) bool {
+	names := make([]string, len(arranged))
+	for i, proc := range arranged {
+		names[i] = proc.Name
+	}
+
+	i := slices.Index(names, name)
+	j := i + delta
+	if i < 0 || j < 0 || j >= len(names) || p.IsPinned(names[i]) != p.IsPinned(names[j]) {
+		return false
+	}
+	names[i], names[j] = names[j], names[i]
+
+	// Remember processes that aren't running in this session too, so their
+	// place survives a config change
+	for _, n := range p.Order {
+		if !slices.Contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	p.Order = names
+	return true
+}
@@ -0,0 +1,168 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charliek/prox/internal/api"
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/humanize"
+)
+
+// Process actions offered in the processes view
+const (
+	actionStart   = "start"
+	actionStop    = "stop"
+	actionRestart = "restart"
+)
+
+// processActionText is how each action is described in the status bar, on
+// success and on failure
+var processActionText = map[string][2]string{
+	actionStart:   {"Started", "Start failed"},
+	actionStop:    {"Stopped", "Stop failed"},
+	actionRestart: {"Restarted", "Restart failed"},
+}
+
+// processActionKeys maps the processes view's keys to actions
+var processActionKeys = map[string]string{
+	"s": actionStart,
+	"x": actionStop,
+	"r": actionRestart,
+}
+
+// selectedProcessName returns the process under the cursor in the processes
+// view
+func (b *BaseModel) selectedProcessName() (string, bool) {
+	if b.viewMode != ViewModeProcesses || b.selectedProcess >= len(b.processes) {
+		return "", false
+	}
+	return b.processes[b.selectedProcess].Name, true
+}
+
+// selectedProcessAction returns the process and action a key asks for in
+// the processes view, if any
+func (b *BaseModel) selectedProcessAction(key string) (name, action string, ok bool) {
+	action, ok = processActionKeys[key]
+	if !ok {
+		return "", "", false
+	}
+	name, ok = b.selectedProcessName()
+	return name, action, ok
+}
+
+// showSelectedProcessLogs solos the selected process and switches to the
+// logs view
+func (b *BaseModel) showSelectedProcessLogs() {
+	name, ok := b.selectedProcessName()
+	if !ok {
+		return
+	}
+	b.soloProcess = name
+	b.viewMode = ViewModeLogs
+	b.updateViewport()
+}
+
+// clampSelectedProcess keeps the processes view's selection in range
+func (b *BaseModel) clampSelectedProcess() {
+	if b.selectedProcess >= len(b.processes) {
+		b.selectedProcess = len(b.processes) - 1
+	}
+	if b.selectedProcess < 0 {
+		b.selectedProcess = 0
+	}
+}
+
+// formatProcesses formats the processes view: a line per process, then the
+// details of the selected one
+func (b *BaseModel) formatProcesses() []string {
+	if len(b.processes) == 0 {
+		return []string{dimStyle.Render("No processes")}
+	}
+
+	lines := []string{
+		headerStyle.Render("Processes"),
+		"",
+		dimStyle.Render(fmt.Sprintf("  %-16s %-10s %-8s %-8s %-9s %s", "NAME", "STATUS", "PID", "UPTIME", "RESTARTS", "HEALTH")),
+	}
+	for i, proc := range b.processes {
+		cursor := "  "
+		if i == b.selectedProcess {
+			cursor = "> "
+		}
+		pid := "-"
+		if proc.PID > 0 {
+			pid = fmt.Sprintf("%d", proc.PID)
+		}
+		uptime := "-"
+		if !proc.StartedAt.IsZero() && proc.State == domain.ProcessStateRunning {
+			uptime = humanize.Duration(time.Since(proc.StartedAt).Truncate(time.Second))
+		}
+		lines = append(lines, fmt.Sprintf("%s%-16s %s %-8s %-8s %-9d %s",
+			cursor, proc.Name, processStyle(proc.State).Render(fmt.Sprintf("%-10s", proc.State)),
+			pid, uptime, proc.RestartCount, proc.Health))
+	}
+
+	lines = append(lines, b.formatProcessDetail(b.processes[b.selectedProcess])...)
+	lines = append(lines, "")
+	lines = append(lines, dimStyle.Render("s: start | x: stop | r: restart | Enter: logs | P/ESC: back to logs"))
+	return lines
+}
+
+// formatProcessDetail formats the details of the selected process. A
+// fetched detail is used if it is for the process, since the client's
+// process list leaves out the command, environment, and health checks.
+func (b *BaseModel) formatProcessDetail(proc domain.ProcessInfo) []string {
+	if b.processDetail != nil && b.processDetail.Name == proc.Name {
+		proc = *b.processDetail
+	}
+
+	lines := []string{"", headerStyle.Render(proc.Name)}
+	if proc.Cmd != "" {
+		lines = append(lines, "  Command:  "+b.redactor.Redact(proc.Cmd))
+	}
+	if proc.Port > 0 {
+		lines = append(lines, fmt.Sprintf("  Port:     %d", proc.Port))
+	}
+	if proc.Lazy {
+		lines = append(lines, "  Lazy:     started on first request")
+	}
+	if proc.RestartReason != "" {
+		lines = append(lines, fmt.Sprintf("  Restart:  %s (last of %d)", proc.RestartReason, proc.RestartCount))
+	}
+	if proc.PendingRestart {
+		lines = append(lines, "  "+httpWarningStyle.Render("Definition changed; restart to apply"))
+	}
+	if proc.LastError != "" {
+		lines = append(lines, "  "+errorStyle.Render("Last error: "+b.redactor.Redact(proc.LastError)))
+	}
+
+	if hc := proc.HealthDetails; hc != nil && hc.Enabled {
+		lines = append(lines, "", headerStyle.Render("Health Check"))
+		lines = append(lines, fmt.Sprintf("  Status:   %s", hc.Status))
+		if !hc.LastCheck.IsZero() {
+			lines = append(lines, "  Checked:  "+hc.LastCheck.Format("15:04:05"))
+		}
+		if hc.ConsecutiveFailures > 0 {
+			lines = append(lines, fmt.Sprintf("  Failures: %d in a row", hc.ConsecutiveFailures))
+		}
+		if hc.LastOutput != "" {
+			lines = append(lines, "  Output:   "+b.redactor.Redact(hc.LastOutput))
+		}
+	}
+
+	// Secrets are hidden as in the API's process details
+	if env := api.FilterSensitiveEnv(proc.Env); len(env) > 0 {
+		names := make([]string, 0, len(env))
+		for name := range env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		lines = append(lines, "", headerStyle.Render("Environment"))
+		for _, name := range names {
+			lines = append(lines, fmt.Sprintf("  %s=%s", dimStyle.Render(name), b.redactor.Redact(env[name])))
+		}
+	}
+	return lines
+}
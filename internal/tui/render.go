@@ -0,0 +1,115 @@
+package tui
+
+import (
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/charliek/prox/internal/domain"
+	"github.com/charliek/prox/internal/proxy"
+)
+
+// Under log storms, thousands of lines a second arrive. Rebuilding the
+// viewport for each would leave no time to handle keys, so the forwarders
+// send whatever has piled up as one batch message, new entries only mark the
+// viewport stale, and it is rebuilt at most once a frame. Log lines are
+// formatted once and cached, so a rebuild only formats the new ones.
+
+// frameInterval is the shortest time between viewport rebuilds (30fps)
+const frameInterval = time.Second / 30
+
+// maxBatch is the most entries or requests forwarded in one message
+const maxBatch = maxLogEntries
+
+// LogBatchMsg is sent with log entries that arrived together
+type LogBatchMsg []domain.LogEntry
+
+// ProxyRequestBatchMsg is sent with proxy requests that arrived together
+type ProxyRequestBatchMsg []proxy.RequestRecord
+
+// frameMsg is sent when a scheduled frame is due
+type frameMsg struct{}
+
+// drain returns first followed by the values already waiting on ch, up to
+// limit in all, without blocking
+func drain[T any](first T, ch <-chan T, limit int) []T {
+	batch := []T{first}
+	for len(batch) < limit {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, v)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// markStale notes that the viewport content is out of date and returns a
+// command for the frame that rebuilds it, unless one is already scheduled
+func (b *BaseModel) markStale() tea.Cmd {
+	b.viewportStale = true
+	if b.frameScheduled {
+		return nil
+	}
+	b.frameScheduled = true
+	return tea.Tick(frameInterval, func(time.Time) tea.Msg {
+		return frameMsg{}
+	})
+}
+
+// handleFrame rebuilds the viewport if entries arrived since the last frame,
+// keeping it at the bottom while following
+func (b *BaseModel) handleFrame() {
+	b.frameScheduled = false
+	if !b.viewportStale {
+		return
+	}
+	b.viewportStale = false
+
+	// Check if we're at/near bottom BEFORE adding new content
+	wasNearBottom := b.isNearBottom()
+	b.updateViewport()
+
+	// While scrubbing the view stays frozen with the cursor at the bottom
+	if b.scrubbing {
+		if b.viewMode == ViewModeRequests {
+			b.viewport.GotoBottom()
+		}
+		return
+	}
+
+	// If user was at bottom, re-enable follow mode and stay at bottom
+	if wasNearBottom {
+		b.followMode = true
+		b.viewport.GotoBottom()
+	} else if b.followMode {
+		b.viewport.GotoBottom()
+	}
+}
+
+// logLine returns the formatted line of b.logEntries[i], formatting it on
+// first use
+func (b *BaseModel) logLine(i int) string {
+	if b.logLines[i] == "" {
+		b.logLines[i] = b.formatLogEntry(b.logEntries[i])
+	}
+	return b.logLines[i]
+}
+
+// clearLogLines drops the formatted log lines, for when their styling changes
+func (b *BaseModel) clearLogLines() {
+	clear(b.logLines)
+}
+
+// sameProcessOrder reports whether two process lists name the same processes
+// in the same order, and so give them the same colors
+func sameProcessOrder(a, b []domain.ProcessInfo) bool {
+	return slices.EqualFunc(a, b, func(x, y domain.ProcessInfo) bool {
+		return x.Name == y.Name
+	})
+}
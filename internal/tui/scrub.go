@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charliek/prox/internal/proxy"
+)
+
+// Time-travel scrubbing freezes the requests view at a recorded request and
+// steps through the request history in time order. The position is tracked by
+// request ID (with its timestamp as a fallback) so it survives new requests
+// arriving and old ones being trimmed from the buffer.
+
+// timeOrderedRequests returns the filtered proxy requests sorted by timestamp
+func (b *BaseModel) timeOrderedRequests() []proxy.RequestRecord {
+	requests := b.filteredProxyRequests()
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].Timestamp.Before(requests[j].Timestamp)
+	})
+	return requests
+}
+
+// scrubIndex returns the position of the scrub cursor in requests. If the
+// cursor request is no longer present, it falls back to the latest request at
+// or before the cursor time, then to the oldest request.
+func (b *BaseModel) scrubIndex(requests []proxy.RequestRecord) int {
+	fallback := 0
+	for i, req := range requests {
+		if req.ID == b.scrubID {
+			return i
+		}
+		if !req.Timestamp.After(b.scrubAt) {
+			fallback = i
+		}
+	}
+	return fallback
+}
+
+// startScrub freezes the requests view at the most recent request
+func (b *BaseModel) startScrub() {
+	requests := b.timeOrderedRequests()
+	if len(requests) == 0 {
+		return
+	}
+	b.scrubbing = true
+	b.followMode = false
+	b.setScrubCursor(requests[len(requests)-1])
+}
+
+// stopScrub returns the requests view to live mode
+func (b *BaseModel) stopScrub() {
+	b.scrubbing = false
+	b.scrubID = ""
+	b.scrubAt = time.Time{}
+	b.followMode = true
+	b.updateViewport()
+	b.viewport.GotoBottom()
+}
+
+// scrubStep moves the scrub cursor by delta requests, clamped to the history
+func (b *BaseModel) scrubStep(delta int) {
+	requests := b.timeOrderedRequests()
+	if len(requests) == 0 {
+		return
+	}
+	idx := b.scrubIndex(requests) + delta
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(requests) {
+		idx = len(requests) - 1
+	}
+	b.setScrubCursor(requests[idx])
+}
+
+// setScrubCursor moves the scrub cursor to req and redraws the view
+func (b *BaseModel) setScrubCursor(req proxy.RequestRecord) {
+	b.scrubID = req.ID
+	b.scrubAt = req.Timestamp
+	b.updateViewport()
+	b.viewport.GotoBottom()
+}
+
+// formatScrubRequests formats the requests up to and including the scrub cursor
+func (b *BaseModel) formatScrubRequests() []string {
+	requests := b.timeOrderedRequests()
+	if len(requests) == 0 {
+		return nil
+	}
+	idx := b.scrubIndex(requests)
+	lines := make([]string, 0, idx+1)
+	for i, req := range requests[:idx+1] {
+		prefix := "  "
+		if i == idx {
+			prefix = "> "
+		}
+		lines = append(lines, prefix+b.formatProxyRequest(req))
+	}
+	return lines
+}
+
+// scrubStatus returns the status bar indicator and position while scrubbing
+func (b *BaseModel) scrubStatus() (indicator string, position, total int) {
+	requests := b.timeOrderedRequests()
+	if len(requests) == 0 {
+		return "[AT --:--:--]", 0, 0
+	}
+	idx := b.scrubIndex(requests)
+	return fmt.Sprintf("[AT %s]", requests[idx].Timestamp.Format("15:04:05.000")), idx + 1, len(requests)
+}
@@ -1,6 +1,10 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"github.com/charliek/prox/internal/theme"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
 
 // Colors
 var (
@@ -24,90 +28,148 @@ var (
 	warningColor  = lipgloss.Color("11") // Yellow for 4xx
 	// errorColor already defined above for 5xx
 
-	// Process name colors (for log lines)
-	processColorList = []lipgloss.Color{
-		lipgloss.Color("14"),  // Cyan
-		lipgloss.Color("13"),  // Magenta
-		lipgloss.Color("12"),  // Blue
-		lipgloss.Color("11"),  // Yellow
-		lipgloss.Color("10"),  // Green
-		lipgloss.Color("208"), // Orange
-		lipgloss.Color("207"), // Pink
-		lipgloss.Color("159"), // Light blue
-		lipgloss.Color("156"), // Light green
+	// Process name colors (for log lines) on a dark background
+	processColorList = []string{
+		"14",  // Cyan
+		"13",  // Magenta
+		"12",  // Blue
+		"11",  // Yellow
+		"10",  // Green
+		"208", // Orange
+		"207", // Pink
+		"159", // Light blue
+		"156", // Light green
 	}
 )
 
 // Styles
 var (
 	// Process state styles
+	runningStyle  lipgloss.Style
+	stoppedStyle  lipgloss.Style
+	crashedStyle  lipgloss.Style
+	startingStyle lipgloss.Style
+	stoppingStyle lipgloss.Style
+
+	defaultProcessStyle = lipgloss.NewStyle()
+
+	// Header, status bar, and help overlay styles
+	headerStyle lipgloss.Style
+	statusStyle lipgloss.Style
+	helpStyle   lipgloss.Style
+
+	// Error indicator style
+	errorStyle lipgloss.Style
+
+	// Dim style for timestamps
+	dimStyle lipgloss.Style
+
+	// HTTP status styles
+	httpSuccessStyle  lipgloss.Style
+	httpRedirectStyle lipgloss.Style
+	httpWarningStyle  lipgloss.Style
+	httpErrorStyle    lipgloss.Style
+
+	// Process colors for log lines: those set by the theme, by process, and
+	// the palette given to the others in turn
+	namedProcessColors map[string]lipgloss.Style
+	processColors      []lipgloss.Style
+
+	// displayTheme is the theme log lines are timestamped with
+	displayTheme *theme.Theme
+)
+
+func init() {
+	buildStyles()
+}
+
+// SetTheme sets the colors and timestamps of the TUI. It must be called
+// before the TUI starts.
+func SetTheme(t *theme.Theme) {
+	displayTheme = t
+	if !t.Colored() {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+	if t.Light {
+		runningColor = lipgloss.Color("28")
+		stoppedColor = lipgloss.Color("245")
+		crashedColor = lipgloss.Color("160")
+		startingColor = lipgloss.Color("130")
+		stoppingColor = lipgloss.Color("130")
+		headerBg = lipgloss.Color("254")
+		statusBg = lipgloss.Color("253")
+		helpBg = lipgloss.Color("255")
+		errorColor = lipgloss.Color("160")
+		dimColor = lipgloss.Color("245")
+		successColor = lipgloss.Color("28")
+		redirectColor = lipgloss.Color("30")
+		warningColor = lipgloss.Color("130")
+	}
+	buildStyles()
+}
+
+// buildStyles builds the styles from the colors and the theme
+func buildStyles() {
 	runningStyle = lipgloss.NewStyle().
-			Foreground(runningColor).
-			Bold(true)
+		Foreground(runningColor).
+		Bold(true)
 
 	stoppedStyle = lipgloss.NewStyle().
-			Foreground(stoppedColor)
+		Foreground(stoppedColor)
 
 	crashedStyle = lipgloss.NewStyle().
-			Foreground(crashedColor).
-			Bold(true)
+		Foreground(crashedColor).
+		Bold(true)
 
 	startingStyle = lipgloss.NewStyle().
-			Foreground(startingColor)
+		Foreground(startingColor)
 
 	stoppingStyle = lipgloss.NewStyle().
-			Foreground(stoppingColor)
-
-	defaultProcessStyle = lipgloss.NewStyle()
+		Foreground(stoppingColor)
 
-	// Header style
 	headerStyle = lipgloss.NewStyle().
-			Background(headerBg).
-			Padding(0, 1).
-			MarginBottom(1)
+		Background(headerBg).
+		Padding(0, 1).
+		MarginBottom(1)
 
-	// Status bar style
 	statusStyle = lipgloss.NewStyle().
-			Background(statusBg).
-			Padding(0, 1)
+		Background(statusBg).
+		Padding(0, 1)
 
-	// Help overlay style
 	helpStyle = lipgloss.NewStyle().
-			Background(helpBg).
-			Padding(1, 2).
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("240"))
+		Background(helpBg).
+		Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240"))
 
-	// Error indicator style
 	errorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("15")).
-			Background(errorColor).
-			Bold(true)
+		Foreground(lipgloss.Color("15")).
+		Background(errorColor).
+		Bold(true)
 
-	// Dim style for timestamps
 	dimStyle = lipgloss.NewStyle().
-			Foreground(dimColor)
+		Foreground(dimColor)
 
-	// HTTP status styles
 	httpSuccessStyle = lipgloss.NewStyle().
-				Foreground(successColor)
+		Foreground(successColor)
 
 	httpRedirectStyle = lipgloss.NewStyle().
-				Foreground(redirectColor)
+		Foreground(redirectColor)
 
 	httpWarningStyle = lipgloss.NewStyle().
-				Foreground(warningColor)
+		Foreground(warningColor)
 
 	httpErrorStyle = lipgloss.NewStyle().
-			Foreground(errorColor)
+		Foreground(errorColor)
 
-	// Process colors for log lines
-	processColors []lipgloss.Style
-)
-
-func init() {
-	// Initialize process color styles
-	for _, color := range processColorList {
-		processColors = append(processColors, lipgloss.NewStyle().Foreground(color))
+	processColors = nil
+	for _, color := range displayTheme.Palette(processColorList) {
+		processColors = append(processColors, lipgloss.NewStyle().Foreground(lipgloss.Color(color)))
+	}
+	namedProcessColors = make(map[string]lipgloss.Style)
+	if displayTheme != nil {
+		for process, color := range displayTheme.Colors {
+			namedProcessColors[process] = lipgloss.NewStyle().Foreground(lipgloss.Color(color))
+		}
 	}
 }
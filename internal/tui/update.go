@@ -10,8 +10,9 @@ import (
 	"github.com/charliek/prox/internal/proxy"
 )
 
-// restartTimeout is the maximum time to wait for a restart operation
-const restartTimeout = 30 * time.Second
+// processActionTimeout is the maximum time to wait for a start, stop, or
+// restart operation
+const processActionTimeout = 30 * time.Second
 
 // Update handles messages
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -27,37 +28,59 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.updateViewport()
 
 	case LogEntryMsg:
-		m.handleLogEntry(domain.LogEntry(msg))
+		cmds = append(cmds, m.handleLogEntries([]domain.LogEntry{domain.LogEntry(msg)}))
+
+	case LogBatchMsg:
+		cmds = append(cmds, m.handleLogEntries(msg))
 
 	case ProxyRequestMsg:
-		m.handleProxyRequest(proxy.RequestRecord(msg))
+		cmds = append(cmds, m.handleProxyRequests([]proxy.RequestRecord{proxy.RequestRecord(msg)}))
+
+	case ProxyRequestBatchMsg:
+		cmds = append(cmds, m.handleProxyRequests(msg))
+
+	case frameMsg:
+		m.handleFrame()
 
 	case ProcessesMsg:
-		m.processes = m.supervisor.Processes()
+		m.setProcesses(m.supervisor.Processes())
 
 	case TickMsg:
-		m.processes = m.supervisor.Processes()
+		m.setProcesses(m.supervisor.Processes())
+		if m.proxyService != nil {
+			m.handleRules(m.proxyService.Rules())
+			m.recordingPaused = !m.proxyService.Recording()
+		}
 		cmds = append(cmds, tickCmd())
 
+	case RuleToggleResultMsg:
+		m.lastRuleToggle = &msg
+		if m.proxyService != nil {
+			m.handleRules(m.proxyService.Rules())
+		}
+		cmds = append(cmds, ruleToggleClearCmd())
+
+	case RuleToggleClearMsg:
+		m.lastRuleToggle = nil
+
 	case subIDMsg:
 		m.subID = string(msg)
 
-	case RestartResultMsg:
-		m.lastRestartProcess = msg.Process
-		m.lastRestartError = msg.Err
-		cmds = append(cmds, restartResultClearCmd())
+	case ProcessActionMsg:
+		m.lastAction = &msg
+		m.setProcesses(m.supervisor.Processes())
+		cmds = append(cmds, processActionClearCmd())
 
-	case RestartResultClearMsg:
-		m.lastRestartProcess = ""
-		m.lastRestartError = nil
+	case ProcessActionClearMsg:
+		m.lastAction = nil
 	}
 
 	// Handle viewport updates
 	m.viewport, cmd = m.viewport.Update(msg)
 	cmds = append(cmds, cmd)
 
-	// Handle text input if in filter/search mode
-	if m.mode == ModeFilter || m.mode == ModeSearch || m.mode == ModeStringFilter {
+	// Handle text input if in filter/search/palette mode
+	if m.mode == ModeFilter || m.mode == ModeSearch || m.mode == ModeStringFilter || m.mode == ModePalette {
 		m.textInput, cmd = m.textInput.Update(msg)
 		cmds = append(cmds, cmd)
 	}
@@ -81,6 +104,17 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case ModeHelp:
 		m.handleHelpKey(msg)
 		return m, nil
+	case ModePalette:
+		restart, cmd := m.handlePaletteKey(msg)
+		if restart != "" {
+			return m, m.restartProcess(restart)
+		}
+		return m, cmd
+	}
+
+	// Start, stop, and restart the process selected in the processes view
+	if name, action, ok := m.selectedProcessAction(msg.String()); ok {
+		return m, m.processAction(name, action)
 	}
 
 	// Normal mode keys
@@ -91,17 +125,23 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "r":
 		// Restart the solo'd process (selected via 1-9 keys)
 		if m.soloProcess != "" {
-			processName := m.soloProcess
-			return m, func() tea.Msg {
-				ctx, cancel := context.WithTimeout(context.Background(), restartTimeout)
-				defer cancel()
-				err := m.supervisor.RestartProcess(ctx, processName)
-				return RestartResultMsg{Process: processName, Err: err}
-			}
+			return m, m.restartProcess(m.soloProcess)
 		}
 		return m, nil
 
+	case " ":
+		return m, m.toggleSelectedRule()
+
 	case "enter":
+		// In rules view, toggle the selected rule
+		if m.viewMode == ViewModeRules {
+			return m, m.toggleSelectedRule()
+		}
+		// In processes view, show the selected process's logs
+		if m.viewMode == ViewModeProcesses {
+			m.showSelectedProcessLogs()
+			return m, nil
+		}
 		// In requests view, show detail for selected request
 		if m.viewMode == ViewModeRequests {
 			requestID := m.getSelectedRequest()
@@ -129,6 +169,41 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// restartProcess returns a command that restarts a process
+func (m Model) restartProcess(name string) tea.Cmd {
+	return m.processAction(name, actionRestart)
+}
+
+// processAction returns a command that starts, stops, or restarts a process
+func (m Model) processAction(name, action string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), processActionTimeout)
+		defer cancel()
+		var err error
+		switch action {
+		case actionStart:
+			err = m.supervisor.StartProcess(ctx, name)
+		case actionStop:
+			err = m.supervisor.StopProcess(ctx, name)
+		default:
+			err = m.supervisor.RestartProcess(ctx, name)
+		}
+		return ProcessActionMsg{Process: name, Action: action, Err: err}
+	}
+}
+
+// toggleSelectedRule returns a command that flips the selected proxy rule
+func (m Model) toggleSelectedRule() tea.Cmd {
+	rule, ok := m.selectedRuleItem()
+	if !ok || m.proxyService == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		updated, err := m.proxyService.SetRuleEnabled(rule.ID, !rule.Enabled)
+		return RuleToggleResultMsg{ID: rule.ID, Enabled: updated.Enabled, Err: err}
+	}
+}
+
 // nearBottomThreshold is the scroll percentage (0.0-1.0) at which we consider
 // the viewport to be "near" the bottom for auto-follow purposes.
 const nearBottomThreshold = 0.98
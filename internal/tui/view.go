@@ -16,20 +16,15 @@ func (m Model) View() string {
 	case ModeHelp:
 		return m.helpView()
 	default:
-		statusInfo := ""
-		if m.lastRestartProcess != "" {
-			if m.lastRestartError != nil {
-				statusInfo = "Restart failed: " + truncateError(m.lastRestartError, maxErrorDisplayLen)
-			} else {
-				statusInfo = "Restarted: " + m.lastRestartProcess
-			}
-		}
-		return m.mainView(statusInfo)
+		return m.mainView(m.statusInfo())
 	}
 }
 
 // getProcessStyle returns the style for a process name
 func getProcessStyle(name string, processes []domain.ProcessInfo) lipgloss.Style {
+	if style, ok := namedProcessColors[name]; ok {
+		return style
+	}
 	// Find process index for color
 	for i, p := range processes {
 		if p.Name == name {
@@ -46,7 +41,7 @@ func processStyle(state domain.ProcessState) lipgloss.Style {
 		return runningStyle
 	case domain.ProcessStateStopped:
 		return stoppedStyle
-	case domain.ProcessStateCrashed:
+	case domain.ProcessStateCrashed, domain.ProcessStateFailed:
 		return crashedStyle
 	case domain.ProcessStateStarting:
 		return startingStyle
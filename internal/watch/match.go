@@ -0,0 +1,76 @@
+package watch
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// Match reports whether a slash-separated path relative to the watched
+// directory matches a pattern. Like a .gitignore entry, a pattern without a
+// slash matches a file or directory name at any depth, and a pattern with
+// one matches from the watched directory, with ** standing for any number of
+// directories. A pattern matching a directory matches everything in it.
+func Match(pattern, name string) bool {
+	pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/")
+	segments := strings.Split(name, "/")
+
+	if !strings.Contains(pattern, "/") {
+		for _, segment := range segments {
+			if ok, _ := path.Match(pattern, segment); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	patternSegments := strings.Split(pattern, "/")
+	for i := 1; i <= len(segments); i++ {
+		if matchSegments(patternSegments, segments[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches a path's segments against a pattern's
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ValidatePattern checks that a pattern is well formed and stays within the
+// watched directory
+func ValidatePattern(pattern string) error {
+	if strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/") == "" {
+		return errors.New("pattern is empty")
+	}
+	if strings.HasPrefix(pattern, "/") {
+		return errors.New("must be relative to the config file's directory")
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == ".." {
+			return errors.New("can't reach outside the config file's directory")
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+// Package watch reports changes to files matching per-process patterns, so
+// prox can restart a process when its source changes. It watches a directory
+// tree with fsnotify and reports a rule's changes once they have stopped
+// coming in for its debounce time, so saving many files restarts once.
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// skipDirs are directories never watched. Version control and dependency
+// trees are large and churn during installs and checkouts, and .prox holds
+// prox's own state.
+var skipDirs = map[string]bool{
+	".git":         true,
+	".hg":          true,
+	".svn":         true,
+	".prox":        true,
+	"node_modules": true,
+}
+
+// Rule names the files whose changes are reported for a process
+type Rule struct {
+	Name     string        // Process the changes are reported for
+	Patterns []string      // Files that count as changes; see Match
+	Ignore   []string      // Files that don't, even if Patterns match them
+	Debounce time.Duration // Quiet time after the last change before reporting
+}
+
+// matches reports whether a change to a file concerns the rule
+func (r Rule) matches(name string) bool {
+	match := func(pattern string) bool { return Match(pattern, name) }
+	return slices.ContainsFunc(r.Patterns, match) && !slices.ContainsFunc(r.Ignore, match)
+}
+
+// Watcher watches a directory tree, calling onChange with a rule's name and
+// the changed paths, relative to the directory and sorted
+type Watcher struct {
+	root     string
+	fs       *fsnotify.Watcher
+	onChange func(name string, paths []string)
+
+	// mu protects rules and pending, the changes of each rule waiting for
+	// its debounce time to pass
+	mu      sync.Mutex
+	rules   []Rule
+	pending map[string]*pendingChange
+}
+
+// pendingChange collects a rule's changes until its timer fires
+type pendingChange struct {
+	timer *time.Timer
+	paths []string
+}
+
+// New creates a watcher for the directory tree under root, skipping version
+// control and dependency directories. It reports nothing until SetRules is
+// called.
+func New(root string, onChange func(name string, paths []string)) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		root:     root,
+		fs:       fsw,
+		onChange: onChange,
+		pending:  make(map[string]*pendingChange),
+	}
+	if _, err := w.addTree(root); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// SetRules replaces the rules. Changes waiting on a removed rule are dropped.
+func (w *Watcher) SetRules(rules []Rule) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.rules = rules
+	for name, p := range w.pending {
+		if !slices.ContainsFunc(rules, func(r Rule) bool { return r.Name == name }) {
+			p.timer.Stop()
+			delete(w.pending, name)
+		}
+	}
+}
+
+// Run handles file events until ctx is cancelled, then stops watching
+func (w *Watcher) Run(ctx context.Context) {
+	defer w.close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+		case _, ok := <-w.fs.Errors:
+			// Errors such as an event queue overflow lose events, which the
+			// next change makes up for
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// handle starts or extends the debounce of each rule a file event matches
+func (w *Watcher) handle(event fsnotify.Event) {
+	// Permission and timestamp changes leave the content alone
+	if event.Op == fsnotify.Chmod {
+		return
+	}
+	rel, ok := w.rel(event.Name)
+	if !ok {
+		return
+	}
+	changed := []string{rel}
+
+	// New directories are watched too, since fsnotify doesn't recurse. Files
+	// written to them before they were watched count as changes.
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			files, _ := w.addTree(event.Name)
+			for _, file := range files {
+				if rel, ok := w.rel(file); ok {
+					changed = append(changed, rel)
+				}
+			}
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, rule := range w.rules {
+		for _, rel := range changed {
+			if rule.matches(rel) {
+				w.record(rule, rel)
+			}
+		}
+	}
+}
+
+// rel returns a path relative to the watched directory with forward
+// slashes, or false if it is in a skipped directory
+func (w *Watcher) rel(path string) (string, bool) {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	if slices.ContainsFunc(strings.Split(rel, "/"), func(s string) bool { return skipDirs[s] }) {
+		return "", false
+	}
+	return rel, true
+}
+
+// record adds a changed path to a rule's pending changes, restarting its
+// debounce time. w.mu must be held.
+func (w *Watcher) record(rule Rule, rel string) {
+	p, ok := w.pending[rule.Name]
+	if !ok {
+		name := rule.Name
+		p = &pendingChange{timer: time.AfterFunc(rule.Debounce, func() { w.fire(name) })}
+		w.pending[name] = p
+	} else {
+		p.timer.Reset(rule.Debounce)
+	}
+	if !slices.Contains(p.paths, rel) {
+		p.paths = append(p.paths, rel)
+	}
+}
+
+// fire reports a rule's changes once its debounce time has passed
+func (w *Watcher) fire(name string) {
+	w.mu.Lock()
+	p, ok := w.pending[name]
+	delete(w.pending, name)
+	w.mu.Unlock()
+	if !ok {
+		return
+	}
+	sort.Strings(p.paths)
+	w.onChange(name, p.paths)
+}
+
+// addTree watches dir and the directories under it, returning the files in
+// them. Only failing to watch dir itself is an error; subdirectories may
+// vanish while they are walked.
+func (w *Watcher) addTree(dir string) ([]string, error) {
+	if err := w.fs.Add(dir); err != nil {
+		return nil, err
+	}
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil || path == dir:
+			return nil
+		case !d.IsDir():
+			files = append(files, path)
+		case skipDirs[d.Name()]:
+			return filepath.SkipDir
+		default:
+			_ = w.fs.Add(path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// close stops watching and drops pending changes
+func (w *Watcher) close() {
+	w.mu.Lock()
+	for name, p := range w.pending {
+		p.timer.Stop()
+		delete(w.pending, name)
+	}
+	w.mu.Unlock()
+	w.fs.Close()
+}
@@ -0,0 +1,122 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/prox/main.go", true},
+		{"*.go", "main.js", false},
+		{"src/*.go", "src/main.go", true},
+		{"src/*.go", "src/pkg/main.go", false},
+		{"./src/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/pkg/util/main.go", true},
+		{"src/**/*.go", "lib/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"src", "src/pkg/main.go", true},
+		{"src/", "src/main.go", true},
+		{"tmp", "src/tmp/cache.bin", true},
+		{"src/pkg", "src/pkg/main.go", true},
+		{"src/pkg", "lib/src/pkg/main.go", false},
+		{"config/*.yaml", "config/app.yaml", true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Match(tt.pattern, tt.name), "%s against %s", tt.pattern, tt.name)
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	assert.NoError(t, ValidatePattern("src/**/*.go"))
+	assert.NoError(t, ValidatePattern("./config/"))
+	assert.Error(t, ValidatePattern(""))
+	assert.Error(t, ValidatePattern("./"))
+	assert.Error(t, ValidatePattern("/etc/*.conf"))
+	assert.Error(t, ValidatePattern("../shared/*.go"))
+	assert.Error(t, ValidatePattern("src/[.go"))
+}
+
+// recorder collects the changes a watcher reports
+type recorder struct {
+	mu      sync.Mutex
+	changes map[string][][]string
+}
+
+func (r *recorder) onChange(name string, paths []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.changes == nil {
+		r.changes = make(map[string][][]string)
+	}
+	r.changes[name] = append(r.changes[name], paths)
+}
+
+func (r *recorder) get(name string) [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.changes[name]
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "node_modules", "dep"), 0755))
+
+	var rec recorder
+	w, err := New(dir, rec.onChange)
+	require.NoError(t, err)
+	w.SetRules([]Rule{
+		{Name: "web", Patterns: []string{"src/**/*.go"}, Ignore: []string{"*_test.go"}, Debounce: 50 * time.Millisecond},
+		{Name: "assets", Patterns: []string{"*.css", "*.js"}, Debounce: 50 * time.Millisecond},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	write := func(name string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0644))
+	}
+
+	// Changes within the debounce time are reported together
+	write("src/main.go")
+	write("src/util.go")
+	write("src/main_test.go")
+	require.Eventually(t, func() bool { return len(rec.get("web")) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, [][]string{{"src/main.go", "src/util.go"}}, rec.get("web"))
+
+	// Files in new directories are watched
+	write("src/pkg/handler.go")
+	require.Eventually(t, func() bool { return len(rec.get("web")) == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"src/pkg/handler.go"}, rec.get("web")[1])
+
+	// Dependency directories aren't watched
+	write("node_modules/dep/index.js")
+	write("app.css")
+	require.Eventually(t, func() bool { return len(rec.get("assets")) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"app.css"}, rec.get("assets")[0])
+
+	// Removed rules report nothing
+	w.SetRules([]Rule{{Name: "assets", Patterns: []string{"*.css"}, Debounce: 50 * time.Millisecond}})
+	write("src/main.go")
+	write("app.css")
+	require.Eventually(t, func() bool { return len(rec.get("assets")) == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, rec.get("web"), 2)
+}
@@ -0,0 +1,188 @@
+// Package watchdog tracks long-lived stream goroutines (SSE subscribers,
+// process output readers, health checkers) and force-cleans leaked ones.
+package watchdog
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kind identifies a category of tracked goroutine
+type Kind string
+
+const (
+	KindSSESubscriber Kind = "sse_subscriber"
+	KindOutputReader  Kind = "output_reader"
+	KindHealthChecker Kind = "health_checker"
+)
+
+// Kinds lists every kind of tracked goroutine, in display order
+var Kinds = []Kind{KindSSESubscriber, KindOutputReader, KindHealthChecker}
+
+// Watchdog tracks goroutines and periodically checks them for leaks.
+// All methods are safe to call on a nil Watchdog, which tracks nothing.
+type Watchdog struct {
+	mu      sync.Mutex
+	nextID  uint64
+	tracked map[uint64]*Handle
+	leaked  map[Kind]int
+}
+
+// Handle is a tracked goroutine. Call Done when the goroutine exits.
+type Handle struct {
+	w       *Watchdog
+	id      uint64
+	kind    Kind
+	name    string
+	started time.Time
+	leaked  func() bool
+	cleanup func()
+}
+
+// Stats reports tracked goroutines per kind
+type Stats struct {
+	Active map[Kind]int // Currently tracked
+	Leaked map[Kind]int // Detected as leaked and force-cleaned since start
+}
+
+// New creates a watchdog
+func New() *Watchdog {
+	return &Watchdog{
+		tracked: make(map[uint64]*Handle),
+		leaked:  make(map[Kind]int),
+	}
+}
+
+// Track registers a goroutine. leaked reports whether the goroutine has
+// outlived its purpose; cleanup is then called once to force it to exit.
+func (w *Watchdog) Track(kind Kind, name string, leaked func() bool, cleanup func()) *Handle {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.nextID++
+	h := &Handle{
+		w:       w,
+		id:      w.nextID,
+		kind:    kind,
+		name:    name,
+		started: time.Now(),
+		leaked:  leaked,
+		cleanup: cleanup,
+	}
+	w.tracked[h.id] = h
+	return h
+}
+
+// Done stops tracking the goroutine. It is safe to call more than once.
+func (h *Handle) Done() {
+	if h == nil {
+		return
+	}
+	h.w.mu.Lock()
+	delete(h.w.tracked, h.id)
+	h.w.mu.Unlock()
+}
+
+// Sweep checks every tracked goroutine and force-cleans the leaked ones.
+// It returns the number cleaned.
+func (w *Watchdog) Sweep() int {
+	if w == nil {
+		return 0
+	}
+
+	w.mu.Lock()
+	handles := make([]*Handle, 0, len(w.tracked))
+	for _, h := range w.tracked {
+		handles = append(handles, h)
+	}
+	w.mu.Unlock()
+
+	// Leak checks and cleanups run without the lock since they may take
+	// other locks or call back into Done
+	sort.Slice(handles, func(i, j int) bool { return handles[i].id < handles[j].id })
+	cleaned := 0
+	for _, h := range handles {
+		if h.leaked == nil || !h.leaked() {
+			continue
+		}
+
+		w.mu.Lock()
+		_, stillTracked := w.tracked[h.id]
+		if stillTracked {
+			delete(w.tracked, h.id)
+			w.leaked[h.kind]++
+		}
+		w.mu.Unlock()
+		if !stillTracked {
+			continue
+		}
+
+		log.Printf("watchdog: cleaning leaked %s %s (running %s)", h.kind, h.name, time.Since(h.started).Round(time.Second))
+		if h.cleanup != nil {
+			h.cleanup()
+		}
+		cleaned++
+	}
+	return cleaned
+}
+
+// Run sweeps at the given interval until ctx is cancelled
+func (w *Watchdog) Run(ctx context.Context, interval time.Duration) {
+	if w == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Sweep()
+		}
+	}
+}
+
+// Stats returns the current counts. Every kind is present in both maps.
+func (w *Watchdog) Stats() Stats {
+	stats := Stats{
+		Active: make(map[Kind]int, len(Kinds)),
+		Leaked: make(map[Kind]int, len(Kinds)),
+	}
+	for _, kind := range Kinds {
+		stats.Active[kind] = 0
+		stats.Leaked[kind] = 0
+	}
+	if w == nil {
+		return stats
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, h := range w.tracked {
+		stats.Active[h.kind]++
+	}
+	for kind, n := range w.leaked {
+		stats.Leaked[kind] = n
+	}
+	return stats
+}
+
+// Closed reports whether ch has been closed, for use in leak checks
+func Closed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
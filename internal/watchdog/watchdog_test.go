@@ -0,0 +1,92 @@
+package watchdog
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchdog_TrackAndDone(t *testing.T) {
+	w := New()
+
+	h1 := w.Track(KindSSESubscriber, "sub-1", nil, nil)
+	w.Track(KindOutputReader, "web stdout", nil, nil)
+
+	stats := w.Stats()
+	assert.Equal(t, 1, stats.Active[KindSSESubscriber])
+	assert.Equal(t, 1, stats.Active[KindOutputReader])
+	assert.Equal(t, 0, stats.Active[KindHealthChecker])
+
+	h1.Done()
+	h1.Done() // idempotent
+	assert.Equal(t, 0, w.Stats().Active[KindSSESubscriber])
+}
+
+func TestWatchdog_SweepCleansLeaked(t *testing.T) {
+	w := New()
+
+	var leaked atomic.Bool
+	var cleanups atomic.Int32
+	w.Track(KindHealthChecker, "web", leaked.Load, func() { cleanups.Add(1) })
+	w.Track(KindHealthChecker, "api", func() bool { return false }, func() { cleanups.Add(1) })
+
+	assert.Equal(t, 0, w.Sweep())
+	assert.Equal(t, int32(0), cleanups.Load())
+
+	leaked.Store(true)
+	assert.Equal(t, 1, w.Sweep())
+	assert.Equal(t, int32(1), cleanups.Load())
+
+	// A cleaned goroutine is no longer tracked
+	assert.Equal(t, 0, w.Sweep())
+
+	stats := w.Stats()
+	assert.Equal(t, 1, stats.Active[KindHealthChecker])
+	assert.Equal(t, 1, stats.Leaked[KindHealthChecker])
+}
+
+func TestWatchdog_CleanupMayCallDone(t *testing.T) {
+	w := New()
+
+	var h *Handle
+	h = w.Track(KindOutputReader, "web stdout", func() bool { return true }, func() { h.Done() })
+
+	assert.Equal(t, 1, w.Sweep())
+	assert.Equal(t, 0, w.Stats().Active[KindOutputReader])
+}
+
+func TestWatchdog_Run(t *testing.T) {
+	w := New()
+
+	cleaned := make(chan struct{})
+	w.Track(KindSSESubscriber, "sub-1", func() bool { return true }, func() { close(cleaned) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx, 10*time.Millisecond)
+
+	select {
+	case <-cleaned:
+	case <-time.After(time.Second):
+		t.Fatal("leaked goroutine was not cleaned")
+	}
+}
+
+func TestWatchdog_Nil(t *testing.T) {
+	var w *Watchdog
+
+	h := w.Track(KindSSESubscriber, "sub-1", nil, nil)
+	h.Done()
+	assert.Equal(t, 0, w.Sweep())
+	assert.Equal(t, 0, w.Stats().Active[KindSSESubscriber])
+}
+
+func TestClosed(t *testing.T) {
+	ch := make(chan struct{})
+	assert.False(t, Closed(ch))
+	close(ch)
+	assert.True(t, Closed(ch))
+}
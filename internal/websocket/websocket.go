@@ -0,0 +1,295 @@
+// Package websocket implements the small part of the WebSocket protocol (RFC
+// 6455) prox uses to carry a process's input and output over its API: the
+// opening handshake, data messages, ping, pong, and close. Extensions and
+// subprotocols aren't supported.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Opcodes of WebSocket frames
+const (
+	OpContinuation byte = 0x0
+	OpText         byte = 0x1
+	OpBinary       byte = 0x2
+	OpClose        byte = 0x8
+	OpPing         byte = 0x9
+	OpPong         byte = 0xA
+)
+
+// MaxMessageSize is the largest message ReadMessage accepts
+const MaxMessageSize = 1 << 20
+
+// acceptGUID is appended to the client's key to compute Sec-WebSocket-Accept
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// closeNormal is the payload of a close frame with status 1000 (normal
+// closure)
+var closeNormal = []byte{0x03, 0xE8}
+
+var (
+	// ErrBadHandshake is returned when a request or response isn't a valid
+	// WebSocket handshake
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+	// ErrMessageTooLarge is returned for a message over MaxMessageSize
+	ErrMessageTooLarge = errors.New("websocket: message too large")
+	// ErrProtocol is returned for frames that break the protocol
+	ErrProtocol = errors.New("websocket: protocol error")
+)
+
+// Conn is a WebSocket connection. One goroutine may read messages while
+// others write them.
+type Conn struct {
+	conn   io.ReadWriteCloser
+	br     *bufio.Reader
+	client bool // Clients mask the frames they write
+
+	wmu sync.Mutex // Serializes frame writes
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// IsUpgrade reports whether r asks to upgrade to a WebSocket
+func IsUpgrade(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Upgrade completes the handshake of a WebSocket request and takes over its
+// connection. It returns ErrBadHandshake, having written nothing, when r
+// isn't a valid WebSocket request, so the caller can respond with an error.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !IsUpgrade(r) || key == "" ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, ErrBadHandshake
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	// The server's read and write timeouts don't apply to the WebSocket
+	_ = conn.SetDeadline(time.Time{})
+
+	fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	return &Conn{conn: conn, br: brw.Reader}, nil
+}
+
+// Dial opens a WebSocket connection to url, an http or https URL, through
+// client, sending header with the handshake. When the server refuses the
+// upgrade, Dial returns ErrBadHandshake with the response, whose body the
+// caller must close.
+func Dial(client *http.Client, url string, header http.Header) (*Conn, *http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, resp, ErrBadHandshake
+	}
+	body, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok || resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		resp.Body.Close()
+		return nil, nil, ErrBadHandshake
+	}
+	return &Conn{conn: body, br: bufio.NewReader(body), client: true}, resp, nil
+}
+
+// acceptKey returns the Sec-WebSocket-Accept value for a client's key
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ReadMessage reads the next text or binary message, answering pings and
+// skipping pongs on the way. It returns io.EOF once the peer closes the
+// connection.
+func (c *Conn) ReadMessage() (op byte, data []byte, err error) {
+	for {
+		fin, frameOp, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch frameOp {
+		case OpPing:
+			if err := c.writeFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			continue
+		case OpClose:
+			c.Close()
+			return 0, nil, io.EOF
+		case OpContinuation:
+			if op == 0 {
+				return 0, nil, ErrProtocol
+			}
+		case OpText, OpBinary:
+			if op != 0 {
+				return 0, nil, ErrProtocol
+			}
+			op = frameOp
+		default:
+			return 0, nil, ErrProtocol
+		}
+
+		if len(data)+len(payload) > MaxMessageSize {
+			return 0, nil, ErrMessageTooLarge
+		}
+		data = append(data, payload...)
+		if fin {
+			return op, data, nil
+		}
+	}
+}
+
+// readFrame reads a single frame, unmasking its payload
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	op = head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+
+	size := uint64(head[1] & 0x7F)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if size > MaxMessageSize {
+		return false, 0, nil, ErrMessageTooLarge
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// WriteMessage sends data as a single message of type op
+func (c *Conn) WriteMessage(op byte, data []byte) error {
+	return c.writeFrame(op, data)
+}
+
+// writeFrame writes a single, final frame, masking it if c is a client
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|op)
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch size := len(payload); {
+	case size < 126:
+		frame = append(frame, maskBit|byte(size))
+	case size <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(size))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(size))
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[start+i] ^= mask[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// Close sends a close frame, if the connection is still open, and closes
+// it. It is safe to call more than once.
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		_ = c.writeFrame(OpClose, closeNormal)
+		c.closeErr = c.conn.Close()
+	})
+	return c.closeErr
+}
@@ -0,0 +1,115 @@
+package websocket
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoServer echoes each message back until the client closes
+func echoServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.Close()
+		for {
+			op, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(op, data); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAcceptKey(t *testing.T) {
+	// The example from RFC 6455
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", acceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func TestDialEcho(t *testing.T) {
+	server := echoServer(t)
+	conn, _, err := Dial(server.Client(), server.URL, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Short, 16-bit, and 64-bit lengths
+	for _, size := range []int{5, 300, 70000} {
+		data := bytes.Repeat([]byte("x"), size)
+		require.NoError(t, conn.WriteMessage(OpBinary, data))
+		op, got, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, OpBinary, op)
+		assert.Equal(t, data, got)
+	}
+
+	require.NoError(t, conn.WriteMessage(OpText, []byte("hello")))
+	op, got, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, OpText, op)
+	assert.Equal(t, "hello", string(got))
+}
+
+func TestPingAndClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		require.NoError(t, err)
+		require.NoError(t, conn.writeFrame(OpPing, []byte("ping")))
+		require.NoError(t, conn.WriteMessage(OpBinary, []byte("after ping")))
+		// The client's pong arrives before anything else
+		_, op, payload, err := conn.readFrame()
+		require.NoError(t, err)
+		assert.Equal(t, OpPong, op)
+		assert.Equal(t, "ping", string(payload))
+		conn.Close()
+	}))
+	defer server.Close()
+
+	conn, _, err := Dial(server.Client(), server.URL, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "after ping", string(data))
+	_, _, err = conn.ReadMessage()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDialRefused(t *testing.T) {
+	server := echoServer(t)
+
+	// Not a WebSocket request
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	_, resp, err = Dial(notFound.Client(), notFound.URL, nil)
+	assert.ErrorIs(t, err, ErrBadHandshake)
+	require.NotNil(t, resp)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestIsUpgrade(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.False(t, IsUpgrade(r))
+	r.Header.Set("Upgrade", "WebSocket")
+	r.Header.Set("Connection", "keep-alive, Upgrade")
+	assert.True(t, IsUpgrade(r))
+}
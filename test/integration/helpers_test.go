@@ -59,19 +59,32 @@ func waitForAPI(t *testing.T, addr string, timeout time.Duration) {
 	t.Fatalf("API did not become ready within %v", timeout)
 }
 
-// startProx starts the prox binary with the given arguments
-func startProx(t *testing.T, binary string, args ...string) *exec.Cmd {
+// projectDir returns a temporary project directory holding a copy of the
+// test configs and scripts, so the .prox/ state prox writes (such as
+// last-run.json) stays out of the source tree
+func projectDir(t *testing.T) string {
 	t.Helper()
 
-	// Get project root
 	wd, err := os.Getwd()
 	if err != nil {
 		t.Fatalf("failed to get working directory: %v", err)
 	}
-	projectRoot := filepath.Join(wd, "..", "..")
+	testdata := filepath.Join(wd, "..", "..", "testdata")
+
+	dir := t.TempDir()
+	if err := os.CopyFS(filepath.Join(dir, "testdata"), os.DirFS(testdata)); err != nil {
+		t.Fatalf("failed to copy testdata: %v", err)
+	}
+	return dir
+}
+
+// startProx starts the prox binary with the given arguments, in a copy of
+// the test project
+func startProx(t *testing.T, binary string, args ...string) *exec.Cmd {
+	t.Helper()
 
 	cmd := exec.Command(binary, args...)
-	cmd.Dir = projectRoot
+	cmd.Dir = projectDir(t)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -89,19 +102,13 @@ type proxWithOutput struct {
 	stderr *bytes.Buffer
 }
 
-// startProxWithOutput starts prox and captures its stdout/stderr
+// startProxWithOutput starts prox in a copy of the test project and
+// captures its stdout/stderr
 func startProxWithOutput(t *testing.T, binary string, args ...string) *proxWithOutput {
 	t.Helper()
 
-	// Get project root
-	wd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("failed to get working directory: %v", err)
-	}
-	projectRoot := filepath.Join(wd, "..", "..")
-
 	cmd := exec.Command(binary, args...)
-	cmd.Dir = projectRoot
+	cmd.Dir = projectDir(t)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout